@@ -0,0 +1,351 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+/*
+	绩效分析：根据平仓记录和资金快照计算收益、回撤、夏普比率等指标
+*/
+
+// Trade one closed position
+type Trade struct {
+	Symbol    string    `json:"symbol"`
+	OpenTime  time.Time `json:"opentime"`
+	CloseTime time.Time `json:"closetime"`
+	PnL       float64   `json:"pnl"`
+	// Notional the traded value of all the legs, which is used by the turnover
+	Notional float64 `json:"notional"`
+}
+
+// Snapshot the equity of the account at the time
+type Snapshot struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
+
+// PeriodReturn the return of a day, week or month
+type PeriodReturn struct {
+	Start  time.Time `json:"start"`
+	Return float64   `json:"return"`
+	// PnL the sum of the trades closed in the period
+	PnL    float64 `json:"pnl"`
+	Trades int     `json:"trades"`
+}
+
+// PnLPoint the cumulative pnl after the trade is closed
+type PnLPoint struct {
+	Time time.Time `json:"time"`
+	PnL  float64   `json:"pnl"`
+}
+
+// Attribution the pnl contributed by the symbol
+type Attribution struct {
+	Symbol   string  `json:"symbol"`
+	Trades   int     `json:"trades"`
+	WinRate  float64 `json:"winrate"`
+	PnL      float64 `json:"pnl"`
+	Notional float64 `json:"notional"`
+	// Share the ratio of the pnl in the total pnl
+	Share float64 `json:"share"`
+}
+
+type Report struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	Daily   []PeriodReturn `json:"daily"`
+	Weekly  []PeriodReturn `json:"weekly"`
+	Monthly []PeriodReturn `json:"monthly"`
+
+	CumulativePnL []PnLPoint `json:"cumulativepnl"`
+	TotalPnL      float64    `json:"totalpnl"`
+	TotalReturn   float64    `json:"totalreturn"`
+	AnnualReturn  float64    `json:"annualreturn"`
+
+	MaxDrawdown      float64 `json:"maxdrawdown"`
+	MaxDrawdownHours float64 `json:"maxdrawdownhours"`
+
+	Sharpe  float64 `json:"sharpe"`
+	Sortino float64 `json:"sortino"`
+	Calmar  float64 `json:"calmar"`
+
+	Turnover        float64       `json:"turnover"`
+	AvgHoldingHours float64       `json:"avgholdinghours"`
+	WinRate         float64       `json:"winrate"`
+	Attribution     []Attribution `json:"attribution"`
+}
+
+type Options struct {
+	// Location the time zone in which the days are cut
+	Location *time.Location
+	// PeriodsPerYear the number of the daily returns in one year, 365 for the crypto markets
+	PeriodsPerYear float64
+	// RiskFree the annual risk free rate
+	RiskFree float64
+}
+
+var DefaultOptions = &Options{
+	Location:       time.Local,
+	PeriodsPerYear: 365,
+}
+
+const (
+	periodDay = iota
+	periodWeek
+	periodMonth
+)
+
+func periodStart(t time.Time, period int, location *time.Location) time.Time {
+	t = t.In(location)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+	switch period {
+	case periodWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case periodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, location)
+	}
+	return day
+}
+
+// Compute calculates the report, the trades and snapshots don't need to be sorted
+func Compute(trades []Trade, snapshots []Snapshot, options *Options) *Report {
+	if options == nil {
+		options = DefaultOptions
+	}
+
+	trades = append([]Trade(nil), trades...)
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].CloseTime.Before(trades[j].CloseTime)
+	})
+
+	snapshots = append([]Snapshot(nil), snapshots...)
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+
+	report := new(Report)
+	if len(snapshots) > 0 {
+		report.Start = snapshots[0].Time
+		report.End = snapshots[len(snapshots)-1].Time
+	}
+	if len(trades) > 0 {
+		if report.Start.IsZero() || trades[0].OpenTime.Before(report.Start) {
+			report.Start = trades[0].OpenTime
+		}
+		if trades[len(trades)-1].CloseTime.After(report.End) {
+			report.End = trades[len(trades)-1].CloseTime
+		}
+	}
+
+	var returns []float64
+	report.Daily, returns = periodReturns(trades, snapshots, periodDay, options.Location)
+	report.Weekly, _ = periodReturns(trades, snapshots, periodWeek, options.Location)
+	report.Monthly, _ = periodReturns(trades, snapshots, periodMonth, options.Location)
+
+	computeTrades(report, trades)
+	computeEquity(report, snapshots)
+	computeRatios(report, returns, options)
+
+	if len(snapshots) > 0 {
+		var total float64
+		for _, snapshot := range snapshots {
+			total += snapshot.Equity
+		}
+		if average := total / float64(len(snapshots)); average > 0 {
+			var notional float64
+			for _, trade := range trades {
+				notional += trade.Notional
+			}
+			report.Turnover = notional / average
+		}
+	}
+
+	return report
+}
+
+// periodReturns returns all the periods, and the returns of the periods which have the snapshots
+func periodReturns(trades []Trade, snapshots []Snapshot, period int, location *time.Location) ([]PeriodReturn, []float64) {
+	type bucket struct {
+		item   PeriodReturn
+		equity float64
+		valid  bool
+	}
+
+	buckets := make(map[time.Time]*bucket)
+	get := func(t time.Time) *bucket {
+		start := periodStart(t, period, location)
+		if buckets[start] == nil {
+			buckets[start] = &bucket{item: PeriodReturn{Start: start}}
+		}
+		return buckets[start]
+	}
+
+	for _, trade := range trades {
+		b := get(trade.CloseTime)
+		b.item.PnL += trade.PnL
+		b.item.Trades++
+	}
+
+	// the equity of the period is the last snapshot in it
+	for _, snapshot := range snapshots {
+		b := get(snapshot.Time)
+		b.equity = snapshot.Equity
+		b.valid = true
+	}
+
+	var result []PeriodReturn
+	var returns []float64
+	var starts []time.Time
+	for start := range buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+
+	var last float64
+	if len(snapshots) > 0 {
+		last = snapshots[0].Equity
+	}
+	for _, start := range starts {
+		b := buckets[start]
+		if b.valid {
+			if last > 0 {
+				b.item.Return = b.equity/last - 1
+			}
+			last = b.equity
+			returns = append(returns, b.item.Return)
+		}
+		result = append(result, b.item)
+	}
+
+	return result, returns
+}
+
+func computeTrades(report *Report, trades []Trade) {
+	if len(trades) == 0 {
+		return
+	}
+
+	symbols := make(map[string]*Attribution)
+	symbolWins := make(map[string]int)
+	var wins int
+	var holding time.Duration
+
+	for _, trade := range trades {
+		report.TotalPnL += trade.PnL
+		report.CumulativePnL = append(report.CumulativePnL, PnLPoint{
+			Time: trade.CloseTime,
+			PnL:  report.TotalPnL,
+		})
+
+		holding += trade.CloseTime.Sub(trade.OpenTime)
+
+		attribution := symbols[trade.Symbol]
+		if attribution == nil {
+			attribution = &Attribution{Symbol: trade.Symbol}
+			symbols[trade.Symbol] = attribution
+		}
+		attribution.Trades++
+		attribution.PnL += trade.PnL
+		attribution.Notional += trade.Notional
+		if trade.PnL > 0 {
+			wins++
+			symbolWins[trade.Symbol]++
+		}
+	}
+
+	report.WinRate = float64(wins) / float64(len(trades))
+	report.AvgHoldingHours = holding.Hours() / float64(len(trades))
+
+	for symbol, attribution := range symbols {
+		attribution.WinRate = float64(symbolWins[symbol]) / float64(attribution.Trades)
+		if report.TotalPnL != 0 {
+			attribution.Share = attribution.PnL / report.TotalPnL
+		}
+		report.Attribution = append(report.Attribution, *attribution)
+	}
+
+	sort.Slice(report.Attribution, func(i, j int) bool {
+		return report.Attribution[i].PnL > report.Attribution[j].PnL
+	})
+}
+
+func computeEquity(report *Report, snapshots []Snapshot) {
+	if len(snapshots) == 0 {
+		return
+	}
+
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+	if first.Equity > 0 {
+		report.TotalReturn = last.Equity/first.Equity - 1
+		if days := last.Time.Sub(first.Time).Hours() / 24; days > 0 && last.Equity > 0 {
+			report.AnnualReturn = math.Pow(last.Equity/first.Equity, 365/days) - 1
+		}
+	}
+
+	// the duration is from the peak to the recovery, or to the last snapshot if it is not recovered
+	peak := first
+	var longest time.Duration
+	var underwater bool
+	for _, snapshot := range snapshots {
+		if snapshot.Equity >= peak.Equity {
+			if duration := snapshot.Time.Sub(peak.Time); underwater && duration > longest {
+				longest = duration
+			}
+			peak = snapshot
+			underwater = false
+			continue
+		}
+
+		underwater = true
+		if peak.Equity > 0 {
+			if drawdown := (peak.Equity - snapshot.Equity) / peak.Equity; drawdown > report.MaxDrawdown {
+				report.MaxDrawdown = drawdown
+			}
+		}
+		if duration := snapshot.Time.Sub(peak.Time); duration > longest {
+			longest = duration
+		}
+	}
+
+	report.MaxDrawdownHours = longest.Hours()
+}
+
+func computeRatios(report *Report, returns []float64, options *Options) {
+	if report.MaxDrawdown > 0 {
+		report.Calmar = report.AnnualReturn / report.MaxDrawdown
+	}
+
+	if len(returns) < 2 || options.PeriodsPerYear <= 0 {
+		return
+	}
+
+	riskFree := options.RiskFree / options.PeriodsPerYear
+	var mean, downside float64
+	for _, value := range returns {
+		mean += value - riskFree
+		if value < riskFree {
+			downside += (value - riskFree) * (value - riskFree)
+		}
+	}
+	mean /= float64(len(returns))
+	downside = math.Sqrt(downside / float64(len(returns)))
+
+	var variance float64
+	for _, value := range returns {
+		variance += (value - riskFree - mean) * (value - riskFree - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(returns)-1))
+
+	annual := math.Sqrt(options.PeriodsPerYear)
+	if stddev > 0 {
+		report.Sharpe = mean / stddev * annual
+	}
+	if downside > 0 {
+		report.Sortino = mean / downside * annual
+	}
+}
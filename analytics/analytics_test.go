@@ -0,0 +1,82 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func testDay(day int, hour int) time.Time {
+	return time.Date(2018, 3, 4+day, hour, 0, 0, 0, time.UTC)
+}
+
+func checkFloat(t *testing.T, name string, value float64, expect float64) {
+	if math.Abs(value-expect) > 1e-9*math.Max(1, math.Abs(expect)) {
+		t.Errorf("%s:%v Expect:%v", name, value, expect)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	snapshots := []Snapshot{
+		{Time: testDay(3, 23), Equity: 121},
+		{Time: testDay(0, 23), Equity: 100},
+		{Time: testDay(1, 23), Equity: 110},
+		{Time: testDay(2, 23), Equity: 99},
+	}
+
+	trades := []Trade{
+		{Symbol: "eth/usdt", OpenTime: testDay(0, 0), CloseTime: testDay(0, 12), PnL: 10, Notional: 200},
+		{Symbol: "btc/usdt", OpenTime: testDay(1, 12), CloseTime: testDay(2, 12), PnL: -5, Notional: 100},
+		{Symbol: "eth/usdt", OpenTime: testDay(3, 0), CloseTime: testDay(3, 6), PnL: 15, Notional: 300},
+	}
+
+	report := Compute(trades, snapshots, &Options{Location: time.UTC, PeriodsPerYear: 365})
+
+	if len(report.Daily) != 4 || len(report.Weekly) != 2 || len(report.Monthly) != 1 {
+		t.Fatalf("Invalid periods:%v %v %v", report.Daily, report.Weekly, report.Monthly)
+	}
+	checkFloat(t, "Daily", report.Daily[1].Return, 0.1)
+	checkFloat(t, "Daily", report.Daily[2].Return, -0.1)
+	checkFloat(t, "Daily PnL", report.Daily[2].PnL, -5)
+	// 2018-03-04 is Sunday
+	checkFloat(t, "Weekly", report.Weekly[1].Return, 0.21)
+	checkFloat(t, "Monthly", report.Monthly[0].Return, 0.21)
+
+	checkFloat(t, "TotalPnL", report.TotalPnL, 20)
+	if len(report.CumulativePnL) != 3 || report.CumulativePnL[1].PnL != 5 {
+		t.Errorf("Invalid cumulative pnl:%v", report.CumulativePnL)
+	}
+
+	checkFloat(t, "TotalReturn", report.TotalReturn, 0.21)
+	checkFloat(t, "MaxDrawdown", report.MaxDrawdown, 0.1)
+	checkFloat(t, "MaxDrawdownHours", report.MaxDrawdownHours, 48)
+	checkFloat(t, "Sharpe", report.Sharpe, 7.69761305556773)
+	checkFloat(t, "Sortino", report.Sortino, 21.22774797171423)
+	checkFloat(t, "Calmar", report.Calmar, report.AnnualReturn/0.1)
+
+	checkFloat(t, "Turnover", report.Turnover, 600/107.5)
+	checkFloat(t, "AvgHoldingHours", report.AvgHoldingHours, 14)
+	checkFloat(t, "WinRate", report.WinRate, 2.0/3)
+
+	if len(report.Attribution) != 2 || report.Attribution[0].Symbol != "eth/usdt" {
+		t.Fatalf("Invalid attribution:%v", report.Attribution)
+	}
+	checkFloat(t, "Attribution", report.Attribution[0].PnL, 25)
+	checkFloat(t, "Share", report.Attribution[0].Share, 1.25)
+	checkFloat(t, "Share", report.Attribution[1].Share, -0.25)
+}
+
+func TestComputeUnrecovered(t *testing.T) {
+	snapshots := []Snapshot{
+		{Time: testDay(0, 0), Equity: 100},
+		{Time: testDay(1, 0), Equity: 80},
+		{Time: testDay(2, 0), Equity: 90},
+	}
+
+	report := Compute(nil, snapshots, &Options{Location: time.UTC, PeriodsPerYear: 365})
+	checkFloat(t, "MaxDrawdown", report.MaxDrawdown, 0.2)
+	checkFloat(t, "MaxDrawdownHours", report.MaxDrawdownHours, 48)
+	if report.TotalPnL != 0 || report.Attribution != nil {
+		t.Errorf("Invalid report:%v", report)
+	}
+}
@@ -0,0 +1,113 @@
+package analytics
+
+import (
+	"strings"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	MongoTrend "madaoQT/mongo/trend"
+)
+
+// 现货手续费率和合约每张手续费
+const spotFeeRate = 0.002
+const futureFee = 100 * 2 * 0.0005
+
+// FundProfit the profit of the spot and future legs of the okexdiff record, contractRatio is the value of one contract in usd
+func FundProfit(record Mongo.FundInfo, contractRatio float64) (spotProfit float64, futureProfit float64, fee float64) {
+
+	if record.SpotType == Exchange.TradeTypeString[Exchange.TradeTypeBuy] {
+		spotProfit = (record.SpotClose - record.SpotOpen) * record.SpotAmount
+	} else {
+		spotProfit = (record.SpotOpen - record.SpotClose) * record.SpotAmount
+	}
+	fee = (record.SpotOpen + record.SpotClose) * record.SpotAmount * spotFeeRate
+
+	// the future leg is invalid
+	if record.FutureOpen == 0 || record.FutureClose == 0 {
+		return
+	}
+
+	amount := contractRatio * record.FutureAmount
+	if record.FutureType == Exchange.TradeTypeString[Exchange.TradeTypeOpenLong] {
+		futureProfit = (amount/record.FutureClose - amount/record.FutureOpen) * record.FutureClose * (-1)
+	} else {
+		futureProfit = (amount/record.FutureOpen - amount/record.FutureClose) * record.FutureClose * (-1)
+	}
+	fee += futureFee
+
+	return
+}
+
+// FromFunds converts the closed records of okexdiff
+func FromFunds(records []Mongo.FundInfo, contractRatio map[string]float64) []Trade {
+	var trades []Trade
+	for _, record := range records {
+		if record.Status != Mongo.FundStatusClose {
+			continue
+		}
+
+		coin := Exchange.ParsePair(record.Pair)[0]
+		spotProfit, futureProfit, fee := FundProfit(record, contractRatio[coin])
+		trades = append(trades, Trade{
+			Symbol:    record.Pair,
+			OpenTime:  record.OpenTime,
+			CloseTime: record.CloseTime,
+			PnL:       spotProfit + futureProfit - fee,
+			Notional:  (record.SpotOpen+record.SpotClose)*record.SpotAmount + 2*contractRatio[coin]*record.FutureAmount,
+		})
+	}
+	return trades
+}
+
+// FromTrendTrades converts the closed records of trend, the amount is counted in the coin
+func FromTrendTrades(records []MongoTrend.TradeInfo) []Trade {
+	var trades []Trade
+	for _, record := range records {
+		if record.Status != MongoTrend.TradeStatusClose {
+			continue
+		}
+
+		pnl := (record.FutureClose - record.FutureOpen) * record.FutureAmount
+		if record.FutureType == Exchange.TradeTypeString[Exchange.TradeTypeOpenShort] ||
+			record.FutureType == Exchange.TradeTypeString[Exchange.TradeTypeSell] {
+			pnl = -pnl
+		}
+
+		trades = append(trades, Trade{
+			Symbol:    record.Pair,
+			OpenTime:  record.OpenTime,
+			CloseTime: record.CloseTime,
+			PnL:       pnl,
+			Notional:  (record.FutureOpen + record.FutureClose) * record.FutureAmount,
+		})
+	}
+	return trades
+}
+
+// FromBalances converts the balances of the coin saved by okexdiff
+func FromBalances(records []Mongo.BalanceInfo, coin string) []Snapshot {
+	var snapshots []Snapshot
+	for _, record := range records {
+		for _, item := range record.Coins {
+			if strings.ToLower(item.Coin) == strings.ToLower(coin) {
+				snapshots = append(snapshots, Snapshot{Time: record.Time, Equity: item.Balance})
+				break
+			}
+		}
+	}
+	return snapshots
+}
+
+// FromTrendBalances converts the balances of the coin saved by trend
+func FromTrendBalances(records []MongoTrend.BalanceInfo, coin string) []Snapshot {
+	var snapshots []Snapshot
+	for _, record := range records {
+		for _, item := range record.Item {
+			if strings.ToLower(item.Coin) == strings.ToLower(coin) {
+				snapshots = append(snapshots, Snapshot{Time: record.Time, Equity: item.Balance})
+				break
+			}
+		}
+	}
+	return snapshots
+}
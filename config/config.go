@@ -19,3 +19,6 @@ const Version = "0.9.2"
 
 // ServerPort indication the port of the server
 var ServerPort = "80"
+
+// RegistryAddress the address of the gRPC registry which the strategy processes register to
+var RegistryAddress = "127.0.0.1:9090"
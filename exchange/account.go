@@ -0,0 +1,82 @@
+package exchange
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+	账户快照：余额、持仓和保证金，用于组合层面的敞口和保证金监控
+	持仓数量统一换算为基础币数量，做空为负数；合约张数按合约面值和标记价格换算
+*/
+
+// AccountPosition the position of the account, Quantity is in the base asset and negative for the short
+// positions, the quote asset of the pair is the currency of the mark price
+type AccountPosition struct {
+	Pair     string  `json:"pair"`
+	Quantity float64 `json:"quantity"`
+	// Amount the position in the unit of the orders, e.g. the contracts, negative for the short positions
+	Amount           float64 `json:"amount"`
+	MarkPrice        float64 `json:"markprice"`
+	LiquidationPrice float64 `json:"liquidationprice"`
+	// Paid the cost of the holding is paid by the balance of the quote asset, e.g. the stocks
+	Paid bool `json:"paid"`
+}
+
+// AccountInfo the balances, the positions and the margin of the account
+type AccountInfo struct {
+	// Balances the equities of the assets, the unrealized profits of the positions are included
+	Balances  map[string]float64 `json:"balances"`
+	Positions []AccountPosition  `json:"positions"`
+	// MarginRatio the maintenance margin divided by the equity, the positions are liquidated at 1. It's 0 if the
+	// exchange doesn't report the margin
+	MarginRatio float64 `json:"marginratio"`
+}
+
+// IAccount the exchange which reports the snapshot of the account
+type IAccount interface {
+	GetAccount() (error, *AccountInfo)
+}
+
+// IOpenOrders the exchange which lists the orders of the pair which aren't filled, e.g. for the reconciliation
+type IOpenOrders interface {
+	GetOpenOrders(pair string) (error, []OrderInfo)
+}
+
+// InverseContractValue the face value in USD of the inverse contracts of OKEX and Huobi
+func InverseContractValue(coin string) float64 {
+	if strings.ToLower(coin) == "btc" {
+		return 100
+	}
+	return 10
+}
+
+// accountFloat the numbers are strings in the responses of some exchanges
+func accountFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+// addBalance adds the balance of the asset, the zero balances are ignored
+func (a *AccountInfo) addBalance(asset string, value float64) {
+	if value == 0 {
+		return
+	}
+	if a.Balances == nil {
+		a.Balances = make(map[string]float64)
+	}
+	a.Balances[strings.ToLower(asset)] += value
+}
+
+// setMarginRatio keeps the highest ratio of the sub-accounts
+func (a *AccountInfo) setMarginRatio(ratio float64) {
+	if ratio > a.MarginRatio {
+		a.MarginRatio = ratio
+	}
+}
@@ -0,0 +1,166 @@
+package exchange
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func accountEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func checkPosition(t *testing.T, position AccountPosition, expect AccountPosition) {
+	t.Helper()
+	if position.Pair != expect.Pair || !accountEqual(position.Quantity, expect.Quantity) || position.Amount != expect.Amount ||
+		position.MarkPrice != expect.MarkPrice || position.LiquidationPrice != expect.LiquidationPrice ||
+		position.Paid != expect.Paid {
+		t.Errorf("Position:%v Expect:%v", position, expect)
+	}
+}
+
+func TestParseOkexV3SwapAccount(t *testing.T) {
+	accounts := []byte(`{"info":[
+		{"instrument_id":"BTC-USD-SWAP","equity":"0.5","margin_ratio":"0.1","maint_margin_ratio":"0.005"},
+		{"instrument_id":"ETH-USD-SWAP","equity":"","margin_ratio":"","maint_margin_ratio":"0.01"}]}`)
+	positions := []byte(`[{"margin_mode":"crossed","holding":[
+		{"instrument_id":"BTC-USD-SWAP","position":"20","side":"short","last":"10000","liquidation_price":"12000"},
+		{"instrument_id":"ETH-USD-SWAP","position":"0","side":"long","last":"200"}]}]`)
+
+	err, account := parseOkexV3SwapAccount(accounts, positions)
+	if err != nil {
+		t.Fatalf("Fail to parse:%v", err)
+	}
+	if len(account.Balances) != 1 || account.Balances["btc"] != 0.5 || !accountEqual(account.MarginRatio, 0.05) {
+		t.Errorf("Invalid account:%v", account)
+	}
+	if len(account.Positions) != 1 {
+		t.Fatalf("Invalid positions:%v", account.Positions)
+	}
+	checkPosition(t, account.Positions[0], AccountPosition{Pair: "btc/usd", Quantity: -0.2, Amount: -20, MarkPrice: 10000, LiquidationPrice: 12000})
+
+	if err, _ = parseOkexV3SwapAccount([]byte(`{"code":30008,"message":"timestamp expired"}`), positions); err == nil ||
+		err.Error() != "timestamp expired" {
+		t.Errorf("The error should be returned:%v", err)
+	}
+}
+
+func TestParseHuobiContractAccount(t *testing.T) {
+	var accounts, positions map[string]interface{}
+	json.Unmarshal([]byte(`{"status":"ok","data":[
+		{"symbol":"ETH","margin_balance":2,"risk_rate":4,"liquidation_price":150}]}`), &accounts)
+	json.Unmarshal([]byte(`{"status":"ok","data":[
+		{"symbol":"ETH","volume":30,"direction":"buy","last_price":200}]}`), &positions)
+
+	err, account := parseHuobiContractAccount(accounts, positions)
+	if err != nil {
+		t.Fatalf("Fail to parse:%v", err)
+	}
+	if account.Balances["eth"] != 2 || account.MarginRatio != 0.25 || len(account.Positions) != 1 {
+		t.Fatalf("Invalid account:%v", account)
+	}
+	checkPosition(t, account.Positions[0], AccountPosition{Pair: "eth/usd", Quantity: 1.5, Amount: 30, MarkPrice: 200, LiquidationPrice: 150})
+
+	json.Unmarshal([]byte(`{"status":"error","err_msg":"invalid key"}`), &positions)
+	if err, _ = parseHuobiContractAccount(accounts, positions); err == nil {
+		t.Errorf("The error should be returned")
+	}
+}
+
+func TestParseBitmexAccount(t *testing.T) {
+	margin := []byte(`{"currency":"XBt","marginBalance":50000000,"maintMargin":5000000}`)
+	positions := []byte(`[
+		{"underlying":"XBT","quoteCurrency":"USD","isOpen":true,"homeNotional":-0.3,"currentQty":-3000,"markPrice":10000,"liquidationPrice":13000},
+		{"underlying":"ETH","quoteCurrency":"USD","isOpen":false,"homeNotional":0}]`)
+
+	err, account := parseBitmexAccount(margin, positions)
+	if err != nil {
+		t.Fatalf("Fail to parse:%v", err)
+	}
+	if account.Balances["btc"] != 0.5 || account.MarginRatio != 0.1 || len(account.Positions) != 1 {
+		t.Fatalf("Invalid account:%v", account)
+	}
+	checkPosition(t, account.Positions[0], AccountPosition{Pair: "btc/usd", Quantity: -0.3, Amount: -3000, MarkPrice: 10000, LiquidationPrice: 13000})
+
+	if err, _ = parseBitmexAccount([]byte(`{"error":{"message":"Invalid API Key.","name":"HTTPError"}}`), positions); err == nil ||
+		err.Error() != "Invalid API Key." {
+		t.Errorf("The error should be returned:%v", err)
+	}
+}
+
+func TestParseDeribitAccount(t *testing.T) {
+	var summary, positions interface{}
+	json.Unmarshal([]byte(`{"currency":"BTC","equity":1,"maintenance_margin":0.2}`), &summary)
+	json.Unmarshal([]byte(`[{"instrument_name":"BTC-PERPETUAL","size":-5000,"mark_price":10000,"estimated_liquidation_price":19000}]`), &positions)
+
+	account := parseDeribitAccount(map[string]interface{}{"BTC": summary}, map[string]interface{}{"BTC": positions})
+	if account.Balances["btc"] != 1 || account.MarginRatio != 0.2 || len(account.Positions) != 1 {
+		t.Fatalf("Invalid account:%v", account)
+	}
+	checkPosition(t, account.Positions[0], AccountPosition{Pair: "btc/usd", Quantity: -0.5, Amount: -5000, MarkPrice: 10000, LiquidationPrice: 19000})
+}
+
+func TestParseOandaAccount(t *testing.T) {
+	var account, prices map[string]interface{}
+	json.Unmarshal([]byte(`{"account":{"currency":"USD","NAV":"1000.5","marginCloseoutPercent":"0.3","positions":[
+		{"instrument":"EUR_USD","long":{"units":"1000"},"short":{"units":"-200"}},
+		{"instrument":"USD_JPY","long":{"units":"0"},"short":{"units":"0"}}]}}`), &account)
+	json.Unmarshal([]byte(`{"prices":[{"instrument":"EUR_USD","closeoutBid":"1.1","closeoutAsk":"1.2"}]}`), &prices)
+
+	err, result := parseOandaAccount(account, prices)
+	if err != nil {
+		t.Fatalf("Fail to parse:%v", err)
+	}
+	if result.Balances["usd"] != 1000.5 || result.MarginRatio != 0.3 || len(result.Positions) != 1 {
+		t.Fatalf("Invalid account:%v", result)
+	}
+	checkPosition(t, result.Positions[0], AccountPosition{Pair: "eur/usd", Quantity: 800, Amount: 800, MarkPrice: 1.15})
+}
+
+func TestParseIBAccount(t *testing.T) {
+	var ledger, summary map[string]interface{}
+	var positions []interface{}
+	json.Unmarshal([]byte(`{"BASE":{"cashbalance":2000},"USD":{"cashbalance":1500},"HKD":{"cashbalance":3900}}`), &ledger)
+	json.Unmarshal([]byte(`{"netliquidation":{"amount":10000},"maintmarginreq":{"amount":2500}}`), &summary)
+	json.Unmarshal([]byte(`[
+		{"contractDesc":"AAPL","position":10,"mktPrice":300,"currency":"USD","assetClass":"STK"},
+		{"contractDesc":"ES DEC2020","position":-1,"mktPrice":3000,"currency":"USD","assetClass":"FUT"}]`), &positions)
+
+	account := parseIBAccount(ledger, summary, positions)
+	if len(account.Balances) != 2 || account.Balances["usd"] != 1500 || account.MarginRatio != 0.25 || len(account.Positions) != 2 {
+		t.Fatalf("Invalid account:%v", account)
+	}
+	checkPosition(t, account.Positions[0], AccountPosition{Pair: "aapl/usd", Quantity: 10, Amount: 10, MarkPrice: 300, Paid: true})
+	checkPosition(t, account.Positions[1], AccountPosition{Pair: "es/usd", Quantity: -1, Amount: -1, MarkPrice: 3000})
+}
+
+func TestParseOpenOrders(t *testing.T) {
+	okex := &OKEXV3API{InstrumentType: InstrumentTypeSwap}
+	err, orders := okex.parseOpenOrders("btc/usdt", []byte(`{"order_info":[
+		{"order_id":"1","price":"9000","size":"10","filled_qty":"2","type":"1","state":"1"}]}`))
+	if err != nil || len(orders) != 1 || orders[0].OrderID != "1" || orders[0].Type != TradeTypeOpenLong ||
+		orders[0].Amount != 10 || orders[0].DealAmount != 2 || orders[0].Status != OrderStatusPartDone {
+		t.Errorf("Invalid swap orders:%v %v", err, orders)
+	}
+
+	err, orders = okex.parseOpenOrders("eth/usdt", []byte(`[
+		{"order_id":"2","price":"200","size":"1.5","filled_size":"0","side":"sell","state":"0"}]`))
+	if err != nil || len(orders) != 1 || orders[0].Type != TradeTypeSell || orders[0].Status != OrderStatusOpen {
+		t.Errorf("Invalid spot orders:%v %v", err, orders)
+	}
+
+	if err, _ = okex.parseOpenOrders("eth/usdt", []byte(`{"code":30008,"message":"timestamp expired"}`)); err == nil {
+		t.Errorf("The error should be returned")
+	}
+
+	binance := new(Binance)
+	err, orders = binance.parseOpenOrders("eth/usdt", []byte(`[
+		{"clientOrderId":"abc","price":"200.5","origQty":"1","executedQty":"0.5","status":"PARTIALLY_FILLED","side":"SELL"}]`))
+	if err != nil || len(orders) != 1 || orders[0].OrderID != "abc" || orders[0].Type != TradeTypeSell ||
+		orders[0].Price != 200.5 || orders[0].Status != OrderStatusPartDone {
+		t.Errorf("Invalid binance orders:%v %v", err, orders)
+	}
+	if err, _ = binance.parseOpenOrders("eth/usdt", []byte(`{"code":-2015,"msg":"Invalid API-key"}`)); err == nil {
+		t.Errorf("The error should be returned")
+	}
+}
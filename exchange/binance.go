@@ -269,6 +269,78 @@ func (p *Binance) GetBalance() map[string]interface{} {
 	return nil
 }
 
+// GetAccount the spot account reports the free and the locked balances
+func (p *Binance) GetAccount() (error, *AccountInfo) {
+	err, response := p.orderRequest("GET", "/api/v3/account", map[string]string{})
+	if err != nil {
+		return err, nil
+	}
+	return parseBinanceAccount(response)
+}
+
+func parseBinanceAccount(response []byte) (error, *AccountInfo) {
+	var values struct {
+		Msg      string                   `json:"msg"`
+		Balances []map[string]interface{} `json:"balances"`
+	}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, nil
+	}
+	if values.Msg != "" {
+		return errors.New(values.Msg), nil
+	}
+
+	account := &AccountInfo{}
+	for _, balance := range values.Balances {
+		if asset, ok := balance["asset"].(string); ok {
+			account.addBalance(asset, accountFloat(balance["free"])+accountFloat(balance["locked"]))
+		}
+	}
+	return nil, account
+}
+
+func binanceTransferParams(config TransferConfig) map[string]string {
+	params := map[string]string{
+		"asset":           strings.ToUpper(config.Coin),
+		"amount":          formatTransferAmount(config.Amount),
+		"fromAccountType": "SPOT",
+		"toAccountType":   "SPOT",
+	}
+	// the master account is used if the email isn't set
+	if config.ToMaster {
+		params["fromEmail"] = config.SubAccount
+	} else {
+		params["toEmail"] = config.SubAccount
+	}
+	return params
+}
+
+func parseBinanceTransfer(response []byte) (error, string) {
+	var values struct {
+		TranID json.Number `json:"tranId"`
+		Msg    string      `json:"msg"`
+	}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, ""
+	}
+	if values.TranID == "" {
+		if values.Msg != "" {
+			return errors.New(values.Msg), ""
+		}
+		return errors.New("Invalid response"), ""
+	}
+	return nil, values.TranID.String()
+}
+
+// SubAccountTransfer the transfer between the spot accounts, the keys should be the keys of the master account
+func (p *Binance) SubAccountTransfer(config TransferConfig) (error, string) {
+	err, response := p.orderRequest("POST", "/sapi/v1/sub-account/universalTransfer", binanceTransferParams(config))
+	if err != nil {
+		return err, ""
+	}
+	return parseBinanceTransfer(response)
+}
+
 // Trade() trade as the configs
 func (p *Binance) Trade(configs TradeConfig) *TradeResult {
 	symbol := p.getSymbol(configs.Pair)
@@ -379,6 +451,53 @@ func (p *Binance) GetOrderInfo(filter OrderInfo) []OrderInfo {
 	}
 }
 
+// GetOpenOrders the IDs are the client order IDs, the same as the ones returned by Trade
+func (p *Binance) GetOpenOrders(pair string) (error, []OrderInfo) {
+	err, response := p.orderRequest("GET", "/api/v3/openOrders", map[string]string{
+		"symbol": p.getSymbol(pair),
+	})
+	if err != nil {
+		return err, nil
+	}
+	return p.parseOpenOrders(pair, response)
+}
+
+func (p *Binance) parseOpenOrders(pair string, response []byte) (error, []OrderInfo) {
+	var values interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, nil
+	}
+	if message, ok := values.(map[string]interface{}); ok {
+		return fmt.Errorf("%v", message["msg"]), nil
+	}
+
+	list, _ := values.([]interface{})
+	var orders []OrderInfo
+	for _, item := range list {
+		order, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		side, _ := order["side"].(string)
+		status, _ := order["status"].(string)
+		orderID, _ := order["clientOrderId"].(string)
+		info := OrderInfo{
+			Pair:       pair,
+			OrderID:    orderID,
+			Price:      accountFloat(order["price"]),
+			Amount:     accountFloat(order["origQty"]),
+			DealAmount: accountFloat(order["executedQty"]),
+			Status:     p.getStatusType(status),
+			Type:       TradeTypeBuy,
+		}
+		if side == BinanceTradeTypeMap[TradeTypeSell] {
+			info.Type = TradeTypeSell
+		}
+		orders = append(orders, info)
+	}
+	return nil, orders
+}
+
 func (p *Binance) GetKline(pair string, period int, limit int) []KlineValue {
 	coins := ParsePair(pair)
 	symbol := strings.ToUpper(coins[0] + coins[1])
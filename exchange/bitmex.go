@@ -100,7 +100,7 @@ func (p *ExchangeBitmex) orderRequest(method string, path string, params map[str
 		if err != nil {
 			return err, nil
 		}
-	} else if method == "POST" {
+	} else if method == "POST" || method == "DELETE" {
 		request, err = http.NewRequest(method, BitmexAPIRoot+path, strings.NewReader(bodystr))
 		if err != nil {
 			return err, nil
@@ -113,7 +113,7 @@ func (p *ExchangeBitmex) orderRequest(method string, path string, params map[str
 
 	if method == "GET" {
 		request.Header.Add("api-signature", p.sign(method, "/api/v1"+path+"?"+bodystr, expire, ""))
-	} else if method == "POST" {
+	} else if method == "POST" || method == "DELETE" {
 		request.Header.Add("api-signature", p.sign(method, "/api/v1"+path, expire, bodystr))
 		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	}
@@ -237,6 +237,82 @@ func (p *ExchangeBitmex) GetBalance() map[string]interface{} {
 	return nil
 }
 
+// GetAccount the margin is in XBT, the quantities of the positions are the home notionals
+func (p *ExchangeBitmex) GetAccount() (error, *AccountInfo) {
+	err, margin := p.orderRequest("GET", "/user/margin", map[string]string{
+		"currency": "XBt",
+	})
+	if err != nil {
+		return err, nil
+	}
+	err, positions := p.orderRequest("GET", "/position", map[string]string{})
+	if err != nil {
+		return err, nil
+	}
+	return parseBitmexAccount(margin, positions)
+}
+
+// bitmexError the error response is {"error":{"message":""}}
+func bitmexError(response []byte) error {
+	var values struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err
+	}
+	if values.Error.Message != "" {
+		return errors.New(values.Error.Message)
+	}
+	return errors.New("Invalid response")
+}
+
+// bitmexAsset XBT is the symbol of btc in Bitmex
+func bitmexAsset(symbol string) string {
+	symbol = strings.ToLower(symbol)
+	if symbol == "xbt" {
+		return "btc"
+	}
+	return symbol
+}
+
+func parseBitmexAccount(margin []byte, positions []byte) (error, *AccountInfo) {
+	var marginValues map[string]interface{}
+	if err := json.Unmarshal(margin, &marginValues); err != nil || marginValues["error"] != nil {
+		return bitmexError(margin), nil
+	}
+
+	var positionValues []map[string]interface{}
+	if err := json.Unmarshal(positions, &positionValues); err != nil {
+		return bitmexError(positions), nil
+	}
+
+	account := &AccountInfo{}
+	equity := accountFloat(marginValues["marginBalance"])
+	account.addBalance("btc", equity/1e8)
+	if equity > 0 {
+		account.setMarginRatio(accountFloat(marginValues["maintMargin"]) / equity)
+	}
+
+	for _, position := range positionValues {
+		quantity := accountFloat(position["homeNotional"])
+		if isOpen, _ := position["isOpen"].(bool); !isOpen || quantity == 0 {
+			continue
+		}
+		underlying, _ := position["underlying"].(string)
+		quote, _ := position["quoteCurrency"].(string)
+		account.Positions = append(account.Positions, AccountPosition{
+			Pair:             bitmexAsset(underlying) + "/" + bitmexAsset(quote),
+			Quantity:         quantity,
+			Amount:           accountFloat(position["currentQty"]),
+			MarkPrice:        accountFloat(position["markPrice"]),
+			LiquidationPrice: accountFloat(position["liquidationPrice"]),
+		})
+	}
+	return nil, account
+}
+
 // Trade() trade as the configs
 func (p *ExchangeBitmex) Trade(configs TradeConfig) *TradeResult {
 
@@ -298,6 +374,126 @@ func (p *ExchangeBitmex) Trade(configs TradeConfig) *TradeResult {
 	}
 }
 
+// bitmexSymbol the perpetual contract of the coin
+func bitmexSymbol(pair string) string {
+	coin := strings.ToUpper(ParsePair(pair)[0])
+	if coin == "BTC" {
+		coin = "XBT"
+	}
+	return coin + "USD"
+}
+
+// bitmexStopParams the orders close the position only and are triggered by the last price
+func bitmexStopParams(config StopOrderConfig) map[string]string {
+	side := "Buy"
+	if stopSell(config.Type) {
+		side = "Sell"
+	}
+	params := map[string]string{
+		"symbol":   bitmexSymbol(config.Pair),
+		"side":     side,
+		"orderQty": strconv.FormatFloat(config.Amount, 'f', 0, 64),
+		"execInst": "Close,LastPrice",
+	}
+
+	switch config.StopType {
+	case StopOrderLoss:
+		params["ordType"] = "Stop"
+		params["stopPx"] = formatStopPrice(config.Trigger, 1)
+	case StopOrderProfit:
+		params["ordType"] = "MarketIfTouched"
+		params["stopPx"] = formatStopPrice(config.Trigger, 1)
+	case StopOrderTrailing:
+		offset := stopDistance(config)
+		if side == "Sell" {
+			offset = -offset
+		}
+		params["ordType"] = "Stop"
+		params["pegPriceType"] = "TrailingStopPeg"
+		params["pegOffsetValue"] = formatStopPrice(offset, 1)
+	}
+	return params
+}
+
+func parseBitmexStopOrder(response []byte) (error, string) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil || values["error"] != nil {
+		return bitmexError(response), ""
+	}
+	orderID, _ := values["orderID"].(string)
+	if orderID == "" {
+		return bitmexError(response), ""
+	}
+	return nil, orderID
+}
+
+// PlaceStopOrder the conditional order of the perpetual contract
+func (p *ExchangeBitmex) PlaceStopOrder(config StopOrderConfig) (error, string) {
+	err, response := p.orderRequest("POST", "/order", bitmexStopParams(config))
+	if err != nil {
+		return err, ""
+	}
+	return parseBitmexStopOrder(response)
+}
+
+// CancelStopOrder the cancelled orders are returned in an array
+func (p *ExchangeBitmex) CancelStopOrder(pair string, orderID string) error {
+	err, response := p.orderRequest("DELETE", "/order", map[string]string{
+		"orderID": orderID,
+	})
+	if err != nil {
+		return err
+	}
+	var values []map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return bitmexError(response)
+	}
+	return nil
+}
+
+var bitmexOrderStatus = map[string]OrderStatusType{
+	"New":             OrderStatusOpen,
+	"PartiallyFilled": OrderStatusPartDone,
+	"Filled":          OrderStatusDone,
+	"Canceled":        OrderStatusCanceled,
+	"Rejected":        OrderStatusRejected,
+}
+
+func parseBitmexStopStatus(response []byte) (error, *OrderInfo) {
+	var values []map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return bitmexError(response), nil
+	}
+	if len(values) == 0 {
+		return errors.New("The order isn't found"), nil
+	}
+
+	order := values[0]
+	name, _ := order["ordStatus"].(string)
+	status, ok := bitmexOrderStatus[name]
+	if !ok {
+		status = OrderStatusUnknown
+	}
+	info := &OrderInfo{Status: status}
+	info.OrderID, _ = order["orderID"].(string)
+	info.Amount, _ = order["orderQty"].(float64)
+	info.DealAmount, _ = order["cumQty"].(float64)
+	info.AvgPrice, _ = order["avgPx"].(float64)
+	return nil, info
+}
+
+// GetStopOrder the untriggered orders are new
+func (p *ExchangeBitmex) GetStopOrder(pair string, orderID string) (error, *OrderInfo) {
+	err, response := p.orderRequest("GET", "/order", map[string]string{
+		"symbol": bitmexSymbol(pair),
+		"filter": `{"orderID":"` + orderID + `"}`,
+	})
+	if err != nil {
+		return err, nil
+	}
+	return parseBitmexStopStatus(response)
+}
+
 func (p *ExchangeBitmex) GetComposite(symbol string, count int) (error, float64) {
 
 	if err, response := p.orderRequest("GET", "/instrument/compositeIndex", map[string]string{
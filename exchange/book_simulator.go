@@ -0,0 +1,769 @@
+package exchange
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	Global "madaoQT/config"
+)
+
+/*
+	L2 order book simulator: replays the recorded book deltas and trades, and matches the orders
+	of the strategy with the estimated queue position and the injected latency
+*/
+
+const NameBookSimulator = "BookSimulator"
+
+const simEpsilon = 1e-9
+
+// BookEventType the type of the recorded book event
+type BookEventType int8
+
+const (
+	// BookEventSnapshot clears the book of the pair before setting the levels
+	BookEventSnapshot BookEventType = iota
+	// BookEventDelta sets the quantity of the levels, the quantity 0 removes the level
+	BookEventDelta
+	// BookEventTrade the trade in the market
+	BookEventTrade
+)
+
+// BookEvent one recorded event of the order book
+type BookEvent struct {
+	Type BookEventType
+	Time time.Time
+	Pair string
+
+	Bids []DepthPrice
+	Asks []DepthPrice
+
+	// Price, Quantity and Side are used by the trade, Side is TradeTypeBuy if the taker buys
+	Price    float64
+	Quantity float64
+	Side     TradeType
+}
+
+// SimulatorFill one fill of the simulated orders
+type SimulatorFill struct {
+	Time    time.Time
+	OrderID string
+	Pair    string
+	Type    TradeType
+	Price   float64
+	Amount  float64
+	// Maker whether the fill is from the resting order
+	Maker bool
+}
+
+// BookSimulator the simulated exchange driven by the recorded events, it can be used by the tasks as an IExchange
+type BookSimulator struct {
+	// OrderLatency the delay from Trade()/CancelOrder() to the order arriving at the matching engine
+	OrderLatency time.Duration
+	// MarketLatency the delay from the book changing to the strategy seeing it
+	MarketLatency time.Duration
+	// Balances the initial balances of the coins
+	Balances map[string]float64
+
+	lock   sync.Mutex
+	config Config
+	event  chan EventType
+	now    time.Time
+
+	events []BookEvent
+	// exchangeIndex the next event applied to the matching engine, marketIndex the next event seen by the strategy
+	exchangeIndex int
+	marketIndex   int
+
+	books   map[string]*simBook
+	views   map[string]*simBook
+	trades  map[string][]KlineValue
+	tickers map[string]*TickerValue
+	watches map[string]bool
+
+	orderID int
+	orders  map[string]*simOrder
+	resting []*simOrder
+	actions []*simAction
+	fills   []SimulatorFill
+}
+
+type simOrder struct {
+	info OrderInfo
+	side int
+	// queue the quantity ahead of the order at the price level
+	queue float64
+}
+
+type simAction struct {
+	time   time.Time
+	order  *simOrder
+	cancel bool
+}
+
+type simBook struct {
+	levels [2]map[float64]float64
+}
+
+func newSimBook() *simBook {
+	return &simBook{
+		levels: [2]map[float64]float64{
+			make(map[float64]float64),
+			make(map[float64]float64),
+		},
+	}
+}
+
+func (b *simBook) apply(event *BookEvent) {
+	if event.Type == BookEventSnapshot {
+		b.levels[DepthTypeBids] = make(map[float64]float64)
+		b.levels[DepthTypeAsks] = make(map[float64]float64)
+	}
+
+	b.set(DepthTypeBids, event.Bids)
+	b.set(DepthTypeAsks, event.Asks)
+}
+
+func (b *simBook) set(side int, levels []DepthPrice) {
+	for _, level := range levels {
+		if level.Quantity <= simEpsilon {
+			delete(b.levels[side], level.Price)
+		} else {
+			b.levels[side][level.Price] = level.Quantity
+		}
+	}
+}
+
+func (b *simBook) take(side int, price float64, quantity float64) {
+	if b.levels[side][price]-quantity <= simEpsilon {
+		delete(b.levels[side], price)
+	} else {
+		b.levels[side][price] -= quantity
+	}
+}
+
+func (b *simBook) quantity(side int, price float64) float64 {
+	return b.levels[side][price]
+}
+
+// depth returns the bids from high to low, or the asks from low to high
+func (b *simBook) depth(side int) []DepthPrice {
+	var list []DepthPrice
+	for price, quantity := range b.levels[side] {
+		list = append(list, DepthPrice{Price: price, Quantity: quantity})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if side == DepthTypeBids {
+			return list[i].Price > list[j].Price
+		}
+		return list[i].Price < list[j].Price
+	})
+
+	return list
+}
+
+// orderSide returns the side of the book in which the order rests
+func orderSide(tradeType TradeType) int {
+	switch tradeType {
+	case TradeTypeBuy, TradeTypeOpenLong, TradeTypeCloseShort:
+		return DepthTypeBids
+	case TradeTypeSell, TradeTypeOpenShort, TradeTypeCloseLong:
+		return DepthTypeAsks
+	}
+	return -1
+}
+
+// crosses whether the order can trade with the price of the opposite side
+func (o *simOrder) crosses(price float64) bool {
+	if o.side == DepthTypeBids {
+		return price <= o.info.Price
+	}
+	return price >= o.info.Price
+}
+
+func (o *simOrder) remain() float64 {
+	return o.info.Amount - o.info.DealAmount
+}
+
+func (o *simOrder) finished() bool {
+	return o.info.Status == OrderStatusDone || o.info.Status == OrderStatusCanceled
+}
+
+func (p *BookSimulator) init() {
+	if p.books != nil {
+		return
+	}
+
+	p.event = make(chan EventType)
+	p.books = make(map[string]*simBook)
+	p.views = make(map[string]*simBook)
+	p.trades = make(map[string][]KlineValue)
+	p.tickers = make(map[string]*TickerValue)
+	p.watches = make(map[string]bool)
+	p.orders = make(map[string]*simOrder)
+	if p.Balances == nil {
+		p.Balances = make(map[string]float64)
+	}
+}
+
+// LoadBookEvents loads the recorded events from the file, one json object in each line
+func LoadBookEvents(path string) (error, []BookEvent) {
+	file, err := os.Open(path)
+	if err != nil {
+		return err, nil
+	}
+	defer file.Close()
+
+	var events []BookEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event BookEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return err, nil
+		}
+		events = append(events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err, nil
+	}
+
+	return nil, events
+}
+
+// AddEvents adds the recorded events, which should be called before the replay
+func (p *BookSimulator) AddEvents(events ...BookEvent) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.init()
+	p.events = append(p.events, events...)
+
+	start := p.exchangeIndex
+	if p.marketIndex < start {
+		start = p.marketIndex
+	}
+	pending := p.events[start:]
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Time.Before(pending[j].Time)
+	})
+
+	if p.now.IsZero() && len(p.events) > 0 {
+		p.now = p.events[0].Time
+	}
+}
+
+// Now returns the time of the simulation
+func (p *BookSimulator) Now() time.Time {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.now
+}
+
+// GetFills returns all the fills of the simulated orders
+func (p *BookSimulator) GetFills() []SimulatorFill {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]SimulatorFill(nil), p.fills...)
+}
+
+// Next returns the time of the next step, false if nothing is left
+func (p *BookSimulator) Next() (time.Time, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	next, step := p.next()
+	return next, step != simStepNone
+}
+
+const (
+	simStepNone = iota
+	simStepExchange
+	simStepAction
+	simStepMarket
+)
+
+// next returns the earliest step, the events are applied to the matching engine before the orders at the same time
+func (p *BookSimulator) next() (time.Time, int) {
+	var next time.Time
+	step := simStepNone
+
+	if p.exchangeIndex < len(p.events) {
+		next, step = p.events[p.exchangeIndex].Time, simStepExchange
+	}
+
+	if len(p.actions) > 0 && (step == simStepNone || p.actions[0].time.Before(next)) {
+		next, step = p.actions[0].time, simStepAction
+	}
+
+	if p.marketIndex < len(p.events) {
+		visible := p.events[p.marketIndex].Time.Add(p.MarketLatency)
+		if step == simStepNone || visible.Before(next) {
+			next, step = visible, simStepMarket
+		}
+	}
+
+	return next, step
+}
+
+// Step replays the next event or order action, returns false if nothing is left
+func (p *BookSimulator) Step() bool {
+	p.lock.Lock()
+	p.init()
+
+	next, step := p.next()
+	if step == simStepNone {
+		p.lock.Unlock()
+		return false
+	}
+
+	if next.After(p.now) {
+		p.now = next
+	}
+
+	var notify *BookEvent
+	switch step {
+	case simStepExchange:
+		p.applyExchange(&p.events[p.exchangeIndex])
+		p.exchangeIndex++
+	case simStepAction:
+		action := p.actions[0]
+		p.actions = p.actions[1:]
+		if action.cancel {
+			p.cancel(action.order)
+		} else {
+			p.arrive(action.order)
+		}
+	case simStepMarket:
+		event := p.events[p.marketIndex]
+		p.marketIndex++
+		if p.applyMarket(&event) {
+			notify = &event
+		}
+	}
+
+	ticker := p.config.Ticker
+	p.lock.Unlock()
+
+	// the ticker is notified without the lock, so that it can call the simulator
+	if notify != nil && ticker != nil {
+		ticker.Ticker(NameBookSimulator, notify.Pair, *p.GetTicker(notify.Pair))
+	}
+
+	return true
+}
+
+// RunUntil replays all the steps before the time and moves the clock to it, returns false if nothing is left
+func (p *BookSimulator) RunUntil(t time.Time) bool {
+	for {
+		p.lock.Lock()
+		next, step := p.next()
+		p.lock.Unlock()
+
+		if step == simStepNone || next.After(t) {
+			break
+		}
+		p.Step()
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if t.After(p.now) {
+		p.now = t
+	}
+	_, step := p.next()
+	return step != simStepNone
+}
+
+func (p *BookSimulator) book(books map[string]*simBook, pair string) *simBook {
+	book := books[pair]
+	if book == nil {
+		book = newSimBook()
+		books[pair] = book
+	}
+	return book
+}
+
+func (p *BookSimulator) applyExchange(event *BookEvent) {
+	book := p.book(p.books, event.Pair)
+
+	if event.Type == BookEventTrade {
+		p.matchTrade(event)
+		p.removeFinished()
+		return
+	}
+
+	book.apply(event)
+	for _, order := range p.resting {
+		if order.info.Pair != event.Pair || order.finished() {
+			continue
+		}
+
+		// the orders leaving the level are assumed to be behind us unless the level is smaller than the queue
+		if quantity := book.quantity(order.side, order.info.Price); quantity < order.queue {
+			order.queue = quantity
+		}
+
+		// the opposite side moves through the price, which would have traded with the resting order
+		for _, level := range book.depth(1 - order.side) {
+			if order.remain() <= simEpsilon || !order.crosses(level.Price) {
+				break
+			}
+			amount := math.Min(order.remain(), level.Quantity)
+			book.take(1-order.side, level.Price, amount)
+			p.fill(order, order.info.Price, amount, true)
+		}
+	}
+
+	p.removeFinished()
+}
+
+// matchTrade fills the resting orders when the traded volume exceeds the queue ahead of them. The orders priced through
+// the trade are filled first, the rest of the volume moves the queues at the price of the trade
+func (p *BookSimulator) matchTrade(event *BookEvent) {
+	passive := DepthTypeAsks
+	if event.Side == TradeTypeSell {
+		passive = DepthTypeBids
+	}
+
+	var used float64
+	for _, order := range p.resting {
+		if order.info.Pair != event.Pair || order.side != passive || order.finished() ||
+			order.info.Price == event.Price || !order.crosses(event.Price) {
+			continue
+		}
+
+		// the trade is through the price of the order
+		amount := math.Min(event.Quantity-used, order.remain())
+		if amount > simEpsilon {
+			used += amount
+			p.fill(order, order.info.Price, amount, true)
+		}
+	}
+
+	for _, order := range p.resting {
+		if order.info.Pair != event.Pair || order.side != passive || order.finished() || order.info.Price != event.Price {
+			continue
+		}
+
+		// only the volume left after the orders before are filled moves the queue
+		left := event.Quantity - used
+		amount := math.Min(left-order.queue, order.remain())
+		order.queue = math.Max(order.queue-left, 0)
+		if amount > simEpsilon {
+			used += amount
+			p.fill(order, order.info.Price, amount, true)
+		}
+	}
+}
+
+func (p *BookSimulator) arrive(order *simOrder) {
+	if order.finished() {
+		return
+	}
+
+	book := p.book(p.books, order.info.Pair)
+	opposite := 1 - order.side
+	for _, level := range book.depth(opposite) {
+		if order.remain() <= simEpsilon || !order.crosses(level.Price) {
+			break
+		}
+		amount := math.Min(order.remain(), level.Quantity)
+		book.take(opposite, level.Price, amount)
+		p.fill(order, level.Price, amount, false)
+	}
+
+	if order.finished() {
+		return
+	}
+
+	if order.info.Status == OrderStatusOrdering {
+		order.info.Status = OrderStatusOpen
+	}
+	order.queue = book.quantity(order.side, order.info.Price)
+	p.resting = append(p.resting, order)
+}
+
+func (p *BookSimulator) cancel(order *simOrder) {
+	if order.finished() {
+		return
+	}
+
+	order.info.Status = OrderStatusCanceled
+	p.removeFinished()
+}
+
+func (p *BookSimulator) removeFinished() {
+	var resting []*simOrder
+	for _, order := range p.resting {
+		if !order.finished() {
+			resting = append(resting, order)
+		}
+	}
+	p.resting = resting
+}
+
+func (p *BookSimulator) fill(order *simOrder, price float64, amount float64, maker bool) {
+	deal := order.info.DealAmount + amount
+	order.info.AvgPrice = (order.info.AvgPrice*order.info.DealAmount + price*amount) / deal
+	order.info.DealAmount = deal
+	if order.remain() <= simEpsilon {
+		order.info.Status = OrderStatusDone
+	} else {
+		order.info.Status = OrderStatusPartDone
+	}
+
+	// the balances are counted as the spot trading
+	if coins := ParsePair(order.info.Pair); len(coins) == 2 {
+		if order.side == DepthTypeBids {
+			p.Balances[coins[0]] += amount
+			p.Balances[coins[1]] -= amount * price
+		} else {
+			p.Balances[coins[0]] -= amount
+			p.Balances[coins[1]] += amount * price
+		}
+	}
+
+	p.fills = append(p.fills, SimulatorFill{
+		Time:    p.now,
+		OrderID: order.info.OrderID,
+		Pair:    order.info.Pair,
+		Type:    order.info.Type,
+		Price:   price,
+		Amount:  amount,
+		Maker:   maker,
+	})
+}
+
+// applyMarket updates the book seen by the strategy, returns true if the ticker should be notified
+func (p *BookSimulator) applyMarket(event *BookEvent) bool {
+	if event.Type != BookEventTrade {
+		p.book(p.views, event.Pair).apply(event)
+		return false
+	}
+
+	ticker := p.tickers[event.Pair]
+	if ticker == nil {
+		ticker = &TickerValue{High: event.Price, Low: event.Price}
+		p.tickers[event.Pair] = ticker
+	}
+	ticker.Last = event.Price
+	ticker.High = math.Max(ticker.High, event.Price)
+	ticker.Low = math.Min(ticker.Low, event.Price)
+	ticker.Volume += event.Quantity
+	ticker.Time = event.Time.Format(Global.TimeFormat)
+
+	p.trades[event.Pair] = append(p.trades[event.Pair], KlineValue{
+		OpenTime: float64(event.Time.Unix()),
+		Open:     event.Price,
+		High:     event.Price,
+		Low:      event.Price,
+		Close:    event.Price,
+		Volumn:   event.Quantity,
+	})
+
+	return p.watches[event.Pair]
+}
+
+func (p *BookSimulator) GetExchangeName() string {
+	return NameBookSimulator
+}
+
+// SetConfigure()
+func (p *BookSimulator) SetConfigure(config Config) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.config = config
+}
+
+// WatchEvent() return a channel which notified the application of the event triggered by exchange
+func (p *BookSimulator) WatchEvent() chan EventType {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+	return p.event
+}
+
+// Start() prepare the connection to the exchange
+func (p *BookSimulator) Start() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+	return nil
+}
+
+// Close() close the connection to the exchange and other handles
+func (p *BookSimulator) Close() {
+}
+
+// StartTicker() send message to the exchange to start the ticker of the given pairs
+func (p *BookSimulator) StartTicker(pair string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+	p.watches[pair] = true
+}
+
+// GetTicker(), better to use the ITicker to notify the ticker information
+func (p *BookSimulator) GetTicker(pair string) *TickerValue {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	if ticker := p.tickers[pair]; ticker != nil {
+		value := *ticker
+		return &value
+	}
+	return nil
+}
+
+// GetDepthValue() get the depth seen by the strategy, which is delayed by MarketLatency
+func (p *BookSimulator) GetDepthValue(pair string) [][]DepthPrice {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	book := p.views[pair]
+	if book == nil {
+		return nil
+	}
+
+	list := make([][]DepthPrice, 2)
+	list[DepthTypeBids] = book.depth(DepthTypeBids)
+	list[DepthTypeAsks] = book.depth(DepthTypeAsks)
+	return list
+}
+
+// GetBalance() get the balances of all the coins
+func (p *BookSimulator) GetBalance() map[string]interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	result := make(map[string]interface{})
+	for coin, balance := range p.Balances {
+		result[coin] = map[string]interface{}{
+			"balance": balance,
+		}
+	}
+	return result
+}
+
+// Trade() the limit order arrives at the matching engine after OrderLatency
+func (p *BookSimulator) Trade(configs TradeConfig) *TradeResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	side := orderSide(configs.Type)
+	if side < 0 || configs.Price <= 0 || configs.Amount <= 0 {
+		return &TradeResult{
+			Error: errors.New("Invalid trade config"),
+		}
+	}
+
+	p.orderID++
+	order := &simOrder{
+		info: OrderInfo{
+			Pair:    configs.Pair,
+			OrderID: strconv.Itoa(p.orderID),
+			Price:   configs.Price,
+			Amount:  configs.Amount,
+			Type:    configs.Type,
+			Status:  OrderStatusOrdering,
+		},
+		side: side,
+	}
+	p.orders[order.info.OrderID] = order
+	p.schedule(&simAction{time: p.now.Add(p.OrderLatency), order: order})
+
+	info := order.info
+	return &TradeResult{
+		OrderID: info.OrderID,
+		Info:    &info,
+	}
+}
+
+// CancelOrder() the cancel arrives at the matching engine after OrderLatency
+func (p *BookSimulator) CancelOrder(order OrderInfo) *TradeResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	target := p.orders[order.OrderID]
+	if target == nil || target.finished() {
+		return &TradeResult{
+			Error: errors.New("Invalid order"),
+		}
+	}
+
+	p.schedule(&simAction{time: p.now.Add(p.OrderLatency), order: target, cancel: true})
+
+	info := target.info
+	return &TradeResult{
+		OrderID: info.OrderID,
+		Info:    &info,
+	}
+}
+
+// schedule inserts the action after the ones at the same time
+func (p *BookSimulator) schedule(action *simAction) {
+	index := sort.Search(len(p.actions), func(i int) bool {
+		return p.actions[i].time.After(action.time)
+	})
+	p.actions = append(p.actions, nil)
+	copy(p.actions[index+1:], p.actions[index:])
+	p.actions[index] = action
+}
+
+// GetOrderInfo() get the order by OrderID, or all the unfinished orders of the pair if OrderID is empty
+func (p *BookSimulator) GetOrderInfo(filter OrderInfo) []OrderInfo {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	if filter.OrderID != "" {
+		if order := p.orders[filter.OrderID]; order != nil {
+			return []OrderInfo{order.info}
+		}
+		return nil
+	}
+
+	var result []OrderInfo
+	for id := 1; id <= p.orderID; id++ {
+		order := p.orders[strconv.Itoa(id)]
+		if order.finished() || (filter.Pair != "" && order.info.Pair != filter.Pair) {
+			continue
+		}
+		result = append(result, order.info)
+	}
+	return result
+}
+
+// GetKline() the klines are built from the trades seen by the strategy
+func (p *BookSimulator) GetKline(pair string, period int, limit int) []KlineValue {
+	p.lock.Lock()
+	trades := p.trades[pair]
+	p.lock.Unlock()
+
+	klines := ResampleKlines(trades, period, SessionCrypto)
+	for i := range klines {
+		// the bars of the trades open at the start of the period rather than the first trade
+		start, _ := SessionCrypto.BucketStart(time.Unix(int64(klines[i].OpenTime), 0), period)
+		klines[i].OpenTime = float64(start.Unix())
+	}
+	if limit > 0 && len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	return klines
+}
@@ -0,0 +1,200 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+)
+
+var simStart = time.Date(2018, 3, 5, 0, 0, 0, 0, time.UTC)
+
+func simAt(ms int) time.Time {
+	return simStart.Add(time.Duration(ms) * time.Millisecond)
+}
+
+func simSnapshot() BookEvent {
+	return BookEvent{
+		Type: BookEventSnapshot,
+		Time: simAt(0),
+		Pair: "eth/usdt",
+		Bids: []DepthPrice{{Price: 100, Quantity: 5}, {Price: 99, Quantity: 10}},
+		Asks: []DepthPrice{{Price: 101, Quantity: 2}, {Price: 102, Quantity: 3}},
+	}
+}
+
+func simTrade(ms int, side TradeType, price float64, quantity float64) BookEvent {
+	return BookEvent{
+		Type:     BookEventTrade,
+		Time:     simAt(ms),
+		Pair:     "eth/usdt",
+		Price:    price,
+		Quantity: quantity,
+		Side:     side,
+	}
+}
+
+func checkOrder(t *testing.T, sim *BookSimulator, id string, status OrderStatusType, deal float64) {
+	orders := sim.GetOrderInfo(OrderInfo{OrderID: id})
+	if len(orders) != 1 || orders[0].Status != status || orders[0].DealAmount != deal {
+		t.Errorf("Order:%v Expect status:%v deal:%v", orders, status, deal)
+	}
+}
+
+func TestBookSimulatorQueue(t *testing.T) {
+	sim := new(BookSimulator)
+	sim.AddEvents(simSnapshot(),
+		simTrade(100, TradeTypeSell, 100, 3),
+		simTrade(200, TradeTypeSell, 100, 3),
+	)
+
+	sim.RunUntil(simAt(0))
+	result := sim.Trade(TradeConfig{Pair: "eth/usdt", Type: TradeTypeBuy, Price: 100, Amount: 1})
+	if result.Error != nil {
+		t.Fatalf("Fail to trade:%v", result.Error)
+	}
+
+	// 5 ahead of the order, the first trade only moves the queue
+	sim.RunUntil(simAt(150))
+	checkOrder(t, sim, result.OrderID, OrderStatusOpen, 0)
+
+	sim.RunUntil(simAt(250))
+	checkOrder(t, sim, result.OrderID, OrderStatusDone, 1)
+
+	balances := sim.GetBalance()
+	if balances["eth"].(map[string]interface{})["balance"].(float64) != 1 ||
+		balances["usdt"].(map[string]interface{})["balance"].(float64) != -100 {
+		t.Errorf("Invalid balances:%v", balances)
+	}
+}
+
+func TestBookSimulatorQueueShrink(t *testing.T) {
+	sim := new(BookSimulator)
+	sim.AddEvents(simSnapshot(),
+		BookEvent{Type: BookEventDelta, Time: simAt(100), Pair: "eth/usdt", Bids: []DepthPrice{{Price: 100, Quantity: 1}}},
+		simTrade(200, TradeTypeSell, 100, 1.5),
+	)
+
+	sim.RunUntil(simAt(0))
+	result := sim.Trade(TradeConfig{Pair: "eth/usdt", Type: TradeTypeBuy, Price: 100, Amount: 1})
+
+	// the level is cancelled down to 1, so only 1 is ahead of the order
+	sim.RunUntil(simAt(300))
+	checkOrder(t, sim, result.OrderID, OrderStatusPartDone, 0.5)
+
+	fills := sim.GetFills()
+	if len(fills) != 1 || !fills[0].Maker || fills[0].Price != 100 {
+		t.Errorf("Invalid fills:%v", fills)
+	}
+}
+
+func TestBookSimulatorTradeThrough(t *testing.T) {
+	sim := new(BookSimulator)
+	sim.AddEvents(simSnapshot(),
+		simTrade(100, TradeTypeSell, 100, 1),
+		simTrade(200, TradeTypeSell, 100, 3),
+	)
+
+	sim.RunUntil(simAt(0))
+	first := sim.Trade(TradeConfig{Pair: "eth/usdt", Type: TradeTypeBuy, Price: 100.5, Amount: 2})
+	second := sim.Trade(TradeConfig{Pair: "eth/usdt", Type: TradeTypeBuy, Price: 100.5, Amount: 2})
+
+	// the trade through the orders only fills its own volume
+	sim.RunUntil(simAt(150))
+	checkOrder(t, sim, first.OrderID, OrderStatusPartDone, 1)
+	checkOrder(t, sim, second.OrderID, OrderStatusOpen, 0)
+
+	sim.RunUntil(simAt(250))
+	checkOrder(t, sim, first.OrderID, OrderStatusDone, 2)
+	checkOrder(t, sim, second.OrderID, OrderStatusDone, 2)
+}
+
+func TestBookSimulatorTaker(t *testing.T) {
+	sim := new(BookSimulator)
+	sim.AddEvents(simSnapshot())
+	sim.RunUntil(simAt(0))
+
+	result := sim.Trade(TradeConfig{Pair: "eth/usdt", Type: TradeTypeBuy, Price: 102, Amount: 4})
+	sim.RunUntil(simAt(10))
+	checkOrder(t, sim, result.OrderID, OrderStatusDone, 4)
+
+	orders := sim.GetOrderInfo(OrderInfo{OrderID: result.OrderID})
+	if orders[0].AvgPrice != (101*2+102*2)/4.0 {
+		t.Errorf("Invalid avg price:%v", orders[0].AvgPrice)
+	}
+}
+
+func TestBookSimulatorLatency(t *testing.T) {
+	sim := &BookSimulator{
+		OrderLatency:  100 * time.Millisecond,
+		MarketLatency: 1 * time.Second,
+	}
+	sim.AddEvents(simSnapshot(),
+		// the ask 101 is taken before the order arrives
+		BookEvent{Type: BookEventDelta, Time: simAt(50), Pair: "eth/usdt", Asks: []DepthPrice{{Price: 101, Quantity: 0}}},
+		simTrade(300, TradeTypeSell, 101, 1),
+	)
+
+	sim.RunUntil(simAt(0))
+	if depth := sim.GetDepthValue("eth/usdt"); depth != nil {
+		t.Errorf("The depth should be delayed:%v", depth)
+	}
+
+	result := sim.Trade(TradeConfig{Pair: "eth/usdt", Type: TradeTypeBuy, Price: 101, Amount: 1})
+	checkOrder(t, sim, result.OrderID, OrderStatusOrdering, 0)
+
+	sim.RunUntil(simAt(200))
+	checkOrder(t, sim, result.OrderID, OrderStatusOpen, 0)
+
+	// nobody is ahead of the order at the new level
+	sim.RunUntil(simAt(400))
+	checkOrder(t, sim, result.OrderID, OrderStatusDone, 1)
+
+	sim.RunUntil(simAt(1020))
+	depth := sim.GetDepthValue("eth/usdt")
+	if len(depth[DepthTypeAsks]) != 2 || depth[DepthTypeAsks][0].Price != 101 {
+		t.Errorf("Invalid delayed depth:%v", depth)
+	}
+
+	sim.RunUntil(simAt(2000))
+	depth = sim.GetDepthValue("eth/usdt")
+	if len(depth[DepthTypeAsks]) != 1 || depth[DepthTypeAsks][0].Price != 102 {
+		t.Errorf("Invalid depth:%v", depth)
+	}
+
+	if ticker := sim.GetTicker("eth/usdt"); ticker == nil || ticker.Last != 101 {
+		t.Errorf("Invalid ticker:%v", ticker)
+	}
+}
+
+func TestBookSimulatorCancel(t *testing.T) {
+	sim := &BookSimulator{OrderLatency: 100 * time.Millisecond}
+	sim.AddEvents(simSnapshot(), simTrade(500, TradeTypeSell, 100, 10))
+
+	sim.RunUntil(simAt(0))
+	result := sim.Trade(TradeConfig{Pair: "eth/usdt", Type: TradeTypeBuy, Price: 100, Amount: 1})
+	sim.RunUntil(simAt(200))
+	sim.CancelOrder(OrderInfo{OrderID: result.OrderID})
+
+	for sim.Step() {
+	}
+
+	checkOrder(t, sim, result.OrderID, OrderStatusCanceled, 0)
+	if orders := sim.GetOrderInfo(OrderInfo{Pair: "eth/usdt"}); len(orders) != 0 {
+		t.Errorf("Invalid open orders:%v", orders)
+	}
+}
+
+func TestBookSimulatorKline(t *testing.T) {
+	sim := new(BookSimulator)
+	sim.AddEvents(simSnapshot(),
+		simTrade(61000, TradeTypeSell, 100, 1),
+		simTrade(62000, TradeTypeBuy, 101, 2),
+	)
+	sim.RunUntil(simAt(70000))
+
+	// the bar opens at the start of the period rather than at the first trade
+	klines := sim.GetKline("eth/usdt", KlinePeriod5Min, 0)
+	if len(klines) != 1 || klines[0].OpenTime != float64(simAt(0).Unix()) ||
+		klines[0].Open != 100 || klines[0].Close != 101 || klines[0].Volumn != 3 {
+		t.Errorf("Invalid klines:%v", klines)
+	}
+}
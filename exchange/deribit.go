@@ -848,6 +848,193 @@ func (p *DeribitV2API) GetBalance2(pair string) (error, float64) {
 	}
 }
 
+// deribitCurrencies the currencies of the accounts
+var deribitCurrencies = []string{"BTC", "ETH"}
+
+// call sends the private request and waits for the result
+func (p *DeribitV2API) call(method string, params map[string]interface{}) (error, interface{}) {
+	id := p.commandID
+	p.commandID++
+
+	params["access_token"] = p.accessToken
+	channel := strconv.Itoa(id)
+	response := make(chan interface{})
+	p.channelMap.Store(channel, response)
+
+	if err := p.command(map[string]interface{}{
+		"json":   "2.0",
+		"method": method,
+		"id":     id,
+		"params": params,
+	}); err != nil {
+		p.channelMap.Delete(channel)
+		return err, nil
+	}
+
+	select {
+	case <-time.After(10 * time.Second):
+		return errors.New("Timeout to " + method), nil
+	case result := <-response:
+		rsp := result.(map[string]interface{})
+		if rsp["error"] != nil {
+			return errors.New(rsp["error"].(map[string]interface{})["message"].(string)), nil
+		}
+		return nil, rsp["result"]
+	}
+}
+
+// GetAccount the summaries and the future positions of the BTC and ETH accounts
+func (p *DeribitV2API) GetAccount() (error, *AccountInfo) {
+	summaries := make(map[string]interface{})
+	positions := make(map[string]interface{})
+	for _, currency := range deribitCurrencies {
+		err, summary := p.call("private/get_account_summary", map[string]interface{}{"currency": currency})
+		if err != nil {
+			return err, nil
+		}
+		err, position := p.call("private/get_positions", map[string]interface{}{"currency": currency, "kind": "future"})
+		if err != nil {
+			return err, nil
+		}
+		summaries[currency] = summary
+		positions[currency] = position
+	}
+	return nil, parseDeribitAccount(summaries, positions)
+}
+
+// deribitStopParams the orders of the perpetual contract close the position only
+func deribitStopParams(config StopOrderConfig) (string, map[string]interface{}) {
+	method := "private/buy"
+	if stopSell(config.Type) {
+		method = "private/sell"
+	}
+	params := map[string]interface{}{
+		"instrument_name": strings.ToUpper(ParsePair(config.Pair)[0]) + "-PERPETUAL",
+		"amount":          config.Amount,
+		"reduce_only":     true,
+		"trigger":         "last_price",
+	}
+
+	switch config.StopType {
+	case StopOrderLoss:
+		params["type"] = "stop_market"
+		params["trigger_price"] = config.Trigger
+	case StopOrderProfit:
+		params["type"] = "take_market"
+		params["trigger_price"] = config.Trigger
+	case StopOrderTrailing:
+		params["type"] = "trailing_stop"
+		params["trigger_offset"] = stopDistance(config)
+	}
+	return method, params
+}
+
+func parseDeribitStopOrder(result interface{}) (error, string) {
+	values, _ := result.(map[string]interface{})
+	order, _ := values["order"].(map[string]interface{})
+	orderID, _ := order["order_id"].(string)
+	if orderID == "" {
+		return errors.New("Invalid response"), ""
+	}
+	return nil, orderID
+}
+
+// PlaceStopOrder the conditional order is triggered by the last price
+func (p *DeribitV2API) PlaceStopOrder(config StopOrderConfig) (error, string) {
+	method, params := deribitStopParams(config)
+	err, result := p.call(method, params)
+	if err != nil {
+		return err, ""
+	}
+	return parseDeribitStopOrder(result)
+}
+
+func (p *DeribitV2API) CancelStopOrder(pair string, orderID string) error {
+	err, _ := p.call("private/cancel", map[string]interface{}{"order_id": orderID})
+	return err
+}
+
+var deribitOrderStatus = map[string]OrderStatusType{
+	"untriggered": OrderStatusOpen,
+	"triggered":   OrderStatusOpen,
+	"open":        OrderStatusOpen,
+	"filled":      OrderStatusDone,
+	"cancelled":   OrderStatusCanceled,
+	"rejected":    OrderStatusRejected,
+}
+
+func parseDeribitStopStatus(result interface{}) (error, *OrderInfo) {
+	order, ok := result.(map[string]interface{})
+	if !ok {
+		return errors.New("Invalid response"), nil
+	}
+	state, _ := order["order_state"].(string)
+	status, ok := deribitOrderStatus[state]
+	if !ok {
+		status = OrderStatusUnknown
+	}
+	info := &OrderInfo{
+		Status:     status,
+		Amount:     accountFloat(order["amount"]),
+		DealAmount: accountFloat(order["filled_amount"]),
+		AvgPrice:   accountFloat(order["average_price"]),
+	}
+	info.OrderID, _ = order["order_id"].(string)
+	if info.Status == OrderStatusOpen && info.DealAmount > 0 {
+		info.Status = OrderStatusPartDone
+	}
+	return nil, info
+}
+
+// GetStopOrder the order is untriggered before the price reaches the trigger
+func (p *DeribitV2API) GetStopOrder(pair string, orderID string) (error, *OrderInfo) {
+	err, result := p.call("private/get_order_state", map[string]interface{}{"order_id": orderID})
+	if err != nil {
+		return err, nil
+	}
+	return parseDeribitStopStatus(result)
+}
+
+// parseDeribitAccount the sizes of the futures are in USD and negative for the short positions
+func parseDeribitAccount(summaries map[string]interface{}, positions map[string]interface{}) *AccountInfo {
+	account := &AccountInfo{}
+	for currency, value := range summaries {
+		summary, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		equity := accountFloat(summary["equity"])
+		account.addBalance(currency, equity)
+		if equity > 0 {
+			account.setMarginRatio(accountFloat(summary["maintenance_margin"]) / equity)
+		}
+	}
+
+	for _, value := range positions {
+		list, _ := value.([]interface{})
+		for _, item := range list {
+			position, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			instrument, _ := position["instrument_name"].(string)
+			size := accountFloat(position["size"])
+			mark := accountFloat(position["mark_price"])
+			if size == 0 || mark <= 0 {
+				continue
+			}
+			account.Positions = append(account.Positions, AccountPosition{
+				Pair:             strings.ToLower(strings.Split(instrument, "-")[0]) + "/usd",
+				Quantity:         size / mark,
+				Amount:           size,
+				MarkPrice:        mark,
+				LiquidationPrice: accountFloat(position["estimated_liquidation_price"]),
+			})
+		}
+	}
+	return account
+}
+
 func (o *DeribitV2API) getTradeTypeByString(orderType string) TradeType {
 	switch orderType {
 	case "1":
@@ -497,165 +497,19 @@ func GetThreshHoldByAverage(avg1first float64, avg1 float64, interval1 float64,
 	return (interval1*interval2*avg2 - interval1*avg2first - interval1*interval2*avg1 + interval2*avg1first) / (interval2 - interval1)
 }
 
+// CTPDailyKlinesToWeek Deprecated: use ResampleKlines with SessionCTP. The weeks are labeled by their last day as before
 func CTPDailyKlinesToWeek(klines []KlineValue) []KlineValue {
-	var KlinesByWeek []KlineValue
-
-	var high, low, open, close, volumn float64
-	var klineTime time.Time
-
-	location, _ := time.LoadLocation("Asia/Shanghai")
-
-	for _, kline := range klines {
-
-		klineTime, _ = time.ParseInLocation("2006-01-02", kline.Time, location)
-		if open == 0 {
-			open = kline.Open
-		}
-		// log.Printf("Time:%v", klineTime)
-		if high == 0 || high < kline.High {
-			high = kline.High
-		}
-
-		if low == 0 || low > kline.Low {
-			low = kline.Low
-		}
-
-		close = kline.Close
-		volumn += kline.Volumn
-
-		if klineTime.Weekday() == time.Friday {
-			if high != 0 && low != 0 && close != 0 {
-				lastKline := KlineValue{
-					High:   high,
-					Low:    low,
-					Open:   open,
-					Close:  close,
-					Time:   klineTime.Format("2006-01-02"),
-					Volumn: volumn,
-				}
-				KlinesByWeek = append(KlinesByWeek, lastKline)
-			}
-			open = 0
-			high = 0
-			low = 0
-			close = 0
-			volumn = 0
-		}
-
-	}
-
-	if high != 0 && low != 0 && close != 0 {
-		lastKline := KlineValue{
-			High:   high,
-			Low:    low,
-			Open:   open,
-			Close:  close,
-			Time:   klineTime.Format("2006-01-02"),
-			Volumn: volumn,
-		}
-		KlinesByWeek = append(KlinesByWeek, lastKline)
-	}
-
-	return KlinesByWeek
+	return resampleByLastDay(klines, KlinePeriod1Week, SessionCTP)
 }
 
+// CTPDailyKlinesToMonth Deprecated: use ResampleKlines with SessionCTP. The months are labeled by their last day as before
 func CTPDailyKlinesToMonth(klines []KlineValue) []KlineValue {
-	var KlinesByMonth []KlineValue
-
-	var high, low, open, close float64
-	var klineTime time.Time
-	var currentMonth time.Month
-
-	location, _ := time.LoadLocation("Asia/Shanghai")
-	first, _ := time.ParseInLocation("2006-01-02", klines[0].Time, location)
-	currentMonth = first.Month()
-
-	for i, kline := range klines {
-
-		klineTime, _ = time.ParseInLocation("2006-01-02", kline.Time, location)
-		if open == 0 {
-			open = kline.Open
-		}
-		// log.Printf("Time:%v", klineTime)
-		if high == 0 || high < kline.High {
-			high = kline.High
-		}
-
-		if low == 0 || low > kline.Low {
-			low = kline.Low
-		}
-
-		close = kline.Close
-
-		if i+1 < len(klines) {
-			nextTime, _ := time.ParseInLocation("2006-01-02", klines[i+1].Time, location)
-			if nextTime.Month() != currentMonth {
-				if high != 0 && low != 0 && close != 0 {
-					lastKline := KlineValue{
-						High:  high,
-						Low:   low,
-						Open:  open,
-						Close: close,
-						Time:  klineTime.Format("2006-01-02"),
-					}
-					KlinesByMonth = append(KlinesByMonth, lastKline)
-				}
-				currentMonth = nextTime.Month()
-				open = 0
-				high = 0
-				low = 0
-				close = 0
-			}
-		} else {
-			if high != 0 && low != 0 && close != 0 {
-				lastKline := KlineValue{
-					High:  high,
-					Low:   low,
-					Open:  open,
-					Close: close,
-					Time:  klineTime.Format("2006-01-02"),
-				}
-				KlinesByMonth = append(KlinesByMonth, lastKline)
-			}
-		}
-
-	}
-
-	return KlinesByMonth
-
+	return resampleByLastDay(klines, KlinePeriod1Month, SessionCTP)
 }
 
+// CTPDailyKlinesSplitToYears Deprecated: use SplitKlines with SessionCTP
 func CTPDailyKlinesSplitToYears(klines []KlineValue) [][]KlineValue {
-	var KlinesByYear [][]KlineValue
-	var tmp []KlineValue
-	var currentYear int
-
-	location, _ := time.LoadLocation("Asia/Shanghai")
-	first, _ := time.ParseInLocation("2006-01-02", klines[0].Time, location)
-	currentYear = first.Year()
-
-	for i, kline := range klines {
-		tmp = append(tmp, kline)
-		if i+1 < len(klines) {
-			nextTime, _ := time.ParseInLocation("2006-01-02", klines[i+1].Time, location)
-			if nextTime.Year() != currentYear {
-				if tmp != nil && len(tmp) > 0 {
-					KlinesByYear = append(KlinesByYear, tmp)
-				}
-				currentYear = nextTime.Year()
-				tmp = nil
-			}
-		} else {
-			if tmp != nil && len(tmp) > 0 {
-				KlinesByYear = append(KlinesByYear, tmp)
-			}
-
-		}
-
-	}
-
-	return KlinesByYear
-
+	return SplitKlines(klines, KlinePeriod1Year, SessionCTP)
 }
 
 func KlinesFilter(klines []KlineValue, amp float64, showMax bool) []KlineValue {
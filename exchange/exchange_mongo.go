@@ -2,6 +2,9 @@ package exchange
 
 import (
 	"errors"
+	"time"
+
+	Global "madaoQT/config"
 	Mongo "madaoQT/mongo"
 	Utils "madaoQT/utils"
 )
@@ -14,6 +17,11 @@ type ExchangeInfo struct {
 }
 
 func GetExchangeKey(mongo *Mongo.ExchangeDB, exchange string, key []byte, nonce []byte) (error, *ExchangeInfo) {
+	return GetAccountKey(mongo, exchange, "", key, nonce)
+}
+
+// GetAccountKey the keys of the labeled account, the empty label is the default account
+func GetAccountKey(mongo *Mongo.ExchangeDB, exchange string, account string, key []byte, nonce []byte) (error, *ExchangeInfo) {
 
 	if mongo == nil {
 		return errors.New("Invalid mongo handler"), nil
@@ -25,7 +33,7 @@ func GetExchangeKey(mongo *Mongo.ExchangeDB, exchange string, key []byte, nonce
 
 	defer mongo.Close()
 
-	err, record := mongo.FindOne(exchange)
+	err, record := mongo.FindAccount(exchange, account)
 	if err != nil {
 		return errors.New("APIKEY not found"), nil
 	}
@@ -53,6 +61,11 @@ func GetExchangeKey(mongo *Mongo.ExchangeDB, exchange string, key []byte, nonce
 }
 
 func AddExchangeKey(mongo *Mongo.ExchangeDB, name string, api string, secret string) error {
+	return AddAccountKey(mongo, name, "", api, secret)
+}
+
+// AddAccountKey the keys of the account are replaced if they exist
+func AddAccountKey(mongo *Mongo.ExchangeDB, name string, account string, api string, secret string) error {
 
 	if mongo == nil {
 		return errors.New("Invalid mongo handler")
@@ -78,10 +91,137 @@ func AddExchangeKey(mongo *Mongo.ExchangeDB, name string, api string, secret str
 		return err
 	}
 
-	return mongo.Insert(&Mongo.ExchangeInfo{
-		Name:   name,
-		API:    encryptedAPI,
-		Secret: encryptedSecret,
+	return mongo.Upsert(&Mongo.ExchangeInfo{
+		Name:    name,
+		Account: account,
+		API:     encryptedAPI,
+		Secret:  encryptedSecret,
 	})
 
 }
+
+// KlineOpenTime the open time is in seconds, or parsed from the time string
+func KlineOpenTime(kline KlineValue) time.Time {
+	if kline.OpenTime != 0 {
+		return time.Unix(int64(kline.OpenTime), 0)
+	}
+
+	for _, layout := range []string{Global.TimeFormat, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, kline.Time, time.Local); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// KlineToRecord converts the kline into the record saved in the kline collection
+func KlineToRecord(exchange string, pair string, period int, kline KlineValue) Mongo.KlineRecord {
+	record := Mongo.KlineRecord{
+		Exchange: exchange,
+		Symbol:   pair,
+		Period:   period,
+		OpenTime: KlineOpenTime(kline),
+		Open:     kline.Open,
+		High:     kline.High,
+		Low:      kline.Low,
+		Close:    kline.Close,
+		Volume:   kline.Volumn,
+	}
+
+	if kline.CloseTime != 0 {
+		record.CloseTime = time.Unix(int64(kline.CloseTime), 0)
+	}
+
+	return record
+}
+
+// RecordToKline converts the record of the kline collection into the kline
+func RecordToKline(record Mongo.KlineRecord) KlineValue {
+	kline := KlineValue{
+		Time:     record.OpenTime.Format(Global.TimeFormat),
+		OpenTime: float64(record.OpenTime.Unix()),
+		Open:     record.Open,
+		High:     record.High,
+		Low:      record.Low,
+		Close:    record.Close,
+		Volumn:   record.Volume,
+	}
+
+	if !record.CloseTime.IsZero() {
+		kline.CloseTime = float64(record.CloseTime.Unix())
+	}
+
+	return kline
+}
+
+// SaveKlines upserts the klines of the pair into the kline collection
+func SaveKlines(mongo *Mongo.Klines, exchange string, pair string, period int, klines []KlineValue) error {
+
+	if mongo == nil {
+		return errors.New("Invalid mongo handler")
+	}
+
+	records := make([]Mongo.KlineRecord, 0, len(klines))
+	for _, kline := range klines {
+		record := KlineToRecord(exchange, pair, period, kline)
+		if record.OpenTime.IsZero() {
+			logger.Errorf("Invalid kline time:%v", kline)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return mongo.Upsert(records)
+}
+
+// ImportHistory saves the klines of the history file, which is saved by SaveHistoryWithSub(), into the kline collection
+func ImportHistory(mongo *Mongo.Klines, subdir string, code string, exchange string, pair string, period int) error {
+
+	klines := LoadHistoryWithSub(subdir, code)
+	if len(klines) == 0 {
+		return errors.New("No klines in the history " + code)
+	}
+
+	return SaveKlines(mongo, exchange, pair, period, klines)
+}
+
+// LoadKlines loads the latest count klines of the pair from the kline collection
+func LoadKlines(mongo *Mongo.Klines, exchange string, pair string, period int, count int) (error, []KlineValue) {
+
+	if mongo == nil {
+		return errors.New("Invalid mongo handler"), nil
+	}
+
+	records, err := mongo.FindLatest(exchange, pair, period, count)
+	if err != nil {
+		return err, nil
+	}
+
+	klines := make([]KlineValue, len(records))
+	for i, record := range records {
+		klines[i] = RecordToKline(record)
+	}
+
+	return nil, klines
+}
+
+// LoadKlinesByRange loads the klines of the pair whose open time is in [start, end)
+func LoadKlinesByRange(mongo *Mongo.Klines, exchange string, pair string, period int, start time.Time, end time.Time) (error, []KlineValue) {
+
+	if mongo == nil {
+		return errors.New("Invalid mongo handler"), nil
+	}
+
+	records, err := mongo.FindRange(exchange, pair, period, start, end)
+	if err != nil {
+		return err, nil
+	}
+
+	klines := make([]KlineValue, len(records))
+	for i, record := range records {
+		klines[i] = RecordToKline(record)
+	}
+
+	return nil, klines
+}
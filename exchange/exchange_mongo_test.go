@@ -0,0 +1,35 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKlineRecordConvert(t *testing.T) {
+	openTime := time.Date(2018, 3, 5, 8, 0, 0, 0, time.UTC)
+	kline := KlineValue{
+		OpenTime:  float64(openTime.Unix()),
+		CloseTime: float64(openTime.Add(time.Hour).Unix()),
+		Open:      1,
+		High:      2,
+		Low:       0.5,
+		Close:     1.5,
+		Volumn:    100,
+	}
+
+	record := KlineToRecord(NameBinance, "eth/usdt", KlinePeriod1Hour, kline)
+	if !record.OpenTime.Equal(openTime) || record.Volume != 100 || record.Period != KlinePeriod1Hour {
+		t.Errorf("Invalid record:%v", record)
+	}
+
+	result := RecordToKline(record)
+	if result.OpenTime != kline.OpenTime || result.CloseTime != kline.CloseTime || result.Close != kline.Close || result.Volumn != kline.Volumn {
+		t.Errorf("Invalid kline:%v", result)
+	}
+
+	// CTP daily klines only have the date
+	record = KlineToRecord("ctp", "RB0", KlinePeriod1Day, KlineValue{Time: "2018-03-05"})
+	if record.OpenTime.IsZero() {
+		t.Errorf("Fail to parse the time of the kline")
+	}
+}
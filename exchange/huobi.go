@@ -633,6 +633,136 @@ func (p *Huobi) GetBalance() map[string]interface{} {
 	return nil
 }
 
+// GetAccount the contract account reports the positions and the margin, the spot account reports the balances only
+func (p *Huobi) GetAccount() (error, *AccountInfo) {
+	if p.InstrumentType == InstrumentTypeSpot {
+		balance := p.GetBalance()
+		if balance == nil {
+			return errors.New("Fail to get balance"), nil
+		}
+		account := &AccountInfo{}
+		for key, value := range balance {
+			account.addBalance(key, accountFloat(value))
+		}
+		return nil, account
+	}
+
+	err, accounts := p.orderRequest("POST", "/api/v1/contract_account_info", map[string]string{})
+	if err != nil {
+		return err, nil
+	}
+	err, positions := p.orderRequest("POST", "/api/v1/contract_position_info", map[string]string{})
+	if err != nil {
+		return err, nil
+	}
+	return parseHuobiContractAccount(accounts, positions)
+}
+
+func huobiTransferParams(config TransferConfig) map[string]string {
+	transferType := "master-transfer-out"
+	if config.ToMaster {
+		transferType = "master-transfer-in"
+	}
+	return map[string]string{
+		"sub-uid":  config.SubAccount,
+		"currency": strings.ToLower(config.Coin),
+		"amount":   formatTransferAmount(config.Amount),
+		"type":     transferType,
+	}
+}
+
+func parseHuobiTransfer(response map[string]interface{}) (error, string) {
+	if err := huobiError(response); err != nil {
+		return err, ""
+	}
+	switch id := response["data"].(type) {
+	case float64:
+		return nil, strconv.FormatFloat(id, 'f', 0, 64)
+	case string:
+		return nil, id
+	}
+	return nil, ""
+}
+
+// SubAccountTransfer only the spot account of the master account transfers to the sub-accounts
+func (p *Huobi) SubAccountTransfer(config TransferConfig) (error, string) {
+	if p.InstrumentType != InstrumentTypeSpot {
+		return errors.New("The transfers of the sub-accounts are only supported by the spot"), ""
+	}
+	err, response := p.orderRequest("POST", "/v1/subuser/transfer", huobiTransferParams(config))
+	if err != nil {
+		return err, ""
+	}
+	return parseHuobiTransfer(response)
+}
+
+func huobiError(response map[string]interface{}) error {
+	if response["status"] == "ok" {
+		return nil
+	}
+	if message, ok := response["err_msg"].(string); ok {
+		return errors.New(message)
+	}
+	return errors.New("Invalid response")
+}
+
+// parseHuobiContractAccount the risk rate is the equity divided by the position margin, the liquidation price is
+// reported by the account of the coin
+func parseHuobiContractAccount(accounts map[string]interface{}, positions map[string]interface{}) (error, *AccountInfo) {
+	if err := huobiError(accounts); err != nil {
+		return err, nil
+	}
+	if err := huobiError(positions); err != nil {
+		return err, nil
+	}
+
+	account := &AccountInfo{}
+	liquidation := make(map[string]float64)
+	list, _ := accounts["data"].([]interface{})
+	for _, item := range list {
+		values, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		symbol, _ := values["symbol"].(string)
+		symbol = strings.ToLower(symbol)
+		account.addBalance(symbol, accountFloat(values["margin_balance"]))
+		if rate := accountFloat(values["risk_rate"]); rate > 0 {
+			account.setMarginRatio(1 / rate)
+		}
+		liquidation[symbol] = accountFloat(values["liquidation_price"])
+	}
+
+	list, _ = positions["data"].([]interface{})
+	for _, item := range list {
+		values, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		symbol, _ := values["symbol"].(string)
+		symbol = strings.ToLower(symbol)
+		volume := accountFloat(values["volume"])
+		last := accountFloat(values["last_price"])
+		if volume == 0 || last <= 0 {
+			continue
+		}
+
+		quantity := volume * InverseContractValue(symbol) / last
+		if values["direction"] == "sell" {
+			quantity = -quantity
+			volume = -volume
+		}
+		account.Positions = append(account.Positions, AccountPosition{
+			Pair:             symbol + "/usd",
+			Quantity:         quantity,
+			Amount:           volume,
+			MarkPrice:        last,
+			LiquidationPrice: liquidation[symbol],
+		})
+	}
+	return nil, account
+}
+
 func (p *Huobi) getTradeType(tradeType TradeType) (string, string) {
 	switch tradeType {
 	case TradeTypeOpenLong:
@@ -0,0 +1,177 @@
+package indicator
+
+/*
+	流式技术指标，每根K线收盘后调用Update更新指标
+*/
+
+import (
+	"math"
+
+	Exchange "madaoQT/exchange"
+)
+
+// IIndicator the interface of the streaming indicators, the same as SchaffTrend
+type IIndicator interface {
+	// Update() update the indicator with the closed kline
+	Update(kline Exchange.KlineValue)
+	// Ready() whether the indicator has received enough klines to give the value
+	Ready() bool
+}
+
+// window keeps the latest values of the fixed size
+type window struct {
+	size   int
+	values []float64
+	sum    float64
+}
+
+func (w *window) push(value float64) {
+	w.values = append(w.values, value)
+	w.sum += value
+	if len(w.values) > w.size {
+		w.sum -= w.values[0]
+		w.values = w.values[1:]
+	}
+}
+
+func (w *window) full() bool {
+	return w.size > 0 && len(w.values) == w.size
+}
+
+func (w *window) mean() float64 {
+	if len(w.values) == 0 {
+		return 0
+	}
+	return w.sum / float64(len(w.values))
+}
+
+// stddev the population standard deviation
+func (w *window) stddev() float64 {
+	if len(w.values) == 0 {
+		return 0
+	}
+	mean := w.mean()
+	var total float64
+	for _, value := range w.values {
+		total += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(total / float64(len(w.values)))
+}
+
+func (w *window) highest() float64 {
+	highest := w.values[0]
+	for _, value := range w.values {
+		if value > highest {
+			highest = value
+		}
+	}
+	return highest
+}
+
+func (w *window) lowest() float64 {
+	lowest := w.values[0]
+	for _, value := range w.values {
+		if value < lowest {
+			lowest = value
+		}
+	}
+	return lowest
+}
+
+// SMA simple moving average of the close price
+type SMA struct {
+	Period int
+
+	window window
+}
+
+func (p *SMA) Update(kline Exchange.KlineValue) {
+	p.Add(kline.Close)
+}
+
+// Add() update the average with the value
+func (p *SMA) Add(value float64) float64 {
+	p.window.size = p.Period
+	p.window.push(value)
+	return p.Value()
+}
+
+func (p *SMA) Ready() bool {
+	return p.window.full()
+}
+
+func (p *SMA) Value() float64 {
+	if !p.Ready() {
+		return 0
+	}
+	return p.window.mean()
+}
+
+// EMA exponential moving average of the close price, seeded by the SMA of the first Period values
+type EMA struct {
+	Period int
+
+	count int
+	sum   float64
+	value float64
+}
+
+func (p *EMA) Update(kline Exchange.KlineValue) {
+	p.Add(kline.Close)
+}
+
+// Add() update the average with the value
+func (p *EMA) Add(value float64) float64 {
+	p.count++
+	if p.count < p.Period {
+		p.sum += value
+		return 0
+	} else if p.count == p.Period {
+		p.sum += value
+		p.value = p.sum / float64(p.Period)
+		return p.value
+	}
+
+	alpha := 2.0 / float64(p.Period+1)
+	p.value = alpha*(value-p.value) + p.value
+	return p.value
+}
+
+func (p *EMA) Ready() bool {
+	return p.Period > 0 && p.count >= p.Period
+}
+
+func (p *EMA) Value() float64 {
+	if !p.Ready() {
+		return 0
+	}
+	return p.value
+}
+
+// wilder the smoothing used by RSI, ATR and ADX, seeded by the average of the first Period values
+type wilder struct {
+	period int
+	count  int
+	value  float64
+}
+
+func (w *wilder) add(value float64) {
+	w.count++
+	if w.count <= w.period {
+		w.value += (value - w.value) / float64(w.count)
+		return
+	}
+	w.value = (w.value*float64(w.period-1) + value) / float64(w.period)
+}
+
+func (w *wilder) ready() bool {
+	return w.period > 0 && w.count >= w.period
+}
+
+// trueRange the true range of the kline, which is High-Low for the first kline
+func trueRange(kline Exchange.KlineValue, last *Exchange.KlineValue) float64 {
+	if last == nil {
+		return kline.High - kline.Low
+	}
+	return math.Max(kline.High-kline.Low, math.Max(math.Abs(kline.High-last.Close), math.Abs(kline.Low-last.Close)))
+}
@@ -0,0 +1,149 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+)
+
+// the closes are the RSI example of Wilder, the reference values are calculated by the batch formulas
+var testCloses = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+	46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+}
+
+func makeKlines() []Exchange.KlineValue {
+	var klines []Exchange.KlineValue
+	for i, close := range testCloses {
+		klines = append(klines, Exchange.KlineValue{
+			Close:  close,
+			High:   close + 0.3 + 0.05*float64(i%4),
+			Low:    close - 0.25 - 0.04*float64(i%3),
+			Volumn: float64(100 + 10*(i%5)),
+		})
+	}
+	return klines
+}
+
+func feed(indicator IIndicator, klines []Exchange.KlineValue) {
+	for _, kline := range klines {
+		indicator.Update(kline)
+	}
+}
+
+func checkValue(t *testing.T, name string, value float64, expect float64) {
+	if math.Abs(value-expect) > 1e-9 {
+		t.Errorf("%s:%v Expect:%v", name, value, expect)
+	}
+}
+
+func TestSMAEMA(t *testing.T) {
+	sma := &SMA{Period: 5}
+	ema := &EMA{Period: 10}
+	feed(sma, makeKlines())
+	feed(ema, makeKlines())
+	checkValue(t, "SMA", sma.Value(), 44.47)
+	checkValue(t, "EMA", ema.Value(), 44.999460890617605)
+}
+
+func TestMACD(t *testing.T) {
+	macd := &MACD{FastPeriod: 5, SlowPeriod: 10, SignalPeriod: 4}
+	feed(macd, makeKlines())
+	value, signal, histogram := macd.Value()
+	checkValue(t, "MACD", value, -0.3758210507171569)
+	checkValue(t, "Signal", signal, -0.3434125495074125)
+	checkValue(t, "Histogram", histogram, -0.03240850120974437)
+}
+
+func TestRSI(t *testing.T) {
+	rsi := &RSI{Period: 14}
+	klines := makeKlines()
+	feed(rsi, klines[:14])
+	if rsi.Ready() {
+		t.Errorf("RSI should not be ready")
+	}
+	rsi.Update(klines[14])
+	checkValue(t, "RSI", rsi.Value(), 70.46413502109705)
+	feed(rsi, klines[15:])
+	checkValue(t, "RSI", rsi.Value(), 45.499497238680405)
+}
+
+func TestVolatility(t *testing.T) {
+	atr := &ATR{Period: 14}
+	feed(atr, makeKlines())
+	checkValue(t, "ATR", atr.Value(), 0.8072637399560174)
+
+	bollinger := &Bollinger{Period: 20, Factor: 2}
+	feed(bollinger, makeKlines())
+	upper, middle, lower := bollinger.Value()
+	checkValue(t, "Bollinger upper", upper, 47.179275927681964)
+	checkValue(t, "Bollinger middle", middle, 45.657)
+	checkValue(t, "Bollinger lower", lower, 44.13472407231803)
+
+	donchian := &Donchian{Period: 10}
+	feed(donchian, makeKlines())
+	upper, middle, lower = donchian.Value()
+	checkValue(t, "Donchian upper", upper, 46.9)
+	checkValue(t, "Donchian middle", middle, 45.3)
+	checkValue(t, "Donchian lower", lower, 43.7)
+
+	keltner := &Keltner{Period: 10, ATRPeriod: 10, Factor: 2}
+	feed(keltner, makeKlines())
+	upper, middle, lower = keltner.Value()
+	checkValue(t, "Keltner upper", upper, 46.64316877527528)
+	checkValue(t, "Keltner middle", middle, 44.999460890617605)
+	checkValue(t, "Keltner lower", lower, 43.35575300595993)
+}
+
+func TestADX(t *testing.T) {
+	adx := &ADX{Period: 7}
+	feed(adx, makeKlines())
+	value, plusDI, minusDI := adx.Value()
+	checkValue(t, "ADX", value, 27.57370738561298)
+	checkValue(t, "+DI", plusDI, 21.007290933944972)
+	checkValue(t, "-DI", minusDI, 31.198559384030823)
+}
+
+func TestStochastic(t *testing.T) {
+	stochastic := &Stochastic{KPeriod: 14, KSmooth: 3, DPeriod: 3}
+	feed(stochastic, makeKlines())
+	k, d := stochastic.Value()
+	checkValue(t, "%K", k, 19.47916666666659)
+	checkValue(t, "%D", d, 15.674818840579647)
+}
+
+func TestVolume(t *testing.T) {
+	vwap := &VWAP{}
+	obv := &OBV{}
+	feed(vwap, makeKlines())
+	feed(obv, makeKlines())
+	checkValue(t, "VWAP", vwap.Value(), 45.39491666666668)
+	checkValue(t, "OBV", obv.Value(), 760)
+}
+
+func TestVWAPSession(t *testing.T) {
+	vwap := &VWAP{Session: Exchange.SessionCrypto}
+	start := time.Date(2018, 3, 5, 22, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		price := float64(10 + i)
+		vwap.Update(Exchange.KlineValue{
+			OpenTime: float64(start.Add(time.Duration(i) * time.Hour).Unix()),
+			High:     price,
+			Low:      price,
+			Close:    price,
+			Volumn:   float64(i + 1),
+		})
+	}
+	// restarted at 00:00, only 12*3 and 13*4 are counted
+	checkValue(t, "VWAP", vwap.Value(), (12.0*3+13*4)/7)
+}
+
+func TestSchaffTrendIndicator(t *testing.T) {
+	var indicator IIndicator = &Exchange.SchaffTrend{}
+	if indicator.Ready() {
+		t.Errorf("SchaffTrend should not be ready")
+	}
+}
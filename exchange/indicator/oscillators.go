@@ -0,0 +1,139 @@
+package indicator
+
+import (
+	Exchange "madaoQT/exchange"
+)
+
+// MACD the difference of the fast and slow EMA, with the EMA of the difference as the signal line
+type MACD struct {
+	FastPeriod   int
+	SlowPeriod   int
+	SignalPeriod int
+
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	macd   float64
+}
+
+func (p *MACD) Update(kline Exchange.KlineValue) {
+	if p.fast == nil {
+		p.fast = &EMA{Period: p.FastPeriod}
+		p.slow = &EMA{Period: p.SlowPeriod}
+		p.signal = &EMA{Period: p.SignalPeriod}
+	}
+
+	p.fast.Add(kline.Close)
+	p.slow.Add(kline.Close)
+	if p.fast.Ready() && p.slow.Ready() {
+		p.macd = p.fast.Value() - p.slow.Value()
+		p.signal.Add(p.macd)
+	}
+}
+
+func (p *MACD) Ready() bool {
+	return p.signal != nil && p.signal.Ready()
+}
+
+// Value() returns the macd, signal and histogram
+func (p *MACD) Value() (float64, float64, float64) {
+	if !p.Ready() {
+		return 0, 0, 0
+	}
+	return p.macd, p.signal.Value(), p.macd - p.signal.Value()
+}
+
+// RSI relative strength index with the Wilder smoothing
+type RSI struct {
+	Period int
+
+	gain  *wilder
+	loss  *wilder
+	last  float64
+	count int
+}
+
+func (p *RSI) Update(kline Exchange.KlineValue) {
+	if p.gain == nil {
+		p.gain = &wilder{period: p.Period}
+		p.loss = &wilder{period: p.Period}
+	}
+
+	p.count++
+	if p.count > 1 {
+		change := kline.Close - p.last
+		if change > 0 {
+			p.gain.add(change)
+			p.loss.add(0)
+		} else {
+			p.gain.add(0)
+			p.loss.add(-change)
+		}
+	}
+	p.last = kline.Close
+}
+
+func (p *RSI) Ready() bool {
+	return p.gain != nil && p.gain.ready()
+}
+
+func (p *RSI) Value() float64 {
+	if !p.Ready() {
+		return 0
+	}
+	if p.loss.value == 0 {
+		return 100
+	}
+	return 100 - 100/(1+p.gain.value/p.loss.value)
+}
+
+// Stochastic the slow stochastic oscillator, %K is smoothed by the SMA of KSmooth and %D is the SMA of %K
+type Stochastic struct {
+	KPeriod int
+	KSmooth int
+	DPeriod int
+
+	highs window
+	lows  window
+	k     *SMA
+	d     *SMA
+}
+
+func (p *Stochastic) Update(kline Exchange.KlineValue) {
+	if p.k == nil {
+		p.highs.size = p.KPeriod
+		p.lows.size = p.KPeriod
+		p.k = &SMA{Period: p.KSmooth}
+		p.d = &SMA{Period: p.DPeriod}
+	}
+
+	p.highs.push(kline.High)
+	p.lows.push(kline.Low)
+	if !p.highs.full() {
+		return
+	}
+
+	var raw float64
+	highest, lowest := p.highs.highest(), p.lows.lowest()
+	if highest > lowest {
+		raw = (kline.Close - lowest) * 100 / (highest - lowest)
+	} else {
+		raw = 50
+	}
+
+	if p.k.Add(raw); p.k.Ready() {
+		p.d.Add(p.k.Value())
+	}
+}
+
+func (p *Stochastic) Ready() bool {
+	return p.d != nil && p.d.Ready()
+}
+
+// Value() returns %K and %D
+func (p *Stochastic) Value() (float64, float64) {
+	if !p.Ready() {
+		return 0, 0
+	}
+	return p.k.Value(), p.d.Value()
+}
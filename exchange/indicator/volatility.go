@@ -0,0 +1,202 @@
+package indicator
+
+import (
+	"math"
+
+	Exchange "madaoQT/exchange"
+)
+
+// ATR average true range with the Wilder smoothing
+type ATR struct {
+	Period int
+
+	atr  *wilder
+	last *Exchange.KlineValue
+}
+
+func (p *ATR) Update(kline Exchange.KlineValue) {
+	if p.atr == nil {
+		p.atr = &wilder{period: p.Period}
+	}
+
+	p.atr.add(trueRange(kline, p.last))
+	p.last = &kline
+}
+
+func (p *ATR) Ready() bool {
+	return p.atr != nil && p.atr.ready()
+}
+
+func (p *ATR) Value() float64 {
+	if !p.Ready() {
+		return 0
+	}
+	return p.atr.value
+}
+
+// ADX average directional index, returns ADX, +DI and -DI
+type ADX struct {
+	Period int
+
+	tr      float64
+	plusDM  float64
+	minusDM float64
+	count   int
+	adx     *wilder
+	plusDI  float64
+	minusDI float64
+	last    *Exchange.KlineValue
+}
+
+func (p *ADX) Update(kline Exchange.KlineValue) {
+	if p.adx == nil {
+		p.adx = &wilder{period: p.Period}
+	}
+
+	if p.last == nil {
+		p.last = &kline
+		return
+	}
+
+	up := kline.High - p.last.High
+	down := p.last.Low - kline.Low
+	var plusDM, minusDM float64
+	if up > down && up > 0 {
+		plusDM = up
+	}
+	if down > up && down > 0 {
+		minusDM = down
+	}
+	tr := trueRange(kline, p.last)
+	p.last = &kline
+
+	// the Wilder's smoothed sums
+	p.count++
+	period := float64(p.Period)
+	if p.count <= p.Period {
+		p.tr += tr
+		p.plusDM += plusDM
+		p.minusDM += minusDM
+		if p.count < p.Period {
+			return
+		}
+	} else {
+		p.tr = p.tr - p.tr/period + tr
+		p.plusDM = p.plusDM - p.plusDM/period + plusDM
+		p.minusDM = p.minusDM - p.minusDM/period + minusDM
+	}
+
+	if p.tr == 0 {
+		p.plusDI, p.minusDI = 0, 0
+	} else {
+		p.plusDI = p.plusDM * 100 / p.tr
+		p.minusDI = p.minusDM * 100 / p.tr
+	}
+
+	var dx float64
+	if p.plusDI+p.minusDI != 0 {
+		dx = math.Abs(p.plusDI-p.minusDI) * 100 / (p.plusDI + p.minusDI)
+	}
+	p.adx.add(dx)
+}
+
+func (p *ADX) Ready() bool {
+	return p.adx != nil && p.adx.ready()
+}
+
+// Value() returns ADX, +DI and -DI
+func (p *ADX) Value() (float64, float64, float64) {
+	if !p.Ready() {
+		return 0, 0, 0
+	}
+	return p.adx.value, p.plusDI, p.minusDI
+}
+
+// Bollinger bollinger bands with the population standard deviation
+type Bollinger struct {
+	Period int
+	Factor float64
+
+	window window
+}
+
+func (p *Bollinger) Update(kline Exchange.KlineValue) {
+	p.window.size = p.Period
+	p.window.push(kline.Close)
+}
+
+func (p *Bollinger) Ready() bool {
+	return p.window.full()
+}
+
+// Value() returns the upper, middle and lower band
+func (p *Bollinger) Value() (float64, float64, float64) {
+	if !p.Ready() {
+		return 0, 0, 0
+	}
+	middle := p.window.mean()
+	width := p.Factor * p.window.stddev()
+	return middle + width, middle, middle - width
+}
+
+// Donchian the highest high and the lowest low of the last Period klines
+type Donchian struct {
+	Period int
+
+	highs window
+	lows  window
+}
+
+func (p *Donchian) Update(kline Exchange.KlineValue) {
+	p.highs.size = p.Period
+	p.lows.size = p.Period
+	p.highs.push(kline.High)
+	p.lows.push(kline.Low)
+}
+
+func (p *Donchian) Ready() bool {
+	return p.highs.full()
+}
+
+// Value() returns the upper, middle and lower band
+func (p *Donchian) Value() (float64, float64, float64) {
+	if !p.Ready() {
+		return 0, 0, 0
+	}
+	upper, lower := p.highs.highest(), p.lows.lowest()
+	return upper, (upper + lower) / 2, lower
+}
+
+// Keltner keltner channel, the EMA of the close price with the bands of Factor*ATR
+type Keltner struct {
+	Period    int
+	ATRPeriod int
+	Factor    float64
+
+	ema *EMA
+	atr *ATR
+}
+
+func (p *Keltner) Update(kline Exchange.KlineValue) {
+	if p.ema == nil {
+		p.ema = &EMA{Period: p.Period}
+		p.atr = &ATR{Period: p.ATRPeriod}
+	}
+
+	p.ema.Update(kline)
+	p.atr.Update(kline)
+}
+
+func (p *Keltner) Ready() bool {
+	return p.ema != nil && p.ema.Ready() && p.atr.Ready()
+}
+
+// Value() returns the upper, middle and lower band
+func (p *Keltner) Value() (float64, float64, float64) {
+	if !p.Ready() {
+		return 0, 0, 0
+	}
+	middle := p.ema.Value()
+	width := p.Factor * p.atr.Value()
+	return middle + width, middle, middle - width
+}
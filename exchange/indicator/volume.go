@@ -0,0 +1,69 @@
+package indicator
+
+import (
+	"time"
+
+	Exchange "madaoQT/exchange"
+)
+
+// VWAP volume weighted average of the typical price, restarted at each trading day of the Session if assigned
+type VWAP struct {
+	Session *Exchange.KlineSession
+
+	day    time.Time
+	amount float64
+	volume float64
+}
+
+func (p *VWAP) Update(kline Exchange.KlineValue) {
+	if p.Session != nil {
+		day := p.Session.TradingDay(time.Unix(int64(kline.OpenTime), 0))
+		if !day.Equal(p.day) {
+			p.day = day
+			p.amount = 0
+			p.volume = 0
+		}
+	}
+
+	typical := (kline.High + kline.Low + kline.Close) / 3
+	p.amount += typical * kline.Volumn
+	p.volume += kline.Volumn
+}
+
+func (p *VWAP) Ready() bool {
+	return p.volume > 0
+}
+
+func (p *VWAP) Value() float64 {
+	if !p.Ready() {
+		return 0
+	}
+	return p.amount / p.volume
+}
+
+// OBV on balance volume
+type OBV struct {
+	count int
+	last  float64
+	value float64
+}
+
+func (p *OBV) Update(kline Exchange.KlineValue) {
+	p.count++
+	if p.count > 1 {
+		if kline.Close > p.last {
+			p.value += kline.Volumn
+		} else if kline.Close < p.last {
+			p.value -= kline.Volumn
+		}
+	}
+	p.last = kline.Close
+}
+
+func (p *OBV) Ready() bool {
+	return p.count > 0
+}
+
+func (p *OBV) Value() float64 {
+	return p.value
+}
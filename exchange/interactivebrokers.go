@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -368,6 +369,69 @@ func (p *InteractiveBrokers) GetPortfolio(uid string) map[string]interface{} {
 	}
 }
 
+// GetAccount the cash balances of the ledger and the positions of the first page, the stocks are the holdings
+// whose cost is paid
+func (p *InteractiveBrokers) GetAccount() (error, *AccountInfo) {
+	if p.uid == "" {
+		if err, _ := p.GetAccountUID(); err != nil {
+			return err, nil
+		}
+	}
+
+	ledger := p.GetAccountLedger(p.uid)
+	summary := p.GetAccountSummary(p.uid)
+	if ledger == nil || summary == nil {
+		return errors.New("Fail to get the portfolio"), nil
+	}
+	return nil, parseIBAccount(ledger, summary, p.GetAllPositions())
+}
+
+func parseIBAccount(ledger map[string]interface{}, summary map[string]interface{}, positions []interface{}) *AccountInfo {
+	account := &AccountInfo{}
+	for currency, value := range ledger {
+		// BASE is the sum of the currencies
+		if entry, ok := value.(map[string]interface{}); ok && currency != "BASE" {
+			account.addBalance(currency, accountFloat(entry["cashbalance"]))
+		}
+	}
+
+	amount := func(key string) float64 {
+		if value, ok := summary[key].(map[string]interface{}); ok {
+			return accountFloat(value["amount"])
+		}
+		return 0
+	}
+	if equity := amount("netliquidation"); equity > 0 {
+		account.setMarginRatio(amount("maintmarginreq") / equity)
+	}
+
+	for _, item := range positions {
+		position, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		quantity := accountFloat(position["position"])
+		if quantity == 0 {
+			continue
+		}
+		// the description is like "AAPL" or "ES DEC2020"
+		fields := strings.Fields(fmt.Sprint(position["contractDesc"]))
+		currency, _ := position["currency"].(string)
+		if len(fields) == 0 {
+			continue
+		}
+		assetClass, _ := position["assetClass"].(string)
+		account.Positions = append(account.Positions, AccountPosition{
+			Pair:      strings.ToLower(fields[0] + "/" + currency),
+			Quantity:  quantity,
+			Amount:    quantity,
+			MarkPrice: accountFloat(position["mktPrice"]),
+			Paid:      assetClass == "STK",
+		})
+	}
+	return account
+}
+
 func (p *InteractiveBrokers) GetAccountInformation(uid string) map[string]interface{} {
 	if err, response := p.marketRequest("/portfolio/"+uid+"/meta", map[string]string{}); err != nil {
 		logger.Errorf("Invalid request:%v", err)
@@ -0,0 +1,277 @@
+package exchange
+
+import (
+	"time"
+
+	Global "madaoQT/config"
+)
+
+/*
+	Generic kline resampler: aggregates the klines of a smaller period into any larger period
+	with configurable session boundaries
+*/
+
+// KlinePeriod1Month and KlinePeriod1Year are only used as the target of the resampler
+const KlinePeriod1Month = 31 * 24 * 60
+const KlinePeriod1Year = 366 * 24 * 60
+
+// KlineSession describes how a market cuts its bars into trading days and weeks
+type KlineSession struct {
+	Name string
+	// Location the time zone in which the trading day is counted
+	Location *time.Location
+	// DayOffset is added to the bar time before taking the date, eg: 3 hours moves the CTP night session(21:00) into the next trading day
+	DayOffset time.Duration
+	// WeekStart the first day of the trading week
+	WeekStart time.Weekday
+	// RollWeekend moves a trading day which falls on Saturday or Sunday to the next Monday, eg: the CTP night session of Friday
+	RollWeekend bool
+}
+
+// SessionCrypto 24/7 markets, the day starts at 00:00 UTC
+var SessionCrypto = &KlineSession{
+	Name:      "crypto",
+	Location:  time.UTC,
+	WeekStart: time.Monday,
+}
+
+// SessionCTP the night session starting at 21:00 belongs to the next trading day
+var SessionCTP = &KlineSession{
+	Name:        "ctp",
+	Location:    loadLocation("Asia/Shanghai", 8*60*60),
+	DayOffset:   3 * time.Hour,
+	WeekStart:   time.Monday,
+	RollWeekend: true,
+}
+
+// SessionFX the trading day rolls at 17:00 New York, and the week opens on Sunday 17:00
+var SessionFX = &KlineSession{
+	Name:      "fx",
+	Location:  loadLocation("America/New_York", -5*60*60),
+	DayOffset: 7 * time.Hour,
+	WeekStart: time.Monday,
+}
+
+// sessionShanghai keeps the day boundary of the old converters, which cut the crypto klines at 00:00 of Beijing time
+var sessionShanghai = &KlineSession{
+	Name:      "shanghai",
+	Location:  loadLocation("Asia/Shanghai", 8*60*60),
+	WeekStart: time.Monday,
+}
+
+// sessionShanghaiFriday the weeks of the old daily converter, which are cut after Friday
+var sessionShanghaiFriday = &KlineSession{
+	Name:      "shanghai-friday",
+	Location:  sessionShanghai.Location,
+	WeekStart: time.Saturday,
+}
+
+func loadLocation(name string, offset int) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		logger.Errorf("Fail to load location %s:%v", name, err)
+		return time.FixedZone(name, offset)
+	}
+	return location
+}
+
+// TradingDay returns the date(00:00 in the session location) of the trading day which the time belongs to
+func (s *KlineSession) TradingDay(t time.Time) time.Time {
+	day := s.calendarDay(t)
+	if s.RollWeekend {
+		for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+	return day
+}
+
+func (s *KlineSession) calendarDay(t time.Time) time.Time {
+	local := t.In(s.Location).Add(s.DayOffset)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.Location)
+}
+
+// BucketStart returns the start time of the bar of the period which the time belongs to,
+// and the trading date used to label the bar
+func (s *KlineSession) BucketStart(t time.Time, period int) (time.Time, time.Time) {
+
+	var day time.Time
+
+	switch {
+	case period == KlinePeriod1Year:
+		day = s.TradingDay(t)
+		day = time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, s.Location)
+	case period == KlinePeriod1Month:
+		day = s.TradingDay(t)
+		day = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, s.Location)
+	case period == KlinePeriod1Week:
+		day = s.TradingDay(t)
+		diff := (int(day.Weekday()) - int(s.WeekStart) + 7) % 7
+		day = day.AddDate(0, 0, -diff)
+	case period%KlinePeriod1Day == 0:
+		day = s.TradingDay(t)
+		days := int(time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+		day = day.AddDate(0, 0, -(days % (period / KlinePeriod1Day)))
+	default:
+		// intraday bars are aligned to the start of the calendar day of the session
+		day = s.calendarDay(t)
+		start := day.Add(-s.DayOffset)
+		elapsed := t.Sub(start).Truncate(time.Duration(period) * time.Minute)
+		return start.Add(elapsed), day
+	}
+
+	return day.Add(-s.DayOffset), day
+}
+
+func (s *KlineSession) klineTime(kline KlineValue) (time.Time, bool) {
+	if kline.OpenTime != 0 {
+		return time.Unix(int64(kline.OpenTime), 0), true
+	}
+
+	for _, layout := range []string{Global.TimeFormat, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, kline.Time, s.Location); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func (s *KlineSession) bucketEnd(start time.Time, day time.Time, period int) time.Time {
+	switch {
+	case period == KlinePeriod1Year:
+		return day.AddDate(1, 0, 0).Add(-s.DayOffset)
+	case period == KlinePeriod1Month:
+		return day.AddDate(0, 1, 0).Add(-s.DayOffset)
+	case period == KlinePeriod1Week:
+		return day.AddDate(0, 0, 7).Add(-s.DayOffset)
+	case period%KlinePeriod1Day == 0:
+		return day.AddDate(0, 0, period/KlinePeriod1Day).Add(-s.DayOffset)
+	}
+	return start.Add(time.Duration(period) * time.Minute)
+}
+
+// ResampleKlines aggregates the klines(sorted by time ascending) into the larger period in minutes.
+// KlinePeriod1Week, KlinePeriod1Month and KlinePeriod1Year are cut by the calendar of the session,
+// the OpenTime of the bar is the time of its first kline
+func ResampleKlines(klines []KlineValue, period int, session *KlineSession) []KlineValue {
+
+	var result []KlineValue
+	for _, group := range splitKlines(klines, period, session) {
+		result = append(result, group.merge())
+	}
+
+	return result
+}
+
+// SplitKlines splits the klines(sorted by time ascending) into the groups of the given period
+func SplitKlines(klines []KlineValue, period int, session *KlineSession) [][]KlineValue {
+
+	var result [][]KlineValue
+	for _, group := range splitKlines(klines, period, session) {
+		result = append(result, group.klines)
+	}
+
+	return result
+}
+
+// skipUntil drops the klines before the first one accepted by opens, as the old converters started from
+// a whole bar. Nothing is dropped if no kline is accepted
+func skipUntil(klines []KlineValue, session *KlineSession, opens func(t time.Time) bool) []KlineValue {
+	for i, kline := range klines {
+		if t, ok := session.klineTime(kline); ok && opens(t.In(session.Location)) {
+			return klines[i:]
+		}
+	}
+	return klines
+}
+
+// resampleByLastDay labels the bars by the trading day of their last kline, as the old CTP converters did
+func resampleByLastDay(klines []KlineValue, period int, session *KlineSession) []KlineValue {
+
+	var result []KlineValue
+	for _, group := range splitKlines(klines, period, session) {
+		kline := group.merge()
+		kline.Time = session.TradingDay(group.last).Format("2006-01-02")
+		result = append(result, kline)
+	}
+
+	return result
+}
+
+type klineGroup struct {
+	start time.Time
+	end   time.Time
+	day   time.Time
+	// first and last the time of the first and the last kline
+	first  time.Time
+	last   time.Time
+	daily  bool
+	klines []KlineValue
+}
+
+func (g *klineGroup) merge() KlineValue {
+
+	kline := KlineValue{
+		OpenTime:  float64(g.first.Unix()),
+		CloseTime: float64(g.end.Unix()),
+		Open:      g.klines[0].Open,
+		High:      g.klines[0].High,
+		Low:       g.klines[0].Low,
+		Close:     g.klines[len(g.klines)-1].Close,
+	}
+
+	if g.daily {
+		kline.Time = g.day.Format("2006-01-02")
+	} else {
+		kline.Time = g.start.In(g.day.Location()).Format(Global.TimeFormat)
+	}
+
+	for _, item := range g.klines {
+		if item.High > kline.High {
+			kline.High = item.High
+		}
+		if item.Low < kline.Low {
+			kline.Low = item.Low
+		}
+		kline.Volumn += item.Volumn
+	}
+
+	return kline
+}
+
+func splitKlines(klines []KlineValue, period int, session *KlineSession) []*klineGroup {
+
+	if period <= 0 || session == nil {
+		logger.Errorf("Invalid period:%v or session:%v", period, session)
+		return nil
+	}
+
+	var groups []*klineGroup
+	var current *klineGroup
+
+	for _, kline := range klines {
+		t, ok := session.klineTime(kline)
+		if !ok {
+			logger.Errorf("Invalid kline time:%v", kline)
+			continue
+		}
+
+		start, day := session.BucketStart(t, period)
+		if current == nil || !current.start.Equal(start) {
+			current = &klineGroup{
+				start: start,
+				end:   session.bucketEnd(start, day, period),
+				day:   day,
+				first: t,
+				daily: period >= KlinePeriod1Day,
+			}
+			groups = append(groups, current)
+		}
+
+		current.last = t
+		current.klines = append(current.klines, kline)
+	}
+
+	return groups
+}
@@ -0,0 +1,196 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+)
+
+func makeHourKlines(start time.Time, hours int) []KlineValue {
+	var klines []KlineValue
+	for i := 0; i < hours; i++ {
+		value := float64(i)
+		klines = append(klines, KlineValue{
+			OpenTime: float64(start.Add(time.Duration(i) * time.Hour).Unix()),
+			Open:     value,
+			High:     value + 0.5,
+			Low:      value - 0.5,
+			Close:    value + 0.2,
+			Volumn:   1,
+		})
+	}
+	return klines
+}
+
+func checkKline(t *testing.T, kline KlineValue, expect KlineValue) {
+	if kline.Time != expect.Time || kline.Open != expect.Open || kline.High != expect.High ||
+		kline.Low != expect.Low || kline.Close != expect.Close || kline.Volumn != expect.Volumn {
+		t.Errorf("Kline:%v Expect:%v", kline, expect)
+	}
+}
+
+func TestResampleCryptoDaily(t *testing.T) {
+	start := time.Date(2018, 3, 5, 0, 0, 0, 0, time.UTC)
+	klines := ResampleKlines(makeHourKlines(start, 48), KlinePeriod1Day, SessionCrypto)
+
+	if len(klines) != 2 {
+		t.Fatalf("Invalid length:%v", len(klines))
+	}
+
+	checkKline(t, klines[0], KlineValue{Time: "2018-03-05", Open: 0, High: 23.5, Low: -0.5, Close: 23.2, Volumn: 24})
+	checkKline(t, klines[1], KlineValue{Time: "2018-03-06", Open: 24, High: 47.5, Low: 23.5, Close: 47.2, Volumn: 24})
+
+	if klines[1].OpenTime != float64(start.AddDate(0, 0, 1).Unix()) {
+		t.Errorf("Invalid open time:%v", klines[1].OpenTime)
+	}
+}
+
+func TestResampleCryptoHours(t *testing.T) {
+	start := time.Date(2018, 3, 5, 2, 0, 0, 0, time.UTC)
+	klines := ResampleKlines(makeHourKlines(start, 10), KlinePeriod4Hour, SessionCrypto)
+
+	// 00:00-04:00 only has two klines, then 04:00-08:00 and 08:00-12:00
+	if len(klines) != 3 {
+		t.Fatalf("Invalid length:%v", len(klines))
+	}
+
+	checkKline(t, klines[0], KlineValue{Time: "2018-03-05 00:00:00", Open: 0, High: 1.5, Low: -0.5, Close: 1.2, Volumn: 2})
+	checkKline(t, klines[1], KlineValue{Time: "2018-03-05 04:00:00", Open: 2, High: 5.5, Low: 1.5, Close: 5.2, Volumn: 4})
+	checkKline(t, klines[2], KlineValue{Time: "2018-03-05 08:00:00", Open: 6, High: 9.5, Low: 5.5, Close: 9.2, Volumn: 4})
+}
+
+func TestResampleCTPNightSession(t *testing.T) {
+	location := SessionCTP.Location
+	var klines []KlineValue
+	// Thursday night and Friday day session belong to Friday
+	klines = append(klines, makeHourKlines(time.Date(2018, 3, 8, 21, 0, 0, 0, location), 2)...)
+	klines = append(klines, makeHourKlines(time.Date(2018, 3, 9, 9, 0, 0, 0, location), 2)...)
+	// Friday night session belongs to Monday
+	klines = append(klines, makeHourKlines(time.Date(2018, 3, 9, 21, 0, 0, 0, location), 3)...)
+	klines = append(klines, makeHourKlines(time.Date(2018, 3, 12, 9, 0, 0, 0, location), 2)...)
+
+	daily := ResampleKlines(klines, KlinePeriod1Day, SessionCTP)
+	if len(daily) != 2 {
+		t.Fatalf("Invalid length:%v", len(daily))
+	}
+
+	checkKline(t, daily[0], KlineValue{Time: "2018-03-09", Open: 0, High: 1.5, Low: -0.5, Close: 1.2, Volumn: 4})
+	checkKline(t, daily[1], KlineValue{Time: "2018-03-12", Open: 0, High: 2.5, Low: -0.5, Close: 1.2, Volumn: 5})
+
+	weekly := ResampleKlines(klines, KlinePeriod1Week, SessionCTP)
+	if len(weekly) != 2 {
+		t.Fatalf("Invalid length:%v", len(weekly))
+	}
+
+	checkKline(t, weekly[0], KlineValue{Time: "2018-03-05", Open: 0, High: 1.5, Low: -0.5, Close: 1.2, Volumn: 4})
+	checkKline(t, weekly[1], KlineValue{Time: "2018-03-12", Open: 0, High: 2.5, Low: -0.5, Close: 1.2, Volumn: 5})
+
+	// the bar opens with the Friday night session rolled into it
+	if weekly[1].OpenTime != float64(time.Date(2018, 3, 9, 21, 0, 0, 0, location).Unix()) {
+		t.Errorf("Invalid open time:%v", time.Unix(int64(weekly[1].OpenTime), 0).In(location))
+	}
+}
+
+func TestResampleFXWeekOpen(t *testing.T) {
+	location := SessionFX.Location
+	var klines []KlineValue
+	klines = append(klines, makeHourKlines(time.Date(2018, 3, 9, 15, 0, 0, 0, location), 2)...)
+	// the week opens on Sunday 17:00 New York
+	klines = append(klines, makeHourKlines(time.Date(2018, 3, 11, 17, 0, 0, 0, location), 8)...)
+
+	daily := ResampleKlines(klines, KlinePeriod1Day, SessionFX)
+	if len(daily) != 2 {
+		t.Fatalf("Invalid length:%v", len(daily))
+	}
+
+	checkKline(t, daily[0], KlineValue{Time: "2018-03-09", Open: 0, High: 1.5, Low: -0.5, Close: 1.2, Volumn: 2})
+	checkKline(t, daily[1], KlineValue{Time: "2018-03-12", Open: 0, High: 7.5, Low: -0.5, Close: 7.2, Volumn: 8})
+
+	if daily[1].OpenTime != float64(time.Date(2018, 3, 11, 17, 0, 0, 0, location).Unix()) {
+		t.Errorf("Invalid open time:%v", time.Unix(int64(daily[1].OpenTime), 0).In(location))
+	}
+
+	weekly := ResampleKlines(klines, KlinePeriod1Week, SessionFX)
+	if len(weekly) != 2 || weekly[1].Time != "2018-03-12" {
+		t.Errorf("Invalid weekly klines:%v", weekly)
+	}
+}
+
+func TestResampleDailyByTime(t *testing.T) {
+	klines := []KlineValue{
+		{Time: "2017-12-28", Open: 10, High: 12, Low: 9, Close: 11, Volumn: 100},
+		{Time: "2017-12-29", Open: 11, High: 13, Low: 10, Close: 12, Volumn: 200},
+		{Time: "2018-01-02", Open: 12, High: 15, Low: 8, Close: 14, Volumn: 300},
+		{Time: "2018-01-03", Open: 14, High: 14, Low: 12, Close: 13, Volumn: 400},
+	}
+
+	monthly := ResampleKlines(klines, KlinePeriod1Month, SessionCTP)
+	if len(monthly) != 2 {
+		t.Fatalf("Invalid length:%v", len(monthly))
+	}
+
+	checkKline(t, monthly[0], KlineValue{Time: "2017-12-01", Open: 10, High: 13, Low: 9, Close: 12, Volumn: 300})
+	checkKline(t, monthly[1], KlineValue{Time: "2018-01-01", Open: 12, High: 15, Low: 8, Close: 13, Volumn: 700})
+
+	years := CTPDailyKlinesSplitToYears(klines)
+	if len(years) != 2 || len(years[0]) != 2 || len(years[1]) != 2 {
+		t.Errorf("Invalid years:%v", years)
+	}
+
+	weekly := ResampleKlines(klines, KlinePeriod1Week, SessionCTP)
+	if len(weekly) != 2 || weekly[0].Time != "2017-12-25" || weekly[1].Time != "2018-01-01" {
+		t.Errorf("Invalid weekly klines:%v", weekly)
+	}
+
+	// the old converters label the bars by their last day
+	monthly = CTPDailyKlinesToMonth(klines)
+	if len(monthly) != 2 {
+		t.Fatalf("Invalid length:%v", len(monthly))
+	}
+
+	checkKline(t, monthly[0], KlineValue{Time: "2017-12-29", Open: 10, High: 13, Low: 9, Close: 12, Volumn: 300})
+	checkKline(t, monthly[1], KlineValue{Time: "2018-01-03", Open: 12, High: 15, Low: 8, Close: 13, Volumn: 700})
+
+	weekly = CTPDailyKlinesToWeek(klines)
+	if len(weekly) != 2 || weekly[0].Time != "2017-12-29" || weekly[1].Time != "2018-01-03" {
+		t.Errorf("Invalid weekly klines:%v", weekly)
+	}
+}
+
+func TestSwithDialyToWeekKlines(t *testing.T) {
+	location := sessionShanghai.Location
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, location)
+	var klines []KlineValue
+	for i := 0; i < 14; i++ {
+		value := float64(i)
+		klines = append(klines, KlineValue{
+			OpenTime: float64(start.AddDate(0, 0, i).Unix()),
+			Open:     value,
+			High:     value + 0.5,
+			Low:      value - 0.5,
+			Close:    value + 0.2,
+			Volumn:   1,
+		})
+	}
+
+	// Thursday to Sunday before the first Monday are dropped, the next week starts from Saturday
+	weekly := SwithDialyToWeekKlines(klines)
+	if len(weekly) != 2 {
+		t.Fatalf("Invalid length:%v", len(weekly))
+	}
+
+	checkKline(t, weekly[0], KlineValue{Time: "2018-03-03", Open: 4, High: 8.5, Low: 3.5, Close: 8.2, Volumn: 5})
+	checkKline(t, weekly[1], KlineValue{Time: "2018-03-10", Open: 9, High: 13.5, Low: 8.5, Close: 13.2, Volumn: 5})
+
+	if weekly[0].OpenTime != float64(time.Date(2018, 3, 5, 0, 0, 0, 0, location).Unix()) ||
+		weekly[1].OpenTime != float64(time.Date(2018, 3, 10, 0, 0, 0, 0, location).Unix()) {
+		t.Errorf("Invalid open time:%v", weekly)
+	}
+
+	// the hours before the first 00:00 are dropped
+	daily := Swith1HourToDialyKlines(makeHourKlines(time.Date(2018, 3, 1, 22, 0, 0, 0, location), 6))
+	if len(daily) != 1 {
+		t.Fatalf("Invalid length:%v", len(daily))
+	}
+
+	checkKline(t, daily[0], KlineValue{Time: "2018-03-02", Open: 2, High: 5.5, Low: 1.5, Close: 5.2, Volumn: 4})
+}
@@ -505,6 +505,139 @@ func (p *OandaAPI) Trade(configs TradeConfig) *TradeResult {
 	return nil
 }
 
+// oandaStopOrder the dependent orders of the trade, the trailing distance is in price
+func oandaStopOrder(config StopOrderConfig) map[string]interface{} {
+	order := map[string]string{
+		"tradeID":     config.TradeID,
+		"timeInForce": "GTC",
+	}
+	switch config.StopType {
+	case StopOrderLoss:
+		order["type"] = "STOP_LOSS"
+		order["price"] = formatStopPrice(config.Trigger, 5)
+	case StopOrderProfit:
+		order["type"] = "TAKE_PROFIT"
+		order["price"] = formatStopPrice(config.Trigger, 5)
+	case StopOrderTrailing:
+		order["type"] = "TRAILING_STOP_LOSS"
+		order["distance"] = formatStopPrice(stopDistance(config), 5)
+	}
+	return map[string]interface{}{
+		"order": order,
+	}
+}
+
+// oandaTransaction returns the id of the transaction, or the error message of the rejection
+func oandaTransaction(response []byte, key string) (error, string) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, ""
+	}
+	if transaction, ok := values[key].(map[string]interface{}); ok {
+		if id, _ := transaction["id"].(string); id != "" {
+			return nil, id
+		}
+	}
+	if message, ok := values["errorMessage"].(string); ok {
+		return errors.New(message), ""
+	}
+	return errors.New("Invalid response"), ""
+}
+
+// PlaceStopOrder the order is attached to the trade which opened the position
+func (p *OandaAPI) PlaceStopOrder(config StopOrderConfig) (error, string) {
+	if config.TradeID == "" {
+		return errors.New("The trade isn't assigned"), ""
+	}
+	data, _ := json.Marshal(oandaStopOrder(config))
+	err, response := p.marketRequest("POST", "/v3/accounts/"+p.config.Custom["account"].(string)+"/orders", map[string]string{
+		"data": string(data),
+	})
+	if err != nil {
+		return err, ""
+	}
+	return oandaTransaction(response, "orderCreateTransaction")
+}
+
+func (p *OandaAPI) CancelStopOrder(pair string, orderID string) error {
+	err, response := p.marketRequest("PUT", "/v3/accounts/"+p.config.Custom["account"].(string)+"/orders/"+orderID+"/cancel", nil)
+	if err != nil {
+		return err
+	}
+	err, _ = oandaTransaction(response, "orderCancelTransaction")
+	return err
+}
+
+var oandaStopStatus = map[string]OrderStatusType{
+	"PENDING":   OrderStatusOpen,
+	"TRIGGERED": OrderStatusOpen,
+	"FILLED":    OrderStatusDone,
+	"CANCELLED": OrderStatusCanceled,
+}
+
+// parseOandaStopOrder returns the transaction which filled the order
+func parseOandaStopOrder(response []byte) (error, *OrderInfo, string) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, nil, ""
+	}
+	order, ok := values["order"].(map[string]interface{})
+	if !ok {
+		if message, ok := values["errorMessage"].(string); ok {
+			return errors.New(message), nil, ""
+		}
+		return errors.New("Invalid response"), nil, ""
+	}
+	state, _ := order["state"].(string)
+	status, ok := oandaStopStatus[state]
+	if !ok {
+		status = OrderStatusUnknown
+	}
+	info := &OrderInfo{Status: status}
+	info.OrderID, _ = order["id"].(string)
+	filling, _ := order["fillingTransactionID"].(string)
+	return nil, info, filling
+}
+
+// parseOandaFill the units are negative if the order sells
+func parseOandaFill(response []byte, info *OrderInfo) error {
+	var values map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err
+	}
+	transaction, ok := values["transaction"].(map[string]interface{})
+	if !ok {
+		return errors.New("Invalid response")
+	}
+	units, _ := transaction["units"].(string)
+	price, _ := transaction["price"].(string)
+	amount, _ := strconv.ParseFloat(units, 64)
+	info.DealAmount = math.Abs(amount)
+	info.Amount = info.DealAmount
+	info.AvgPrice, _ = strconv.ParseFloat(price, 64)
+	return nil
+}
+
+// GetStopOrder the fill of the order is found in the transaction which filled it
+func (p *OandaAPI) GetStopOrder(pair string, orderID string) (error, *OrderInfo) {
+	account := "/v3/accounts/" + p.config.Custom["account"].(string)
+	err, response := p.marketRequest("GET", account+"/orders/"+orderID, map[string]string{})
+	if err != nil {
+		return err, nil
+	}
+	err, info, filling := parseOandaStopOrder(response)
+	if err != nil || info.Status != OrderStatusDone || filling == "" {
+		return err, info
+	}
+	if err, response = p.marketRequest("GET", account+"/transactions/"+filling, map[string]string{}); err != nil {
+		return err, nil
+	}
+	if err := parseOandaFill(response, info); err != nil {
+		return err, nil
+	}
+	return nil, info
+}
+
 // CancelOrder() cancel the order as the order information
 func (p *OandaAPI) CancelOrder(order OrderInfo) *TradeResult {
 	return nil
@@ -769,6 +902,91 @@ func (p *OandaAPI) GetAccountInfo() map[string]interface{} {
 	}
 }
 
+// GetAccount the NAV is the balance in the currency of the account, the positions are priced by the closeout prices
+func (p *OandaAPI) GetAccount() (error, *AccountInfo) {
+	account := p.GetAccountInfo()
+	if account == nil {
+		return errors.New("Fail to get account info"), nil
+	}
+
+	var instruments []string
+	if values, ok := account["account"].(map[string]interface{}); ok {
+		positions, _ := values["positions"].([]interface{})
+		for _, item := range positions {
+			if position, ok := item.(map[string]interface{}); ok {
+				if instrument, ok := position["instrument"].(string); ok {
+					instruments = append(instruments, instrument)
+				}
+			}
+		}
+	}
+
+	var prices map[string]interface{}
+	if len(instruments) != 0 {
+		err, response := p.marketRequest("GET", "/v3/accounts/"+p.config.Custom["account"].(string)+"/pricing", map[string]string{
+			"instruments": strings.Join(instruments, ","),
+		})
+		if err != nil {
+			return err, nil
+		}
+		if err = json.Unmarshal(response, &prices); err != nil {
+			return err, nil
+		}
+	}
+	return parseOandaAccount(account, prices)
+}
+
+// parseOandaAccount the positions are closed out when the closeout percent reaches 1
+func parseOandaAccount(account map[string]interface{}, prices map[string]interface{}) (error, *AccountInfo) {
+	values, ok := account["account"].(map[string]interface{})
+	if !ok {
+		if message, ok := account["errorMessage"].(string); ok {
+			return errors.New(message), nil
+		}
+		return errors.New("Invalid response"), nil
+	}
+
+	mid := make(map[string]float64)
+	list, _ := prices["prices"].([]interface{})
+	for _, item := range list {
+		if price, ok := item.(map[string]interface{}); ok {
+			instrument, _ := price["instrument"].(string)
+			mid[instrument] = (accountFloat(price["closeoutBid"]) + accountFloat(price["closeoutAsk"])) / 2
+		}
+	}
+
+	result := &AccountInfo{}
+	currency, _ := values["currency"].(string)
+	result.addBalance(currency, accountFloat(values["NAV"]))
+	result.setMarginRatio(accountFloat(values["marginCloseoutPercent"]))
+
+	positions, _ := values["positions"].([]interface{})
+	for _, item := range positions {
+		position, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		instrument, _ := position["instrument"].(string)
+		var quantity float64
+		if long, ok := position["long"].(map[string]interface{}); ok {
+			quantity += accountFloat(long["units"])
+		}
+		if short, ok := position["short"].(map[string]interface{}); ok {
+			quantity += accountFloat(short["units"])
+		}
+		if quantity == 0 {
+			continue
+		}
+		result.Positions = append(result.Positions, AccountPosition{
+			Pair:      strings.ToLower(strings.Replace(instrument, "_", "/", 1)),
+			Quantity:  quantity,
+			Amount:    quantity,
+			MarkPrice: mid[instrument],
+		})
+	}
+	return nil, result
+}
+
 func (p *OandaAPI) GetInstruments() map[string]interface{} {
 	if err, response := p.marketRequest("GET", "/v3/accounts/"+p.config.Custom["account"].(string)+"/instruments", map[string]string{}); err != nil {
 		logger.Errorf("无法获取账户信息:%v", err)
@@ -431,6 +431,14 @@ func (o *OKEXV3API) Start2(errChan chan EventType) error {
 }
 
 func (p *OKEXV3API) orderRequest(method string, path string, params map[string]string) (error, []byte) {
+	if params == nil {
+		return p.request(method, path, nil)
+	}
+	return p.request(method, path, params)
+}
+
+// request the params are encoded in JSON, the arrays are allowed
+func (p *OKEXV3API) request(method string, path string, params interface{}) (error, []byte) {
 
 	logger.Infof("Path:%v", path)
 	timestamp := time.Now().UTC().Format(time.RFC3339)
@@ -919,6 +927,69 @@ func (o *OKEXV3API) GetOrderInfo(filter OrderInfo) []OrderInfo {
 	return nil
 }
 
+// GetOpenOrders the orders of the swap or the spot which aren't filled
+func (o *OKEXV3API) GetOpenOrders(pair string) (error, []OrderInfo) {
+	coins := ParsePair(pair)
+	var path string
+	if o.InstrumentType == InstrumentTypeSwap {
+		path = "/api/swap/v3/orders/" + strings.ToUpper(coins[0]) + "-USD-SWAP?state=6"
+	} else {
+		path = "/api/spot/v3/orders_pending?instrument_id=" + strings.ToUpper(coins[0]) + "-" + strings.ToUpper(coins[1])
+	}
+
+	err, response := o.orderRequest("GET", path, nil)
+	if err != nil {
+		return err, nil
+	}
+	return o.parseOpenOrders(pair, response)
+}
+
+// parseOpenOrders the orders of the swap are in order_info, while the spot returns the list
+func (o *OKEXV3API) parseOpenOrders(pair string, response []byte) (error, []OrderInfo) {
+	var values interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, nil
+	}
+
+	list, _ := values.([]interface{})
+	if value, ok := values.(map[string]interface{}); ok {
+		if value["order_info"] == nil {
+			return okexV3Error(response), nil
+		}
+		list, _ = value["order_info"].([]interface{})
+	}
+
+	var orders []OrderInfo
+	for _, item := range list {
+		order, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		orderID, _ := order["order_id"].(string)
+		info := OrderInfo{
+			Pair:       pair,
+			OrderID:    orderID,
+			Price:      accountFloat(order["price"]),
+			Amount:     accountFloat(order["size"]),
+			DealAmount: accountFloat(order["filled_qty"]),
+			Status:     OrderStatusOpen,
+		}
+		// the state 1 is partly filled
+		if order["state"] == "1" {
+			info.Status = OrderStatusPartDone
+		}
+		if side, ok := order["side"].(string); ok {
+			info.Type = o.getTradeTypeByString(side)
+			info.DealAmount = accountFloat(order["filled_size"])
+		} else {
+			orderType, _ := order["type"].(string)
+			info.Type = o.getTradeTypeByString(orderType)
+		}
+		orders = append(orders, info)
+	}
+	return nil, orders
+}
+
 func (o *OKEXV3API) WatchEvent() chan EventType {
 	return nil
 }
@@ -1033,6 +1104,269 @@ func (o *OKEXV3API) GetBalance2(instrument string) map[string]interface{} {
 
 }
 
+// GetAccount the swap account reports the positions and the margin, the spot account reports the balances only
+func (o *OKEXV3API) GetAccount() (error, *AccountInfo) {
+	if o.InstrumentType == InstrumentTypeSpot {
+		err, response := o.orderRequest("GET", "/api/spot/v3/accounts", map[string]string{})
+		if err != nil {
+			return err, nil
+		}
+		return parseOkexV3SpotAccount(response)
+	}
+
+	err, accounts := o.orderRequest("GET", "/api/swap/v3/accounts", map[string]string{})
+	if err != nil {
+		return err, nil
+	}
+	err, positions := o.orderRequest("GET", "/api/swap/v3/position", map[string]string{})
+	if err != nil {
+		return err, nil
+	}
+	return parseOkexV3SwapAccount(accounts, positions)
+}
+
+const (
+	okexV3AlgoTrigger = "1"
+	okexV3AlgoTrail   = "2"
+)
+
+// okexV3AlgoParams the algo orders of the swap, the trigger orders are placed in market price
+func okexV3AlgoParams(config StopOrderConfig) map[string]string {
+	params := map[string]string{
+		"instrument_id": strings.ToUpper(ParsePair(config.Pair)[0]) + "-USD-SWAP",
+		"type":          OkexGetTradeTypeString(config.Type),
+		"size":          strconv.FormatFloat(config.Amount, 'f', 0, 64),
+	}
+	if config.StopType == StopOrderTrailing {
+		params["order_type"] = okexV3AlgoTrail
+		params["callback_rate"] = strconv.FormatFloat(config.Callback, 'f', 4, 64)
+		params["trigger_price"] = formatStopPrice(config.Price, 2)
+	} else {
+		params["order_type"] = okexV3AlgoTrigger
+		params["algo_type"] = "2"
+		params["trigger_price"] = formatStopPrice(config.Trigger, 2)
+	}
+	return params
+}
+
+// parseOkexV3Algo the id is prefixed by the order type which is required by the cancellation
+func parseOkexV3Algo(orderType string, response []byte) (error, string) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, ""
+	}
+	data, _ := values["data"].(map[string]interface{})
+	if id, _ := data["algo_id"].(string); id != "" && id != "-1" {
+		return nil, orderType + ":" + id
+	}
+	if message, _ := data["error_message"].(string); message != "" {
+		return errors.New(message), ""
+	}
+	return okexV3Error(response), ""
+}
+
+// PlaceStopOrder only the swap supports the algo orders
+func (o *OKEXV3API) PlaceStopOrder(config StopOrderConfig) (error, string) {
+	if o.InstrumentType != InstrumentTypeSwap {
+		return errors.New("The algo orders of the spot aren't supported"), ""
+	}
+	params := okexV3AlgoParams(config)
+	err, response := o.orderRequest("POST", "/api/swap/v3/order_algo", params)
+	if err != nil {
+		return err, ""
+	}
+	return parseOkexV3Algo(params["order_type"], response)
+}
+
+func (o *OKEXV3API) CancelStopOrder(pair string, orderID string) error {
+	values := strings.SplitN(orderID, ":", 2)
+	if len(values) != 2 {
+		return errors.New("Invalid algo order:" + orderID)
+	}
+	instrument := strings.ToUpper(ParsePair(pair)[0]) + "-USD-SWAP"
+	err, response := o.request("POST", "/api/swap/v3/cancel_algos", map[string]interface{}{
+		"instrument_id": instrument,
+		"algo_ids":      []string{values[1]},
+		"order_type":    values[0],
+	})
+	if err != nil {
+		return err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(response, &result); err != nil {
+		return err
+	}
+	if data, ok := result["data"].(map[string]interface{}); ok && data["result"] == "true" {
+		return nil
+	}
+	return okexV3Error(response)
+}
+
+var okexV3AlgoStatus = map[string]OrderStatusType{
+	"1": OrderStatusOpen,
+	"2": OrderStatusDone,
+	"3": OrderStatusCanceled,
+	"4": OrderStatusPartDone,
+	"5": OrderStatusOpen,
+	"6": OrderStatusRejected,
+}
+
+func parseOkexV3AlgoStatus(response []byte) (error, *OrderInfo) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, nil
+	}
+	orders, _ := values["orderStrategyVOS"].([]interface{})
+	if len(orders) == 0 {
+		return okexV3Error(response), nil
+	}
+	order, _ := orders[0].(map[string]interface{})
+	status, ok := okexV3AlgoStatus[fmt.Sprint(order["status"])]
+	if !ok {
+		status = OrderStatusUnknown
+	}
+	info := &OrderInfo{Status: status}
+	info.OrderID, _ = order["algo_id"].(string)
+	info.Amount, _ = strconv.ParseFloat(fmt.Sprint(order["size"]), 64)
+	info.DealAmount, _ = strconv.ParseFloat(fmt.Sprint(order["real_amount"]), 64)
+	info.AvgPrice, _ = strconv.ParseFloat(fmt.Sprint(order["real_price"]), 64)
+	return nil, info
+}
+
+// GetStopOrder the algo order is effective after it's triggered and filled
+func (o *OKEXV3API) GetStopOrder(pair string, orderID string) (error, *OrderInfo) {
+	values := strings.SplitN(orderID, ":", 2)
+	if len(values) != 2 {
+		return errors.New("Invalid algo order:" + orderID), nil
+	}
+	path := "/api/swap/v3/order_algo/" + strings.ToUpper(ParsePair(pair)[0]) + "-USD-SWAP" +
+		"?order_type=" + values[0] + "&algo_id=" + values[1]
+	err, response := o.orderRequest("GET", path, nil)
+	if err != nil {
+		return err, nil
+	}
+	return parseOkexV3AlgoStatus(response)
+}
+
+// okexV3AccountFunding the transfers between the master account and the sub-accounts are in the funding accounts
+const okexV3AccountFunding = "6"
+
+func okexV3TransferParams(config TransferConfig) map[string]string {
+	transferType := "1"
+	if config.ToMaster {
+		transferType = "2"
+	}
+	return map[string]string{
+		"currency":    strings.ToUpper(config.Coin),
+		"amount":      formatTransferAmount(config.Amount),
+		"from":        okexV3AccountFunding,
+		"to":          okexV3AccountFunding,
+		"type":        transferType,
+		"sub_account": config.SubAccount,
+	}
+}
+
+func parseOkexV3Transfer(response []byte) (error, string) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err, ""
+	}
+	if values["result"] == true {
+		id, _ := values["transfer_id"].(string)
+		return nil, id
+	}
+	return okexV3Error(response), ""
+}
+
+// SubAccountTransfer the keys should be the keys of the master account
+func (o *OKEXV3API) SubAccountTransfer(config TransferConfig) (error, string) {
+	err, response := o.orderRequest("POST", "/api/account/v3/transfer", okexV3TransferParams(config))
+	if err != nil {
+		return err, ""
+	}
+	return parseOkexV3Transfer(response)
+}
+
+// okexV3Error the error response is an object with the message
+func okexV3Error(response []byte) error {
+	var values map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return err
+	}
+	if message, ok := values["message"].(string); ok && message != "" {
+		return errors.New(message)
+	}
+	return errors.New("Invalid response")
+}
+
+func parseOkexV3SpotAccount(response []byte) (error, *AccountInfo) {
+	var values []map[string]interface{}
+	if err := json.Unmarshal(response, &values); err != nil {
+		return okexV3Error(response), nil
+	}
+
+	account := &AccountInfo{}
+	for _, value := range values {
+		if currency, ok := value["currency"].(string); ok {
+			account.addBalance(currency, accountFloat(value["balance"]))
+		}
+	}
+	return nil, account
+}
+
+// parseOkexV3SwapAccount the accounts of the coins are liquidated when the margin ratio falls to the maintenance
+// margin ratio, the positions are the USD contracts
+func parseOkexV3SwapAccount(accounts []byte, positions []byte) (error, *AccountInfo) {
+	var accountValues struct {
+		Info []map[string]interface{} `json:"info"`
+	}
+	if err := json.Unmarshal(accounts, &accountValues); err != nil || accountValues.Info == nil {
+		return okexV3Error(accounts), nil
+	}
+
+	var positionValues []struct {
+		Holding []map[string]interface{} `json:"holding"`
+	}
+	if err := json.Unmarshal(positions, &positionValues); err != nil {
+		return okexV3Error(positions), nil
+	}
+
+	account := &AccountInfo{}
+	for _, info := range accountValues.Info {
+		instrument, _ := info["instrument_id"].(string)
+		account.addBalance(strings.Split(instrument, "-")[0], accountFloat(info["equity"]))
+		if ratio := accountFloat(info["margin_ratio"]); ratio > 0 {
+			account.setMarginRatio(accountFloat(info["maint_margin_ratio"]) / ratio)
+		}
+	}
+
+	for _, value := range positionValues {
+		for _, holding := range value.Holding {
+			instrument, _ := holding["instrument_id"].(string)
+			symbols := strings.Split(strings.ToLower(instrument), "-")
+			contracts := accountFloat(holding["position"])
+			last := accountFloat(holding["last"])
+			if len(symbols) < 2 || contracts == 0 || last <= 0 {
+				continue
+			}
+
+			quantity := contracts * InverseContractValue(symbols[0]) / last
+			if holding["side"] == "short" {
+				quantity = -quantity
+				contracts = -contracts
+			}
+			account.Positions = append(account.Positions, AccountPosition{
+				Pair:             symbols[0] + "/" + symbols[1],
+				Quantity:         quantity,
+				Amount:           contracts,
+				MarkPrice:        last,
+				LiquidationPrice: accountFloat(holding["liquidation_price"]),
+			})
+		}
+	}
+	return nil, account
+}
+
 func (o *OKEXV3API) getTradeTypeByString(orderType string) TradeType {
 	switch orderType {
 	case "1":
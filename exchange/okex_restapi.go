@@ -470,300 +470,91 @@ func (p *OkexRestAPI) GetOrderInfo(filter OrderInfo) []OrderInfo {
 	}
 }
 
-func SwithMinutesToHourKlines(klines []KlineValue) []KlineValue {
-	var KlinesByHour []KlineValue
-
-	var high, low, open, close float64
-	// var klineTime time.Time
-
-	location, _ := time.LoadLocation("Asia/Shanghai")
-	var first time.Time
-
-	for i, kline := range klines {
-		first = time.Unix(int64(kline.OpenTime), 0).In(location)
-		if first.Minute() == 0 { // start from 01:33
-			klines = klines[i:]
-			break
-		}
-
-	}
-
-	for i, kline := range klines {
-
-		// klineTime = time.Unix(int64(kline.OpenTime), 0).In(location)
-		if open == 0 {
-			open = kline.Open
-		}
-		// log.Printf("Time:%v", klineTime)
-		if high == 0 || high < kline.High {
-			high = kline.High
-		}
+// okexOpenOrdersPage the open orders are listed by the page
+const okexOpenOrdersPage = 50
 
-		if low == 0 || low > kline.Low {
-			low = kline.Low
-		}
+// GetContractOpenOrders the unfilled orders of the pair in the contract type, e.g. this_week
+func (p *OkexRestAPI) GetContractOpenOrders(pair string, contractType string) (error, []OrderInfo) {
+	coins := ParsePair(pair)
 
-		close = kline.Close
-
-		if i+1 < len(klines) {
-			nextTime := time.Unix(int64(klines[i+1].OpenTime), 0).In(location)
-			if nextTime.Hour() != first.Hour() {
-				if high != 0 && low != 0 && close != 0 {
-					lastKline := KlineValue{
-						High:     high,
-						Low:      low,
-						Open:     open,
-						Close:    close,
-						OpenTime: float64(time.Date(first.Year(), first.Month(), first.Day(), first.Hour(), 0, 0, 0, location).Unix()),
-					}
-					KlinesByHour = append(KlinesByHour, lastKline)
-				}
-				first = nextTime
-				open = 0
-				high = 0
-				low = 0
-				close = 0
-			}
-		} else {
-			if high != 0 && low != 0 && close != 0 {
-				lastKline := KlineValue{
-					High:     high,
-					Low:      low,
-					Open:     open,
-					Close:    close,
-					OpenTime: float64(time.Date(first.Year(), first.Month(), first.Day(), first.Hour(), 0, 0, 0, location).Unix()),
-				}
-				KlinesByHour = append(KlinesByHour, lastKline)
-			}
+	var result []OrderInfo
+	for page := 1; ; page++ {
+		parameters := p.sign(map[string]string{
+			"symbol":        coins[0] + "_usd",
+			"contract_type": contractType,
+			"api_key":       p.apiKey,
+			"order_id":      "-1",
+			"status":        "1",
+			"current_page":  strconv.Itoa(page),
+			"page_length":   strconv.Itoa(okexOpenOrdersPage),
+		})
+
+		err, response := p.tradeRequest("future_order_info.do", parameters)
+		if err != nil {
+			return err, nil
+		}
+
+		var values struct {
+			Result bool `json:"result"`
+			Orders []struct {
+				OrderID    json.Number `json:"order_id"`
+				Price      float64     `json:"price"`
+				Amount     float64     `json:"amount"`
+				DealAmount float64     `json:"deal_amount"`
+				PriceAvg   float64     `json:"price_avg"`
+				Type       float64     `json:"type"`
+				Status     float64     `json:"status"`
+			} `json:"orders"`
+		}
+		if err := json.Unmarshal(response, &values); err != nil {
+			return err, nil
+		}
+		if !values.Result {
+			return errors.New("Fail to get the open orders:" + string(response)), nil
+		}
+
+		for _, order := range values.Orders {
+			result = append(result, OrderInfo{
+				Pair:       pair,
+				OrderID:    order.OrderID.String(),
+				Price:      order.Price,
+				Amount:     order.Amount,
+				Type:       OkexGetTradeTypeByFloat(order.Type),
+				Status:     OkexGetTradeStatus(order.Status),
+				DealAmount: order.DealAmount,
+				AvgPrice:   order.PriceAvg,
+			})
+		}
+		if len(values.Orders) < okexOpenOrdersPage {
+			return nil, result
 		}
-
 	}
+}
 
-	return KlinesByHour
+// SwithMinutesToHourKlines Deprecated: use ResampleKlines, the klines before the first whole hour are dropped
+func SwithMinutesToHourKlines(klines []KlineValue) []KlineValue {
+	klines = skipUntil(klines, sessionShanghai, func(t time.Time) bool { return t.Minute() == 0 })
+	return ResampleKlines(klines, KlinePeriod1Hour, sessionShanghai)
 }
 
+// Swith1HourToDialyKlines Deprecated: use ResampleKlines, the klines before the first 00:00 are dropped
 func Swith1HourToDialyKlines(klines []KlineValue) []KlineValue {
-	var KlinesByDate []KlineValue
-
-	var high, low, open, close float64
-	// var klineTime time.Time
-
-	location, _ := time.LoadLocation("Asia/Shanghai")
-	var first time.Time
-
-	for i, kline := range klines {
-		first = time.Unix(int64(kline.OpenTime), 0).In(location)
-		if first.Hour() == 0 { // start from 01:33
-			klines = klines[i:]
-			break
-		}
-
-	}
-
-	for i, kline := range klines {
-
-		// klineTime = time.Unix(int64(kline.OpenTime), 0).In(location)
-		if open == 0 {
-			open = kline.Open
-		}
-		// log.Printf("Time:%v", klineTime)
-		if high == 0 || high < kline.High {
-			high = kline.High
-		}
-
-		if low == 0 || low > kline.Low {
-			low = kline.Low
-		}
-
-		close = kline.Close
-
-		if i+1 < len(klines) {
-			nextTime := time.Unix(int64(klines[i+1].OpenTime), 0).In(location)
-			if nextTime.Day() != first.Day() {
-				if high != 0 && low != 0 && close != 0 {
-					lastKline := KlineValue{
-						High:     high,
-						Low:      low,
-						Open:     open,
-						Close:    close,
-						OpenTime: float64(time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, location).Unix()),
-					}
-					KlinesByDate = append(KlinesByDate, lastKline)
-				}
-				first = nextTime
-				open = 0
-				high = 0
-				low = 0
-				close = 0
-			}
-		} else {
-			if high != 0 && low != 0 && close != 0 {
-				lastKline := KlineValue{
-					High:     high,
-					Low:      low,
-					Open:     open,
-					Close:    close,
-					OpenTime: float64(time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, location).Unix()),
-				}
-				KlinesByDate = append(KlinesByDate, lastKline)
-			}
-		}
-
-	}
-
-	return KlinesByDate
+	klines = skipUntil(klines, sessionShanghai, func(t time.Time) bool { return t.Hour() == 0 })
+	return ResampleKlines(klines, KlinePeriod1Day, sessionShanghai)
 }
 
+// Swith1HourToHoursKlines Deprecated: use ResampleKlines, the klines before the first 00:00 are dropped
 func Swith1HourToHoursKlines(hours int, klines []KlineValue) []KlineValue {
-	var KlinesByHours []KlineValue
-
-	var high, low, open, close float64
-	var klineTime time.Time
-
-	location, _ := time.LoadLocation("Asia/Shanghai")
-	var first time.Time
-
 	if hours == 0 {
 		return nil
 	}
-
-	for i, kline := range klines {
-		first = time.Unix(int64(kline.OpenTime), 0).In(location)
-		if first.Hour() == 0 { // start from 01:33
-			klines = klines[i:]
-			break
-		}
-
-	}
-
-	for i, kline := range klines {
-		klineTime = time.Unix(int64(kline.OpenTime), 0).In(location)
-		if open == 0 {
-			open = kline.Open
-		}
-		// log.Printf("Time:%v", klineTime)
-		if high == 0 || high < kline.High {
-			high = kline.High
-		}
-
-		if low == 0 || low > kline.Low {
-			low = kline.Low
-		}
-
-		close = kline.Close
-
-		if klineTime.Hour()%hours == (hours - 1) {
-			if high != 0 && low != 0 && close != 0 {
-				lastKline := KlineValue{
-					High:     high,
-					Low:      low,
-					Open:     open,
-					Close:    close,
-					OpenTime: float64(time.Date(first.Year(), first.Month(), first.Day(), first.Hour(), 0, 0, 0, location).Unix()),
-				}
-				KlinesByHours = append(KlinesByHours, lastKline)
-			}
-			if i+1 < len(klines) {
-				first = time.Unix(int64(klines[i+1].OpenTime), 0).In(location)
-			}
-
-			open = 0
-			high = 0
-			low = 0
-			close = 0
-
-		}
-
-	}
-
-	// okex 包含了最新的k线数据，所以不用担心有时间但没有数据的问题
-	if high != 0 && low != 0 && close != 0 {
-		lastKline := KlineValue{
-			High:     high,
-			Low:      low,
-			Open:     open,
-			Close:    close,
-			OpenTime: float64(time.Date(first.Year(), first.Month(), first.Day(), first.Hour(), 0, 0, 0, location).Unix()),
-		}
-		KlinesByHours = append(KlinesByHours, lastKline)
-	}
-
-	return KlinesByHours
+	klines = skipUntil(klines, sessionShanghai, func(t time.Time) bool { return t.Hour() == 0 })
+	return ResampleKlines(klines, hours*KlinePeriod1Hour, sessionShanghai)
 }
 
+// SwithDialyToWeekKlines Deprecated: use ResampleKlines. It keeps the old weeks, which start from the first Monday
+// and are cut after Friday, so the weekend belongs to the next week
 func SwithDialyToWeekKlines(klines []KlineValue) []KlineValue {
-	var KlinesByWeek []KlineValue
-
-	var high, low, open, close float64
-	var klineTime time.Time
-
-	location, _ := time.LoadLocation("Asia/Shanghai")
-	var first time.Time
-
-	if klines == nil || len(klines) == 0 {
-		return nil
-	}
-
-	for i, kline := range klines {
-		first = time.Unix(int64(kline.OpenTime), 0).In(location)
-		if first.Weekday() == time.Monday { // start from 01:33
-			klines = klines[i:]
-			break
-		}
-
-	}
-
-	for i, kline := range klines {
-		klineTime = time.Unix(int64(kline.OpenTime), 0).In(location)
-		if open == 0 {
-			open = kline.Open
-		}
-		// log.Printf("Time:%v", klineTime)
-		if high == 0 || high < kline.High {
-			high = kline.High
-		}
-
-		if low == 0 || low > kline.Low {
-			low = kline.Low
-		}
-
-		close = kline.Close
-
-		if klineTime.Weekday() == time.Friday {
-			if high != 0 && low != 0 && close != 0 {
-				lastKline := KlineValue{
-					High:     high,
-					Low:      low,
-					Open:     open,
-					Close:    close,
-					OpenTime: float64(time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, location).Unix()),
-				}
-				KlinesByWeek = append(KlinesByWeek, lastKline)
-			}
-			if i+1 < len(klines) {
-				first = time.Unix(int64(klines[i+1].OpenTime), 0).In(location)
-			}
-
-			open = 0
-			high = 0
-			low = 0
-			close = 0
-
-		}
-
-	}
-
-	// okex 包含了最新的k线数据，所以不用担心有时间但没有数据的问题
-	if high != 0 && low != 0 && close != 0 {
-		lastKline := KlineValue{
-			High:     high,
-			Low:      low,
-			Open:     open,
-			Close:    close,
-			OpenTime: float64(time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, location).Unix()),
-		}
-		KlinesByWeek = append(KlinesByWeek, lastKline)
-	}
-
-	return KlinesByWeek
+	klines = skipUntil(klines, sessionShanghai, func(t time.Time) bool { return t.Weekday() == time.Monday })
+	return ResampleKlines(klines, KlinePeriod1Week, sessionShanghaiFriday)
 }
@@ -405,3 +405,13 @@ func (p *SchaffTrend) calcSchaff(value float64) float64 {
 	return currentPFF
 
 }
+
+// Update() the same as UpdateSchaff, so that SchaffTrend can be used as a streaming indicator
+func (p *SchaffTrend) Update(kline KlineValue) {
+	p.UpdateSchaff(kline)
+}
+
+// Ready() whether the schaff value is available
+func (p *SchaffTrend) Ready() bool {
+	return len(p.pff) > 0
+}
@@ -0,0 +1,65 @@
+package exchange
+
+import (
+	"strconv"
+)
+
+/*
+	条件单：止损、止盈和移动止损由交易所触发，任务停止后仓位仍然受保护
+*/
+
+type StopOrderType int
+
+const (
+	// StopOrderLoss the position is closed when the price crosses the trigger against it
+	StopOrderLoss StopOrderType = iota
+	// StopOrderProfit the position is closed when the price reaches the trigger
+	StopOrderProfit
+	// StopOrderTrailing the position is closed when the price retraces from the best price by the callback
+	StopOrderTrailing
+)
+
+var StopOrderTypeString = map[StopOrderType]string{
+	StopOrderLoss:     "stoploss",
+	StopOrderProfit:   "takeprofit",
+	StopOrderTrailing: "trailing",
+}
+
+// StopOrderConfig the conditional order which closes the position
+type StopOrderConfig struct {
+	Pair string
+	// Type the trade which closes the position
+	Type     TradeType
+	StopType StopOrderType
+	Amount   float64
+	// Trigger the price which triggers the order, it isn't used by the trailing stop
+	Trigger float64
+	// Callback the ratio of the retracement which triggers the trailing stop, e.g. 0.01
+	Callback float64
+	// Price the current price, the venues set the trailing distance in price
+	Price float64
+	// TradeID the trade closed by the order on the venues like OANDA
+	TradeID string
+}
+
+// IStopOrders the exchange which places the conditional orders natively. GetStopOrder returns the status done only
+// after the order is triggered and filled
+type IStopOrders interface {
+	PlaceStopOrder(config StopOrderConfig) (error, string)
+	CancelStopOrder(pair string, orderID string) error
+	GetStopOrder(pair string, orderID string) (error, *OrderInfo)
+}
+
+// stopSell whether the closing trade sells
+func stopSell(tradeType TradeType) bool {
+	return tradeType == TradeTypeCloseLong || tradeType == TradeTypeSell || tradeType == TradeTypeOpenShort
+}
+
+// stopDistance the trailing distance in price
+func stopDistance(config StopOrderConfig) float64 {
+	return config.Callback * config.Price
+}
+
+func formatStopPrice(price float64, precision int) string {
+	return strconv.FormatFloat(price, 'f', precision, 64)
+}
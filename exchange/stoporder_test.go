@@ -0,0 +1,102 @@
+package exchange
+
+import (
+	"testing"
+)
+
+func TestStopOrderParams(t *testing.T) {
+	loss := StopOrderConfig{Pair: "btc/usd", Type: TradeTypeCloseLong, StopType: StopOrderLoss, Amount: 100, Trigger: 9500.26, Price: 10000}
+	trailing := StopOrderConfig{Pair: "btc/usd", Type: TradeTypeCloseShort, StopType: StopOrderTrailing, Amount: 100, Callback: 0.02, Price: 10000}
+
+	params := bitmexStopParams(loss)
+	if params["symbol"] != "XBTUSD" || params["side"] != "Sell" || params["ordType"] != "Stop" || params["stopPx"] != "9500.3" {
+		t.Errorf("Invalid bitmex params:%v", params)
+	}
+	if params = bitmexStopParams(trailing); params["side"] != "Buy" || params["pegPriceType"] != "TrailingStopPeg" || params["pegOffsetValue"] != "200.0" {
+		t.Errorf("Invalid bitmex trailing params:%v", params)
+	}
+
+	method, values := deribitStopParams(loss)
+	if method != "private/sell" || values["type"] != "stop_market" || values["trigger_price"] != 9500.26 || values["reduce_only"] != true {
+		t.Errorf("Invalid deribit params:%s %v", method, values)
+	}
+	if method, values = deribitStopParams(trailing); method != "private/buy" || values["trigger_offset"] != 200.0 {
+		t.Errorf("Invalid deribit trailing params:%s %v", method, values)
+	}
+
+	loss.TradeID = "42"
+	order := oandaStopOrder(loss)["order"].(map[string]string)
+	if order["type"] != "STOP_LOSS" || order["tradeID"] != "42" || order["price"] != "9500.26000" {
+		t.Errorf("Invalid oanda order:%v", order)
+	}
+
+	if params = okexV3AlgoParams(loss); params["instrument_id"] != "BTC-USD-SWAP" || params["type"] != "3" ||
+		params["order_type"] != okexV3AlgoTrigger || params["trigger_price"] != "9500.26" {
+		t.Errorf("Invalid okex params:%v", params)
+	}
+	if params = okexV3AlgoParams(trailing); params["type"] != "4" || params["order_type"] != okexV3AlgoTrail || params["callback_rate"] != "0.0200" {
+		t.Errorf("Invalid okex trailing params:%v", params)
+	}
+}
+
+func TestParseStopOrder(t *testing.T) {
+	if err, id := parseBitmexStopOrder([]byte(`{"orderID":"abc","ordStatus":"New"}`)); err != nil || id != "abc" {
+		t.Errorf("Invalid bitmex order:%v %s", err, id)
+	}
+	if err, _ := parseBitmexStopOrder([]byte(`{"error":{"message":"Invalid stopPx"}}`)); err == nil || err.Error() != "Invalid stopPx" {
+		t.Errorf("Invalid bitmex error:%v", err)
+	}
+
+	if err, id := parseDeribitStopOrder(map[string]interface{}{"order": map[string]interface{}{"order_id": "ETH-1"}}); err != nil || id != "ETH-1" {
+		t.Errorf("Invalid deribit order:%v %s", err, id)
+	}
+
+	if err, id := oandaTransaction([]byte(`{"orderCreateTransaction":{"id":"101","type":"STOP_LOSS_ORDER"}}`), "orderCreateTransaction"); err != nil || id != "101" {
+		t.Errorf("Invalid oanda order:%v %s", err, id)
+	}
+	if err, _ := oandaTransaction([]byte(`{"errorMessage":"Trade not found"}`), "orderCreateTransaction"); err == nil {
+		t.Errorf("The error should be returned")
+	}
+
+	if err, id := parseOkexV3Algo("2", []byte(`{"code":"0","data":{"algo_id":"123","result":"true"}}`)); err != nil || id != "2:123" {
+		t.Errorf("Invalid okex order:%v %s", err, id)
+	}
+	if err, _ := parseOkexV3Algo("1", []byte(`{"code":"0","data":{"algo_id":"-1","error_message":"size error","result":"false"}}`)); err == nil {
+		t.Errorf("The error should be returned")
+	}
+}
+
+func TestParseStopStatus(t *testing.T) {
+	if err, info := parseBitmexStopStatus([]byte(`[{"orderID":"abc","ordStatus":"Filled","orderQty":100,"cumQty":100,"avgPx":9480.5}]`)); err != nil ||
+		info.Status != OrderStatusDone || info.DealAmount != 100 || info.AvgPrice != 9480.5 {
+		t.Errorf("Invalid bitmex status:%v %v", err, info)
+	}
+	if err, _ := parseBitmexStopStatus([]byte(`[]`)); err == nil {
+		t.Errorf("The missing order should be an error")
+	}
+
+	if err, info := parseDeribitStopStatus(map[string]interface{}{"order_id": "ETH-1", "order_state": "untriggered", "amount": 10.0, "filled_amount": 0.0}); err != nil ||
+		info.Status != OrderStatusOpen {
+		t.Errorf("Invalid deribit status:%v %v", err, info)
+	}
+	if err, info := parseDeribitStopStatus(map[string]interface{}{"order_state": "filled", "filled_amount": 10.0, "average_price": 200.5}); err != nil ||
+		info.Status != OrderStatusDone || info.AvgPrice != 200.5 {
+		t.Errorf("Invalid deribit status:%v %v", err, info)
+	}
+
+	err, info, filling := parseOandaStopOrder([]byte(`{"order":{"id":"101","state":"FILLED","fillingTransactionID":"105"}}`))
+	if err != nil || info.Status != OrderStatusDone || filling != "105" {
+		t.Fatalf("Invalid oanda status:%v %v %s", err, info, filling)
+	}
+	if err := parseOandaFill([]byte(`{"transaction":{"id":"105","units":"-1000","price":"1.10502"}}`), info); err != nil || info.DealAmount != 1000 || info.AvgPrice != 1.10502 {
+		t.Errorf("Invalid oanda fill:%v %v", err, info)
+	}
+
+	if err, info := parseOkexV3AlgoStatus([]byte(`{"orderStrategyVOS":[{"algo_id":"123","status":"2","size":"3","real_amount":"3","real_price":"9490.1"}]}`)); err != nil ||
+		info.Status != OrderStatusDone || info.DealAmount != 3 || info.AvgPrice != 9490.1 {
+		t.Errorf("Invalid okex status:%v %v", err, info)
+	}
+	if err, info := parseOkexV3AlgoStatus([]byte(`{"orderStrategyVOS":[{"algo_id":"123","status":"1","size":"3","real_amount":"0"}]}`)); err != nil || info.Status != OrderStatusOpen {
+		t.Errorf("Invalid okex status:%v %v", err, info)
+	}
+}
@@ -0,0 +1,57 @@
+package exchange
+
+import (
+	"errors"
+	"strconv"
+)
+
+/*
+	子账户划转：母账户的密钥在母账户和子账户之间划转资金，子账户自己的密钥按账户名称保存在ExchangeDB
+*/
+
+// TransferConfig the transfer between the master account and the sub-account
+type TransferConfig struct {
+	Coin   string
+	Amount float64
+	// SubAccount the name of the sub-account on OKEX, the email on Binance and the uid on Huobi
+	SubAccount string
+	// ToMaster transfers from the sub-account to the master account, otherwise from the master account
+	ToMaster bool
+}
+
+// ISubAccounts the master account which transfers the funds to and from the sub-accounts, it returns the id of the
+// transfer
+type ISubAccounts interface {
+	SubAccountTransfer(config TransferConfig) (error, string)
+}
+
+// NewSubAccounts creates the master account of the exchange by the keys, the passphrase of OKEX is in the custom
+// config
+func NewSubAccounts(name string, config Config) (error, ISubAccounts) {
+	switch name {
+	case NameOKEXV3:
+		passphrase, _ := config.Custom["passphrase"].(string)
+		return nil, &OKEXV3API{
+			ApiKey:    config.API,
+			SecretKey: config.Secret,
+			Passphare: passphrase,
+			Proxy:     config.Proxy,
+		}
+	case NameBinance:
+		binance := new(Binance)
+		binance.SetConfigure(config)
+		return nil, binance
+	case ExchangeHuobi:
+		return nil, &Huobi{
+			InstrumentType: InstrumentTypeSpot,
+			ApiKey:         config.API,
+			SecretKey:      config.Secret,
+			Proxy:          config.Proxy,
+		}
+	}
+	return errors.New("The transfers of the sub-accounts aren't supported by " + name), nil
+}
+
+func formatTransferAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -0,0 +1,69 @@
+package exchange
+
+import (
+	"testing"
+)
+
+func TestTransferParams(t *testing.T) {
+	config := TransferConfig{Coin: "eth", Amount: 1.5, SubAccount: "sub1"}
+
+	params := okexV3TransferParams(config)
+	if params["currency"] != "ETH" || params["amount"] != "1.5" || params["type"] != "1" || params["sub_account"] != "sub1" {
+		t.Errorf("Invalid params of OKEX:%v", params)
+	}
+	params = binanceTransferParams(config)
+	if params["asset"] != "ETH" || params["toEmail"] != "sub1" || params["fromEmail"] != "" {
+		t.Errorf("Invalid params of Binance:%v", params)
+	}
+	params = huobiTransferParams(config)
+	if params["currency"] != "eth" || params["sub-uid"] != "sub1" || params["type"] != "master-transfer-out" {
+		t.Errorf("Invalid params of Huobi:%v", params)
+	}
+
+	config.ToMaster = true
+	if params = okexV3TransferParams(config); params["type"] != "2" {
+		t.Errorf("Invalid params of OKEX:%v", params)
+	}
+	if params = binanceTransferParams(config); params["fromEmail"] != "sub1" || params["toEmail"] != "" {
+		t.Errorf("Invalid params of Binance:%v", params)
+	}
+	if params = huobiTransferParams(config); params["type"] != "master-transfer-in" {
+		t.Errorf("Invalid params of Huobi:%v", params)
+	}
+}
+
+func TestParseTransfer(t *testing.T) {
+	if err, id := parseOkexV3Transfer([]byte(`{"transfer_id":"754147","currency":"ETH","from":"6","amount":"0.1","to":"6","result":true}`)); err != nil || id != "754147" {
+		t.Errorf("Invalid transfer of OKEX:%v %v", id, err)
+	}
+	if err, _ := parseOkexV3Transfer([]byte(`{"code":30031,"message":"token does not exist"}`)); err == nil || err.Error() != "token does not exist" {
+		t.Errorf("Invalid error of OKEX:%v", err)
+	}
+
+	if err, id := parseBinanceTransfer([]byte(`{"tranId":11945860693}`)); err != nil || id != "11945860693" {
+		t.Errorf("Invalid transfer of Binance:%v %v", id, err)
+	}
+	if err, _ := parseBinanceTransfer([]byte(`{"code":-1100,"msg":"Illegal characters"}`)); err == nil || err.Error() != "Illegal characters" {
+		t.Errorf("Invalid error of Binance:%v", err)
+	}
+
+	if err, id := parseHuobiTransfer(map[string]interface{}{"status": "ok", "data": float64(12345)}); err != nil || id != "12345" {
+		t.Errorf("Invalid transfer of Huobi:%v %v", id, err)
+	}
+	if err, _ := parseHuobiTransfer(map[string]interface{}{"status": "error", "err_msg": "insufficient"}); err == nil {
+		t.Errorf("The transfer of Huobi should fail")
+	}
+}
+
+func TestNewSubAccounts(t *testing.T) {
+	err, master := NewSubAccounts(NameOKEXV3, Config{API: "api", Secret: "secret", Custom: map[string]interface{}{"passphrase": "pass"}})
+	if okex, ok := master.(*OKEXV3API); err != nil || !ok || okex.ApiKey != "api" || okex.Passphare != "pass" {
+		t.Errorf("Invalid master account of OKEX:%v %v", err, master)
+	}
+	if err, master = NewSubAccounts(ExchangeHuobi, Config{API: "api"}); err != nil || master.(*Huobi).InstrumentType != InstrumentTypeSpot {
+		t.Errorf("Invalid master account of Huobi:%v %v", err, master)
+	}
+	if err, _ = NewSubAccounts(NameBitmex, Config{}); err == nil {
+		t.Errorf("The exchange without the sub-accounts should fail")
+	}
+}
@@ -0,0 +1,1013 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: strategy.proto
+
+package gprc
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Empty struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Empty) Reset()         { *m = Empty{} }
+func (m *Empty) String() string { return proto.CompactTextString(m) }
+func (*Empty) ProtoMessage()    {}
+func (*Empty) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{0}
+}
+
+func (m *Empty) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Empty.Unmarshal(m, b)
+}
+func (m *Empty) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Empty.Marshal(b, m, deterministic)
+}
+func (m *Empty) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Empty.Merge(m, src)
+}
+func (m *Empty) XXX_Size() int {
+	return xxx_messageInfo_Empty.Size(m)
+}
+func (m *Empty) XXX_DiscardUnknown() {
+	xxx_messageInfo_Empty.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Empty proto.InternalMessageInfo
+
+type Result struct {
+	Result               bool     `protobuf:"varint,1,opt,name=result,proto3" json:"result,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Result) Reset()         { *m = Result{} }
+func (m *Result) String() string { return proto.CompactTextString(m) }
+func (*Result) ProtoMessage()    {}
+func (*Result) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{1}
+}
+
+func (m *Result) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Result.Unmarshal(m, b)
+}
+func (m *Result) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Result.Marshal(b, m, deterministic)
+}
+func (m *Result) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Result.Merge(m, src)
+}
+func (m *Result) XXX_Size() int {
+	return xxx_messageInfo_Result.Size(m)
+}
+func (m *Result) XXX_DiscardUnknown() {
+	xxx_messageInfo_Result.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Result proto.InternalMessageInfo
+
+func (m *Result) GetResult() bool {
+	if m != nil {
+		return m.Result
+	}
+	return false
+}
+
+func (m *Result) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type RegisterRequest struct {
+	Name          string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Title         string `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Desc          string `protobuf:"bytes,3,opt,name=desc,proto3" json:"desc,omitempty"`
+	DefaultConfig string `protobuf:"bytes,4,opt,name=defaultConfig,proto3" json:"defaultConfig,omitempty"`
+	// address the address of the Strategy service
+	Address string `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	Pid     int32  `protobuf:"varint,6,opt,name=pid,proto3" json:"pid,omitempty"`
+	// schema the JSON Schema of the config
+	Schema               string   `protobuf:"bytes,7,opt,name=schema,proto3" json:"schema,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RegisterRequest) Reset()         { *m = RegisterRequest{} }
+func (m *RegisterRequest) String() string { return proto.CompactTextString(m) }
+func (*RegisterRequest) ProtoMessage()    {}
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{2}
+}
+
+func (m *RegisterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RegisterRequest.Unmarshal(m, b)
+}
+func (m *RegisterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RegisterRequest.Marshal(b, m, deterministic)
+}
+func (m *RegisterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RegisterRequest.Merge(m, src)
+}
+func (m *RegisterRequest) XXX_Size() int {
+	return xxx_messageInfo_RegisterRequest.Size(m)
+}
+func (m *RegisterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RegisterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RegisterRequest proto.InternalMessageInfo
+
+func (m *RegisterRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetDesc() string {
+	if m != nil {
+		return m.Desc
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetDefaultConfig() string {
+	if m != nil {
+		return m.DefaultConfig
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetPid() int32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *RegisterRequest) GetSchema() string {
+	if m != nil {
+		return m.Schema
+	}
+	return ""
+}
+
+type UnregisterRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Address              string   `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UnregisterRequest) Reset()         { *m = UnregisterRequest{} }
+func (m *UnregisterRequest) String() string { return proto.CompactTextString(m) }
+func (*UnregisterRequest) ProtoMessage()    {}
+func (*UnregisterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{3}
+}
+
+func (m *UnregisterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UnregisterRequest.Unmarshal(m, b)
+}
+func (m *UnregisterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UnregisterRequest.Marshal(b, m, deterministic)
+}
+func (m *UnregisterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UnregisterRequest.Merge(m, src)
+}
+func (m *UnregisterRequest) XXX_Size() int {
+	return xxx_messageInfo_UnregisterRequest.Size(m)
+}
+func (m *UnregisterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UnregisterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UnregisterRequest proto.InternalMessageInfo
+
+func (m *UnregisterRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *UnregisterRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+type InstanceRequest struct {
+	Instance             string   `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InstanceRequest) Reset()         { *m = InstanceRequest{} }
+func (m *InstanceRequest) String() string { return proto.CompactTextString(m) }
+func (*InstanceRequest) ProtoMessage()    {}
+func (*InstanceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{4}
+}
+
+func (m *InstanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InstanceRequest.Unmarshal(m, b)
+}
+func (m *InstanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InstanceRequest.Marshal(b, m, deterministic)
+}
+func (m *InstanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InstanceRequest.Merge(m, src)
+}
+func (m *InstanceRequest) XXX_Size() int {
+	return xxx_messageInfo_InstanceRequest.Size(m)
+}
+func (m *InstanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InstanceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InstanceRequest proto.InternalMessageInfo
+
+func (m *InstanceRequest) GetInstance() string {
+	if m != nil {
+		return m.Instance
+	}
+	return ""
+}
+
+type ConfigRequest struct {
+	Instance             string   `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	Namespace            string   `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Config               string   `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConfigRequest) Reset()         { *m = ConfigRequest{} }
+func (m *ConfigRequest) String() string { return proto.CompactTextString(m) }
+func (*ConfigRequest) ProtoMessage()    {}
+func (*ConfigRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{5}
+}
+
+func (m *ConfigRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ConfigRequest.Unmarshal(m, b)
+}
+func (m *ConfigRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ConfigRequest.Marshal(b, m, deterministic)
+}
+func (m *ConfigRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ConfigRequest.Merge(m, src)
+}
+func (m *ConfigRequest) XXX_Size() int {
+	return xxx_messageInfo_ConfigRequest.Size(m)
+}
+func (m *ConfigRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ConfigRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ConfigRequest proto.InternalMessageInfo
+
+func (m *ConfigRequest) GetInstance() string {
+	if m != nil {
+		return m.Instance
+	}
+	return ""
+}
+
+func (m *ConfigRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *ConfigRequest) GetConfig() string {
+	if m != nil {
+		return m.Config
+	}
+	return ""
+}
+
+type StatusResponse struct {
+	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatusResponse) Reset()         { *m = StatusResponse{} }
+func (m *StatusResponse) String() string { return proto.CompactTextString(m) }
+func (*StatusResponse) ProtoMessage()    {}
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{6}
+}
+
+func (m *StatusResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatusResponse.Unmarshal(m, b)
+}
+func (m *StatusResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatusResponse.Marshal(b, m, deterministic)
+}
+func (m *StatusResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatusResponse.Merge(m, src)
+}
+func (m *StatusResponse) XXX_Size() int {
+	return xxx_messageInfo_StatusResponse.Size(m)
+}
+func (m *StatusResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatusResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatusResponse proto.InternalMessageInfo
+
+func (m *StatusResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *StatusResponse) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type PositionsResponse struct {
+	Positions            string   `protobuf:"bytes,1,opt,name=positions,proto3" json:"positions,omitempty"`
+	Failed               string   `protobuf:"bytes,2,opt,name=failed,proto3" json:"failed,omitempty"`
+	Balances             string   `protobuf:"bytes,3,opt,name=balances,proto3" json:"balances,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PositionsResponse) Reset()         { *m = PositionsResponse{} }
+func (m *PositionsResponse) String() string { return proto.CompactTextString(m) }
+func (*PositionsResponse) ProtoMessage()    {}
+func (*PositionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{7}
+}
+
+func (m *PositionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PositionsResponse.Unmarshal(m, b)
+}
+func (m *PositionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PositionsResponse.Marshal(b, m, deterministic)
+}
+func (m *PositionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PositionsResponse.Merge(m, src)
+}
+func (m *PositionsResponse) XXX_Size() int {
+	return xxx_messageInfo_PositionsResponse.Size(m)
+}
+func (m *PositionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PositionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PositionsResponse proto.InternalMessageInfo
+
+func (m *PositionsResponse) GetPositions() string {
+	if m != nil {
+		return m.Positions
+	}
+	return ""
+}
+
+func (m *PositionsResponse) GetFailed() string {
+	if m != nil {
+		return m.Failed
+	}
+	return ""
+}
+
+func (m *PositionsResponse) GetBalances() string {
+	if m != nil {
+		return m.Balances
+	}
+	return ""
+}
+
+type LogsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogsRequest) Reset()         { *m = LogsRequest{} }
+func (m *LogsRequest) String() string { return proto.CompactTextString(m) }
+func (*LogsRequest) ProtoMessage()    {}
+func (*LogsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{8}
+}
+
+func (m *LogsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogsRequest.Unmarshal(m, b)
+}
+func (m *LogsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogsRequest.Marshal(b, m, deterministic)
+}
+func (m *LogsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogsRequest.Merge(m, src)
+}
+func (m *LogsRequest) XXX_Size() int {
+	return xxx_messageInfo_LogsRequest.Size(m)
+}
+func (m *LogsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogsRequest proto.InternalMessageInfo
+
+type LogEntry struct {
+	// time the unix time in milliseconds
+	Time int64 `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	// level the golog level
+	Level                int32    `protobuf:"varint,2,opt,name=level,proto3" json:"level,omitempty"`
+	Message              string   `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogEntry) Reset()         { *m = LogEntry{} }
+func (m *LogEntry) String() string { return proto.CompactTextString(m) }
+func (*LogEntry) ProtoMessage()    {}
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46ec5ce6dd46feab, []int{9}
+}
+
+func (m *LogEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogEntry.Unmarshal(m, b)
+}
+func (m *LogEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogEntry.Marshal(b, m, deterministic)
+}
+func (m *LogEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogEntry.Merge(m, src)
+}
+func (m *LogEntry) XXX_Size() int {
+	return xxx_messageInfo_LogEntry.Size(m)
+}
+func (m *LogEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogEntry proto.InternalMessageInfo
+
+func (m *LogEntry) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *LogEntry) GetLevel() int32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *LogEntry) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*Empty)(nil), "gprc.Empty")
+	proto.RegisterType((*Result)(nil), "gprc.Result")
+	proto.RegisterType((*RegisterRequest)(nil), "gprc.RegisterRequest")
+	proto.RegisterType((*UnregisterRequest)(nil), "gprc.UnregisterRequest")
+	proto.RegisterType((*InstanceRequest)(nil), "gprc.InstanceRequest")
+	proto.RegisterType((*ConfigRequest)(nil), "gprc.ConfigRequest")
+	proto.RegisterType((*StatusResponse)(nil), "gprc.StatusResponse")
+	proto.RegisterType((*PositionsResponse)(nil), "gprc.PositionsResponse")
+	proto.RegisterType((*LogsRequest)(nil), "gprc.LogsRequest")
+	proto.RegisterType((*LogEntry)(nil), "gprc.LogEntry")
+}
+
+func init() { proto.RegisterFile("strategy.proto", fileDescriptor_46ec5ce6dd46feab) }
+
+var fileDescriptor_46ec5ce6dd46feab = []byte{
+	// 545 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0xae, 0xdb, 0x38, 0x71, 0xa6, 0x4d, 0x4a, 0x96, 0x02, 0x56, 0xd4, 0x43, 0xb4, 0x42, 0x22,
+	0x07, 0x1a, 0x41, 0x2b, 0xe0, 0x04, 0x12, 0x42, 0x3d, 0x20, 0x55, 0x08, 0x39, 0xea, 0x03, 0x6c,
+	0xed, 0x89, 0x6b, 0xc9, 0xf1, 0x9a, 0xdd, 0x09, 0x28, 0x8f, 0xc6, 0x3b, 0xf1, 0x10, 0x68, 0x7f,
+	0x9c, 0x34, 0x89, 0x22, 0xe5, 0x36, 0xdf, 0xec, 0xcc, 0x7c, 0xf3, 0xf3, 0xd9, 0xd0, 0xd7, 0xa4,
+	0x04, 0x61, 0xbe, 0x9c, 0xd4, 0x4a, 0x92, 0x64, 0xad, 0xbc, 0x56, 0x29, 0xef, 0x40, 0x78, 0x3b,
+	0xaf, 0x69, 0xc9, 0x3f, 0x42, 0x3b, 0x41, 0xbd, 0x28, 0x89, 0xbd, 0x84, 0xb6, 0xb2, 0x56, 0x1c,
+	0x8c, 0x82, 0x71, 0x94, 0x78, 0xc4, 0x2e, 0x20, 0x44, 0xa5, 0xa4, 0x8a, 0x8f, 0x47, 0xc1, 0xb8,
+	0x9b, 0x38, 0xc0, 0xff, 0x06, 0x70, 0x9e, 0x60, 0x5e, 0x68, 0x42, 0x95, 0xe0, 0xaf, 0x05, 0x6a,
+	0x62, 0x0c, 0x5a, 0x95, 0x98, 0xa3, 0xcd, 0xef, 0x26, 0xd6, 0x36, 0xd9, 0x54, 0x50, 0x89, 0x4d,
+	0xb6, 0x05, 0x26, 0x32, 0x43, 0x9d, 0xc6, 0x27, 0x2e, 0xd2, 0xd8, 0xec, 0x35, 0xf4, 0x32, 0x9c,
+	0x89, 0x45, 0x49, 0xdf, 0x64, 0x35, 0x2b, 0xf2, 0xb8, 0x65, 0x1f, 0x37, 0x9d, 0x2c, 0x86, 0x8e,
+	0xc8, 0x32, 0x85, 0x5a, 0xc7, 0xa1, 0x7d, 0x6f, 0x20, 0x7b, 0x06, 0x27, 0x75, 0x91, 0xc5, 0xed,
+	0x51, 0x30, 0x0e, 0x13, 0x63, 0x9a, 0x89, 0x74, 0xfa, 0x88, 0x73, 0x11, 0x77, 0x6c, 0xa8, 0x47,
+	0xfc, 0x2b, 0x0c, 0xee, 0x2b, 0x75, 0x40, 0xf3, 0x4f, 0xc8, 0x8e, 0x37, 0xc8, 0xf8, 0x15, 0x9c,
+	0x7f, 0xaf, 0x34, 0x89, 0x2a, 0xc5, 0xa6, 0xc0, 0x10, 0xa2, 0xc2, 0xbb, 0x7c, 0x91, 0x15, 0xe6,
+	0x02, 0x7a, 0xae, 0xff, 0x03, 0x82, 0xd9, 0x25, 0x74, 0x0d, 0xbb, 0xae, 0x45, 0xda, 0xac, 0x6d,
+	0xed, 0x30, 0x43, 0xa5, 0x6e, 0x3f, 0x6e, 0x79, 0x1e, 0xf1, 0x2f, 0xd0, 0x9f, 0x92, 0xa0, 0x85,
+	0x4e, 0x50, 0xd7, 0xb2, 0xd2, 0x36, 0x52, 0x5b, 0x8f, 0x65, 0x08, 0x13, 0x8f, 0xf6, 0x1c, 0x14,
+	0x61, 0xf0, 0x53, 0xea, 0x82, 0x0a, 0x59, 0xad, 0x4b, 0x5c, 0x42, 0xb7, 0x6e, 0x9c, 0xbe, 0xcf,
+	0xb5, 0xc3, 0x10, 0xcc, 0x44, 0x51, 0x62, 0xe6, 0x2b, 0x79, 0x64, 0x86, 0x7b, 0x10, 0xa5, 0x99,
+	0x45, 0xfb, 0x26, 0x57, 0x98, 0xf7, 0xe0, 0xf4, 0x4e, 0xe6, 0xda, 0xef, 0x81, 0xff, 0x80, 0xe8,
+	0x4e, 0xe6, 0xb7, 0x15, 0xa9, 0xa5, 0xb9, 0x00, 0x15, 0xfe, 0x02, 0x27, 0x89, 0xb5, 0x4d, 0xaf,
+	0x25, 0xfe, 0xc6, 0xd2, 0x32, 0x84, 0x89, 0x03, 0xe6, 0x2e, 0x73, 0xd4, 0x5a, 0xe4, 0xe8, 0xeb,
+	0x37, 0xf0, 0x9a, 0x20, 0x72, 0xaa, 0x54, 0x4b, 0xf6, 0xbe, 0xb1, 0x51, 0xb1, 0x17, 0x13, 0x23,
+	0xfb, 0xc9, 0x96, 0x62, 0x87, 0x67, 0x8d, 0xdb, 0x28, 0x9d, 0x1f, 0xb1, 0x0f, 0x00, 0x6b, 0x65,
+	0xb0, 0x57, 0xee, 0x75, 0x47, 0x2b, 0xdb, 0x69, 0xd7, 0xff, 0x8e, 0x21, 0x9a, 0xfa, 0xcf, 0x8c,
+	0xbd, 0x85, 0x70, 0x4a, 0x42, 0x11, 0x7b, 0xee, 0xa2, 0x36, 0x0e, 0xbf, 0xc3, 0x78, 0x05, 0xad,
+	0x29, 0xc9, 0xba, 0x69, 0x70, 0x4b, 0x54, 0x3b, 0xe1, 0x37, 0x70, 0x76, 0x5f, 0x67, 0x82, 0xd0,
+	0x7f, 0x0e, 0x07, 0x71, 0x7c, 0x82, 0xb6, 0x93, 0xc6, 0x3e, 0x96, 0x0b, 0xe7, 0xde, 0xd4, 0x0f,
+	0x3f, 0x62, 0x9f, 0xa1, 0xbb, 0xd2, 0xc4, 0xbe, 0x5c, 0xbf, 0xa4, 0x1d, 0xed, 0xb8, 0xd9, 0xcc,
+	0xad, 0xd9, 0xc0, 0x85, 0x3c, 0xb9, 0xfb, 0xb0, 0xbf, 0x72, 0xd9, 0xdb, 0xf3, 0xa3, 0x77, 0x01,
+	0x7b, 0x03, 0xd1, 0xf4, 0x71, 0x41, 0x99, 0xfc, 0x53, 0xb1, 0x53, 0xf7, 0x6e, 0xff, 0x51, 0xdb,
+	0xf3, 0x3c, 0xb4, 0xed, 0x9f, 0xec, 0xe6, 0xff, 0x00, 0xec, 0xab, 0xfb, 0x6f, 0xdb, 0x04, 0x00,
+	0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// RegistryClient is the client API for Registry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RegistryClient interface {
+	// Register is also called periodically as the heartbeat
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*Result, error)
+	Unregister(ctx context.Context, in *UnregisterRequest, opts ...grpc.CallOption) (*Result, error)
+}
+
+type registryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRegistryClient(cc grpc.ClientConnInterface) RegistryClient {
+	return &registryClient{cc}
+}
+
+func (c *registryClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/gprc.Registry/Register", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) Unregister(ctx context.Context, in *UnregisterRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/gprc.Registry/Unregister", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegistryServer is the server API for Registry service.
+type RegistryServer interface {
+	// Register is also called periodically as the heartbeat
+	Register(context.Context, *RegisterRequest) (*Result, error)
+	Unregister(context.Context, *UnregisterRequest) (*Result, error)
+}
+
+// UnimplementedRegistryServer can be embedded to have forward compatible implementations.
+type UnimplementedRegistryServer struct {
+}
+
+func (*UnimplementedRegistryServer) Register(ctx context.Context, req *RegisterRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (*UnimplementedRegistryServer) Unregister(ctx context.Context, req *UnregisterRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unregister not implemented")
+}
+
+func RegisterRegistryServer(s *grpc.Server, srv RegistryServer) {
+	s.RegisterService(&_Registry_serviceDesc, srv)
+}
+
+func _Registry_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gprc.Registry/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_Unregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnregisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).Unregister(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gprc.Registry/Unregister",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).Unregister(ctx, req.(*UnregisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Registry_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gprc.Registry",
+	HandlerType: (*RegistryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Registry_Register_Handler,
+		},
+		{
+			MethodName: "Unregister",
+			Handler:    _Registry_Unregister_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "strategy.proto",
+}
+
+// StrategyClient is the client API for Strategy service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type StrategyClient interface {
+	Start(ctx context.Context, in *ConfigRequest, opts ...grpc.CallOption) (*Result, error)
+	Stop(ctx context.Context, in *InstanceRequest, opts ...grpc.CallOption) (*Result, error)
+	UpdateConfig(ctx context.Context, in *ConfigRequest, opts ...grpc.CallOption) (*Result, error)
+	Status(ctx context.Context, in *InstanceRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	Positions(ctx context.Context, in *InstanceRequest, opts ...grpc.CallOption) (*PositionsResponse, error)
+	Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (Strategy_LogsClient, error)
+	// Shutdown stops all the instances and exits the process
+	Shutdown(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Result, error)
+}
+
+type strategyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStrategyClient(cc grpc.ClientConnInterface) StrategyClient {
+	return &strategyClient{cc}
+}
+
+func (c *strategyClient) Start(ctx context.Context, in *ConfigRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/gprc.Strategy/Start", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strategyClient) Stop(ctx context.Context, in *InstanceRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/gprc.Strategy/Stop", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strategyClient) UpdateConfig(ctx context.Context, in *ConfigRequest, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/gprc.Strategy/UpdateConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strategyClient) Status(ctx context.Context, in *InstanceRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, "/gprc.Strategy/Status", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strategyClient) Positions(ctx context.Context, in *InstanceRequest, opts ...grpc.CallOption) (*PositionsResponse, error) {
+	out := new(PositionsResponse)
+	err := c.cc.Invoke(ctx, "/gprc.Strategy/Positions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strategyClient) Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (Strategy_LogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Strategy_serviceDesc.Streams[0], "/gprc.Strategy/Logs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &strategyLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Strategy_LogsClient interface {
+	Recv() (*LogEntry, error)
+	grpc.ClientStream
+}
+
+type strategyLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *strategyLogsClient) Recv() (*LogEntry, error) {
+	m := new(LogEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *strategyClient) Shutdown(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/gprc.Strategy/Shutdown", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StrategyServer is the server API for Strategy service.
+type StrategyServer interface {
+	Start(context.Context, *ConfigRequest) (*Result, error)
+	Stop(context.Context, *InstanceRequest) (*Result, error)
+	UpdateConfig(context.Context, *ConfigRequest) (*Result, error)
+	Status(context.Context, *InstanceRequest) (*StatusResponse, error)
+	Positions(context.Context, *InstanceRequest) (*PositionsResponse, error)
+	Logs(*LogsRequest, Strategy_LogsServer) error
+	// Shutdown stops all the instances and exits the process
+	Shutdown(context.Context, *Empty) (*Result, error)
+}
+
+// UnimplementedStrategyServer can be embedded to have forward compatible implementations.
+type UnimplementedStrategyServer struct {
+}
+
+func (*UnimplementedStrategyServer) Start(ctx context.Context, req *ConfigRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Start not implemented")
+}
+func (*UnimplementedStrategyServer) Stop(ctx context.Context, req *InstanceRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
+}
+func (*UnimplementedStrategyServer) UpdateConfig(ctx context.Context, req *ConfigRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateConfig not implemented")
+}
+func (*UnimplementedStrategyServer) Status(ctx context.Context, req *InstanceRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (*UnimplementedStrategyServer) Positions(ctx context.Context, req *InstanceRequest) (*PositionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Positions not implemented")
+}
+func (*UnimplementedStrategyServer) Logs(req *LogsRequest, srv Strategy_LogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method Logs not implemented")
+}
+func (*UnimplementedStrategyServer) Shutdown(ctx context.Context, req *Empty) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Shutdown not implemented")
+}
+
+func RegisterStrategyServer(s *grpc.Server, srv StrategyServer) {
+	s.RegisterService(&_Strategy_serviceDesc, srv)
+}
+
+func _Strategy_Start_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrategyServer).Start(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gprc.Strategy/Start",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrategyServer).Start(ctx, req.(*ConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strategy_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrategyServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gprc.Strategy/Stop",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrategyServer).Stop(ctx, req.(*InstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strategy_UpdateConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrategyServer).UpdateConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gprc.Strategy/UpdateConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrategyServer).UpdateConfig(ctx, req.(*ConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strategy_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrategyServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gprc.Strategy/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrategyServer).Status(ctx, req.(*InstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strategy_Positions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrategyServer).Positions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gprc.Strategy/Positions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrategyServer).Positions(ctx, req.(*InstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strategy_Logs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StrategyServer).Logs(m, &strategyLogsServer{stream})
+}
+
+type Strategy_LogsServer interface {
+	Send(*LogEntry) error
+	grpc.ServerStream
+}
+
+type strategyLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *strategyLogsServer) Send(m *LogEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Strategy_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrategyServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gprc.Strategy/Shutdown",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrategyServer).Shutdown(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Strategy_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gprc.Strategy",
+	HandlerType: (*StrategyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Start",
+			Handler:    _Strategy_Start_Handler,
+		},
+		{
+			MethodName: "Stop",
+			Handler:    _Strategy_Stop_Handler,
+		},
+		{
+			MethodName: "UpdateConfig",
+			Handler:    _Strategy_UpdateConfig_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _Strategy_Status_Handler,
+		},
+		{
+			MethodName: "Positions",
+			Handler:    _Strategy_Positions_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _Strategy_Shutdown_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Logs",
+			Handler:       _Strategy_Logs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "strategy.proto",
+}
@@ -7,6 +7,7 @@ import (
 
 	Config "madaoQT/config"
 	Server "madaoQT/server"
+	Task "madaoQT/task"
 	Utils "madaoQT/utils"
 
 	"github.com/kataras/golog"
@@ -31,6 +32,17 @@ func handleCmd() {
 		case "q":
 			Logger.Info("Exiting...")
 			os.Exit(0)
+		case "kill":
+			// 冻结所有任务的新订单并撤销挂单
+			Task.GlobalKillSwitch.Engage(Task.KillSourceConsole, "console", false)
+		case "flatten":
+			// 熔断并平掉所有任务的仓位
+			Task.GlobalKillSwitch.Engage(Task.KillSourceConsole, "console", true)
+		case "resume":
+			Task.GlobalKillSwitch.Release(Task.KillSourceConsole)
+		case "status":
+			state := Task.GlobalKillSwitch.State()
+			Logger.Infof("熔断:%v 来源:%s 原因:%s 时间:%v", state.Engaged, state.Source, state.Reason, state.Updated)
 		}
 	}
 
@@ -42,6 +54,22 @@ func main() {
 	go handleCmd()
 
 	http := new(Server.HttpServer)
+	http.SetupTasks()
+	// the kill switch is restored before the tasks, so that the orders are frozen once they start
+	if err := Task.GlobalKillSwitch.Restore(); err != nil {
+		Logger.Errorf("Fail to restore the kill switch:%v", err)
+	}
+	if err := http.Tasks.Restore(); err != nil {
+		Logger.Errorf("Fail to restore the tasks:%v", err)
+	}
+	if err := http.Scheduler.Start(); err != nil {
+		Logger.Errorf("Fail to start the scheduler:%v", err)
+	}
+
+	registry := &Task.Registry{Manager: http.Tasks}
+	if err := registry.Serve(Config.RegistryAddress); err != nil {
+		Logger.Errorf("Fail to start the registry of the strategy processes:%v", err)
+	}
 	go http.SetupHttpServer()
 
 	if Config.ProductionEnv {
@@ -1,23 +1,19 @@
 package mongo
 
 import (
-	"fmt"
 	"errors"
-	"log"
-
-	mgo "gopkg.in/mgo.v2"
-	bson "gopkg.in/mgo.v2/bson"
 )
 
+const defaultChartCount = 1000
+
 type Charts struct {
-	session *mgo.Session
-	collection *mgo.Collection
+	klines *Klines
 
 	Charts []ChartItem
 }
 
 type ChartItem struct {
-	Name			string `json:"name"`
+	Name            string  `json:"name"`
 	Date            int64   `json:"date"`
 	Hm              string  `json:"hm"`
 	High            float64 `json:"high"`
@@ -27,42 +23,51 @@ type ChartItem struct {
 	Volume          float64 `json:"volume"`
 	QuoteVolume     float64 `json:"quoteVolume"`
 	WeightedAverage float64 `json:"weightedAverage"`
-	Exchange		string `json:"exchange"`
+	Exchange        string  `json:"exchange"`
 }
 
 func (t *Charts) Connect() error {
-	session, err := mgo.Dial(MongoURL)
-	if err != nil {
-		fmt.Println("Connect to redis error", err)
+	klines := new(Klines)
+	if err := klines.Connect(); err != nil {
 		return err
 	}
-	session.SetMode(mgo.Monotonic, true)
-	c := session.DB(Database).C(ChartCollectin)
-
-	t.session = session
-	t.collection = c
 
+	t.klines = klines
 	return nil
 }
 
 func (t *Charts) Close() {
-	if t.session != nil {
-		t.session.Close()
+	if t.klines != nil {
+		t.klines.Close()
 	}
 }
 
+// LoadCharts loads the latest klines of the period(in minutes) from the kline collection
 func (t *Charts) LoadCharts(exchange string, name string, period int) error {
 
-	if t.collection == nil {
-		return errors.New("Mongo is not connected")
+	if t.klines == nil {
+		return errors.New(ErrorNotConnected)
 	}
 
-	t.Charts = []ChartItem{}
-	t.collection.Find(bson.M{"exchange": exchange, "name": name}).All(&t.Charts);
+	records, err := t.klines.FindLatest(exchange, name, period, defaultChartCount)
+	if err != nil {
+		return err
+	}
 
-	for i := 0; i < len(t.Charts); i++{
-		log.Printf("chart:%v",t.Charts[i])
+	t.Charts = make([]ChartItem, len(records))
+	for i, record := range records {
+		t.Charts[i] = ChartItem{
+			Name:     record.Symbol,
+			Date:     record.OpenTime.Unix(),
+			Hm:       record.OpenTime.Format("15:04"),
+			High:     record.High,
+			Low:      record.Low,
+			Open:     record.Open,
+			Close:    record.Close,
+			Volume:   record.Volume,
+			Exchange: record.Exchange,
+		}
 	}
 
-	return nil;
-}
\ No newline at end of file
+	return nil
+}
@@ -2,8 +2,43 @@ package mongo
 
 import (
 	"testing"
+
+	"gopkg.in/mgo.v2/bson"
 )
 
 func _TestAddRecrod(t *testing.T) {
 
 }
+
+func TestAccountCondition(t *testing.T) {
+	condition := accountCondition("okex", "")
+	accounts, ok := condition["account"].(bson.M)
+	if !ok || condition["name"] != "okex" || len(accounts["$in"].([]interface{})) != 2 {
+		t.Errorf("The default account should match the records without the label:%v", condition)
+	}
+	if condition = accountCondition("okex", "sub1"); condition["account"] != "sub1" {
+		t.Errorf("Invalid condition:%v", condition)
+	}
+}
+
+func _TestAccounts(t *testing.T) {
+	db := &ExchangeDB{}
+	if err := db.Connect(); err != nil {
+		t.Fatalf("Fail to connect:%v", err)
+	}
+	defer db.Close()
+
+	for _, account := range []string{"", "sub1"} {
+		if err := db.Upsert(&ExchangeInfo{Name: "test", Account: account, API: []byte(account)}); err != nil {
+			t.Fatalf("Fail to save the keys:%v", err)
+		}
+	}
+	if err, record := db.FindAccount("test", "sub1"); err != nil || string(record.API) != "sub1" {
+		t.Errorf("Invalid keys of the account:%v %v", record, err)
+	}
+	if err, record := db.FindOne("test"); err != nil || record.Account != "" {
+		t.Errorf("Invalid keys of the default account:%v %v", record, err)
+	}
+	db.Remove("test", "")
+	db.Remove("test", "sub1")
+}
@@ -13,6 +13,8 @@ import (
 
 type ExchangeInfo struct {
 	Name string
+	// Account the label of the account or the sub-account, the empty one is the default account of the exchange
+	Account string
 	/* User password encrypted */
 	API []byte
 	/* User password encrypted */
@@ -92,10 +94,23 @@ func (t *ExchangeDB) Insert(record *ExchangeInfo) error {
 	return errors.New("Connection is lost")
 }
 
+// FindOne the keys of the default account
 func (t *ExchangeDB) FindOne(name string) (error, *ExchangeInfo) {
+	return t.FindAccount(name, "")
+}
+
+// accountCondition the records saved before the accounts are labeled belong to the default account
+func accountCondition(name string, account string) bson.M {
+	if account == "" {
+		return bson.M{"name": name, "account": bson.M{"$in": []interface{}{"", nil}}}
+	}
+	return bson.M{"name": name, "account": account}
+}
+
+func (t *ExchangeDB) FindAccount(name string, account string) (error, *ExchangeInfo) {
 	result := &ExchangeInfo{}
 	if t.session != nil {
-		err := t.collection.Find(bson.M{"name": name}).One(&result)
+		err := t.collection.Find(accountCondition(name, account)).One(&result)
 		if err != nil {
 			return err, nil
 		}
@@ -105,3 +120,33 @@ func (t *ExchangeDB) FindOne(name string) (error, *ExchangeInfo) {
 
 	return errors.New("Connection is lost"), nil
 }
+
+// FindAll the keys of all the accounts, sorted by the exchange and the account
+func (t *ExchangeDB) FindAll() (error, []ExchangeInfo) {
+	var result []ExchangeInfo
+	if t.session != nil {
+		if err := t.collection.Find(nil).Sort("name", "account").All(&result); err != nil {
+			return err, nil
+		}
+		return nil, result
+	}
+
+	return errors.New("Connection is lost"), nil
+}
+
+// Upsert replaces the keys of the account
+func (t *ExchangeDB) Upsert(record *ExchangeInfo) error {
+	if t.session != nil {
+		_, err := t.collection.Upsert(accountCondition(record.Name, record.Account), record)
+		return err
+	}
+	return errors.New("Connection is lost")
+}
+
+func (t *ExchangeDB) Remove(name string, account string) error {
+	if t.session != nil {
+		_, err := t.collection.RemoveAll(accountCondition(name, account))
+		return err
+	}
+	return errors.New("Connection is lost")
+}
@@ -0,0 +1,94 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const ExecutionCollection = "Executions"
+
+// ExecutionRecord the prices of the order from the decision to the fill
+type ExecutionRecord struct {
+	Task     string    `json:"task"`
+	Batch    string    `json:"batch"`
+	Exchange string    `json:"exchange"`
+	Pair     string    `json:"pair"`
+	Oper     string    `json:"oper"`
+	Time     time.Time `json:"time"`
+	// Decision the price when the task decided to trade
+	Decision float64 `json:"decision"`
+	// Arrival the mid of the depth when the order is placed, 0 if the depth isn't available
+	Arrival float64 `json:"arrival"`
+	// Placed the limit price of the order
+	Placed     float64 `json:"placed"`
+	AvgPrice   float64 `json:"avgprice"`
+	Amount     float64 `json:"amount"`
+	DealAmount float64 `json:"dealamount"`
+	// Latency the milliseconds from the placing to the fill
+	Latency int64 `json:"latency"`
+}
+
+type Executions struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultExecutionDBConfig = &DBConfig{
+	CollectionName: ExecutionCollection,
+}
+
+func (e *Executions) Connect() error {
+	session, err := Dial(e.Server, e.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if e.Config == nil {
+		e.Config = defaultExecutionDBConfig
+	}
+
+	e.session = session
+	e.collection = session.DB(Database).C(e.Config.CollectionName)
+
+	return nil
+}
+
+func (e *Executions) Close() {
+	if e.session != nil {
+		e.session.Close()
+		e.session = nil
+	}
+}
+
+func (e *Executions) Insert(record *ExecutionRecord) error {
+	if e.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+	return e.collection.Insert(record)
+}
+
+// Find the records since the time, the empty task matches all the tasks
+func (e *Executions) Find(task string, since time.Time) (error, []ExecutionRecord) {
+	if e.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	conditions := bson.M{"time": bson.M{"$gte": since}}
+	if task != "" {
+		conditions["task"] = task
+	}
+
+	var records []ExecutionRecord
+	if err := e.collection.Find(conditions).Sort("time").All(&records); err != nil {
+		return err, nil
+	}
+	return nil, records
+}
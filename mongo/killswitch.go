@@ -0,0 +1,91 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+)
+
+const KillSwitchCollection = "KillSwitch"
+
+// killSwitchID there is only one global switch
+const killSwitchID = "global"
+
+// KillSwitchState the state of the global kill switch, which is kept after restarting
+type KillSwitchState struct {
+	ID      string    `json:"-" bson:"_id"`
+	Engaged bool      `json:"engaged"`
+	Flatten bool      `json:"flatten"`
+	Source  string    `json:"source"`
+	Reason  string    `json:"reason"`
+	Updated time.Time `json:"updated"`
+}
+
+type KillSwitches struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultKillSwitchDBConfig = &DBConfig{
+	CollectionName: KillSwitchCollection,
+}
+
+func (k *KillSwitches) Connect() error {
+	session, err := Dial(k.Server, k.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if k.Config == nil {
+		k.Config = defaultKillSwitchDBConfig
+	}
+
+	k.session = session
+	k.collection = session.DB(Database).C(k.Config.CollectionName)
+
+	return nil
+}
+
+func (k *KillSwitches) Close() {
+	if k.session != nil {
+		k.session.Close()
+		k.session = nil
+	}
+}
+
+// Save replaces the state of the switch
+func (k *KillSwitches) Save(state *KillSwitchState) error {
+	if k.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	state.ID = killSwitchID
+	if state.Updated.IsZero() {
+		state.Updated = time.Now()
+	}
+	_, err := k.collection.UpsertId(killSwitchID, state)
+	return err
+}
+
+// Load returns the released state if the switch is never saved
+func (k *KillSwitches) Load() (error, *KillSwitchState) {
+	if k.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	state := &KillSwitchState{}
+	if err := k.collection.FindId(killSwitchID).One(state); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, &KillSwitchState{ID: killSwitchID}
+		}
+		return err, nil
+	}
+
+	return nil, state
+}
@@ -0,0 +1,150 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// KlineRecord one kline of the pair in the exchange, Period is in minutes
+type KlineRecord struct {
+	Exchange  string    `json:"exchange"`
+	Symbol    string    `json:"symbol"`
+	Period    int       `json:"period"`
+	OpenTime  time.Time `json:"opentime"`
+	CloseTime time.Time `json:"closetime"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+	Volume    float64   `json:"volume"`
+}
+
+// 该类用于保存K线数据，所有的任务和图表都从此处读取历史数据
+
+type Klines struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultKlineDBConfig = &DBConfig{
+	CollectionName: KlineCollection,
+}
+
+func (t *Klines) Connect() error {
+	session, err := Dial(t.Server, t.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if t.Config == nil {
+		t.Config = defaultKlineDBConfig
+	}
+
+	c := session.DB(Database).C(t.Config.CollectionName)
+	if err := c.EnsureIndex(mgo.Index{
+		Key:        []string{"exchange", "symbol", "period", "opentime"},
+		Unique:     true,
+		Background: true,
+	}); err != nil {
+		session.Close()
+		return err
+	}
+
+	t.session = session
+	t.collection = c
+
+	return nil
+}
+
+func (t *Klines) Close() {
+	if t.session != nil {
+		t.session.Close()
+		t.session = nil
+	}
+}
+
+func (t *Klines) Refresh() {
+	if t.session != nil {
+		t.session.Refresh()
+	}
+}
+
+// Upsert inserts the klines or replaces the ones with the same open time
+func (t *Klines) Upsert(records []KlineRecord) error {
+	if t.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	bulk := t.collection.Bulk()
+	bulk.Unordered()
+	for i := range records {
+		record := records[i]
+		bulk.Upsert(bson.M{
+			"exchange": record.Exchange,
+			"symbol":   record.Symbol,
+			"period":   record.Period,
+			"opentime": record.OpenTime,
+		}, record)
+	}
+
+	_, err := bulk.Run()
+	return err
+}
+
+// FindRange returns the klines whose open time is in [start, end), sorted by time ascending
+func (t *Klines) FindRange(exchange string, symbol string, period int, start time.Time, end time.Time) ([]KlineRecord, error) {
+	var result []KlineRecord
+	if t.session == nil {
+		return nil, errors.New(ErrorNotConnected)
+	}
+
+	err := t.collection.Find(bson.M{
+		"exchange": exchange,
+		"symbol":   symbol,
+		"period":   period,
+		"opentime": bson.M{
+			"$gte": start,
+			"$lt":  end,
+		},
+	}).Sort("opentime").All(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// FindLatest returns the latest count klines, sorted by time ascending
+func (t *Klines) FindLatest(exchange string, symbol string, period int, count int) ([]KlineRecord, error) {
+	var result []KlineRecord
+	if t.session == nil {
+		return nil, errors.New(ErrorNotConnected)
+	}
+
+	err := t.collection.Find(bson.M{
+		"exchange": exchange,
+		"symbol":   symbol,
+		"period":   period,
+	}).Sort("-opentime").Limit(count).All(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKlinesUpsert(t *testing.T) {
+	klines := &Klines{
+		Config: &DBConfig{
+			CollectionName: "KlinesTest",
+		},
+	}
+
+	if err := klines.Connect(); err != nil {
+		t.Skipf("Mongo isn't available:%v", err)
+	}
+
+	defer klines.Close()
+
+	start := time.Date(2018, 3, 5, 0, 0, 0, 0, time.UTC)
+	var records []KlineRecord
+	for i := 0; i < 10; i++ {
+		records = append(records, KlineRecord{
+			Exchange: "test",
+			Symbol:   "eth/usdt",
+			Period:   60,
+			OpenTime: start.Add(time.Duration(i) * time.Hour),
+			Close:    float64(i),
+		})
+	}
+
+	// upsert twice should not duplicate the records
+	if err := klines.Upsert(records); err != nil {
+		t.Fatalf("Error:%v", err)
+	}
+
+	records[9].Close = 100
+	if err := klines.Upsert(records); err != nil {
+		t.Fatalf("Error:%v", err)
+	}
+
+	latest, err := klines.FindLatest("test", "eth/usdt", 60, 3)
+	if err != nil || len(latest) != 3 || latest[2].Close != 100 {
+		t.Errorf("Invalid latest records:%v %v", latest, err)
+	}
+
+	ranges, err := klines.FindRange("test", "eth/usdt", 60, start, start.Add(5*time.Hour))
+	if err != nil || len(ranges) != 5 {
+		t.Errorf("Invalid range records:%v %v", ranges, err)
+	}
+}
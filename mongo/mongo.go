@@ -1,9 +1,15 @@
 package mongo
 
 import (
+	"errors"
+	"net"
+	"strings"
+
 	Global "madaoQT/config"
 
 	"github.com/kataras/golog"
+	"golang.org/x/net/proxy"
+	mgo "gopkg.in/mgo.v2"
 )
 
 const MongoURL = "mongodb://localhost"
@@ -17,6 +23,7 @@ const ExchangeCollection = "Exchanges"
 const BalancesCollection = "Balances"
 const FundCollection = "Funds"
 const OkexDiffHistory = "OkexDiffHistory"
+const KlineCollection = "Klines"
 
 const ErrorNotConnected = "Mongo is not connected"
 
@@ -33,3 +40,36 @@ func init() {
 	Logger.SetTimeFormat(Global.TimeFormat)
 	Logger.SetPrefix("[MONG]")
 }
+
+// Dial connects to the server(MongoURL if empty), through the proxy like "SOCKS5:127.0.0.1:1080" if assigned
+func Dial(server string, sock5Proxy string) (*mgo.Session, error) {
+
+	if server == "" {
+		server = MongoURL
+	}
+
+	if sock5Proxy == "" {
+		return mgo.Dial(server)
+	}
+
+	dialInfo, err := mgo.ParseURL(server)
+	if err != nil {
+		return nil, err
+	}
+
+	dialInfo.DialServer = func(addr *mgo.ServerAddr) (net.Conn, error) {
+		values := strings.Split(sock5Proxy, ":")
+		if values[0] == "SOCKS5" {
+			dialer, err := proxy.SOCKS5("tcp", values[1]+":"+values[2], nil, proxy.Direct)
+			if err != nil {
+				return nil, err
+			}
+
+			return dialer.Dial("tcp", addr.String())
+		}
+
+		return nil, errors.New("Invalid protocal")
+	}
+
+	return mgo.DialWithInfo(dialInfo)
+}
@@ -41,6 +41,25 @@ type FundInfo struct {
 	FutureOpen   float64 `json:"futureopen"`
 	FutureClose  float64 `json:"futureclose"`
 	FutureAmount float64 `json:"futureamount"`
+
+	// Steps the recovery of the legs which aren't filled equally
+	Steps []FundStep `json:"steps,omitempty"`
+}
+
+const FundStepComplete = "complete"
+const FundStepUnwind = "unwind"
+
+// FundStep the order placed to complete the missing leg or to unwind the filled leg
+type FundStep struct {
+	Time       time.Time `json:"time"`
+	Leg        string    `json:"leg"`
+	Action     string    `json:"action"`
+	Type       string    `json:"type"`
+	Price      float64   `json:"price"`
+	Amount     float64   `json:"amount"`
+	DealAmount float64   `json:"dealamount"`
+	AvgPrice   float64   `json:"avgprice"`
+	Error      string    `json:"error,omitempty"`
 }
 
 type Funds struct {
@@ -106,6 +125,15 @@ func (t *Funds) Update(conditions map[string]interface{}, update map[string]inte
 	return errors.New("Connection is lost")
 }
 
+// AddStep appends the recovery step to the record of the batch
+func (t *Funds) AddStep(batch string, step FundStep) error {
+	if t.session != nil {
+		_, err := t.collection.UpdateAll(bson.M{"batch": batch}, bson.M{"$push": bson.M{"steps": step}})
+		return err
+	}
+	return errors.New("Connection is lost")
+}
+
 func (t *Funds) GetDailySuccessRecords(date time.Time) (error, []FundInfo) {
 	var result []FundInfo
 	var start, end time.Time
@@ -0,0 +1,123 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const ProtectionCollection = "Protections"
+
+const (
+	ProtectionStatusActive    = "active"
+	ProtectionStatusTriggered = "triggered"
+	ProtectionStatusCancelled = "cancelled"
+	ProtectionStatusError     = "error"
+)
+
+// ProtectionRecord the stop loss, the take profit and the trailing stop of the position
+type ProtectionRecord struct {
+	ID string `json:"id" bson:"_id"`
+	// Task the instance which opened the position
+	Task string `json:"task"`
+	// Source, Venue and Account decide how to create the exchange when the position is monitored locally
+	Source  string                 `json:"source"`
+	Venue   string                 `json:"venue"`
+	Account string                 `json:"account,omitempty"`
+	Custom  map[string]interface{} `json:"custom,omitempty"`
+	Proxy   string                 `json:"proxy,omitempty"`
+
+	Pair string `json:"pair"`
+	// CloseType the trade type which closes the position
+	CloseType int     `json:"closetype"`
+	Amount    float64 `json:"amount"`
+	Entry     float64 `json:"entry"`
+	TradeID   string  `json:"tradeid,omitempty"`
+
+	// the ratios from the entry price, 0 disables the rule
+	StopLoss   float64 `json:"stoploss"`
+	TakeProfit float64 `json:"takeprofit"`
+	Trailing   float64 `json:"trailing"`
+
+	// Native the rules are placed as the conditional orders of the exchange
+	Native bool              `json:"native"`
+	Orders map[string]string `json:"orders,omitempty"`
+	// Best the best price since the position is opened, which is used by the local trailing stop
+	Best float64 `json:"best"`
+	// Price the price when the protection is triggered, or the average price of the closing orders
+	Price float64 `json:"price,omitempty"`
+	// Closed the amount closed by the local monitor, the amount left is still protected
+	Closed float64 `json:"closed,omitempty"`
+
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+	Updated time.Time `json:"updated"`
+}
+
+type Protections struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultProtectionDBConfig = &DBConfig{
+	CollectionName: ProtectionCollection,
+}
+
+func (p *Protections) Connect() error {
+	session, err := Dial(p.Server, p.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if p.Config == nil {
+		p.Config = defaultProtectionDBConfig
+	}
+
+	p.session = session
+	p.collection = session.DB(Database).C(p.Config.CollectionName)
+
+	return nil
+}
+
+func (p *Protections) Close() {
+	if p.session != nil {
+		p.session.Close()
+		p.session = nil
+	}
+}
+
+// Save replaces the record by the id
+func (p *Protections) Save(record *ProtectionRecord) error {
+	if p.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	record.Updated = time.Now()
+	_, err := p.collection.UpsertId(record.ID, record)
+	return err
+}
+
+// FindActive the records which are still protecting the positions
+func (p *Protections) FindActive() (error, []ProtectionRecord) {
+	return p.Find(bson.M{"status": ProtectionStatusActive})
+}
+
+func (p *Protections) Find(conditions bson.M) (error, []ProtectionRecord) {
+	if p.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	var records []ProtectionRecord
+	if err := p.collection.Find(conditions).Sort("-time").All(&records); err != nil {
+		return err, nil
+	}
+	return nil, records
+}
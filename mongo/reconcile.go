@@ -0,0 +1,124 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const ReconcileCollection = "Reconciliations"
+
+const (
+	// ReconcileAmount the saved position differs from the exchange
+	ReconcileAmount = "amount"
+	// ReconcileOrphanPosition the position of the exchange isn't saved by the task
+	ReconcileOrphanPosition = "orphanposition"
+	// ReconcileOrphanOrder the open order isn't placed by the task
+	ReconcileOrphanOrder = "orphanorder"
+	// ReconcileBalance the balance is less than the saved positions hold
+	ReconcileBalance = "balance"
+)
+
+// ReconcileItem the discrepancy between the records of the task and the exchange
+type ReconcileItem struct {
+	Kind     string  `json:"kind"`
+	Exchange string  `json:"exchange"`
+	Pair     string  `json:"pair"`
+	Saved    float64 `json:"saved"`
+	Actual   float64 `json:"actual"`
+	OrderID  string  `json:"orderid,omitempty"`
+}
+
+// ReconcileRecord the last reconciliation of the task
+type ReconcileRecord struct {
+	Task  string          `json:"task" bson:"_id"`
+	Time  time.Time       `json:"time"`
+	Items []ReconcileItem `json:"items"`
+	// Adopted the discrepancies which are adopted into the records of the task
+	Adopted []ReconcileItem `json:"adopted,omitempty"`
+	// Acknowledged the task is started with the discrepancies
+	Acknowledged bool `json:"acknowledged"`
+	// Adopt the discrepancies are adopted in the next reconciliation
+	Adopt bool `json:"adopt"`
+	// Tolerance the ratio the amounts of the acknowledged discrepancies may change, 0 is the default ratio
+	Tolerance float64 `json:"tolerance,omitempty"`
+}
+
+type Reconciliations struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultReconcileDBConfig = &DBConfig{
+	CollectionName: ReconcileCollection,
+}
+
+func (r *Reconciliations) Connect() error {
+	session, err := Dial(r.Server, r.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if r.Config == nil {
+		r.Config = defaultReconcileDBConfig
+	}
+
+	r.session = session
+	r.collection = session.DB(Database).C(r.Config.CollectionName)
+
+	return nil
+}
+
+func (r *Reconciliations) Close() {
+	if r.session != nil {
+		r.session.Close()
+		r.session = nil
+	}
+}
+
+// Save replaces the record of the task
+func (r *Reconciliations) Save(record *ReconcileRecord) error {
+	if r.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	_, err := r.collection.UpsertId(record.Task, record)
+	return err
+}
+
+// Load returns nil if the task is never reconciled
+func (r *Reconciliations) Load(task string) (error, *ReconcileRecord) {
+	if r.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	record := &ReconcileRecord{}
+	if err := r.collection.FindId(task).One(record); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
+		return err, nil
+	}
+
+	return nil, record
+}
+
+// FindAll the records of all the tasks
+func (r *Reconciliations) FindAll() (error, []ReconcileRecord) {
+	if r.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	var records []ReconcileRecord
+	if err := r.collection.Find(bson.M{}).Sort("_id").All(&records); err != nil {
+		return err, nil
+	}
+	return nil, records
+}
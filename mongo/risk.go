@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const RiskCollection = "RiskRejections"
+
+// RiskRecord the order rejected by the pre-trade checks
+type RiskRecord struct {
+	Time     time.Time `json:"time"`
+	Task     string    `json:"task"`
+	Exchange string    `json:"exchange"`
+	Pair     string    `json:"pair"`
+	Oper     string    `json:"oper"`
+	Price    float64   `json:"price"`
+	Quantity float64   `json:"quantity"`
+	Rule     string    `json:"rule"`
+	Limit    float64   `json:"limit"`
+	Value    float64   `json:"value"`
+}
+
+type RiskRecords struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultRiskDBConfig = &DBConfig{
+	CollectionName: RiskCollection,
+}
+
+func (r *RiskRecords) Connect() error {
+	session, err := Dial(r.Server, r.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if r.Config == nil {
+		r.Config = defaultRiskDBConfig
+	}
+
+	r.session = session
+	r.collection = session.DB(Database).C(r.Config.CollectionName)
+
+	return nil
+}
+
+func (r *RiskRecords) Close() {
+	if r.session != nil {
+		r.session.Close()
+		r.session = nil
+	}
+}
+
+func (r *RiskRecords) Insert(record *RiskRecord) error {
+	if r.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	return r.collection.Insert(record)
+}
+
+// FindAll the rejections of the task, or of all the tasks if task is empty, the latest first
+func (r *RiskRecords) FindAll(task string, limit int) (error, []RiskRecord) {
+	var result []RiskRecord
+	if r.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	query := bson.M{}
+	if task != "" {
+		query["task"] = task
+	}
+
+	if err := r.collection.Find(query).Sort("-time").Limit(limit).All(&result); err != nil {
+		return err, nil
+	}
+
+	return nil, result
+}
@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const ScheduleCollection = "Schedules"
+
+// ScheduleRecord the last run of the scheduled job, the jobs created by the operators also save the definitions
+type ScheduleRecord struct {
+	Name   string `json:"name" bson:"_id"`
+	Spec   string `json:"spec"`
+	Desc   string `json:"desc"`
+	Action string `json:"action"`
+	TaskID string `json:"taskid" bson:"taskid"`
+	Config string `json:"config"`
+
+	LastRun   time.Time `json:"lastrun" bson:"lastrun"`
+	LastError string    `json:"lasterror" bson:"lasterror"`
+	// LastDuration in milliseconds
+	LastDuration int64     `json:"lastduration" bson:"lastduration"`
+	Updated      time.Time `json:"updated"`
+}
+
+type Schedules struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultScheduleDBConfig = &DBConfig{
+	CollectionName: ScheduleCollection,
+}
+
+func (s *Schedules) Connect() error {
+	session, err := Dial(s.Server, s.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if s.Config == nil {
+		s.Config = defaultScheduleDBConfig
+	}
+
+	s.session = session
+	s.collection = session.DB(Database).C(s.Config.CollectionName)
+
+	return nil
+}
+
+func (s *Schedules) Close() {
+	if s.session != nil {
+		s.session.Close()
+		s.session = nil
+	}
+}
+
+// Save inserts or replaces the definition of the job, the last run is kept
+func (s *Schedules) Save(record *ScheduleRecord) error {
+	if s.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	_, err := s.collection.UpsertId(record.Name, bson.M{"$set": bson.M{
+		"spec":    record.Spec,
+		"desc":    record.Desc,
+		"action":  record.Action,
+		"taskid":  record.TaskID,
+		"config":  record.Config,
+		"updated": time.Now(),
+	}})
+	return err
+}
+
+// SetLastRun records the last run of the job
+func (s *Schedules) SetLastRun(name string, lastRun time.Time, lastError string, duration time.Duration) error {
+	if s.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	_, err := s.collection.UpsertId(name, bson.M{"$set": bson.M{
+		"lastrun":      lastRun,
+		"lasterror":    lastError,
+		"lastduration": int64(duration / time.Millisecond),
+		"updated":      time.Now(),
+	}})
+	return err
+}
+
+func (s *Schedules) Remove(name string) error {
+	if s.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	return s.collection.RemoveId(name)
+}
+
+func (s *Schedules) FindAll() (error, []ScheduleRecord) {
+	var result []ScheduleRecord
+	if s.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	if err := s.collection.Find(nil).All(&result); err != nil {
+		return err, nil
+	}
+
+	return nil, result
+}
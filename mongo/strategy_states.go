@@ -0,0 +1,85 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+)
+
+const StrategyStateCollection = "StrategyStates"
+
+// StrategyState the state saved by the strategy, which is loaded when the strategy restarts
+type StrategyState struct {
+	ID      string    `json:"id" bson:"_id"`
+	State   string    `json:"state"`
+	Updated time.Time `json:"updated"`
+}
+
+type StrategyStates struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultStrategyStateDBConfig = &DBConfig{
+	CollectionName: StrategyStateCollection,
+}
+
+func (s *StrategyStates) Connect() error {
+	session, err := Dial(s.Server, s.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if s.Config == nil {
+		s.Config = defaultStrategyStateDBConfig
+	}
+
+	s.session = session
+	s.collection = session.DB(Database).C(s.Config.CollectionName)
+
+	return nil
+}
+
+func (s *StrategyStates) Close() {
+	if s.session != nil {
+		s.session.Close()
+		s.session = nil
+	}
+}
+
+// Save replaces the state of the strategy
+func (s *StrategyStates) Save(id string, state string) error {
+	if s.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	_, err := s.collection.UpsertId(id, &StrategyState{
+		ID:      id,
+		State:   state,
+		Updated: time.Now(),
+	})
+	return err
+}
+
+// Load returns the empty state if it is never saved
+func (s *StrategyStates) Load(id string) (error, string) {
+	if s.session == nil {
+		return errors.New(ErrorNotConnected), ""
+	}
+
+	var record StrategyState
+	if err := s.collection.FindId(id).One(&record); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ""
+		}
+		return err, ""
+	}
+
+	return nil, record.State
+}
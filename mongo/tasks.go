@@ -0,0 +1,150 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const TaskCollection = "Tasks"
+const TaskConfigAuditCollection = "TaskConfigAudits"
+
+const TaskStateRunning = "running"
+const TaskStateStopped = "stopped"
+
+// TaskDefinition the definition of the task instance, which is restored when the server starts
+type TaskDefinition struct {
+	ID     string `json:"id" bson:"_id"`
+	Name   string `json:"name"`
+	Config string `json:"config"`
+	// Desired the state the task should be in, running or stopped
+	Desired string `json:"desired"`
+
+	LastStart       time.Time `json:"laststart"`
+	LastStartReason string    `json:"laststartreason"`
+	LastStop        time.Time `json:"laststop"`
+	LastStopReason  string    `json:"laststopreason"`
+	Restarts        int       `json:"restarts"`
+	Updated         time.Time `json:"updated"`
+}
+
+// TaskConfigChange the changed field of the config, Old or New is nil if the field is added or removed
+type TaskConfigChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// TaskConfigAudit the record of the config update, Error is set if the update is rejected
+type TaskConfigAudit struct {
+	TaskID  string             `json:"taskid" bson:"taskid"`
+	Time    time.Time          `json:"time"`
+	Running bool               `json:"running"`
+	Old     string             `json:"old"`
+	New     string             `json:"new"`
+	Changes []TaskConfigChange `json:"changes"`
+	Error   string             `json:"error"`
+}
+
+type TaskDefinitions struct {
+	session    *mgo.Session
+	collection *mgo.Collection
+
+	Config     *DBConfig
+	Server     string
+	Sock5Proxy string
+}
+
+var defaultTaskDBConfig = &DBConfig{
+	CollectionName: TaskCollection,
+}
+
+func (t *TaskDefinitions) Connect() error {
+	session, err := Dial(t.Server, t.Sock5Proxy)
+	if err != nil {
+		Logger.Errorf("Connect to Mongo error:%v", err)
+		return err
+	}
+	session.SetMode(mgo.Monotonic, true)
+	if t.Config == nil {
+		t.Config = defaultTaskDBConfig
+	}
+
+	t.session = session
+	t.collection = session.DB(Database).C(t.Config.CollectionName)
+
+	return nil
+}
+
+func (t *TaskDefinitions) Close() {
+	if t.session != nil {
+		t.session.Close()
+		t.session = nil
+	}
+}
+
+// Save inserts or replaces the definition
+func (t *TaskDefinitions) Save(record *TaskDefinition) error {
+	if t.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	record.Updated = time.Now()
+	_, err := t.collection.UpsertId(record.ID, record)
+	return err
+}
+
+// Update sets the fields of the definition
+func (t *TaskDefinitions) Update(id string, update map[string]interface{}) error {
+	if t.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	update["updated"] = time.Now()
+	return t.collection.UpdateId(id, bson.M{"$set": bson.M(update)})
+}
+
+func (t *TaskDefinitions) Remove(id string) error {
+	if t.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	return t.collection.RemoveId(id)
+}
+
+func (t *TaskDefinitions) FindAll() (error, []TaskDefinition) {
+	var result []TaskDefinition
+	if t.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	if err := t.collection.Find(nil).Sort("updated").All(&result); err != nil {
+		return err, nil
+	}
+
+	return nil, result
+}
+
+func (t *TaskDefinitions) InsertAudit(record *TaskConfigAudit) error {
+	if t.session == nil {
+		return errors.New(ErrorNotConnected)
+	}
+
+	return t.session.DB(Database).C(TaskConfigAuditCollection).Insert(record)
+}
+
+// FindAudits returns the config updates of the task, the latest first
+func (t *TaskDefinitions) FindAudits(id string) (error, []TaskConfigAudit) {
+	var result []TaskConfigAudit
+	if t.session == nil {
+		return errors.New(ErrorNotConnected), nil
+	}
+
+	if err := t.session.DB(Database).C(TaskConfigAuditCollection).Find(bson.M{"taskid": id}).Sort("-time").All(&result); err != nil {
+		return err, nil
+	}
+
+	return nil, result
+}
@@ -19,6 +19,13 @@ type TradesRecord struct {
 	Quantity float64   `json:"quantity"`
 	OrderID  string    `json:"orderid"`
 	Status   string    `json:"status"`
+	// Decision and Arrival the price decided by the task and the mid of the depth when the order is placed
+	Decision float64 `json:"decision"`
+	Arrival  float64 `json:"arrival"`
+	// AvgPrice, DealAmount and Latency(ms) are set when the order is filled
+	AvgPrice   float64 `json:"avgprice"`
+	DealAmount float64 `json:"dealamount"`
+	Latency    int64   `json:"latency"`
 }
 
 const TradeStatusOpen = "open"
@@ -90,6 +97,20 @@ func (t *Trades) SetDone(orderID string) error {
 	return t.updateStatus(orderID, TradeStatusDone)
 }
 
+// SetFill the order is done with the fill
+func (t *Trades) SetFill(orderID string, avgPrice float64, dealAmount float64, latency int64) error {
+	if t.session != nil {
+		_, err := t.collection.UpdateAll(bson.M{"orderid": orderID}, bson.M{"$set": bson.M{
+			"status":     TradeStatusDone,
+			"avgprice":   avgPrice,
+			"dealamount": dealAmount,
+			"latency":    latency,
+		}})
+		return err
+	}
+	return nil
+}
+
 func (t *Trades) updateStatus(orderID string, status string) error {
 	if t.session != nil {
 		_, err := t.collection.UpdateAll(bson.M{"orderid": orderID}, bson.M{"$set": bson.M{"status": status}})
@@ -24,6 +24,8 @@ type TradeInfo struct {
 	FutureAmount float64 `json:"futureamount"`
 
 	Type int `json:"type"`
+	// OrderID the order or the trade which opened the position, the venues like FXCM and OANDA close the position by it
+	OrderID string `json:"orderid"`
 }
 
 type Trades struct {
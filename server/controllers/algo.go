@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/sessions"
+
+	Algo "madaoQT/task/algo"
+)
+
+type AlgoController struct {
+	Ctx iris.Context
+
+	Sessions *sessions.Sessions `iris:"persistence"`
+	Algos    *Algo.Manager      `iris:"persistence"`
+}
+
+// AlgoInfo the parent order controlled by the API
+type AlgoInfo struct {
+	ID string `json:"id"`
+}
+
+func (a *AlgoController) authen() (bool, iris.Map) {
+	if DEBUG {
+		return true, iris.Map{}
+	}
+	{
+		session := a.Sessions.Start(a.Ctx)
+		username := session.Get("name")
+		if username == nil || username == "" {
+			result := iris.Map{
+				"result": false,
+				"error":  errorCodeInvalidSession,
+			}
+			return false, result
+		}
+		return true, iris.Map{}
+	}
+}
+
+// GetList 获取执行算法母单的进度和成交统计
+// Get route: /algo/list
+func (a *AlgoController) GetList() iris.Map {
+
+	if ok, result := a.authen(); !ok {
+		return result
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   a.Algos.List(),
+	}
+}
+
+// PostSubmit 提交母单，VWAP按前几天的K线生成成交量分布
+// Post route: /algo/submit
+func (a *AlgoController) PostSubmit() iris.Map {
+
+	if ok, result := a.authen(); !ok {
+		return result
+	}
+
+	request := Algo.SubmitRequest{}
+	if err := a.Ctx.ReadJSON(&request); err != nil || request.Venue == "" || request.Order.Pair == "" {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	err, algo := Algo.Submit(request)
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   algo.Progress(),
+	}
+}
+
+func (a *AlgoController) control(action func(algo *Algo.Algo) error) iris.Map {
+
+	if ok, result := a.authen(); !ok {
+		return result
+	}
+
+	info := AlgoInfo{}
+	if err := a.Ctx.ReadJSON(&info); err != nil || info.ID == "" {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	algo := a.Algos.Get(info.ID)
+	if algo == nil {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	if err := action(algo); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   algo.Progress(),
+	}
+}
+
+// PostPause 暂停母单，未成交的子单会被撤销
+// Post route: /algo/pause
+func (a *AlgoController) PostPause() iris.Map {
+	return a.control((*Algo.Algo).Pause)
+}
+
+// PostResume 恢复母单，计划顺延暂停的时间
+// Post route: /algo/resume
+func (a *AlgoController) PostResume() iris.Map {
+	return a.control((*Algo.Algo).Resume)
+}
+
+// PostCancel 撤销母单和未成交的子单
+// Post route: /algo/cancel
+func (a *AlgoController) PostCancel() iris.Map {
+	return a.control((*Algo.Algo).Cancel)
+}
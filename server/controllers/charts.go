@@ -2,11 +2,13 @@ package controllers
 
 import (
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/kataras/iris"
 
+	Analytics "madaoQT/analytics"
 	"madaoQT/exchange"
 	Mongo "madaoQT/mongo"
 	MongoTrend "madaoQT/mongo/trend"
@@ -120,11 +122,28 @@ func (c *ChartsController) GetProfit() iris.Map {
 
 func (c *ChartsController) GetExamples() iris.Map {
 
-	var result []exchange.KlineValue
-
 	filename := "poloniex-ethusdt-2hour-20160101"
 
-	result = exchange.LoadHistory(filename)
+	db := new(Mongo.Klines)
+	if err := db.Connect(); err != nil {
+		return iris.Map{
+			"result": true,
+			"data":   exchange.LoadHistory(filename),
+		}
+	}
+
+	defer db.Close()
+
+	err, result := exchange.LoadKlines(db, "Poloniex", "eth/usdt", exchange.KlinePeriod2Hour, 1000)
+	if err == nil && len(result) == 0 {
+		// the history file saved by the previous versions is imported for the first time
+		if err = exchange.ImportHistory(db, "", filename, "Poloniex", "eth/usdt", exchange.KlinePeriod2Hour); err == nil {
+			err, result = exchange.LoadKlines(db, "Poloniex", "eth/usdt", exchange.KlinePeriod2Hour, 1000)
+		}
+	}
+	if err != nil || len(result) == 0 {
+		result = exchange.LoadHistory(filename)
+	}
 
 	// areas := exchange.StrategyTrendTest(result, true, true)
 
@@ -134,6 +153,78 @@ func (c *ChartsController) GetExamples() iris.Map {
 	}
 }
 
+func (c *ChartsController) GetKlines() iris.Map {
+
+	var err error
+	var period, limit int
+	var result []exchange.KlineValue
+
+	name := c.Ctx.URLParam("exchange")
+	symbol := c.Ctx.URLParam("symbol")
+	if period, err = strconv.Atoi(c.Ctx.URLParam("period")); err != nil || name == "" || symbol == "" {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	db := new(Mongo.Klines)
+	if err := db.Connect(); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeMongoDisconnect],
+		}
+	}
+
+	defer db.Close()
+
+	if c.Ctx.URLParam("start") != "" {
+		var start, end int64
+		if start, err = strconv.ParseInt(c.Ctx.URLParam("start"), 10, 64); err != nil {
+			return iris.Map{
+				"result": false,
+				"error":  errorMessage[errorCodeInvalidParameters],
+			}
+		}
+
+		end = time.Now().Unix()
+		if c.Ctx.URLParam("end") != "" {
+			if end, err = strconv.ParseInt(c.Ctx.URLParam("end"), 10, 64); err != nil {
+				return iris.Map{
+					"result": false,
+					"error":  errorMessage[errorCodeInvalidParameters],
+				}
+			}
+		}
+
+		err, result = exchange.LoadKlinesByRange(db, name, symbol, period, time.Unix(start, 0), time.Unix(end, 0))
+	} else {
+		limit = 500
+		if c.Ctx.URLParam("limit") != "" {
+			if limit, err = strconv.Atoi(c.Ctx.URLParam("limit")); err != nil || limit <= 0 {
+				return iris.Map{
+					"result": false,
+					"error":  errorMessage[errorCodeInvalidParameters],
+				}
+			}
+		}
+
+		err, result = exchange.LoadKlines(db, name, symbol, period, limit)
+	}
+
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   result,
+	}
+}
+
 func (c *ChartsController) GetProfitBy(name string, coin string) iris.Map {
 
 	var collection string
@@ -209,3 +300,156 @@ func (c *ChartsController) GetProfitBy(name string, coin string) iris.Map {
 		"data":   records,
 	}
 }
+
+// timeRange 解析start和end参数(unix时间，秒)，默认为全部记录
+func (c *ChartsController) timeRange() (error, time.Time, time.Time) {
+	start, end := time.Unix(0, 0), time.Now()
+
+	if c.Ctx.URLParam("start") != "" {
+		value, err := strconv.ParseInt(c.Ctx.URLParam("start"), 10, 64)
+		if err != nil {
+			return err, start, end
+		}
+		start = time.Unix(value, 0)
+	}
+
+	if c.Ctx.URLParam("end") != "" {
+		value, err := strconv.ParseInt(c.Ctx.URLParam("end"), 10, 64)
+		if err != nil {
+			return err, start, end
+		}
+		end = time.Unix(value, 0)
+	}
+
+	return nil, start, end
+}
+
+// GetPerformanceBy 任务的绩效分析，name为okexdiff，或者趋势任务的交易所okex/binance
+// Get route: /charts/performance/{name}?coin=eth&start=1522512000&end=1525104000
+func (c *ChartsController) GetPerformanceBy(name string) iris.Map {
+
+	var trades []Analytics.Trade
+	var snapshots []Analytics.Snapshot
+
+	err, start, end := c.timeRange()
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	coin := c.Ctx.URLParam("coin")
+
+	switch name {
+	case "okexdiff":
+		if coin == "" {
+			coin = "usdt"
+		}
+
+		fundManager := new(OkexDiff.OkexFundManage)
+		if err := fundManager.Init(); err != nil {
+			return iris.Map{
+				"result": false,
+				"error":  errorMessage[errorCodeMongoDisconnect],
+			}
+		}
+		defer fundManager.Close()
+
+		if err, trades = fundManager.LoadTrades(start, end); err != nil {
+			return iris.Map{
+				"result": false,
+				"error":  err.Error(),
+			}
+		}
+
+		balanceDB := new(Mongo.Balances)
+		if err := balanceDB.Connect(); err != nil {
+			return iris.Map{
+				"result": false,
+				"error":  errorMessage[errorCodeMongoDisconnect],
+			}
+		}
+		defer balanceDB.Close()
+
+		err, balances := balanceDB.FindAll()
+		if err != nil {
+			return iris.Map{
+				"result": false,
+				"error":  err.Error(),
+			}
+		}
+
+		for _, snapshot := range Analytics.FromBalances(balances, coin) {
+			if !snapshot.Time.Before(start) && snapshot.Time.Before(end) {
+				snapshots = append(snapshots, snapshot)
+			}
+		}
+
+	case "okex", "binance":
+		if coin == "" {
+			return iris.Map{
+				"result": false,
+				"error":  errorMessage[errorCodeInvalidParameters],
+			}
+		}
+
+		suffix := "_" + strings.ToUpper(coin)
+		db := &MongoTrend.TrendMongo{
+			TradeCollectionName:   Task.TrendTradeCollectionOKEX + suffix,
+			BalanceCollectionName: Task.TrendBalanceOKEX + suffix,
+			Server:                Trend.MongoServer,
+		}
+		if name == "binance" {
+			db.TradeCollectionName = Task.TrendTradeCollectionBinance + suffix
+			db.BalanceCollectionName = Task.TrendBalanceBinance + suffix
+		}
+
+		if err := db.Connect(); err != nil {
+			return iris.Map{
+				"result": false,
+				"error":  errorMessage[errorCodeMongoDisconnect],
+			}
+		}
+		defer db.Disconnect()
+
+		period := map[string]interface{}{
+			"$gte": start,
+			"$lt":  end,
+		}
+
+		err, records := db.TradeCollection.Find(map[string]interface{}{
+			"status":    MongoTrend.TradeStatusClose,
+			"closetime": period,
+		})
+		if err != nil {
+			return iris.Map{
+				"result": false,
+				"error":  err.Error(),
+			}
+		}
+		trades = Analytics.FromTrendTrades(records)
+
+		err, balances := db.BalanceCollection.FindAll(map[string]interface{}{
+			"time": period,
+		}, "time")
+		if err != nil {
+			return iris.Map{
+				"result": false,
+				"error":  err.Error(),
+			}
+		}
+		snapshots = Analytics.FromTrendBalances(balances, coin)
+
+	default:
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   Analytics.Compute(trades, snapshots, nil),
+	}
+}
@@ -19,9 +19,24 @@ type ExchangeController struct {
 }
 
 type ExchangeInfo struct {
-	Name   string `json:"name"`
-	API    string `json:"api"`
-	Secret string `json:"secret"`
+	Name string `json:"name"`
+	// Account the label of the account, the empty one is the default account of the exchange
+	Account string `json:"account"`
+	API     string `json:"api"`
+	Secret  string `json:"secret"`
+}
+
+// TransferInfo the transfer between the master account and the sub-account
+type TransferInfo struct {
+	Name string `json:"name"`
+	// Account the label of the master account
+	Account string `json:"account"`
+	// Passphrase required by OKEX
+	Passphrase string  `json:"passphrase"`
+	Coin       string  `json:"coin"`
+	Amount     float64 `json:"amount"`
+	SubAccount string  `json:"subaccount"`
+	ToMaster   bool    `json:"tomaster"`
 }
 
 func (e *ExchangeController) authen() (bool, iris.Map) {
@@ -86,6 +101,7 @@ func (e *ExchangeController) PostAddkey() iris.Map {
 		errMsg = err.Error()
 		goto _ERROR
 	}
+	defer exchangesDB.Close()
 
 	// err, encryptedAPI = Utils.GCM_encrypt(password.(string), username.(string), info.APIKey)
 	// if err != nil {
@@ -99,8 +115,9 @@ func (e *ExchangeController) PostAddkey() iris.Map {
 	// 	goto _ERROR
 	// }
 
-	if err = exchangesDB.Insert(&Mongo.ExchangeInfo{
-		Name: info.Name,
+	if err = exchangesDB.Upsert(&Mongo.ExchangeInfo{
+		Name:    info.Name,
+		Account: info.Account,
 		// API:    string(encryptedAPI),
 		// Secret: string(encryptedSecret),
 		// User:   username.(string),
@@ -121,3 +138,143 @@ _ERROR:
 		"error":  errMsg,
 	}
 }
+
+// GetKeys 获取已保存密钥的账户，不返回密钥
+// Get route: /exchange/keys
+func (e *ExchangeController) GetKeys() iris.Map {
+
+	if ok, result := e.authen(); !ok {
+		return result
+	}
+
+	exchangesDB := Mongo.ExchangeDB{}
+	if err := exchangesDB.Connect(); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+	defer exchangesDB.Close()
+
+	err, records := exchangesDB.FindAll()
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	keys := []ExchangeInfo{}
+	for _, record := range records {
+		keys = append(keys, ExchangeInfo{
+			Name:    record.Name,
+			Account: record.Account,
+		})
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   keys,
+	}
+}
+
+// PostRemovekey 删除账户的密钥
+// Post route: /exchange/removekey
+func (e *ExchangeController) PostRemovekey() iris.Map {
+
+	if ok, result := e.authen(); !ok {
+		return result
+	}
+
+	info := ExchangeInfo{}
+	if err := e.Ctx.ReadJSON(&info); err != nil || info.Name == "" {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	exchangesDB := Mongo.ExchangeDB{}
+	if err := exchangesDB.Connect(); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+	defer exchangesDB.Close()
+
+	if err := exchangesDB.Remove(info.Name, info.Account); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
+
+// PostTransfer 用母账户的密钥在母账户和子账户之间划转
+// Post route: /exchange/transfer
+func (e *ExchangeController) PostTransfer() iris.Map {
+
+	if ok, result := e.authen(); !ok {
+		return result
+	}
+
+	info := TransferInfo{}
+	if err := e.Ctx.ReadJSON(&info); err != nil || info.Name == "" || info.Coin == "" || info.Amount <= 0 || info.SubAccount == "" {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	exchangesDB := Mongo.ExchangeDB{}
+	if err := exchangesDB.Connect(); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+	defer exchangesDB.Close()
+
+	err, record := exchangesDB.FindAccount(info.Name, info.Account)
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	err, master := Exchange.NewSubAccounts(info.Name, Exchange.Config{
+		API:    string(record.API),
+		Secret: string(record.Secret),
+		Custom: map[string]interface{}{"passphrase": info.Passphrase},
+	})
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	err, id := master.SubAccountTransfer(Exchange.TransferConfig{
+		Coin:       info.Coin,
+		Amount:     info.Amount,
+		SubAccount: info.SubAccount,
+		ToMaster:   info.ToMaster,
+	})
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   id,
+	}
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/sessions"
+
+	Task "madaoQT/task"
+)
+
+// defaultReportDays the period of the report if it isn't assigned
+const defaultReportDays = 7
+
+type ExecutionController struct {
+	Ctx iris.Context
+
+	Sessions   *sessions.Sessions      `iris:"persistence"`
+	Executions *Task.ExecutionRecorder `iris:"persistence"`
+}
+
+// GetReport 按任务、交易所和交易对统计执行差额，参数task为空时统计所有任务，days为统计天数
+// Get route: /execution/report?task=&days=
+func (e *ExecutionController) GetReport() iris.Map {
+	days, err := strconv.Atoi(e.Ctx.URLParam("days"))
+	if err != nil || days <= 0 {
+		days = defaultReportDays
+	}
+
+	err, stats := e.Executions.Report(e.Ctx.URLParam("task"), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   stats,
+	}
+}
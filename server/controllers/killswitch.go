@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/sessions"
+
+	Task "madaoQT/task"
+)
+
+type KillSwitchController struct {
+	Ctx iris.Context
+
+	Sessions *sessions.Sessions `iris:"persistence"`
+	Switch   *Task.KillSwitch   `iris:"persistence"`
+}
+
+// EngageInfo the reason of the kill switch, the positions of all the tasks are closed if Flatten is set
+type EngageInfo struct {
+	Reason  string `json:"reason"`
+	Flatten bool   `json:"flatten"`
+}
+
+func (k *KillSwitchController) authen() (bool, iris.Map) {
+	if DEBUG {
+		return true, iris.Map{}
+	}
+	{
+		session := k.Sessions.Start(k.Ctx)
+		username := session.Get("name")
+		if username == nil || username == "" {
+			result := iris.Map{
+				"result": false,
+				"error":  errorCodeInvalidSession,
+			}
+			return false, result
+		}
+		return true, iris.Map{}
+	}
+}
+
+// GetStatus 获取全局熔断状态
+// Get route: /killswitch/status
+func (k *KillSwitchController) GetStatus() iris.Map {
+	return iris.Map{
+		"result": true,
+		"data":   k.Switch.State(),
+	}
+}
+
+// PostEngage 触发全局熔断，冻结所有任务的新订单并撤销挂单
+// Post route: /killswitch/engage
+func (k *KillSwitchController) PostEngage() iris.Map {
+
+	if ok, result := k.authen(); !ok {
+		return result
+	}
+
+	info := EngageInfo{}
+	if err := k.Ctx.ReadJSON(&info); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	if err := k.Switch.Engage(Task.KillSourceAPI, info.Reason, info.Flatten); err != nil {
+		// the switch is engaged even if it isn't saved
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
+
+// PostRelease 解除全局熔断
+// Post route: /killswitch/release
+func (k *KillSwitchController) PostRelease() iris.Map {
+
+	if ok, result := k.authen(); !ok {
+		return result
+	}
+
+	if err := k.Switch.Release(Task.KillSourceAPI); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
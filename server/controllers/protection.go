@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/sessions"
+
+	Task "madaoQT/task"
+)
+
+type ProtectionController struct {
+	Ctx iris.Context
+
+	Sessions  *sessions.Sessions `iris:"persistence"`
+	Protector *Task.Protector    `iris:"persistence"`
+}
+
+// GetList 获取仓位保护，包括条件单和本地监控的仓位
+// Get route: /protection/list
+func (p *ProtectionController) GetList() iris.Map {
+	return iris.Map{
+		"result": true,
+		"data":   p.Protector.Records(),
+	}
+}
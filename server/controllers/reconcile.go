@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/sessions"
+
+	Task "madaoQT/task"
+)
+
+type ReconcileController struct {
+	Ctx iris.Context
+
+	Sessions   *sessions.Sessions `iris:"persistence"`
+	Reconciler *Task.Reconciler   `iris:"persistence"`
+}
+
+// AcknowledgeInfo the discrepancies are adopted into the records of the task at the next start if Adopt is set,
+// Tolerance is the ratio the amounts of the acknowledged discrepancies may change, 0 is the default 5%
+type AcknowledgeInfo struct {
+	Task      string  `json:"task"`
+	Adopt     bool    `json:"adopt"`
+	Tolerance float64 `json:"tolerance"`
+}
+
+func (r *ReconcileController) authen() (bool, iris.Map) {
+	if DEBUG {
+		return true, iris.Map{}
+	}
+	{
+		session := r.Sessions.Start(r.Ctx)
+		username := session.Get("name")
+		if username == nil || username == "" {
+			result := iris.Map{
+				"result": false,
+				"error":  errorCodeInvalidSession,
+			}
+			return false, result
+		}
+		return true, iris.Map{}
+	}
+}
+
+// GetList 获取所有任务的核对结果
+// Get route: /reconcile/list
+func (r *ReconcileController) GetList() iris.Map {
+
+	if ok, result := r.authen(); !ok {
+		return result
+	}
+
+	err, records := r.Reconciler.Records()
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   records,
+	}
+}
+
+// PostAcknowledge 确认任务的差异，允许任务带着差异启动，或者在下次启动时接管差异
+// Post route: /reconcile/acknowledge
+func (r *ReconcileController) PostAcknowledge() iris.Map {
+
+	if ok, result := r.authen(); !ok {
+		return result
+	}
+
+	info := AcknowledgeInfo{}
+	if err := r.Ctx.ReadJSON(&info); err != nil || info.Task == "" {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	if err := r.Reconciler.Acknowledge(info.Task, info.Adopt, info.Tolerance); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
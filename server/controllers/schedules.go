@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/sessions"
+
+	Task "madaoQT/task"
+)
+
+type ScheduleController struct {
+	Ctx iris.Context
+
+	Sessions  *sessions.Sessions `iris:"persistence"`
+	Scheduler *Task.Scheduler    `iris:"persistence"`
+}
+
+// ScheduleInfo the job which starts, stops or restarts the task instance
+type ScheduleInfo struct {
+	Name   string `json:"name"`
+	Spec   string `json:"spec"`
+	Action string `json:"action"`
+	TaskID string `json:"taskid"`
+	Config string `json:"config"`
+}
+
+func (s *ScheduleController) authen() (bool, iris.Map) {
+	if DEBUG {
+		return true, iris.Map{}
+	}
+	{
+		session := s.Sessions.Start(s.Ctx)
+		username := session.Get("name")
+		if username == nil || username == "" {
+			result := iris.Map{
+				"result": false,
+				"error":  errorCodeInvalidSession,
+			}
+			return false, result
+		}
+		return true, iris.Map{}
+	}
+}
+
+// GetList 获取所有定时任务，按下次运行时间排序
+// Get route: /schedule/list
+func (s *ScheduleController) GetList() iris.Map {
+	return iris.Map{
+		"result": true,
+		"data":   s.Scheduler.List(),
+	}
+}
+
+// GetJobBy 获取定时任务的下次运行时间和最后一次运行记录
+// Get route: /schedule/job/{name}
+func (s *ScheduleController) GetJobBy(name string) iris.Map {
+	err, info := s.Scheduler.Get(name)
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   info,
+	}
+}
+
+// PostCreate 创建定时启动、停止或重启任务实例的定时任务
+// Post route: /schedule/create
+func (s *ScheduleController) PostCreate() iris.Map {
+
+	if ok, result := s.authen(); !ok {
+		return result
+	}
+
+	info := ScheduleInfo{}
+	if err := s.Ctx.ReadJSON(&info); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	if err := s.Scheduler.AddTaskJob(info.Name, info.Spec, info.Action, info.TaskID, info.Config); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
+
+// PostRunBy 立即运行定时任务
+// Post route: /schedule/run/{name}
+func (s *ScheduleController) PostRunBy(name string) iris.Map {
+
+	if ok, result := s.authen(); !ok {
+		return result
+	}
+
+	if err := s.Scheduler.Run(name); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
+
+// PostRemoveBy 删除定时任务
+// Post route: /schedule/remove/{name}
+func (s *ScheduleController) PostRemoveBy(name string) iris.Map {
+
+	if ok, result := s.authen(); !ok {
+		return result
+	}
+
+	if err := s.Scheduler.Remove(name); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
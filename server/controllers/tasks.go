@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"io/ioutil"
-	"sync"
 
 	"github.com/kataras/iris"
 	"github.com/kataras/iris/sessions"
@@ -19,7 +18,7 @@ type TaskController struct {
 	// Database
 	// Global properties
 	Sessions *sessions.Sessions `iris:"persistence"`
-	Tasks    *sync.Map          `iris:"persistence"`
+	Tasks    *Task.TaskManager  `iris:"persistence"`
 }
 
 // 管理员接口
@@ -45,66 +44,55 @@ func (t *TaskController) authen() (bool, iris.Map) {
 
 }
 
-// GetList 获取任务列表
+// GetList 获取策略列表
 // Get route: /task/list
 func (t *TaskController) GetList() iris.Map {
-	var tasks []Task.Description
-	t.Tasks.Range(func(key, value interface{}) bool {
-		Logger.Infof("KEY:%s", key)
-		task := value.(Task.ITask)
-		tasks = append(tasks, task.GetDescription())
-		return true
-	})
-
 	return iris.Map{
 		"result": true,
-		"data":   tasks,
+		"data":   t.Tasks.Strategies(),
 	}
 
 }
 
-// Get route: /task
+// GetBy 获取策略的配置Schema和默认配置，用于生成配置表单
+// Get route: /task/{name}
 func (t *TaskController) GetBy(name string) iris.Map {
 
-	// info := map[string]string{}
-	// err := t.Ctx.ReadJSON(&info)
-	// if err != nil {
-	// 	Logger.Errorf("Error:%v", err)
-	// 	t.Ctx.StatusCode(iris.StatusInternalServerError)
-	// 	return nil
-	// }
+	err, schema := t.Tasks.Schema(name)
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
 
-	// Logger.Infof("Data:%v", info)
-
-	t.Ctx.ViewData("task", name)
-	t.Ctx.View("task.html")
-
-	return nil
-	// var tasksInfo []map[string]string
-	// t.Tasks.Range(func(key, value interface{}) bool {
-	// 	Logger.Infof("KEY:%s", key)
-	// 	defaultConfig, _ := json.Marshal(value.(Task.ITask).GetDefaultConfig())
-	// 	taskInfo := map[string]string{
-	// 		"name":    key.(string),
-	// 		"default": string(defaultConfig),
-	// 	}
-	// 	tasksInfo = append(tasksInfo, taskInfo)
-	// 	return true
-	// })
-
-	// // configBytes, _ := json.Marshal(tasksInfo)
-
-	// return iris.Map{
-	// 	"result": true,
-	// 	"tasks":  tasksInfo,
-	// }
+	return iris.Map{
+		"result": true,
+		"data": iris.Map{
+			"desc":    t.Tasks.Get(name).GetDescription(),
+			"schema":  schema,
+			"default": t.Tasks.Get(name).GetDefaultConfig(),
+		},
+	}
 }
 
-// PostStart 启动任务
-// Post route: /task/start
-func (t *TaskController) PostStartBy(name string) iris.Map {
+// configError returns the errors of the fields if the config is invalid
+func configError(err error) iris.Map {
+	result := iris.Map{
+		"result": false,
+		"error":  err.Error(),
+	}
+
+	if errs, ok := err.(Task.ConfigErrors); ok {
+		result["error"] = Task.TaskErrorMsg[Task.TaskInvalidConfig]
+		result["fields"] = errs
+	}
+	return result
+}
 
-	var errMsg string
+// PostStartBy 启动任务实例，默认实例的ID为策略名称
+// Post route: /task/start/{id}
+func (t *TaskController) PostStartBy(name string) iris.Map {
 
 	if ok, result := t.authen(); !ok {
 		return result
@@ -118,7 +106,7 @@ func (t *TaskController) PostStartBy(name string) iris.Map {
 		}
 	}
 
-	if task, ok := t.Tasks.Load(name); ok {
+	if task := t.Tasks.Get(name); task != nil {
 		body, err := ioutil.ReadAll(t.Ctx.Request().Body)
 		if err != nil {
 			Logger.Debugf("fail to read:%v", err)
@@ -128,11 +116,10 @@ func (t *TaskController) PostStartBy(name string) iris.Map {
 			}
 		}
 
-		err = task.(Task.ITask).Start(string(body))
-		Logger.Errorf("Error:%v", err)
+		err = t.Tasks.Start(name, string(body))
 		if err != nil {
-			errMsg = err.Error()
-			goto _ERROR
+			Logger.Errorf("Error:%v", err)
+			return configError(err)
 		}
 
 		return iris.Map{
@@ -140,10 +127,9 @@ func (t *TaskController) PostStartBy(name string) iris.Map {
 		}
 	}
 
-_ERROR:
 	return iris.Map{
 		"result": false,
-		"error":  errMsg,
+		"error":  Task.TaskErrorMsg[Task.TaskNotFound],
 	}
 }
 
@@ -157,8 +143,8 @@ func (t *TaskController) GetStatusBy(name string) iris.Map {
 		}
 	}
 
-	if task, ok := t.Tasks.Load(name); ok {
-		result := task.(Task.ITask).GetStatus()
+	if task := t.Tasks.Get(name); task != nil {
+		result := task.GetStatus()
 		return iris.Map{
 			"result": true,
 			"data":   result,
@@ -180,8 +166,8 @@ func (t *TaskController) GetBalancesBy(name string) iris.Map {
 		}
 	}
 
-	if task, ok := t.Tasks.Load(name); ok {
-		result := task.(Task.ITask).GetBalances()
+	if task := t.Tasks.Get(name); task != nil {
+		result := task.GetBalances()
 		return iris.Map{
 			"result": true,
 			"data":   result,
@@ -195,8 +181,8 @@ func (t *TaskController) GetBalancesBy(name string) iris.Map {
 
 func (t *TaskController) GetTrades() iris.Map {
 
-	if task, ok := t.Tasks.Load("okexdiff"); ok {
-		result := task.(Task.ITask).GetTrades()
+	if task := t.Tasks.Get("okexdiff"); task != nil {
+		result := task.GetTrades()
 		// Logger.Debugf("getTrades:%v", result)
 		return iris.Map{
 			"result": true,
@@ -219,16 +205,16 @@ func (t *TaskController) GetPositionsBy(name string) iris.Map {
 		}
 	}
 
-	if task, ok := t.Tasks.Load(name); ok {
+	if task := t.Tasks.Get(name); task != nil {
 
-		if task.(Task.ITask).GetStatus() != Task.StatusProcessing {
+		if task.GetStatus() != Task.StatusProcessing {
 			return iris.Map{
 				"result": false,
 				"error":  errorMessage[errorCodeTaskNotRunning],
 			}
 		}
 
-		result := task.(Task.ITask).GetPositions()
+		result := task.GetPositions()
 		if result != nil {
 			Logger.Debugf("GetPositions:%v", result)
 			return iris.Map{
@@ -244,8 +230,8 @@ func (t *TaskController) GetPositionsBy(name string) iris.Map {
 }
 
 func (t *TaskController) GetFailed() iris.Map {
-	if task, ok := t.Tasks.Load("okexdiff"); ok {
-		result := task.(Task.ITask).GetFailedPositions()
+	if task := t.Tasks.Get("okexdiff"); task != nil {
+		result := task.GetFailedPositions()
 		if result != nil {
 			Logger.Debugf("GetFailedPositions:%v", result)
 			return iris.Map{
@@ -262,7 +248,7 @@ func (t *TaskController) GetFailed() iris.Map {
 
 func (t *TaskController) PostFix() iris.Map {
 
-	if task, ok := t.Tasks.Load("okexdiff"); ok {
+	if task := t.Tasks.Get("okexdiff"); task != nil {
 		body, err := ioutil.ReadAll(t.Ctx.Request().Body)
 		if err != nil {
 			Logger.Debugf("fail to read:%v", err)
@@ -274,7 +260,7 @@ func (t *TaskController) PostFix() iris.Map {
 
 		Logger.Infof("Body:%v", string(body))
 
-		result := task.(Task.ITask).FixFailedPosition(string(body))
+		result := task.FixFailedPosition(string(body))
 		if result == nil {
 			return iris.Map{
 				"result": true,
@@ -299,8 +285,7 @@ func (t *TaskController) GetStop() iris.Map {
 	// 	return result
 	// }
 
-	if task, ok := t.Tasks.Load("okexdiff"); ok {
-		task.(Task.ITask).Close()
+	if err := t.Tasks.Stop("okexdiff"); err == nil {
 		return iris.Map{
 			"result": true,
 		}
@@ -311,6 +296,144 @@ func (t *TaskController) GetStop() iris.Map {
 	}
 }
 
+// GetInstances 获取所有任务实例
+// Get route: /task/instances
+func (t *TaskController) GetInstances() iris.Map {
+	return iris.Map{
+		"result": true,
+		"data":   t.Tasks.List(),
+	}
+}
+
+// GetInstanceBy 获取任务实例的状态
+// Get route: /task/instance/{id}
+func (t *TaskController) GetInstanceBy(id string) iris.Map {
+	err, info := t.Tasks.Status(id)
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   info,
+	}
+}
+
+// PostCreateBy 根据策略和配置创建任务实例，返回实例ID
+// Post route: /task/create/{name}
+func (t *TaskController) PostCreateBy(name string) iris.Map {
+
+	if ok, result := t.authen(); !ok {
+		return result
+	}
+
+	body, err := ioutil.ReadAll(t.Ctx.Request().Body)
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	err, id := t.Tasks.Create(name, string(body))
+	if err != nil {
+		return configError(err)
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   id,
+	}
+}
+
+// PostConfigBy 更新任务实例的配置，运行中的任务不需要重启
+// Post route: /task/config/{id}
+func (t *TaskController) PostConfigBy(id string) iris.Map {
+
+	if ok, result := t.authen(); !ok {
+		return result
+	}
+
+	body, err := ioutil.ReadAll(t.Ctx.Request().Body)
+	if err != nil || len(body) == 0 {
+		return iris.Map{
+			"result": false,
+			"error":  errorMessage[errorCodeInvalidParameters],
+		}
+	}
+
+	err, changes := t.Tasks.UpdateConfig(id, string(body))
+	if err != nil {
+		return configError(err)
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   changes,
+	}
+}
+
+// GetConfigBy 获取任务实例的配置修改记录
+// Get route: /task/config/{id}
+func (t *TaskController) GetConfigBy(id string) iris.Map {
+
+	err, records := t.Tasks.Audits(id)
+	if err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+		"data":   records,
+	}
+}
+
+// PostStopBy 停止任务实例
+// Post route: /task/stop/{id}
+func (t *TaskController) PostStopBy(id string) iris.Map {
+
+	if ok, result := t.authen(); !ok {
+		return result
+	}
+
+	if err := t.Tasks.Stop(id); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
+
+// PostRemoveBy 停止并删除任务实例
+// Post route: /task/remove/{id}
+func (t *TaskController) PostRemoveBy(id string) iris.Map {
+
+	if ok, result := t.authen(); !ok {
+		return result
+	}
+
+	if err := t.Tasks.Remove(id); err != nil {
+		return iris.Map{
+			"result": false,
+			"error":  err.Error(),
+		}
+	}
+
+	return iris.Map{
+		"result": true,
+	}
+}
+
 // func (t *TaskController) GetRun() iris.Map {
 
 // 	if ok, result := t.authen(); !ok {
@@ -1,7 +1,6 @@
 package server
 
 import (
-	"sync"
 	"time"
 
 	"github.com/kataras/iris/view"
@@ -13,12 +12,17 @@ import (
 	"github.com/kataras/iris/sessions"
 
 	Global "madaoQT/config"
+	Mongo "madaoQT/mongo"
 	Controllers "madaoQT/server/controllers"
 	Websocket "madaoQT/server/websocket"
 	Utils "madaoQT/utils"
 
 	// task
+	Task "madaoQT/task"
+	Algo "madaoQT/task/algo"
+	Arbitrage "madaoQT/task/arbitrage"
 	OkexDiff "madaoQT/task/okexdiff"
+	Portfolio "madaoQT/task/portfolio"
 	Trend "madaoQT/task/trend"
 )
 
@@ -27,7 +31,8 @@ type HttpServer struct {
 	ws   *Websocket.WebsocketServer
 	sess *sessions.Sessions
 
-	Tasks *sync.Map
+	Tasks     *Task.TaskManager
+	Scheduler *Task.Scheduler
 }
 
 const CookiesName = "madao-sessions"
@@ -90,6 +95,12 @@ func (h *HttpServer) setupControllers() {
 	mvc.New(h.app.Party(prefix + "user")).Handle(&Controllers.UserController{Sessions: h.sess})
 	mvc.New(h.app.Party(prefix + "task")).Handle(&Controllers.TaskController{Sessions: h.sess, Tasks: h.Tasks})
 	mvc.New(h.app.Party(prefix + "exchange")).Handle(&Controllers.ExchangeController{Sessions: h.sess})
+	mvc.New(h.app.Party(prefix + "schedule")).Handle(&Controllers.ScheduleController{Sessions: h.sess, Scheduler: h.Scheduler})
+	mvc.New(h.app.Party(prefix + "killswitch")).Handle(&Controllers.KillSwitchController{Sessions: h.sess, Switch: Task.GlobalKillSwitch})
+	mvc.New(h.app.Party(prefix + "reconcile")).Handle(&Controllers.ReconcileController{Sessions: h.sess, Reconciler: Task.GlobalReconciler})
+	mvc.New(h.app.Party(prefix + "protection")).Handle(&Controllers.ProtectionController{Sessions: h.sess, Protector: Task.GlobalProtector})
+	mvc.New(h.app.Party(prefix + "execution")).Handle(&Controllers.ExecutionController{Sessions: h.sess, Executions: Task.GlobalExecutions})
+	mvc.New(h.app.Party(prefix + "algo")).Handle(&Controllers.AlgoController{Sessions: h.sess, Algos: Algo.GlobalAlgos})
 
 }
 
@@ -123,7 +134,9 @@ func (h *HttpServer) SetupHttpServer() {
 	h.app.RegisterView(views)
 
 	// task
-	h.setupTasks()
+	if h.Tasks == nil {
+		h.SetupTasks()
+	}
 
 	// http
 	h.setupSessions()
@@ -133,16 +146,80 @@ func (h *HttpServer) SetupHttpServer() {
 	h.app.Run(iris.Addr(":"+Global.ServerPort))
 }
 
-func (h *HttpServer) setupTasks() {
+// SetupTasks registers the strategies, the task definitions are saved in mongo if it is connected
+func (h *HttpServer) SetupTasks() {
 
-	if h.Tasks == nil {
-		h.Tasks = &sync.Map{}
+	h.Tasks = new(Task.TaskManager)
+
+	db := new(Mongo.TaskDefinitions)
+	if err := db.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the task definitions, the tasks will not be saved:%v", err)
+	} else {
+		h.Tasks.DB = db
 	}
 
-	okexdiff := new(OkexDiff.IAnalyzer)
-	h.Tasks.Store(okexdiff.GetDescription().Name, okexdiff)
+	klines := new(Mongo.Klines)
+	if err := klines.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the klines, the tasks will read the klines from the exchanges:%v", err)
+	} else {
+		Task.GlobalKlines.SetDB(klines)
+	}
 
-	trend := new(Trend.TrendOkex)
-	h.Tasks.Store(trend.GetDescription().Name, trend)
+	h.Scheduler = &Task.Scheduler{Manager: h.Tasks}
+	schedules := new(Mongo.Schedules)
+	if err := schedules.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the schedules, the last runs will not be saved:%v", err)
+	} else {
+		h.Scheduler.DB = schedules
+	}
+	h.Tasks.Scheduler = h.Scheduler
+
+	killSwitches := new(Mongo.KillSwitches)
+	if err := killSwitches.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the kill switch, the state will not be saved:%v", err)
+	} else {
+		Task.GlobalKillSwitch.SetDB(killSwitches)
+	}
+	Task.GlobalKillSwitch.Flatten = h.Tasks.ForceCloseAll
+
+	reconciliations := new(Mongo.Reconciliations)
+	if err := reconciliations.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the reconciliations, the tasks will not be reconciled:%v", err)
+	} else {
+		Task.GlobalReconciler.SetDB(reconciliations)
+	}
+
+	executions := new(Mongo.Executions)
+	if err := executions.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the executions, the execution quality will not be saved:%v", err)
+	} else {
+		Task.GlobalExecutions.SetDB(executions)
+	}
+
+	protections := new(Mongo.Protections)
+	if err := protections.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the protections, the positions will not be protected after restarting:%v", err)
+	} else {
+		Task.GlobalProtector.SetDB(protections)
+		if err := Task.GlobalProtector.Restore(); err != nil {
+			Logger.Errorf("Fail to restore the protections:%v", err)
+		}
+	}
+
+	h.Tasks.Register(func() Task.ITask {
+		return new(OkexDiff.IAnalyzer)
+	})
+
+	h.Tasks.Register(func() Task.ITask {
+		return new(Trend.TrendOkex)
+	})
+
+	h.Tasks.Register(func() Task.ITask {
+		return new(Arbitrage.Arbitrage)
+	})
+
+	h.Tasks.Register(func() Task.ITask {
+		return new(Portfolio.Portfolio)
+	})
 
 }
@@ -0,0 +1,572 @@
+package algo
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kataras/golog"
+
+	Global "madaoQT/config"
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+	Strategy "madaoQT/task/strategy"
+)
+
+/*
+	执行算法：大单(母单)按TWAP、VWAP、POV或冰山拆分成子单，子单通过OrderManager下单和跟踪，
+	未成交的子单在下一次下单前撤销，剩余数量按计划补上。支持暂停、恢复和撤销，结束后统计相对到达价的执行差额
+*/
+
+var Logger *golog.Logger
+
+func init() {
+	logger := golog.New()
+	Logger = logger
+	Logger.SetLevel("debug")
+	Logger.SetTimeFormat(Global.TimeFormat)
+	Logger.SetPrefix("[ALGO]")
+}
+
+type AlgoType string
+
+const (
+	// AlgoTWAP releases the amount evenly over the duration
+	AlgoTWAP AlgoType = "twap"
+	// AlgoVWAP releases the amount by the volume profile of the previous days
+	AlgoVWAP AlgoType = "vwap"
+	// AlgoPOV follows the ratio of the market volume
+	AlgoPOV AlgoType = "pov"
+	// AlgoIceberg shows the display amount at the limit price each time
+	AlgoIceberg AlgoType = "iceberg"
+)
+
+type StatusType string
+
+const (
+	StatusRunning   StatusType = "running"
+	StatusPaused    StatusType = "paused"
+	StatusCanceling StatusType = "canceling"
+	StatusCanceled  StatusType = "canceled"
+	StatusDone      StatusType = "done"
+)
+
+const algoEpsilon = 1e-9
+
+const defaultInterval = 1 * time.Minute
+
+// ParentOrder the order executed by the algorithm
+type ParentOrder struct {
+	Type      AlgoType           `json:"type"`
+	Exchange  string             `json:"exchange"`
+	Pair      string             `json:"pair"`
+	TradeType Exchange.TradeType `json:"tradetype"`
+	Amount    float64            `json:"amount"`
+	// Price the limit price of the child orders, 0 means no limit. It's required by the iceberg
+	Price float64 `json:"price"`
+	// Limit the ratio the child orders cross the best price, e.g. 0.001
+	Limit float64 `json:"limit"`
+	// Duration the time of TWAP and VWAP
+	Duration time.Duration `json:"duration"`
+	// Interval the time between the child orders, the open child orders are canceled after it
+	Interval time.Duration `json:"interval"`
+	// MinAmount the child orders are the multiples of it, e.g. the unit amount of the contracts
+	MinAmount float64 `json:"minamount"`
+	// Participation the ratio of the market volume followed by POV
+	Participation float64 `json:"participation"`
+	// Display the amount shown by the iceberg
+	Display float64 `json:"display"`
+	// Profile the volume weights of the slices of VWAP, see LoadProfile(). TWAP is used if it's empty
+	Profile []float64 `json:"profile,omitempty"`
+}
+
+// Stats the post-trade statistics, the shortfall is in bps from the arrival mid and the positive one is the cost
+type Stats struct {
+	Arrival   float64 `json:"arrival"`
+	AvgPrice  float64 `json:"avgprice"`
+	Shortfall float64 `json:"shortfall"`
+	FillRatio float64 `json:"fillratio"`
+	Children  int     `json:"children"`
+	Errors    int     `json:"errors"`
+	// Duration the seconds from the start
+	Duration float64 `json:"duration"`
+	// Participation the dealt amount over the market volume, it's only measured by POV
+	Participation float64 `json:"participation"`
+}
+
+// Progress the state of the parent order
+type Progress struct {
+	ID       string        `json:"id"`
+	Order    ParentOrder   `json:"order"`
+	Status   StatusType    `json:"status"`
+	Start    time.Time     `json:"start"`
+	Target   float64       `json:"target"`
+	Dealt    float64       `json:"dealt"`
+	Open     float64       `json:"open"`
+	Children int           `json:"children"`
+	Stats    Stats         `json:"stats"`
+	Paused   time.Duration `json:"paused"`
+}
+
+// Algo executes the parent order, the steps are called by the loop after Start(), or by the caller directly
+type Algo struct {
+	ID    string
+	Order ParentOrder
+	// Trades records the child orders if it's set
+	Trades *Mongo.Trades
+	// Volume the market volume since the start for POV, the klines of the exchange are used if it's nil
+	Volume func(start time.Time, now time.Time) float64
+
+	// step serializes the steps, which call the exchange without holding the lock of the state
+	step      sync.Mutex
+	lock      sync.Mutex
+	exchange  Exchange.IExchange
+	orders    *Strategy.OrderManager
+	execution *Task.Execution
+	status    StatusType
+	start     time.Time
+	end       time.Time
+	pausedAt  time.Time
+	next      time.Time
+	paused    time.Duration
+	target    float64
+	dealt     float64
+	cost      float64
+	market    float64
+	open      float64
+	children  int
+	errors    int
+	done      chan bool
+}
+
+func validate(order ParentOrder) error {
+	if order.Amount <= 0 || order.Interval < 0 || order.MinAmount < 0 || order.Limit < 0 || order.Price < 0 {
+		return errors.New("Invalid amount, interval, limit or price of the parent order")
+	}
+
+	switch order.Type {
+	case AlgoTWAP, AlgoVWAP:
+		if order.Duration <= 0 {
+			return errors.New("The duration is required by TWAP and VWAP")
+		}
+	case AlgoPOV:
+		if order.Participation <= 0 || order.Participation > 1 {
+			return errors.New("The participation of POV should be in (0, 1]")
+		}
+	case AlgoIceberg:
+		if order.Display <= 0 || order.Price <= 0 {
+			return errors.New("The display amount and the price are required by the iceberg")
+		}
+	default:
+		return errors.New("Invalid algorithm:" + string(order.Type))
+	}
+	return nil
+}
+
+func NewAlgo(id string, exchange Exchange.IExchange, order ParentOrder) (error, *Algo) {
+	if exchange == nil {
+		return errors.New("Invalid exchange"), nil
+	}
+	if err := validate(order); err != nil {
+		return err, nil
+	}
+	if order.Interval == 0 {
+		order.Interval = defaultInterval
+	}
+	if order.Exchange == "" {
+		order.Exchange = exchange.GetExchangeName()
+	}
+
+	return nil, &Algo{
+		ID:       id,
+		Order:    order,
+		exchange: exchange,
+		done:     make(chan bool),
+	}
+}
+
+// begin records the arrival price, the schedule starts from the time
+func (a *Algo) begin(now time.Time) {
+	// the arrival mid is read from the exchange
+	execution := Task.StartExecution(a.exchange, Exchange.TradeConfig{
+		Batch:  a.ID,
+		Pair:   a.Order.Pair,
+		Type:   a.Order.TradeType,
+		Price:  a.Order.Price,
+		Amount: a.Order.Amount,
+	})
+	if execution.Record.Decision == 0 {
+		execution.Record.Decision = execution.Record.Arrival
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.orders = &Strategy.OrderManager{
+		Exchanges: map[string]Exchange.IExchange{a.Order.Exchange: a.exchange},
+		Trades:    a.Trades,
+		Batch:     a.ID,
+	}
+	a.execution = execution
+	a.status = StatusRunning
+	a.start = now
+}
+
+// Begin adds the order to GlobalAlgos without the loop, the caller runs the steps
+func (a *Algo) Begin() {
+	a.begin(time.Now())
+	GlobalAlgos.Add(a)
+	Logger.Infof("[%s]Start %s %s %s amount:%v", a.ID, a.Order.Type, Exchange.TradeTypeString[a.Order.TradeType], a.Order.Pair, a.Order.Amount)
+}
+
+// Start runs the steps at the interval until the order is finished, the order is controlled by GlobalAlgos
+func (a *Algo) Start() {
+	a.Begin()
+
+	go func() {
+		// the cancellation and the fills are checked more often than the child orders are placed
+		interval := a.Order.Interval / 4
+		if interval < time.Second {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		a.Step(time.Now())
+		for {
+			select {
+			case <-a.done:
+				return
+			case <-ticker.C:
+				a.Step(time.Now())
+			}
+		}
+	}()
+}
+
+// Done is closed when the order is done or canceled
+func (a *Algo) Done() chan bool {
+	return a.done
+}
+
+func (a *Algo) Pause() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.status != StatusRunning {
+		return errors.New("The order is not running")
+	}
+	a.status = StatusPaused
+	a.pausedAt = time.Now()
+	return nil
+}
+
+// Resume the schedule is shifted by the paused time
+func (a *Algo) Resume() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.status != StatusPaused {
+		return errors.New("The order is not paused")
+	}
+	a.status = StatusRunning
+	a.paused += time.Since(a.pausedAt)
+	return nil
+}
+
+// Cancel the order is canceled once the open child orders are canceled
+func (a *Algo) Cancel() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.status != StatusRunning && a.status != StatusPaused {
+		return errors.New("The order is finished")
+	}
+	a.status = StatusCanceling
+	return nil
+}
+
+// Step updates the fills, and places the child order which is due. The lock isn't held while the exchange is called,
+// so that the order can be paused, canceled and listed meanwhile
+func (a *Algo) Step(now time.Time) {
+	a.step.Lock()
+	defer a.step.Unlock()
+
+	a.lock.Lock()
+	finished := a.status == StatusDone || a.status == StatusCanceled || a.orders == nil
+	a.lock.Unlock()
+	if finished {
+		return
+	}
+
+	fills := a.orders.Poll(now)
+	open := a.orders.Open()
+
+	a.lock.Lock()
+	for _, fill := range fills {
+		a.dealt += fill.Amount
+		a.cost += fill.Amount * fill.Price
+	}
+	a.open = openAmount(open)
+	status := a.status
+	dealt := a.dealt
+	next := a.next
+	a.lock.Unlock()
+
+	if status == StatusPaused || status == StatusCanceling {
+		a.cancel(open, now, 0)
+		if status == StatusCanceling && len(open) == 0 {
+			a.finish(StatusCanceled, now)
+		}
+		return
+	}
+
+	if a.Order.Amount-dealt <= algoEpsilon {
+		a.cancel(open, now, 0)
+		if len(open) == 0 {
+			a.finish(StatusDone, now)
+		}
+		return
+	}
+
+	if a.Order.Type != AlgoIceberg {
+		a.cancel(open, now, a.Order.Interval)
+	}
+	if len(open) != 0 || (a.Order.Type != AlgoIceberg && now.Before(next)) {
+		return
+	}
+
+	var market float64
+	if a.Order.Type == AlgoPOV {
+		market = a.volume(now)
+	}
+
+	a.lock.Lock()
+	if a.status != StatusRunning {
+		a.lock.Unlock()
+		return
+	}
+	a.market = market
+	a.target = a.targetAmount(now)
+	amount := a.target - a.dealt
+	if a.Order.Type == AlgoIceberg {
+		amount = math.Min(a.Order.Display, a.Order.Amount-a.dealt)
+	}
+	if a.Order.MinAmount > 0 {
+		amount = math.Floor(amount/a.Order.MinAmount+algoEpsilon) * a.Order.MinAmount
+	}
+	scheduled := a.target >= a.Order.Amount-algoEpsilon
+	a.lock.Unlock()
+
+	if amount <= algoEpsilon {
+		// the rest is less than the minimum amount after the schedule
+		if scheduled && a.Order.Type != AlgoPOV {
+			a.finish(StatusDone, now)
+		}
+		return
+	}
+
+	price := a.childPrice()
+	if price <= 0 {
+		Logger.Warnf("[%s]No price for the child order", a.ID)
+		return
+	}
+
+	err, _ := a.orders.Place(a.Order.Exchange, Exchange.TradeConfig{
+		Batch:  a.ID,
+		Pair:   a.Order.Pair,
+		Type:   a.Order.TradeType,
+		Price:  price,
+		Amount: amount,
+	}, now)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if err != nil {
+		Logger.Errorf("[%s]Fail to place the child order:%v", a.ID, err)
+		a.errors++
+		return
+	}
+	a.children++
+	a.open += amount
+	a.next = now.Add(a.Order.Interval)
+}
+
+// openAmount the amount of the open child orders which isn't dealt
+func openAmount(open []*Strategy.Order) float64 {
+	var amount float64
+	for _, order := range open {
+		amount += order.Amount - order.DealAmount
+	}
+	return amount
+}
+
+// cancel the child orders which are open for the age
+func (a *Algo) cancel(open []*Strategy.Order, now time.Time, age time.Duration) {
+	for _, order := range open {
+		if order.Status == Exchange.OrderStatusCanceling || now.Sub(order.Time) < age {
+			continue
+		}
+		if err := a.orders.Cancel(order); err != nil {
+			Logger.Errorf("[%s]Fail to cancel the child order %s:%v", a.ID, order.ID, err)
+			continue
+		}
+		order.Status = Exchange.OrderStatusCanceling
+	}
+}
+
+func (a *Algo) finish(status StatusType, now time.Time) {
+	a.lock.Lock()
+	a.status = status
+	a.end = now
+	dealt := a.dealt
+	var avgPrice float64
+	if dealt > 0 {
+		avgPrice = a.cost / dealt
+	}
+	a.lock.Unlock()
+
+	a.execution.Filled(dealt, avgPrice)
+	Logger.Infof("[%s]Finished %s dealt:%v avg price:%v", a.ID, status, dealt, avgPrice)
+	close(a.done)
+}
+
+// targetAmount the amount which should be dealt by now, TWAP and VWAP release the slice at the beginning of it
+func (a *Algo) targetAmount(now time.Time) float64 {
+	elapsed := now.Sub(a.start) - a.paused + a.Order.Interval
+	switch a.Order.Type {
+	case AlgoTWAP:
+		return a.Order.Amount * scheduled(elapsed, a.Order.Duration, a.Order.Interval, nil)
+	case AlgoVWAP:
+		return a.Order.Amount * scheduled(elapsed, a.Order.Duration, a.Order.Interval, a.Order.Profile)
+	case AlgoPOV:
+		return math.Min(a.Order.Amount, a.market*a.Order.Participation)
+	}
+	return a.Order.Amount
+}
+
+func (a *Algo) volume(now time.Time) float64 {
+	if a.Volume != nil {
+		return a.Volume(a.start, now)
+	}
+	limit := int(now.Sub(a.start)/(time.Duration(profilePeriod)*time.Minute)) + 2
+	return marketVolume(Task.GlobalKlines.GetKline(a.exchange, a.Order.Pair, profilePeriod, limit), a.start, now)
+}
+
+// childPrice crosses the best price by the limit, and is bounded by the price of the parent order
+func (a *Algo) childPrice() float64 {
+	if a.Order.Type == AlgoIceberg {
+		return a.Order.Price
+	}
+
+	depth := a.exchange.GetDepthValue(a.Order.Pair)
+	buying := a.Order.TradeType == Exchange.TradeTypeOpenLong || a.Order.TradeType == Exchange.TradeTypeCloseShort ||
+		a.Order.TradeType == Exchange.TradeTypeBuy
+	side := Exchange.DepthTypeBids
+	if buying {
+		side = Exchange.DepthTypeAsks
+	}
+	if len(depth) <= side || len(depth[side]) == 0 {
+		return a.Order.Price
+	}
+
+	price := Task.GetPlacedPrice(a.Order.TradeType, depth[side][0].Price, a.Order.Limit)
+	if a.Order.Price > 0 {
+		if buying {
+			price = math.Min(price, a.Order.Price)
+		} else {
+			price = math.Max(price, a.Order.Price)
+		}
+	}
+	return price
+}
+
+func (a *Algo) Progress() Progress {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	progress := Progress{
+		ID:       a.ID,
+		Order:    a.Order,
+		Status:   a.status,
+		Start:    a.start,
+		Target:   a.target,
+		Dealt:    a.dealt,
+		Children: a.children,
+		Paused:   a.paused,
+		Open:     a.open,
+	}
+
+	stats := Stats{
+		FillRatio: a.dealt / a.Order.Amount,
+		Children:  a.children,
+		Errors:    a.errors,
+	}
+	if a.execution != nil {
+		stats.Arrival = a.execution.Record.Arrival
+	}
+	if a.dealt > 0 {
+		stats.AvgPrice = a.cost / a.dealt
+		stats.Shortfall = Task.Shortfall(a.Order.TradeType, stats.Arrival, stats.AvgPrice)
+	}
+	if a.market > 0 {
+		stats.Participation = a.dealt / a.market
+	}
+	if !a.start.IsZero() {
+		end := a.end
+		if end.IsZero() {
+			end = time.Now()
+		}
+		stats.Duration = end.Sub(a.start).Seconds()
+	}
+	progress.Stats = stats
+	return progress
+}
+
+// Manager the parent orders of the process, which are controlled by the API
+type Manager struct {
+	lock  sync.Mutex
+	algos map[string]*Algo
+}
+
+var GlobalAlgos = &Manager{algos: make(map[string]*Algo)}
+
+// algoExpiry the finished orders are removed after the time
+const algoExpiry = 24 * time.Hour
+
+func (m *Manager) Add(algo *Algo) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for id, existing := range m.algos {
+		existing.lock.Lock()
+		expired := !existing.end.IsZero() && time.Since(existing.end) > algoExpiry
+		existing.lock.Unlock()
+		if expired {
+			delete(m.algos, id)
+		}
+	}
+	m.algos[algo.ID] = algo
+}
+
+func (m *Manager) Get(id string) *Algo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.algos[id]
+}
+
+// List the progress of the orders from the latest
+func (m *Manager) List() []Progress {
+	m.lock.Lock()
+	algos := make([]*Algo, 0, len(m.algos))
+	for _, algo := range m.algos {
+		algos = append(algos, algo)
+	}
+	m.lock.Unlock()
+
+	list := make([]Progress, 0, len(algos))
+	for _, algo := range algos {
+		list = append(list, algo.Progress())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Start.After(list[j].Start) })
+	return list
+}
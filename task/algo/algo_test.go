@@ -0,0 +1,316 @@
+package algo
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+type algoExchange struct {
+	Exchange.IExchange
+	// fill the ratio of the child order which is dealt when it's placed
+	fill   float64
+	trades []Exchange.TradeConfig
+	orders map[string]*Exchange.OrderInfo
+}
+
+func newAlgoExchange(fill float64) *algoExchange {
+	return &algoExchange{fill: fill, orders: make(map[string]*Exchange.OrderInfo)}
+}
+
+func (p *algoExchange) GetExchangeName() string {
+	return "fake"
+}
+
+func (p *algoExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return [][]Exchange.DepthPrice{{{Price: 99, Quantity: 10}}, {{Price: 101, Quantity: 10}}}
+}
+
+func (p *algoExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	p.trades = append(p.trades, configs)
+	id := strconv.Itoa(len(p.trades))
+	order := &Exchange.OrderInfo{
+		OrderID:    id,
+		Pair:       configs.Pair,
+		Price:      configs.Price,
+		Amount:     configs.Amount,
+		DealAmount: configs.Amount * p.fill,
+		AvgPrice:   configs.Price,
+		Status:     Exchange.OrderStatusOpen,
+	}
+	if p.fill >= 1 {
+		order.Status = Exchange.OrderStatusDone
+	}
+	p.orders[id] = order
+	return &Exchange.TradeResult{OrderID: id}
+}
+
+func (p *algoExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	if order := p.orders[filter.OrderID]; order != nil {
+		return []Exchange.OrderInfo{*order}
+	}
+	return nil
+}
+
+func (p *algoExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	p.orders[order.OrderID].Status = Exchange.OrderStatusCanceled
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+func TestProfile(t *testing.T) {
+	start := time.Date(2018, 3, 5, 9, 0, 0, 0, time.UTC)
+	var records []Mongo.KlineRecord
+	for day := 1; day <= 2; day++ {
+		for minute := 0; minute < 60; minute += 5 {
+			volume := 1.0
+			if minute >= 30 {
+				volume = 3
+			}
+			records = append(records, Mongo.KlineRecord{OpenTime: start.AddDate(0, 0, -day).Add(time.Duration(minute) * time.Minute), Volume: volume})
+		}
+	}
+	// out of the window
+	records = append(records, Mongo.KlineRecord{OpenTime: start.Add(-time.Hour), Volume: 100})
+
+	profile := Profile(records, start, time.Hour, 30*time.Minute)
+	if len(profile) != 2 || math.Abs(profile[0]-0.25) > 1e-9 || math.Abs(profile[1]-0.75) > 1e-9 {
+		t.Fatalf("Invalid profile:%v", profile)
+	}
+	if ratio := scheduled(45*time.Minute, time.Hour, 30*time.Minute, profile); math.Abs(ratio-0.625) > 1e-9 {
+		t.Errorf("Invalid scheduled ratio:%v", ratio)
+	}
+	if ratio := scheduled(15*time.Minute, time.Hour, 30*time.Minute, nil); math.Abs(ratio-0.25) > 1e-9 {
+		t.Errorf("Invalid ratio of TWAP:%v", ratio)
+	}
+	if Profile(nil, start, time.Hour, 30*time.Minute) != nil {
+		t.Errorf("The profile without volume should be nil")
+	}
+}
+
+func TestMarketVolume(t *testing.T) {
+	start := time.Unix(1520000000, 0)
+	klines := []Exchange.KlineValue{
+		{OpenTime: float64(start.Add(-4 * time.Minute).Unix()), Volumn: 10},
+		{OpenTime: float64(start.Add(time.Minute).Unix()), Volumn: 5},
+		{OpenTime: float64(start.Add(-10 * time.Minute).Unix()), Volumn: 100},
+	}
+	if volume := marketVolume(klines, start, start.Add(10*time.Minute)); math.Abs(volume-7) > 1e-9 {
+		t.Errorf("Invalid market volume:%v", volume)
+	}
+}
+
+func TestTWAP(t *testing.T) {
+	if err, _ := NewAlgo("twap", newAlgoExchange(1), ParentOrder{Type: AlgoTWAP, Amount: 10}); err == nil {
+		t.Errorf("The duration should be required")
+	}
+
+	exchange := newAlgoExchange(1)
+	err, algo := NewAlgo("twap", exchange, ParentOrder{
+		Type:      AlgoTWAP,
+		Pair:      "eth/usdt",
+		TradeType: Exchange.TradeTypeBuy,
+		Amount:    10,
+		Price:     101.05,
+		Limit:     0.001,
+		Duration:  4 * time.Minute,
+		Interval:  time.Minute,
+		MinAmount: 1,
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the algo:%v", err)
+	}
+
+	start := time.Now()
+	algo.begin(start)
+	for seconds := 0; seconds <= 300; seconds += 15 {
+		algo.Step(start.Add(time.Duration(seconds) * time.Second))
+	}
+
+	// the slices are 2.5, which are rounded down to the unit amount and caught up by the next slices
+	var amounts []float64
+	for _, trade := range exchange.trades {
+		amounts = append(amounts, trade.Amount)
+		if trade.Price != 101.05 {
+			t.Errorf("The price should be bounded by the parent order:%v", trade.Price)
+		}
+	}
+	if len(amounts) != 4 || amounts[0] != 2 || amounts[1] != 3 || amounts[2] != 2 || amounts[3] != 3 {
+		t.Errorf("Invalid child orders:%v", amounts)
+	}
+
+	progress := algo.Progress()
+	if progress.Status != StatusDone || progress.Dealt != 10 || progress.Stats.Children != 4 || progress.Stats.FillRatio != 1 {
+		t.Errorf("Invalid progress:%v", progress)
+	}
+	// the arrival mid is 100
+	if math.Abs(progress.Stats.Shortfall-105) > 1e-6 {
+		t.Errorf("Invalid shortfall:%v", progress.Stats)
+	}
+	select {
+	case <-algo.Done():
+	default:
+		t.Errorf("The algo should be done")
+	}
+}
+
+func TestPOVPauseCancel(t *testing.T) {
+	exchange := newAlgoExchange(0.5)
+	err, algo := NewAlgo("pov", exchange, ParentOrder{
+		Type:          AlgoPOV,
+		Pair:          "eth/usdt",
+		TradeType:     Exchange.TradeTypeSell,
+		Amount:        100,
+		Participation: 0.1,
+		Interval:      time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the algo:%v", err)
+	}
+	algo.Volume = func(start time.Time, now time.Time) float64 {
+		return now.Sub(start).Minutes() * 60
+	}
+
+	start := time.Now()
+	algo.begin(start)
+	algo.Step(start.Add(time.Minute))
+	if len(exchange.trades) != 1 || exchange.trades[0].Amount != 6 || exchange.trades[0].Price != 99 {
+		t.Fatalf("Invalid child order:%v", exchange.trades)
+	}
+
+	// the open half is canceled after the interval, and the rest is placed again
+	algo.Step(start.Add(2 * time.Minute))
+	if exchange.orders["1"].Status != Exchange.OrderStatusCanceled {
+		t.Errorf("The child order should be canceled")
+	}
+	algo.Step(start.Add(2*time.Minute + time.Second))
+	if len(exchange.trades) != 2 || math.Abs(exchange.trades[1].Amount-(12.1-3)) > 1e-6 {
+		t.Errorf("Invalid child orders:%v", exchange.trades)
+	}
+
+	if err := algo.Pause(); err != nil {
+		t.Fatalf("Fail to pause:%v", err)
+	}
+	algo.Step(start.Add(3 * time.Minute))
+	algo.Step(start.Add(4 * time.Minute))
+	if len(exchange.trades) != 2 || exchange.orders["2"].Status != Exchange.OrderStatusCanceled {
+		t.Errorf("The paused algo should cancel the child orders:%v", exchange.trades)
+	}
+
+	if err := algo.Resume(); err != nil {
+		t.Fatalf("Fail to resume:%v", err)
+	}
+	if err := algo.Cancel(); err != nil {
+		t.Fatalf("Fail to cancel:%v", err)
+	}
+	algo.Step(start.Add(5 * time.Minute))
+	progress := algo.Progress()
+	if progress.Status != StatusCanceled || progress.Stats.Participation <= 0 || progress.Dealt >= 100 {
+		t.Errorf("Invalid progress:%v", progress)
+	}
+	if err := algo.Pause(); err == nil {
+		t.Errorf("The canceled algo shouldn't be paused")
+	}
+}
+
+func TestIceberg(t *testing.T) {
+	exchange := newAlgoExchange(0)
+	err, algo := NewAlgo("iceberg", exchange, ParentOrder{
+		Type:      AlgoIceberg,
+		Pair:      "eth/usdt",
+		TradeType: Exchange.TradeTypeBuy,
+		Amount:    5,
+		Price:     100,
+		Display:   2,
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the algo:%v", err)
+	}
+
+	start := time.Now()
+	algo.begin(start)
+	for i := 0; i < 5; i++ {
+		now := start.Add(time.Duration(i) * time.Hour)
+		algo.Step(now)
+		// the visible order is filled later
+		for _, order := range exchange.orders {
+			order.DealAmount = order.Amount
+			order.Status = Exchange.OrderStatusDone
+		}
+	}
+
+	if len(exchange.trades) != 3 || exchange.trades[0].Amount != 2 || exchange.trades[2].Amount != 1 || exchange.trades[2].Price != 100 {
+		t.Errorf("Invalid child orders:%v", exchange.trades)
+	}
+	if progress := algo.Progress(); progress.Status != StatusDone || progress.Dealt != 5 {
+		t.Errorf("Invalid progress:%v", progress)
+	}
+}
+
+// blockedExchange blocks the child order until it's released
+type blockedExchange struct {
+	*algoExchange
+	placing chan bool
+	release chan bool
+}
+
+func (p *blockedExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	p.placing <- true
+	<-p.release
+	return p.algoExchange.Trade(configs)
+}
+
+func TestStepWithoutLock(t *testing.T) {
+	exchange := &blockedExchange{algoExchange: newAlgoExchange(0), placing: make(chan bool), release: make(chan bool)}
+	err, algo := NewAlgo("blocked", exchange, ParentOrder{
+		Type:      AlgoTWAP,
+		Pair:      "eth/usdt",
+		TradeType: Exchange.TradeTypeBuy,
+		Amount:    10,
+		Duration:  10 * time.Minute,
+		Interval:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the algo:%v", err)
+	}
+
+	start := time.Now()
+	algo.begin(start)
+	stepped := make(chan bool)
+	go func() {
+		algo.Step(start)
+		close(stepped)
+	}()
+
+	// the order is paused and listed while the child order is being placed
+	<-exchange.placing
+	if err := algo.Pause(); err != nil {
+		t.Errorf("Fail to pause:%v", err)
+	}
+	if progress := algo.Progress(); progress.Status != StatusPaused {
+		t.Errorf("Invalid progress:%v", progress)
+	}
+	close(exchange.release)
+	<-stepped
+
+	if progress := algo.Progress(); progress.Children != 1 || progress.Open != 1 {
+		t.Errorf("Invalid progress:%v", progress)
+	}
+	// the paused algo cancels the child order
+	algo.Step(start.Add(time.Second))
+	if exchange.orders["1"].Status != Exchange.OrderStatusCanceled {
+		t.Errorf("The child order should be canceled")
+	}
+}
+
+func TestSubmitInvalid(t *testing.T) {
+	if err, _ := Submit(SubmitRequest{Venue: "unknown", Order: ParentOrder{Type: AlgoTWAP, Amount: 1, Duration: time.Minute}}); err == nil {
+		t.Errorf("The unknown venue should be rejected")
+	}
+	if err, _ := Submit(SubmitRequest{Venue: VenueBinance, Order: ParentOrder{Type: AlgoVWAP, Amount: 1}}); err == nil {
+		t.Errorf("The duration of VWAP should be required")
+	}
+}
@@ -0,0 +1,108 @@
+package algo
+
+import (
+	"math"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+// profilePeriod the period of the klines used by the volume profile and the market volume
+const profilePeriod = Exchange.KlinePeriod5Min
+
+// timeOfDay the offset from the midnight
+func timeOfDay(t time.Time) time.Duration {
+	year, month, day := t.Date()
+	return t.Sub(time.Date(year, month, day, 0, 0, 0, 0, t.Location()))
+}
+
+// Profile the volume weights of the slices from the start, the klines of the previous days are grouped by the time of
+// the day. It returns nil if no volume is found
+func Profile(records []Mongo.KlineRecord, start time.Time, duration time.Duration, interval time.Duration) []float64 {
+	if duration <= 0 || interval <= 0 {
+		return nil
+	}
+
+	slices := int(math.Ceil(float64(duration) / float64(interval)))
+	weights := make([]float64, slices)
+	var total float64
+	begin := timeOfDay(start)
+	for _, record := range records {
+		offset := timeOfDay(record.OpenTime.In(start.Location())) - begin
+		if offset < 0 {
+			offset += 24 * time.Hour
+		}
+		if offset >= duration {
+			continue
+		}
+		weights[int(offset/interval)] += record.Volume
+		total += record.Volume
+	}
+
+	if total <= 0 {
+		return nil
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights
+}
+
+// LoadProfile the volume profile from the klines of the days before the start
+func LoadProfile(db *Mongo.Klines, exchange string, pair string, start time.Time, duration time.Duration,
+	interval time.Duration, days int) (error, []float64) {
+
+	var records []Mongo.KlineRecord
+	for day := 1; day <= days; day++ {
+		begin := start.AddDate(0, 0, -day)
+		found, err := db.FindRange(exchange, pair, profilePeriod, begin, begin.Add(duration))
+		if err != nil {
+			return err, nil
+		}
+		records = append(records, found...)
+	}
+	return nil, Profile(records, start, duration, interval)
+}
+
+// scheduled the ratio of the amount which should be dealt after the elapsed time, the profile is linear in each slice
+func scheduled(elapsed time.Duration, duration time.Duration, interval time.Duration, profile []float64) float64 {
+	if duration <= 0 || elapsed >= duration {
+		return 1
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	if len(profile) == 0 {
+		return float64(elapsed) / float64(duration)
+	}
+
+	var ratio float64
+	slice := int(elapsed / interval)
+	for i := 0; i < slice && i < len(profile); i++ {
+		ratio += profile[i]
+	}
+	if slice < len(profile) {
+		ratio += profile[slice] * float64(elapsed-time.Duration(slice)*interval) / float64(interval)
+	}
+	return math.Min(ratio, 1)
+}
+
+// marketVolume the volume of the klines since the start, the kline which contains the start is counted in proportion
+func marketVolume(klines []Exchange.KlineValue, start time.Time, now time.Time) float64 {
+	period := time.Duration(profilePeriod) * time.Minute
+	var volume float64
+	for _, kline := range klines {
+		open := Exchange.KlineOpenTime(kline)
+		end := open.Add(period)
+		if open.IsZero() || !end.After(start) || !open.Before(now) {
+			continue
+		}
+		if open.Before(start) {
+			volume += kline.Volumn * float64(end.Sub(start)) / float64(period)
+			continue
+		}
+		volume += kline.Volumn
+	}
+	return volume
+}
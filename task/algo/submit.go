@@ -0,0 +1,207 @@
+package algo
+
+import (
+	"errors"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+	Utils "madaoQT/utils"
+)
+
+/*
+	提交母单：按venue用保存的密钥创建交易所，VWAP按前几天的5分钟K线生成成交量分布，
+	母单启动后由GlobalAlgos管理，结束后关闭交易所
+*/
+
+const (
+	VenueOkexV3     = "okexv3"
+	VenueOkexV3Spot = "okexv3spot"
+	VenueBinance    = "binance"
+	VenueBitmex     = "bitmex"
+	VenueDeribit    = "deribit"
+	VenueHuobiSpot  = "huobispot"
+	VenueHuobiDM    = "huobidm"
+)
+
+// defaultProfileDays the days of the klines of the VWAP profile
+const defaultProfileDays = 5
+
+type venue struct {
+	// Exchange the name of the keys saved in ExchangeDB
+	Exchange string
+	create   func(config Exchange.Config) Exchange.IExchange
+}
+
+func customString(config Exchange.Config, key string, value string) string {
+	if v, ok := config.Custom[key].(string); ok && v != "" {
+		return v
+	}
+	return value
+}
+
+var venues = map[string]*venue{
+	VenueOkexV3: {
+		Exchange: Exchange.NameOKEXV3,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.OKEXV3API{
+				InstrumentType: Exchange.InstrumentTypeSwap,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Passphare:      customString(config, "passphrase", ""),
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueOkexV3Spot: {
+		Exchange: Exchange.NameOKEXV3,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.OKEXV3API{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Passphare:      customString(config, "passphrase", ""),
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueBinance: {
+		Exchange: Exchange.NameBinance,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			binance := new(Exchange.Binance)
+			binance.SetConfigure(config)
+			return binance
+		},
+	},
+	VenueBitmex: {
+		Exchange: Exchange.NameBitmex,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			bitmex := new(Exchange.ExchangeBitmex)
+			bitmex.SetConfigure(config)
+			return bitmex
+		},
+	},
+	VenueDeribit: {
+		Exchange: Exchange.NameDeribit,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.DeribitV2API{
+				ApiKey:    config.API,
+				SecretKey: config.Secret,
+				Proxy:     config.Proxy,
+			}
+		},
+	},
+	VenueHuobiSpot: {
+		Exchange: Exchange.ExchangeHuobi,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueHuobiDM: {
+		Exchange: Exchange.ExchangeHuobi,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSwap,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+}
+
+// SubmitRequest the parent order submitted by the API
+type SubmitRequest struct {
+	Venue string `json:"venue"`
+	// Account the label of the keys, the empty one is the default account
+	Account string                 `json:"account"`
+	Custom  map[string]interface{} `json:"custom,omitempty"`
+	Proxy   string                 `json:"proxy,omitempty"`
+	// Days the days of the klines of the VWAP profile, 5 days by default
+	Days  int         `json:"days"`
+	Order ParentOrder `json:"order"`
+}
+
+// NewProfile the VWAP profile of the pair from the kline collection, see LoadProfile()
+func NewProfile(exchange string, pair string, start time.Time, duration time.Duration, interval time.Duration,
+	days int) (error, []float64) {
+
+	db := new(Mongo.Klines)
+	if err := db.Connect(); err != nil {
+		return err, nil
+	}
+	defer db.Close()
+
+	return LoadProfile(db, exchange, pair, start, duration, interval, days)
+}
+
+// Submit creates the exchange of the venue by the saved keys and starts the parent order, the exchange is closed after
+// the order is finished. VWAP falls back to TWAP if no volume is found
+func Submit(request SubmitRequest) (error, *Algo) {
+	venue := venues[request.Venue]
+	if venue == nil {
+		return errors.New("Invalid venue:" + request.Venue), nil
+	}
+
+	order := request.Order
+	if err := validate(order); err != nil {
+		return err, nil
+	}
+
+	mongo := new(Mongo.ExchangeDB)
+	if err := mongo.Connect(); err != nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb]), nil
+	}
+	defer mongo.Close()
+
+	err, record := mongo.FindAccount(venue.Exchange, request.Account)
+	if err != nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskAPINotFound]), nil
+	}
+
+	exchange := Task.NewRiskGateway(venue.create(Exchange.Config{
+		API:    string(record.API),
+		Secret: string(record.Secret),
+		Custom: request.Custom,
+		Proxy:  request.Proxy,
+	}), "algo", Task.RiskConfig{}, nil)
+	if err := exchange.Start(); err != nil {
+		exchange.Close()
+		return err, nil
+	}
+
+	if order.Type == AlgoVWAP && len(order.Profile) == 0 {
+		interval := order.Interval
+		if interval == 0 {
+			interval = defaultInterval
+		}
+		days := request.Days
+		if days <= 0 {
+			days = defaultProfileDays
+		}
+		err, profile := NewProfile(exchange.GetExchangeName(), order.Pair, time.Now(), order.Duration, interval, days)
+		if err != nil {
+			Logger.Warnf("Fail to load the volume profile, TWAP is used:%v", err)
+		}
+		order.Profile = profile
+	}
+
+	err, algo := NewAlgo(Utils.GetRandomHexString(12), exchange, order)
+	if err != nil {
+		exchange.Close()
+		return err, nil
+	}
+
+	algo.Start()
+	go func() {
+		<-algo.Done()
+		exchange.Close()
+	}()
+	return nil, algo
+}
@@ -0,0 +1,752 @@
+package arbitrage
+
+/*
+	跨交易所现货套利：按各交易所的深度计算扣除手续费后的可成交价差，
+	可成交数量受买方的计价币余额和卖方的基础币余额限制，价差达到最小利润时同时下单买入和卖出，
+	未成交部分立即撤单(IOC)，两边成交数量不一致时记录敞口，敞口超过单次数量时暂停该交易对，
+	定时检查各交易所的库存并提醒调仓
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	Global "madaoQT/config"
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+	Utils "madaoQT/utils"
+
+	"github.com/kataras/golog"
+)
+
+var Logger *golog.Logger
+
+func init() {
+	logger := golog.New()
+	Logger = logger
+	Logger.SetLevel("debug")
+	Logger.SetTimeFormat(Global.TimeFormat)
+	Logger.SetPrefix("[ARBITRAGE]")
+}
+
+const checkingPeriod = time.Second
+
+// balancePeriod the cached balances are refreshed periodically and after the trades
+const balancePeriod = time.Minute
+
+// maxAlerts the latest alerts are kept in memory
+const maxAlerts = 100
+
+// iocWaitCount the legs wait a short time before the rest is canceled
+const iocWaitCount = 3
+
+var iocWaitPeriod = 200 * time.Millisecond
+
+type PairConfig struct {
+	MinEdge   float64 `json:"minedge" title:"最小利润" desc:"扣除手续费后的百分比" min:"0" required:"true"`
+	Amount    float64 `json:"amount" title:"单次最大数量" desc:"敞口超过该数量时暂停交易" min:"0" required:"true"`
+	MinAmount float64 `json:"minamount" title:"单次最小数量" min:"0"`
+}
+
+type ArbitrageConfig struct {
+	Venues        []string               `json:"venues" title:"交易所" desc:"okex、huobi、binance、liqui、bittrex" required:"true" readonly:"true"`
+	Accounts      map[string]string      `json:"accounts,omitempty" title:"账户" desc:"按交易所设置密钥的账户名称，未设置的使用默认账户" readonly:"true"`
+	Pairs         map[string]*PairConfig `json:"pairs" title:"交易对" required:"true"`
+	Fees          map[string]float64     `json:"fees,omitempty" title:"手续费率" desc:"未设置的交易所使用默认费率"`
+	MinShare      float64                `json:"minshare" title:"库存下限" desc:"余额低于平均值的比例时提醒调仓" min:"0" max:"1"`
+	RebalanceSpec string                 `json:"rebalancespec" title:"库存检查" desc:"cron表达式" readonly:"true"`
+	Proxy         string                 `json:"proxy" title:"代理" readonly:"true"`
+	Risk          Task.RiskConfig        `json:"risk" title:"风控" desc:"每个交易所分别检查"`
+}
+
+var defaultConfig = ArbitrageConfig{
+	Venues: []string{VenueOKEX, VenueHuobi, VenueBinance},
+	Pairs: map[string]*PairConfig{
+		"eth/usdt": {MinEdge: 0.2, Amount: 0.5, MinAmount: 0.01},
+		"btc/usdt": {MinEdge: 0.2, Amount: 0.02, MinAmount: 0.001},
+	},
+	MinShare:      0.3,
+	RebalanceSpec: "0 0 * * * *",
+	Proxy:         "SOCKS5:127.0.0.1:1080",
+}
+
+// Alert the imbalance of the legs or the inventory
+type Alert struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+type Arbitrage struct {
+	config    ArbitrageConfig
+	exchanges map[string]Exchange.IExchange
+	trades    *Mongo.Trades
+	riskDB    *Mongo.RiskRecords
+
+	status    Task.StatusType
+	namespace string
+	scheduler *Task.Scheduler
+
+	// lock protects the balances, the exposures and the alerts which are read by the server
+	lock        sync.Mutex
+	balances    map[string]map[string]float64
+	balanceTime time.Time
+	// exposure the base coin bought minus sold of the pair, which is caused by the partly filled legs
+	exposure map[string]float64
+	alerts   []Alert
+
+	// pending the updated config, which is applied by the trading loop
+	pending     *ArbitrageConfig
+	pendingLock sync.Mutex
+
+	quit chan bool
+}
+
+func (p *Arbitrage) GetDescription() Task.Description {
+	return Task.Description{
+		Name:  "arbitrage",
+		Title: "跨交易所套利",
+		Desc:  "在多个现货交易所之间搬砖，按深度和余额计算可成交价差",
+	}
+}
+
+func (p *Arbitrage) GetDefaultConfig() interface{} {
+	return defaultConfig
+}
+
+// SetNamespace isolates the collections of the instance
+func (p *Arbitrage) SetNamespace(namespace string) {
+	p.namespace = namespace
+}
+
+// SetScheduler the inventory check is registered to the scheduler
+func (p *Arbitrage) SetScheduler(scheduler *Task.Scheduler) {
+	p.scheduler = scheduler
+}
+
+func (p *Arbitrage) GetStatus() Task.StatusType {
+	return p.status
+}
+
+func checkConfig(config ArbitrageConfig) Task.ConfigErrors {
+	var errs Task.ConfigErrors
+	if len(config.Venues) < 2 {
+		errs = append(errs, Task.ConfigError{Field: "venues", Message: "at least two venues"})
+	}
+	for _, name := range config.Venues {
+		if venues[name] == nil {
+			errs = append(errs, Task.ConfigError{Field: "venues", Message: "unsupported venue:" + name})
+		}
+	}
+	for name := range config.Fees {
+		if venues[name] == nil {
+			errs = append(errs, Task.ConfigError{Field: "fees", Message: "unsupported venue:" + name})
+		}
+	}
+	if len(config.Pairs) == 0 {
+		errs = append(errs, Task.ConfigError{Field: "pairs", Message: "at least one pair"})
+	}
+	for pair, pairConfig := range config.Pairs {
+		if len(Exchange.ParsePair(pair)) != 2 {
+			errs = append(errs, Task.ConfigError{Field: "pairs", Message: "invalid pair:" + pair})
+		} else if pairConfig == nil || pairConfig.Amount <= 0 || pairConfig.MinAmount > pairConfig.Amount {
+			errs = append(errs, Task.ConfigError{Field: "pairs", Message: "invalid amount of " + pair})
+		}
+	}
+	return errs
+}
+
+func (p *Arbitrage) Start(configJSON string) error {
+
+	if p.status != Task.StatusNone {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	config := defaultConfig
+	if configJSON != "" {
+		config = ArbitrageConfig{}
+		if err := Task.ParseConfig(configJSON, &config); err != nil {
+			Logger.Errorf("Fail to get config:%v", err)
+			return err
+		}
+	}
+	if errs := checkConfig(config); errs != nil {
+		return errs
+	}
+	if config.RebalanceSpec == "" {
+		config.RebalanceSpec = defaultConfig.RebalanceSpec
+	}
+	p.config = config
+
+	Logger.Infof("Config:%v", p.config)
+
+	mongo := new(Mongo.ExchangeDB)
+	if mongo.Connect() != nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+	defer mongo.Close()
+
+	p.riskDB = Task.ConnectRiskRecords()
+	exchanges := make(map[string]Exchange.IExchange)
+	closeAll := func() {
+		for _, exchange := range exchanges {
+			exchange.Close()
+		}
+		if p.riskDB != nil {
+			p.riskDB.Close()
+			p.riskDB = nil
+		}
+	}
+
+	var pairs []string
+	for pair := range p.config.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	for _, name := range p.config.Venues {
+		err, record := mongo.FindAccount(venues[name].Exchange, p.config.Accounts[name])
+		if err != nil {
+			closeAll()
+			return errors.New(Task.TaskErrorMsg[Task.TaskAPINotFound])
+		}
+
+		exchange := Task.NewRiskGateway(venues[name].create(Exchange.Config{
+			API:    string(record.API),
+			Secret: string(record.Secret),
+			Proxy:  p.config.Proxy,
+		}), Task.CollectionName(p.namespace, "arbitrage_"+name), p.config.Risk, p.riskDB)
+		if err := exchange.Start(); err != nil {
+			Logger.Errorf("Fail to start %s:%v", name, err)
+			exchange.Unregister()
+			closeAll()
+			return err
+		}
+		exchanges[name] = exchange
+
+		// the inventory held before the task is started is the position of the gateway
+		if err := exchange.SeedPositions(pairs); err != nil {
+			Logger.Errorf("Fail to load the positions of %s:%v", name, err)
+			closeAll()
+			return err
+		}
+	}
+
+	p.trades = &Mongo.Trades{
+		Config: &Mongo.DBConfig{
+			CollectionName: Task.CollectionName(p.namespace, "ArbitrageTrades"),
+		},
+	}
+	if err := p.trades.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the trades:%v", err)
+		p.trades = nil
+		closeAll()
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+
+	if p.scheduler != nil {
+		if err := p.scheduler.Add(Task.Job{
+			Name: p.rebalanceJob(),
+			Spec: p.config.RebalanceSpec,
+			Desc: "检查套利库存",
+			Run:  p.CheckInventory,
+		}); err != nil {
+			Logger.Errorf("Fail to add the job:%v", err)
+		}
+	}
+
+	Logger.Infof("启动套利:%v", p.config.Venues)
+	p.run(exchanges)
+	return nil
+}
+
+// run starts the trading loop on the exchanges
+func (p *Arbitrage) run(exchanges map[string]Exchange.IExchange) {
+	p.exchanges = exchanges
+	p.exposure = make(map[string]float64)
+	p.balanceTime = time.Time{}
+	p.status = Task.StatusProcessing
+	p.quit = make(chan bool)
+
+	for _, exchange := range exchanges {
+		go func(exchange Exchange.IExchange, quit chan bool) {
+			for {
+				select {
+				case <-quit:
+					return
+				case event := <-exchange.WatchEvent():
+					if event == Exchange.EventLostConnection && p.status == Task.StatusProcessing {
+						go Task.Reconnect(exchange)
+					}
+				}
+			}
+		}(exchange, p.quit)
+	}
+
+	go func(quit chan bool) {
+		for {
+			select {
+			case <-quit:
+				return
+			case <-time.After(checkingPeriod):
+				p.applyConfig()
+				p.Watch()
+			}
+		}
+	}(p.quit)
+}
+
+func (p *Arbitrage) rebalanceJob() string {
+	return Task.CollectionName(p.namespace, "ArbitrageRebalance")
+}
+
+// UpdateConfig 运行时更新交易对、利润和手续费，交易所不能修改
+func (p *Arbitrage) UpdateConfig(configJSON string) error {
+
+	if p.status != Task.StatusProcessing {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	var config ArbitrageConfig
+	if err := Task.ParseConfig(configJSON, &config); err != nil {
+		return err
+	}
+
+	errs := checkConfig(config)
+	if !sameVenues(config.Venues, p.config.Venues) {
+		errs = append(errs, Task.ConfigError{Field: "venues", Message: "can't be changed while the task is running"})
+	}
+	if errs != nil {
+		return errs
+	}
+	config.RebalanceSpec = p.config.RebalanceSpec
+
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+	p.pending = &config
+	return nil
+}
+
+func sameVenues(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Arbitrage) applyConfig() {
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+
+	if p.pending != nil {
+		p.config = *p.pending
+		p.pending = nil
+		for _, exchange := range p.exchanges {
+			if gateway, ok := exchange.(*Task.RiskGateway); ok {
+				gateway.SetConfig(p.config.Risk)
+			}
+		}
+		Logger.Infof("更新配置:%v", p.config)
+	}
+}
+
+// fees the configured fees override the defaults of the venues
+func (p *Arbitrage) fees() map[string]float64 {
+	fees := make(map[string]float64)
+	for name := range p.exchanges {
+		fees[name] = venues[name].Fee
+		if fee, ok := p.config.Fees[name]; ok {
+			fees[name] = fee
+		}
+	}
+	return fees
+}
+
+// coins the coins of all the pairs in lower case
+func (p *Arbitrage) coins() []string {
+	set := make(map[string]bool)
+	for pair := range p.config.Pairs {
+		for _, coin := range Exchange.ParsePair(pair) {
+			set[coin] = true
+		}
+	}
+
+	var coins []string
+	for coin := range set {
+		coins = append(coins, coin)
+	}
+	sort.Strings(coins)
+	return coins
+}
+
+// refreshBalances gets the balances of the coins from the venues, the venue failed keeps the last values
+func (p *Arbitrage) refreshBalances() {
+	balances := make(map[string]map[string]float64)
+	coins := p.coins()
+	for name, exchange := range p.exchanges {
+		values := exchange.GetBalance()
+		if values == nil {
+			Logger.Errorf("Fail to get the balances of %s", name)
+			p.lock.Lock()
+			balances[name] = p.balances[name]
+			p.lock.Unlock()
+			continue
+		}
+
+		balances[name] = make(map[string]float64)
+		for _, coin := range coins {
+			if amount, ok := Task.ParseBalance(values, coin); ok {
+				balances[name][coin] = amount
+			}
+		}
+	}
+
+	p.lock.Lock()
+	p.balances = balances
+	p.balanceTime = time.Now()
+	p.lock.Unlock()
+}
+
+// getBalances a copy of the cached balances
+func (p *Arbitrage) getBalances() map[string]map[string]float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	balances := make(map[string]map[string]float64)
+	for name, values := range p.balances {
+		balances[name] = make(map[string]float64)
+		for coin, amount := range values {
+			balances[name][coin] = amount
+		}
+	}
+	return balances
+}
+
+func (p *Arbitrage) alert(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	Logger.Warn(message)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.alerts = append(p.alerts, Alert{Time: time.Now(), Message: message})
+	if len(p.alerts) > maxAlerts {
+		p.alerts = p.alerts[len(p.alerts)-maxAlerts:]
+	}
+}
+
+// paused the pair isn't traded until the exposure is fixed
+func (p *Arbitrage) paused(pair string, config *PairConfig) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return math.Abs(p.exposure[pair]) >= config.Amount
+}
+
+func (p *Arbitrage) Watch() {
+	if p.status != Task.StatusProcessing {
+		return
+	}
+
+	if time.Since(p.balanceTime) > balancePeriod {
+		p.refreshBalances()
+	}
+
+	fees := p.fees()
+	balances := p.getBalances()
+
+	var pairs []string
+	for pair := range p.config.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	for _, pair := range pairs {
+		config := p.config.Pairs[pair]
+		if p.paused(pair, config) {
+			continue
+		}
+
+		books := make(map[string][][]Exchange.DepthPrice)
+		for name, exchange := range p.exchanges {
+			if depth := exchange.GetDepthValue(pair); len(depth) == 2 {
+				books[name] = depth
+			}
+		}
+
+		op := FindOpportunity(pair, *config, books, fees, balances)
+		if op == nil {
+			continue
+		}
+
+		p.execute(op)
+		// the balances are changed by the trades
+		p.refreshBalances()
+		balances = p.getBalances()
+	}
+}
+
+// execute places the buying and the selling legs at the same time
+func (p *Arbitrage) execute(op *Opportunity) {
+	Logger.Infof("套利 %s 买入%s:%v 卖出%s:%v 数量:%v 利润:%.3f%%",
+		op.Pair, op.Buy, op.BuyPrice, op.Sell, op.SellPrice, op.Amount, op.Edge)
+
+	batch := Utils.GetRandomHexString(12)
+
+	var bought, sold float64
+	var wait sync.WaitGroup
+	wait.Add(2)
+	go func() {
+		defer wait.Done()
+		bought = p.ioc(op.Buy, batch, Exchange.TradeConfig{
+			Batch:  batch,
+			Pair:   op.Pair,
+			Type:   Exchange.TradeTypeBuy,
+			Price:  op.BuyPrice,
+			Amount: op.Amount,
+		})
+	}()
+	go func() {
+		defer wait.Done()
+		sold = p.ioc(op.Sell, batch, Exchange.TradeConfig{
+			Batch:  batch,
+			Pair:   op.Pair,
+			Type:   Exchange.TradeTypeSell,
+			Price:  op.SellPrice,
+			Amount: op.Amount,
+		})
+	}()
+	wait.Wait()
+
+	if diff := bought - sold; math.Abs(diff) > minQuantity {
+		p.lock.Lock()
+		p.exposure[op.Pair] += diff
+		exposure := p.exposure[op.Pair]
+		p.lock.Unlock()
+		p.alert("%s 成交数量不一致 买入%s:%v 卖出%s:%v 敞口:%v", op.Pair, op.Buy, bought, op.Sell, sold, exposure)
+	}
+}
+
+// ioc places the limit order and cancels the rest if it isn't filled at once, returns the deal amount
+func (p *Arbitrage) ioc(name string, batch string, config Exchange.TradeConfig) float64 {
+	exchange := p.exchanges[name]
+
+	result := exchange.Trade(config)
+	if result == nil || result.Error != nil || result.OrderID == "" {
+		Logger.Errorf("[%s]下单失败:%v", name, result)
+		return 0
+	}
+
+	if p.trades != nil {
+		if err := p.trades.Insert(&Mongo.TradesRecord{
+			Batch:    batch,
+			Oper:     Exchange.TradeTypeString[config.Type],
+			Exchange: name,
+			Pair:     config.Pair,
+			Price:    config.Price,
+			Quantity: config.Amount,
+			OrderID:  result.OrderID,
+		}); err != nil {
+			Logger.Errorf("保存交易记录失败:%v", err)
+		}
+	}
+
+	filter := Exchange.OrderInfo{
+		OrderID: result.OrderID,
+		Pair:    config.Pair,
+		Type:    config.Type,
+	}
+
+	info := result.Info
+	for i := 0; i < iocWaitCount && (info == nil || info.Status != Exchange.OrderStatusDone); i++ {
+		time.Sleep(iocWaitPeriod)
+		if orders := exchange.GetOrderInfo(filter); len(orders) > 0 {
+			info = &orders[0]
+		}
+	}
+
+	if info == nil || info.Status != Exchange.OrderStatusDone {
+		exchange.CancelOrder(filter)
+		if orders := exchange.GetOrderInfo(filter); len(orders) > 0 {
+			info = &orders[0]
+		}
+	}
+
+	var dealAmount float64
+	if info != nil {
+		dealAmount = info.DealAmount
+	}
+
+	if p.trades != nil {
+		var err error
+		if info != nil && info.Status == Exchange.OrderStatusDone {
+			err = p.trades.SetDone(result.OrderID)
+		} else {
+			err = p.trades.SetCanceled(result.OrderID)
+		}
+		if err != nil {
+			Logger.Errorf("更新交易记录失败:%v", err)
+		}
+	}
+
+	return dealAmount
+}
+
+// CheckInventory 检查各交易所的库存，余额过低时提醒调仓
+func (p *Arbitrage) CheckInventory() error {
+	if p.status != Task.StatusProcessing {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	p.refreshBalances()
+	balances := p.getBalances()
+	for _, coin := range p.coins() {
+		if transfer := Rebalance(coin, balances, p.config.MinShare); transfer != nil {
+			p.alert("%s 库存不平衡，建议从%s转移%v到%s", coin, transfer.From, transfer.Amount, transfer.To)
+		}
+	}
+	return nil
+}
+
+func (p *Arbitrage) GetBalances() map[string]interface{} {
+	if p.status != Task.StatusProcessing {
+		return nil
+	}
+
+	balances := p.getBalances()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	exposure := make(map[string]float64)
+	for pair, amount := range p.exposure {
+		exposure[pair] = amount
+	}
+
+	return map[string]interface{}{
+		"balances": balances,
+		"exposure": exposure,
+		"alerts":   append([]Alert(nil), p.alerts...),
+	}
+}
+
+func (p *Arbitrage) GetTrades() []Mongo.TradesRecord {
+	if p.trades == nil {
+		return nil
+	}
+
+	err, records := p.trades.FindAll()
+	if err != nil {
+		Logger.Errorf("Fail to get trades:%v", err)
+		return nil
+	}
+	return records
+}
+
+// positions the exposures, including the paused ones if all is true
+func (p *Arbitrage) positions(all bool) []map[string]interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var pairs []string
+	for pair := range p.exposure {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	var positions []map[string]interface{}
+	for _, pair := range pairs {
+		amount := p.exposure[pair]
+		if math.Abs(amount) <= minQuantity {
+			continue
+		}
+		config := p.config.Pairs[pair]
+		paused := config == nil || math.Abs(amount) >= config.Amount
+		if all || paused {
+			positions = append(positions, map[string]interface{}{
+				"pair":     pair,
+				"exposure": amount,
+				"paused":   paused,
+			})
+		}
+	}
+	return positions
+}
+
+// GetPositions the exposures of the pairs
+func (p *Arbitrage) GetPositions() []map[string]interface{} {
+	return p.positions(true)
+}
+
+// GetFailedPositions the pairs paused by the exposures
+func (p *Arbitrage) GetFailedPositions() []map[string]interface{} {
+	return p.positions(false)
+}
+
+// FixFailedPosition 手动处理敞口后更新，如{"pair":"eth/usdt","exposure":0}
+func (p *Arbitrage) FixFailedPosition(updateJSON string) error {
+	var fix struct {
+		Pair     string  `json:"pair"`
+		Exposure float64 `json:"exposure"`
+	}
+	if err := json.Unmarshal([]byte(updateJSON), &fix); err != nil || fix.Pair == "" {
+		return errors.New(Task.TaskErrorMsg[Task.TaskInvalidInput])
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.exposure[fix.Pair]; !ok {
+		return errors.New(Task.TaskErrorMsg[Task.TaskInvalidInput])
+	}
+	Logger.Infof("更新敞口 %s:%v => %v", fix.Pair, p.exposure[fix.Pair], fix.Exposure)
+	p.exposure[fix.Pair] = fix.Exposure
+	return nil
+}
+
+// ForceClosePositions 套利没有持仓，敞口需要手动处理后调用FixFailedPosition
+func (p *Arbitrage) ForceClosePositions() {
+	Logger.Info("套利没有持仓")
+}
+
+func (p *Arbitrage) Close() {
+
+	Logger.Info("关闭任务")
+
+	p.status = Task.StatusNone
+
+	if p.quit != nil {
+		close(p.quit)
+		p.quit = nil
+	}
+
+	if p.scheduler != nil {
+		p.scheduler.Remove(p.rebalanceJob())
+	}
+
+	for _, exchange := range p.exchanges {
+		exchange.Close()
+	}
+	p.exchanges = nil
+
+	if p.trades != nil {
+		p.trades.Close()
+		p.trades = nil
+	}
+
+	if p.riskDB != nil {
+		p.riskDB.Close()
+		p.riskDB = nil
+	}
+}
@@ -0,0 +1,243 @@
+package arbitrage
+
+import (
+	"math"
+	"testing"
+
+	Exchange "madaoQT/exchange"
+	Task "madaoQT/task"
+)
+
+func TestSpread(t *testing.T) {
+	asks := []Exchange.DepthPrice{{Price: 100, Quantity: 1}, {Price: 101, Quantity: 1}, {Price: 103, Quantity: 5}}
+	bids := []Exchange.DepthPrice{{Price: 103, Quantity: 1.5}, {Price: 102, Quantity: 5}}
+
+	// the third ask is above the bids
+	op := Spread(asks, bids, 0, 0, 0, 10, 10000, 10)
+	if op == nil || math.Abs(op.Amount-2) > minQuantity || op.BuyPrice != 101 || op.SellPrice != 102 {
+		t.Fatalf("Invalid opportunity:%v", op)
+	}
+	if math.Abs(op.Cost-201) > 1e-6 || math.Abs(op.Income-205.5) > 1e-6 {
+		t.Errorf("Invalid cost or income:%v", op)
+	}
+
+	// the fees take the edge of the last level
+	op = Spread(asks, bids, 0.005, 0.005, 0, 10, 10000, 10)
+	if op == nil || math.Abs(op.Amount-1.5) > minQuantity || op.SellPrice != 103 {
+		t.Errorf("Invalid opportunity with the fees:%v", op)
+	}
+
+	// the minimum edge
+	if op = Spread(asks, bids, 0, 0, 3.5, 10, 10000, 10); op != nil {
+		t.Errorf("The edge is less than the minimum:%v", op)
+	}
+
+	// the amount and the balances
+	if op = Spread(asks, bids, 0, 0, 0, 0.5, 10000, 10); op == nil || op.Amount != 0.5 {
+		t.Errorf("The amount is limited:%v", op)
+	}
+	if op = Spread(asks, bids, 0, 0, 0, 10, 10000, 0.3); op == nil || op.Amount != 0.3 {
+		t.Errorf("The amount is limited by the base balance:%v", op)
+	}
+	if op = Spread(asks, bids, 0, 0, 0, 10, 50, 10); op == nil || math.Abs(op.Amount-0.5) > minQuantity {
+		t.Errorf("The amount is limited by the quote balance:%v", op)
+	}
+	if op = Spread(asks, bids, 0, 0, 0, 10, 0, 10); op != nil {
+		t.Errorf("There is no quote balance:%v", op)
+	}
+}
+
+func TestFindOpportunity(t *testing.T) {
+	books := map[string][][]Exchange.DepthPrice{
+		VenueOKEX: {
+			{{Price: 99, Quantity: 1}},
+			{{Price: 100, Quantity: 1}},
+		},
+		VenueBinance: {
+			{{Price: 102, Quantity: 1}},
+			{{Price: 103, Quantity: 1}},
+		},
+		VenueHuobi: {
+			{{Price: 101, Quantity: 1}},
+			{{Price: 102, Quantity: 1}},
+		},
+	}
+	fees := map[string]float64{VenueOKEX: 0.001, VenueBinance: 0.001, VenueHuobi: 0.001}
+	balances := map[string]map[string]float64{
+		VenueOKEX:    {"eth": 10, "usdt": 1000},
+		VenueBinance: {"eth": 10, "usdt": 1000},
+		VenueHuobi:   {"eth": 10, "usdt": 1000},
+	}
+	config := PairConfig{MinEdge: 0.1, Amount: 1}
+
+	op := FindOpportunity("eth/usdt", config, books, fees, balances)
+	if op == nil || op.Buy != VenueOKEX || op.Sell != VenueBinance || op.Pair != "eth/usdt" {
+		t.Fatalf("Invalid opportunity:%v", op)
+	}
+
+	// Binance has no eth to sell
+	balances[VenueBinance]["eth"] = 0
+	op = FindOpportunity("eth/usdt", config, books, fees, balances)
+	if op == nil || op.Buy != VenueOKEX || op.Sell != VenueHuobi {
+		t.Fatalf("Invalid opportunity:%v", op)
+	}
+
+	config.MinAmount = 2
+	if op = FindOpportunity("eth/usdt", config, books, fees, balances); op != nil {
+		t.Errorf("The amount is less than the minimum:%v", op)
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	balances := map[string]map[string]float64{
+		VenueOKEX:    {"eth": 9},
+		VenueBinance: {"eth": 0.5},
+		VenueHuobi:   {"eth": 5.5},
+	}
+
+	transfer := Rebalance("eth", balances, 0.3)
+	if transfer == nil || transfer.From != VenueOKEX || transfer.To != VenueBinance || transfer.Amount != 4.5 {
+		t.Errorf("Invalid transfer:%v", transfer)
+	}
+
+	if transfer = Rebalance("eth", balances, 0.1); transfer != nil {
+		t.Errorf("The inventory is balanced:%v", transfer)
+	}
+	if transfer = Rebalance("btc", balances, 0.3); transfer != nil {
+		t.Errorf("There is no btc:%v", transfer)
+	}
+}
+
+type fakeExchange struct {
+	Exchange.IExchange
+	depth    [][]Exchange.DepthPrice
+	balances map[string]interface{}
+	// fill the ratio of the amount filled
+	fill     float64
+	trades   []Exchange.TradeConfig
+	canceled int
+}
+
+func (p *fakeExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return p.depth
+}
+
+func (p *fakeExchange) GetBalance() map[string]interface{} {
+	return p.balances
+}
+
+func (p *fakeExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	p.trades = append(p.trades, configs)
+	return &Exchange.TradeResult{OrderID: configs.Batch}
+}
+
+func (p *fakeExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	trade := p.trades[len(p.trades)-1]
+	status := Exchange.OrderStatusOpen
+	if p.canceled > 0 {
+		status = Exchange.OrderStatusCanceled
+	}
+	if p.fill >= 1 {
+		status = Exchange.OrderStatusDone
+	}
+	return []Exchange.OrderInfo{{
+		OrderID:    filter.OrderID,
+		Pair:       trade.Pair,
+		Type:       trade.Type,
+		Amount:     trade.Amount,
+		DealAmount: trade.Amount * p.fill,
+		Status:     status,
+	}}
+}
+
+func (p *fakeExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	p.canceled++
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+func (p *fakeExchange) WatchEvent() chan Exchange.EventType {
+	return nil
+}
+
+func (p *fakeExchange) Close() {
+}
+
+func TestWatch(t *testing.T) {
+	iocWaitPeriod = 0
+
+	buy := &fakeExchange{
+		depth: [][]Exchange.DepthPrice{
+			{{Price: 99, Quantity: 1}},
+			{{Price: 100, Quantity: 1}},
+		},
+		balances: map[string]interface{}{"ETH": 0.0, "USDT": 1000.0},
+		fill:     0.5,
+	}
+	sell := &fakeExchange{
+		depth: [][]Exchange.DepthPrice{
+			{{Price: 103, Quantity: 1}},
+			{{Price: 104, Quantity: 1}},
+		},
+		balances: map[string]interface{}{"ETH": 2.0, "USDT": 0.0},
+		fill:     1,
+	}
+
+	config := ArbitrageConfig{
+		Venues:   []string{VenueOKEX, VenueBinance},
+		Pairs:    map[string]*PairConfig{"eth/usdt": {MinEdge: 0.5, Amount: 1}},
+		MinShare: 0.3,
+	}
+	task := &Arbitrage{config: config}
+	task.run(map[string]Exchange.IExchange{VenueOKEX: buy, VenueBinance: sell})
+	close(task.quit)
+	task.quit = nil
+
+	task.Watch()
+
+	if len(buy.trades) != 1 || buy.trades[0].Type != Exchange.TradeTypeBuy || buy.trades[0].Price != 100 ||
+		buy.trades[0].Amount != 1 || buy.canceled != 1 {
+		t.Fatalf("Invalid buying leg:%v", buy.trades)
+	}
+	if len(sell.trades) != 1 || sell.trades[0].Type != Exchange.TradeTypeSell || sell.trades[0].Price != 103 ||
+		sell.trades[0].Batch != buy.trades[0].Batch || sell.canceled != 0 {
+		t.Fatalf("Invalid selling leg:%v", sell.trades)
+	}
+
+	positions := task.GetPositions()
+	if len(positions) != 1 || positions[0]["exposure"] != -0.5 || positions[0]["paused"] != false {
+		t.Fatalf("Invalid exposure:%v", positions)
+	}
+	if len(task.GetFailedPositions()) != 0 || len(task.GetBalances()["alerts"].([]Alert)) != 1 {
+		t.Errorf("Invalid alerts:%v", task.GetBalances())
+	}
+
+	// the pair is paused once the exposure reaches the amount
+	task.Watch()
+	task.Watch()
+	if len(buy.trades) != 2 || len(task.GetFailedPositions()) != 1 {
+		t.Fatalf("The pair should be paused:%v %v", buy.trades, task.GetPositions())
+	}
+
+	if err := task.FixFailedPosition(`{"pair":"eth/usdt","exposure":0}`); err != nil {
+		t.Fatalf("Fail to fix:%v", err)
+	}
+	if task.GetPositions() != nil {
+		t.Errorf("The exposure should be fixed:%v", task.GetPositions())
+	}
+
+	// the inventory of eth is moved to OKEX
+	buy.balances = map[string]interface{}{"ETH": 2.0, "USDT": 500.0}
+	sell.balances = map[string]interface{}{"ETH": 0.2, "USDT": 500.0}
+	if err := task.CheckInventory(); err != nil {
+		t.Fatalf("Fail to check:%v", err)
+	}
+	alerts := task.GetBalances()["alerts"].([]Alert)
+	if len(alerts) != 3 {
+		t.Errorf("Invalid alerts:%v", alerts)
+	}
+
+	task.Close()
+	if task.GetStatus() != Task.StatusNone {
+		t.Errorf("The task should be closed")
+	}
+}
@@ -0,0 +1,175 @@
+package arbitrage
+
+import (
+	"math"
+	"sort"
+
+	Exchange "madaoQT/exchange"
+)
+
+// Opportunity buying the pair on one venue and selling it on another
+type Opportunity struct {
+	Pair string `json:"pair"`
+	Buy  string `json:"buy"`
+	Sell string `json:"sell"`
+	// BuyPrice and SellPrice the limit prices of the legs, which are the worst levels taken
+	BuyPrice  float64 `json:"buyprice"`
+	SellPrice float64 `json:"sellprice"`
+	Amount    float64 `json:"amount"`
+	// Cost and Income in the quote coin including the fees
+	Cost   float64 `json:"cost"`
+	Income float64 `json:"income"`
+	// Edge the net profit in percent
+	Edge float64 `json:"edge"`
+}
+
+func (o *Opportunity) Profit() float64 {
+	return o.Income - o.Cost
+}
+
+const minQuantity = 1e-9
+
+// Spread takes the asks of the buying venue and the bids of the selling venue level by level while the net edge of the
+// level is not less than minEdge(in percent). The quantity is limited by the amount, the quote balance of the buying
+// venue and the base balance of the selling venue
+func Spread(asks []Exchange.DepthPrice, bids []Exchange.DepthPrice, buyFee float64, sellFee float64,
+	minEdge float64, amount float64, quote float64, base float64) *Opportunity {
+
+	result := &Opportunity{}
+	limit := math.Min(amount, base)
+
+	i, j := 0, 0
+	var askLeft, bidLeft float64
+	if len(asks) > 0 {
+		askLeft = asks[0].Quantity
+	}
+	if len(bids) > 0 {
+		bidLeft = bids[0].Quantity
+	}
+
+	for i < len(asks) && j < len(bids) && limit-result.Amount > minQuantity {
+		ask := asks[i].Price * (1 + buyFee)
+		bid := bids[j].Price * (1 - sellFee)
+		if ask <= 0 || (bid-ask)*100/ask < minEdge {
+			break
+		}
+
+		quantity := math.Min(math.Min(askLeft, bidLeft), limit-result.Amount)
+		quantity = math.Min(quantity, (quote-result.Cost)/ask)
+		if quantity <= minQuantity {
+			break
+		}
+
+		result.Amount += quantity
+		result.Cost += quantity * ask
+		result.Income += quantity * bid
+		result.BuyPrice = asks[i].Price
+		result.SellPrice = bids[j].Price
+
+		if askLeft -= quantity; askLeft <= minQuantity {
+			if i++; i < len(asks) {
+				askLeft = asks[i].Quantity
+			}
+		}
+		if bidLeft -= quantity; bidLeft <= minQuantity {
+			if j++; j < len(bids) {
+				bidLeft = bids[j].Quantity
+			}
+		}
+	}
+
+	if result.Amount <= minQuantity {
+		return nil
+	}
+
+	result.Edge = (result.Income - result.Cost) * 100 / result.Cost
+	return result
+}
+
+// FindOpportunity returns the most profitable opportunity of the pair among the venues, the balances of the venues are
+// the free balances of the coins in lower case
+func FindOpportunity(pair string, config PairConfig, books map[string][][]Exchange.DepthPrice,
+	fees map[string]float64, balances map[string]map[string]float64) *Opportunity {
+
+	coins := Exchange.ParsePair(pair)
+	if len(coins) != 2 {
+		return nil
+	}
+
+	var names []string
+	for name := range books {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var best *Opportunity
+	for _, buy := range names {
+		for _, sell := range names {
+			if buy == sell || books[buy] == nil || books[sell] == nil {
+				continue
+			}
+
+			op := Spread(books[buy][Exchange.DepthTypeAsks], books[sell][Exchange.DepthTypeBids],
+				fees[buy], fees[sell], config.MinEdge, config.Amount, balances[buy][coins[1]], balances[sell][coins[0]])
+			if op == nil || op.Amount < config.MinAmount {
+				continue
+			}
+
+			op.Pair = pair
+			op.Buy = buy
+			op.Sell = sell
+			if best == nil || op.Profit() > best.Profit() {
+				best = op
+			}
+		}
+	}
+
+	return best
+}
+
+// Transfer the suggested transfer to rebalance the coin between the venues
+type Transfer struct {
+	Coin   string  `json:"coin"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+}
+
+// Rebalance suggests moving the coin from the venue with the most to the one with the least, if the least is below
+// minShare of the average. The arbitrage keeps buying on the cheap venue and selling on the expensive one, so the
+// inventory drifts
+func Rebalance(coin string, balances map[string]map[string]float64, minShare float64) *Transfer {
+	var names []string
+	for name := range balances {
+		names = append(names, name)
+	}
+	if len(names) < 2 {
+		return nil
+	}
+	sort.Strings(names)
+
+	var total float64
+	from, to := names[0], names[0]
+	for _, name := range names {
+		value := balances[name][coin]
+		total += value
+		if value > balances[from][coin] {
+			from = name
+		}
+		if value < balances[to][coin] {
+			to = name
+		}
+	}
+
+	average := total / float64(len(names))
+	if average <= 0 || balances[to][coin] >= average*minShare {
+		return nil
+	}
+
+	return &Transfer{
+		Coin:   coin,
+		From:   from,
+		To:     to,
+		Amount: average - balances[to][coin],
+	}
+}
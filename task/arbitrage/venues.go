@@ -0,0 +1,91 @@
+package arbitrage
+
+import (
+	Exchange "madaoQT/exchange"
+)
+
+/*
+	套利支持的现货交易所，keys保存在ExchangeDB中
+*/
+
+const (
+	VenueOKEX    = "okex"
+	VenueHuobi   = "huobi"
+	VenueBinance = "binance"
+	VenueLiqui   = "liqui"
+	VenueBittrex = "bittrex"
+)
+
+type venue struct {
+	// Exchange the name of the keys saved in ExchangeDB
+	Exchange string
+	// Fee the default taker fee
+	Fee    float64
+	create func(config Exchange.Config) Exchange.IExchange
+}
+
+// websocketExchange the exchanges like Huobi connect the websocket in Start2()
+type websocketExchange struct {
+	Exchange.IExchange
+	start func(event chan Exchange.EventType) error
+	event chan Exchange.EventType
+}
+
+func (p *websocketExchange) Start() error {
+	p.event = make(chan Exchange.EventType)
+	return p.start(p.event)
+}
+
+func (p *websocketExchange) WatchEvent() chan Exchange.EventType {
+	return p.event
+}
+
+var venues = map[string]*venue{
+	VenueOKEX: {
+		Exchange: Exchange.NameOKEX,
+		Fee:      0.0015,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return Exchange.NewOKExSpotApi(&config)
+		},
+	},
+	VenueHuobi: {
+		Exchange: Exchange.ExchangeHuobi,
+		Fee:      0.002,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			huobi := &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+			return &websocketExchange{IExchange: huobi, start: huobi.Start2}
+		},
+	},
+	VenueBinance: {
+		Exchange: Exchange.NameBinance,
+		Fee:      0.001,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			binance := new(Exchange.Binance)
+			binance.SetConfigure(config)
+			return binance
+		},
+	},
+	VenueLiqui: {
+		Exchange: Exchange.LiquiExchange,
+		Fee:      0.0025,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			liqui := new(Exchange.Liqui)
+			liqui.SetConfigure(config)
+			return liqui
+		},
+	},
+	VenueBittrex: {
+		Exchange: Exchange.BittrexExchangeName,
+		Fee:      0.0025,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			bittrex := new(Exchange.Bittrex)
+			bittrex.SetConfigure(config)
+			return bittrex
+		},
+	},
+}
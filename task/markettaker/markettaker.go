@@ -1,13 +1,28 @@
 // package markettaker
 package main
 
+/*
+	做市策略：以盘口中间价或微观价格为公允价同时挂买单和卖单，
+	按库存偏移报价，库存达到上限时只挂减仓方向，
+	深度变化时重新报价，两次报价之间至少间隔MinInterval，断线时撤销所有挂单
+*/
+
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/kataras/golog"
 
 	Global "madaoQT/config"
 	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+	Sdk "madaoQT/task/sdk"
+	Utils "madaoQT/utils"
 )
 
 var Logger *golog.Logger
@@ -17,41 +32,630 @@ func init() {
 	Logger = logger
 	Logger.SetLevel("debug")
 	Logger.SetTimeFormat(Global.TimeFormat)
-	Logger.SetPrefix("[PARA]")
+	Logger.SetPrefix("[MAKER]")
+}
+
+// pollingPeriod the streaming book is read from the cache of the exchange
+const pollingPeriod = 100 * time.Millisecond
+
+// syncPeriod the fills of the quotes are checked periodically
+const syncPeriod = time.Second
+
+// maxTrades the latest fills are kept in memory
+const maxTrades = 200
+
+// the sides of the quotes
+const (
+	sideBid = iota
+	sideAsk
+)
+
+type MarketTakerConfig struct {
+	Venue       string                 `json:"venue" title:"交易所" enum:"okex|okexfuture|okexv3|okexv3spot|huobispot|huobidm|deribit" required:"true" readonly:"true"`
+	Pair        string                 `json:"pair" title:"交易对" required:"true" readonly:"true"`
+	Fair        string                 `json:"fair" title:"公允价" enum:"mid|micro" required:"true"`
+	Spread      float64                `json:"spread" title:"报价价差(%)" desc:"买卖报价之间的距离" min:"0" required:"true"`
+	Size        float64                `json:"size" title:"报价数量" min:"0" required:"true"`
+	MaxPosition float64                `json:"maxposition" title:"最大库存" min:"0" required:"true"`
+	Skew        float64                `json:"skew" title:"库存偏移(%)" desc:"库存达到上限时报价中心的偏移" min:"0"`
+	Tolerance   float64                `json:"tolerance" title:"重新报价阈值(%)" desc:"挂单价格偏离超过阈值时撤单重挂" min:"0"`
+	MinInterval int                    `json:"mininterval" title:"最小报价间隔" desc:"毫秒" min:"0"`
+	Account     string                 `json:"account,omitempty" title:"账户" desc:"密钥的账户名称，为空时使用默认账户" readonly:"true"`
+	Custom      map[string]interface{} `json:"custom,omitempty" title:"交易所配置" desc:"如passphrase、period" readonly:"true"`
+	Proxy       string                 `json:"proxy" title:"代理" readonly:"true"`
+	Risk        Task.RiskConfig        `json:"risk" title:"风控"`
+}
+
+var defaultConfig = MarketTakerConfig{
+	Venue:       VenueHuobiSpot,
+	Pair:        "ltc/usdt",
+	Fair:        FairMicro,
+	Spread:      0.3,
+	Size:        1,
+	MaxPosition: 5,
+	Skew:        0.2,
+	Tolerance:   0.05,
+	MinInterval: 500,
+	Proxy:       "SOCKS5:127.0.0.1:1080",
+}
+
+// order the resting quote
+type order struct {
+	OrderID string
+	Type    Exchange.TradeType
+	Price   float64
+	Amount  float64
+	Dealt   float64
 }
 
 type MarketTaker struct {
-	binance Exchange.IExchange
+	exchange Exchange.IExchange
+	riskDB   *Mongo.RiskRecords
+	venue    *venue
+	config   MarketTakerConfig
+	status   Task.StatusType
+	// pending the updated config, which is applied by the watching loop
+	pending chan MarketTakerConfig
+
+	// lock protects the inventory, the quotes and the fills which are read by the server
+	lock     sync.Mutex
+	position float64
+	orders   [2]*order
+	trades   []Mongo.TradesRecord
+
+	book      string
+	lastQuote time.Time
+	lastSync  time.Time
+	// disconnected the quotes are canceled and resumed when the book is updated again
+	disconnected bool
+	staleBook    string
+	forceClose   bool
+	// paused the quoting is stopped after the inventory is closed, and resumed by updating the config
+	paused bool
+
+	quit chan bool
+}
+
+func (p *MarketTaker) GetDefaultConfig() interface{} {
+	return defaultConfig
+}
+
+func (p *MarketTaker) GetDescription() Task.Description {
+	return Task.Description{
+		Name:  "markettaker",
+		Title: "做市",
+		Desc:  "在推送深度的交易所双边报价",
+	}
+}
+
+func checkConfig(config MarketTakerConfig) Task.ConfigErrors {
+	var errs Task.ConfigErrors
+	if venues[config.Venue] == nil {
+		errs = append(errs, Task.ConfigError{Field: "venue", Message: "unsupported venue"})
+	}
+	if len(Exchange.ParsePair(config.Pair)) != 2 {
+		errs = append(errs, Task.ConfigError{Field: "pair", Message: "invalid pair"})
+	}
+	if config.Size <= 0 || config.Size > config.MaxPosition {
+		errs = append(errs, Task.ConfigError{Field: "size", Message: "should be positive and not more than maxposition"})
+	}
+	return errs
+}
+
+func (p *MarketTaker) Start(configJSON string) error {
+	if p.status != Task.StatusNone {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	config := defaultConfig
+	if configJSON != "" {
+		config = MarketTakerConfig{}
+		if err := Task.ParseConfig(configJSON, &config); err != nil {
+			return err
+		}
+	}
+	if errs := checkConfig(config); errs != nil {
+		return errs
+	}
+	p.config = config
+	p.venue = venues[config.Venue]
+
+	Logger.Infof("Config:%v", p.config)
+
+	mongo := new(Mongo.ExchangeDB)
+	if mongo.Connect() != nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+	defer mongo.Close()
+
+	err, record := mongo.FindAccount(p.venue.Exchange, p.config.Account)
+	if err != nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskAPINotFound])
+	}
+
+	p.riskDB = Task.ConnectRiskRecords()
+	exchange := Task.NewRiskGateway(p.venue.create(Exchange.Config{
+		API:    string(record.API),
+		Secret: string(record.Secret),
+		Custom: p.config.Custom,
+		Proxy:  p.config.Proxy,
+	}), "markettaker", p.config.Risk, p.riskDB)
+	if p.venue.Contract != nil {
+		exchange.SetContract(p.venue.Contract)
+	}
+	if err := exchange.Start(); err != nil {
+		Logger.Errorf("Fail to start:%v", err)
+		exchange.Unregister()
+		if p.riskDB != nil {
+			p.riskDB.Close()
+			p.riskDB = nil
+		}
+		return err
+	}
+
+	// the task isn't started if the positions held can't be loaded
+	if err := exchange.SeedPositions([]string{p.config.Pair}); err != nil {
+		Logger.Errorf("Fail to load the positions:%v", err)
+		exchange.Close()
+		if p.riskDB != nil {
+			p.riskDB.Close()
+			p.riskDB = nil
+		}
+		return err
+	}
+
+	Logger.Infof("启动%s做市:%s", p.config.Venue, p.config.Pair)
+	p.run(exchange)
+	return nil
+}
+
+// run starts the quoting loop on the exchange
+func (p *MarketTaker) run(exchange Exchange.IExchange) {
+	p.exchange = exchange
+	p.pending = make(chan MarketTakerConfig, 1)
+	p.book = ""
+	p.disconnected = false
+	p.paused = false
+	p.status = Task.StatusProcessing
+	p.quit = make(chan bool)
+
+	go func(quit chan bool) {
+		for {
+			select {
+			case <-quit:
+				return
+			case config := <-p.pending:
+				p.config = config
+				if gateway, ok := exchange.(*Task.RiskGateway); ok {
+					gateway.SetConfig(config.Risk)
+				}
+				// the quotes are replaced with the new parameters
+				p.book = ""
+				p.paused = false
+				Logger.Infof("Config:%v", config)
+			case event := <-exchange.WatchEvent():
+				p.onEvent(event)
+			case <-time.After(pollingPeriod):
+				p.Watch()
+			}
+		}
+	}(p.quit)
+}
+
+func (p *MarketTaker) onEvent(event Exchange.EventType) {
+	switch event {
+	case Exchange.EventLostConnection:
+		if p.status != Task.StatusProcessing {
+			return
+		}
+		Logger.Error("连接断开，撤销所有挂单")
+		p.disconnected = true
+		p.staleBook = ""
+		p.cancelAll()
+		go Task.Reconnect(p.exchange)
+	case Exchange.EventConnected:
+		if p.disconnected {
+			Logger.Info("连接恢复")
+			p.disconnected = false
+			p.book = ""
+		}
+	}
+}
+
+// UpdateConfig 运行时更新报价参数，交易所和交易对不能修改
+func (p *MarketTaker) UpdateConfig(configJSON string) error {
+	if p.status != Task.StatusProcessing {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	var config MarketTakerConfig
+	if err := Task.ParseConfig(configJSON, &config); err != nil {
+		return err
+	}
+
+	errs := checkConfig(config)
+	if config.Venue != p.config.Venue {
+		errs = append(errs, Task.ConfigError{Field: "venue", Message: "can't be changed while the task is running"})
+	}
+	if config.Pair != p.config.Pair {
+		errs = append(errs, Task.ConfigError{Field: "pair", Message: "can't be changed while the task is running"})
+	}
+	if errs != nil {
+		return errs
+	}
+
+	p.pending <- config
+	return nil
+}
+
+func (p *MarketTaker) Close() {
+	Logger.Info("关闭任务")
+
+	p.status = Task.StatusNone
+
+	if p.quit != nil {
+		close(p.quit)
+		p.quit = nil
+	}
+
+	if p.exchange != nil {
+		p.cancelAll()
+		p.exchange.Close()
+	}
+
+	if p.riskDB != nil {
+		p.riskDB.Close()
+		p.riskDB = nil
+	}
+}
+
+func (p *MarketTaker) GetStatus() Task.StatusType {
+	return p.status
+}
+
+func (p *MarketTaker) GetBalances() map[string]interface{} {
+	if p.exchange == nil {
+		return nil
+	}
+
+	var items []map[string]interface{}
+	if balances := p.exchange.GetBalance(); balances != nil {
+		for _, coin := range Exchange.ParsePair(p.config.Pair) {
+			if amount, ok := Task.ParseBalance(balances, coin); ok {
+				items = append(items, map[string]interface{}{
+					"name":    coin,
+					"balance": amount,
+				})
+			}
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return map[string]interface{}{
+		"venue":    p.config.Venue,
+		"balances": items,
+		"position": p.position,
+	}
+}
+
+// GetTrades the fills of the quotes
+func (p *MarketTaker) GetTrades() []Mongo.TradesRecord {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]Mongo.TradesRecord(nil), p.trades...)
 }
 
-func (p *MarketTaker) Start() error {
-	// 测试
-	p.binance = new(Exchange.Binance)
+// GetPositions the inventory and the resting quotes
+func (p *MarketTaker) GetPositions() []map[string]interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
 
-	for {
-		select {
-		case <-time.After(1 * time.Second):
-			p.Watch()
+	position := map[string]interface{}{
+		"pair":     p.config.Pair,
+		"position": p.position,
+	}
+	for side, name := range []string{"bid", "ask"} {
+		if o := p.orders[side]; o != nil {
+			position[name] = Quote{Price: o.Price, Amount: o.Amount - o.Dealt}
 		}
 	}
+	return []map[string]interface{}{position}
+}
+
+func (p *MarketTaker) GetFailedPositions() []map[string]interface{} { return nil }
+
+// FixFailedPosition 手动调整库存后更新，如{"position":0}
+func (p *MarketTaker) FixFailedPosition(updateJSON string) error {
+	var fix struct {
+		Position *float64 `json:"position"`
+	}
+	if err := json.Unmarshal([]byte(updateJSON), &fix); err != nil || fix.Position == nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskInvalidInput])
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	Logger.Infof("更新库存:%v => %v", p.position, *fix.Position)
+	p.position = *fix.Position
 	return nil
 }
 
+// ForceClosePositions 在下一次检查时撤单并平掉库存，更新配置后恢复报价
+func (p *MarketTaker) ForceClosePositions() {
+	p.forceClose = true
+}
+
+// bookKey the quotes are refreshed when the best levels are changed
+func bookKey(depth [][]Exchange.DepthPrice) string {
+	bid := depth[Exchange.DepthTypeBids][0]
+	ask := depth[Exchange.DepthTypeAsks][0]
+	return fmt.Sprintf("%v:%v|%v:%v", bid.Price, bid.Quantity, ask.Price, ask.Quantity)
+}
+
 func (p *MarketTaker) Watch() {
+	if p.status != Task.StatusProcessing {
+		return
+	}
+
+	depth := p.exchange.GetDepthValue(p.config.Pair)
+	fair, ok := FairPrice(depth, p.config.Fair)
+	if !ok {
+		// the book is lost or stale, the quotes can't be priced
+		if p.book != "" {
+			Logger.Warn("深度无效，撤销所有挂单")
+			p.cancelAll()
+			p.book = ""
+		}
+		return
+	}
+
+	if time.Since(p.lastSync) >= syncPeriod {
+		p.syncOrders()
+	}
+
+	if p.forceClose {
+		p.forceClose = false
+		p.cancelAll()
+		p.flatten(depth)
+		p.paused = true
+		return
+	}
+	if p.paused {
+		return
+	}
+
+	key := bookKey(depth)
+	if p.disconnected {
+		// the exchanges which don't notify the connection resume once the cached book is updated
+		if p.staleBook == "" {
+			p.staleBook = key
+		}
+		if key == p.staleBook {
+			return
+		}
+		Logger.Info("深度恢复")
+		p.disconnected = false
+	}
+
+	p.lock.Lock()
+	missing := p.orders[sideBid] == nil || p.orders[sideAsk] == nil
+	p.lock.Unlock()
+
+	if key == p.book && !missing {
+		return
+	}
+	if time.Since(p.lastQuote) < time.Duration(p.config.MinInterval)*time.Millisecond {
+		return
+	}
+
+	p.book = key
+	p.lastQuote = time.Now()
+	p.quote(depth, fair)
+}
+
+// quote replaces the quotes which are away from the target by more than the tolerance
+func (p *MarketTaker) quote(depth [][]Exchange.DepthPrice, fair float64) {
+	p.lock.Lock()
+	position := p.position
+	p.lock.Unlock()
+
+	bid, ask := Quotes(p.config, fair, depth[Exchange.DepthTypeBids][0].Price,
+		depth[Exchange.DepthTypeAsks][0].Price, position)
+
+	for side, target := range []*Quote{bid, ask} {
+		p.lock.Lock()
+		o := p.orders[side]
+		p.lock.Unlock()
 
-	depths := p.binance.GetDepthValue("ltc/usdt")
-	// log.Printf("Depth:%v", depths)
-	ask := depths[Exchange.DepthTypeAsks][0]
-	bid := depths[Exchange.DepthTypeBids][0]
+		if o != nil {
+			if target != nil && math.Abs(o.Price-target.Price)*100/fair <= p.config.Tolerance &&
+				math.Abs(o.Amount-o.Dealt-target.Amount) <= minQuantity {
+				continue
+			}
+			if !p.cancel(side) {
+				continue
+			}
+		}
+
+		if target != nil {
+			p.place(side, target, position)
+		}
+	}
+}
 
-	diff := (ask.Price - bid.Price) * 100 / bid.Price
-	if diff > 0.3 {
-		Logger.Debugf("Diff:%v", diff)
+// tradeType the futures close the opposite position before opening
+func (p *MarketTaker) tradeType(side int, position float64, amount float64) (Exchange.TradeType, float64) {
+	if p.venue != nil && p.venue.Spot {
+		if side == sideBid {
+			return Exchange.TradeTypeBuy, amount
+		}
+		return Exchange.TradeTypeSell, amount
 	}
 
+	if side == sideBid {
+		if position < -minQuantity {
+			return Exchange.TradeTypeCloseShort, math.Min(amount, -position)
+		}
+		return Exchange.TradeTypeOpenLong, amount
+	}
+	if position > minQuantity {
+		return Exchange.TradeTypeCloseLong, math.Min(amount, position)
+	}
+	return Exchange.TradeTypeOpenShort, amount
+}
+
+func (p *MarketTaker) place(side int, quote *Quote, position float64) {
+	tradeType, amount := p.tradeType(side, position, quote.Amount)
+
+	result := p.exchange.Trade(Exchange.TradeConfig{
+		Batch:  Utils.GetRandomHexString(12),
+		Pair:   p.config.Pair,
+		Type:   tradeType,
+		Price:  quote.Price,
+		Amount: amount,
+	})
+	if result == nil || result.Error != nil || result.OrderID == "" {
+		Logger.Errorf("报价失败:%v", result)
+		return
+	}
+
+	o := &order{
+		OrderID: result.OrderID,
+		Type:    tradeType,
+		Price:   quote.Price,
+		Amount:  amount,
+	}
+	p.lock.Lock()
+	p.orders[side] = o
+	p.lock.Unlock()
+
+	if result.Info != nil {
+		p.update(side, *result.Info)
+	}
+}
+
+// update records the fills of the quote, the quote is removed once it's closed
+func (p *MarketTaker) update(side int, info Exchange.OrderInfo) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	o := p.orders[side]
+	if o == nil || info.OrderID != "" && info.OrderID != o.OrderID {
+		return
+	}
+
+	if dealt := info.DealAmount - o.Dealt; dealt > minQuantity {
+		if side == sideBid {
+			p.position += dealt
+		} else {
+			p.position -= dealt
+		}
+		o.Dealt = info.DealAmount
+
+		price := info.AvgPrice
+		if price == 0 {
+			price = o.Price
+		}
+		p.trades = append(p.trades, Mongo.TradesRecord{
+			Time:     time.Now(),
+			Oper:     Exchange.TradeTypeString[o.Type],
+			Exchange: p.config.Venue,
+			Pair:     p.config.Pair,
+			Price:    price,
+			Quantity: dealt,
+			OrderID:  o.OrderID,
+			Status:   Mongo.TradeStatusDone,
+		})
+		if len(p.trades) > maxTrades {
+			p.trades = p.trades[len(p.trades)-maxTrades:]
+		}
+		Logger.Infof("成交 %s 价格:%v 数量:%v 库存:%v", Exchange.TradeTypeString[o.Type], price, dealt, p.position)
+	}
+
+	switch info.Status {
+	case Exchange.OrderStatusDone, Exchange.OrderStatusCanceled, Exchange.OrderStatusRejected, Exchange.OrderStatusExpired:
+		p.orders[side] = nil
+	}
+}
+
+func (p *MarketTaker) orderFilter(o *order) Exchange.OrderInfo {
+	return Exchange.OrderInfo{
+		OrderID: o.OrderID,
+		Pair:    p.config.Pair,
+		Type:    o.Type,
+	}
+}
+
+// syncOrders checks the fills of the resting quotes
+func (p *MarketTaker) syncOrders() {
+	p.lastSync = time.Now()
+	for _, side := range []int{sideBid, sideAsk} {
+		p.lock.Lock()
+		o := p.orders[side]
+		p.lock.Unlock()
+
+		if o != nil {
+			if orders := p.exchange.GetOrderInfo(p.orderFilter(o)); len(orders) > 0 {
+				p.update(side, orders[0])
+			}
+		}
+	}
+}
+
+// cancel cancels the quote and records the fills before it's canceled, returns false if the quote is still open
+func (p *MarketTaker) cancel(side int) bool {
+	p.lock.Lock()
+	o := p.orders[side]
+	p.lock.Unlock()
+
+	if o == nil {
+		return true
+	}
+
+	filter := p.orderFilter(o)
+	p.exchange.CancelOrder(filter)
+	if orders := p.exchange.GetOrderInfo(filter); len(orders) > 0 {
+		p.update(side, orders[0])
+	}
+
+	// the quote is kept until the cancellation is confirmed, so that it isn't quoted twice
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.orders[side] == o {
+		Logger.Warnf("撤单未确认:%v", o.OrderID)
+		return false
+	}
+	return true
+}
+
+func (p *MarketTaker) cancelAll() {
+	p.cancel(sideBid)
+	p.cancel(sideAsk)
+}
+
+// flatten closes the inventory at the best price of the other side
+func (p *MarketTaker) flatten(depth [][]Exchange.DepthPrice) {
+	p.lock.Lock()
+	position := p.position
+	p.lock.Unlock()
+
+	if math.Abs(position) <= minQuantity {
+		return
+	}
+
+	Logger.Infof("平掉库存:%v", position)
+	if position > 0 {
+		p.place(sideAsk, &Quote{Price: depth[Exchange.DepthTypeBids][0].Price, Amount: position}, position)
+	} else {
+		p.place(sideBid, &Quote{Price: depth[Exchange.DepthTypeAsks][0].Price, Amount: -position}, position)
+	}
 }
 
 func main() {
-	marketMaker := new(MarketTaker)
-	marketMaker.Start()
+	if err := Sdk.Serve(func() Task.ITask {
+		return new(MarketTaker)
+	}, Logger); err != nil {
+		Logger.Errorf("Fail to serve:%v", err)
+	}
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+)
+
+func equal(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func book(bid float64, bidQuantity float64, ask float64, askQuantity float64) [][]Exchange.DepthPrice {
+	return [][]Exchange.DepthPrice{
+		{{Price: bid, Quantity: bidQuantity}},
+		{{Price: ask, Quantity: askQuantity}},
+	}
+}
+
+func TestFairPrice(t *testing.T) {
+	depth := book(99, 3, 101, 1)
+
+	if fair, ok := FairPrice(depth, FairMid); !ok || fair != 100 {
+		t.Errorf("Invalid mid price:%v", fair)
+	}
+	// the ask is thin, so the price is closer to it
+	if fair, ok := FairPrice(depth, FairMicro); !ok || fair != 100.5 {
+		t.Errorf("Invalid microprice:%v", fair)
+	}
+	if _, ok := FairPrice(book(101, 1, 99, 1), FairMid); ok {
+		t.Errorf("The crossed book should be invalid")
+	}
+	if _, ok := FairPrice(nil, FairMid); ok {
+		t.Errorf("The empty book should be invalid")
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	config := MarketTakerConfig{Spread: 1, Size: 1, MaxPosition: 2, Skew: 0.5}
+
+	bid, ask := Quotes(config, 100, 99.9, 100.1, 0)
+	if bid == nil || ask == nil || !equal(bid.Price, 99.5) || !equal(ask.Price, 100.5) || bid.Amount != 1 || ask.Amount != 1 {
+		t.Fatalf("Invalid quotes:%v %v", bid, ask)
+	}
+
+	// the long inventory lowers the quotes and the bid is limited
+	bid, ask = Quotes(config, 100, 99.9, 100.1, 1.5)
+	if bid == nil || ask == nil || bid.Amount != 0.5 || ask.Amount != 1 ||
+		!equal(bid.Price, 99.126875) || !equal(ask.Price, 100.123125) {
+		t.Fatalf("Invalid skewed quotes:%v %v", bid, ask)
+	}
+
+	// only the reducing side is quoted at the limit
+	if bid, ask = Quotes(config, 100, 99.9, 100.1, -2); bid == nil || ask != nil || bid.Amount != 1 {
+		t.Fatalf("Invalid quotes at the limit:%v %v", bid, ask)
+	}
+
+	// the quotes stay passive
+	config.Spread = 0.1
+	config.Skew = 5
+	if bid, ask = Quotes(config, 100, 99.99, 100.01, -2); bid.Price != 99.99 {
+		t.Errorf("The bid shouldn't cross the book:%v", bid)
+	}
+}
+
+type fakeExchange struct {
+	Exchange.IExchange
+	depth  [][]Exchange.DepthPrice
+	orders map[string]*Exchange.OrderInfo
+	trades []Exchange.TradeConfig
+	// canceled the IDs of the canceled orders
+	canceled []string
+}
+
+func (p *fakeExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return p.depth
+}
+
+func (p *fakeExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	p.trades = append(p.trades, configs)
+	id := strconv.Itoa(len(p.trades))
+	p.orders[id] = &Exchange.OrderInfo{
+		OrderID: id,
+		Pair:    configs.Pair,
+		Type:    configs.Type,
+		Price:   configs.Price,
+		Amount:  configs.Amount,
+		Status:  Exchange.OrderStatusOpen,
+	}
+	return &Exchange.TradeResult{OrderID: id}
+}
+
+func (p *fakeExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	if order := p.orders[filter.OrderID]; order != nil {
+		return []Exchange.OrderInfo{*order}
+	}
+	return nil
+}
+
+func (p *fakeExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	p.canceled = append(p.canceled, order.OrderID)
+	if info := p.orders[order.OrderID]; info != nil && info.Status == Exchange.OrderStatusOpen {
+		info.Status = Exchange.OrderStatusCanceled
+	}
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+func (p *fakeExchange) WatchEvent() chan Exchange.EventType {
+	return nil
+}
+
+func (p *fakeExchange) Close() {
+}
+
+func newTask(exchange *fakeExchange) *MarketTaker {
+	task := &MarketTaker{
+		config: MarketTakerConfig{
+			Venue:       VenueHuobiSpot,
+			Pair:        "ltc/usdt",
+			Fair:        FairMid,
+			Spread:      1,
+			Size:        1,
+			MaxPosition: 2,
+			Tolerance:   0.2,
+		},
+		venue: venues[VenueHuobiSpot],
+	}
+	task.run(exchange)
+	close(task.quit)
+	task.quit = nil
+	return task
+}
+
+func TestWatch(t *testing.T) {
+	exchange := &fakeExchange{depth: book(99.9, 1, 100.1, 1), orders: map[string]*Exchange.OrderInfo{}}
+	task := newTask(exchange)
+
+	task.Watch()
+	if len(exchange.trades) != 2 || exchange.trades[0].Type != Exchange.TradeTypeBuy || !equal(exchange.trades[0].Price, 99.5) ||
+		exchange.trades[1].Type != Exchange.TradeTypeSell || !equal(exchange.trades[1].Price, 100.5) {
+		t.Fatalf("Invalid quotes:%v", exchange.trades)
+	}
+
+	// the quotes are kept if the book isn't changed or the change is within the tolerance
+	task.Watch()
+	exchange.depth = book(99.95, 1, 100.15, 1)
+	task.Watch()
+	if len(exchange.trades) != 2 || len(exchange.canceled) != 0 {
+		t.Fatalf("The quotes shouldn't be replaced:%v %v", exchange.trades, exchange.canceled)
+	}
+
+	// the bid is filled partly before the book moves
+	exchange.orders["1"].DealAmount = 0.4
+	exchange.depth = book(100.9, 1, 101.1, 1)
+	task.Watch()
+	if len(exchange.canceled) != 2 || len(exchange.trades) != 4 {
+		t.Fatalf("The quotes should be replaced:%v %v", exchange.trades, exchange.canceled)
+	}
+	if !equal(task.position, 0.4) || len(task.GetTrades()) != 1 || exchange.trades[2].Amount != 1 || exchange.trades[3].Amount != 1 {
+		t.Fatalf("Invalid inventory:%v %v", task.position, exchange.trades)
+	}
+
+	// the fill is found by the periodical check
+	exchange.orders["4"].DealAmount = 1
+	exchange.orders["4"].Status = Exchange.OrderStatusDone
+	task.lastSync = time.Time{}
+	task.Watch()
+	if !equal(task.position, -0.6) || len(exchange.trades) != 5 || exchange.trades[4].Type != Exchange.TradeTypeSell {
+		t.Fatalf("The ask should be quoted again:%v %v", task.position, exchange.trades)
+	}
+
+	// the minimum interval
+	task.config.MinInterval = 60000
+	exchange.depth = book(102, 1, 102.2, 1)
+	task.Watch()
+	if len(exchange.trades) != 5 {
+		t.Fatalf("The quotes shouldn't be replaced in the interval:%v", exchange.trades)
+	}
+	task.config.MinInterval = 0
+
+	// the quotes are canceled on the disconnection and resumed when the book is updated
+	task.onEvent(Exchange.EventLostConnection)
+	if task.orders[sideBid] != nil || task.orders[sideAsk] != nil {
+		t.Fatalf("The quotes should be canceled:%v", task.orders)
+	}
+	task.Watch()
+	if len(exchange.trades) != 5 {
+		t.Fatalf("The quotes shouldn't be placed before the book is updated:%v", exchange.trades)
+	}
+	exchange.depth = book(102.1, 1, 102.3, 1)
+	task.Watch()
+	if len(exchange.trades) != 7 {
+		t.Fatalf("The quotes should be resumed:%v", exchange.trades)
+	}
+
+	// the stale book
+	exchange.depth = nil
+	task.Watch()
+	if task.orders[sideBid] != nil || task.orders[sideAsk] != nil {
+		t.Fatalf("The quotes should be canceled without the book:%v", task.orders)
+	}
+}
+
+func TestForceClose(t *testing.T) {
+	exchange := &fakeExchange{depth: book(99.9, 1, 100.1, 1), orders: map[string]*Exchange.OrderInfo{}}
+	task := newTask(exchange)
+
+	task.Watch()
+	task.position = 1.5
+	task.ForceClosePositions()
+	task.Watch()
+
+	if len(exchange.canceled) != 2 || len(exchange.trades) != 3 || exchange.trades[2].Type != Exchange.TradeTypeSell ||
+		exchange.trades[2].Price != 99.9 || exchange.trades[2].Amount != 1.5 {
+		t.Fatalf("Invalid closing:%v %v", exchange.trades, exchange.canceled)
+	}
+
+	// the quoting is paused until the config is updated
+	exchange.depth = book(98, 1, 98.2, 1)
+	task.Watch()
+	if len(exchange.trades) != 3 {
+		t.Fatalf("The quoting should be paused:%v", exchange.trades)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+
+	Exchange "madaoQT/exchange"
+)
+
+// the methods of the fair price
+const (
+	FairMid   = "mid"
+	FairMicro = "micro"
+)
+
+const minQuantity = 1e-9
+
+// FairPrice mid is the average of the best bid and ask, microprice weights them by the quantity on the other side so
+// the price leans to the side which is about to be taken
+func FairPrice(depth [][]Exchange.DepthPrice, method string) (float64, bool) {
+	if len(depth) != 2 || len(depth[Exchange.DepthTypeBids]) == 0 || len(depth[Exchange.DepthTypeAsks]) == 0 {
+		return 0, false
+	}
+
+	bid := depth[Exchange.DepthTypeBids][0]
+	ask := depth[Exchange.DepthTypeAsks][0]
+	if bid.Price <= 0 || ask.Price < bid.Price {
+		return 0, false
+	}
+
+	if method == FairMicro && bid.Quantity+ask.Quantity > 0 {
+		return (bid.Price*ask.Quantity + ask.Price*bid.Quantity) / (bid.Quantity + ask.Quantity), true
+	}
+	return (bid.Price + ask.Price) / 2, true
+}
+
+type Quote struct {
+	Price  float64 `json:"price"`
+	Amount float64 `json:"amount"`
+}
+
+// Quotes places the bid and the ask around the fair price. The center is shifted against the inventory by up to Skew
+// percent at the position limit, and the side which would exceed the limit is not quoted. The quotes never cross the
+// book so that they stay passive
+func Quotes(config MarketTakerConfig, fair float64, bestBid float64, bestAsk float64, position float64) (*Quote, *Quote) {
+	var skew float64
+	if config.MaxPosition > 0 {
+		skew = config.Skew * math.Max(-1, math.Min(1, position/config.MaxPosition))
+	}
+
+	center := fair * (1 - skew/100)
+	half := config.Spread / 200
+
+	var bid, ask *Quote
+	if amount := math.Min(config.Size, config.MaxPosition-position); amount > minQuantity {
+		bid = &Quote{Price: center * (1 - half), Amount: amount}
+		if bid.Price >= bestAsk {
+			bid.Price = bestBid
+		}
+	}
+	if amount := math.Min(config.Size, config.MaxPosition+position); amount > minQuantity {
+		ask = &Quote{Price: center * (1 + half), Amount: amount}
+		if ask.Price <= bestBid {
+			ask.Price = bestAsk
+		}
+	}
+
+	return bid, ask
+}
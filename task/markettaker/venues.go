@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+
+	Exchange "madaoQT/exchange"
+	Task "madaoQT/task"
+)
+
+/*
+	做市支持的交易所，深度由websocket推送，GetDepthValue读取的是缓存的深度
+*/
+
+const (
+	VenueOKEX       = "okex"
+	VenueOKEXFuture = "okexfuture"
+	VenueOkexV3     = "okexv3"
+	VenueOkexV3Spot = "okexv3spot"
+	VenueHuobiSpot  = "huobispot"
+	VenueHuobiDM    = "huobidm"
+	VenueDeribit    = "deribit"
+)
+
+type venue struct {
+	// Exchange the name of the keys saved in ExchangeDB
+	Exchange string
+	// Spot the quotes are buying and selling, otherwise opening and closing the positions
+	Spot bool
+	// Contract the contracts of the futures for the risk checks
+	Contract func(pair string) Task.RiskContract
+	create   func(config Exchange.Config) Exchange.IExchange
+}
+
+// websocketExchange the exchanges like Huobi connect the websocket in Start2()
+type websocketExchange struct {
+	Exchange.IExchange
+	start func(event chan Exchange.EventType) error
+	event chan Exchange.EventType
+}
+
+func (p *websocketExchange) Start() error {
+	p.event = make(chan Exchange.EventType)
+	return p.start(p.event)
+}
+
+func (p *websocketExchange) WatchEvent() chan Exchange.EventType {
+	return p.event
+}
+
+// GetAccount the positions of the wrapped exchange are seeded into the risk gateway
+func (p *websocketExchange) GetAccount() (error, *Exchange.AccountInfo) {
+	if account, ok := p.IExchange.(Exchange.IAccount); ok {
+		return account.GetAccount()
+	}
+	return errors.New("The account isn't supported"), nil
+}
+
+func customString(config Exchange.Config, key string, value string) string {
+	if v, ok := config.Custom[key].(string); ok && v != "" {
+		return v
+	}
+	return value
+}
+
+var venues = map[string]*venue{
+	VenueOKEX: {
+		Exchange: Exchange.NameOKEX,
+		Spot:     true,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return Exchange.NewOKExSpotApi(&config)
+		},
+	},
+	VenueOKEXFuture: {
+		Exchange: Exchange.NameOKEX,
+		Contract: Task.InverseContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			future := new(Exchange.OKExAPI)
+			config.Custom = map[string]interface{}{
+				"exchangeType": Exchange.ExchangeTypeFuture,
+				"period":       customString(config, "period", "quarter"),
+			}
+			future.SetConfigure(config)
+			return future
+		},
+	},
+	VenueOkexV3: {
+		Exchange: Exchange.NameOKEXV3,
+		Contract: Task.InverseContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			okex := &Exchange.OKEXV3API{
+				InstrumentType: Exchange.InstrumentTypeSwap,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Passphare:      customString(config, "passphrase", ""),
+				Proxy:          config.Proxy,
+			}
+			return &websocketExchange{IExchange: okex, start: okex.Start2}
+		},
+	},
+	VenueOkexV3Spot: {
+		Exchange: Exchange.NameOKEXV3,
+		Spot:     true,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			okex := &Exchange.OKEXV3API{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Passphare:      customString(config, "passphrase", ""),
+				Proxy:          config.Proxy,
+			}
+			return &websocketExchange{IExchange: okex, start: okex.Start2}
+		},
+	},
+	VenueHuobiSpot: {
+		Exchange: Exchange.ExchangeHuobi,
+		Spot:     true,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			huobi := &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+			return &websocketExchange{IExchange: huobi, start: huobi.Start2}
+		},
+	},
+	VenueHuobiDM: {
+		Exchange: Exchange.ExchangeHuobi,
+		Contract: Task.InverseContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			huobi := &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSwap,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+			return &websocketExchange{IExchange: huobi, start: huobi.Start2}
+		},
+	},
+	VenueDeribit: {
+		Exchange: Exchange.NameDeribit,
+		Contract: Task.USDContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			deribit := &Exchange.DeribitV2API{
+				ApiKey:    config.API,
+				SecretKey: config.Secret,
+				Proxy:     config.Proxy,
+			}
+			return &websocketExchange{IExchange: deribit, start: deribit.Start2}
+		},
+	},
+}
@@ -20,11 +20,11 @@ import (
 	Mongo "madaoQT/mongo"
 	Message "madaoQT/server/websocket"
 	Task "madaoQT/task"
+	Algo "madaoQT/task/algo"
 	Utils "madaoQT/utils"
 
 	Websocket "github.com/gorilla/websocket"
 	"github.com/kataras/golog"
-	Cron "github.com/robfig/cron"
 )
 
 /*
@@ -58,16 +58,31 @@ type IAnalyzer struct {
 	opIndex             uint
 
 	tradeDB *Mongo.Trades
+	riskDB  *Mongo.RiskRecords
+	// futureRisk and spotRisk the gateways are assigned to future and spot after the exchanges are connected, they're
+	// closed by Close() even if the connections never succeed
+	futureRisk *Task.RiskGateway
+	spotRisk   *Task.RiskGateway
 	// orderDB *Mongo.Orders
 	diffDB      *Mongo.OKExDiff
 	diffList    *list.List
 	diffBalance float64
 	forceClose  bool
 
-	conn *Websocket.Conn
-	cron *Cron.Cron
+	conn      *Websocket.Conn
+	scheduler *Task.Scheduler
 
 	errorCount int
+	// namespace the prefix of the collections of the instance
+	namespace string
+
+	// pending the updated config, which is applied by the trading loop
+	pending     *AnalyzerConfig
+	pendingLock sync.Mutex
+
+	// algos the parent orders which open the coins
+	algos    map[string]*Algo.Algo
+	algoLock sync.Mutex
 }
 
 type OperationItem struct {
@@ -77,20 +92,28 @@ type OperationItem struct {
 }
 
 type AnalyzerConfig struct {
-	API        string
-	Secret     string
-	Area       map[string]*TriggerArea `json:"area"`
-	LimitOpen  float64                 `json:"limitopen"`
-	LimitClose float64                 `json:"limitclose"`
-	UnitAmount float64                 `json:"unitamount"`
-	AutoAdjust bool                    `json:"autoadjust"`
-	StepValue  float64                 `json:"stepvalue"`
+	API        string                  `title:"API Key" readonly:"true"`
+	Secret     string                  `title:"Secret Key" readonly:"true"`
+	Account    string                  `json:"account,omitempty" title:"账户" desc:"密钥的账户名称，为空时使用默认账户" readonly:"true"`
+	Area       map[string]*TriggerArea `json:"area" title:"开平仓价差" desc:"按币种设置" required:"true"`
+	LimitOpen  float64                 `json:"limitopen" title:"价格波动范围" min:"0" max:"0.1" required:"true"`
+	LimitClose float64                 `json:"limitclose" title:"止损幅度" min:"0" max:"1" required:"true"`
+	UnitAmount float64                 `json:"unitamount" title:"单位数量" min:"0" required:"true"`
+	AutoAdjust bool                    `json:"autoadjust" title:"自动调整价差"`
+	StepValue  float64                 `json:"stepvalue" title:"调整步长" min:"0"`
+	// OpenPause and ClosePause use the default windows if they are not set, the empty window disables the pause
+	OpenPause  *Task.Window `json:"openpause,omitempty" title:"暂停开仓时段"`
+	ClosePause *Task.Window `json:"closepause,omitempty" title:"暂停平仓时段"`
+	Risk       Task.RiskConfig `json:"risk" title:"风控" desc:"合约和现货分别检查"`
+	Recovery   RecoveryConfig  `json:"recovery" title:"单腿成交处理" desc:"补单失败时平掉多成交的部分"`
+	// Algo the opening amount is sliced into the unit amount by the algorithm if it's set
+	Algo *AlgoConfig `json:"algo,omitempty" title:"拆单开仓" desc:"可开仓数量按算法拆成单位数量的子单"`
 }
 
 type TriggerArea struct {
-	Open   float64 `json:"open"`
-	Close  float64 `json:"close"`
-	Amount float64 `json:"amount"`
+	Open   float64 `json:"open" title:"开仓价差" required:"true"`
+	Close  float64 `json:"close" title:"平仓价差" required:"true"`
+	Amount float64 `json:"amount" title:"数量" min:"0" required:"true"`
 }
 
 // const CheckPeriodSec = 10
@@ -115,8 +138,15 @@ var defaultConfig = AnalyzerConfig{
 	LimitOpen:  0.005, // 允许操作价格的波动范围
 	UnitAmount: 50,
 	StepValue:  0.8,
+	// 周五交割前后
+	OpenPause:  &Task.Window{Start: "0 0 11 * * 5", End: "0 0 17 * * 5"},
+	ClosePause: &Task.Window{Start: "0 0 17 * * 5", End: "0 0 0 * * 6"},
+	Recovery:   RecoveryConfig{MaxSlippage: 1, Steps: 3},
 }
 
+// recordBalancesSpec the daily balance snapshot
+const recordBalancesSpec = "@daily"
+
 var constContractRatio = map[string]float64{
 	"btc": 100,
 	"ltc": 10,
@@ -131,6 +161,16 @@ func (a *IAnalyzer) GetDescription() Task.Description {
 	}
 }
 
+// SetNamespace isolates the collections of the instance
+func (a *IAnalyzer) SetNamespace(namespace string) {
+	a.namespace = namespace
+}
+
+// SetScheduler the daily balance snapshot is registered to the scheduler
+func (a *IAnalyzer) SetScheduler(scheduler *Task.Scheduler) {
+	a.scheduler = scheduler
+}
+
 func (a *IAnalyzer) GetDefaultConfig() interface{} {
 	return defaultConfig
 }
@@ -171,7 +211,11 @@ func (a *IAnalyzer) wsPublish(topic string, message string) {
 }
 
 func (a *IAnalyzer) RecordBalances() {
-	balanceDB := new(Mongo.Balances)
+	balanceDB := &Mongo.Balances{
+		Config: &Mongo.DBConfig{
+			CollectionName: Task.CollectionName(a.namespace, Mongo.BalancesCollection),
+		},
+	}
 	if err := balanceDB.Connect(); err != nil {
 		Logger.Errorf("Fail to connect BalanceDB:%v", err)
 		return
@@ -413,10 +457,13 @@ func (a *IAnalyzer) Start(configJSON string) error {
 
 	if configJSON != "" {
 		var config AnalyzerConfig
-		err := json.Unmarshal([]byte(configJSON), &config)
+		err := Task.ParseConfig(configJSON, &config)
 		if err != nil {
 			log.Printf("Fail to get config:%v", err)
-			return errors.New(Task.TaskErrorMsg[Task.TaskInvalidConfig])
+			return err
+		}
+		if errs := append(checkWindows(config), checkAlgo(config)...); errs != nil {
+			return errs
 		}
 		a.config = config
 	} else {
@@ -430,25 +477,37 @@ func (a *IAnalyzer) Start(configJSON string) error {
 		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
 	}
 
-	err, record := mongo.FindOne(Exchange.NameOKEX)
+	err, record := mongo.FindAccount(Exchange.NameOKEX, a.config.Account)
 	if err != nil {
 		return errors.New(Task.TaskErrorMsg[Task.TaskAPINotFound])
 	}
 
 	a.ops = make(map[uint]*OperationItem)
+	// the exchanges of the last run are assigned again after they're connected
+	a.future, a.spot = nil, nil
 	a.diffList = list.New()
 	a.checkPeriodSec = CheckingPeriod
 	a.forceClose = false
 	a.errorCount = 0
 	a.tradeDB = &Mongo.Trades{
 		Config: &Mongo.DBConfig{
-			CollectionName: "DiffOKExFutureSpotTrade",
+			CollectionName: Task.CollectionName(a.namespace, "DiffOKExFutureSpotTrade"),
 		},
 	}
 
-	// a.cron = Cron.New()
-	// a.cron.AddFunc("@daily", a.RecordBalances)
-	// a.cron.Start()
+	if a.scheduler != nil {
+		if err := a.scheduler.Add(Task.Job{
+			Name: a.recordBalancesJob(),
+			Spec: recordBalancesSpec,
+			Desc: "记录余额",
+			Run: func() error {
+				a.RecordBalances()
+				return nil
+			},
+		}); err != nil {
+			Logger.Errorf("Fail to add the job:%v", err)
+		}
+	}
 
 	err = a.tradeDB.Connect()
 	if err != nil {
@@ -458,7 +517,7 @@ func (a *IAnalyzer) Start(configJSON string) error {
 
 	a.diffDB = &Mongo.OKExDiff{
 		Config: &Mongo.DBConfig{
-			CollectionName: "DiffOKExHistory",
+			CollectionName: Task.CollectionName(a.namespace, "DiffOKExHistory"),
 		},
 	}
 
@@ -498,14 +557,45 @@ func (a *IAnalyzer) Start(configJSON string) error {
 		return err
 	}
 
-	a.fund = new(OkexFundManage)
+	// the orders are checked by the gateways, which are kept through the reconnections
+	a.riskDB = Task.ConnectRiskRecords()
+	futureRisk := Task.NewRiskGateway(futureExchange, Task.CollectionName(a.namespace, "okexdiff_future"), a.config.Risk, a.riskDB)
+	spotRisk := Task.NewRiskGateway(spotExchange, Task.CollectionName(a.namespace, "okexdiff_spot"), a.config.Risk, a.riskDB)
+	a.futureRisk, a.spotRisk = futureRisk, spotRisk
+	futureRisk.SetContract(func(pair string) Task.RiskContract {
+		return Task.RiskContract{Value: constContractRatio[Exchange.ParsePair(pair)[0]], Inverse: true}
+	})
+
+	a.fund = &OkexFundManage{Namespace: a.namespace}
 	a.fund.Init()
 
+	// load current positions, the task isn't started if they can't be loaded and reconciled
+	err = a.loadPosition()
+	if err == nil {
+		err = Task.GlobalReconciler.Check(Task.CollectionName(a.namespace, "okexdiff"), a.reconcile(Exchange.Config{
+			API:    string(record.API),
+			Secret: string(record.Secret),
+			Proxy:  "SOCKS5:127.0.0.1:1080",
+		}, spotExchange))
+	}
+	if err != nil {
+		Logger.Errorf("Fail to reconcile the positions:%v", err)
+		a.Close()
+		return err
+	}
+
 	a.wsConnect()
 	a.status = Task.StatusProcessing
 
-	// load current positions
-	a.loadPosition()
+	for _, op := range a.ops {
+		if op != nil {
+			// the operations keep the closing orders of the positions
+			futureRisk.AddPosition(op.futureConfig.Pair, openingType(op.futureConfig.Type),
+				op.futureConfig.Amount, op.futureConfig.Price)
+			spotRisk.AddPosition(op.spotConfig.Pair, openingType(op.spotConfig.Type),
+				op.spotConfig.Amount, op.spotConfig.Price)
+		}
+	}
 	a.loadDiffHistory()
 
 	go func() {
@@ -518,7 +608,7 @@ func (a *IAnalyzer) Start(configJSON string) error {
 						pair := (k + "/usdt")
 						futureExchange.GetDepthValue(pair)
 					}
-					a.future = Exchange.IExchange(futureExchange)
+					a.future = futureRisk
 
 				} else if event == Exchange.EventLostConnection {
 					if a.status != Task.StatusNone && a.status != Task.StatusError {
@@ -532,7 +622,7 @@ func (a *IAnalyzer) Start(configJSON string) error {
 						spotExchange.GetDepthValue(pair)
 					}
 
-					a.spot = Exchange.IExchange(spotExchange)
+					a.spot = spotRisk
 
 				} else if event == Exchange.EventLostConnection {
 					if a.status != Task.StatusNone && a.status != Task.StatusError {
@@ -545,6 +635,7 @@ func (a *IAnalyzer) Start(configJSON string) error {
 					return
 				}
 
+				a.applyConfig()
 				a.Watch()
 			}
 		}
@@ -553,13 +644,89 @@ func (a *IAnalyzer) Start(configJSON string) error {
 	return nil
 }
 
-func (a *IAnalyzer) loadPosition() {
+// UpdateConfig 运行时更新价差、数量和币种，有持仓的币种不能删除
+func (a *IAnalyzer) UpdateConfig(configJSON string) error {
+
+	if a.status != Task.StatusProcessing {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	var config AnalyzerConfig
+	if err := Task.ParseConfig(configJSON, &config); err != nil {
+		return err
+	}
+
+	errs := append(checkWindows(config), checkAlgo(config)...)
+	if config.API != a.config.API || config.Secret != a.config.Secret {
+		errs = append(errs, Task.ConfigError{Field: "API", Message: "can't be changed while the task is running"})
+	}
+
+	for coin, area := range config.Area {
+		if _, ok := constContractRatio[coin]; !ok {
+			errs = append(errs, Task.ConfigError{Field: "area." + coin, Message: "unsupported coin"})
+		} else if area == nil {
+			errs = append(errs, Task.ConfigError{Field: "area." + coin, Message: "is required"})
+		}
+	}
+
+	err, records := a.fund.CheckPosition()
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		coin := Exchange.ParsePair(record.Pair)[0]
+		if config.Area[coin] == nil {
+			errs = append(errs, Task.ConfigError{Field: "area." + coin, Message: "has open positions"})
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	a.pendingLock.Lock()
+	defer a.pendingLock.Unlock()
+	a.pending = &config
+	return nil
+}
+
+func (a *IAnalyzer) applyConfig() {
+	a.pendingLock.Lock()
+	defer a.pendingLock.Unlock()
+
+	if a.pending != nil {
+		// 检查后新开仓的币种保留原配置
+		for _, op := range a.ops {
+			if op == nil {
+				continue
+			}
+			coin := Exchange.ParsePair(op.spotConfig.Pair)[0]
+			if a.pending.Area[coin] == nil {
+				if a.pending.Area == nil {
+					a.pending.Area = make(map[string]*TriggerArea)
+				}
+				a.pending.Area[coin] = a.config.Area[coin]
+			}
+		}
+
+		a.config = *a.pending
+		a.pending = nil
+		for _, exchange := range []Exchange.IExchange{a.future, a.spot} {
+			if gateway, ok := exchange.(*Task.RiskGateway); ok {
+				gateway.SetConfig(a.config.Risk)
+			}
+		}
+		Logger.Infof("更新配置:%v", a.config)
+	}
+}
+
+func (a *IAnalyzer) loadPosition() error {
 	var records []Mongo.FundInfo
 	var err error
 
 	if err, records = a.fund.CheckPosition(); err != nil {
 		Logger.Errorf("Fail to load positions:%v", err)
-		return
+		return err
 	}
 
 	if records != nil && len(records) > 0 {
@@ -589,6 +756,18 @@ func (a *IAnalyzer) loadPosition() {
 			a.opIndex++
 		}
 	}
+	return nil
+}
+
+// openingType the trade which opened the position closed by the trade
+func openingType(closing Exchange.TradeType) Exchange.TradeType {
+	switch closing {
+	case Exchange.TradeTypeCloseLong:
+		return Exchange.TradeTypeOpenLong
+	case Exchange.TradeTypeCloseShort:
+		return Exchange.TradeTypeOpenShort
+	}
+	return Exchange.RevertTradeType(closing)
 }
 
 func (a *IAnalyzer) loadDiffHistory() {
@@ -657,6 +836,8 @@ func (a *IAnalyzer) Watch() {
 	// var orderAmount = 50
 	// var orderFutureQuantity = orderAmount / 10
 
+	a.stepAlgos()
+
 	for coin := range a.config.Area {
 		pair := coin + "/usdt"
 
@@ -705,6 +886,7 @@ func (a *IAnalyzer) Watch() {
 
 		// 检测是否需要平仓
 		if a.checkPosition(coin, askFuture, bidFuture, askSpot, bidSpot) {
+			a.cancelAlgo(coin)
 			a.adjustDuration(true)
 			Logger.Info("已执行平仓操作...不做交易")
 			continue
@@ -726,7 +908,7 @@ func (a *IAnalyzer) Watch() {
 		}
 
 		if !a.checkOpenTime() {
-			Logger.Info("周五10点至17点不开仓")
+			Logger.Info("暂停开仓时段不开仓")
 			continue
 		}
 
@@ -735,46 +917,20 @@ func (a *IAnalyzer) Watch() {
 
 			Logger.Info("卖出合约，买入现货")
 			a.adjustDuration(true)
-			batch := Utils.GetRandomHexString(12)
-
-			a.placeOrdersByQuantity(a.future, Exchange.TradeConfig{
-				Batch:  batch,
-				Pair:   pair,
-				Type:   Exchange.TradeTypeOpenShort,
-				Price:  askFuturePlacePrice,
-				Amount: placeAmount / constContractRatio[coin],
-				Limit:  a.config.LimitOpen,
-			},
-				a.spot, Exchange.TradeConfig{
-					Batch:  batch,
-					Pair:   pair,
-					Type:   Exchange.TradeTypeBuy,
-					Price:  bidSpotPlacePrice,
-					Amount: placeAmount / bidSpot,
-					Limit:  a.config.LimitOpen,
-				})
+			if a.config.Algo != nil {
+				a.startAlgo(coin, true)
+			} else {
+				a.openPosition(pair, true, placeAmount, askFuturePlacePrice, bidSpotPlacePrice, bidSpot)
+			}
 
 		} else if diff1 > a.config.Area[coin].Open {
 			Logger.Info("买入合约, 卖出现货")
 			a.adjustDuration(true)
-			batch := Utils.GetRandomHexString(12)
-
-			a.placeOrdersByQuantity(a.future, Exchange.TradeConfig{
-				Batch:  batch,
-				Pair:   pair,
-				Type:   Exchange.TradeTypeOpenLong,
-				Price:  bidFuturePlacePrice,
-				Amount: placeAmount / constContractRatio[coin],
-				Limit:  a.config.LimitOpen,
-			},
-				a.spot, Exchange.TradeConfig{
-					Batch:  batch,
-					Pair:   pair,
-					Type:   Exchange.TradeTypeSell,
-					Price:  askSpotPlacePrice,
-					Amount: placeAmount / askSpot,
-					Limit:  a.config.LimitOpen,
-				})
+			if a.config.Algo != nil {
+				a.startAlgo(coin, false)
+			} else {
+				a.openPosition(pair, false, placeAmount, bidFuturePlacePrice, askSpotPlacePrice, askSpot)
+			}
 		} else {
 			a.adjustDuration(false)
 		}
@@ -789,6 +945,11 @@ func (a *IAnalyzer) Close() {
 	Logger.Info("关闭任务")
 
 	a.status = Task.StatusNone
+	a.cancelAlgo("")
+
+	if a.scheduler != nil {
+		a.scheduler.Remove(a.recordBalancesJob())
+	}
 
 	if a.future != nil {
 		a.future.Close()
@@ -798,6 +959,15 @@ func (a *IAnalyzer) Close() {
 		a.spot.Close()
 	}
 
+	// the gateways which aren't assigned are unregistered from the kill switch
+	if a.futureRisk != nil && a.future != Exchange.IExchange(a.futureRisk) {
+		a.futureRisk.Close()
+	}
+	if a.spotRisk != nil && a.spot != Exchange.IExchange(a.spotRisk) {
+		a.spotRisk.Close()
+	}
+	a.futureRisk, a.spotRisk = nil, nil
+
 	if a.conn != nil {
 		a.conn.Close()
 	}
@@ -810,16 +980,56 @@ func (a *IAnalyzer) Close() {
 		a.tradeDB.Close()
 	}
 
+	if a.riskDB != nil {
+		a.riskDB.Close()
+		a.riskDB = nil
+	}
+
 	if a.fund != nil {
 		a.fund.Close()
 	}
 }
 
+// ForceClosePositions 在下一次检查时平掉所有仓位
+func (a *IAnalyzer) ForceClosePositions() {
+	a.forceClose = true
+}
+
+// openPosition sells the future and buys the spot if it's short, otherwise the opposite. The spot amount is the value at
+// the average depth price, it returns the fills of both legs, which are empty if no position is kept
+func (a *IAnalyzer) openPosition(pair string, short bool, placeAmount float64, futurePrice float64, spotPrice float64,
+	spotDepthPrice float64) (Task.TradeResult, Task.TradeResult) {
+
+	coin := Exchange.ParsePair(pair)[0]
+	futureType, spotType := Exchange.TradeTypeOpenLong, Exchange.TradeTypeSell
+	if short {
+		futureType, spotType = Exchange.TradeTypeOpenShort, Exchange.TradeTypeBuy
+	}
+	batch := Utils.GetRandomHexString(12)
+
+	return a.placeOrdersByQuantity(a.future, Exchange.TradeConfig{
+		Batch:  batch,
+		Pair:   pair,
+		Type:   futureType,
+		Price:  futurePrice,
+		Amount: placeAmount / constContractRatio[coin],
+		Limit:  a.config.LimitOpen,
+	},
+		a.spot, Exchange.TradeConfig{
+			Batch:  batch,
+			Pair:   pair,
+			Type:   spotType,
+			Price:  spotPrice,
+			Amount: placeAmount / spotDepthPrice,
+			Limit:  a.config.LimitOpen,
+		})
+}
+
 /*
-	根据持仓量限价买入
+	根据持仓量限价买入，返回合约和现货的成交
 */
 func (a *IAnalyzer) placeOrdersByQuantity(future Exchange.IExchange, futureConfig Exchange.TradeConfig,
-	spot Exchange.IExchange, spotConfig Exchange.TradeConfig) {
+	spot Exchange.IExchange, spotConfig Exchange.TradeConfig) (Task.TradeResult, Task.TradeResult) {
 
 	channelFuture := a.ProcessTradeRoutine(future, futureConfig, a.tradeDB)
 	channelSpot := a.ProcessTradeRoutine(spot, spotConfig, a.tradeDB)
@@ -858,6 +1068,32 @@ func (a *IAnalyzer) placeOrdersByQuantity(future Exchange.IExchange, futureConfi
 		Logger.Error("Fail to save fund info")
 	}
 
+	if futureResult.Error != Task.TaskErrorSuccess || spotResult.Error != Task.TaskErrorSuccess {
+		// 单腿成交时补单或者平掉多成交的部分
+		coin := Exchange.ParsePair(futureConfig.Pair)[0]
+		futureLeg := &Leg{Name: TypeFuture, Exchange: future, Config: futureConfig, Ratio: constContractRatio[coin], Result: futureResult}
+		spotLeg := &Leg{Name: TypeSpot, Exchange: spot, Config: spotConfig, Result: spotResult}
+
+		hedged := Hedge(futureLeg, spotLeg, recoveryLimits(a.config.LimitOpen, a.config.Recovery), a.tradeLeg, a.recordStep(spotConfig.Batch))
+		futureResult = futureLeg.Result
+		spotResult = spotLeg.Result
+
+		if !hedged {
+			Logger.Errorf("单腿成交处理失败，请手工检查 合约:%v 现货:%v", futureResult, spotResult)
+			a.fund.ClosePosition(spotConfig.Batch, spotLeg.ClosePrice, futureLeg.ClosePrice, Mongo.FundStatusError)
+			a.countError()
+			return Task.TradeResult{}, Task.TradeResult{}
+		}
+
+		if futureResult.DealAmount == 0 {
+			Logger.Infof("已平掉单腿成交的部分，现货剩余:%v", spotResult.DealAmount)
+			a.fund.ClosePosition(spotConfig.Batch, spotLeg.ClosePrice, futureLeg.ClosePrice, Mongo.FundStatusClose)
+			return Task.TradeResult{}, Task.TradeResult{}
+		}
+
+		a.fund.UpdatePosition(spotConfig.Batch, spotResult.AvgPrice, spotResult.DealAmount, futureResult.AvgPrice, futureResult.DealAmount)
+	}
+
 	operation := OperationItem{}
 
 	futureConfig.Type = Exchange.RevertTradeType(futureConfig.Type)
@@ -871,86 +1107,71 @@ func (a *IAnalyzer) placeOrdersByQuantity(future Exchange.IExchange, futureConfi
 	operation.futureConfig = futureConfig
 	operation.spotConfig = spotConfig
 
-	if futureResult.Error == Task.TaskErrorSuccess && spotResult.Error == Task.TaskErrorSuccess {
+	Logger.Debug("锁仓成功")
+	a.ops[a.opIndex] = &operation
+	a.ops[a.opIndex].Amount = spotResult.AvgPrice * spotResult.DealAmount
+	a.opIndex++
+	return futureResult, spotResult
+}
 
-		Logger.Debug("锁仓成功")
-		a.ops[a.opIndex] = &operation
-		a.ops[a.opIndex].Amount = spotResult.AvgPrice * spotResult.DealAmount
-		a.opIndex++
-		return
+func (a *IAnalyzer) recordBalancesJob() string {
+	return Task.CollectionName(a.namespace, "RecordBalances")
+}
 
-	} else {
+// inWindow the default window is used if the window isn't configured
+func inWindow(window *Task.Window, defaultWindow *Task.Window) bool {
+	if window == nil {
+		window = defaultWindow
+	}
 
-		if spotResult.DealAmount > 0 {
-			channelSpot = a.ProcessTradeRoutine(spot, operation.spotConfig, a.tradeDB)
-			waitGroup.Add(1)
-			go func() {
-				select {
-				case spotResult = <-channelSpot:
-					Logger.Debugf("现货平仓结果:%v", spotResult)
-					if spotResult.Error != Task.TaskErrorSuccess {
-						Logger.Errorf("平仓失败，请手工检查:%v", spotResult)
-						// a.status = StatusError
-						a.countError()
-					}
-					waitGroup.Done()
-				}
-			}()
-		}
+	err, active := window.Active(time.Now())
+	if err != nil {
+		Logger.Errorf("Invalid window %v:%v", window, err)
+		return false
+	}
+	return active
+}
 
-		if futureResult.DealAmount > 0 {
-			channelFuture = a.ProcessTradeRoutine(future, operation.futureConfig, a.tradeDB)
-			waitGroup.Add(1)
-			select {
-			case futureResult = <-channelFuture:
-				Logger.Debugf("合约平仓结果:%v", futureResult)
-				if futureResult.Error != Task.TaskErrorSuccess {
-					Logger.Errorf("平仓失败，请手工检查:%v", futureResult)
-					// a.status = StatusError
-					a.countError()
-				}
-				waitGroup.Done()
-			}
+func checkWindows(config AnalyzerConfig) Task.ConfigErrors {
+	var errs Task.ConfigErrors
+	windows := map[string]*Task.Window{
+		"openpause":  config.OpenPause,
+		"closepause": config.ClosePause,
+	}
+	for field, window := range windows {
+		if window == nil {
+			continue
 		}
-
-		waitGroup.Wait()
-		if spotResult.Error == Task.TaskErrorSuccess && futureResult.Error == Task.TaskErrorSuccess {
-			a.fund.ClosePosition(spotConfig.Batch, spotResult.AvgPrice, futureResult.AvgPrice, Mongo.FundStatusClose)
-		} else {
-			a.fund.ClosePosition(spotConfig.Batch, spotResult.AvgPrice, futureResult.AvgPrice, Mongo.FundStatusError)
+		if err, _ := window.Active(time.Now()); err != nil {
+			errs = append(errs, Task.ConfigError{Field: field, Message: err.Error()})
 		}
-
 	}
-
-	return
+	return errs
 }
 
 func (a *IAnalyzer) checkOpenTime() bool {
-	now := time.Now()
-	if now.Weekday() == time.Friday && (now.Hour() > 10 && now.Hour() < 17) {
-		return false
-	}
-
-	return true
+	return !inWindow(a.config.OpenPause, defaultConfig.OpenPause)
 }
 
 func (a *IAnalyzer) checkCloseTime() bool {
-	now := time.Now()
-	if now.Weekday() == time.Friday && now.Hour() > 16 {
-		return false
-	}
-
-	return true
+	return !inWindow(a.config.ClosePause, defaultConfig.ClosePause)
 }
 
-func (a *IAnalyzer) checkFunds(pair string, diff float64) float64 {
-	var usedAmount, ratio float64
-
+// usedAmount the value of the positions of the pair
+func (a *IAnalyzer) usedAmount(pair string) float64 {
+	var usedAmount float64
 	for _, op := range a.ops {
 		if op != nil && op.futureConfig.Pair == pair {
 			usedAmount += op.Amount
 		}
 	}
+	return usedAmount
+}
+
+func (a *IAnalyzer) checkFunds(pair string, diff float64) float64 {
+	var ratio float64
+
+	usedAmount := a.usedAmount(pair)
 	coin := Exchange.ParsePair(pair)[0]
 
 	// base := a.config.Area[coin].Open
@@ -1052,6 +1273,22 @@ func (a *IAnalyzer) checkPosition(coin string, askFuturePrice float64, bidFuture
 
 					waitGroup.Wait()
 
+					if futureResult.Error != Task.TaskErrorSuccess || spotResult.Error != Task.TaskErrorSuccess {
+						// 平仓失败的一边逐步放宽限价补单
+						limits := recoveryLimits(a.config.LimitOpen, a.config.Recovery)
+						record := a.recordStep(op.spotConfig.Batch)
+						futureLeg := &Leg{Name: TypeFuture, Exchange: a.future, Config: op.futureConfig, Ratio: constContractRatio[coin], Result: futureResult}
+						spotLeg := &Leg{Name: TypeSpot, Exchange: a.spot, Config: op.spotConfig, Result: spotResult}
+						futureDone := Complete(futureLeg, limits, a.tradeLeg, record)
+						spotDone := Complete(spotLeg, limits, a.tradeLeg, record)
+						futureResult = futureLeg.Result
+						spotResult = spotLeg.Result
+						if futureDone && spotDone {
+							futureResult.Error = Task.TaskErrorSuccess
+							spotResult.Error = Task.TaskErrorSuccess
+						}
+					}
+
 					if futureResult.Error == Task.TaskErrorSuccess && spotResult.Error == Task.TaskErrorSuccess {
 						Logger.Info("平仓完成")
 						a.fund.ClosePosition(op.spotConfig.Batch, spotResult.AvgPrice, futureResult.AvgPrice, Mongo.FundStatusClose)
@@ -1080,6 +1317,9 @@ func (a *IAnalyzer) checkPosition(coin string, askFuturePrice float64, bidFuture
 
 func (a *IAnalyzer) countError() {
 	Logger.Errorf("Error Counter:%v", a.errorCount)
+	if gateway, ok := a.future.(*Task.RiskGateway); ok {
+		gateway.CountError()
+	}
 	if a.errorCount < 10 {
 		a.errorCount++
 	} else {
@@ -1111,6 +1351,7 @@ func (a *IAnalyzer) ProcessTradeRoutine(exchange Exchange.IExchange,
 		// var depth [][]Exchange.DepthPrice
 		var tradePrice, tradeAmount float64
 		// var err error
+		execution := Task.StartExecution(exchange, tradeConfig)
 
 		for {
 
@@ -1138,6 +1379,8 @@ func (a *IAnalyzer) ProcessTradeRoutine(exchange Exchange.IExchange,
 					Quantity: tradeAmount,
 					Price:    tradePrice,
 					OrderID:  trade.OrderID,
+					Decision: tradeConfig.Price,
+					Arrival:  execution.Record.Arrival,
 				}); err != nil {
 					Logger.Errorf("Fail to save trade record:%v", err)
 				}
@@ -1171,7 +1414,7 @@ func (a *IAnalyzer) ProcessTradeRoutine(exchange Exchange.IExchange,
 						dealAmount += info[0].DealAmount
 						totalCost += (info[0].AvgPrice * info[0].DealAmount) //手续费如何？
 						if dbTrades != nil {
-							dbTrades.SetDone(trade.OrderID)
+							dbTrades.SetFill(trade.OrderID, info[0].AvgPrice, info[0].DealAmount, execution.Latency())
 						}
 						goto __CheckDealAmount
 					}
@@ -1243,6 +1486,7 @@ func (a *IAnalyzer) ProcessTradeRoutine(exchange Exchange.IExchange,
 			if dealAmount != 0 {
 				avePrice = totalCost / dealAmount
 			}
+			execution.Filled(dealAmount, avePrice)
 
 			channel <- Task.TradeResult{
 				Error:      errorCode,
@@ -1255,6 +1499,7 @@ func (a *IAnalyzer) ProcessTradeRoutine(exchange Exchange.IExchange,
 			if dealAmount != 0 {
 				avePrice = totalCost / dealAmount
 			}
+			execution.Filled(dealAmount, avePrice)
 
 			channel <- Task.TradeResult{
 				Error:      Task.TaskErrorSuccess,
@@ -0,0 +1,227 @@
+package okexdiff
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Task "madaoQT/task"
+	Algo "madaoQT/task/algo"
+	Utils "madaoQT/utils"
+)
+
+/*
+	拆单开仓：配置了执行算法时，币种可开仓的数量作为母单按TWAP或VWAP拆成单位数量的子单，
+	每个子单按当时的深度同时开合约和现货，单腿成交和单次开仓一样处理，所以两边始终是对冲的。
+	子单在Watch中执行，价差不足或资金用完时子单失败，下一个间隔再补
+*/
+
+// AlgoConfig the execution algorithm of the opening amount of the coins
+type AlgoConfig struct {
+	Type     string `json:"type" title:"算法" desc:"twap或vwap" required:"true"`
+	Duration int    `json:"duration" title:"执行时长" desc:"分钟" min:"1" required:"true"`
+	Interval int    `json:"interval" title:"子单间隔" desc:"秒，为0时为1分钟"`
+	// Days the days of the klines of the VWAP profile
+	Days int `json:"days" title:"成交量天数" desc:"VWAP按前几天的成交量分布，为0时为5天"`
+}
+
+func checkAlgo(config AnalyzerConfig) Task.ConfigErrors {
+	var errs Task.ConfigErrors
+	if config.Algo == nil {
+		return errs
+	}
+	if config.Algo.Type != string(Algo.AlgoTWAP) && config.Algo.Type != string(Algo.AlgoVWAP) {
+		errs = append(errs, Task.ConfigError{Field: "algo.type", Message: "should be twap or vwap"})
+	}
+	if config.Algo.Duration <= 0 {
+		errs = append(errs, Task.ConfigError{Field: "algo.duration", Message: "should be positive"})
+	}
+	if config.Algo.Interval < 0 {
+		errs = append(errs, Task.ConfigError{Field: "algo.interval", Message: "can't be negative"})
+	}
+	return errs
+}
+
+// pairExchange places the child orders of the algorithm, the child order opens the future and the spot together and
+// is finished when it returns. The amount of the child orders is the face value of the future in usdt, which is dealt
+// at the average price of the future
+type pairExchange struct {
+	// IExchange the future, whose depth prices the child orders
+	Exchange.IExchange
+	analyzer *IAnalyzer
+	coin     string
+	short    bool
+	orders   map[string]*Exchange.OrderInfo
+}
+
+func (p *pairExchange) GetExchangeName() string {
+	return "okexdiff"
+}
+
+func (p *pairExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	err, future := p.analyzer.openPair(p.coin, p.short, configs.Amount)
+	if err != nil {
+		return &Exchange.TradeResult{Error: err}
+	}
+
+	id := configs.Batch + "-" + strconv.Itoa(len(p.orders)+1)
+	p.orders[id] = &Exchange.OrderInfo{
+		OrderID:    id,
+		Pair:       configs.Pair,
+		Price:      configs.Price,
+		Amount:     configs.Amount,
+		DealAmount: future.DealAmount * constContractRatio[p.coin],
+		AvgPrice:   future.AvgPrice,
+		Status:     Exchange.OrderStatusDone,
+	}
+	return &Exchange.TradeResult{OrderID: id}
+}
+
+func (p *pairExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	if order := p.orders[filter.OrderID]; order != nil {
+		return []Exchange.OrderInfo{*order}
+	}
+	return nil
+}
+
+// CancelOrder the child orders are always finished
+func (p *pairExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+// openPair opens the position of the value at the current depth, it fails if the diff is below the open diff or the
+// funds are used up. It returns the fill of the future
+func (a *IAnalyzer) openPair(coin string, short bool, placeAmount float64) (error, Task.TradeResult) {
+	pair := coin + "/usdt"
+	err1, askFuture, askFuturePlacePrice, bidFuture, bidFuturePlacePrice := Task.CalcDepthPrice(true, constContractRatio, a.future, pair, placeAmount)
+	err2, askSpot, askSpotPlacePrice, bidSpot, bidSpotPlacePrice := Task.CalcDepthPrice(false, map[string]float64{}, a.spot, pair, placeAmount)
+	if err1 != nil || err2 != nil {
+		return errors.New("Invalid depth"), Task.TradeResult{}
+	}
+
+	diff := (bidSpot - askFuture) * 100 / bidSpot
+	if short {
+		diff = (bidFuture - askSpot) * 100 / askSpot
+	}
+	if diff <= a.config.Area[coin].Open {
+		return errors.New("The diff is below the open diff"), Task.TradeResult{}
+	}
+	if a.checkFunds(pair, diff) == 0 {
+		return errors.New("No funds to open"), Task.TradeResult{}
+	}
+
+	var future Task.TradeResult
+	if short {
+		future, _ = a.openPosition(pair, true, placeAmount, askFuturePlacePrice, bidSpotPlacePrice, bidSpot)
+	} else {
+		future, _ = a.openPosition(pair, false, placeAmount, bidFuturePlacePrice, askSpotPlacePrice, askSpot)
+	}
+	return nil, future
+}
+
+// startAlgo starts the parent order of the coin with the rest of the area, unless one is running
+func (a *IAnalyzer) startAlgo(coin string, short bool) {
+	a.algoLock.Lock()
+	running := a.algos[coin] != nil
+	a.algoLock.Unlock()
+	if running {
+		return
+	}
+
+	pair := coin + "/usdt"
+	amount := math.Floor((a.config.Area[coin].Amount-a.usedAmount(pair))/a.config.UnitAmount) * a.config.UnitAmount
+	if amount <= 0 {
+		return
+	}
+
+	tradeType := Exchange.TradeTypeOpenLong
+	if short {
+		tradeType = Exchange.TradeTypeOpenShort
+	}
+	order := Algo.ParentOrder{
+		Type:      Algo.AlgoType(a.config.Algo.Type),
+		Pair:      pair,
+		TradeType: tradeType,
+		Amount:    amount,
+		Duration:  time.Duration(a.config.Algo.Duration) * time.Minute,
+		Interval:  time.Duration(a.config.Algo.Interval) * time.Second,
+		MinAmount: a.config.UnitAmount,
+	}
+	if order.Type == Algo.AlgoVWAP {
+		interval := order.Interval
+		if interval == 0 {
+			interval = time.Minute
+		}
+		days := a.config.Algo.Days
+		if days <= 0 {
+			days = 5
+		}
+		err, profile := Algo.NewProfile(a.spot.GetExchangeName(), pair, time.Now(), order.Duration, interval, days)
+		if err != nil {
+			Logger.Warnf("加载成交量分布失败，使用TWAP:%v", err)
+		}
+		order.Profile = profile
+	}
+
+	exchange := &pairExchange{IExchange: a.future, analyzer: a, coin: coin, short: short, orders: make(map[string]*Exchange.OrderInfo)}
+	err, algo := Algo.NewAlgo(Utils.GetRandomHexString(12), exchange, order)
+	if err != nil {
+		Logger.Errorf("拆单开仓失败:%v", err)
+		return
+	}
+
+	Logger.Infof("拆单开仓 币种:%s 数量:%v", coin, amount)
+	algo.Begin()
+	a.algoLock.Lock()
+	if a.algos == nil {
+		a.algos = make(map[string]*Algo.Algo)
+	}
+	a.algos[coin] = algo
+	a.algoLock.Unlock()
+}
+
+// stepAlgos runs the child orders which are due, the finished parent orders are removed
+func (a *IAnalyzer) stepAlgos() {
+	a.algoLock.Lock()
+	algos := make(map[string]*Algo.Algo)
+	for coin, algo := range a.algos {
+		algos[coin] = algo
+	}
+	a.algoLock.Unlock()
+
+	now := time.Now()
+	for coin, algo := range algos {
+		algo.Step(now)
+		select {
+		case <-algo.Done():
+			a.algoLock.Lock()
+			if a.algos[coin] == algo {
+				delete(a.algos, coin)
+			}
+			a.algoLock.Unlock()
+		default:
+		}
+	}
+}
+
+// cancelAlgo cancels the parent order of the coin, the empty coin cancels all of them
+func (a *IAnalyzer) cancelAlgo(coin string) {
+	a.algoLock.Lock()
+	var algos []*Algo.Algo
+	for key, algo := range a.algos {
+		if coin == "" || key == coin {
+			algos = append(algos, algo)
+			delete(a.algos, key)
+		}
+	}
+	a.algoLock.Unlock()
+
+	for _, algo := range algos {
+		if err := algo.Cancel(); err == nil {
+			// no child order is left open
+			algo.Step(time.Now())
+		}
+	}
+}
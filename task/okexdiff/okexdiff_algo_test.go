@@ -0,0 +1,79 @@
+package okexdiff
+
+import (
+	"testing"
+
+	Exchange "madaoQT/exchange"
+)
+
+type depthExchange struct {
+	Exchange.IExchange
+	depth  [][]Exchange.DepthPrice
+	trades []Exchange.TradeConfig
+}
+
+func (d *depthExchange) GetExchangeName() string {
+	return "depth"
+}
+
+func (d *depthExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return d.depth
+}
+
+func (d *depthExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	d.trades = append(d.trades, configs)
+	return &Exchange.TradeResult{OrderID: "1"}
+}
+
+func TestCheckAlgo(t *testing.T) {
+	if errs := checkAlgo(AnalyzerConfig{}); errs != nil {
+		t.Errorf("The algo is optional:%v", errs)
+	}
+	if errs := checkAlgo(AnalyzerConfig{Algo: &AlgoConfig{Type: "pov"}}); len(errs) != 2 {
+		t.Errorf("Invalid errors:%v", errs)
+	}
+	if errs := checkAlgo(AnalyzerConfig{Algo: &AlgoConfig{Type: "twap", Duration: 30}}); errs != nil {
+		t.Errorf("Invalid errors:%v", errs)
+	}
+}
+
+func TestAlgoBelowOpen(t *testing.T) {
+	depth := [][]Exchange.DepthPrice{{{Price: 100, Quantity: 1000}}, {{Price: 100.1, Quantity: 1000}}}
+	future := &depthExchange{depth: depth}
+	spot := &depthExchange{depth: depth}
+	analyzer := &IAnalyzer{
+		config: AnalyzerConfig{
+			Area:       map[string]*TriggerArea{"btc": {Open: 1, Close: 0.5, Amount: 120}},
+			UnitAmount: 50,
+			Algo:       &AlgoConfig{Type: "twap", Duration: 2},
+		},
+		future: future,
+		spot:   spot,
+		ops:    make(map[uint]*OperationItem),
+	}
+
+	analyzer.startAlgo("btc", true)
+	algo := analyzer.algos["btc"]
+	if algo == nil || algo.Order.Amount != 100 || algo.Order.MinAmount != 50 {
+		t.Fatalf("Invalid parent order:%v", algo)
+	}
+
+	// the first slice is the unit amount, the diff is below the open diff so neither leg is traded
+	analyzer.stepAlgos()
+	if len(future.trades) != 0 || len(spot.trades) != 0 {
+		t.Errorf("No order should be placed:%v %v", future.trades, spot.trades)
+	}
+	if progress := algo.Progress(); progress.Stats.Errors != 1 || progress.Dealt != 0 {
+		t.Errorf("Invalid progress:%v", progress)
+	}
+
+	analyzer.cancelAlgo("btc")
+	select {
+	case <-algo.Done():
+	default:
+		t.Errorf("The parent order should be canceled")
+	}
+	if len(analyzer.algos) != 0 {
+		t.Errorf("The canceled order should be removed")
+	}
+}
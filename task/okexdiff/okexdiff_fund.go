@@ -3,6 +3,7 @@ package okexdiff
 import (
 	"errors"
 	"log"
+	Analytics "madaoQT/analytics"
 	Exchange "madaoQT/exchange"
 	Mongo "madaoQT/mongo"
 	Task "madaoQT/task"
@@ -11,6 +12,8 @@ import (
 
 type OkexFundManage struct {
 	fundDB *Mongo.Funds
+	// Namespace the prefix of the collection for the task instance
+	Namespace string
 }
 
 const TypeSpot = "spot"
@@ -19,7 +22,7 @@ const TypeFuture = "future"
 func (h *OkexFundManage) Init() error {
 	h.fundDB = &Mongo.Funds{
 		Config: &Mongo.DBConfig{
-			CollectionName: "DiffOKExFunds",
+			CollectionName: Task.CollectionName(h.Namespace, "DiffOKExFunds"),
 		},
 	}
 
@@ -73,6 +76,31 @@ func (h *OkexFundManage) OpenPosition(batch string,
 	return nil
 }
 
+// UpdatePosition updates the opening prices and amounts after the legs are recovered
+func (h *OkexFundManage) UpdatePosition(batch string, spotOpen float64, spotAmount float64, futureOpen float64, futureAmount float64) error {
+	if err := h.fundDB.Update(map[string]interface{}{
+		"batch": batch,
+	}, map[string]interface{}{
+		"spotopen":     spotOpen,
+		"spotamount":   spotAmount,
+		"futureopen":   futureOpen,
+		"futureamount": futureAmount,
+	}); err != nil {
+		Logger.Errorf("Error:%v", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddStep records the recovery step of the batch
+func (h *OkexFundManage) AddStep(batch string, step Mongo.FundStep) error {
+	if h.fundDB == nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+	return h.fundDB.AddStep(batch, step)
+}
+
 func (h *OkexFundManage) ClosePosition(batch string, spotClose float64, futureClose float64, result string) error {
 
 	// var result string
@@ -156,28 +184,12 @@ func (h *OkexFundManage) CheckProfit(records []Mongo.FundInfo) float64 {
 
 	for _, record := range records {
 		coin := Exchange.ParsePair(record.Pair)[0]
-		var spotProfit, futureProfit, fee float64
-
-		if record.SpotType == Exchange.TradeTypeString[Exchange.TradeTypeBuy] {
-			spotProfit = (record.SpotClose - record.SpotOpen) * record.SpotAmount
-		} else {
-			spotProfit = (record.SpotOpen - record.SpotClose) * record.SpotAmount
-		}
-		fee = (record.SpotOpen + record.SpotClose) * record.SpotAmount * 0.002
-
-		amount := constContractRatio[coin] * record.FutureAmount
 		if record.FutureOpen == 0 || record.FutureClose == 0 {
 			log.Printf("[%v]无效合约数据", record.Batch)
-		} else {
-			if record.FutureType == Exchange.TradeTypeString[Exchange.TradeTypeOpenLong] {
-				futureProfit = (amount/record.FutureClose - amount/record.FutureOpen) * record.FutureClose * (-1)
-			} else {
-				futureProfit = (amount/record.FutureOpen - amount/record.FutureClose) * record.FutureClose * (-1)
-			}
-
-			fee += 100 * 2 * 0.0005
 		}
 
+		spotProfit, futureProfit, fee := Analytics.FundProfit(record, constContractRatio[coin])
+
 		log.Printf("[%v][%v]现货收益:%v 合约收益:%v 收益:%v", record.Batch, record.CloseTime, spotProfit, futureProfit, (spotProfit + futureProfit - fee))
 		total += (spotProfit + futureProfit - fee)
 	}
@@ -185,3 +197,19 @@ func (h *OkexFundManage) CheckProfit(records []Mongo.FundInfo) float64 {
 	log.Printf("总收益:%v", total)
 	return total
 }
+
+// LoadTrades loads the closed records in [start, end) for the analytics
+func (h *OkexFundManage) LoadTrades(start time.Time, end time.Time) (error, []Analytics.Trade) {
+	err, records := h.fundDB.Find(map[string]interface{}{
+		"status": Mongo.FundStatusClose,
+		"closetime": map[string]interface{}{
+			"$gte": start,
+			"$lt":  end,
+		},
+	})
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, Analytics.FromFunds(records, constContractRatio)
+}
@@ -0,0 +1,134 @@
+package okexdiff
+
+import (
+	"errors"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+)
+
+/*
+	启动前核对：合约按资金记录汇总每个交易对的仓位（张），与交易所当周合约的持仓对比，次周和季度合约的持仓都是孤儿仓位；
+	合约和现货的挂单都报告为孤儿订单；平仓时需要卖出的现货不能多于现货余额。差异只报告，不支持接管
+*/
+
+// reconcileTolerance the differences less than it are ignored
+const reconcileTolerance = 1e-6
+
+// reconcileContracts the contract types which are checked, the task trades the first one
+var reconcileContracts = []string{Exchange.ContractTypeThisWeek, Exchange.ContractTypeNextWeek, Exchange.ContractTypeQuarter}
+
+// contractAccount the futures positions and orders queried by the REST API, because the websocket API doesn't
+// support them
+type contractAccount interface {
+	GetPosition(pair string, contractType string) map[string]float64
+	GetContractOpenOrders(pair string, contractType string) (error, []Exchange.OrderInfo)
+}
+
+// contractKey the positions of the contract types which aren't traded are keyed by the type
+func contractKey(pair string, contractType string) string {
+	if contractType == reconcileContracts[0] {
+		return pair
+	}
+	return pair + ":" + contractType
+}
+
+// savedState the short positions are negative, the closing orders of the operations decide the directions
+func savedState(ops map[uint]*OperationItem) Task.ReconcileState {
+	state := Task.ReconcileState{
+		Positions: make(map[string]float64),
+		Balances:  make(map[string]float64),
+	}
+	for _, op := range ops {
+		if op == nil {
+			continue
+		}
+
+		switch op.futureConfig.Type {
+		case Exchange.TradeTypeCloseLong:
+			state.Positions[op.futureConfig.Pair] += op.futureConfig.Amount
+		case Exchange.TradeTypeCloseShort:
+			state.Positions[op.futureConfig.Pair] -= op.futureConfig.Amount
+		}
+
+		if op.spotConfig.Type == Exchange.TradeTypeSell {
+			coin := Exchange.ParsePair(op.spotConfig.Pair)[0]
+			state.Balances[coin] += op.spotConfig.Amount
+		}
+	}
+	return state
+}
+
+// actualState the positions and the open orders of every contract type, and the spot balances and orders. No order
+// is open before the task is started, so all of them are orphans
+func actualState(rest contractAccount, spot Exchange.IExchange, pairs map[string]bool, coins map[string]float64) (error, Task.ReconcileState) {
+	actual := Task.ReconcileState{
+		Positions: make(map[string]float64),
+		Balances:  make(map[string]float64),
+	}
+
+	for pair := range pairs {
+		for _, contractType := range reconcileContracts {
+			position := rest.GetPosition(pair, contractType)
+			if position == nil {
+				return errors.New("Fail to get the position of " + pair + " " + contractType), actual
+			}
+			if amount := position["long"] - position["short"]; amount != 0 || contractType == reconcileContracts[0] {
+				actual.Positions[contractKey(pair, contractType)] = amount
+			}
+
+			err, orders := rest.GetContractOpenOrders(pair, contractType)
+			if err != nil {
+				return err, actual
+			}
+			actual.Orders = append(actual.Orders, orders...)
+		}
+
+		err, orders := Task.OpenOrders(spot, pair)
+		if err != nil {
+			return err, actual
+		}
+		actual.Orders = append(actual.Orders, orders...)
+	}
+
+	balances := spot.GetBalance()
+	if balances == nil {
+		return errors.New("Fail to get the balances"), actual
+	}
+	for coin := range coins {
+		actual.Balances[coin], _ = Task.ParseBalance(balances, coin)
+	}
+	return nil, actual
+}
+
+// reconcile the futures are queried by the REST API with the keys of the task
+func (a *IAnalyzer) reconcile(config Exchange.Config, spot Exchange.IExchange) Task.ReconcileFunc {
+	rest := new(Exchange.OkexRestAPI)
+	rest.SetConfigure(config)
+	return a.reconcileWith(rest, spot)
+}
+
+func (a *IAnalyzer) reconcileWith(rest contractAccount, spot Exchange.IExchange) Task.ReconcileFunc {
+	return func(adopt bool) (error, []Mongo.ReconcileItem, []Mongo.ReconcileItem) {
+		saved := savedState(a.ops)
+
+		pairs := make(map[string]bool)
+		for coin := range a.config.Area {
+			pairs[coin+"/usdt"] = true
+		}
+		for pair := range saved.Positions {
+			pairs[pair] = true
+		}
+
+		err, actual := actualState(rest, spot, pairs, saved.Balances)
+		if err != nil {
+			return err, nil, nil
+		}
+
+		if adopt {
+			Logger.Info("资金记录不支持接管差异，请手工处理后确认")
+		}
+		return nil, Task.Compare(Exchange.NameOKEX, saved, actual, reconcileTolerance), nil
+	}
+}
@@ -0,0 +1,82 @@
+package okexdiff
+
+import (
+	"testing"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+func TestSavedState(t *testing.T) {
+	ops := map[uint]*OperationItem{
+		0: {
+			futureConfig: Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeCloseShort, Amount: 5},
+			spotConfig:   Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeSell, Amount: 0.25},
+		},
+		1: nil,
+		2: {
+			futureConfig: Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeCloseLong, Amount: 2},
+			spotConfig:   Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Amount: 0.1},
+		},
+	}
+	state := savedState(ops)
+	if state.Positions["eth/usdt"] != -3 || len(state.Balances) != 1 || state.Balances["eth"] != 0.25 {
+		t.Errorf("Invalid state:%v", state)
+	}
+}
+
+type restAccount struct {
+	positions map[string]map[string]float64
+	orders    map[string][]Exchange.OrderInfo
+}
+
+func (r *restAccount) GetPosition(pair string, contractType string) map[string]float64 {
+	if position := r.positions[contractType]; position != nil {
+		return position
+	}
+	return map[string]float64{"long": 0, "short": 0}
+}
+
+func (r *restAccount) GetContractOpenOrders(pair string, contractType string) (error, []Exchange.OrderInfo) {
+	return nil, r.orders[contractType]
+}
+
+type balanceExchange struct {
+	Exchange.IExchange
+}
+
+func (b *balanceExchange) GetBalance() map[string]interface{} {
+	return map[string]interface{}{"eth": map[string]interface{}{"balance": 1.0}}
+}
+
+func TestReconcile(t *testing.T) {
+	analyzer := &IAnalyzer{
+		config: AnalyzerConfig{Area: map[string]*TriggerArea{"eth": {}}},
+		ops: map[uint]*OperationItem{
+			0: {
+				futureConfig: Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeCloseShort, Amount: 5},
+				spotConfig:   Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeSell, Amount: 0.25},
+			},
+		},
+	}
+	rest := &restAccount{
+		positions: map[string]map[string]float64{
+			Exchange.ContractTypeThisWeek: {"long": 0, "short": 5},
+			Exchange.ContractTypeQuarter:  {"long": 2, "short": 0},
+		},
+		orders: map[string][]Exchange.OrderInfo{
+			Exchange.ContractTypeNextWeek: {{Pair: "eth/usdt", OrderID: "1", Amount: 3}},
+		},
+	}
+
+	err, items, _ := analyzer.reconcileWith(rest, new(balanceExchange))(false)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Invalid items:%v %v", err, items)
+	}
+	if items[0].Kind != Mongo.ReconcileOrphanPosition || items[0].Pair != "eth/usdt:quarter" || items[0].Actual != 2 {
+		t.Errorf("The position of the quarter contract should be an orphan:%v", items[0])
+	}
+	if items[1].Kind != Mongo.ReconcileOrphanOrder || items[1].OrderID != "1" {
+		t.Errorf("The open order should be an orphan:%v", items[1])
+	}
+}
@@ -0,0 +1,200 @@
+package okexdiff
+
+import (
+	"math"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+)
+
+/*
+	单腿成交处理：一边成交另一边失败时，先按逐步放宽的限价补单，
+	放宽到最大滑点仍未对齐时，反向平掉多成交的部分，每一步都记录到资金记录中，
+	仍无法对齐时才标记为失败，需要手工处理
+*/
+
+type RecoveryConfig struct {
+	MaxSlippage float64 `json:"maxslippage" title:"最大滑点" desc:"百分比，补单的限价从开仓波动范围逐步放宽到该值" min:"0" max:"10"`
+	Steps       int     `json:"steps" title:"补单次数" min:"1" max:"10"`
+}
+
+// Leg one side of the batch, the amounts of the future are contracts
+type Leg struct {
+	Name     string
+	Exchange Exchange.IExchange
+	Config   Exchange.TradeConfig
+	// Ratio the value of one contract, the value of the spot is the amount times the price
+	Ratio  float64
+	Result Task.TradeResult
+	// ClosePrice the average price of the unwinding orders
+	ClosePrice   float64
+	closedAmount float64
+}
+
+// TradeFunc places the order of the leg and waits for the result
+type TradeFunc func(leg *Leg, config Exchange.TradeConfig) Task.TradeResult
+
+func (l *Leg) value(amount float64) float64 {
+	if l.Ratio > 0 {
+		return amount * l.Ratio
+	}
+	return amount * l.Config.Price
+}
+
+// amount the contracts are rounded
+func (l *Leg) amount(value float64) float64 {
+	if l.Ratio > 0 {
+		return math.Floor(value/l.Ratio + 0.5)
+	}
+	if l.Config.Price <= 0 {
+		return 0
+	}
+	return value / l.Config.Price
+}
+
+// done the rest less than the minimum amount of ProcessTradeRoutine is ignored
+func (l *Leg) done() bool {
+	return l.Config.Amount-l.Result.DealAmount < 0.01
+}
+
+func (l *Leg) fill(result Task.TradeResult) {
+	if deal := l.Result.DealAmount + result.DealAmount; deal > 0 {
+		l.Result.AvgPrice = (l.Result.AvgPrice*l.Result.DealAmount + result.AvgPrice*result.DealAmount) / deal
+		l.Result.DealAmount = deal
+	}
+}
+
+func (l *Leg) unwind(result Task.TradeResult) {
+	if closed := l.closedAmount + result.DealAmount; closed > 0 {
+		l.ClosePrice = (l.ClosePrice*l.closedAmount + result.AvgPrice*result.DealAmount) / closed
+		l.closedAmount = closed
+	}
+	l.Result.DealAmount -= result.DealAmount
+}
+
+// recoveryLimits widens the limit from limitOpen to the max slippage step by step
+func recoveryLimits(limitOpen float64, config RecoveryConfig) []float64 {
+	max := config.MaxSlippage / 100
+	if max <= limitOpen {
+		return []float64{limitOpen}
+	}
+
+	steps := config.Steps
+	if steps < 1 {
+		steps = 1
+	}
+	var limits []float64
+	for i := 1; i <= steps; i++ {
+		limits = append(limits, limitOpen+(max-limitOpen)*float64(i)/float64(steps))
+	}
+	return limits
+}
+
+func tradeStep(leg *Leg, action string, config Exchange.TradeConfig, result Task.TradeResult) Mongo.FundStep {
+	step := Mongo.FundStep{
+		Time:       time.Now(),
+		Leg:        leg.Name,
+		Action:     action,
+		Type:       Exchange.TradeTypeString[config.Type],
+		Price:      Task.GetPlacedPrice(config.Type, config.Price, config.Limit),
+		Amount:     config.Amount,
+		DealAmount: result.DealAmount,
+		AvgPrice:   result.AvgPrice,
+	}
+	if result.Error != Task.TaskErrorSuccess {
+		step.Error = Task.TaskErrorMsg[result.Error]
+	}
+	return step
+}
+
+// Hedge completes the leg filled less with the widening limits, and unwinds the excess of the other leg if it still
+// isn't filled at the max slippage. The legs are hedged if the difference is less than half a contract
+func Hedge(future *Leg, spot *Leg, limits []float64, trade TradeFunc, record func(Mongo.FundStep)) bool {
+	tolerance := future.Ratio / 2
+	diff := func() float64 {
+		return future.value(future.Result.DealAmount) - spot.value(spot.Result.DealAmount)
+	}
+
+	for _, limit := range limits {
+		d := diff()
+		if math.Abs(d) <= tolerance {
+			return true
+		}
+		missing := spot
+		if d < 0 {
+			missing = future
+		}
+
+		config := missing.Config
+		config.Amount = missing.amount(math.Abs(d))
+		config.Limit = limit
+		if config.Amount <= 0 {
+			break
+		}
+
+		Logger.Infof("[%s]补单:%v 限价范围:%.4f", missing.Name, config.Amount, limit)
+		result := trade(missing, config)
+		missing.fill(result)
+		record(tradeStep(missing, Mongo.FundStepComplete, config, result))
+	}
+
+	for _, limit := range limits {
+		d := diff()
+		if math.Abs(d) <= tolerance {
+			return true
+		}
+		filled := future
+		if d < 0 {
+			filled = spot
+		}
+
+		config := filled.Config
+		config.Type = Exchange.RevertTradeType(config.Type)
+		config.Amount = math.Min(filled.amount(math.Abs(d)), filled.Result.DealAmount)
+		config.Limit = limit
+		if config.Amount <= 0 {
+			break
+		}
+
+		Logger.Infof("[%s]平掉多成交的部分:%v 限价范围:%.4f", filled.Name, config.Amount, limit)
+		result := trade(filled, config)
+		filled.unwind(result)
+		record(tradeStep(filled, Mongo.FundStepUnwind, config, result))
+	}
+
+	return math.Abs(diff()) <= tolerance
+}
+
+// Complete retries the rest of the leg with the widening limits, which is used by the closing orders
+func Complete(leg *Leg, limits []float64, trade TradeFunc, record func(Mongo.FundStep)) bool {
+	for _, limit := range limits {
+		if leg.done() {
+			return true
+		}
+
+		config := leg.Config
+		config.Amount = leg.Config.Amount - leg.Result.DealAmount
+		config.Limit = limit
+
+		Logger.Infof("[%s]补单:%v 限价范围:%.4f", leg.Name, config.Amount, limit)
+		result := trade(leg, config)
+		leg.fill(result)
+		record(tradeStep(leg, Mongo.FundStepComplete, config, result))
+	}
+	return leg.done()
+}
+
+// tradeLeg places the order of the leg by ProcessTradeRoutine
+func (a *IAnalyzer) tradeLeg(leg *Leg, config Exchange.TradeConfig) Task.TradeResult {
+	return <-a.ProcessTradeRoutine(leg.Exchange, config, a.tradeDB)
+}
+
+func (a *IAnalyzer) recordStep(batch string) func(Mongo.FundStep) {
+	return func(step Mongo.FundStep) {
+		if err := a.fund.AddStep(batch, step); err != nil {
+			Logger.Errorf("Fail to save the step:%v", err)
+		}
+	}
+}
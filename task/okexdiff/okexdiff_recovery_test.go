@@ -0,0 +1,117 @@
+package okexdiff
+
+import (
+	"math"
+	"testing"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+)
+
+func TestRecoveryLimits(t *testing.T) {
+	limits := recoveryLimits(0.005, RecoveryConfig{MaxSlippage: 2, Steps: 3})
+	if len(limits) != 3 || math.Abs(limits[0]-0.01) > 1e-9 || math.Abs(limits[2]-0.02) > 1e-9 {
+		t.Errorf("Invalid limits:%v", limits)
+	}
+	if limits = recoveryLimits(0.005, RecoveryConfig{}); len(limits) != 1 || limits[0] != 0.005 {
+		t.Errorf("The original limit should be retried once:%v", limits)
+	}
+}
+
+// fakeLegs fills the orders of the leg by the rates of the limits, the limits not listed fail
+type fakeLegs struct {
+	fills  map[string]map[float64]float64
+	trades []Exchange.TradeConfig
+	steps  []Mongo.FundStep
+}
+
+func (f *fakeLegs) trade(leg *Leg, config Exchange.TradeConfig) Task.TradeResult {
+	f.trades = append(f.trades, config)
+	rate := f.fills[leg.Name][config.Limit]
+	result := Task.TradeResult{DealAmount: config.Amount * rate, AvgPrice: config.Price}
+	if rate < 1 {
+		result.Error = Task.TaskErrorTimeout
+	}
+	return result
+}
+
+func (f *fakeLegs) record(step Mongo.FundStep) {
+	f.steps = append(f.steps, step)
+}
+
+func testLegs(futureDeal float64, spotDeal float64) (*Leg, *Leg) {
+	future := &Leg{
+		Name:   TypeFuture,
+		Config: Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeOpenShort, Price: 200, Amount: 5},
+		Ratio:  10,
+		Result: Task.TradeResult{DealAmount: futureDeal, AvgPrice: 200},
+	}
+	spot := &Leg{
+		Name:   TypeSpot,
+		Config: Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 200, Amount: 0.25},
+		Result: Task.TradeResult{DealAmount: spotDeal, AvgPrice: 200},
+	}
+	return future, spot
+}
+
+func TestHedge(t *testing.T) {
+	limits := []float64{0.01, 0.02}
+
+	// the spot is completed at the second limit
+	fake := &fakeLegs{fills: map[string]map[float64]float64{TypeSpot: {0.01: 0.5, 0.02: 1}}}
+	future, spot := testLegs(5, 0)
+	if !Hedge(future, spot, limits, fake.trade, fake.record) {
+		t.Fatalf("The legs should be hedged")
+	}
+	if len(fake.steps) != 2 || fake.steps[0].Action != Mongo.FundStepComplete || fake.steps[1].Amount != 0.125 ||
+		math.Abs(spot.Result.DealAmount-0.25) > 1e-9 || math.Abs(fake.steps[1].Price-204) > 1e-9 {
+		t.Errorf("Invalid steps:%v spot:%v", fake.steps, spot.Result)
+	}
+
+	// the future isn't filled, the spot is unwound
+	fake = &fakeLegs{fills: map[string]map[float64]float64{TypeSpot: {0.01: 1}}}
+	future, spot = testLegs(0, 0.25)
+	if !Hedge(future, spot, limits, fake.trade, fake.record) {
+		t.Fatalf("The spot should be unwound")
+	}
+	if len(fake.steps) != 3 || fake.steps[2].Action != Mongo.FundStepUnwind || fake.steps[2].Type != Exchange.TradeTypeString[Exchange.TradeTypeSell] ||
+		spot.Result.DealAmount != 0 || spot.ClosePrice != 200 {
+		t.Errorf("Invalid steps:%v spot:%v", fake.steps, spot)
+	}
+	if fake.trades[0].Amount != 5 || fake.trades[0].Type != Exchange.TradeTypeOpenShort {
+		t.Errorf("The future should be retried first:%v", fake.trades[0])
+	}
+
+	// the difference less than half a contract is ignored
+	fake = &fakeLegs{}
+	future, spot = testLegs(5, 0.23)
+	if !Hedge(future, spot, limits, fake.trade, fake.record) || len(fake.trades) != 0 {
+		t.Errorf("The legs are hedged:%v", fake.trades)
+	}
+
+	// nothing is filled
+	fake = &fakeLegs{}
+	future, spot = testLegs(3, 0)
+	if Hedge(future, spot, limits, fake.trade, fake.record) || len(fake.steps) != 4 {
+		t.Errorf("The legs shouldn't be hedged:%v", fake.steps)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	fake := &fakeLegs{fills: map[string]map[float64]float64{TypeFuture: {0.02: 1}}}
+	future, _ := testLegs(2, 0)
+	future.Result.AvgPrice = 190
+	if !Complete(future, []float64{0.01, 0.02}, fake.trade, fake.record) || len(fake.steps) != 2 {
+		t.Fatalf("The leg should be completed:%v", fake.steps)
+	}
+	if future.Result.DealAmount != 5 || math.Abs(future.Result.AvgPrice-196) > 1e-9 {
+		t.Errorf("Invalid result:%v", future.Result)
+	}
+
+	fake = &fakeLegs{}
+	future, _ = testLegs(2, 0)
+	if Complete(future, []float64{0.01}, fake.trade, fake.record) {
+		t.Errorf("The leg shouldn't be completed")
+	}
+}
@@ -3,7 +3,9 @@ package okexdiff
 import (
 	"encoding/json"
 	"log"
+	Exchange "madaoQT/exchange"
 	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
 	"testing"
 )
 
@@ -74,3 +76,27 @@ func TestRecordBalances(t *testing.T) {
 	Logger.Infof("Balances:%v", coinInfos)
 
 }
+
+type closeExchange struct {
+	Exchange.IExchange
+	closed int
+}
+
+func (c *closeExchange) Close() {
+	c.closed++
+}
+
+func TestCloseGateways(t *testing.T) {
+	future, spot := new(closeExchange), new(closeExchange)
+	analyzer := &IAnalyzer{
+		futureRisk: Task.NewRiskGateway(future, "future", Task.RiskConfig{}, nil),
+		spotRisk:   Task.NewRiskGateway(spot, "spot", Task.RiskConfig{}, nil),
+	}
+	// only the future is connected
+	analyzer.future = analyzer.futureRisk
+
+	analyzer.Close()
+	if future.closed != 1 || spot.closed != 1 {
+		t.Errorf("The gateways should be closed once:%v %v", future.closed, spot.closed)
+	}
+}
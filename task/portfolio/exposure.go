@@ -0,0 +1,282 @@
+package portfolio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	Exchange "madaoQT/exchange"
+)
+
+// minValue the deltas below it are ignored
+const minValue = 1e-9
+
+// AccountSnapshot the last account info of the configured account, Info is nil if it has never been fetched
+type AccountSnapshot struct {
+	Name    string
+	Info    *Exchange.AccountInfo
+	Updated time.Time
+	Error   string
+}
+
+// AssetExposure the net delta of the asset across the accounts
+type AssetExposure struct {
+	Asset string  `json:"asset"`
+	Delta float64 `json:"delta"`
+	Price float64 `json:"price"`
+	Value float64 `json:"value"`
+}
+
+// AccountSummary the equity and the margin of the account in the base currency
+type AccountSummary struct {
+	Name        string    `json:"name"`
+	Equity      float64   `json:"equity"`
+	MarginRatio float64   `json:"marginratio"`
+	Updated     time.Time `json:"updated"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// PositionExposure the position of the account, Distance is the percent to the liquidation price, 0 if unknown
+type PositionExposure struct {
+	Account string `json:"account"`
+	Exchange.AccountPosition
+	Value    float64 `json:"value"`
+	Distance float64 `json:"distance"`
+}
+
+// Snapshot the exposures of the portfolio, the values are in the base currency
+type Snapshot struct {
+	Time      time.Time          `json:"time"`
+	Base      string             `json:"base"`
+	Equity    float64            `json:"equity"`
+	Gross     float64            `json:"gross"`
+	Net       float64            `json:"net"`
+	Assets    []AssetExposure    `json:"assets"`
+	Accounts  []AccountSummary   `json:"accounts"`
+	Positions []PositionExposure `json:"positions"`
+	// Missing the assets without the price, which are valued as 0
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Pricer converts the assets to the base currency. The base and the cash assets are 1, then the configured rates,
+// the tickers of asset/base and at last the mark prices of the positions
+type Pricer struct {
+	Base   string
+	Cash   []string
+	Rates  map[string]float64
+	Ticker func(pair string) float64
+
+	marks  map[string]Exchange.AccountPosition
+	prices map[string]float64
+}
+
+func (p *Pricer) setMarks(accounts []AccountSnapshot) {
+	p.marks = make(map[string]Exchange.AccountPosition)
+	for _, account := range accounts {
+		if account.Info == nil {
+			continue
+		}
+		for _, position := range account.Info.Positions {
+			coins := Exchange.ParsePair(position.Pair)
+			if len(coins) == 2 && position.MarkPrice > 0 {
+				p.marks[coins[0]] = position
+			}
+		}
+	}
+}
+
+// Price the price of the asset in the base currency, returns false if it's unknown
+func (p *Pricer) Price(asset string) (float64, bool) {
+	return p.price(strings.ToLower(asset), 0)
+}
+
+func (p *Pricer) price(asset string, depth int) (float64, bool) {
+	if asset == p.Base {
+		return 1, true
+	}
+	for _, cash := range p.Cash {
+		if asset == cash {
+			return 1, true
+		}
+	}
+	if price, ok := p.prices[asset]; ok {
+		return price, price > 0
+	}
+
+	var price float64
+	if rate, ok := p.Rates[asset]; ok {
+		price = rate
+	} else if p.Ticker != nil {
+		price = p.Ticker(asset + "/" + p.Base)
+	}
+	// the quote of the mark price is priced too, e.g. the futures quoted in cny
+	if price <= 0 && depth < 2 {
+		if position, ok := p.marks[asset]; ok {
+			coins := Exchange.ParsePair(position.Pair)
+			if quote, ok := p.price(coins[1], depth+1); ok {
+				price = position.MarkPrice * quote
+			}
+		}
+	}
+
+	if p.prices == nil {
+		p.prices = make(map[string]float64)
+	}
+	p.prices[asset] = price
+	return price, price > 0
+}
+
+// isCash the base and the cash assets aren't counted in the exposures
+func (p *Pricer) isCash(asset string) bool {
+	if asset == p.Base {
+		return true
+	}
+	for _, cash := range p.Cash {
+		if asset == cash {
+			return true
+		}
+	}
+	return false
+}
+
+// Compute sums the balances and the positions of the accounts. The futures add the quantity to the delta of the base
+// asset and the opposite value to the quote asset, while the paid positions, e.g. the stocks, only add the quantity
+func Compute(accounts []AccountSnapshot, pricer *Pricer) *Snapshot {
+	pricer.prices = nil
+	pricer.setMarks(accounts)
+
+	snapshot := &Snapshot{Time: time.Now(), Base: pricer.Base}
+	deltas := make(map[string]float64)
+	missing := make(map[string]bool)
+
+	value := func(asset string, quantity float64) float64 {
+		price, ok := pricer.Price(asset)
+		if !ok {
+			missing[asset] = true
+		}
+		return quantity * price
+	}
+
+	for _, account := range accounts {
+		summary := AccountSummary{Name: account.Name, Updated: account.Updated, Error: account.Error}
+		if account.Info != nil {
+			summary.MarginRatio = account.Info.MarginRatio
+			for asset, amount := range account.Info.Balances {
+				asset = strings.ToLower(asset)
+				deltas[asset] += amount
+				summary.Equity += value(asset, amount)
+			}
+
+			for _, position := range account.Info.Positions {
+				coins := Exchange.ParsePair(position.Pair)
+				if len(coins) != 2 {
+					continue
+				}
+				deltas[coins[0]] += position.Quantity
+				exposure := PositionExposure{
+					Account:         account.Name,
+					AccountPosition: position,
+					Value:           value(coins[0], position.Quantity),
+				}
+				if position.Paid {
+					summary.Equity += exposure.Value
+				} else {
+					deltas[coins[1]] -= position.Quantity * position.MarkPrice
+				}
+				if position.MarkPrice > 0 && position.LiquidationPrice > 0 {
+					exposure.Distance = math.Abs(position.MarkPrice-position.LiquidationPrice) * 100 / position.MarkPrice
+				}
+				snapshot.Positions = append(snapshot.Positions, exposure)
+			}
+		}
+		snapshot.Equity += summary.Equity
+		snapshot.Accounts = append(snapshot.Accounts, summary)
+	}
+
+	var assets []string
+	for asset := range deltas {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	for _, asset := range assets {
+		delta := deltas[asset]
+		if math.Abs(delta) < minValue {
+			continue
+		}
+		price, _ := pricer.Price(asset)
+		exposure := AssetExposure{Asset: asset, Delta: delta, Price: price, Value: delta * price}
+		snapshot.Assets = append(snapshot.Assets, exposure)
+		if !pricer.isCash(asset) {
+			snapshot.Gross += math.Abs(exposure.Value)
+			snapshot.Net += exposure.Value
+		}
+		if price <= 0 {
+			missing[asset] = true
+		}
+	}
+
+	for asset := range missing {
+		snapshot.Missing = append(snapshot.Missing, asset)
+	}
+	sort.Strings(snapshot.Missing)
+	return snapshot
+}
+
+// Breach the limit breached, Key identifies the breach so that it's alerted only once
+type Breach struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// Check the breaches of the limits sorted by the keys
+func Check(snapshot *Snapshot, config PortfolioConfig) []Breach {
+	var breaches []Breach
+	add := func(key string, format string, args ...interface{}) {
+		breaches = append(breaches, Breach{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if config.MaxGross > 0 && snapshot.Gross > config.MaxGross {
+		add("gross", "总敞口%.2f%s超过上限%v", snapshot.Gross, snapshot.Base, config.MaxGross)
+	}
+	if config.MaxNet > 0 && math.Abs(snapshot.Net) > config.MaxNet {
+		add("net", "净敞口%.2f%s超过上限%v", snapshot.Net, snapshot.Base, config.MaxNet)
+	}
+	for _, asset := range snapshot.Assets {
+		if limit, ok := config.MaxDelta[asset.Asset]; ok && math.Abs(asset.Delta) > limit {
+			add("delta:"+asset.Asset, "%s净头寸%v超过上限%v", asset.Asset, asset.Delta, limit)
+		}
+	}
+	for _, asset := range snapshot.Missing {
+		add("price:"+asset, "%s没有价格", asset)
+	}
+
+	for _, account := range snapshot.Accounts {
+		if account.Error != "" {
+			add("error:"+account.Name, "%s获取账户失败:%s", account.Name, account.Error)
+		}
+		limit := config.MaxMarginRatio
+		if accountConfig := config.Accounts[account.Name]; accountConfig != nil && accountConfig.MaxMarginRatio > 0 {
+			limit = accountConfig.MaxMarginRatio
+		}
+		if limit > 0 && account.MarginRatio > limit {
+			add("margin:"+account.Name, "%s保证金占用%.4f超过上限%v", account.Name, account.MarginRatio, limit)
+		}
+	}
+
+	if config.MinLiquidation > 0 {
+		for _, position := range snapshot.Positions {
+			if position.Distance > 0 && position.Distance < config.MinLiquidation {
+				add("liquidation:"+position.Account+":"+position.Pair, "%s %s距离强平价%.2f%%(标记价格:%v 强平价格:%v)",
+					position.Account, position.Pair, position.Distance, position.MarkPrice, position.LiquidationPrice)
+			}
+		}
+	}
+
+	sort.Slice(breaches, func(i, j int) bool {
+		return breaches[i].Key < breaches[j].Key
+	})
+	return breaches
+}
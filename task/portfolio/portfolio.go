@@ -0,0 +1,449 @@
+package portfolio
+
+/*
+	组合监控：定时获取所有账户的余额和持仓，按行情换算为基准货币，
+	计算每个资产的净头寸、总敞口和净敞口，以及每个账户的保证金占用和距离强平价的幅度，
+	超过限制时提醒，不下单
+*/
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	Global "madaoQT/config"
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+
+	"github.com/kataras/golog"
+)
+
+var Logger *golog.Logger
+
+func init() {
+	logger := golog.New()
+	Logger = logger
+	Logger.SetLevel("debug")
+	Logger.SetTimeFormat(Global.TimeFormat)
+	Logger.SetPrefix("[PORTFOLIO]")
+}
+
+// maxAlerts the latest alerts are kept in memory
+const maxAlerts = 100
+
+type AccountConfig struct {
+	Venue string `json:"venue" title:"账户类型" desc:"okexv3、okexv3spot、huobispot、huobidm、binance、bitmex、deribit、oanda、ib、ctp" required:"true"`
+	// Keys the name of the keys saved in ExchangeDB, the accounts of the same exchange are saved with different names
+	Keys           string                 `json:"keys,omitempty" title:"密钥名称" desc:"默认使用交易所名称"`
+	Account        string                 `json:"account,omitempty" title:"账户" desc:"密钥的账户名称，为空时使用默认账户"`
+	Custom         map[string]interface{} `json:"custom,omitempty" title:"自定义参数" desc:"如okex的passphrase，ctp的dll、config和instruments"`
+	MaxMarginRatio float64                `json:"maxmarginratio,omitempty" title:"保证金占用上限" desc:"覆盖全局设置" min:"0" max:"1"`
+}
+
+type PortfolioConfig struct {
+	Accounts map[string]*AccountConfig `json:"accounts" title:"账户" required:"true" readonly:"true"`
+	Base     string                    `json:"base" title:"基准货币" readonly:"true"`
+	Cash     []string                  `json:"cash" title:"现金" desc:"与基准货币等值，不计入敞口"`
+	Rates    map[string]float64        `json:"rates,omitempty" title:"固定汇率" desc:"以基准货币计价，如cny、hkd"`
+	Interval int                       `json:"interval" title:"检查间隔" desc:"秒" min:"10"`
+
+	MaxGross       float64            `json:"maxgross" title:"总敞口上限" desc:"基准货币，0不限制" min:"0"`
+	MaxNet         float64            `json:"maxnet" title:"净敞口上限" desc:"基准货币，0不限制" min:"0"`
+	MaxDelta       map[string]float64 `json:"maxdelta,omitempty" title:"净头寸上限" desc:"资产数量"`
+	MaxMarginRatio float64            `json:"maxmarginratio" title:"保证金占用上限" desc:"维持保证金/权益，1时强平" min:"0" max:"1"`
+	MinLiquidation float64            `json:"minliquidation" title:"强平距离下限" desc:"百分比" min:"0"`
+	Proxy          string             `json:"proxy" title:"代理" readonly:"true"`
+}
+
+var defaultConfig = PortfolioConfig{
+	Accounts: map[string]*AccountConfig{
+		"okex": {Venue: VenueOkexV3},
+	},
+	Base:           "usdt",
+	Cash:           []string{"usdt", "usd"},
+	Interval:       60,
+	MaxMarginRatio: 0.5,
+	MinLiquidation: 10,
+	Proxy:          "SOCKS5:127.0.0.1:1080",
+}
+
+// Alert the breach of the limits
+type Alert struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+type Portfolio struct {
+	config   PortfolioConfig
+	accounts map[string]Exchange.IAccount
+	// ticker the mid price of the pair, which is 0 if the pair isn't listed
+	ticker func(pair string) float64
+
+	status Task.StatusType
+
+	// lock protects the snapshots, the breaches and the alerts which are read by the server
+	lock      sync.Mutex
+	snapshots map[string]AccountSnapshot
+	snapshot  *Snapshot
+	breaches  map[string]Breach
+	alerts    []Alert
+
+	// pending the updated config, which is applied by the checking loop
+	pending     *PortfolioConfig
+	pendingLock sync.Mutex
+
+	quit chan bool
+}
+
+func (p *Portfolio) GetDescription() Task.Description {
+	return Task.Description{
+		Name:  "portfolio",
+		Title: "组合监控",
+		Desc:  "汇总所有账户的余额和持仓，监控敞口和保证金",
+	}
+}
+
+func (p *Portfolio) GetDefaultConfig() interface{} {
+	return defaultConfig
+}
+
+func (p *Portfolio) GetStatus() Task.StatusType {
+	return p.status
+}
+
+func checkConfig(config PortfolioConfig) Task.ConfigErrors {
+	var errs Task.ConfigErrors
+	if len(config.Accounts) == 0 {
+		errs = append(errs, Task.ConfigError{Field: "accounts", Message: "at least one account"})
+	}
+	for name, account := range config.Accounts {
+		if account == nil || venues[account.Venue] == nil {
+			errs = append(errs, Task.ConfigError{Field: "accounts", Message: "unsupported venue of " + name})
+		}
+	}
+	if config.Base == "" {
+		errs = append(errs, Task.ConfigError{Field: "base", Message: "required"})
+	}
+	if config.Interval < 10 {
+		errs = append(errs, Task.ConfigError{Field: "interval", Message: "at least 10 seconds"})
+	}
+	for asset, rate := range config.Rates {
+		if rate <= 0 {
+			errs = append(errs, Task.ConfigError{Field: "rates", Message: "invalid rate of " + asset})
+		}
+	}
+	return errs
+}
+
+func (p *Portfolio) Start(configJSON string) error {
+
+	if p.status != Task.StatusNone {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	config := defaultConfig
+	if configJSON != "" {
+		config = PortfolioConfig{}
+		if err := Task.ParseConfig(configJSON, &config); err != nil {
+			Logger.Errorf("Fail to get config:%v", err)
+			return err
+		}
+	}
+	if errs := checkConfig(config); errs != nil {
+		return errs
+	}
+	p.config = config
+
+	Logger.Infof("Config:%v", p.config)
+
+	mongo := new(Mongo.ExchangeDB)
+	if mongo.Connect() != nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+	defer mongo.Close()
+
+	accounts := make(map[string]Exchange.IAccount)
+	for name, account := range p.config.Accounts {
+		venue := venues[account.Venue]
+		exchangeConfig := Exchange.Config{
+			Custom: account.Custom,
+			Proxy:  p.config.Proxy,
+		}
+		if !venue.NoKeys {
+			keys := account.Keys
+			if keys == "" {
+				keys = venue.Exchange
+			}
+			err, record := mongo.FindAccount(keys, account.Account)
+			if err != nil {
+				Logger.Errorf("Fail to get the keys of %s:%v", name, err)
+				closeAccounts(accounts)
+				return errors.New(Task.TaskErrorMsg[Task.TaskAPINotFound])
+			}
+			exchangeConfig.API = string(record.API)
+			exchangeConfig.Secret = string(record.Secret)
+		}
+		accounts[name] = venue.create(exchangeConfig)
+	}
+
+	if p.ticker == nil {
+		binance := new(Exchange.Binance)
+		binance.SetConfigure(Exchange.Config{Proxy: p.config.Proxy})
+		p.ticker = func(pair string) float64 {
+			if depth := binance.GetDepthValue(pair); len(depth) == 2 && len(depth[0]) > 0 && len(depth[1]) > 0 {
+				return (depth[Exchange.DepthTypeAsks][0].Price + depth[Exchange.DepthTypeBids][0].Price) / 2
+			}
+			return 0
+		}
+	}
+
+	Logger.Infof("启动组合监控:%d个账户", len(accounts))
+	p.run(accounts)
+	return nil
+}
+
+// closeAccounts some accounts keep the connections, e.g. Deribit and CTP
+func closeAccounts(accounts map[string]Exchange.IAccount) {
+	for _, account := range accounts {
+		if closer, ok := account.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
+
+// run checks the accounts immediately and then periodically
+func (p *Portfolio) run(accounts map[string]Exchange.IAccount) {
+	p.accounts = accounts
+	p.snapshots = make(map[string]AccountSnapshot)
+	p.breaches = make(map[string]Breach)
+	p.status = Task.StatusProcessing
+	p.quit = make(chan bool)
+
+	go func(quit chan bool) {
+		for {
+			p.applyConfig()
+			p.Watch()
+			select {
+			case <-quit:
+				return
+			case <-time.After(time.Duration(p.config.Interval) * time.Second):
+			}
+		}
+	}(p.quit)
+}
+
+// UpdateConfig 运行时更新限制和汇率，账户和基准货币不能修改
+func (p *Portfolio) UpdateConfig(configJSON string) error {
+
+	if p.status != Task.StatusProcessing {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	var config PortfolioConfig
+	if err := Task.ParseConfig(configJSON, &config); err != nil {
+		return err
+	}
+	if errs := checkConfig(config); errs != nil {
+		return errs
+	}
+	config.Accounts = p.config.Accounts
+	config.Base = p.config.Base
+	config.Proxy = p.config.Proxy
+
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+	p.pending = &config
+	return nil
+}
+
+func (p *Portfolio) applyConfig() {
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+
+	if p.pending != nil {
+		p.config = *p.pending
+		p.pending = nil
+		Logger.Infof("更新配置:%v", p.config)
+	}
+}
+
+// refresh gets the accounts at the same time, the account failed keeps the last info
+func (p *Portfolio) refresh() []AccountSnapshot {
+	var wait sync.WaitGroup
+	for name, account := range p.accounts {
+		wait.Add(1)
+		go func(name string, account Exchange.IAccount) {
+			defer wait.Done()
+			err, info := account.GetAccount()
+
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			snapshot := p.snapshots[name]
+			snapshot.Name = name
+			if err != nil {
+				Logger.Errorf("Fail to get the account %s:%v", name, err)
+				snapshot.Error = err.Error()
+			} else {
+				snapshot.Info = info
+				snapshot.Updated = time.Now()
+				snapshot.Error = ""
+			}
+			p.snapshots[name] = snapshot
+		}(name, account)
+	}
+	wait.Wait()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var snapshots []AccountSnapshot
+	for _, snapshot := range p.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name < snapshots[j].Name
+	})
+	return snapshots
+}
+
+func (p *Portfolio) pricer() *Pricer {
+	return &Pricer{
+		Base:   p.config.Base,
+		Cash:   p.config.Cash,
+		Rates:  p.config.Rates,
+		Ticker: p.ticker,
+	}
+}
+
+func (p *Portfolio) Watch() {
+	if p.status != Task.StatusProcessing {
+		return
+	}
+
+	snapshot := Compute(p.refresh(), p.pricer())
+	Logger.Infof("权益:%.2f 总敞口:%.2f 净敞口:%.2f", snapshot.Equity, snapshot.Gross, snapshot.Net)
+
+	p.lock.Lock()
+	p.snapshot = snapshot
+	p.lock.Unlock()
+
+	p.update(Check(snapshot, p.config))
+}
+
+// update alerts the new breaches, the recovered ones are only logged
+func (p *Portfolio) update(breaches []Breach) {
+	current := make(map[string]Breach)
+	for _, breach := range breaches {
+		current[breach.Key] = breach
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, breach := range breaches {
+		if _, ok := p.breaches[breach.Key]; !ok {
+			Logger.Warn(breach.Message)
+			p.alerts = append(p.alerts, Alert{Time: time.Now(), Message: breach.Message})
+		}
+	}
+	if len(p.alerts) > maxAlerts {
+		p.alerts = p.alerts[len(p.alerts)-maxAlerts:]
+	}
+
+	for key, breach := range p.breaches {
+		if _, ok := current[key]; !ok {
+			Logger.Infof("恢复:%s", breach.Message)
+		}
+	}
+	p.breaches = current
+}
+
+func (p *Portfolio) GetBalances() map[string]interface{} {
+	if p.status != Task.StatusProcessing {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return map[string]interface{}{
+		"snapshot": p.snapshot,
+		"alerts":   append([]Alert(nil), p.alerts...),
+	}
+}
+
+// GetTrades 组合监控不下单
+func (p *Portfolio) GetTrades() []Mongo.TradesRecord {
+	return nil
+}
+
+// GetPositions the positions of all the accounts with the distance to the liquidation price
+func (p *Portfolio) GetPositions() []map[string]interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.snapshot == nil {
+		return nil
+	}
+
+	var positions []map[string]interface{}
+	for _, position := range p.snapshot.Positions {
+		positions = append(positions, map[string]interface{}{
+			"account":          position.Account,
+			"pair":             position.Pair,
+			"quantity":         position.Quantity,
+			"markprice":        position.MarkPrice,
+			"liquidationprice": position.LiquidationPrice,
+			"value":            position.Value,
+			"distance":         position.Distance,
+		})
+	}
+	return positions
+}
+
+// GetFailedPositions the limits breached currently
+func (p *Portfolio) GetFailedPositions() []map[string]interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var keys []string
+	for key := range p.breaches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var positions []map[string]interface{}
+	for _, key := range keys {
+		positions = append(positions, map[string]interface{}{
+			"key":     key,
+			"message": p.breaches[key].Message,
+		})
+	}
+	return positions
+}
+
+// FixFailedPosition 超限需要在各个任务或交易所处理
+func (p *Portfolio) FixFailedPosition(updateJSON string) error {
+	return errors.New(Task.TaskErrorMsg[Task.TaskNotSupported])
+}
+
+// ForceClosePositions 组合监控不下单
+func (p *Portfolio) ForceClosePositions() {
+	Logger.Info("组合监控没有持仓")
+}
+
+func (p *Portfolio) Close() {
+
+	Logger.Info("关闭任务")
+
+	p.status = Task.StatusNone
+
+	if p.quit != nil {
+		close(p.quit)
+		p.quit = nil
+	}
+
+	closeAccounts(p.accounts)
+	p.accounts = nil
+}
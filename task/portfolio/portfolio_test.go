@@ -0,0 +1,142 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+)
+
+func testAccounts() []AccountSnapshot {
+	return []AccountSnapshot{
+		{Name: "bad", Error: "timeout"},
+		{Name: "ctp", Info: &Exchange.AccountInfo{
+			Balances:    map[string]float64{"cny": 70000},
+			Positions:   []Exchange.AccountPosition{{Pair: "rb2010/cny", Quantity: 10, MarkPrice: 3500}},
+			MarginRatio: 0.6,
+		}},
+		{Name: "ib", Info: &Exchange.AccountInfo{
+			Balances:  map[string]float64{"usd": 1500, "xyz": 1},
+			Positions: []Exchange.AccountPosition{{Pair: "aapl/usd", Quantity: 10, MarkPrice: 300, Paid: true}},
+		}},
+		{Name: "okex", Info: &Exchange.AccountInfo{
+			Balances:    map[string]float64{"btc": 0.5},
+			Positions:   []Exchange.AccountPosition{{Pair: "btc/usd", Quantity: -0.2, MarkPrice: 10000, LiquidationPrice: 12000}},
+			MarginRatio: 0.05,
+		}},
+	}
+}
+
+func testPricer() *Pricer {
+	return &Pricer{
+		Base:  "usdt",
+		Cash:  []string{"usdt", "usd"},
+		Rates: map[string]float64{"cny": 0.2},
+		Ticker: func(pair string) float64 {
+			if pair == "btc/usdt" {
+				return 10000
+			}
+			return 0
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	snapshot := Compute(testAccounts(), testPricer())
+
+	expect := map[string]AssetExposure{
+		"aapl":   {Delta: 10, Price: 300, Value: 3000},
+		"btc":    {Delta: 0.3, Price: 10000, Value: 3000},
+		"cny":    {Delta: 35000, Price: 0.2, Value: 7000},
+		"rb2010": {Delta: 10, Price: 700, Value: 7000},
+		"usd":    {Delta: 3500, Price: 1, Value: 3500},
+		"xyz":    {Delta: 1},
+	}
+	if len(snapshot.Assets) != len(expect) {
+		t.Fatalf("Invalid assets:%v", snapshot.Assets)
+	}
+	for _, asset := range snapshot.Assets {
+		e := expect[asset.Asset]
+		if math.Abs(asset.Delta-e.Delta) > 1e-9 || math.Abs(asset.Price-e.Price) > 1e-9 || math.Abs(asset.Value-e.Value) > 1e-6 {
+			t.Errorf("Invalid exposure of %s:%v", asset.Asset, asset)
+		}
+	}
+
+	// the cash isn't counted in the exposures
+	if math.Abs(snapshot.Gross-20000) > 1e-6 || math.Abs(snapshot.Net-20000) > 1e-6 {
+		t.Errorf("Invalid gross:%v net:%v", snapshot.Gross, snapshot.Net)
+	}
+	if math.Abs(snapshot.Equity-23500) > 1e-6 {
+		t.Errorf("Invalid equity:%v", snapshot.Equity)
+	}
+	equities := map[string]float64{"bad": 0, "ctp": 14000, "ib": 4500, "okex": 5000}
+	for _, account := range snapshot.Accounts {
+		if math.Abs(account.Equity-equities[account.Name]) > 1e-6 {
+			t.Errorf("Invalid equity of %s:%v", account.Name, account.Equity)
+		}
+	}
+	if len(snapshot.Missing) != 1 || snapshot.Missing[0] != "xyz" {
+		t.Errorf("Invalid missing prices:%v", snapshot.Missing)
+	}
+
+	if len(snapshot.Positions) != 3 || math.Abs(snapshot.Positions[2].Distance-20) > 1e-9 || snapshot.Positions[0].Distance != 0 {
+		t.Errorf("Invalid positions:%v", snapshot.Positions)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	snapshot := Compute(testAccounts(), testPricer())
+	config := PortfolioConfig{
+		Accounts:       map[string]*AccountConfig{"okex": {Venue: VenueOkexV3, MaxMarginRatio: 0.01}},
+		MaxGross:       15000,
+		MaxNet:         25000,
+		MaxDelta:       map[string]float64{"btc": 0.2, "aapl": 20},
+		MaxMarginRatio: 0.5,
+		MinLiquidation: 25,
+	}
+
+	breaches := Check(snapshot, config)
+	keys := []string{"delta:btc", "error:bad", "gross", "liquidation:okex:btc/usd", "margin:ctp", "margin:okex", "price:xyz"}
+	if len(breaches) != len(keys) {
+		t.Fatalf("Invalid breaches:%v", breaches)
+	}
+	for i, key := range keys {
+		if breaches[i].Key != key {
+			t.Errorf("Invalid breach:%v expect:%s", breaches[i], key)
+		}
+	}
+
+	if breaches = Check(snapshot, PortfolioConfig{}); len(breaches) != 2 {
+		t.Errorf("Only the errors and the prices are checked without the limits:%v", breaches)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	p := &Portfolio{breaches: make(map[string]Breach)}
+	breaches := []Breach{{Key: "gross", Message: "gross"}, {Key: "margin:okex", Message: "margin"}}
+
+	p.update(breaches)
+	if len(p.alerts) != 2 {
+		t.Fatalf("The breaches should be alerted:%v", p.alerts)
+	}
+
+	// the breaches are alerted once until they are recovered
+	p.update(breaches)
+	p.update(breaches[1:])
+	if len(p.alerts) != 2 || len(p.GetFailedPositions()) != 1 {
+		t.Fatalf("Invalid alerts:%v", p.alerts)
+	}
+	p.update(breaches)
+	if len(p.alerts) != 3 || p.alerts[2].Message != "gross" {
+		t.Fatalf("The breach should be alerted again:%v", p.alerts)
+	}
+
+	for i := 0; i < maxAlerts; i++ {
+		p.update(nil)
+		p.update([]Breach{{Key: "net", Message: time.Now().String()}})
+	}
+	if len(p.alerts) != maxAlerts {
+		t.Errorf("Only the latest alerts are kept:%d", len(p.alerts))
+	}
+}
@@ -0,0 +1,135 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+
+	Exchange "madaoQT/exchange"
+)
+
+/*
+	CTP账户通过CTPDll查询，custom中配置dll、config和instruments(需要监控的合约，逗号分隔)。
+	DLL返回的字段与CTP的结构体一致：资金的Balance、CurrMargin，持仓的Position、PosiDirection，
+	合约的VolumeMultiple，行情的LastPrice。风险度(占用保证金/权益)作为保证金占用
+*/
+
+func init() {
+	venues[VenueCTP] = &venue{
+		Exchange: "CTP",
+		NoKeys:   true,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			return &ctpAccount{
+				CTPDll: &Exchange.CTPDll{
+					Dll: syscall.NewLazyDLL(customString(config, "dll", "ctp.dll")),
+					URL: customString(config, "url", ""),
+				},
+				config:      customString(config, "config", ""),
+				instruments: strings.Split(customString(config, "instruments", ""), ","),
+			}
+		},
+	}
+}
+
+type ctpAccount struct {
+	*Exchange.CTPDll
+	config      string
+	instruments []string
+	started     bool
+}
+
+func (p *ctpAccount) start() error {
+	if p.started {
+		return nil
+	}
+	if p.config != "" && !p.CTPDll.SetConfig(p.config) {
+		return errors.New("Invalid CTP config")
+	}
+	if !p.CTPDll.InitMarket() || !p.CTPDll.InitTrade() {
+		return errors.New("Fail to init CTP")
+	}
+	p.started = true
+	return nil
+}
+
+func (p *ctpAccount) Close() {
+	if p.started {
+		p.CTPDll.CloseTrade()
+		p.CTPDll.CloseMarket()
+		p.started = false
+	}
+}
+
+func ctpFloat(values map[string]interface{}, key string) float64 {
+	switch v := values[key].(type) {
+	case float64:
+		return v
+	case string:
+		var f float64
+		fmt.Sscan(v, &f)
+		return f
+	}
+	return 0
+}
+
+// ctpShort the direction of the short position is '3'
+func ctpShort(values map[string]interface{}) bool {
+	switch v := values["PosiDirection"].(type) {
+	case string:
+		return v == "3"
+	case float64:
+		return v == '3'
+	}
+	return false
+}
+
+func (p *ctpAccount) GetAccount() (error, *Exchange.AccountInfo) {
+	if err := p.start(); err != nil {
+		return err, nil
+	}
+
+	balance := p.CTPDll.GetBalance()
+	if balance == nil {
+		return errors.New("Fail to get balance"), nil
+	}
+
+	account := &Exchange.AccountInfo{Balances: map[string]float64{}}
+	equity := ctpFloat(balance, "Balance")
+	account.Balances["cny"] = equity
+	if equity > 0 {
+		account.MarginRatio = ctpFloat(balance, "CurrMargin") / equity
+	}
+
+	for _, instrument := range p.instruments {
+		instrument = strings.TrimSpace(instrument)
+		if instrument == "" {
+			continue
+		}
+		position := p.CTPDll.GetPositionInfo(instrument)
+		if position == nil || ctpFloat(position, "Position") == 0 {
+			continue
+		}
+
+		multiple := 1.0
+		if info := p.CTPDll.GetInstrumentInfo(instrument); info != nil && ctpFloat(info, "VolumeMultiple") > 0 {
+			multiple = ctpFloat(info, "VolumeMultiple")
+		}
+		var price float64
+		if depth := p.CTPDll.GetDepth(instrument); depth != nil {
+			price = ctpFloat(depth, "LastPrice")
+		}
+
+		amount := ctpFloat(position, "Position")
+		if ctpShort(position) {
+			amount = -amount
+		}
+		account.Positions = append(account.Positions, Exchange.AccountPosition{
+			Pair:      strings.ToLower(instrument) + "/cny",
+			Quantity:  amount * multiple,
+			Amount:    amount,
+			MarkPrice: price,
+		})
+	}
+	return nil, account
+}
@@ -0,0 +1,190 @@
+package portfolio
+
+import (
+	"errors"
+	"sync"
+
+	Exchange "madaoQT/exchange"
+)
+
+/*
+	组合监控支持的账户，账户只需要实现Exchange.IAccount
+*/
+
+const (
+	VenueOkexV3     = "okexv3"
+	VenueOkexV3Spot = "okexv3spot"
+	VenueHuobiSpot  = "huobispot"
+	VenueHuobiDM    = "huobidm"
+	VenueBinance    = "binance"
+	VenueBitmex     = "bitmex"
+	VenueDeribit    = "deribit"
+	VenueOanda      = "oanda"
+	VenueIB         = "ib"
+	VenueCTP        = "ctp"
+)
+
+type venue struct {
+	// Exchange the default name of the keys saved in ExchangeDB
+	Exchange string
+	// NoKeys the account is logged in by its own config, e.g. CTP
+	NoKeys bool
+	create func(config Exchange.Config) Exchange.IAccount
+}
+
+func customString(config Exchange.Config, key string, value string) string {
+	if v, ok := config.Custom[key].(string); ok && v != "" {
+		return v
+	}
+	return value
+}
+
+// tokenConfig OANDA uses the API key as the token and the secret as the account
+func tokenConfig(config Exchange.Config) Exchange.Config {
+	custom := map[string]interface{}{}
+	for k, v := range config.Custom {
+		custom[k] = v
+	}
+	custom["token"] = customString(config, "token", config.API)
+	custom["account"] = customString(config, "account", config.Secret)
+	config.Custom = custom
+	return config
+}
+
+// deribitAccount the private requests are sent by the websocket, which is connected and authorized on demand
+type deribitAccount struct {
+	*Exchange.DeribitV2API
+	lock      sync.Mutex
+	connected bool
+}
+
+func (p *deribitAccount) connect() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.connected {
+		return nil
+	}
+
+	event := make(chan Exchange.EventType, 1)
+	if err := p.DeribitV2API.Start2(event); err != nil {
+		return err
+	}
+	if <-event != Exchange.EventConnected {
+		return errors.New("Fail to connect Deribit")
+	}
+	if err := p.DeribitV2API.Authen(false); err != nil {
+		p.DeribitV2API.Close()
+		return err
+	}
+	p.connected = true
+
+	go func() {
+		for e := range event {
+			if e == Exchange.EventLostConnection {
+				p.lock.Lock()
+				p.connected = false
+				p.lock.Unlock()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *deribitAccount) GetAccount() (error, *Exchange.AccountInfo) {
+	if err := p.connect(); err != nil {
+		return err, nil
+	}
+	return p.DeribitV2API.GetAccount()
+}
+
+var venues = map[string]*venue{
+	VenueOkexV3: {
+		Exchange: Exchange.NameOKEXV3,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			return &Exchange.OKEXV3API{
+				InstrumentType: Exchange.InstrumentTypeSwap,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Passphare:      customString(config, "passphrase", ""),
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueOkexV3Spot: {
+		Exchange: Exchange.NameOKEXV3,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			return &Exchange.OKEXV3API{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Passphare:      customString(config, "passphrase", ""),
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueHuobiSpot: {
+		Exchange: Exchange.ExchangeHuobi,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			return &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueHuobiDM: {
+		Exchange: Exchange.ExchangeHuobi,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			return &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSwap,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueBinance: {
+		Exchange: Exchange.NameBinance,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			binance := new(Exchange.Binance)
+			binance.SetConfigure(config)
+			return binance
+		},
+	},
+	VenueBitmex: {
+		Exchange: Exchange.NameBitmex,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			bitmex := new(Exchange.ExchangeBitmex)
+			bitmex.SetConfigure(config)
+			return bitmex
+		},
+	},
+	VenueDeribit: {
+		Exchange: Exchange.NameDeribit,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			return &deribitAccount{DeribitV2API: &Exchange.DeribitV2API{
+				ApiKey:    config.API,
+				SecretKey: config.Secret,
+				Proxy:     config.Proxy,
+			}}
+		},
+	},
+	VenueOanda: {
+		Exchange: Exchange.NameOdanda,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			oanda := new(Exchange.OandaAPI)
+			oanda.SetConfigure(tokenConfig(config))
+			return oanda
+		},
+	},
+	VenueIB: {
+		Exchange: Exchange.NameInteractiveBrokers,
+		create: func(config Exchange.Config) Exchange.IAccount {
+			ib := new(Exchange.InteractiveBrokers)
+			ib.SetConfigure(config)
+			return ib
+		},
+	},
+}
@@ -0,0 +1,390 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/kataras/golog"
+	"google.golang.org/grpc"
+
+	Global "madaoQT/config"
+	Rpc "madaoQT/gprc"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+)
+
+/*
+	策略进程SDK：策略和内置策略一样实现Task.ITask，调用Serve后注册到主程序，由主程序启动、停止和更新配置
+
+	func main() {
+		Sdk.Serve(func() Task.ITask { return new(Strategy) }, Logger)
+	}
+*/
+
+var Logger *golog.Logger
+
+func init() {
+	logger := golog.New()
+	Logger = logger
+	Logger.SetLevel("debug")
+	Logger.SetTimeFormat(Global.TimeFormat)
+	Logger.SetPrefix("[SDK]")
+}
+
+var registryAddress = flag.String("registry", Global.RegistryAddress, "the address of the registry of the server")
+var listenAddress = flag.String("listen", "127.0.0.1:0", "the address of the strategy service")
+var defaultConfig = flag.String("config", "", "start the default instance with the config after registering")
+
+const heartbeatPeriod = 10 * time.Second
+const rpcTimeout = 10 * time.Second
+
+// logBuffer the logs are dropped if the server doesn't read in time
+const logBuffer = 256
+
+type strategyServer struct {
+	factory Task.TaskFactory
+
+	lock      sync.Mutex
+	instances map[string]Task.ITask
+
+	// logLock the tasks log with the lock of the instances
+	logLock     sync.Mutex
+	subscribers map[chan *Rpc.LogEntry]bool
+
+	quit     chan bool
+	quitOnce sync.Once
+}
+
+func result(err error) *Rpc.Result {
+	if err != nil {
+		return &Rpc.Result{Error: err.Error()}
+	}
+	return &Rpc.Result{Result: true}
+}
+
+func (s *strategyServer) getInstance(id string) Task.ITask {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.instances[id]
+}
+
+// Start creates the instance if it doesn't exist
+func (s *strategyServer) Start(ctx context.Context, request *Rpc.ConfigRequest) (*Rpc.Result, error) {
+	s.lock.Lock()
+	task := s.instances[request.Instance]
+	if task == nil {
+		task = s.factory()
+		if namespace, ok := task.(Task.INamespace); ok {
+			namespace.SetNamespace(request.Namespace)
+		}
+		s.instances[request.Instance] = task
+	}
+	s.lock.Unlock()
+
+	Logger.Infof("Start instance %s", request.Instance)
+	return result(task.Start(request.Config)), nil
+}
+
+func (s *strategyServer) Stop(ctx context.Context, request *Rpc.InstanceRequest) (*Rpc.Result, error) {
+	task := s.getInstance(request.Instance)
+	if task == nil {
+		return result(errors.New(Task.TaskErrorMsg[Task.TaskNotFound])), nil
+	}
+
+	Logger.Infof("Stop instance %s", request.Instance)
+	task.Close()
+	return result(nil), nil
+}
+
+func (s *strategyServer) UpdateConfig(ctx context.Context, request *Rpc.ConfigRequest) (*Rpc.Result, error) {
+	task := s.getInstance(request.Instance)
+	if task == nil {
+		return result(errors.New(Task.TaskErrorMsg[Task.TaskNotFound])), nil
+	}
+
+	return result(task.UpdateConfig(request.Config)), nil
+}
+
+func (s *strategyServer) Status(ctx context.Context, request *Rpc.InstanceRequest) (*Rpc.StatusResponse, error) {
+	task := s.getInstance(request.Instance)
+	if task == nil {
+		return &Rpc.StatusResponse{Status: int32(Task.StatusNone)}, nil
+	}
+
+	return &Rpc.StatusResponse{Status: int32(task.GetStatus())}, nil
+}
+
+func (s *strategyServer) Positions(ctx context.Context, request *Rpc.InstanceRequest) (*Rpc.PositionsResponse, error) {
+	task := s.getInstance(request.Instance)
+	if task == nil {
+		return &Rpc.PositionsResponse{}, nil
+	}
+
+	positions, _ := json.Marshal(task.GetPositions())
+	failed, _ := json.Marshal(task.GetFailedPositions())
+	balances, _ := json.Marshal(task.GetBalances())
+	return &Rpc.PositionsResponse{
+		Positions: string(positions),
+		Failed:    string(failed),
+		Balances:  string(balances),
+	}, nil
+}
+
+func (s *strategyServer) Logs(request *Rpc.LogsRequest, stream Rpc.Strategy_LogsServer) error {
+	channel := make(chan *Rpc.LogEntry, logBuffer)
+
+	s.logLock.Lock()
+	s.subscribers[channel] = true
+	s.logLock.Unlock()
+
+	defer func() {
+		s.logLock.Lock()
+		delete(s.subscribers, channel)
+		s.logLock.Unlock()
+	}()
+
+	for {
+		select {
+		case entry := <-channel:
+			if err := stream.Send(entry); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.quit:
+			return nil
+		}
+	}
+}
+
+func (s *strategyServer) Shutdown(ctx context.Context, request *Rpc.Empty) (*Rpc.Result, error) {
+	Logger.Info("Shutdown by the server")
+	s.quitOnce.Do(func() {
+		close(s.quit)
+	})
+	return result(nil), nil
+}
+
+func (s *strategyServer) publish(log *golog.Log) bool {
+	entry := &Rpc.LogEntry{
+		Time:    log.Time.UnixNano() / int64(time.Millisecond),
+		Level:   int32(log.Level),
+		Message: log.Message,
+	}
+
+	s.logLock.Lock()
+	defer s.logLock.Unlock()
+	for channel := range s.subscribers {
+		select {
+		case channel <- entry:
+		default:
+		}
+	}
+
+	// keep printing to the console
+	return false
+}
+
+func (s *strategyServer) closeAll() {
+	s.lock.Lock()
+	instances := make(map[string]Task.ITask)
+	for id, task := range s.instances {
+		instances[id] = task
+	}
+	s.lock.Unlock()
+
+	for id, task := range instances {
+		if status := task.GetStatus(); status == Task.StatusProcessing || status == Task.StatusInit {
+			Logger.Infof("Stop instance %s", id)
+			task.Close()
+		}
+	}
+}
+
+func (s *strategyServer) forceCloseAll() {
+	s.lock.Lock()
+	var tasks []Task.ITask
+	for _, task := range s.instances {
+		tasks = append(tasks, task)
+	}
+	s.lock.Unlock()
+
+	for _, task := range tasks {
+		if task.GetStatus() == Task.StatusProcessing {
+			task.ForceClosePositions()
+		}
+	}
+}
+
+func registerRequest(task Task.ITask, address string) (*Rpc.RegisterRequest, error) {
+	desc := task.GetDescription()
+	request := &Rpc.RegisterRequest{
+		Name:    desc.Name,
+		Title:   desc.Title,
+		Desc:    desc.Desc,
+		Address: address,
+		Pid:     int32(os.Getpid()),
+	}
+
+	if config := task.GetDefaultConfig(); config != nil {
+		data, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		request.DefaultConfig = string(data)
+	}
+
+	var schema *Task.Schema
+	if value, ok := task.(Task.ISchema); ok {
+		schema = value.GetConfigSchema()
+	} else {
+		schema = Task.GenerateSchema(task.GetDefaultConfig())
+	}
+	if schema != nil {
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return nil, err
+		}
+		request.Schema = string(data)
+	}
+
+	return request, nil
+}
+
+// Serve runs the strategy service and registers to the server until the server shuts down the process or it is interrupted,
+// the logs of the loggers are forwarded to the server
+func Serve(factory Task.TaskFactory, loggers ...*golog.Logger) error {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+
+	listener, err := net.Listen("tcp", *listenAddress)
+	if err != nil {
+		return err
+	}
+
+	strategy := &strategyServer{
+		factory:     factory,
+		instances:   make(map[string]Task.ITask),
+		subscribers: make(map[chan *Rpc.LogEntry]bool),
+		quit:        make(chan bool),
+	}
+
+	request, err := registerRequest(factory(), listener.Addr().String())
+	if err != nil {
+		return err
+	}
+
+	for _, logger := range append(loggers, Logger, Task.Logger) {
+		logger.Handle(strategy.publish)
+	}
+
+	// the orders are frozen by the kill switch of the server as well, it's connected in the background so that the
+	// registration isn't delayed
+	Task.GlobalKillSwitch.Flatten = strategy.forceCloseAll
+	go func() {
+		killSwitches := new(Mongo.KillSwitches)
+		if err := killSwitches.Connect(); err != nil {
+			Logger.Errorf("Fail to connect the kill switch:%v", err)
+			return
+		}
+		Task.GlobalKillSwitch.SetDB(killSwitches)
+		if err := Task.GlobalKillSwitch.Restore(); err != nil {
+			Logger.Errorf("Fail to restore the kill switch:%v", err)
+		}
+	}()
+	go func() {
+		reconciliations := new(Mongo.Reconciliations)
+		if err := reconciliations.Connect(); err != nil {
+			Logger.Errorf("Fail to connect the reconciliations:%v", err)
+			return
+		}
+		Task.GlobalReconciler.SetDB(reconciliations)
+	}()
+	go func() {
+		executions := new(Mongo.Executions)
+		if err := executions.Connect(); err != nil {
+			Logger.Errorf("Fail to connect the executions:%v", err)
+			return
+		}
+		Task.GlobalExecutions.SetDB(executions)
+	}()
+
+	// the klines read by the tasks are saved into the kline collection as the server does
+	go func() {
+		klines := new(Mongo.Klines)
+		if err := klines.Connect(); err != nil {
+			Logger.Errorf("Fail to connect the klines:%v", err)
+			return
+		}
+		Task.GlobalKlines.SetDB(klines)
+	}()
+
+	server := grpc.NewServer()
+	Rpc.RegisterStrategyServer(server, strategy)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			Logger.Errorf("Strategy service exits:%v", err)
+		}
+	}()
+
+	conn, err := grpc.Dial(*registryAddress, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	registry := Rpc.NewRegistryClient(conn)
+
+	registered := false
+	register := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+		defer cancel()
+		if result, err := registry.Register(ctx, request); err != nil || !result.Result {
+			Logger.Errorf("Fail to register to %s:%v %v", *registryAddress, err, result)
+			return
+		}
+
+		if !registered && *defaultConfig != "" {
+			result, _ := strategy.Start(ctx, &Rpc.ConfigRequest{Instance: request.Name, Config: *defaultConfig})
+			if !result.Result {
+				Logger.Errorf("Fail to start the default instance:%v", result.Error)
+			}
+		}
+		registered = true
+	}
+
+	Logger.Infof("Strategy %s is listening on %s", request.Name, request.Address)
+	register()
+
+	kill := make(chan os.Signal, 1)
+	signal.Notify(kill, os.Interrupt)
+
+	running := true
+	for running {
+		select {
+		case <-time.After(heartbeatPeriod):
+			register()
+		case <-kill:
+			Logger.Info("interrupt")
+			running = false
+		case <-strategy.quit:
+			running = false
+		}
+	}
+
+	strategy.closeAll()
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	registry.Unregister(ctx, &Rpc.UnregisterRequest{Name: request.Name, Address: request.Address})
+
+	server.Stop()
+	return nil
+}
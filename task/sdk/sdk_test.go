@@ -0,0 +1,123 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+)
+
+type remoteConfig struct {
+	Coin  string  `json:"coin" required:"true"`
+	Limit float64 `json:"limit" min:"0" max:"1"`
+}
+
+type remoteTask struct {
+	namespace string
+	config    string
+	status    Task.StatusType
+}
+
+func (r *remoteTask) GetDefaultConfig() interface{} { return remoteConfig{Coin: "btc"} }
+func (r *remoteTask) GetDescription() Task.Description {
+	return Task.Description{Name: "remote", Title: "remote"}
+}
+func (r *remoteTask) Start(configJSON string) error {
+	if r.status != Task.StatusNone {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+	r.config = configJSON
+	r.status = Task.StatusProcessing
+	Logger.Infof("Start %s with %s", r.namespace, configJSON)
+	return nil
+}
+func (r *remoteTask) UpdateConfig(configJSON string) error { r.config = configJSON; return nil }
+func (r *remoteTask) Close()                               { r.status = Task.StatusNone }
+func (r *remoteTask) GetStatus() Task.StatusType           { return r.status }
+func (r *remoteTask) GetBalances() map[string]interface{}  { return nil }
+func (r *remoteTask) GetTrades() []Mongo.TradesRecord      { return nil }
+func (r *remoteTask) GetPositions() []map[string]interface{} {
+	return []map[string]interface{}{{"namespace": r.namespace, "config": r.config}}
+}
+func (r *remoteTask) GetFailedPositions() []map[string]interface{} { return nil }
+func (r *remoteTask) FixFailedPosition(updateJSON string) error    { return nil }
+func (r *remoteTask) ForceClosePositions()                         {}
+func (r *remoteTask) SetNamespace(namespace string)                { r.namespace = namespace }
+
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 50; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func TestServe(t *testing.T) {
+	manager := new(Task.TaskManager)
+	registry := &Task.Registry{Manager: manager}
+	if err := registry.Serve("127.0.0.1:0"); err != nil {
+		t.Fatalf("Fail to serve:%v", err)
+	}
+	defer registry.Stop()
+
+	*registryAddress = registry.Addr()
+	done := make(chan error)
+	go func() {
+		done <- Serve(func() Task.ITask { return new(remoteTask) })
+	}()
+
+	if !waitFor(func() bool { return manager.Get("remote") != nil }) {
+		t.Fatalf("The strategy should be registered")
+	}
+
+	if err, schema := manager.Schema("remote"); err != nil || schema.Properties["coin"] == nil {
+		t.Errorf("Invalid schema:%v %v", err, schema)
+	}
+
+	// the instances are managed as the built-in tasks
+	err, id := manager.Create("remote", `{"coin":"eth"}`)
+	if err != nil {
+		t.Fatalf("Fail to create:%v", err)
+	}
+	if err := manager.Start(id, ""); err != nil {
+		t.Fatalf("Fail to start:%v", err)
+	}
+	if err, info := manager.Status(id); err != nil || info.Status != Task.StatusProcessing {
+		t.Errorf("Invalid status:%v %v", err, info)
+	}
+	if err, _ := manager.Create("remote", `{"coin":"eth","limit":2}`); err == nil {
+		t.Errorf("The config should be validated with the schema of the strategy")
+	}
+
+	if err, _ := manager.UpdateConfig(id, `{"coin":"eth","limit":0.5}`); err != nil {
+		t.Errorf("Fail to update:%v", err)
+	}
+	positions := manager.Get(id).GetPositions()
+	if len(positions) != 1 || positions[0]["namespace"] != id || positions[0]["config"] != `{"coin":"eth","limit":0.5}` {
+		t.Errorf("Invalid positions:%v", positions)
+	}
+
+	if err := manager.Stop(id); err != nil || manager.Get(id).GetStatus() != Task.StatusNone {
+		t.Errorf("Fail to stop:%v", err)
+	}
+
+	if err := registry.Shutdown("remote"); err != nil {
+		t.Fatalf("Fail to shutdown:%v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve error:%v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("The process should exit")
+	}
+
+	if manager.Get(id).GetStatus() != Task.StatusError {
+		t.Errorf("The task of the unregistered strategy should be error")
+	}
+}
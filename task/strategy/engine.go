@@ -0,0 +1,487 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/kataras/golog"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+)
+
+const defaultInterval = 1 * time.Second
+
+// klineLimit the klines got in each poll, which should cover the klines closed since the last poll
+const klineLimit = 10
+
+// IReplay the simulated exchange which is driven by the backtest, such as Exchange.BookSimulator
+type IReplay interface {
+	// Next() returns the time of the next event, false if nothing is left
+	Next() (time.Time, bool)
+	RunUntil(t time.Time) bool
+}
+
+type subscriptionState struct {
+	ticker *Exchange.TickerValue
+	depth  [][]Exchange.DepthPrice
+	// kline the open time of the last closed kline, it's skipped on the first poll of the live mode
+	kline       float64
+	klineLoaded bool
+	next        time.Time
+}
+
+// Engine runs the strategy, the hooks are called in the polling goroutine in live and paper mode, and by Run() in backtest
+type Engine struct {
+	Strategy  IStrategy
+	Mode      Mode
+	Exchanges map[string]Exchange.IExchange
+	// ID the key of the saved state and the batch of the orders
+	ID     string
+	Config string
+	// Store MemoryStore is used if it's nil
+	Store IStateStore
+	// Trades records the orders if it's set
+	Trades *Mongo.Trades
+	// Interval the polling interval in live and paper mode
+	Interval time.Duration
+
+	lock   sync.Mutex
+	status Task.StatusType
+
+	now           time.Time
+	orders        *OrderManager
+	subscriptions []Subscription
+	states        []*subscriptionState
+	ctx           *Context
+
+	calls chan func()
+	quit  chan bool
+	done  chan bool
+}
+
+func (e *Engine) setup() error {
+	if e.Store == nil {
+		e.Store = &MemoryStore{}
+	}
+	if e.Interval == 0 {
+		e.Interval = defaultInterval
+	}
+
+	if e.Mode == ModePaper {
+		exchanges := make(map[string]Exchange.IExchange)
+		for name, exchange := range e.Exchanges {
+			if _, ok := exchange.(*PaperExchange); !ok {
+				exchange = &PaperExchange{IExchange: exchange}
+			}
+			exchanges[name] = exchange
+		}
+		e.Exchanges = exchanges
+	}
+
+	e.subscriptions = e.Strategy.Subscribe()
+	e.states = make([]*subscriptionState, len(e.subscriptions))
+	for i, subscription := range e.subscriptions {
+		e.states[i] = &subscriptionState{}
+		if subscription.Type == SubscribeTimer {
+			if subscription.Interval <= 0 {
+				return errors.New("Invalid interval of the timer:" + subscription.Name)
+			}
+			continue
+		}
+
+		exchange := e.Exchanges[subscription.Exchange]
+		if exchange == nil {
+			return errors.New("Invalid exchange:" + subscription.Exchange)
+		}
+		exchange.StartTicker(subscription.Pair)
+	}
+
+	e.orders = &OrderManager{
+		Exchanges: e.Exchanges,
+		Trades:    e.Trades,
+		Batch:     e.ID,
+	}
+	e.ctx = &Context{engine: e, Logger: Logger}
+	return nil
+}
+
+// begin the timers start from the time
+func (e *Engine) begin(now time.Time) {
+	e.now = now
+	for i, subscription := range e.subscriptions {
+		if subscription.Type == SubscribeTimer {
+			e.states[i].next = now.Add(subscription.Interval)
+		}
+	}
+}
+
+func (e *Engine) setStatus(status Task.StatusType) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.status = status
+}
+
+func (e *Engine) Status() Task.StatusType {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.status
+}
+
+// Start runs the strategy in live or paper mode, it returns the error of OnStart
+func (e *Engine) Start() error {
+	if e.Status() != Task.StatusNone {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+	if e.Mode == ModeBacktest {
+		return errors.New("The backtest should be run by Run()")
+	}
+
+	if err := e.setup(); err != nil {
+		return err
+	}
+
+	e.begin(time.Now())
+	if err := e.Strategy.OnStart(e.ctx); err != nil {
+		return err
+	}
+
+	e.calls = make(chan func())
+	e.quit = make(chan bool)
+	e.done = make(chan bool)
+	e.setStatus(Task.StatusProcessing)
+
+	for _, exchange := range e.Exchanges {
+		go e.watch(exchange)
+	}
+	go e.loop()
+	return nil
+}
+
+func (e *Engine) watch(exchange Exchange.IExchange) {
+	events := exchange.WatchEvent()
+	for {
+		select {
+		case event := <-events:
+			if event == Exchange.EventLostConnection {
+				go Task.Reconnect(exchange)
+			}
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+func (e *Engine) loop() {
+	defer close(e.done)
+	for {
+		select {
+		case <-e.quit:
+			e.Strategy.OnStop(e.ctx)
+			return
+		case call := <-e.calls:
+			call()
+		case <-time.After(e.Interval):
+			e.poll(time.Now())
+		}
+	}
+}
+
+// Stop waits until OnStop returns
+func (e *Engine) Stop() {
+	if e.Status() != Task.StatusProcessing || e.Mode == ModeBacktest {
+		return
+	}
+
+	close(e.quit)
+	<-e.done
+	e.setStatus(Task.StatusNone)
+}
+
+// call runs the function in the goroutine of the hooks
+func (e *Engine) call(function func()) bool {
+	if e.Mode == ModeBacktest {
+		function()
+		return true
+	}
+
+	if e.Status() != Task.StatusProcessing {
+		return false
+	}
+
+	finished := make(chan bool)
+	select {
+	case e.calls <- func() {
+		function()
+		close(finished)
+	}:
+		<-finished
+		return true
+	case <-e.done:
+		return false
+	}
+}
+
+// UpdateConfig validates the config and calls OnConfig of the strategy, the config is reverted if OnConfig fails
+func (e *Engine) UpdateConfig(configJSON string) error {
+	update, ok := e.Strategy.(IConfigUpdate)
+	if !ok {
+		return errors.New(Task.TaskErrorMsg[Task.TaskNotSupported])
+	}
+
+	if err := Task.ValidateConfig(Task.GenerateSchema(e.Strategy.GetDefaultConfig()), configJSON); err != nil {
+		return err
+	}
+
+	var err error
+	if !e.call(func() {
+		old := e.Config
+		e.Config = configJSON
+		if err = update.OnConfig(e.ctx); err != nil {
+			e.Config = old
+		}
+	}) {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+	return err
+}
+
+// Orders returns the open orders
+func (e *Engine) Orders() []*Order {
+	var orders []*Order
+	e.call(func() {
+		orders = e.orders.Open()
+	})
+	return orders
+}
+
+// CancelAll cancels all the open orders
+func (e *Engine) CancelAll() {
+	e.call(func() {
+		for _, order := range e.orders.Open() {
+			if err := e.orders.Cancel(order); err != nil {
+				Logger.Errorf("Fail to cancel the order %s:%v", order.ID, err)
+			}
+		}
+	})
+}
+
+// Run replays the simulators of the exchanges until the time or the end of the events if it's zero
+func (e *Engine) Run(until time.Time) error {
+	if e.Mode != ModeBacktest {
+		return errors.New("Run() is only used by the backtest")
+	}
+
+	if err := e.setup(); err != nil {
+		return err
+	}
+
+	var replays []IReplay
+	for _, exchange := range e.Exchanges {
+		if replay, ok := exchange.(IReplay); ok {
+			replays = append(replays, replay)
+		}
+	}
+
+	nextEvent := func() (time.Time, bool) {
+		var next time.Time
+		found := false
+		for _, replay := range replays {
+			if t, ok := replay.Next(); ok && (!found || t.Before(next)) {
+				next, found = t, true
+			}
+		}
+		return next, found
+	}
+
+	start, ok := nextEvent()
+	if !ok {
+		return errors.New("No event to replay")
+	}
+
+	e.begin(start)
+	e.setStatus(Task.StatusProcessing)
+	defer e.setStatus(Task.StatusNone)
+
+	if err := e.Strategy.OnStart(e.ctx); err != nil {
+		return err
+	}
+
+	for {
+		next, ok := nextEvent()
+		if !ok {
+			break
+		}
+		// the timers only fire while the events are left
+		if timer, ok := e.nextTimer(); ok && timer.Before(next) {
+			next = timer
+		}
+		if !until.IsZero() && next.After(until) {
+			break
+		}
+
+		for _, replay := range replays {
+			replay.RunUntil(next)
+		}
+		e.poll(next)
+	}
+
+	e.Strategy.OnStop(e.ctx)
+	return nil
+}
+
+func (e *Engine) nextTimer() (time.Time, bool) {
+	var next time.Time
+	found := false
+	for i, subscription := range e.subscriptions {
+		if subscription.Type == SubscribeTimer && (!found || e.states[i].next.Before(next)) {
+			next, found = e.states[i].next, true
+		}
+	}
+	return next, found
+}
+
+// poll dispatches the fills, the changed data and the timers
+func (e *Engine) poll(now time.Time) {
+	e.now = now
+
+	for _, fill := range e.orders.Poll(now) {
+		e.Strategy.OnFill(e.ctx, fill)
+	}
+
+	for i, subscription := range e.subscriptions {
+		state := e.states[i]
+		exchange := e.Exchanges[subscription.Exchange]
+
+		switch subscription.Type {
+		case SubscribeTicker:
+			ticker := exchange.GetTicker(subscription.Pair)
+			if ticker == nil || (state.ticker != nil && *state.ticker == *ticker) {
+				continue
+			}
+			value := *ticker
+			state.ticker = &value
+			e.Strategy.OnTicker(e.ctx, subscription.Exchange, subscription.Pair, value)
+
+		case SubscribeBook:
+			depth := exchange.GetDepthValue(subscription.Pair)
+			if depth == nil || reflect.DeepEqual(depth, state.depth) {
+				continue
+			}
+			state.depth = depth
+			e.Strategy.OnBook(e.ctx, subscription.Exchange, subscription.Pair, depth)
+
+		case SubscribeKline:
+			// the backtest klines aren't saved into the kline collection
+			var klines []Exchange.KlineValue
+			if e.Mode == ModeBacktest {
+				klines = exchange.GetKline(subscription.Pair, subscription.Period, klineLimit)
+			} else {
+				klines = Task.GlobalKlines.GetKline(exchange, subscription.Pair, subscription.Period, klineLimit)
+			}
+			if len(klines) < 2 {
+				continue
+			}
+			// the last kline is not closed
+			closed := klines[:len(klines)-1]
+			if !state.klineLoaded {
+				state.klineLoaded = true
+				if e.Mode != ModeBacktest {
+					state.kline = closed[len(closed)-1].OpenTime
+					continue
+				}
+			}
+			for _, kline := range closed {
+				if kline.OpenTime > state.kline {
+					state.kline = kline.OpenTime
+					e.Strategy.OnKline(e.ctx, subscription.Exchange, subscription.Pair, subscription.Period, kline)
+				}
+			}
+
+		case SubscribeTimer:
+			if now.Before(state.next) {
+				continue
+			}
+			for !now.Before(state.next) {
+				state.next = state.next.Add(subscription.Interval)
+			}
+			e.Strategy.OnTimer(e.ctx, subscription.Name)
+		}
+	}
+}
+
+// Context is passed to the hooks, it should only be used in the hooks
+type Context struct {
+	engine *Engine
+	Logger *golog.Logger
+}
+
+// Now returns the time of the simulation in backtest
+func (c *Context) Now() time.Time {
+	return c.engine.now
+}
+
+func (c *Context) Mode() Mode {
+	return c.engine.Mode
+}
+
+func (c *Context) ID() string {
+	return c.engine.ID
+}
+
+func (c *Context) Exchange(name string) Exchange.IExchange {
+	return c.engine.Exchanges[name]
+}
+
+// Config reads the current config into the config, the default config is used if it's empty
+func (c *Context) Config(config interface{}) error {
+	if c.engine.Config == "" {
+		data, err := json.Marshal(c.engine.Strategy.GetDefaultConfig())
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, config)
+	}
+	return Task.ParseConfig(c.engine.Config, config)
+}
+
+func (c *Context) Place(exchange string, config Exchange.TradeConfig) (error, *Order) {
+	err, order := c.engine.orders.Place(exchange, config, c.engine.now)
+	if err != nil {
+		c.Logger.Errorf("Fail to place the order on %s:%v", exchange, err)
+	}
+	return err, order
+}
+
+func (c *Context) Cancel(order *Order) error {
+	return c.engine.orders.Cancel(order)
+}
+
+// Orders returns the open orders
+func (c *Context) Orders() []*Order {
+	return c.engine.orders.Open()
+}
+
+func (c *Context) SaveState(state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return c.engine.Store.Save(c.engine.ID, string(data))
+}
+
+// LoadState returns false if the state is never saved
+func (c *Context) LoadState(state interface{}) (error, bool) {
+	err, data := c.engine.Store.Load(c.engine.ID)
+	if err != nil || data == "" {
+		return err, false
+	}
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return err, false
+	}
+	return nil, true
+}
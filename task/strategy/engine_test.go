@@ -0,0 +1,219 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Task "madaoQT/task"
+)
+
+var testStart = time.Date(2018, 3, 5, 0, 0, 0, 0, time.UTC)
+
+func testAt(seconds int) time.Time {
+	return testStart.Add(time.Duration(seconds) * time.Second)
+}
+
+type testConfig struct {
+	Amount float64 `json:"amount" min:"0"`
+}
+
+type testState struct {
+	Runs   int     `json:"runs"`
+	Filled float64 `json:"filled"`
+}
+
+type testStrategy struct {
+	Base
+
+	config  testConfig
+	state   testState
+	order   *Order
+	tickers int
+	books   int
+	klines  []float64
+	timers  int
+	fills   []Fill
+	stopped bool
+}
+
+func (s *testStrategy) GetDescription() Task.Description {
+	return Task.Description{Name: "test", Title: "test"}
+}
+
+func (s *testStrategy) GetDefaultConfig() interface{} {
+	return testConfig{Amount: 1}
+}
+
+func (s *testStrategy) Subscribe() []Subscription {
+	return []Subscription{
+		Ticker("sim", "eth/usdt"),
+		Book("sim", "eth/usdt"),
+		Kline("sim", "eth/usdt", 1),
+		Timer("minute", time.Minute),
+	}
+}
+
+func (s *testStrategy) OnStart(ctx *Context) error {
+	if err := ctx.Config(&s.config); err != nil {
+		return err
+	}
+	err, _ := ctx.LoadState(&s.state)
+	return err
+}
+
+func (s *testStrategy) OnTicker(ctx *Context, exchange string, pair string, ticker Exchange.TickerValue) {
+	s.tickers++
+}
+
+func (s *testStrategy) OnBook(ctx *Context, exchange string, pair string, depth [][]Exchange.DepthPrice) {
+	s.books++
+	if s.order != nil || len(depth[Exchange.DepthTypeAsks]) == 0 {
+		return
+	}
+	_, s.order = ctx.Place(exchange, Exchange.TradeConfig{
+		Pair:   pair,
+		Type:   Exchange.TradeTypeBuy,
+		Price:  depth[Exchange.DepthTypeAsks][0].Price,
+		Amount: s.config.Amount,
+	})
+}
+
+func (s *testStrategy) OnKline(ctx *Context, exchange string, pair string, period int, kline Exchange.KlineValue) {
+	s.klines = append(s.klines, kline.OpenTime)
+}
+
+func (s *testStrategy) OnFill(ctx *Context, fill Fill) {
+	s.fills = append(s.fills, fill)
+	s.state.Filled += fill.Amount
+}
+
+func (s *testStrategy) OnTimer(ctx *Context, name string) {
+	s.timers++
+}
+
+func (s *testStrategy) OnStop(ctx *Context) {
+	s.stopped = true
+	s.state.Runs++
+	ctx.SaveState(s.state)
+}
+
+func testSimulator() *Exchange.BookSimulator {
+	sim := new(Exchange.BookSimulator)
+	sim.AddEvents(
+		Exchange.BookEvent{
+			Type: Exchange.BookEventSnapshot,
+			Time: testAt(0),
+			Pair: "eth/usdt",
+			Bids: []Exchange.DepthPrice{{Price: 100, Quantity: 5}},
+			Asks: []Exchange.DepthPrice{{Price: 101, Quantity: 2}},
+		},
+		Exchange.BookEvent{Type: Exchange.BookEventTrade, Time: testAt(10), Pair: "eth/usdt", Price: 101, Quantity: 1, Side: Exchange.TradeTypeBuy},
+		Exchange.BookEvent{Type: Exchange.BookEventTrade, Time: testAt(70), Pair: "eth/usdt", Price: 100, Quantity: 1, Side: Exchange.TradeTypeSell},
+		Exchange.BookEvent{Type: Exchange.BookEventTrade, Time: testAt(130), Pair: "eth/usdt", Price: 101, Quantity: 1, Side: Exchange.TradeTypeBuy},
+		Exchange.BookEvent{Type: Exchange.BookEventTrade, Time: testAt(190), Pair: "eth/usdt", Price: 100, Quantity: 1, Side: Exchange.TradeTypeSell},
+	)
+	return sim
+}
+
+func TestEngineBacktest(t *testing.T) {
+	store := &MemoryStore{}
+	strategy := new(testStrategy)
+	engine := &Engine{
+		Strategy:  strategy,
+		Mode:      ModeBacktest,
+		Exchanges: map[string]Exchange.IExchange{"sim": testSimulator()},
+		ID:        "test",
+		Config:    `{"amount":0.5}`,
+		Store:     store,
+	}
+
+	if err := engine.Run(time.Time{}); err != nil {
+		t.Fatalf("Fail to run:%v", err)
+	}
+
+	if strategy.config.Amount != 0.5 {
+		t.Errorf("Invalid config:%v", strategy.config)
+	}
+	if strategy.books != 1 || strategy.tickers != 4 {
+		t.Errorf("Invalid books:%v tickers:%v", strategy.books, strategy.tickers)
+	}
+	if len(strategy.fills) != 1 || strategy.fills[0].Amount != 0.5 || strategy.fills[0].Price != 101 || strategy.fills[0].Order != strategy.order {
+		t.Errorf("Invalid fills:%v", strategy.fills)
+	}
+	if len(engine.Orders()) != 0 {
+		t.Errorf("The done order should be removed:%v", engine.Orders())
+	}
+
+	expect := []float64{float64(testAt(0).Unix()), float64(testAt(60).Unix()), float64(testAt(120).Unix())}
+	if len(strategy.klines) != len(expect) {
+		t.Fatalf("Invalid klines:%v", strategy.klines)
+	}
+	for i := range expect {
+		if strategy.klines[i] != expect[i] {
+			t.Errorf("Invalid klines:%v", strategy.klines)
+		}
+	}
+
+	// the timer fires at 60s, 120s and 180s
+	if strategy.timers != 3 || !strategy.stopped {
+		t.Errorf("Invalid timers:%v stopped:%v", strategy.timers, strategy.stopped)
+	}
+
+	// the state is loaded by the next run
+	next := new(testStrategy)
+	engine = &Engine{
+		Strategy:  next,
+		Mode:      ModeBacktest,
+		Exchanges: map[string]Exchange.IExchange{"sim": testSimulator()},
+		ID:        "test",
+		Store:     store,
+	}
+	if err := engine.Run(testAt(100)); err != nil {
+		t.Fatalf("Fail to run:%v", err)
+	}
+	if next.config.Amount != 1 {
+		t.Errorf("The default config should be used:%v", next.config)
+	}
+	if next.state.Runs != 2 || next.state.Filled != 1.5 {
+		t.Errorf("Invalid state:%v", next.state)
+	}
+	if next.timers != 1 {
+		t.Errorf("The replay should stop at the time:%v", next.timers)
+	}
+}
+
+func TestEngineLive(t *testing.T) {
+	sim := testSimulator()
+	sim.RunUntil(testAt(0))
+
+	strategy := new(testStrategy)
+	engine := &Engine{
+		Strategy:  strategy,
+		Mode:      ModePaper,
+		Exchanges: map[string]Exchange.IExchange{"sim": sim},
+		ID:        "test",
+		Interval:  10 * time.Millisecond,
+	}
+
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Fail to start:%v", err)
+	}
+	if err := engine.Start(); err == nil {
+		t.Errorf("The running engine can't be started")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if err := engine.UpdateConfig(`{"amount":2}`); err == nil {
+		t.Errorf("The strategy doesn't support updating the config")
+	}
+	engine.Stop()
+
+	if engine.Status() != Task.StatusNone || !strategy.stopped {
+		t.Errorf("Fail to stop")
+	}
+	// the order is simulated by the paper exchange
+	if len(strategy.fills) != 1 || strategy.fills[0].Amount != 1 || len(sim.GetFills()) != 0 {
+		t.Errorf("Invalid fills:%v %v", strategy.fills, sim.GetFills())
+	}
+}
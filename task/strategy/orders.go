@@ -0,0 +1,156 @@
+package strategy
+
+import (
+	"errors"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+// Order the order placed by the strategy
+type Order struct {
+	ID         string                   `json:"id"`
+	Exchange   string                   `json:"exchange"`
+	Pair       string                   `json:"pair"`
+	Type       Exchange.TradeType       `json:"type"`
+	Price      float64                  `json:"price"`
+	Amount     float64                  `json:"amount"`
+	DealAmount float64                  `json:"dealamount"`
+	AvgPrice   float64                  `json:"avgprice"`
+	Status     Exchange.OrderStatusType `json:"status"`
+	Time       time.Time                `json:"time"`
+}
+
+func (o *Order) finished() bool {
+	return o.Status == Exchange.OrderStatusDone || o.Status == Exchange.OrderStatusCanceled ||
+		o.Status == Exchange.OrderStatusRejected || o.Status == Exchange.OrderStatusExpired
+}
+
+// OrderManager places the orders and tracks them until they are finished, the new dealt amount is reported as the fill
+type OrderManager struct {
+	Exchanges map[string]Exchange.IExchange
+	// Trades records the orders if it is set
+	Trades *Mongo.Trades
+	Batch  string
+
+	orders []*Order
+}
+
+func (m *OrderManager) Place(exchange string, config Exchange.TradeConfig, now time.Time) (error, *Order) {
+	target := m.Exchanges[exchange]
+	if target == nil {
+		return errors.New("Invalid exchange:" + exchange), nil
+	}
+
+	if config.Batch == "" {
+		config.Batch = m.Batch
+	}
+
+	result := target.Trade(config)
+	if result == nil || result.Error != nil {
+		if result == nil {
+			return errors.New("Fail to trade"), nil
+		}
+		return result.Error, nil
+	}
+
+	order := &Order{
+		ID:       result.OrderID,
+		Exchange: exchange,
+		Pair:     config.Pair,
+		Type:     config.Type,
+		Price:    config.Price,
+		Amount:   config.Amount,
+		Status:   Exchange.OrderStatusOrdering,
+		Time:     now,
+	}
+	m.orders = append(m.orders, order)
+
+	if m.Trades != nil {
+		m.Trades.Insert(&Mongo.TradesRecord{
+			Batch:    config.Batch,
+			Oper:     Exchange.TradeTypeString[config.Type],
+			Exchange: target.GetExchangeName(),
+			Pair:     config.Pair,
+			Quantity: config.Amount,
+			Price:    config.Price,
+			OrderID:  order.ID,
+		})
+	}
+
+	return nil, order
+}
+
+// Cancel the order is removed when the exchange reports it canceled
+func (m *OrderManager) Cancel(order *Order) error {
+	target := m.Exchanges[order.Exchange]
+	if target == nil {
+		return errors.New("Invalid exchange:" + order.Exchange)
+	}
+
+	result := target.CancelOrder(Exchange.OrderInfo{
+		Pair:    order.Pair,
+		OrderID: order.ID,
+		Type:    order.Type,
+	})
+	if result == nil {
+		return errors.New("Fail to cancel")
+	}
+	return result.Error
+}
+
+// Open returns the unfinished orders
+func (m *OrderManager) Open() []*Order {
+	return append([]*Order(nil), m.orders...)
+}
+
+// Poll updates the orders from the exchanges, and returns the fills since the last poll
+func (m *OrderManager) Poll(now time.Time) []Fill {
+	var fills []Fill
+	var open []*Order
+
+	for _, order := range m.orders {
+		target := m.Exchanges[order.Exchange]
+		infos := target.GetOrderInfo(Exchange.OrderInfo{
+			Pair:    order.Pair,
+			OrderID: order.ID,
+		})
+		if len(infos) == 0 {
+			open = append(open, order)
+			continue
+		}
+		info := infos[0]
+
+		if info.DealAmount > order.DealAmount {
+			amount := info.DealAmount - order.DealAmount
+			price := info.AvgPrice
+			if price == 0 {
+				price = order.Price
+			} else if order.DealAmount > 0 {
+				// the price of the new dealt amount
+				price = (info.AvgPrice*info.DealAmount - order.AvgPrice*order.DealAmount) / amount
+			}
+			order.DealAmount = info.DealAmount
+			order.AvgPrice = info.AvgPrice
+			fills = append(fills, Fill{Order: order, Time: now, Price: price, Amount: amount})
+		}
+		order.Status = info.Status
+
+		if !order.finished() {
+			open = append(open, order)
+			continue
+		}
+
+		if m.Trades != nil {
+			if order.Status == Exchange.OrderStatusDone {
+				m.Trades.SetDone(order.ID)
+			} else {
+				m.Trades.SetCanceled(order.ID)
+			}
+		}
+	}
+
+	m.orders = open
+	return fills
+}
@@ -0,0 +1,245 @@
+package strategy
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+
+	Exchange "madaoQT/exchange"
+)
+
+// PaperExchange uses the market data of the exchange and simulates the orders: the order takes the levels of the book
+// within the price, the rest is filled at the price when the opposite side of the book crosses it. The taken quantity is
+// removed from the book until the book of the exchange changes
+type PaperExchange struct {
+	Exchange.IExchange
+	// Balances the initial balances, the ones of the exchange are used if it's nil
+	Balances map[string]float64
+
+	lock    sync.Mutex
+	orderID int
+	orders  map[string]*Exchange.OrderInfo
+	ids     []string
+
+	books map[string][][]Exchange.DepthPrice
+	taken map[string]map[float64]float64
+}
+
+func isBuy(tradeType Exchange.TradeType) bool {
+	return tradeType == Exchange.TradeTypeBuy || tradeType == Exchange.TradeTypeOpenLong || tradeType == Exchange.TradeTypeCloseShort
+}
+
+func (p *PaperExchange) init() {
+	if p.orders != nil {
+		return
+	}
+
+	p.orders = make(map[string]*Exchange.OrderInfo)
+	p.books = make(map[string][][]Exchange.DepthPrice)
+	p.taken = make(map[string]map[float64]float64)
+	if p.Balances == nil {
+		p.Balances = make(map[string]float64)
+		for coin, value := range p.IExchange.GetBalance() {
+			if balance, ok := value.(map[string]interface{}); ok {
+				if amount, ok := balance["balance"].(float64); ok {
+					p.Balances[coin] = amount
+				}
+			}
+		}
+	}
+}
+
+// depth returns the book of the exchange without the taken quantity
+func (p *PaperExchange) depth(pair string) [][]Exchange.DepthPrice {
+	depth := p.IExchange.GetDepthValue(pair)
+	if depth == nil {
+		return nil
+	}
+
+	if !reflect.DeepEqual(depth, p.books[pair]) {
+		p.books[pair] = depth
+		p.taken[pair] = make(map[float64]float64)
+	}
+
+	taken := p.taken[pair]
+	result := make([][]Exchange.DepthPrice, 2)
+	for side, levels := range depth {
+		for _, level := range levels {
+			if quantity := level.Quantity - taken[level.Price]; quantity > 1e-9 {
+				result[side] = append(result[side], Exchange.DepthPrice{Price: level.Price, Quantity: quantity})
+			}
+		}
+	}
+	return result
+}
+
+func (p *PaperExchange) fill(order *Exchange.OrderInfo, price float64, amount float64) {
+	deal := order.DealAmount + amount
+	order.AvgPrice = (order.AvgPrice*order.DealAmount + price*amount) / deal
+	order.DealAmount = deal
+	if order.Amount-order.DealAmount <= 1e-9 {
+		order.Status = Exchange.OrderStatusDone
+	} else {
+		order.Status = Exchange.OrderStatusPartDone
+	}
+
+	if coins := Exchange.ParsePair(order.Pair); len(coins) == 2 {
+		if isBuy(order.Type) {
+			p.Balances[coins[0]] += amount
+			p.Balances[coins[1]] -= amount * price
+		} else {
+			p.Balances[coins[0]] -= amount
+			p.Balances[coins[1]] += amount * price
+		}
+	}
+}
+
+// take the new order takes the levels within the price
+func (p *PaperExchange) take(order *Exchange.OrderInfo) {
+	depth := p.depth(order.Pair)
+	if depth == nil {
+		return
+	}
+
+	levels := depth[Exchange.DepthTypeBids]
+	if isBuy(order.Type) {
+		levels = depth[Exchange.DepthTypeAsks]
+	}
+
+	for _, level := range levels {
+		if isBuy(order.Type) && level.Price > order.Price || !isBuy(order.Type) && level.Price < order.Price {
+			break
+		}
+		amount := order.Amount - order.DealAmount
+		if level.Quantity < amount {
+			amount = level.Quantity
+		}
+		p.fill(order, level.Price, amount)
+		p.taken[order.Pair][level.Price] += amount
+		if order.Status == Exchange.OrderStatusDone {
+			break
+		}
+	}
+}
+
+// match the resting orders are filled when the book crosses them
+func (p *PaperExchange) match(pair string) {
+	var depth [][]Exchange.DepthPrice
+	for _, id := range p.ids {
+		order := p.orders[id]
+		if order.Pair != pair || (order.Status != Exchange.OrderStatusOpen && order.Status != Exchange.OrderStatusPartDone) {
+			continue
+		}
+
+		if depth == nil {
+			if depth = p.depth(pair); depth == nil {
+				return
+			}
+		}
+
+		if isBuy(order.Type) {
+			asks := depth[Exchange.DepthTypeAsks]
+			if len(asks) > 0 && asks[0].Price <= order.Price {
+				p.fill(order, order.Price, order.Amount-order.DealAmount)
+			}
+		} else {
+			bids := depth[Exchange.DepthTypeBids]
+			if len(bids) > 0 && bids[0].Price >= order.Price {
+				p.fill(order, order.Price, order.Amount-order.DealAmount)
+			}
+		}
+	}
+}
+
+func (p *PaperExchange) GetBalance() map[string]interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	result := make(map[string]interface{})
+	for coin, balance := range p.Balances {
+		result[coin] = map[string]interface{}{
+			"balance": balance,
+		}
+	}
+	return result
+}
+
+func (p *PaperExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	if configs.Type == Exchange.TradeTypeCancel || configs.Type == Exchange.TradeTypeUnknown ||
+		configs.Price <= 0 || configs.Amount <= 0 {
+		return &Exchange.TradeResult{
+			Error: errors.New("Invalid trade config"),
+		}
+	}
+
+	p.orderID++
+	order := &Exchange.OrderInfo{
+		Pair:    configs.Pair,
+		OrderID: "paper-" + strconv.Itoa(p.orderID),
+		Price:   configs.Price,
+		Amount:  configs.Amount,
+		Type:    configs.Type,
+		Status:  Exchange.OrderStatusOpen,
+	}
+	p.orders[order.OrderID] = order
+	p.ids = append(p.ids, order.OrderID)
+	p.take(order)
+
+	info := *order
+	return &Exchange.TradeResult{
+		OrderID: info.OrderID,
+		Info:    &info,
+	}
+}
+
+func (p *PaperExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	target := p.orders[order.OrderID]
+	if target == nil || (target.Status != Exchange.OrderStatusOpen && target.Status != Exchange.OrderStatusPartDone) {
+		return &Exchange.TradeResult{
+			Error: errors.New("Invalid order"),
+		}
+	}
+
+	target.Status = Exchange.OrderStatusCanceled
+	info := *target
+	return &Exchange.TradeResult{
+		OrderID: info.OrderID,
+		Info:    &info,
+	}
+}
+
+// GetOrderInfo() get the order by OrderID, or all the unfinished orders of the pair if OrderID is empty
+func (p *PaperExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.init()
+
+	if filter.OrderID != "" {
+		order := p.orders[filter.OrderID]
+		if order == nil {
+			return nil
+		}
+		p.match(order.Pair)
+		return []Exchange.OrderInfo{*order}
+	}
+
+	p.match(filter.Pair)
+	var result []Exchange.OrderInfo
+	for _, id := range p.ids {
+		order := p.orders[id]
+		if order.Pair == filter.Pair && (order.Status == Exchange.OrderStatusOpen || order.Status == Exchange.OrderStatusPartDone) {
+			result = append(result, *order)
+		}
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+package strategy
+
+import (
+	"testing"
+
+	Exchange "madaoQT/exchange"
+)
+
+func TestPaperExchange(t *testing.T) {
+	sim := new(Exchange.BookSimulator)
+	sim.AddEvents(
+		Exchange.BookEvent{
+			Type: Exchange.BookEventSnapshot,
+			Time: testAt(0),
+			Pair: "eth/usdt",
+			Bids: []Exchange.DepthPrice{{Price: 100, Quantity: 5}},
+			Asks: []Exchange.DepthPrice{{Price: 101, Quantity: 1}, {Price: 102, Quantity: 1}},
+		},
+		Exchange.BookEvent{
+			Type: Exchange.BookEventDelta,
+			Time: testAt(10),
+			Pair: "eth/usdt",
+			Asks: []Exchange.DepthPrice{{Price: 101, Quantity: 0}, {Price: 102, Quantity: 0}, {Price: 99.5, Quantity: 1}},
+		},
+	)
+	sim.RunUntil(testAt(0))
+
+	paper := &PaperExchange{
+		IExchange: sim,
+		Balances:  map[string]float64{"usdt": 1000},
+	}
+
+	// 1 is taken at 101, the rest rests at 101.5
+	result := paper.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 101.5, Amount: 2})
+	if result.Error != nil || result.Info.Status != Exchange.OrderStatusPartDone || result.Info.DealAmount != 1 {
+		t.Fatalf("Invalid result:%v %v", result.Error, result.Info)
+	}
+	if orders := paper.GetOrderInfo(Exchange.OrderInfo{Pair: "eth/usdt"}); len(orders) != 1 {
+		t.Errorf("Invalid open orders:%v", orders)
+	}
+
+	sim.RunUntil(testAt(10))
+	orders := paper.GetOrderInfo(Exchange.OrderInfo{OrderID: result.OrderID})
+	if len(orders) != 1 || orders[0].Status != Exchange.OrderStatusDone || orders[0].AvgPrice != 101.25 {
+		t.Errorf("Invalid order:%v", orders)
+	}
+
+	balance := paper.GetBalance()
+	if balance["eth"].(map[string]interface{})["balance"] != 2.0 || balance["usdt"].(map[string]interface{})["balance"] != 797.5 {
+		t.Errorf("Invalid balance:%v", balance)
+	}
+
+	if result := paper.CancelOrder(orders[0]); result.Error == nil {
+		t.Errorf("The done order can't be canceled")
+	}
+	if len(sim.GetFills()) != 0 {
+		t.Errorf("The orders should not be sent to the exchange")
+	}
+}
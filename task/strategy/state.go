@@ -0,0 +1,32 @@
+package strategy
+
+import "sync"
+
+// IStateStore saves the state of the strategy as JSON, Mongo.StrategyStates is used in live and paper mode
+type IStateStore interface {
+	Save(id string, state string) error
+	// Load() returns the empty state if it is never saved
+	Load(id string) (error, string)
+}
+
+// MemoryStore the state is kept in memory, it's used by the backtest
+type MemoryStore struct {
+	lock   sync.Mutex
+	states map[string]string
+}
+
+func (m *MemoryStore) Save(id string, state string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.states == nil {
+		m.states = make(map[string]string)
+	}
+	m.states[id] = state
+	return nil
+}
+
+func (m *MemoryStore) Load(id string) (error, string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return nil, m.states[id]
+}
@@ -0,0 +1,126 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/kataras/golog"
+
+	Global "madaoQT/config"
+	Exchange "madaoQT/exchange"
+	Task "madaoQT/task"
+)
+
+/*
+	策略框架：策略只实现行情、成交和定时器的回调，订阅在启动前声明，
+	下单通过OrderManager，状态通过StateStore保存，同一份策略代码可以实盘、模拟盘和回测运行
+*/
+
+var Logger *golog.Logger
+
+func init() {
+	logger := golog.New()
+	Logger = logger
+	Logger.SetLevel("debug")
+	Logger.SetTimeFormat(Global.TimeFormat)
+	Logger.SetPrefix("[STRATEGY]")
+}
+
+// Mode the running mode of the strategy
+type Mode int
+
+const (
+	// ModeLive trades with the exchanges
+	ModeLive Mode = iota
+	// ModePaper uses the market data of the exchanges and simulates the orders
+	ModePaper
+	// ModeBacktest replays the recorded events with the simulators
+	ModeBacktest
+)
+
+var ModeString = map[Mode]string{
+	ModeLive:     "live",
+	ModePaper:    "paper",
+	ModeBacktest: "backtest",
+}
+
+type SubscriptionType int
+
+const (
+	SubscribeTicker SubscriptionType = iota
+	SubscribeBook
+	SubscribeKline
+	SubscribeTimer
+)
+
+// Subscription the data or the timer the strategy needs
+type Subscription struct {
+	Type     SubscriptionType
+	Exchange string
+	Pair     string
+	// Period the period of the kline in minutes
+	Period int
+	// Name and Interval are used by the timer
+	Name     string
+	Interval time.Duration
+}
+
+func Ticker(exchange string, pair string) Subscription {
+	return Subscription{Type: SubscribeTicker, Exchange: exchange, Pair: pair}
+}
+
+func Book(exchange string, pair string) Subscription {
+	return Subscription{Type: SubscribeBook, Exchange: exchange, Pair: pair}
+}
+
+// Kline OnKline is called when the kline of the period is closed
+func Kline(exchange string, pair string, period int) Subscription {
+	return Subscription{Type: SubscribeKline, Exchange: exchange, Pair: pair, Period: period}
+}
+
+func Timer(name string, interval time.Duration) Subscription {
+	return Subscription{Type: SubscribeTimer, Name: name, Interval: interval}
+}
+
+// Fill the new dealt amount of the order
+type Fill struct {
+	Order  *Order
+	Time   time.Time
+	Price  float64
+	Amount float64
+}
+
+// IStrategy the hooks are called in one goroutine, so that the strategy doesn't need any lock
+type IStrategy interface {
+	GetDescription() Task.Description
+	// GetDefaultConfig() the config is read by Context.Config()
+	GetDefaultConfig() interface{}
+	// Subscribe() declares the data and the timers before starting
+	Subscribe() []Subscription
+
+	OnStart(ctx *Context) error
+	OnTicker(ctx *Context, exchange string, pair string, ticker Exchange.TickerValue)
+	OnBook(ctx *Context, exchange string, pair string, depth [][]Exchange.DepthPrice)
+	OnKline(ctx *Context, exchange string, pair string, period int, kline Exchange.KlineValue)
+	OnFill(ctx *Context, fill Fill)
+	OnTimer(ctx *Context, name string)
+	OnStop(ctx *Context)
+}
+
+// IConfigUpdate the strategy which can apply the updated config at runtime
+type IConfigUpdate interface {
+	OnConfig(ctx *Context) error
+}
+
+// Base implements the hooks which do nothing, the strategy embeds it and overrides the ones it needs
+type Base struct{}
+
+func (b *Base) OnStart(ctx *Context) error { return nil }
+func (b *Base) OnTicker(ctx *Context, exchange string, pair string, ticker Exchange.TickerValue) {
+}
+func (b *Base) OnBook(ctx *Context, exchange string, pair string, depth [][]Exchange.DepthPrice) {
+}
+func (b *Base) OnKline(ctx *Context, exchange string, pair string, period int, kline Exchange.KlineValue) {
+}
+func (b *Base) OnFill(ctx *Context, fill Fill)    {}
+func (b *Base) OnTimer(ctx *Context, name string) {}
+func (b *Base) OnStop(ctx *Context)               {}
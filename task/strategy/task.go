@@ -0,0 +1,190 @@
+package strategy
+
+import (
+	"errors"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	Task "madaoQT/task"
+)
+
+// ExchangeFactory connects the exchanges used by the subscriptions and the orders, the keys are the names of the exchanges
+type ExchangeFactory func() (error, map[string]Exchange.IExchange)
+
+// StrategyTask runs the strategy as the task in live or paper mode, so that it can be managed by the TaskManager or served by the SDK
+type StrategyTask struct {
+	Strategy  IStrategy
+	Mode      Mode
+	Exchanges ExchangeFactory
+
+	namespace string
+	engine    *Engine
+	exchanges map[string]Exchange.IExchange
+	trades    *Mongo.Trades
+	states    *Mongo.StrategyStates
+}
+
+func (t *StrategyTask) SetNamespace(namespace string) {
+	t.namespace = namespace
+}
+
+func (t *StrategyTask) GetDefaultConfig() interface{} {
+	return t.Strategy.GetDefaultConfig()
+}
+
+func (t *StrategyTask) GetDescription() Task.Description {
+	return t.Strategy.GetDescription()
+}
+
+func (t *StrategyTask) Start(configJSON string) error {
+	if t.GetStatus() != Task.StatusNone {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+	if t.Mode == ModeBacktest {
+		return errors.New(Task.TaskErrorMsg[Task.TaskNotSupported])
+	}
+
+	if configJSON != "" {
+		if err := Task.ValidateConfig(Task.GenerateSchema(t.Strategy.GetDefaultConfig()), configJSON); err != nil {
+			return err
+		}
+	}
+
+	err, exchanges := t.Exchanges()
+	if err != nil {
+		return err
+	}
+	t.exchanges = exchanges
+
+	name := t.Strategy.GetDescription().Name
+	engine := &Engine{
+		Strategy:  t.Strategy,
+		Mode:      t.Mode,
+		Exchanges: exchanges,
+		ID:        Task.CollectionName(t.namespace, name),
+		Config:    configJSON,
+	}
+
+	t.states = &Mongo.StrategyStates{}
+	if err := t.states.Connect(); err != nil {
+		Logger.Warnf("The state of %s is not saved:%v", engine.ID, err)
+		t.states = nil
+	} else {
+		engine.Store = t.states
+	}
+
+	t.trades = &Mongo.Trades{
+		Config: &Mongo.DBConfig{
+			CollectionName: Task.CollectionName(t.namespace, name+"Trades"),
+		},
+	}
+	if err := t.trades.Connect(); err != nil {
+		Logger.Warnf("The orders of %s are not recorded:%v", engine.ID, err)
+		t.trades = nil
+	} else {
+		engine.Trades = t.trades
+	}
+
+	if err := engine.Start(); err != nil {
+		t.close()
+		return err
+	}
+
+	t.engine = engine
+	Logger.Infof("Start %s in %s mode", engine.ID, ModeString[t.Mode])
+	return nil
+}
+
+func (t *StrategyTask) UpdateConfig(configJSON string) error {
+	if t.engine == nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+	return t.engine.UpdateConfig(configJSON)
+}
+
+func (t *StrategyTask) close() {
+	for _, exchange := range t.exchanges {
+		exchange.Close()
+	}
+	if t.states != nil {
+		t.states.Close()
+	}
+	if t.trades != nil {
+		t.trades.Close()
+	}
+}
+
+func (t *StrategyTask) Close() {
+	if t.engine == nil {
+		return
+	}
+	t.engine.Stop()
+	t.close()
+	t.engine = nil
+}
+
+func (t *StrategyTask) GetStatus() Task.StatusType {
+	if t.engine == nil {
+		return Task.StatusNone
+	}
+	return t.engine.Status()
+}
+
+func (t *StrategyTask) GetBalances() map[string]interface{} {
+	if t.engine == nil {
+		return nil
+	}
+
+	balances := make(map[string]interface{})
+	for name, exchange := range t.engine.Exchanges {
+		balances[name] = exchange.GetBalance()
+	}
+	return balances
+}
+
+func (t *StrategyTask) GetTrades() []Mongo.TradesRecord {
+	if t.trades == nil {
+		return nil
+	}
+	err, records := t.trades.FindAll()
+	if err != nil {
+		Logger.Errorf("Fail to get the trades:%v", err)
+		return nil
+	}
+	return records
+}
+
+// GetPositions() returns the open orders
+func (t *StrategyTask) GetPositions() []map[string]interface{} {
+	if t.engine == nil {
+		return nil
+	}
+
+	var positions []map[string]interface{}
+	for _, order := range t.engine.Orders() {
+		positions = append(positions, map[string]interface{}{
+			"exchange":   order.Exchange,
+			"pair":       order.Pair,
+			"orderid":    order.ID,
+			"type":       Exchange.TradeTypeString[order.Type],
+			"price":      order.Price,
+			"amount":     order.Amount,
+			"dealamount": order.DealAmount,
+			"time":       order.Time,
+		})
+	}
+	return positions
+}
+
+func (t *StrategyTask) GetFailedPositions() []map[string]interface{} { return nil }
+
+func (t *StrategyTask) FixFailedPosition(updateJSON string) error {
+	return errors.New(Task.TaskErrorMsg[Task.TaskNotSupported])
+}
+
+// ForceClosePositions() cancels the open orders
+func (t *StrategyTask) ForceClosePositions() {
+	if t.engine != nil {
+		t.engine.CancelAll()
+	}
+}
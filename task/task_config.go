@@ -0,0 +1,452 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	任务配置：根据配置结构体的标签生成JSON Schema，启动任务前校验配置，运行时按字段比较更新配置
+
+	支持的标签：
+		json:"name"      字段名
+		title:"..."      标题
+		desc:"..."       说明
+		min:"0" max:"1"  数值范围
+		enum:"a|b"       可选值
+		required:"true"  必填
+		readonly:"true"  运行时不可修改
+*/
+
+const SchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// Schema the subset of JSON Schema used to describe the config of the task
+type Schema struct {
+	Version              string             `json:"$schema,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// ConfigError the error of the field, the field is the path like "area.btc.open"
+type ConfigError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	var messages []string
+	for _, item := range e {
+		if item.Field == "" {
+			messages = append(messages, item.Message)
+		} else {
+			messages = append(messages, item.Field+": "+item.Message)
+		}
+	}
+	return TaskErrorMsg[TaskInvalidConfig] + ": " + strings.Join(messages, "; ")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// GenerateSchema generates the schema from the config, the values of the config are used as the defaults
+func GenerateSchema(config interface{}) *Schema {
+	if config == nil {
+		return nil
+	}
+
+	schema := generateSchema(reflect.TypeOf(config), reflect.ValueOf(config))
+	schema.Version = SchemaVersion
+	return schema
+}
+
+func generateSchema(t reflect.Type, v reflect.Value) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsValid() {
+			if v.IsNil() {
+				v = reflect.Value{}
+			} else {
+				v = v.Elem()
+			}
+		}
+	}
+
+	schema := new(Schema)
+
+	if t == timeType {
+		schema.Type = "string"
+		schema.Format = "date-time"
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		schema.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		schema.Type = "number"
+	case reflect.String:
+		schema.Type = "string"
+	case reflect.Slice, reflect.Array:
+		schema.Type = "array"
+		schema.Items = generateSchema(t.Elem(), reflect.Value{})
+	case reflect.Map:
+		schema.Type = "object"
+		schema.AdditionalProperties = generateSchema(t.Elem(), reflect.Value{})
+	case reflect.Struct:
+		schema.Type = "object"
+		schema.Properties = make(map[string]*Schema)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			name := fieldName(field)
+			if name == "" {
+				continue
+			}
+
+			var value reflect.Value
+			if v.IsValid() {
+				value = v.Field(i)
+			}
+
+			property := generateSchema(field.Type, value)
+			applyTags(property, field.Tag)
+			if value.IsValid() && !isZero(value) {
+				property.Default = value.Interface()
+			}
+
+			schema.Properties[name] = property
+			if field.Tag.Get("required") == "true" {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	}
+
+	return schema
+}
+
+func fieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func applyTags(schema *Schema, tag reflect.StructTag) {
+	schema.Title = tag.Get("title")
+	schema.Description = tag.Get("desc")
+	schema.ReadOnly = tag.Get("readonly") == "true"
+
+	if value, err := strconv.ParseFloat(tag.Get("min"), 64); err == nil {
+		schema.Minimum = &value
+	}
+	if value, err := strconv.ParseFloat(tag.Get("max"), 64); err == nil {
+		schema.Maximum = &value
+	}
+
+	if enum := tag.Get("enum"); enum != "" {
+		for _, item := range strings.Split(enum, "|") {
+			if schema.Type == "number" || schema.Type == "integer" {
+				if value, err := strconv.ParseFloat(item, 64); err == nil {
+					schema.Enum = append(schema.Enum, value)
+				}
+			} else {
+				schema.Enum = append(schema.Enum, item)
+			}
+		}
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// ValidateConfig checks the config with the schema, returns ConfigErrors if the config is invalid
+func ValidateConfig(schema *Schema, configJSON string) error {
+	if schema == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(configJSON), &value); err != nil {
+		return ConfigErrors{{Message: err.Error()}}
+	}
+
+	var errs ConfigErrors
+	validate(schema, value, "", &errs)
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// ParseConfig validates the config and unmarshals it into the config
+func ParseConfig(configJSON string, config interface{}) error {
+	if err := ValidateConfig(GenerateSchema(config), configJSON); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(configJSON), config); err != nil {
+		return ConfigErrors{{Message: err.Error()}}
+	}
+	return nil
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func validate(schema *Schema, value interface{}, path string, errs *ConfigErrors) {
+
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ConfigError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch schema.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("should be boolean")
+		}
+		return
+
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			fail("should be string")
+			return
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, text); err != nil {
+				fail("should be RFC3339 time")
+				return
+			}
+		}
+		if schema.Enum != nil && !inEnum(schema.Enum, text) {
+			fail("should be one of %v", schema.Enum)
+		}
+		return
+
+	case "number", "integer":
+		number, ok := value.(float64)
+		if !ok {
+			fail("should be %s", schema.Type)
+			return
+		}
+		if schema.Type == "integer" && number != math.Trunc(number) {
+			fail("should be integer")
+			return
+		}
+		if schema.Minimum != nil && number < *schema.Minimum {
+			fail("should be >= %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			fail("should be <= %v", *schema.Maximum)
+		}
+		if schema.Enum != nil && !inEnum(schema.Enum, number) {
+			fail("should be one of %v", schema.Enum)
+		}
+		return
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			if value != nil {
+				fail("should be array")
+			}
+			return
+		}
+		for i, item := range items {
+			validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+		return
+
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			if value != nil {
+				fail("should be object")
+			}
+			return
+		}
+
+		for _, name := range schema.Required {
+			if item, ok := lookupField(object, name); !ok || item == nil {
+				*errs = append(*errs, ConfigError{Field: joinPath(path, name), Message: "is required"})
+			}
+		}
+
+		var names []string
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if property := lookupProperty(schema, name); property != nil {
+				if object[name] != nil {
+					validate(property, object[name], joinPath(path, name), errs)
+				}
+			} else if schema.AdditionalProperties != nil {
+				validate(schema.AdditionalProperties, object[name], joinPath(path, name), errs)
+			} else {
+				*errs = append(*errs, ConfigError{Field: joinPath(path, name), Message: "unknown field"})
+			}
+		}
+	}
+}
+
+// lookupField the names are matched case-insensitively as encoding/json does
+func lookupField(object map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := object[name]; ok {
+		return value, true
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func lookupProperty(schema *Schema, name string) *Schema {
+	if property, ok := schema.Properties[name]; ok {
+		return property
+	}
+	for key, property := range schema.Properties {
+		if strings.EqualFold(key, name) {
+			return property
+		}
+	}
+	return nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, item := range enum {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigChange the changed field of the config, Old or New is nil if the field is added or removed
+type ConfigChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+	// ReadOnly the field can't be changed while the task is running
+	ReadOnly bool `json:"readonly"`
+}
+
+// DiffConfig compares the configs field by field, the arrays are compared as a whole
+func DiffConfig(schema *Schema, oldJSON string, newJSON string) (error, []ConfigChange) {
+	var oldValue, newValue interface{}
+	if oldJSON != "" {
+		if err := json.Unmarshal([]byte(oldJSON), &oldValue); err != nil {
+			return ConfigErrors{{Message: err.Error()}}, nil
+		}
+	}
+	if newJSON != "" {
+		if err := json.Unmarshal([]byte(newJSON), &newValue); err != nil {
+			return ConfigErrors{{Message: err.Error()}}, nil
+		}
+	}
+
+	var changes []ConfigChange
+	diffConfig(schema, oldValue, newValue, "", false, &changes)
+	return nil, changes
+}
+
+func diffConfig(schema *Schema, oldValue interface{}, newValue interface{}, path string, readOnly bool, changes *[]ConfigChange) {
+	if schema != nil && schema.ReadOnly {
+		readOnly = true
+	}
+
+	oldObject, ok1 := oldValue.(map[string]interface{})
+	newObject, ok2 := newValue.(map[string]interface{})
+	if ok1 && ok2 {
+		oldObject = canonicalFields(schema, oldObject)
+		newObject = canonicalFields(schema, newObject)
+
+		var names []string
+		for name := range oldObject {
+			names = append(names, name)
+		}
+		for name := range newObject {
+			if _, ok := oldObject[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			var property *Schema
+			if schema != nil {
+				if property = schema.Properties[name]; property == nil {
+					property = schema.AdditionalProperties
+				}
+			}
+			diffConfig(property, oldObject[name], newObject[name], joinPath(path, name), readOnly, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*changes = append(*changes, ConfigChange{Field: path, Old: oldValue, New: newValue, ReadOnly: readOnly})
+	}
+}
+
+// canonicalFields renames the fields as the properties of the schema
+func canonicalFields(schema *Schema, object map[string]interface{}) map[string]interface{} {
+	if schema == nil || schema.Properties == nil {
+		return object
+	}
+
+	result := make(map[string]interface{})
+	for name, value := range object {
+		if _, ok := schema.Properties[name]; !ok {
+			for key := range schema.Properties {
+				if strings.EqualFold(key, name) {
+					name = key
+					break
+				}
+			}
+		}
+		result[name] = value
+	}
+	return result
+}
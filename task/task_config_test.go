@@ -0,0 +1,125 @@
+package task
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type testArea struct {
+	Open   float64 `json:"open" required:"true"`
+	Amount int     `json:"amount" min:"1" max:"100"`
+}
+
+type testConfig struct {
+	API    string               `title:"API Key"`
+	Secret string               `json:"secret" readonly:"true"`
+	Area   map[string]*testArea `json:"area" required:"true"`
+	Limit  float64              `json:"limit" title:"止损幅度" min:"0" max:"0.1" required:"true"`
+	Period string               `json:"period" enum:"1m|5m|1h"`
+	Coins  []string             `json:"coins"`
+	Auto   bool                 `json:"auto,omitempty"`
+	Hidden string               `json:"-"`
+	hidden string
+}
+
+func TestGenerateSchema(t *testing.T) {
+	schema := GenerateSchema(testConfig{Limit: 0.03, Period: "5m"})
+
+	if schema.Version != SchemaVersion || schema.Type != "object" || len(schema.Properties) != 7 {
+		t.Fatalf("Invalid schema:%v", schema)
+	}
+	if len(schema.Required) != 2 || schema.Required[0] != "area" || schema.Required[1] != "limit" {
+		t.Errorf("Invalid required:%v", schema.Required)
+	}
+
+	limit := schema.Properties["limit"]
+	if limit.Type != "number" || limit.Title != "止损幅度" || *limit.Minimum != 0 || *limit.Maximum != 0.1 || limit.Default != 0.03 {
+		t.Errorf("Invalid property:%v", limit)
+	}
+
+	area := schema.Properties["area"].AdditionalProperties
+	if area == nil || area.Properties["amount"].Type != "integer" || len(area.Required) != 1 {
+		t.Errorf("Invalid map:%v", schema.Properties["area"])
+	}
+
+	if schema.Properties["API"].Type != "string" || schema.Properties["coins"].Items.Type != "string" ||
+		len(schema.Properties["period"].Enum) != 3 || schema.Properties["auto"].Type != "boolean" || !schema.Properties["secret"].ReadOnly {
+		t.Errorf("Invalid properties:%v", schema.Properties)
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("Fail to marshal:%v", err)
+	}
+
+	if GenerateSchema(nil) != nil {
+		t.Errorf("The schema should be nil")
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	var config testConfig
+	if err := ParseConfig(`{"api":"key","area":{"btc":{"open":1.6,"amount":10}},"limit":0.05,"period":"1h","coins":["btc"]}`, &config); err != nil {
+		t.Fatalf("Fail to parse:%v", err)
+	}
+	if config.API != "key" || config.Area["btc"].Amount != 10 || config.Limit != 0.05 {
+		t.Errorf("Invalid config:%v", config)
+	}
+
+	err := ParseConfig(`{"area":{"btc":{"amount":1.5},"ltc":{"open":"3"}},"limit":0.5,"period":"2m","coin":["btc"],"auto":1}`, &testConfig{})
+	errs, ok := err.(ConfigErrors)
+	if !ok {
+		t.Fatalf("Invalid error:%v", err)
+	}
+
+	expect := map[string]bool{
+		"area.btc.open":   true,
+		"area.btc.amount": true,
+		"area.ltc.open":   true,
+		"limit":           true,
+		"period":          true,
+		"coin":            true,
+		"auto":            true,
+	}
+	if len(errs) != len(expect) {
+		t.Errorf("Invalid errors:%v", errs)
+	}
+	for _, item := range errs {
+		if !expect[item.Field] {
+			t.Errorf("Unexpected error:%v", item)
+		}
+	}
+
+	if err := ParseConfig(`{"limit":0.05`, &testConfig{}); err == nil {
+		t.Errorf("The invalid json should fail")
+	}
+	if err := ParseConfig(`{"area":{}}`, &testConfig{}); err == nil || err.(ConfigErrors)[0].Field != "limit" {
+		t.Errorf("The required field should fail:%v", err)
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	schema := GenerateSchema(testConfig{})
+
+	err, changes := DiffConfig(schema,
+		`{"API":"key","secret":"a","area":{"btc":{"open":1.6,"amount":10},"ltc":{"open":3}},"limit":0.05,"coins":["btc"]}`,
+		`{"api":"key","secret":"b","area":{"btc":{"open":1.8,"amount":10},"eth":{"open":2}},"limit":0.05,"coins":["btc","eth"]}`)
+	if err != nil {
+		t.Fatalf("Fail to diff:%v", err)
+	}
+
+	expect := []ConfigChange{
+		{Field: "area.btc.open", Old: 1.6, New: 1.8},
+		{Field: "area.eth", New: map[string]interface{}{"open": 2.0}},
+		{Field: "area.ltc", Old: map[string]interface{}{"open": 3.0}},
+		{Field: "coins", Old: []interface{}{"btc"}, New: []interface{}{"btc", "eth"}},
+		{Field: "secret", Old: "a", New: "b", ReadOnly: true},
+	}
+	if !reflect.DeepEqual(changes, expect) {
+		t.Errorf("Invalid changes:%v", changes)
+	}
+
+	if err, changes := DiffConfig(nil, `{"coin":"btc"}`, `{"coin":"btc"}`); err != nil || len(changes) != 0 {
+		t.Errorf("Invalid changes:%v %v", err, changes)
+	}
+}
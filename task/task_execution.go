@@ -0,0 +1,202 @@
+package task
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+/*
+	执行质量：记录每笔订单的决策价、下单时盘口中间价、成交价和延迟，
+	按任务、交易所和交易对统计执行差额(implementation shortfall)，用于调整LimitOpen、LimitClose
+*/
+
+// ExecutionRecorder saves the executions of the tasks in the process
+type ExecutionRecorder struct {
+	lock sync.Mutex
+	db   *Mongo.Executions
+}
+
+// GlobalExecutions the executions are only logged until the DB is assigned
+var GlobalExecutions = new(ExecutionRecorder)
+
+func (r *ExecutionRecorder) SetDB(db *Mongo.Executions) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.db = db
+}
+
+func (r *ExecutionRecorder) getDB() *Mongo.Executions {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.db
+}
+
+func (r *ExecutionRecorder) Record(record *Mongo.ExecutionRecord) {
+	Logger.Debugf("Execution:%v", *record)
+	if db := r.getDB(); db != nil {
+		if err := db.Insert(record); err != nil {
+			Logger.Errorf("Fail to save the execution:%v", err)
+		}
+	}
+}
+
+// Report the statistics of the executions since the time, the empty task matches all the tasks
+func (r *ExecutionRecorder) Report(task string, since time.Time) (error, []ExecutionStats) {
+	db := r.getDB()
+	if db == nil {
+		return errors.New(Mongo.ErrorNotConnected), nil
+	}
+	err, records := db.Find(task, since)
+	if err != nil {
+		return err, nil
+	}
+	return nil, Summarize(records)
+}
+
+// Execution measures the order from the placing to the fill
+type Execution struct {
+	Record Mongo.ExecutionRecord
+	placed time.Time
+}
+
+// ArrivalMid the mid of the best prices, 0 if the depth isn't available
+func ArrivalMid(exchange Exchange.IExchange, pair string) float64 {
+	depth := exchange.GetDepthValue(pair)
+	if len(depth) < 2 || len(depth[Exchange.DepthTypeBids]) == 0 || len(depth[Exchange.DepthTypeAsks]) == 0 {
+		return 0
+	}
+	return (depth[Exchange.DepthTypeBids][0].Price + depth[Exchange.DepthTypeAsks][0].Price) / 2
+}
+
+// executionTask the task of the gateway, or the name of the exchange
+func executionTask(exchange Exchange.IExchange) string {
+	if gateway, ok := exchange.(*RiskGateway); ok && gateway.Task != "" {
+		return gateway.Task
+	}
+	return exchange.GetExchangeName()
+}
+
+// StartExecution is called before the order is placed, the price of the config is the decision price
+func StartExecution(exchange Exchange.IExchange, config Exchange.TradeConfig) *Execution {
+	return &Execution{
+		Record: Mongo.ExecutionRecord{
+			Task:     executionTask(exchange),
+			Batch:    config.Batch,
+			Exchange: exchange.GetExchangeName(),
+			Pair:     config.Pair,
+			Oper:     Exchange.TradeTypeString[config.Type],
+			Time:     time.Now(),
+			Decision: config.Price,
+			Arrival:  ArrivalMid(exchange, config.Pair),
+			Placed:   GetPlacedPrice(config.Type, config.Price, config.Limit),
+			Amount:   config.Amount,
+		},
+		placed: time.Now(),
+	}
+}
+
+// Filled records the execution, the orders which aren't filled are recorded for the fill ratio
+func (e *Execution) Filled(dealAmount float64, avgPrice float64) {
+	e.Record.DealAmount = dealAmount
+	e.Record.AvgPrice = avgPrice
+	e.Record.Latency = e.Latency()
+	GlobalExecutions.Record(&e.Record)
+}
+
+// Latency the milliseconds since the order is placed
+func (e *Execution) Latency() int64 {
+	return int64(time.Since(e.placed) / time.Millisecond)
+}
+
+// Shortfall the cost in bps of the price from the benchmark, it's negative if the price is better
+func Shortfall(tradeType Exchange.TradeType, benchmark float64, price float64) float64 {
+	if benchmark <= 0 || price <= 0 {
+		return 0
+	}
+	if buyType(tradeType) {
+		return (price - benchmark) / benchmark * 10000
+	}
+	return (benchmark - price) / benchmark * 10000
+}
+
+// ExecutionStats the slippages are in bps weighted by the deal amounts, the positive ones are the costs
+type ExecutionStats struct {
+	Task       string  `json:"task"`
+	Exchange   string  `json:"exchange"`
+	Pair       string  `json:"pair"`
+	Orders     int     `json:"orders"`
+	Amount     float64 `json:"amount"`
+	DealAmount float64 `json:"dealamount"`
+	FillRatio  float64 `json:"fillratio"`
+	// Shortfall the fill price from the decision price, which is the sum of the delay and the slippage
+	Shortfall float64 `json:"shortfall"`
+	// Delay the arrival mid from the decision price
+	Delay float64 `json:"delay"`
+	// Slippage the fill price from the arrival mid
+	Slippage float64 `json:"slippage"`
+	// Latency the average milliseconds to the fill
+	Latency float64 `json:"latency"`
+
+	// the weights of the slippages, the records without the arrival mid aren't counted in the delay and the slippage
+	decisionWeight float64
+	arrivalWeight  float64
+}
+
+// Summarize groups the executions by the task, the exchange and the pair
+func Summarize(records []Mongo.ExecutionRecord) []ExecutionStats {
+	groups := make(map[string]*ExecutionStats)
+	var keys []string
+
+	for _, record := range records {
+		key := record.Task + "|" + record.Exchange + "|" + record.Pair
+		stats := groups[key]
+		if stats == nil {
+			stats = &ExecutionStats{Task: record.Task, Exchange: record.Exchange, Pair: record.Pair}
+			groups[key] = stats
+			keys = append(keys, key)
+		}
+
+		stats.Orders++
+		stats.Amount += record.Amount
+		stats.DealAmount += record.DealAmount
+		stats.Latency += float64(record.Latency)
+		if record.DealAmount <= 0 {
+			continue
+		}
+
+		tradeType := Exchange.TradeTypeInt(record.Oper)
+		if record.Decision > 0 {
+			stats.Shortfall += Shortfall(tradeType, record.Decision, record.AvgPrice) * record.DealAmount
+			stats.decisionWeight += record.DealAmount
+		}
+		if record.Arrival > 0 {
+			stats.Slippage += Shortfall(tradeType, record.Arrival, record.AvgPrice) * record.DealAmount
+			stats.Delay += Shortfall(tradeType, record.Decision, record.Arrival) * record.DealAmount
+			stats.arrivalWeight += record.DealAmount
+		}
+	}
+
+	sort.Strings(keys)
+	var result []ExecutionStats
+	for _, key := range keys {
+		stats := groups[key]
+		stats.Latency /= float64(stats.Orders)
+		if stats.Amount > 0 {
+			stats.FillRatio = stats.DealAmount / stats.Amount
+		}
+		if stats.decisionWeight > 0 {
+			stats.Shortfall /= stats.decisionWeight
+		}
+		if stats.arrivalWeight > 0 {
+			stats.Slippage /= stats.arrivalWeight
+			stats.Delay /= stats.arrivalWeight
+		}
+		result = append(result, *stats)
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package task
+
+import (
+	"math"
+	"testing"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+func TestShortfall(t *testing.T) {
+	if s := Shortfall(Exchange.TradeTypeBuy, 100, 100.5); math.Abs(s-50) > 1e-9 {
+		t.Errorf("Invalid shortfall of the buy:%v", s)
+	}
+	if s := Shortfall(Exchange.TradeTypeCloseLong, 100, 100.5); math.Abs(s+50) > 1e-9 {
+		t.Errorf("Invalid shortfall of the sell:%v", s)
+	}
+	if s := Shortfall(Exchange.TradeTypeBuy, 0, 100); s != 0 {
+		t.Errorf("The shortfall without the benchmark should be 0:%v", s)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	records := []Mongo.ExecutionRecord{
+		{Task: "trend", Exchange: "okex", Pair: "eth/usdt", Oper: Exchange.TradeTypeString[Exchange.TradeTypeBuy],
+			Decision: 100, Arrival: 100.1, AvgPrice: 100.2, Amount: 1, DealAmount: 1, Latency: 100},
+		{Task: "trend", Exchange: "okex", Pair: "eth/usdt", Oper: Exchange.TradeTypeString[Exchange.TradeTypeSell],
+			Decision: 100, AvgPrice: 99.7, Amount: 3, DealAmount: 1, Latency: 300},
+		{Task: "trend", Exchange: "okex", Pair: "eth/usdt", Oper: Exchange.TradeTypeString[Exchange.TradeTypeSell],
+			Decision: 100, Arrival: 100, Amount: 1, Latency: 200},
+		{Task: "arbitrage", Exchange: "binance", Pair: "btc/usdt", Oper: Exchange.TradeTypeString[Exchange.TradeTypeSell],
+			Decision: 10000, Arrival: 10000, AvgPrice: 10010, Amount: 0.5, DealAmount: 0.5},
+	}
+
+	stats := Summarize(records)
+	if len(stats) != 2 || stats[0].Task != "arbitrage" || stats[1].Task != "trend" {
+		t.Fatalf("Invalid groups:%v", stats)
+	}
+
+	trend := stats[1]
+	if trend.Orders != 3 || trend.Amount != 5 || trend.DealAmount != 2 || math.Abs(trend.FillRatio-0.4) > 1e-9 || trend.Latency != 200 {
+		t.Errorf("Invalid statistics:%v", trend)
+	}
+	// the shortfall of the buy is 20bps, the sell is 30bps
+	if math.Abs(trend.Shortfall-25) > 1e-6 || math.Abs(trend.Delay-10) > 1e-6 || math.Abs(trend.Slippage-(0.1/100.1*10000)) > 1e-6 {
+		t.Errorf("Invalid slippages:%v", trend)
+	}
+	if math.Abs(stats[0].Shortfall+10) > 1e-6 {
+		t.Errorf("The better price should be negative:%v", stats[0])
+	}
+}
+
+type depthExchange struct {
+	Exchange.IExchange
+	depth [][]Exchange.DepthPrice
+}
+
+func (d *depthExchange) GetExchangeName() string {
+	return "depth"
+}
+
+func (d *depthExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return d.depth
+}
+
+func TestStartExecution(t *testing.T) {
+	exchange := &depthExchange{depth: [][]Exchange.DepthPrice{{{Price: 99}}, {{Price: 101}}}}
+	gateway := &RiskGateway{IExchange: exchange, Task: "trend"}
+
+	execution := StartExecution(gateway, Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Limit: 0.01, Amount: 2})
+	if execution.Record.Task != "trend" || execution.Record.Exchange != "depth" || execution.Record.Arrival != 100 ||
+		math.Abs(execution.Record.Placed-101) > 1e-9 || execution.Record.Decision != 100 {
+		t.Errorf("Invalid execution:%v", execution.Record)
+	}
+
+	exchange.depth = nil
+	if execution = StartExecution(exchange, Exchange.TradeConfig{Pair: "eth/usdt"}); execution.Record.Arrival != 0 || execution.Record.Task != "depth" {
+		t.Errorf("The arrival should be 0 without the depth:%v", execution.Record)
+	}
+}
@@ -8,14 +8,21 @@ import (
 )
 
 /*
-	实时加载的任务；目前暂不考虑支持
+	实时加载的任务；使用SDK的策略进程会注册到Registry，退出时通过gRPC通知进程
 */
 
+// exitTimeout the process is killed if it doesn't exit in time
+const exitTimeout = 10 * time.Second
+
 type TaskHotLoad struct {
 	// GetTaskExplanation() *TaskExplanation
+	// Name the name which the strategy registers with
 	Name  string
 	Paras string
 	cmd   *exec.Cmd
+
+	// Registry the registry which the process registers to
+	Registry *Registry
 }
 
 func (t *TaskHotLoad) InstallTaskAndRun(name string, paramters string) error {
@@ -28,7 +35,15 @@ func (t *TaskHotLoad) InstallTaskAndRun(name string, paramters string) error {
 		return errors.New(string(out))
 	}
 
-	cmd = exec.Command(name, "-config="+paramters)
+	args := []string{"-config=" + paramters}
+	if t.Registry != nil {
+		args = append(args, "-registry="+t.Registry.Addr())
+	}
+	if t.Name == "" {
+		t.Name = name
+	}
+
+	cmd = exec.Command(name, args...)
 	if cmd == nil {
 		return errors.New("Fail to run task")
 	}
@@ -53,8 +68,18 @@ func (t *TaskHotLoad) ExitTask() {
 		done <- t.cmd.Wait()
 	}()
 	Logger.Infof("Exiting task:%v", t.cmd.Process.Pid)
+
+	timeout := 1 * time.Second
+	if t.Registry != nil {
+		if err := t.Registry.Shutdown(t.Name); err != nil {
+			Logger.Errorf("Fail to shutdown task:%v", err)
+		} else {
+			timeout = exitTimeout
+		}
+	}
+
 	select {
-	case <-time.After(1 * time.Second):
+	case <-time.After(timeout):
 		/*
 			We would like to kill the process by signal, but there maybe some problem in windows; So we will use websocket to send the signal
 		*/
@@ -0,0 +1,217 @@
+package task
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	Mongo "madaoQT/mongo"
+)
+
+/*
+	全局熔断：触发后所有经过RiskGateway的订单只允许减仓，撤销所有挂单，可选平掉所有任务的仓位。
+	状态保存在Mongo中，重启后恢复；策略进程定时读取状态，与主进程保持一致
+*/
+
+const (
+	KillSourceAPI     = "api"
+	KillSourceConsole = "console"
+	KillSourceBreaker = "breaker"
+)
+
+const killSwitchFollowInterval = 5 * time.Second
+
+// killSwitchStore the state saved in Mongo, see Mongo.KillSwitches
+type killSwitchStore interface {
+	Save(state *Mongo.KillSwitchState) error
+	Load() (error, *Mongo.KillSwitchState)
+}
+
+// KillSwitch freezes the new orders of all the gateways in the process
+type KillSwitch struct {
+	// Flatten closes the positions of all the tasks in the process
+	Flatten func()
+
+	lock sync.Mutex
+	// db saves the state if assigned
+	db        killSwitchStore
+	state     Mongo.KillSwitchState
+	gateways  map[*RiskGateway]bool
+	following bool
+}
+
+// GlobalKillSwitch the gateways are registered to it when they're created
+var GlobalKillSwitch = new(KillSwitch)
+
+func (k *KillSwitch) register(gateway *RiskGateway) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if k.gateways == nil {
+		k.gateways = make(map[*RiskGateway]bool)
+	}
+	k.gateways[gateway] = true
+}
+
+func (k *KillSwitch) unregister(gateway *RiskGateway) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	delete(k.gateways, gateway)
+}
+
+// SetDB the state is saved after the DB is assigned
+func (k *KillSwitch) SetDB(db *Mongo.KillSwitches) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if db == nil {
+		k.db = nil
+		return
+	}
+	k.db = db
+}
+
+func (k *KillSwitch) getDB() killSwitchStore {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	return k.db
+}
+
+// Engaged whether the new orders are frozen
+func (k *KillSwitch) Engaged() bool {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	return k.state.Engaged
+}
+
+// State returns the current state
+func (k *KillSwitch) State() Mongo.KillSwitchState {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	return k.state
+}
+
+// killSwitchTime Mongo keeps the milliseconds of the time, the saved state is compared with the one in memory
+func killSwitchTime() time.Time {
+	return time.Now().Truncate(time.Millisecond)
+}
+
+// Engage freezes the orders and cancels the resting orders, the positions are closed if flatten is set. The state is
+// saved before the orders are canceled, and the switch is engaged even if the state fails to be saved
+func (k *KillSwitch) Engage(source string, reason string, flatten bool) error {
+	state := Mongo.KillSwitchState{
+		Engaged: true,
+		Flatten: flatten,
+		Source:  source,
+		Reason:  reason,
+		Updated: killSwitchTime(),
+	}
+
+	k.lock.Lock()
+	k.state = state
+	k.lock.Unlock()
+
+	Logger.Errorf("全局熔断已触发 来源:%s 原因:%s 平仓:%v", source, reason, flatten)
+	err := k.save(&state)
+	k.apply(flatten)
+	return err
+}
+
+// Release allows the new orders again, the positions closed are not reopened
+func (k *KillSwitch) Release(source string) error {
+	state := Mongo.KillSwitchState{
+		Source:  source,
+		Updated: killSwitchTime(),
+	}
+
+	k.lock.Lock()
+	k.state = state
+	k.lock.Unlock()
+
+	Logger.Infof("全局熔断已解除 来源:%s", source)
+	return k.save(&state)
+}
+
+func (k *KillSwitch) save(state *Mongo.KillSwitchState) error {
+	db := k.getDB()
+	if db == nil {
+		return nil
+	}
+	if err := db.Save(state); err != nil {
+		Logger.Errorf("Fail to save the kill switch:%v", err)
+		return err
+	}
+	return nil
+}
+
+// apply cancels the resting orders of all the gateways, and closes the positions in the background
+func (k *KillSwitch) apply(flatten bool) {
+	k.lock.Lock()
+	var gateways []*RiskGateway
+	for gateway := range k.gateways {
+		gateways = append(gateways, gateway)
+	}
+	k.lock.Unlock()
+
+	for _, gateway := range gateways {
+		gateway.CancelAll()
+	}
+
+	if flatten && k.Flatten != nil {
+		go k.Flatten()
+	}
+}
+
+// follow adopts the state saved by the other processes, the orders are canceled again when it's engaged. The saved
+// state older than the one in memory is ignored, and the state in memory is saved again
+func (k *KillSwitch) follow() error {
+	err, state := k.getDB().Load()
+	if err != nil {
+		return err
+	}
+
+	k.lock.Lock()
+	if state.Updated.Before(k.state.Updated) {
+		current := k.state
+		k.lock.Unlock()
+		return k.save(&current)
+	}
+	changed := state.Engaged != k.state.Engaged
+	k.state = *state
+	k.lock.Unlock()
+
+	if changed {
+		if state.Engaged {
+			Logger.Errorf("全局熔断已触发 来源:%s 原因:%s 平仓:%v", state.Source, state.Reason, state.Flatten)
+			k.apply(state.Flatten)
+		} else {
+			Logger.Infof("全局熔断已解除 来源:%s", state.Source)
+		}
+	}
+	return nil
+}
+
+// Restore loads the saved state, and follows the state changed by the other processes in the background
+func (k *KillSwitch) Restore() error {
+	if k.getDB() == nil {
+		return errors.New(Mongo.ErrorNotConnected)
+	}
+
+	if err := k.follow(); err != nil {
+		return err
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if !k.following {
+		k.following = true
+		go func() {
+			for {
+				time.Sleep(killSwitchFollowInterval)
+				if err := k.follow(); err != nil {
+					Logger.Errorf("Fail to load the kill switch:%v", err)
+				}
+			}
+		}()
+	}
+
+	return nil
+}
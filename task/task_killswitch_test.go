@@ -0,0 +1,174 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+type killSwitchExchange struct {
+	*riskExchange
+	canceled []string
+	fail     bool
+}
+
+func (p *killSwitchExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	if p.fail {
+		return &Exchange.TradeResult{Error: errors.New("failed")}
+	}
+	return p.riskExchange.Trade(configs)
+}
+
+func (p *killSwitchExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	p.canceled = append(p.canceled, order.OrderID)
+	if info := p.orders[order.OrderID]; info != nil {
+		info.Status = Exchange.OrderStatusCanceled
+	}
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+func (p *killSwitchExchange) Close() {
+}
+
+func newKillSwitchGateway(t *testing.T, config RiskConfig) (*killSwitchExchange, *RiskGateway) {
+	exchange := &killSwitchExchange{riskExchange: newRiskExchange()}
+	gateway := NewRiskGateway(exchange, "test", config, nil)
+	t.Cleanup(func() {
+		gateway.Close()
+		GlobalKillSwitch.Release(KillSourceAPI)
+	})
+	return exchange, gateway
+}
+
+func TestKillSwitch(t *testing.T) {
+	exchange, gateway := newKillSwitchGateway(t, RiskConfig{})
+
+	flattened := make(chan bool, 1)
+	GlobalKillSwitch.Flatten = func() { flattened <- true }
+	defer func() { GlobalKillSwitch.Flatten = nil }()
+
+	buy := Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 2}
+	expectRule(t, gateway.Trade(buy), "")
+	exchange.done(gateway, "1")
+	expectRule(t, gateway.Trade(buy), "")
+
+	GlobalKillSwitch.Engage(KillSourceAPI, "test", true)
+	if len(exchange.canceled) != 1 || exchange.canceled[0] != "2" {
+		t.Fatalf("The resting orders should be canceled:%v", exchange.canceled)
+	}
+	select {
+	case <-flattened:
+	case <-time.After(time.Second):
+		t.Fatalf("The positions should be closed")
+	}
+
+	// only the reducing orders are accepted
+	expectRule(t, gateway.Trade(buy), RiskKillSwitch)
+	sell := buy
+	sell.Type = Exchange.TradeTypeSell
+	sell.Amount = 3
+	expectRule(t, gateway.Trade(sell), RiskKillSwitch)
+	sell.Amount = 2
+	expectRule(t, gateway.Trade(sell), "")
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usd", Type: Exchange.TradeTypeCloseLong, Price: 100, Amount: 1}), "")
+
+	GlobalKillSwitch.Release(KillSourceAPI)
+	expectRule(t, gateway.Trade(buy), "")
+
+	// the closed gateways aren't canceled any more
+	gateway.Close()
+	GlobalKillSwitch.Engage(KillSourceAPI, "test", false)
+	if len(exchange.canceled) != 1 {
+		t.Fatalf("The closed gateway shouldn't be canceled:%v", exchange.canceled)
+	}
+}
+
+func TestBreakers(t *testing.T) {
+	exchange, gateway := newKillSwitchGateway(t, RiskConfig{BreakerErrors: 3})
+
+	buy := Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 1}
+	exchange.fail = true
+	gateway.Trade(buy)
+	gateway.CountError()
+	if GlobalKillSwitch.Engaged() {
+		t.Fatalf("The switch shouldn't be engaged below the threshold")
+	}
+	gateway.Trade(buy)
+	if state := GlobalKillSwitch.State(); !state.Engaged || state.Source != KillSourceBreaker {
+		t.Fatalf("The switch should be engaged by the errors:%v", state)
+	}
+
+	GlobalKillSwitch.Release(KillSourceAPI)
+	exchange.fail = false
+	gateway.SetConfig(RiskConfig{BreakerLoss: 10})
+	expectRule(t, gateway.Trade(buy), "")
+	exchange.done(gateway, "1")
+	sell := buy
+	sell.Type = Exchange.TradeTypeSell
+	sell.Price = 89
+	expectRule(t, gateway.Trade(sell), "")
+	exchange.done(gateway, "2")
+	if !GlobalKillSwitch.Engaged() {
+		t.Fatalf("The switch should be engaged by the loss")
+	}
+
+	// the loss breaker is tripped once a day
+	GlobalKillSwitch.Release(KillSourceAPI)
+	expectRule(t, gateway.Trade(buy), "")
+	exchange.done(gateway, "3")
+	if GlobalKillSwitch.Engaged() {
+		t.Fatalf("The switch shouldn't be engaged again after it's released")
+	}
+}
+
+type killSwitchStoreFake struct {
+	state Mongo.KillSwitchState
+	fail  bool
+	saved int
+}
+
+func (s *killSwitchStoreFake) Save(state *Mongo.KillSwitchState) error {
+	if s.fail {
+		return errors.New("failed")
+	}
+	s.saved++
+	s.state = *state
+	return nil
+}
+
+func (s *killSwitchStoreFake) Load() (error, *Mongo.KillSwitchState) {
+	state := s.state
+	return nil, &state
+}
+
+func TestKillSwitchFollow(t *testing.T) {
+	// the released state is saved before the switch is engaged, and the state fails to be saved
+	store := &killSwitchStoreFake{state: Mongo.KillSwitchState{Updated: killSwitchTime().Add(-time.Minute)}, fail: true}
+	killSwitch := &KillSwitch{db: store}
+	if err := killSwitch.Engage(KillSourceAPI, "test", false); err == nil {
+		t.Fatalf("The error of saving should be returned")
+	}
+
+	store.fail = false
+	if err := killSwitch.follow(); err != nil {
+		t.Fatalf("Fail to follow:%v", err)
+	}
+	if !killSwitch.Engaged() {
+		t.Fatalf("The stale state shouldn't release the switch")
+	}
+	if store.saved != 1 || !store.state.Engaged {
+		t.Errorf("The state in memory should be saved again:%v", store.state)
+	}
+
+	// the state released by the other process
+	store.state = Mongo.KillSwitchState{Source: KillSourceConsole, Updated: killSwitchTime().Add(time.Second)}
+	if err := killSwitch.follow(); err != nil {
+		t.Fatalf("Fail to follow:%v", err)
+	}
+	if killSwitch.Engaged() || killSwitch.State().Source != KillSourceConsole {
+		t.Errorf("The newer state should be followed:%v", killSwitch.State())
+	}
+}
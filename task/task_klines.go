@@ -0,0 +1,84 @@
+package task
+
+import (
+	"strconv"
+	"sync"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+/*
+	K线存储：任务读取K线时先保存交易所的最新K线，再从数据库读取，和/charts使用同一份数据；
+	数据库不可用时直接使用交易所的K线
+*/
+
+// KlineStore the klines read by the tasks are saved into the kline collection and read back from it
+type KlineStore struct {
+	lock sync.Mutex
+	db   *Mongo.Klines
+	// saved the open time of the last kline saved by exchange:pair:period, the klines before it aren't saved again
+	saved map[string]float64
+}
+
+// GlobalKlines the klines are read from the exchanges directly until the DB is assigned
+var GlobalKlines = new(KlineStore)
+
+func (k *KlineStore) SetDB(db *Mongo.Klines) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.db = db
+	k.saved = make(map[string]float64)
+}
+
+func (k *KlineStore) getDB() *Mongo.Klines {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	return k.db
+}
+
+// GetKline the latest limit klines of the pair, the klines of the exchange are returned if the DB fails
+func (k *KlineStore) GetKline(exchange Exchange.IExchange, pair string, period int, limit int) []Exchange.KlineValue {
+	klines := exchange.GetKline(pair, period, limit)
+	db := k.getDB()
+	if db == nil || len(klines) == 0 {
+		return klines
+	}
+
+	name := exchange.GetExchangeName()
+	key := name + ":" + pair + ":" + strconv.Itoa(period)
+
+	k.lock.Lock()
+	last, ok := k.saved[key]
+	k.lock.Unlock()
+
+	// the last saved kline is saved again as it may not be closed then
+	fresh := klines
+	if ok {
+		fresh = nil
+		for _, kline := range klines {
+			if kline.OpenTime >= last {
+				fresh = append(fresh, kline)
+			}
+		}
+	}
+	if err := Exchange.SaveKlines(db, name, pair, period, fresh); err != nil {
+		Logger.Errorf("Fail to save klines:%v", err)
+		return klines
+	}
+
+	k.lock.Lock()
+	k.saved[key] = klines[len(klines)-1].OpenTime
+	k.lock.Unlock()
+
+	err, stored := Exchange.LoadKlines(db, name, pair, period, limit)
+	if err != nil {
+		Logger.Errorf("Fail to load klines:%v", err)
+		return klines
+	}
+	if len(stored) < len(klines) {
+		return klines
+	}
+
+	return stored
+}
@@ -0,0 +1,67 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+type klineExchange struct {
+	Exchange.IExchange
+	klines []Exchange.KlineValue
+}
+
+func (k *klineExchange) GetExchangeName() string {
+	return "kline-test"
+}
+
+func (k *klineExchange) GetKline(pair string, period int, limit int) []Exchange.KlineValue {
+	if len(k.klines) > limit {
+		return k.klines[len(k.klines)-limit:]
+	}
+	return k.klines
+}
+
+func testKlines(start time.Time, count int) []Exchange.KlineValue {
+	var klines []Exchange.KlineValue
+	for i := 0; i < count; i++ {
+		open := start.Add(time.Duration(i) * 5 * time.Minute)
+		klines = append(klines, Exchange.KlineValue{OpenTime: float64(open.Unix()), Open: float64(i), Close: float64(i), Volumn: 1})
+	}
+	return klines
+}
+
+func TestKlineStoreWithoutDB(t *testing.T) {
+	exchange := &klineExchange{klines: testKlines(time.Unix(1500000000, 0), 3)}
+	store := new(KlineStore)
+	if klines := store.GetKline(exchange, "eth/usdt", Exchange.KlinePeriod5Min, 2); len(klines) != 2 || klines[1].Close != 2 {
+		t.Errorf("Invalid klines:%v", klines)
+	}
+}
+
+func TestKlineStore(t *testing.T) {
+	db := new(Mongo.Klines)
+	if err := db.Connect(); err != nil {
+		t.Skipf("Mongo isn't available:%v", err)
+	}
+	defer db.Close()
+
+	store := new(KlineStore)
+	store.SetDB(db)
+
+	start := time.Now().Truncate(time.Hour).Add(-24 * time.Hour)
+	exchange := &klineExchange{klines: testKlines(start, 10)}
+	if klines := store.GetKline(exchange, "eth/usdt", Exchange.KlinePeriod5Min, 10); len(klines) != 10 {
+		t.Errorf("Invalid klines:%v", klines)
+	}
+
+	// only the last klines are returned by the exchange, the earlier ones are read from the DB
+	exchange.klines = testKlines(start, 12)
+	exchange.klines[9].Close = 100
+	klines := store.GetKline(&klineExchange{klines: exchange.klines[8:]}, "eth/usdt", Exchange.KlinePeriod5Min, 12)
+	if len(klines) != 12 || klines[0].OpenTime != float64(start.Unix()) || klines[9].Close != 100 || klines[11].Close != 11 {
+		t.Errorf("Invalid klines:%v", klines)
+	}
+}
@@ -0,0 +1,807 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	Mongo "madaoQT/mongo"
+	Utils "madaoQT/utils"
+)
+
+/*
+	任务管理：同一策略可以按不同配置运行多个实例，每个实例有独立的ID和Mongo集合命名空间
+	任务定义保存在Mongo中，重启后自动恢复需要运行的任务，异常退出的任务按退避时间重启
+*/
+
+// TaskFactory creates a new instance of the strategy
+type TaskFactory func() ITask
+
+// INamespace the task which supports the isolated collection namespace should implement this interface
+type INamespace interface {
+	SetNamespace(namespace string)
+}
+
+// ISchema the task whose config schema isn't generated from the default config should implement this interface
+type ISchema interface {
+	GetConfigSchema() *Schema
+}
+
+// CollectionName returns the collection name in the namespace of the instance
+func CollectionName(namespace string, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "_" + name
+}
+
+const (
+	ReasonAPI     = "api"
+	ReasonRestore = "restore"
+	ReasonCrash   = "crash"
+	ReasonRemove  = "remove"
+)
+
+const restartBackoffMin = 5 * time.Second
+const restartBackoffMax = 5 * time.Minute
+
+// restartStable the backoff is reset after the task runs for the duration
+const restartStable = 10 * time.Minute
+
+const superviseInterval = 5 * time.Second
+
+var errStartSkipped = errors.New("The task isn't desired to run")
+var errStartStopped = errors.New("The task is stopped while it's being started")
+
+// InstanceInfo the information of the task instance
+type InstanceInfo struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace"`
+	Config    string      `json:"config"`
+	Status    StatusType  `json:"status"`
+	Desired   string      `json:"desired"`
+	Created   time.Time   `json:"created"`
+	Started   time.Time   `json:"started"`
+	Stopped   time.Time   `json:"stopped"`
+	Error     string      `json:"error"`
+	Desc      Description `json:"desc"`
+
+	StartReason string `json:"startreason"`
+	StopReason  string `json:"stopreason"`
+	Restarts    int    `json:"restarts"`
+}
+
+type instance struct {
+	info InstanceInfo
+	task ITask
+
+	// running whether the task is started by the manager and not stopped
+	running   bool
+	failures  int
+	nextRetry time.Time
+	// starting the task is being started, stops counts the stops to find the one while starting
+	starting bool
+	stops    int
+	// version counts the records copied under the lock, the records are written by the version and not after the
+	// instance is removed. written and removed are guarded by the save lock
+	version int
+	written int
+	removed bool
+}
+
+// taskRecord the definition copied under the lock, which is written after the lock is released
+type taskRecord struct {
+	item       *instance
+	version    int
+	remove     bool
+	definition Mongo.TaskDefinition
+}
+
+type TaskManager struct {
+	// DB saves the task definitions if assigned
+	DB *Mongo.TaskDefinitions
+	// Scheduler is passed to the tasks which register their own jobs
+	Scheduler *Scheduler
+
+	lock sync.RWMutex
+	// saveLock orders the writes of the records, which are done without the lock
+	saveLock   sync.Mutex
+	factories  map[string]TaskFactory
+	strategies []string
+	instances  map[string]*instance
+	supervised bool
+	// deferred the restored definitions whose strategies are not registered yet, such as the strategy processes
+	deferred map[string][]Mongo.TaskDefinition
+}
+
+func (m *TaskManager) init() {
+	if m.factories == nil {
+		m.factories = make(map[string]TaskFactory)
+		m.instances = make(map[string]*instance)
+	}
+}
+
+// record copies the definition of the instance, the lock is held
+func (m *TaskManager) record(item *instance) *taskRecord {
+	item.version++
+	return &taskRecord{
+		item:    item,
+		version: item.version,
+		definition: Mongo.TaskDefinition{
+			ID:              item.info.ID,
+			Name:            item.info.Name,
+			Config:          item.info.Config,
+			Desired:         item.info.Desired,
+			LastStart:       item.info.Started,
+			LastStartReason: item.info.StartReason,
+			LastStop:        item.info.Stopped,
+			LastStopReason:  item.info.StopReason,
+			Restarts:        item.info.Restarts,
+		},
+	}
+}
+
+// save writes the record without the lock, the records older than the written one are skipped
+func (m *TaskManager) save(record *taskRecord) {
+	if m.DB == nil {
+		return
+	}
+
+	m.saveLock.Lock()
+	defer m.saveLock.Unlock()
+	item := record.item
+	if item.removed || record.version <= item.written {
+		return
+	}
+	item.written = record.version
+
+	if record.remove {
+		item.removed = true
+		if err := m.DB.Remove(record.definition.ID); err != nil {
+			Logger.Errorf("Fail to remove task %s:%v", record.definition.ID, err)
+		}
+		return
+	}
+
+	if err := m.DB.Save(&record.definition); err != nil {
+		Logger.Errorf("Fail to save task %s:%v", record.definition.ID, err)
+	}
+}
+
+// Register adds the strategy, and creates the default instance whose ID is the strategy name and uses the original collections
+func (m *TaskManager) Register(factory TaskFactory) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+
+	task := factory()
+	name := task.GetDescription().Name
+	if _, ok := m.factories[name]; ok {
+		return errors.New(TaskErrorMsg[TaskInvalidInput])
+	}
+
+	m.factories[name] = factory
+	m.strategies = append(m.strategies, name)
+	m.setScheduler(task)
+	m.instances[name] = &instance{
+		info: InstanceInfo{
+			ID:      name,
+			Name:    name,
+			Desired: Mongo.TaskStateStopped,
+			Created: time.Now(),
+			Desc:    task.GetDescription(),
+		},
+		task: task,
+	}
+
+	for _, record := range m.deferred[name] {
+		m.restoreRecord(record)
+	}
+	delete(m.deferred, name)
+
+	return nil
+}
+
+// Strategies returns the descriptions of the registered strategies
+func (m *TaskManager) Strategies() []Description {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var result []Description
+	for _, name := range m.strategies {
+		result = append(result, m.instances[name].info.Desc)
+	}
+	return result
+}
+
+// Schema returns the config schema of the strategy, nil if the strategy has no config
+func (m *TaskManager) Schema(name string) (error, *Schema) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if _, ok := m.factories[name]; !ok {
+		return errors.New(TaskErrorMsg[TaskNotFound]), nil
+	}
+
+	task := m.instances[name].task
+	if schema, ok := task.(ISchema); ok {
+		return nil, schema.GetConfigSchema()
+	}
+	return nil, GenerateSchema(task.GetDefaultConfig())
+}
+
+// ValidateConfig checks the config of the strategy, returns ConfigErrors if the config is invalid
+func (m *TaskManager) ValidateConfig(name string, configJSON string) error {
+	if configJSON == "" {
+		return nil
+	}
+
+	err, schema := m.Schema(name)
+	if err != nil {
+		return err
+	}
+
+	return ValidateConfig(schema, configJSON)
+}
+
+// Create creates the instance of the strategy with the config, returns the ID of the instance
+func (m *TaskManager) Create(name string, configJSON string) (error, string) {
+	return m.CreateWithID("", name, configJSON)
+}
+
+// CreateWithID creates the instance with the assigned ID, which is generated if it is empty
+func (m *TaskManager) CreateWithID(id string, name string, configJSON string) (error, string) {
+	if err := m.ValidateConfig(name, configJSON); err != nil {
+		return err, ""
+	}
+
+	m.lock.Lock()
+	err, item := m.create(id, name, configJSON)
+	if err != nil {
+		m.lock.Unlock()
+		return err, ""
+	}
+	record := m.record(item)
+	m.lock.Unlock()
+
+	m.save(record)
+	return nil, item.info.ID
+}
+
+func (m *TaskManager) create(id string, name string, configJSON string) (error, *instance) {
+	m.init()
+
+	factory, ok := m.factories[name]
+	if !ok {
+		return errors.New(TaskErrorMsg[TaskNotFound]), nil
+	}
+
+	if id == "" {
+		for id == "" || m.instances[id] != nil {
+			id = name + "-" + Utils.GetRandomHexString(8)
+		}
+	} else if m.instances[id] != nil {
+		return errors.New(TaskErrorMsg[TaskInvalidInput]), nil
+	}
+
+	task := factory()
+	if namespace, ok := task.(INamespace); ok {
+		namespace.SetNamespace(id)
+	}
+	m.setScheduler(task)
+
+	item := &instance{
+		info: InstanceInfo{
+			ID:        id,
+			Name:      name,
+			Namespace: id,
+			Config:    configJSON,
+			Desired:   Mongo.TaskStateStopped,
+			Created:   time.Now(),
+			Desc:      task.GetDescription(),
+		},
+		task: task,
+	}
+	m.instances[id] = item
+
+	Logger.Infof("Create task %s:%s", name, id)
+	return nil, item
+}
+
+func (m *TaskManager) setScheduler(task ITask) {
+	if scheduled, ok := task.(IScheduled); ok && m.Scheduler != nil {
+		scheduled.SetScheduler(m.Scheduler)
+	}
+}
+
+// Get returns the task of the instance, nil if not found
+func (m *TaskManager) Get(id string) ITask {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if item := m.instances[id]; item != nil {
+		return item.task
+	}
+	return nil
+}
+
+// Start starts the instance, the saved config is used if configJSON is empty
+func (m *TaskManager) Start(id string, configJSON string) error {
+	m.lock.Lock()
+	item := m.instances[id]
+	if item == nil {
+		m.lock.Unlock()
+		return errors.New(TaskErrorMsg[TaskNotFound])
+	}
+
+	if configJSON == "" {
+		configJSON = item.info.Config
+	}
+	m.lock.Unlock()
+
+	if err := m.ValidateConfig(item.info.Name, configJSON); err != nil {
+		return err
+	}
+
+	if err := m.start(item, configJSON, ReasonAPI); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	item.failures = 0
+	record := m.record(item)
+	m.lock.Unlock()
+
+	m.save(record)
+	return nil
+}
+
+// start starts the task without the lock, since it may take long time to connect the exchanges. The instance is
+// started once at a time, the supervisor starts it only if it's still desired to run, and the task stopped while
+// starting is closed again
+func (m *TaskManager) start(item *instance, configJSON string, reason string) error {
+	m.lock.Lock()
+	if item.starting {
+		m.lock.Unlock()
+		return errors.New(TaskErrorMsg[TaskErrorStatus])
+	}
+	// the running instance isn't started again by the API, the error is recorded like the task rejects it
+	if reason == ReasonAPI && item.running {
+		item.info.Error = TaskErrorMsg[TaskErrorStatus]
+		m.lock.Unlock()
+		return errors.New(TaskErrorMsg[TaskErrorStatus])
+	}
+	if reason != ReasonAPI && (item.info.Desired != Mongo.TaskStateRunning || item.running) {
+		m.lock.Unlock()
+		return errStartSkipped
+	}
+	item.starting = true
+	stops := item.stops
+	m.lock.Unlock()
+
+	err := item.task.Start(configJSON)
+
+	m.lock.Lock()
+	item.starting = false
+	stopped := item.stops != stops
+	if err != nil {
+		item.info.Error = err.Error()
+	} else if !stopped {
+		item.running = true
+		item.info.Config = configJSON
+		item.info.Desired = Mongo.TaskStateRunning
+		item.info.Error = ""
+		item.info.Started = time.Now()
+		item.info.StartReason = reason
+	}
+	m.lock.Unlock()
+
+	if err != nil {
+		Logger.Errorf("Fail to start task %s(%s):%v", item.info.ID, reason, err)
+		return err
+	}
+	if stopped {
+		Logger.Infof("Task %s is stopped while it's being started", item.info.ID)
+		item.task.Close()
+		return errStartStopped
+	}
+	return nil
+}
+
+// UpdateConfig applies the config to the instance, the running task is updated without restarting,
+// and the read-only fields are rejected. The update is audited whether it succeeds or not
+func (m *TaskManager) UpdateConfig(id string, configJSON string) (error, []ConfigChange) {
+	m.lock.RLock()
+	item := m.instances[id]
+	m.lock.RUnlock()
+	if item == nil {
+		return errors.New(TaskErrorMsg[TaskNotFound]), nil
+	}
+
+	err, schema := m.Schema(item.info.Name)
+	if err != nil {
+		return err, nil
+	}
+
+	m.lock.RLock()
+	oldJSON := item.info.Config
+	m.lock.RUnlock()
+	if oldJSON == "" {
+		if config := item.task.GetDefaultConfig(); config != nil {
+			data, _ := json.Marshal(config)
+			oldJSON = string(data)
+		}
+	}
+
+	status := item.task.GetStatus()
+	running := status == StatusProcessing || status == StatusInit
+
+	if err := ValidateConfig(schema, configJSON); err != nil {
+		m.audit(item, oldJSON, configJSON, running, nil, err)
+		return err, nil
+	}
+
+	err, changes := DiffConfig(schema, oldJSON, configJSON)
+	if err != nil {
+		return err, nil
+	}
+
+	if running {
+		var errs ConfigErrors
+		for _, change := range changes {
+			if change.ReadOnly {
+				errs = append(errs, ConfigError{Field: change.Field, Message: "can't be changed while the task is running"})
+			}
+		}
+		if errs != nil {
+			err = errs
+		} else if len(changes) != 0 {
+			err = item.task.UpdateConfig(configJSON)
+		}
+	}
+
+	m.audit(item, oldJSON, configJSON, running, changes, err)
+	if err != nil {
+		Logger.Errorf("Fail to update the config of task %s:%v", id, err)
+		return err, changes
+	}
+
+	m.lock.Lock()
+	item.info.Config = configJSON
+	record := m.record(item)
+	m.lock.Unlock()
+
+	m.save(record)
+
+	Logger.Infof("Update the config of task %s:%v", id, changes)
+	return nil, changes
+}
+
+func (m *TaskManager) audit(item *instance, oldJSON string, newJSON string, running bool, changes []ConfigChange, err error) {
+	if m.DB == nil {
+		return
+	}
+
+	record := &Mongo.TaskConfigAudit{
+		TaskID:  item.info.ID,
+		Time:    time.Now(),
+		Running: running,
+		Old:     oldJSON,
+		New:     newJSON,
+	}
+	for _, change := range changes {
+		record.Changes = append(record.Changes, Mongo.TaskConfigChange{
+			Field: change.Field,
+			Old:   change.Old,
+			New:   change.New,
+		})
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if err := m.DB.InsertAudit(record); err != nil {
+		Logger.Errorf("Fail to save the audit of task %s:%v", item.info.ID, err)
+	}
+}
+
+// Audits returns the config updates of the instance
+func (m *TaskManager) Audits(id string) (error, []Mongo.TaskConfigAudit) {
+	if m.DB == nil {
+		return errors.New(Mongo.ErrorNotConnected), nil
+	}
+	return m.DB.FindAudits(id)
+}
+
+// Stop closes the task of the instance
+func (m *TaskManager) Stop(id string) error {
+	m.lock.Lock()
+	item := m.instances[id]
+	if item == nil {
+		m.lock.Unlock()
+		return errors.New(TaskErrorMsg[TaskNotFound])
+	}
+
+	m.stop(item, ReasonAPI)
+	record := m.record(item)
+	m.lock.Unlock()
+
+	m.save(record)
+	item.task.Close()
+	return nil
+}
+
+// stop marks the instance as stopped, the task is closed by the caller without the lock since the remote task
+// closes by the call to the strategy process
+func (m *TaskManager) stop(item *instance, reason string) {
+	item.running = false
+	item.stops++
+	item.info.Desired = Mongo.TaskStateStopped
+	item.info.Stopped = time.Now()
+	item.info.StopReason = reason
+}
+
+// ForceCloseAll closes the positions of the running instances, the strategy processes close their own positions
+// when they follow the kill switch
+func (m *TaskManager) ForceCloseAll() {
+	m.lock.RLock()
+	var tasks []ITask
+	for _, item := range m.instances {
+		if _, ok := item.task.(*RemoteTask); ok || !item.running {
+			continue
+		}
+		tasks = append(tasks, item.task)
+	}
+	m.lock.RUnlock()
+
+	for _, task := range tasks {
+		task.ForceClosePositions()
+	}
+}
+
+// Remove stops and removes the instance, the default instances can not be removed
+func (m *TaskManager) Remove(id string) error {
+	m.lock.Lock()
+	item := m.instances[id]
+	if item == nil {
+		m.lock.Unlock()
+		return errors.New(TaskErrorMsg[TaskNotFound])
+	}
+
+	if _, ok := m.factories[id]; ok {
+		m.lock.Unlock()
+		return errors.New(TaskErrorMsg[TaskInvalidInput])
+	}
+
+	m.stop(item, ReasonRemove)
+	delete(m.instances, id)
+	if m.Scheduler != nil {
+		m.Scheduler.RemoveTaskJobs(id)
+	}
+	record := m.record(item)
+	record.remove = true
+	m.lock.Unlock()
+
+	m.save(record)
+
+	if item.task.GetStatus() != StatusNone {
+		item.task.Close()
+	}
+	return nil
+}
+
+// Status returns the information of the instance, the status is requested without the lock since the remote task
+// reports it by the call to the strategy process
+func (m *TaskManager) Status(id string) (error, *InstanceInfo) {
+	m.lock.RLock()
+	item := m.instances[id]
+	if item == nil {
+		m.lock.RUnlock()
+		return errors.New(TaskErrorMsg[TaskNotFound]), nil
+	}
+	info := item.info
+	m.lock.RUnlock()
+
+	info.Status = item.task.GetStatus()
+	return nil, &info
+}
+
+// List returns all the instances sorted by the created time
+func (m *TaskManager) List() []InstanceInfo {
+	m.lock.RLock()
+	var items []*instance
+	var result []InstanceInfo
+	for _, item := range m.instances {
+		items = append(items, item)
+		result = append(result, item.info)
+	}
+	m.lock.RUnlock()
+
+	for i, item := range items {
+		result[i].Status = item.task.GetStatus()
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Created.Equal(result[j].Created) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].Created.Before(result[j].Created)
+	})
+
+	return result
+}
+
+// Restore loads the task definitions from DB, and starts the tasks whose desired state is running in the background
+func (m *TaskManager) Restore() error {
+	if m.DB == nil {
+		return errors.New(Mongo.ErrorNotConnected)
+	}
+
+	err, records := m.DB.FindAll()
+	if err != nil {
+		return err
+	}
+
+	m.restore(records)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !m.supervised {
+		m.supervised = true
+		go func() {
+			for {
+				m.supervise(time.Now())
+				time.Sleep(superviseInterval)
+			}
+		}()
+	}
+
+	return nil
+}
+
+func (m *TaskManager) restore(records []Mongo.TaskDefinition) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+
+	for _, record := range records {
+		if _, ok := m.factories[record.Name]; !ok {
+			Logger.Infof("Task %s(%s) is restored after the strategy is registered", record.ID, record.Name)
+			if m.deferred == nil {
+				m.deferred = make(map[string][]Mongo.TaskDefinition)
+			}
+			m.deferred[record.Name] = append(m.deferred[record.Name], record)
+			continue
+		}
+
+		m.restoreRecord(record)
+	}
+}
+
+func (m *TaskManager) restoreRecord(record Mongo.TaskDefinition) {
+	item := m.instances[record.ID]
+	if item == nil {
+		var err error
+		if err, item = m.create(record.ID, record.Name, record.Config); err != nil {
+			Logger.Errorf("Fail to restore task %s(%s):%v", record.ID, record.Name, err)
+			return
+		}
+	}
+
+	item.info.Config = record.Config
+	item.info.Desired = record.Desired
+	item.info.Started = record.LastStart
+	item.info.StartReason = record.LastStartReason
+	item.info.Stopped = record.LastStop
+	item.info.StopReason = record.LastStopReason
+	item.info.Restarts = record.Restarts
+}
+
+func restartBackoff(failures int) time.Duration {
+	backoff := restartBackoffMin
+	for i := 1; i < failures && backoff < restartBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > restartBackoffMax {
+		backoff = restartBackoffMax
+	}
+	return backoff
+}
+
+// supervise starts the tasks which should be running but are not, with the crash-loop backoff
+func (m *TaskManager) supervise(now time.Time) {
+	type pending struct {
+		item   *instance
+		config string
+		reason string
+	}
+
+	// the status and the close of the remote tasks are called without the lock
+	m.lock.RLock()
+	var items []*instance
+	for _, item := range m.instances {
+		if item.info.Desired == Mongo.TaskStateRunning {
+			items = append(items, item)
+		}
+	}
+	m.lock.RUnlock()
+
+	statuses := make([]StatusType, len(items))
+	for i, item := range items {
+		statuses[i] = item.task.GetStatus()
+	}
+
+	var list []pending
+	var crashed []*instance
+	var records []*taskRecord
+
+	m.lock.Lock()
+	for i, item := range items {
+		// the instance is stopped or removed while the status is requested
+		if item.info.Desired != Mongo.TaskStateRunning || m.instances[item.info.ID] != item {
+			continue
+		}
+
+		status := statuses[i]
+		if status == StatusProcessing || status == StatusInit {
+			if item.failures > 0 && now.Sub(item.info.Started) >= restartStable {
+				item.failures = 0
+			}
+			continue
+		}
+
+		reason := ReasonRestore
+		if item.running {
+			// the task is running before, so it crashed
+			Logger.Errorf("Task %s exits unexpectedly, status:%v", item.info.ID, status)
+			crashed = append(crashed, item)
+			item.running = false
+			item.info.Stopped = now
+			item.info.StopReason = ReasonCrash
+			item.failures++
+			item.nextRetry = now.Add(restartBackoff(item.failures))
+			records = append(records, m.record(item))
+		}
+
+		if item.info.StopReason == ReasonCrash {
+			reason = ReasonCrash
+		}
+
+		if now.Before(item.nextRetry) {
+			continue
+		}
+
+		list = append(list, pending{item, item.info.Config, reason})
+	}
+	m.lock.Unlock()
+
+	for _, record := range records {
+		m.save(record)
+	}
+	for _, item := range crashed {
+		item.task.Close()
+	}
+
+	for _, p := range list {
+		err := m.start(p.item, p.config, p.reason)
+		if err == errStartSkipped || err == errStartStopped {
+			continue
+		}
+
+		m.lock.Lock()
+		if p.reason == ReasonCrash {
+			p.item.info.Restarts++
+		}
+		if err != nil {
+			p.item.failures++
+			p.item.nextRetry = now.Add(restartBackoff(p.item.failures))
+		}
+		record := m.record(p.item)
+		m.lock.Unlock()
+
+		m.save(record)
+	}
+}
@@ -0,0 +1,411 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	Mongo "madaoQT/mongo"
+)
+
+type fakeTask struct {
+	namespace string
+	config    string
+	status    StatusType
+	// fails the count of the failed starts
+	fails int
+}
+
+func (f *fakeTask) GetDefaultConfig() interface{} { return nil }
+func (f *fakeTask) GetDescription() Description {
+	return Description{Name: "fake", Title: "fake"}
+}
+func (f *fakeTask) Start(configJSON string) error {
+	if f.status != StatusNone {
+		return errors.New(TaskErrorMsg[TaskErrorStatus])
+	}
+	if f.fails > 0 {
+		f.fails--
+		return errors.New(TaskErrorMsg[TaskLostMongodb])
+	}
+	f.config = configJSON
+	f.status = StatusProcessing
+	return nil
+}
+func (f *fakeTask) Close()                                       { f.status = StatusNone }
+func (f *fakeTask) GetStatus() StatusType                        { return f.status }
+func (f *fakeTask) GetBalances() map[string]interface{}          { return nil }
+func (f *fakeTask) GetTrades() []Mongo.TradesRecord              { return nil }
+func (f *fakeTask) GetPositions() []map[string]interface{}       { return nil }
+func (f *fakeTask) GetFailedPositions() []map[string]interface{} { return nil }
+func (f *fakeTask) FixFailedPosition(updateJSON string) error    { return nil }
+func (f *fakeTask) ForceClosePositions()                         {}
+func (f *fakeTask) UpdateConfig(configJSON string) error         { f.config = configJSON; return nil }
+func (f *fakeTask) SetNamespace(namespace string)                { f.namespace = namespace }
+
+func TestTaskManager(t *testing.T) {
+	manager := new(TaskManager)
+	manager.Register(func() ITask { return new(fakeTask) })
+
+	if err := manager.Register(func() ITask { return new(fakeTask) }); err == nil {
+		t.Errorf("The strategy should not be registered twice")
+	}
+
+	if strategies := manager.Strategies(); len(strategies) != 1 || strategies[0].Name != "fake" {
+		t.Errorf("Invalid strategies:%v", strategies)
+	}
+
+	err, first := manager.Create("fake", `{"coin":"eth"}`)
+	if err != nil {
+		t.Fatalf("Fail to create:%v", err)
+	}
+	err, second := manager.Create("fake", `{"coin":"btc"}`)
+	if err != nil || first == second {
+		t.Fatalf("Invalid instances:%v %v %v", err, first, second)
+	}
+
+	if err, _ := manager.Create("unknown", ""); err == nil {
+		t.Errorf("The unknown strategy should fail")
+	}
+
+	if task := manager.Get(first).(*fakeTask); task.namespace != first {
+		t.Errorf("Invalid namespace:%v", task.namespace)
+	}
+	if task := manager.Get("fake").(*fakeTask); task.namespace != "" {
+		t.Errorf("The default instance should use the original collections:%v", task.namespace)
+	}
+
+	// the instances of the same strategy run at the same time
+	if err := manager.Start(first, ""); err != nil {
+		t.Errorf("Fail to start:%v", err)
+	}
+	if err := manager.Start(second, ""); err != nil {
+		t.Errorf("Fail to start:%v", err)
+	}
+	if err := manager.Start(first, ""); err == nil {
+		t.Errorf("The running instance should not be started again")
+	}
+
+	if task := manager.Get(second).(*fakeTask); task.config != `{"coin":"btc"}` {
+		t.Errorf("Invalid config:%v", task.config)
+	}
+
+	manager.Stop(first)
+	if err, info := manager.Status(first); err != nil || info.Status != StatusNone || info.Error == "" {
+		t.Errorf("Invalid status:%v %v", err, info)
+	}
+	if err, info := manager.Status(second); err != nil || info.Status != StatusProcessing {
+		t.Errorf("Invalid status:%v %v", err, info)
+	}
+
+	if list := manager.List(); len(list) != 3 || list[0].ID != "fake" {
+		t.Errorf("Invalid list:%v", list)
+	}
+
+	if err := manager.Remove("fake"); err == nil {
+		t.Errorf("The default instance should not be removed")
+	}
+	if err := manager.Remove(second); err != nil || manager.Get(second) != nil {
+		t.Errorf("Fail to remove:%v", err)
+	}
+
+	if CollectionName(first, "Trades") != first+"_Trades" || CollectionName("", "Trades") != "Trades" {
+		t.Errorf("Invalid collection name")
+	}
+}
+
+func TestTaskManagerRestore(t *testing.T) {
+	manager := new(TaskManager)
+	manager.Register(func() ITask { return new(fakeTask) })
+
+	manager.restore([]Mongo.TaskDefinition{
+		{ID: "fake", Name: "fake", Config: "default", Desired: Mongo.TaskStateStopped},
+		{ID: "fake-1", Name: "fake", Config: "eth", Desired: Mongo.TaskStateRunning, Restarts: 2},
+		{ID: "unknown-1", Name: "unknown", Desired: Mongo.TaskStateRunning},
+	})
+
+	if list := manager.List(); len(list) != 2 {
+		t.Fatalf("Invalid list:%v", list)
+	}
+
+	now := time.Now()
+	task := manager.Get("fake-1").(*fakeTask)
+	task.fails = 1
+
+	// the first start fails, and retries after the backoff
+	manager.supervise(now)
+	if task.status != StatusNone {
+		t.Fatalf("The task should fail to start")
+	}
+	manager.supervise(now.Add(restartBackoffMin / 2))
+	if task.status != StatusNone {
+		t.Fatalf("The task should wait for the backoff")
+	}
+	manager.supervise(now.Add(restartBackoffMin))
+	_, info := manager.Status("fake-1")
+	if task.status != StatusProcessing || task.config != "eth" || info.StartReason != ReasonRestore {
+		t.Fatalf("Fail to restore:%v", info)
+	}
+
+	if manager.Get("fake").(*fakeTask).status != StatusNone {
+		t.Errorf("The stopped task should not be started")
+	}
+
+	// crash
+	now = now.Add(time.Minute)
+	task.status = StatusError
+	manager.supervise(now)
+	_, info = manager.Status("fake-1")
+	if task.status != StatusNone || info.StopReason != ReasonCrash {
+		t.Fatalf("The crash should be recorded:%v", info)
+	}
+
+	manager.supervise(now.Add(restartBackoff(2)))
+	_, info = manager.Status("fake-1")
+	if task.status != StatusProcessing || info.StartReason != ReasonCrash || info.Restarts != 3 {
+		t.Errorf("Fail to restart:%v", info)
+	}
+
+	manager.Stop("fake-1")
+	manager.supervise(now.Add(time.Hour))
+	if task.status != StatusNone {
+		t.Errorf("The stopped task should not be restarted")
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	if restartBackoff(1) != restartBackoffMin || restartBackoff(3) != 4*restartBackoffMin || restartBackoff(100) != restartBackoffMax {
+		t.Errorf("Invalid backoff:%v %v %v", restartBackoff(1), restartBackoff(3), restartBackoff(100))
+	}
+}
+
+type configTask struct {
+	fakeTask
+}
+
+func (c *configTask) GetDefaultConfig() interface{} { return testConfig{Limit: 0.03} }
+func (c *configTask) GetDescription() Description {
+	return Description{Name: "config", Title: "config"}
+}
+
+func TestTaskManagerConfig(t *testing.T) {
+	manager := new(TaskManager)
+	manager.Register(func() ITask { return new(fakeTask) })
+	manager.Register(func() ITask { return new(configTask) })
+
+	if err, schema := manager.Schema("fake"); err != nil || schema != nil {
+		t.Errorf("Invalid schema:%v %v", err, schema)
+	}
+	if err, schema := manager.Schema("config"); err != nil || schema.Properties["limit"].Default != 0.03 {
+		t.Errorf("Invalid schema:%v %v", err, schema)
+	}
+	if err, _ := manager.Schema("unknown"); err == nil {
+		t.Errorf("The unknown strategy should fail")
+	}
+
+	if err, _ := manager.Create("config", `{"area":{},"limit":1}`); err == nil {
+		t.Errorf("The invalid config should fail")
+	}
+	if err := manager.Start("config", `{"limit":0.01}`); err == nil {
+		t.Errorf("The invalid config should fail")
+	}
+	if manager.Get("config").GetStatus() != StatusNone {
+		t.Errorf("The task should not be started")
+	}
+	if err := manager.Start("config", `{"area":{},"limit":0.01}`); err != nil {
+		t.Errorf("Fail to start:%v", err)
+	}
+}
+
+func TestTaskManagerUpdateConfig(t *testing.T) {
+	manager := new(TaskManager)
+	manager.Register(func() ITask { return new(configTask) })
+
+	err, id := manager.Create("config", `{"secret":"a","area":{},"limit":0.01}`)
+	if err != nil {
+		t.Fatalf("Fail to create:%v", err)
+	}
+
+	// the read-only fields can be changed before starting
+	if err, changes := manager.UpdateConfig(id, `{"secret":"b","area":{},"limit":0.01}`); err != nil || len(changes) != 1 {
+		t.Errorf("Fail to update:%v %v", err, changes)
+	}
+	task := manager.Get(id).(*configTask)
+	if task.config != "" {
+		t.Errorf("The stopped task should not be updated:%v", task.config)
+	}
+
+	if err := manager.Start(id, ""); err != nil || task.config != `{"secret":"b","area":{},"limit":0.01}` {
+		t.Fatalf("Fail to start:%v %v", err, task.config)
+	}
+
+	err, _ = manager.UpdateConfig(id, `{"secret":"c","area":{},"limit":0.02}`)
+	if errs, ok := err.(ConfigErrors); !ok || len(errs) != 1 || errs[0].Field != "secret" {
+		t.Errorf("The read-only field should be rejected:%v", err)
+	}
+	if err, _ := manager.UpdateConfig(id, `{"secret":"b","area":{},"limit":1}`); err == nil {
+		t.Errorf("The invalid config should be rejected")
+	}
+
+	err, changes := manager.UpdateConfig(id, `{"secret":"b","area":{"btc":{"open":1}},"limit":0.02}`)
+	if err != nil || len(changes) != 2 || changes[0].Field != "area.btc" || changes[1].Field != "limit" {
+		t.Fatalf("Fail to update:%v %v", err, changes)
+	}
+	if _, info := manager.Status(id); task.config != info.Config || task.GetStatus() != StatusProcessing {
+		t.Errorf("The config should be applied without restarting:%v", info)
+	}
+
+	if err, _ := manager.UpdateConfig("unknown", "{}"); err == nil {
+		t.Errorf("The unknown instance should fail")
+	}
+}
+
+func TestTaskManagerDeferredRestore(t *testing.T) {
+	manager := new(TaskManager)
+	manager.Register(func() ITask { return new(fakeTask) })
+
+	// the strategy process registers after restoring
+	manager.restore([]Mongo.TaskDefinition{
+		{ID: "config-1", Name: "config", Config: `{"area":{},"limit":0.01}`, Desired: Mongo.TaskStateRunning},
+	})
+	if manager.Get("config-1") != nil {
+		t.Fatalf("The task should not be restored before the strategy is registered")
+	}
+
+	manager.Register(func() ITask { return new(configTask) })
+	if err, info := manager.Status("config-1"); err != nil || info.Desired != Mongo.TaskStateRunning || info.Namespace != "config-1" {
+		t.Fatalf("Fail to restore:%v %v", err, info)
+	}
+
+	manager.supervise(time.Now())
+	if manager.Get("config-1").GetStatus() != StatusProcessing {
+		t.Errorf("The restored task should be started")
+	}
+}
+
+// slowTask the status is blocked like the remote task whose strategy process hangs
+type slowTask struct {
+	fakeTask
+	entered chan bool
+	release chan bool
+}
+
+func (s *slowTask) GetDescription() Description {
+	return Description{Name: "slow", Title: "slow"}
+}
+
+func (s *slowTask) GetStatus() StatusType {
+	s.entered <- true
+	<-s.release
+	return s.fakeTask.GetStatus()
+}
+
+func TestTaskManagerSlowTask(t *testing.T) {
+	manager := new(TaskManager)
+	manager.Register(func() ITask { return new(fakeTask) })
+	slow := &slowTask{entered: make(chan bool, 1), release: make(chan bool)}
+	manager.Register(func() ITask { return slow })
+	defer close(slow.release)
+
+	go manager.Status("slow")
+	<-slow.entered
+
+	done := make(chan bool)
+	go func() {
+		manager.Start("fake", "")
+		manager.Status("fake")
+		manager.Stop("fake")
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("The other tasks shouldn't be blocked by the slow task")
+	}
+}
+
+// gateTask the start waits until it's opened
+type gateTask struct {
+	fakeTask
+	entered chan bool
+	open    chan bool
+}
+
+func (g *gateTask) GetDescription() Description {
+	return Description{Name: "gate", Title: "gate"}
+}
+
+func (g *gateTask) Start(configJSON string) error {
+	g.entered <- true
+	<-g.open
+	return g.fakeTask.Start(configJSON)
+}
+
+func TestTaskManagerStopWhileStarting(t *testing.T) {
+	manager := new(TaskManager)
+	task := &gateTask{entered: make(chan bool, 1), open: make(chan bool)}
+	manager.Register(func() ITask { return task })
+	manager.restore([]Mongo.TaskDefinition{{ID: "gate", Name: "gate", Desired: Mongo.TaskStateRunning}})
+
+	done := make(chan bool)
+	go func() {
+		manager.supervise(time.Now())
+		done <- true
+	}()
+	<-task.entered
+
+	// the instance is started once at a time
+	if err := manager.Start("gate", ""); err == nil {
+		t.Errorf("The starting instance shouldn't be started again")
+	}
+
+	manager.Stop("gate")
+	close(task.open)
+	<-done
+
+	_, info := manager.Status("gate")
+	if task.status != StatusNone || info.Desired != Mongo.TaskStateStopped || info.StopReason != ReasonAPI {
+		t.Fatalf("The task stopped while starting should be closed:%v %v", task.status, info)
+	}
+	manager.supervise(time.Now().Add(time.Hour))
+	if task.status != StatusNone {
+		t.Errorf("The stopped task shouldn't be restarted")
+	}
+}
+
+// countTask the start always succeeds, even if it's running
+type countTask struct {
+	fakeTask
+	starts int
+}
+
+func (c *countTask) GetDescription() Description {
+	return Description{Name: "count", Title: "count"}
+}
+
+func (c *countTask) Start(configJSON string) error {
+	c.starts++
+	c.status = StatusProcessing
+	return nil
+}
+
+func TestTaskManagerStartRunning(t *testing.T) {
+	manager := new(TaskManager)
+	task := new(countTask)
+	manager.Register(func() ITask { return task })
+
+	if err := manager.Start("count", ""); err != nil {
+		t.Fatalf("Fail to start:%v", err)
+	}
+	if err := manager.Start("count", ""); err == nil || err.Error() != TaskErrorMsg[TaskErrorStatus] {
+		t.Errorf("The running instance should not be started again:%v", err)
+	}
+	if task.starts != 1 {
+		t.Errorf("Invalid starts:%v", task.starts)
+	}
+
+	manager.Stop("count")
+	if err := manager.Start("count", ""); err != nil || task.starts != 2 {
+		t.Errorf("The stopped instance should be started:%v %v", err, task.starts)
+	}
+}
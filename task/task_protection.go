@@ -0,0 +1,652 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+/*
+	仓位保护：开仓后挂止损、止盈和移动止损。交易所支持条件单时(Bitmex、Deribit、OANDA、OKEX V3)直接下条件单，
+	否则由本地按行情监控，触发后平仓。保护记录保存在Mongo中，服务重启后继续监控，任务停止后仓位仍然受保护。
+	保护单只减仓，不经过风控网关，熔断时也会执行
+*/
+
+// ProtectionRule the ratios from the entry price, 0 disables the rule
+type ProtectionRule struct {
+	StopLoss   float64 `json:"stoploss" title:"止损幅度" desc:"相对开仓价，0表示不设置" min:"0" max:"1"`
+	TakeProfit float64 `json:"takeprofit" title:"止盈幅度" desc:"相对开仓价，0表示不设置" min:"0" max:"10"`
+	Trailing   float64 `json:"trailing" title:"移动止损回撤" desc:"相对最优价，0表示不设置" min:"0" max:"1"`
+	// Local the rules are monitored locally even if the exchange supports the conditional orders
+	Local bool `json:"local" title:"本地监控"`
+}
+
+func (r ProtectionRule) Enabled() bool {
+	return r.StopLoss > 0 || r.TakeProfit > 0 || r.Trailing > 0
+}
+
+// ProtectionVenue creates the exchange which monitors the positions after the task is stopped
+type ProtectionVenue struct {
+	// Exchange the name of the keys saved in ExchangeDB
+	Exchange string
+	Create   func(config Exchange.Config) Exchange.IExchange
+}
+
+// protectionSlippage the limit of the closing orders placed by the local monitor
+const protectionSlippage = 0.01
+
+const protectionInterval = 5 * time.Second
+
+// protectionOrderWait the closing order is canceled if it isn't closed after the time
+var protectionOrderWait = 2 * time.Second
+
+const protectionMinAmount = 1e-8
+
+// Protector places the protections of the positions and monitors the ones which aren't placed natively
+type Protector struct {
+	// Interval the period of the local monitor
+	Interval time.Duration
+
+	lock    sync.Mutex
+	db      *Mongo.Protections
+	venues  map[string]func(venue string) *ProtectionVenue
+	records map[string]*Mongo.ProtectionRecord
+	// exchanges the exchanges created by the protector, which are shared by the records of the same venue
+	exchanges map[string]Exchange.IExchange
+	// fallbacks the exchanges of the tasks, which are used if the venue can't be created
+	fallbacks map[string]Exchange.IExchange
+	quit      chan bool
+}
+
+// GlobalProtector the protections of the tasks in the process
+var GlobalProtector = new(Protector)
+
+// SetDB the records are saved after the DB is assigned
+func (p *Protector) SetDB(db *Mongo.Protections) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.db = db
+}
+
+func (p *Protector) getDB() *Mongo.Protections {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.db
+}
+
+// RegisterVenues the lookup of the venues of the source, e.g. the venues of the trend tasks
+func (p *Protector) RegisterVenues(source string, lookup func(venue string) *ProtectionVenue) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.venues == nil {
+		p.venues = make(map[string]func(string) *ProtectionVenue)
+	}
+	p.venues[source] = lookup
+}
+
+func protectionOrders(record *Mongo.ProtectionRecord) []Exchange.StopOrderConfig {
+	config := Exchange.StopOrderConfig{
+		Pair:    record.Pair,
+		Type:    Exchange.TradeType(record.CloseType),
+		Amount:  record.Amount,
+		Price:   record.Entry,
+		TradeID: record.TradeID,
+	}
+	sell := protectionSell(record)
+
+	var orders []Exchange.StopOrderConfig
+	if record.StopLoss > 0 {
+		config.StopType = Exchange.StopOrderLoss
+		config.Trigger = record.Entry * (1 + record.StopLoss)
+		if sell {
+			config.Trigger = record.Entry * (1 - record.StopLoss)
+		}
+		orders = append(orders, config)
+	}
+	if record.TakeProfit > 0 {
+		config.StopType = Exchange.StopOrderProfit
+		config.Trigger = record.Entry * (1 - record.TakeProfit)
+		if sell {
+			config.Trigger = record.Entry * (1 + record.TakeProfit)
+		}
+		orders = append(orders, config)
+	}
+	if record.Trailing > 0 {
+		config.StopType = Exchange.StopOrderTrailing
+		config.Trigger = 0
+		config.Callback = record.Trailing
+		orders = append(orders, config)
+	}
+	return orders
+}
+
+// protectionSell whether the long position is protected
+func protectionSell(record *Mongo.ProtectionRecord) bool {
+	closeType := Exchange.TradeType(record.CloseType)
+	return closeType == Exchange.TradeTypeCloseLong || closeType == Exchange.TradeTypeSell
+}
+
+func stopOrders(exchange Exchange.IExchange) Exchange.IStopOrders {
+	if gateway, ok := exchange.(*RiskGateway); ok {
+		exchange = gateway.IExchange
+	}
+	stop, _ := exchange.(Exchange.IStopOrders)
+	return stop
+}
+
+// Attach protects the position by the rule, the conditional orders are placed if the exchange supports them, or the
+// position is monitored locally. The exchange of the task is used to place the orders
+func (p *Protector) Attach(exchange Exchange.IExchange, record *Mongo.ProtectionRecord, rule ProtectionRule) error {
+	if !rule.Enabled() {
+		return nil
+	}
+
+	record.StopLoss = rule.StopLoss
+	record.TakeProfit = rule.TakeProfit
+	record.Trailing = rule.Trailing
+	record.Best = record.Entry
+	record.Status = Mongo.ProtectionStatusActive
+	record.Time = time.Now()
+	record.Orders = make(map[string]string)
+
+	if stop := stopOrders(exchange); stop != nil && !rule.Local {
+		p.placeOrders(stop, record)
+	}
+
+	p.lock.Lock()
+	if p.records == nil {
+		p.records = make(map[string]*Mongo.ProtectionRecord)
+		p.fallbacks = make(map[string]Exchange.IExchange)
+	}
+	p.records[record.ID] = record
+	p.fallbacks[record.ID] = exchange
+	p.lock.Unlock()
+
+	Logger.Infof("[%s]保护仓位 止损:%v 止盈:%v 移动止损:%v 条件单:%v", record.ID, record.StopLoss, record.TakeProfit, record.Trailing, record.Native)
+	p.save(record)
+	p.start()
+	return nil
+}
+
+// placeOrders places the conditional orders of the record, the position is monitored locally if any of them fails
+func (p *Protector) placeOrders(stop Exchange.IStopOrders, record *Mongo.ProtectionRecord) {
+	record.Native = true
+	for _, config := range protectionOrders(record) {
+		err, orderID := stop.PlaceStopOrder(config)
+		if err != nil {
+			Logger.Errorf("[%s]Fail to place the %s order, monitor it locally:%v", record.ID, Exchange.StopOrderTypeString[config.StopType], err)
+			p.cancelOrders(stop, record)
+			record.Native = false
+			return
+		}
+		record.Orders[Exchange.StopOrderTypeString[config.StopType]] = orderID
+	}
+}
+
+func (p *Protector) cancelOrders(stop Exchange.IStopOrders, record *Mongo.ProtectionRecord) error {
+	var result error
+	for name, orderID := range record.Orders {
+		if err := stop.CancelStopOrder(record.Pair, orderID); err != nil {
+			Logger.Errorf("[%s]Fail to cancel the %s order:%v", record.ID, name, err)
+			result = err
+			continue
+		}
+		delete(record.Orders, name)
+	}
+	return result
+}
+
+// Detach cancels the protection after the position is closed by the task or the protection is triggered
+func (p *Protector) Detach(exchange Exchange.IExchange, id string) error {
+	p.lock.Lock()
+	record := p.records[id]
+	delete(p.records, id)
+	delete(p.fallbacks, id)
+	p.lock.Unlock()
+
+	if record == nil {
+		return nil
+	}
+
+	if record.Status == Mongo.ProtectionStatusActive {
+		record.Status = Mongo.ProtectionStatusCancelled
+	}
+	// the rest of the conditional orders are cancelled if one of them is triggered
+	if record.Native {
+		if stop := stopOrders(exchange); stop != nil {
+			if err := p.cancelOrders(stop, record); err != nil {
+				record.Message = err.Error()
+			}
+		}
+	}
+	p.save(record)
+	return nil
+}
+
+// Resize protects the amount left after the position is closed partly by the task, the conditional orders are
+// placed again with the amount
+func (p *Protector) Resize(exchange Exchange.IExchange, id string, amount float64) error {
+	p.lock.Lock()
+	record := p.records[id]
+	if record == nil || record.Status != Mongo.ProtectionStatusActive {
+		p.lock.Unlock()
+		return nil
+	}
+	record.Amount = amount
+	native := record.Native
+	p.lock.Unlock()
+
+	if native {
+		if stop := stopOrders(exchange); stop != nil {
+			if err := p.cancelOrders(stop, record); err != nil {
+				record.Message = err.Error()
+				p.save(record)
+				return err
+			}
+			p.placeOrders(stop, record)
+		}
+	}
+
+	Logger.Infof("[%s]保护数量调整为:%v 条件单:%v", record.ID, amount, record.Native)
+	p.save(record)
+	return nil
+}
+
+// Triggered returns the price if the protection of the position is triggered
+func (p *Protector) Triggered(id string) (bool, float64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if record := p.records[id]; record != nil && record.Status == Mongo.ProtectionStatusTriggered {
+		return true, record.Price
+	}
+	return false, 0
+}
+
+// Records the protections monitored by the process
+func (p *Protector) Records() []Mongo.ProtectionRecord {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var records []Mongo.ProtectionRecord
+	for _, record := range p.records {
+		records = append(records, *record)
+	}
+	return records
+}
+
+// Restore monitors the active protections saved before restarting
+func (p *Protector) Restore() error {
+	db := p.getDB()
+	if db == nil {
+		return errors.New(Mongo.ErrorNotConnected)
+	}
+	err, records := db.FindActive()
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	if p.records == nil {
+		p.records = make(map[string]*Mongo.ProtectionRecord)
+		p.fallbacks = make(map[string]Exchange.IExchange)
+	}
+	for i := range records {
+		if _, ok := p.records[records[i].ID]; !ok {
+			p.records[records[i].ID] = &records[i]
+		}
+	}
+	p.lock.Unlock()
+
+	Logger.Infof("恢复仓位保护:%d", len(records))
+	p.start()
+	return nil
+}
+
+func (p *Protector) save(record *Mongo.ProtectionRecord) {
+	if db := p.getDB(); db != nil {
+		if err := db.Save(record); err != nil {
+			Logger.Errorf("[%s]Fail to save the protection:%v", record.ID, err)
+		}
+	}
+}
+
+func (p *Protector) start() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.quit != nil {
+		return
+	}
+
+	interval := p.Interval
+	if interval == 0 {
+		interval = protectionInterval
+	}
+	p.quit = make(chan bool)
+	go func(quit chan bool) {
+		for {
+			select {
+			case <-quit:
+				return
+			case <-time.After(interval):
+				p.Check()
+			}
+		}
+	}(p.quit)
+}
+
+// Stop the monitor and closes the exchanges created by the protector, the records are kept in Mongo
+func (p *Protector) Stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.quit != nil {
+		close(p.quit)
+		p.quit = nil
+	}
+	for key, exchange := range p.exchanges {
+		exchange.Close()
+		delete(p.exchanges, key)
+	}
+}
+
+// evaluate updates the best price and returns the triggered rule
+func evaluate(record *Mongo.ProtectionRecord, price float64) (bool, Exchange.StopOrderType) {
+	if protectionSell(record) {
+		if price > record.Best {
+			record.Best = price
+		}
+		switch {
+		case record.StopLoss > 0 && price <= record.Entry*(1-record.StopLoss):
+			return true, Exchange.StopOrderLoss
+		case record.TakeProfit > 0 && price >= record.Entry*(1+record.TakeProfit):
+			return true, Exchange.StopOrderProfit
+		case record.Trailing > 0 && price <= record.Best*(1-record.Trailing):
+			return true, Exchange.StopOrderTrailing
+		}
+		return false, 0
+	}
+
+	if record.Best == 0 || price < record.Best {
+		record.Best = price
+	}
+	switch {
+	case record.StopLoss > 0 && price >= record.Entry*(1+record.StopLoss):
+		return true, Exchange.StopOrderLoss
+	case record.TakeProfit > 0 && price <= record.Entry*(1-record.TakeProfit):
+		return true, Exchange.StopOrderProfit
+	case record.Trailing > 0 && price >= record.Best*(1+record.Trailing):
+		return true, Exchange.StopOrderTrailing
+	}
+	return false, 0
+}
+
+// exchangeOf the exchange of the venue is created once, the exchange of the task is used if it fails. The exchange isn't
+// wrapped by the risk gateway, as the closing orders of the protections are placed even if the kill switch is engaged
+func (p *Protector) exchangeOf(record *Mongo.ProtectionRecord) Exchange.IExchange {
+	key := record.Source + ":" + record.Venue + ":" + record.Account + ":" + record.Proxy
+
+	p.lock.Lock()
+	exchange := p.exchanges[key]
+	lookup := p.venues[record.Source]
+	fallback := p.fallbacks[record.ID]
+	p.lock.Unlock()
+
+	if exchange != nil {
+		return exchange
+	}
+
+	var venue *ProtectionVenue
+	if lookup != nil {
+		venue = lookup(record.Venue)
+	}
+	if venue == nil {
+		return fallback
+	}
+
+	mongo := new(Mongo.ExchangeDB)
+	if err := mongo.Connect(); err != nil {
+		return fallback
+	}
+	defer mongo.Close()
+	err, keys := mongo.FindAccount(venue.Exchange, record.Account)
+	if err != nil {
+		Logger.Errorf("[%s]Fail to load the keys:%v", record.ID, err)
+		return fallback
+	}
+
+	exchange = venue.Create(Exchange.Config{
+		API:    string(keys.API),
+		Secret: string(keys.Secret),
+		Custom: record.Custom,
+		Proxy:  record.Proxy,
+	})
+	if err := exchange.Start(); err != nil {
+		Logger.Errorf("[%s]Fail to start the exchange:%v", record.ID, err)
+		return fallback
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.exchanges == nil {
+		p.exchanges = make(map[string]Exchange.IExchange)
+	}
+	if existing := p.exchanges[key]; existing != nil {
+		exchange.Close()
+		return existing
+	}
+	p.exchanges[key] = exchange
+	return exchange
+}
+
+// protectionPrice the last price of the ticker, or the close of the last kline if the ticker isn't supported
+func protectionPrice(exchange Exchange.IExchange, pair string) float64 {
+	if ticker := exchange.GetTicker(pair); ticker != nil && ticker.Last > 0 {
+		return ticker.Last
+	}
+	if klines := GlobalKlines.GetKline(exchange, pair, Exchange.KlinePeriod5Min, 2); len(klines) > 0 {
+		return klines[len(klines)-1].Close
+	}
+	return 0
+}
+
+// Check the local protections are closed by the orders, the native ones are marked as triggered after the exchange
+// reports the fills
+func (p *Protector) Check() {
+	p.lock.Lock()
+	var records []*Mongo.ProtectionRecord
+	for _, record := range p.records {
+		if record.Status == Mongo.ProtectionStatusActive {
+			records = append(records, record)
+		}
+	}
+	p.lock.Unlock()
+
+	for _, record := range records {
+		exchange := p.exchangeOf(record)
+		if exchange == nil {
+			continue
+		}
+		if record.Native {
+			p.checkNative(exchange, record)
+			continue
+		}
+
+		price := protectionPrice(exchange, record.Pair)
+		if price <= 0 {
+			continue
+		}
+
+		p.lock.Lock()
+		best := record.Best
+		triggered, stopType := evaluate(record, price)
+		moved := record.Best != best
+		p.lock.Unlock()
+
+		if !triggered {
+			if moved && record.Trailing > 0 {
+				p.save(record)
+			}
+			continue
+		}
+
+		Logger.Infof("[%s]触发%s 开仓价:%v 当前价:%v", record.ID, Exchange.StopOrderTypeString[stopType], record.Entry, price)
+		err := p.close(exchange, record, price)
+		if err != nil {
+			Logger.Errorf("[%s]Fail to close the position:%v", record.ID, err)
+		}
+
+		p.lock.Lock()
+		if err == nil {
+			record.Status = Mongo.ProtectionStatusTriggered
+		}
+		record.Message = Exchange.StopOrderTypeString[stopType]
+		p.lock.Unlock()
+		p.save(record)
+	}
+}
+
+// checkNative the record is triggered by the conditional order which is filled, the position is monitored locally if
+// all the orders are canceled or rejected by the exchange
+func (p *Protector) checkNative(exchange Exchange.IExchange, record *Mongo.ProtectionRecord) {
+	stop := stopOrders(exchange)
+	if stop == nil {
+		return
+	}
+
+	p.lock.Lock()
+	orders := make(map[string]string)
+	for name, orderID := range record.Orders {
+		orders[name] = orderID
+	}
+	p.lock.Unlock()
+
+	for name, orderID := range orders {
+		err, info := stop.GetStopOrder(record.Pair, orderID)
+		if err != nil {
+			Logger.Errorf("[%s]Fail to get the %s order:%v", record.ID, name, err)
+			continue
+		}
+
+		switch info.Status {
+		case Exchange.OrderStatusDone:
+			price := info.AvgPrice
+			if price <= 0 {
+				price = protectionPrice(exchange, record.Pair)
+			}
+			Logger.Infof("[%s]触发%s 开仓价:%v 成交价:%v", record.ID, name, record.Entry, price)
+			p.lock.Lock()
+			delete(record.Orders, name)
+			record.Status = Mongo.ProtectionStatusTriggered
+			record.Message = name
+			record.Price = price
+			p.lock.Unlock()
+			p.save(record)
+			return
+		case Exchange.OrderStatusCanceled, Exchange.OrderStatusRejected, Exchange.OrderStatusExpired:
+			Logger.Errorf("[%s]The %s order is closed by the exchange:%s", record.ID, name, Exchange.OrderStatusString[info.Status])
+			p.lock.Lock()
+			delete(record.Orders, name)
+			p.lock.Unlock()
+		}
+	}
+
+	p.lock.Lock()
+	local := len(record.Orders) == 0
+	if local {
+		record.Native = false
+	}
+	p.lock.Unlock()
+	if local {
+		Logger.Errorf("[%s]No conditional order is left, monitor it locally", record.ID)
+		p.save(record)
+	}
+}
+
+// close places the closing order and waits for it, the open order is canceled after protectionOrderWait. The amount
+// of the record is reduced by the fills, and it returns the error until the position is closed
+func (p *Protector) close(exchange Exchange.IExchange, record *Mongo.ProtectionRecord, price float64) error {
+	tradeType := Exchange.TradeType(record.CloseType)
+	batch := record.TradeID
+	if batch == "" {
+		batch = record.ID
+	}
+	config := Exchange.TradeConfig{
+		Batch:  batch,
+		Pair:   record.Pair,
+		Type:   tradeType,
+		Price:  price,
+		Amount: record.Amount,
+		Limit:  protectionSlippage,
+	}
+	execution := StartExecution(exchange, config)
+	config.Price = GetPlacedPrice(tradeType, price, protectionSlippage)
+	result := exchange.Trade(config)
+	if result == nil || result.Error != nil {
+		execution.Filled(0, 0)
+		if result == nil {
+			return errors.New(TaskErrorMsg[TaskUnableTrade])
+		}
+		return result.Error
+	}
+
+	info := waitClosingOrder(exchange, record.Pair, result)
+	var dealt, avgPrice float64
+	if info != nil {
+		dealt, avgPrice = info.DealAmount, info.AvgPrice
+	}
+	execution.Filled(dealt, avgPrice)
+	if avgPrice <= 0 {
+		avgPrice = config.Price
+	}
+
+	p.lock.Lock()
+	if dealt > 0 {
+		record.Price = (record.Price*record.Closed + avgPrice*dealt) / (record.Closed + dealt)
+		record.Closed += dealt
+		record.Amount -= dealt
+	}
+	left := record.Amount
+	p.lock.Unlock()
+
+	if left > protectionMinAmount {
+		return fmt.Errorf("The closing order is dealt %v, %v is left", dealt, left)
+	}
+	return nil
+}
+
+// waitClosingOrder the order is canceled if it's still open after protectionOrderWait, it returns the last status
+func waitClosingOrder(exchange Exchange.IExchange, pair string, result *Exchange.TradeResult) *Exchange.OrderInfo {
+	info := result.Info
+	if info != nil && info.Status == Exchange.OrderStatusDone {
+		return info
+	}
+	if result.OrderID == "" {
+		return info
+	}
+
+	query := func() {
+		if infos := exchange.GetOrderInfo(Exchange.OrderInfo{OrderID: result.OrderID, Pair: pair}); len(infos) > 0 {
+			info = &infos[0]
+		}
+	}
+	closed := func() bool {
+		if info == nil {
+			return false
+		}
+		switch info.Status {
+		case Exchange.OrderStatusDone, Exchange.OrderStatusCanceled, Exchange.OrderStatusRejected, Exchange.OrderStatusExpired:
+			return true
+		}
+		return false
+	}
+
+	time.Sleep(protectionOrderWait)
+	if query(); closed() {
+		return info
+	}
+	exchange.CancelOrder(Exchange.OrderInfo{OrderID: result.OrderID, Pair: pair})
+	query()
+	return info
+}
@@ -0,0 +1,245 @@
+package task
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+func TestEvaluate(t *testing.T) {
+	long := &Mongo.ProtectionRecord{CloseType: int(Exchange.TradeTypeCloseLong), Entry: 100, Best: 100, StopLoss: 0.05, TakeProfit: 0.2, Trailing: 0.1}
+	cases := []struct {
+		price     float64
+		triggered bool
+		stopType  Exchange.StopOrderType
+	}{
+		{101, false, 0},
+		{115, false, 0},
+		// the best price is 115
+		{103, true, Exchange.StopOrderTrailing},
+		{95, true, Exchange.StopOrderLoss},
+		{120, true, Exchange.StopOrderProfit},
+	}
+	for _, c := range cases {
+		if triggered, stopType := evaluate(long, c.price); triggered != c.triggered || stopType != c.stopType {
+			t.Errorf("%v got:%v %v", c, triggered, stopType)
+		}
+	}
+	if long.Best != 120 {
+		t.Errorf("Invalid best price:%v", long.Best)
+	}
+
+	short := &Mongo.ProtectionRecord{CloseType: int(Exchange.TradeTypeCloseShort), Entry: 100, Best: 100, StopLoss: 0.05}
+	if triggered, _ := evaluate(short, 104); triggered {
+		t.Errorf("The short position shouldn't be stopped")
+	}
+	if triggered, stopType := evaluate(short, 105); !triggered || stopType != Exchange.StopOrderLoss {
+		t.Errorf("The short position should be stopped")
+	}
+}
+
+func TestProtectionOrders(t *testing.T) {
+	record := &Mongo.ProtectionRecord{CloseType: int(Exchange.TradeTypeCloseShort), Entry: 100, StopLoss: 0.05, TakeProfit: 0.1, Trailing: 0.02}
+	orders := protectionOrders(record)
+	if len(orders) != 3 || math.Abs(orders[0].Trigger-105) > 1e-9 || math.Abs(orders[1].Trigger-90) > 1e-9 ||
+		orders[2].StopType != Exchange.StopOrderTrailing || orders[2].Callback != 0.02 || orders[2].Price != 100 {
+		t.Errorf("Invalid orders:%v", orders)
+	}
+}
+
+type stopExchange struct {
+	Exchange.IExchange
+	fail      Exchange.StopOrderType
+	placed    []Exchange.StopOrderConfig
+	cancelled []string
+	price     float64
+	trades    []Exchange.TradeConfig
+	// stops the status of the conditional orders
+	stops map[string]Exchange.OrderInfo
+	// fill the ratio of the closing orders which is dealt
+	fill float64
+}
+
+func (s *stopExchange) PlaceStopOrder(config Exchange.StopOrderConfig) (error, string) {
+	if config.StopType == s.fail {
+		return errors.New("not supported"), ""
+	}
+	s.placed = append(s.placed, config)
+	return nil, Exchange.StopOrderTypeString[config.StopType]
+}
+
+func (s *stopExchange) CancelStopOrder(pair string, orderID string) error {
+	s.cancelled = append(s.cancelled, orderID)
+	return nil
+}
+
+func (s *stopExchange) GetStopOrder(pair string, orderID string) (error, *Exchange.OrderInfo) {
+	info, ok := s.stops[orderID]
+	if !ok {
+		return nil, &Exchange.OrderInfo{OrderID: orderID, Status: Exchange.OrderStatusOpen}
+	}
+	return nil, &info
+}
+
+func (s *stopExchange) GetExchangeName() string {
+	return "stop"
+}
+
+func (s *stopExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return nil
+}
+
+func (s *stopExchange) GetTicker(pair string) *Exchange.TickerValue {
+	return &Exchange.TickerValue{Last: s.price}
+}
+
+// Trade the closing orders are dealt at the last price by the ratio, the rest is left open
+func (s *stopExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	s.trades = append(s.trades, configs)
+	info := &Exchange.OrderInfo{
+		OrderID:    strconv.Itoa(len(s.trades)),
+		Pair:       configs.Pair,
+		Amount:     configs.Amount,
+		DealAmount: configs.Amount * s.fill,
+		AvgPrice:   s.price,
+		Status:     Exchange.OrderStatusOpen,
+	}
+	if s.fill >= 1 {
+		info.Status = Exchange.OrderStatusDone
+	}
+	return &Exchange.TradeResult{OrderID: info.OrderID, Info: info}
+}
+
+func (s *stopExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	return nil
+}
+
+func (s *stopExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	s.cancelled = append(s.cancelled, order.OrderID)
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+func TestAttach(t *testing.T) {
+	protector := new(Protector)
+	defer protector.Stop()
+	rule := ProtectionRule{StopLoss: 0.05, TakeProfit: 0.1}
+
+	exchange := &stopExchange{fail: Exchange.StopOrderTrailing}
+	record := &Mongo.ProtectionRecord{ID: "native", Pair: "btc/usd", CloseType: int(Exchange.TradeTypeCloseLong), Amount: 10, Entry: 100}
+	protector.Attach(&RiskGateway{IExchange: exchange}, record, rule)
+	if !record.Native || len(record.Orders) != 2 || len(exchange.placed) != 2 {
+		t.Fatalf("The orders should be placed natively:%v", record)
+	}
+
+	// the price crosses the trigger, but the record isn't triggered until the exchange fills the order
+	exchange.price = 94
+	protector.Check()
+	if triggered, _ := protector.Triggered("native"); triggered {
+		t.Fatalf("The native protection shouldn't be triggered by the price")
+	}
+
+	// the exchange fills the native order, the rest are cancelled after the task closes the record
+	exchange.stops = map[string]Exchange.OrderInfo{
+		"stoploss": {OrderID: "stoploss", Status: Exchange.OrderStatusDone, DealAmount: 10, AvgPrice: 94.5},
+	}
+	protector.Check()
+	if triggered, price := protector.Triggered("native"); !triggered || price != 94.5 || len(exchange.trades) != 0 {
+		t.Errorf("The native protection should be triggered:%v %v", price, exchange.trades)
+	}
+	protector.Detach(exchange, "native")
+	if len(exchange.cancelled) != 1 || exchange.cancelled[0] != "takeprofit" || len(protector.Records()) != 0 {
+		t.Errorf("The rest of the orders should be cancelled:%v", exchange.cancelled)
+	}
+
+	// the orders canceled by the exchange are monitored locally
+	exchange = &stopExchange{fail: Exchange.StopOrderTrailing}
+	record = &Mongo.ProtectionRecord{ID: "canceled", Pair: "btc/usd", CloseType: int(Exchange.TradeTypeCloseLong), Amount: 10, Entry: 100}
+	protector.Attach(exchange, record, rule)
+	exchange.stops = map[string]Exchange.OrderInfo{
+		"stoploss":   {Status: Exchange.OrderStatusCanceled},
+		"takeprofit": {Status: Exchange.OrderStatusRejected},
+	}
+	protector.Check()
+	if record.Native || len(record.Orders) != 0 {
+		t.Errorf("The record should be monitored locally:%v", record)
+	}
+	protector.Detach(exchange, "canceled")
+
+	// the trailing stop isn't supported, the placed orders are cancelled and the position is monitored locally
+	exchange = &stopExchange{fail: Exchange.StopOrderTrailing, price: 100, fill: 1}
+	record = &Mongo.ProtectionRecord{ID: "local", Pair: "btc/usd", CloseType: int(Exchange.TradeTypeCloseLong), Amount: 10, Entry: 100}
+	protector.Attach(exchange, record, ProtectionRule{StopLoss: 0.05, Trailing: 0.02})
+	if record.Native || len(exchange.cancelled) != 1 {
+		t.Fatalf("The position should be monitored locally:%v", record)
+	}
+	protector.Check()
+	exchange.price = 110
+	protector.Check()
+	if triggered, _ := protector.Triggered("local"); triggered || record.Best != 110 {
+		t.Fatalf("The best price should be updated:%v", record)
+	}
+	exchange.price = 107.5
+	protector.Check()
+	if triggered, price := protector.Triggered("local"); !triggered || price != 107.5 || len(exchange.trades) != 1 ||
+		exchange.trades[0].Type != Exchange.TradeTypeCloseLong || exchange.trades[0].Amount != 10 {
+		t.Errorf("The position should be closed:%v", exchange.trades)
+	}
+
+	if protector.Attach(exchange, &Mongo.ProtectionRecord{ID: "none"}, ProtectionRule{}); len(protector.Records()) != 1 {
+		t.Errorf("The disabled rule shouldn't be attached")
+	}
+}
+
+func TestCloseLeft(t *testing.T) {
+	protectionOrderWait = time.Millisecond
+	defer func() { protectionOrderWait = 2 * time.Second }()
+
+	protector := new(Protector)
+	defer protector.Stop()
+	exchange := &stopExchange{price: 100, fill: 0.4}
+	record := &Mongo.ProtectionRecord{ID: "left", Pair: "btc/usd", CloseType: int(Exchange.TradeTypeCloseLong), Amount: 10, Entry: 100}
+	protector.Attach(exchange, record, ProtectionRule{StopLoss: 0.05})
+
+	// the open part of the closing order is canceled, the record is kept active for the rest
+	exchange.price = 94
+	protector.Check()
+	if triggered, _ := protector.Triggered("left"); triggered || record.Amount != 6 || record.Closed != 4 || record.Price != 94 {
+		t.Fatalf("The rest of the position should be protected:%v", record)
+	}
+	if len(exchange.cancelled) != 1 || exchange.cancelled[0] != "1" {
+		t.Errorf("The open order should be canceled:%v", exchange.cancelled)
+	}
+
+	exchange.price = 93
+	exchange.fill = 1
+	protector.Check()
+	if triggered, price := protector.Triggered("left"); !triggered || len(exchange.trades) != 2 || exchange.trades[1].Amount != 6 ||
+		math.Abs(price-(94*4+93*6)/10.0) > 1e-9 {
+		t.Errorf("The position should be closed:%v %v", price, exchange.trades)
+	}
+}
+
+func TestResize(t *testing.T) {
+	protector := new(Protector)
+	defer protector.Stop()
+	exchange := &stopExchange{fail: Exchange.StopOrderTrailing}
+	record := &Mongo.ProtectionRecord{ID: "resize", Pair: "btc/usd", CloseType: int(Exchange.TradeTypeCloseLong), Amount: 10, Entry: 100}
+	protector.Attach(exchange, record, ProtectionRule{StopLoss: 0.05, TakeProfit: 0.1})
+
+	// the orders are placed again with the amount left
+	if err := protector.Resize(exchange, "resize", 4); err != nil {
+		t.Fatalf("Fail to resize:%v", err)
+	}
+	if record.Amount != 4 || !record.Native || len(exchange.cancelled) != 2 || len(exchange.placed) != 4 ||
+		exchange.placed[2].Amount != 4 || exchange.placed[3].Amount != 4 {
+		t.Errorf("Invalid orders after resizing:%v %v", exchange.cancelled, exchange.placed)
+	}
+	if err := protector.Resize(exchange, "unknown", 1); err != nil {
+		t.Errorf("The unknown record should be ignored:%v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+/*
+	仓位核对：任务启动前对比Mongo中保存的仓位、余额与交易所实际的持仓、余额、挂单，
+	存在差异时阻止任务启动，直到差异被确认或者被接管（孤儿仓位、订单写入任务的记录）
+*/
+
+// ReconcileState the positions are signed, the balances are the minimum amounts the positions require
+type ReconcileState struct {
+	Positions map[string]float64
+	Balances  map[string]float64
+	Orders    []Exchange.OrderInfo
+}
+
+// ReconcileFunc compares the records of the task with the exchange, the discrepancies are adopted into the
+// records if adopt is set, and the adopted ones are returned
+type ReconcileFunc func(adopt bool) (err error, items []Mongo.ReconcileItem, adopted []Mongo.ReconcileItem)
+
+// ReconcileError the task is blocked by the discrepancies
+type ReconcileError struct {
+	Task  string
+	Items []Mongo.ReconcileItem
+}
+
+func (e *ReconcileError) Error() string {
+	return fmt.Sprintf("%s[%s]:%d discrepancies", TaskErrorMsg[TaskUnreconciled], e.Task, len(e.Items))
+}
+
+// Compare the differences less than the tolerance are ignored
+func Compare(exchange string, saved ReconcileState, actual ReconcileState, tolerance float64) []Mongo.ReconcileItem {
+	var items []Mongo.ReconcileItem
+
+	for _, pair := range unionKeys(saved.Positions, actual.Positions) {
+		s, a := saved.Positions[pair], actual.Positions[pair]
+		if math.Abs(s-a) <= tolerance {
+			continue
+		}
+		kind := Mongo.ReconcileAmount
+		if math.Abs(s) <= tolerance {
+			kind = Mongo.ReconcileOrphanPosition
+		}
+		items = append(items, Mongo.ReconcileItem{Kind: kind, Exchange: exchange, Pair: pair, Saved: s, Actual: a})
+	}
+
+	for _, coin := range unionKeys(saved.Balances, nil) {
+		s, a := saved.Balances[coin], actual.Balances[coin]
+		if a < s-tolerance {
+			items = append(items, Mongo.ReconcileItem{Kind: Mongo.ReconcileBalance, Exchange: exchange, Pair: coin, Saved: s, Actual: a})
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, order := range saved.Orders {
+		known[order.OrderID] = true
+	}
+	for _, order := range actual.Orders {
+		if !known[order.OrderID] {
+			items = append(items, Mongo.ReconcileItem{
+				Kind:     Mongo.ReconcileOrphanOrder,
+				Exchange: exchange,
+				Pair:     order.Pair,
+				Actual:   order.Amount - order.DealAmount,
+				OrderID:  order.OrderID,
+			})
+		}
+	}
+
+	return items
+}
+
+func unionKeys(a map[string]float64, b map[string]float64) []string {
+	var keys []string
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// OpenOrders returns nil if the exchange can't list the open orders
+func OpenOrders(exchange Exchange.IExchange, pair string) (error, []Exchange.OrderInfo) {
+	if gateway, ok := exchange.(*RiskGateway); ok {
+		exchange = gateway.IExchange
+	}
+	if lister, ok := exchange.(Exchange.IOpenOrders); ok {
+		return lister.GetOpenOrders(pair)
+	}
+	return nil, nil
+}
+
+// Reconciler keeps the reports of the tasks
+type Reconciler struct {
+	lock sync.Mutex
+	db   *Mongo.Reconciliations
+}
+
+// GlobalReconciler the tasks are checked by it before they're started
+var GlobalReconciler = new(Reconciler)
+
+// SetDB the tasks aren't blocked until the DB is assigned
+func (r *Reconciler) SetDB(db *Mongo.Reconciliations) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.db = db
+}
+
+func (r *Reconciler) getDB() *Mongo.Reconciliations {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.db
+}
+
+// Check runs the reconciliation of the task and saves the report, the task is allowed to start if there is no
+// discrepancy, or the same discrepancies are acknowledged
+func (r *Reconciler) Check(task string, reconcile ReconcileFunc) error {
+	db := r.getDB()
+	if db == nil {
+		Logger.Infof("[%s]The reconciliation isn't saved, skip it", task)
+		return nil
+	}
+
+	err, last := db.Load(task)
+	if err != nil {
+		return err
+	}
+
+	adopt := last != nil && last.Adopt
+	err, items, adopted := reconcile(adopt)
+	if err != nil {
+		return err
+	}
+
+	record := &Mongo.ReconcileRecord{
+		Task:    task,
+		Time:    time.Now(),
+		Items:   items,
+		Adopted: adopted,
+	}
+	allowed := len(items) == 0
+	if !allowed && last != nil && last.Acknowledged {
+		tolerance := last.Tolerance
+		if tolerance <= 0 {
+			tolerance = reconcileAckTolerance
+		}
+		if sameItems(last.Items, items, tolerance) {
+			// the acknowledgement is kept until the discrepancies are changed
+			record.Acknowledged = true
+			record.Tolerance = last.Tolerance
+			allowed = true
+		}
+	}
+
+	if err := db.Save(record); err != nil {
+		return err
+	}
+
+	if !allowed {
+		for _, item := range items {
+			Logger.Errorf("[%s]Discrepancy:%v", task, item)
+		}
+		return &ReconcileError{Task: task, Items: items}
+	}
+	return nil
+}
+
+// reconcileAckTolerance the default ratio the amounts of the acknowledged discrepancies may change, e.g. the balance is
+// changed by the fees and the funding
+const reconcileAckTolerance = 0.05
+
+func reconcileKey(item Mongo.ReconcileItem) string {
+	return item.Kind + ":" + item.Exchange + ":" + item.Pair + ":" + item.OrderID
+}
+
+func closeAmount(a float64, b float64, tolerance float64) bool {
+	return math.Abs(a-b) <= math.Max(math.Max(math.Abs(a), math.Abs(b))*tolerance, 1e-8)
+}
+
+// sameItems the discrepancies are the same if they're of the same items, and the amounts are changed within the
+// tolerance ratio
+func sameItems(a []Mongo.ReconcileItem, b []Mongo.ReconcileItem, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	items := make(map[string]Mongo.ReconcileItem)
+	for _, item := range a {
+		items[reconcileKey(item)] = item
+	}
+	for _, item := range b {
+		last, ok := items[reconcileKey(item)]
+		if !ok || !closeAmount(last.Saved, item.Saved, tolerance) || !closeAmount(last.Actual, item.Actual, tolerance) {
+			return false
+		}
+		delete(items, reconcileKey(item))
+	}
+	return len(items) == 0
+}
+
+// Acknowledge the task is allowed to start with the reported discrepancies, or they're adopted at the next start. The
+// acknowledgement is kept while the amounts change within the tolerance ratio, 0 is the default ratio
+func (r *Reconciler) Acknowledge(task string, adopt bool, tolerance float64) error {
+	if tolerance < 0 || tolerance >= 1 {
+		return errors.New(TaskErrorMsg[TaskInvalidInput])
+	}
+
+	db := r.getDB()
+	if db == nil {
+		return errors.New(Mongo.ErrorNotConnected)
+	}
+
+	err, record := db.Load(task)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return errors.New(TaskErrorMsg[TaskNotFound])
+	}
+
+	if adopt {
+		record.Adopt = true
+	} else {
+		record.Acknowledged = true
+		record.Tolerance = tolerance
+	}
+	return db.Save(record)
+}
+
+// Records the last reports of all the tasks
+func (r *Reconciler) Records() (error, []Mongo.ReconcileRecord) {
+	db := r.getDB()
+	if db == nil {
+		return errors.New(Mongo.ErrorNotConnected), nil
+	}
+	return db.FindAll()
+}
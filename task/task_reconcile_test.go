@@ -0,0 +1,103 @@
+package task
+
+import (
+	"reflect"
+	"testing"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+func TestCompare(t *testing.T) {
+	saved := ReconcileState{
+		Positions: map[string]float64{"btc/usdt": -10, "eth/usdt": 5},
+		Balances:  map[string]float64{"eth": 2},
+		Orders:    []Exchange.OrderInfo{{OrderID: "1"}},
+	}
+	actual := ReconcileState{
+		Positions: map[string]float64{"btc/usdt": -10, "eth/usdt": 3, "ltc/usdt": 1},
+		Balances:  map[string]float64{"eth": 1.5, "usdt": 100},
+		Orders:    []Exchange.OrderInfo{{OrderID: "1"}, {OrderID: "2", Pair: "eth/usdt", Amount: 3, DealAmount: 1}},
+	}
+
+	items := Compare("okex", saved, actual, 1e-6)
+	expect := []Mongo.ReconcileItem{
+		{Kind: Mongo.ReconcileAmount, Exchange: "okex", Pair: "eth/usdt", Saved: 5, Actual: 3},
+		{Kind: Mongo.ReconcileOrphanPosition, Exchange: "okex", Pair: "ltc/usdt", Actual: 1},
+		{Kind: Mongo.ReconcileBalance, Exchange: "okex", Pair: "eth", Saved: 2, Actual: 1.5},
+		{Kind: Mongo.ReconcileOrphanOrder, Exchange: "okex", Pair: "eth/usdt", Actual: 2, OrderID: "2"},
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("Invalid items:%v", items)
+	}
+
+	// the balance more than the positions require is fine
+	actual.Balances["eth"] = 3
+	actual.Positions["eth/usdt"] = 5
+	actual.Positions["ltc/usdt"] = 0
+	actual.Orders = nil
+	if items = Compare("okex", saved, actual, 1e-6); len(items) != 0 {
+		t.Errorf("The states should be the same:%v", items)
+	}
+}
+
+func TestSameItems(t *testing.T) {
+	last := []Mongo.ReconcileItem{
+		{Kind: Mongo.ReconcileBalance, Exchange: "okex", Pair: "eth", Saved: 2, Actual: 1.5},
+		{Kind: Mongo.ReconcileOrphanOrder, Exchange: "okex", Pair: "eth/usdt", Actual: 2, OrderID: "2"},
+	}
+
+	// the balance is changed by the fees, and the order of the items isn't compared
+	items := []Mongo.ReconcileItem{
+		{Kind: Mongo.ReconcileOrphanOrder, Exchange: "okex", Pair: "eth/usdt", Actual: 2, OrderID: "2"},
+		{Kind: Mongo.ReconcileBalance, Exchange: "okex", Pair: "eth", Saved: 2, Actual: 1.4995},
+	}
+	if !sameItems(last, items, reconcileAckTolerance) {
+		t.Errorf("The small changes of the amounts should be the same")
+	}
+
+	items[1].Actual = 1
+	if sameItems(last, items, reconcileAckTolerance) {
+		t.Errorf("The large change of the balance should be different")
+	}
+	if !sameItems(last, items, 0.5) {
+		t.Errorf("The change within the configured tolerance should be the same")
+	}
+	items[1].Actual = 1.5
+	items[0].OrderID = "3"
+	if sameItems(last, items, reconcileAckTolerance) {
+		t.Errorf("The different orders should be different")
+	}
+}
+
+type openOrdersExchange struct {
+	Exchange.IExchange
+}
+
+func (o *openOrdersExchange) GetOpenOrders(pair string) (error, []Exchange.OrderInfo) {
+	return nil, []Exchange.OrderInfo{{Pair: pair, OrderID: "1"}}
+}
+
+func TestOpenOrders(t *testing.T) {
+	gateway := &RiskGateway{IExchange: new(openOrdersExchange)}
+	if err, orders := OpenOrders(gateway, "eth/usdt"); err != nil || len(orders) != 1 {
+		t.Errorf("The orders should be listed through the gateway:%v %v", err, orders)
+	}
+	if err, orders := OpenOrders(new(RiskGateway), "eth/usdt"); err != nil || orders != nil {
+		t.Errorf("The exchange can't list the orders:%v %v", err, orders)
+	}
+}
+
+func TestCheckWithoutDB(t *testing.T) {
+	reconciler := new(Reconciler)
+	called := false
+	if err := reconciler.Check("trend", func(adopt bool) (error, []Mongo.ReconcileItem, []Mongo.ReconcileItem) {
+		called = true
+		return nil, nil, nil
+	}); err != nil || called {
+		t.Errorf("The reconciliation should be skipped without the DB:%v", err)
+	}
+	if err := reconciler.Acknowledge("trend", false, 0); err == nil {
+		t.Errorf("The acknowledgement should fail without the DB")
+	}
+}
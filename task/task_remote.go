@@ -0,0 +1,439 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kataras/golog"
+	"google.golang.org/grpc"
+
+	Global "madaoQT/config"
+	Rpc "madaoQT/gprc"
+	Mongo "madaoQT/mongo"
+)
+
+/*
+	策略进程：独立运行的策略通过gRPC注册到主程序，注册后和内置策略一样由TaskManager管理
+	策略进程定期重新注册作为心跳，进程重启后地址变化时重新连接
+*/
+
+const rpcTimeout = 10 * time.Second
+
+// statusTimeout the status is checked by the supervisor with the lock of the manager
+const statusTimeout = 3 * time.Second
+
+// RegistryExpire the strategy process is offline if it doesn't register again in the duration
+const RegistryExpire = 30 * time.Second
+
+const logRetryPeriod = 5 * time.Second
+
+type remoteStrategy struct {
+	lock     sync.RWMutex
+	request  *Rpc.RegisterRequest
+	schema   *Schema
+	conn     *grpc.ClientConn
+	client   Rpc.StrategyClient
+	cancel   context.CancelFunc
+	lastSeen time.Time
+}
+
+// getClient returns nil if the strategy process is offline
+func (s *remoteStrategy) getClient() Rpc.StrategyClient {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.client == nil || time.Since(s.lastSeen) > RegistryExpire {
+		return nil
+	}
+	return s.client
+}
+
+func (s *remoteStrategy) getRequest() *Rpc.RegisterRequest {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.request
+}
+
+// connect dials the strategy process and forwards its logs
+func (s *remoteStrategy) connect(request *Rpc.RegisterRequest, schema *Schema) error {
+	conn, err := grpc.Dial(request.Address, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+
+	s.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := Rpc.NewStrategyClient(conn)
+
+	s.lock.Lock()
+	s.request = request
+	s.schema = schema
+	s.conn = conn
+	s.client = client
+	s.cancel = cancel
+	s.lastSeen = time.Now()
+	s.lock.Unlock()
+
+	go s.forwardLogs(ctx, request.Name, client)
+	return nil
+}
+
+// touch updates the registration of the connected strategy process
+func (s *remoteStrategy) touch(request *Rpc.RegisterRequest, schema *Schema) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.request = request
+	s.schema = schema
+	s.lastSeen = time.Now()
+}
+
+func (s *remoteStrategy) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.client = nil
+}
+
+func (s *remoteStrategy) forwardLogs(ctx context.Context, name string, client Rpc.StrategyClient) {
+	logger := golog.New()
+	logger.SetLevel("debug")
+	logger.SetTimeFormat(Global.TimeFormat)
+	logger.SetPrefix("[" + name + "]")
+
+	for {
+		stream, err := client.Logs(ctx, &Rpc.LogsRequest{})
+		if err == nil {
+			for {
+				entry, err := stream.Recv()
+				if err != nil {
+					break
+				}
+				logger.Log(golog.Level(entry.Level), entry.Message)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(logRetryPeriod):
+		}
+	}
+}
+
+func rpcResult(result *Rpc.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if !result.Result {
+		return errors.New(result.Error)
+	}
+	return nil
+}
+
+// RemoteTask the proxy of the task instance in the strategy process
+type RemoteTask struct {
+	strategy  *remoteStrategy
+	namespace string
+}
+
+// instance the default instance uses the strategy name as the key in the strategy process
+func (r *RemoteTask) instance() string {
+	if r.namespace == "" {
+		return r.strategy.getRequest().Name
+	}
+	return r.namespace
+}
+
+func (r *RemoteTask) SetNamespace(namespace string) {
+	r.namespace = namespace
+}
+
+func (r *RemoteTask) GetConfigSchema() *Schema {
+	r.strategy.lock.RLock()
+	defer r.strategy.lock.RUnlock()
+	return r.strategy.schema
+}
+
+func (r *RemoteTask) GetDefaultConfig() interface{} {
+	if config := r.strategy.getRequest().DefaultConfig; config != "" {
+		return json.RawMessage(config)
+	}
+	return nil
+}
+
+func (r *RemoteTask) GetDescription() Description {
+	request := r.strategy.getRequest()
+	return Description{
+		Name:  request.Name,
+		Title: request.Title,
+		Desc:  request.Desc,
+	}
+}
+
+func (r *RemoteTask) Start(configJSON string) error {
+	client := r.strategy.getClient()
+	if client == nil {
+		return errors.New(TaskErrorMsg[TaskLostStrategy])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	return rpcResult(client.Start(ctx, &Rpc.ConfigRequest{
+		Instance:  r.instance(),
+		Namespace: r.namespace,
+		Config:    configJSON,
+	}))
+}
+
+func (r *RemoteTask) Close() {
+	client := r.strategy.getClient()
+	if client == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	if err := rpcResult(client.Stop(ctx, &Rpc.InstanceRequest{Instance: r.instance()})); err != nil {
+		Logger.Errorf("Fail to stop the remote task %s:%v", r.instance(), err)
+	}
+}
+
+func (r *RemoteTask) UpdateConfig(configJSON string) error {
+	client := r.strategy.getClient()
+	if client == nil {
+		return errors.New(TaskErrorMsg[TaskLostStrategy])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	return rpcResult(client.UpdateConfig(ctx, &Rpc.ConfigRequest{
+		Instance:  r.instance(),
+		Namespace: r.namespace,
+		Config:    configJSON,
+	}))
+}
+
+// GetStatus the task is regarded as error if the strategy process is offline
+func (r *RemoteTask) GetStatus() StatusType {
+	client := r.strategy.getClient()
+	if client == nil {
+		return StatusError
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	response, err := client.Status(ctx, &Rpc.InstanceRequest{Instance: r.instance()})
+	if err != nil {
+		Logger.Errorf("Fail to get the status of the remote task %s:%v", r.instance(), err)
+		return StatusError
+	}
+
+	return StatusType(response.Status)
+}
+
+func (r *RemoteTask) positions() *Rpc.PositionsResponse {
+	client := r.strategy.getClient()
+	if client == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	response, err := client.Positions(ctx, &Rpc.InstanceRequest{Instance: r.instance()})
+	if err != nil {
+		Logger.Errorf("Fail to get the positions of the remote task %s:%v", r.instance(), err)
+		return nil
+	}
+	return response
+}
+
+func (r *RemoteTask) GetBalances() map[string]interface{} {
+	var balances map[string]interface{}
+	if response := r.positions(); response != nil && response.Balances != "" {
+		json.Unmarshal([]byte(response.Balances), &balances)
+	}
+	return balances
+}
+
+func (r *RemoteTask) GetPositions() []map[string]interface{} {
+	var positions []map[string]interface{}
+	if response := r.positions(); response != nil && response.Positions != "" {
+		json.Unmarshal([]byte(response.Positions), &positions)
+	}
+	return positions
+}
+
+func (r *RemoteTask) GetFailedPositions() []map[string]interface{} {
+	var positions []map[string]interface{}
+	if response := r.positions(); response != nil && response.Failed != "" {
+		json.Unmarshal([]byte(response.Failed), &positions)
+	}
+	return positions
+}
+
+func (r *RemoteTask) GetTrades() []Mongo.TradesRecord {
+	return nil
+}
+
+func (r *RemoteTask) FixFailedPosition(updateJSON string) error {
+	return errors.New(TaskErrorMsg[TaskNotSupported])
+}
+
+func (r *RemoteTask) ForceClosePositions() {
+	Logger.Errorf("The remote task %s doesn't support to close the positions", r.instance())
+}
+
+// Registry accepts the registration of the strategy processes, and registers the strategies to the manager
+type Registry struct {
+	Manager *TaskManager
+
+	lock       sync.Mutex
+	strategies map[string]*remoteStrategy
+	server     *grpc.Server
+	listener   net.Listener
+}
+
+// Serve starts the registry in the background
+func (r *Registry) Serve(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	r.listener = listener
+	r.server = grpc.NewServer()
+	Rpc.RegisterRegistryServer(r.server, r)
+
+	go func() {
+		if err := r.server.Serve(listener); err != nil {
+			Logger.Errorf("Registry exits:%v", err)
+		}
+	}()
+
+	Logger.Infof("Registry is listening on %s", r.Addr())
+	return nil
+}
+
+// Addr returns the address which the registry is listening on
+func (r *Registry) Addr() string {
+	if r.listener == nil {
+		return ""
+	}
+	return r.listener.Addr().String()
+}
+
+// Stop closes the registry and the connections of the strategy processes
+func (r *Registry) Stop() {
+	if r.server != nil {
+		r.server.Stop()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, strategy := range r.strategies {
+		strategy.close()
+	}
+}
+
+func (r *Registry) Register(ctx context.Context, request *Rpc.RegisterRequest) (*Rpc.Result, error) {
+	if request.Name == "" || request.Address == "" {
+		return &Rpc.Result{Error: TaskErrorMsg[TaskInvalidInput]}, nil
+	}
+
+	var schema *Schema
+	if request.Schema != "" {
+		schema = new(Schema)
+		if err := json.Unmarshal([]byte(request.Schema), schema); err != nil {
+			return &Rpc.Result{Error: TaskErrorMsg[TaskInvalidConfig]}, nil
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.strategies == nil {
+		r.strategies = make(map[string]*remoteStrategy)
+	}
+
+	strategy := r.strategies[request.Name]
+	if strategy == nil {
+		// the name is used by the built-in strategy
+		if err, _ := r.Manager.Schema(request.Name); err == nil {
+			return &Rpc.Result{Error: TaskErrorMsg[TaskInvalidInput]}, nil
+		}
+
+		strategy = new(remoteStrategy)
+		if err := strategy.connect(request, schema); err != nil {
+			return &Rpc.Result{Error: err.Error()}, nil
+		}
+
+		if err := r.Manager.Register(func() ITask {
+			return &RemoteTask{strategy: strategy}
+		}); err != nil {
+			strategy.close()
+			return &Rpc.Result{Error: err.Error()}, nil
+		}
+
+		r.strategies[request.Name] = strategy
+		Logger.Infof("Strategy %s registers from %s(pid:%d)", request.Name, request.Address, request.Pid)
+		return &Rpc.Result{Result: true}, nil
+	}
+
+	if strategy.getClient() == nil || strategy.getRequest().Address != request.Address {
+		Logger.Infof("Strategy %s reconnects to %s(pid:%d)", request.Name, request.Address, request.Pid)
+		if err := strategy.connect(request, schema); err != nil {
+			return &Rpc.Result{Error: err.Error()}, nil
+		}
+	} else {
+		strategy.touch(request, schema)
+	}
+
+	return &Rpc.Result{Result: true}, nil
+}
+
+// Unregister the instances of the strategy are kept, and restarted after the strategy process registers again
+func (r *Registry) Unregister(ctx context.Context, request *Rpc.UnregisterRequest) (*Rpc.Result, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	strategy := r.strategies[request.Name]
+	if strategy == nil || strategy.getRequest().Address != request.Address {
+		return &Rpc.Result{Error: TaskErrorMsg[TaskNotFound]}, nil
+	}
+
+	strategy.close()
+	Logger.Infof("Strategy %s unregisters from %s", request.Name, request.Address)
+	return &Rpc.Result{Result: true}, nil
+}
+
+// Shutdown asks the strategy process to exit
+func (r *Registry) Shutdown(name string) error {
+	r.lock.Lock()
+	strategy := r.strategies[name]
+	r.lock.Unlock()
+
+	if strategy == nil {
+		return errors.New(TaskErrorMsg[TaskNotFound])
+	}
+
+	client := strategy.getClient()
+	if client == nil {
+		return errors.New(TaskErrorMsg[TaskLostStrategy])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	return rpcResult(client.Shutdown(ctx, &Rpc.Empty{}))
+}
@@ -0,0 +1,641 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+)
+
+/*
+	交易前风控：RiskGateway包装IExchange，在Trade()之前检查数量、金额、价格偏离、挂单数、持仓和当日亏损，
+	拒绝的订单返回RiskError并保存到Mongo。持仓和盈亏按经过网关的成交计算，成交由Trade()和GetOrderInfo()的返回值更新，
+	任务启动前的持仓由AddPosition()或SeedPositions()导入。合约的金额和盈亏按合约面值计算，反向合约按币本位换算成计价货币
+	熔断：当日亏损或一分钟内的下单、撤单失败次数达到阈值时触发全局熔断(GlobalKillSwitch)
+*/
+
+// RiskRule the pre-trade check which rejects the order
+type RiskRule string
+
+const (
+	RiskOrderSize   RiskRule = "ordersize"
+	RiskNotional    RiskRule = "notional"
+	RiskPriceCollar RiskRule = "pricecollar"
+	RiskMarketData  RiskRule = "marketdata"
+	RiskOpenOrders  RiskRule = "openorders"
+	RiskPosition    RiskRule = "position"
+	RiskDailyLoss   RiskRule = "dailyloss"
+	RiskKillSwitch  RiskRule = "killswitch"
+)
+
+// RiskConfig the limits of the task, 0 disables the check. The amounts are in the unit of the exchange, e.g. the
+// contracts of the futures, the notional and the loss are in the quote currency
+type RiskConfig struct {
+	MaxOrderSize  float64 `json:"maxordersize" title:"单笔最大数量" desc:"0表示不限制" min:"0"`
+	MaxNotional   float64 `json:"maxnotional" title:"单笔最大金额" desc:"价格乘以数量，合约按面值计算，0表示不限制" min:"0"`
+	PriceCollar   float64 `json:"pricecollar" title:"价格偏离上限" desc:"相对盘口中间价的比例，0表示不限制" min:"0" max:"1"`
+	MaxOpenOrders int     `json:"maxopenorders" title:"最大挂单数" desc:"0表示不限制" min:"0"`
+	MaxPosition   float64 `json:"maxposition" title:"单个交易对最大持仓" desc:"按任务的成交计算，0表示不限制" min:"0"`
+	MaxDailyLoss  float64 `json:"maxdailyloss" title:"单日最大亏损" desc:"达到后只允许减仓，0表示不限制" min:"0"`
+
+	BreakerLoss    float64 `json:"breakerloss" title:"熔断亏损" desc:"单日亏损达到后触发全局熔断，0表示不启用" min:"0"`
+	BreakerErrors  int     `json:"breakererrors" title:"熔断错误数" desc:"一分钟内下单或撤单失败的次数达到后触发全局熔断，0表示不启用" min:"0"`
+	BreakerFlatten bool    `json:"breakerflatten" title:"熔断时平仓" desc:"自动触发熔断时平掉所有任务的仓位"`
+}
+
+// RiskError the order is rejected by the rule, Value is the checked value and Limit is the configured limit
+type RiskError struct {
+	Rule  RiskRule
+	Limit float64
+	Value float64
+}
+
+func (e *RiskError) Error() string {
+	switch e.Rule {
+	case RiskMarketData:
+		return "Risk check failed: no market data for the price collar"
+	case RiskKillSwitch:
+		return "Risk check failed: the kill switch is engaged, only the reducing orders are accepted"
+	}
+	return fmt.Sprintf("Risk check failed: %s %v exceeds the limit %v", e.Rule, e.Value, e.Limit)
+}
+
+// IsRiskError returns the RiskError if the order is rejected by the gateway
+func IsRiskError(err error) (*RiskError, bool) {
+	riskError, ok := err.(*RiskError)
+	return riskError, ok
+}
+
+// RiskContract the futures whose amount is the number of contracts, Value is the face value of a contract. The value
+// of the inverse contracts is in the quote currency, otherwise in the base currency. The zero value is the spot
+type RiskContract struct {
+	Value   float64
+	Inverse bool
+}
+
+// InverseContract the inverse contracts of OKEX and Huobi, whose face value is 100 USD for BTC and 10 USD for the others
+func InverseContract(pair string) RiskContract {
+	return RiskContract{Value: Exchange.InverseContractValue(Exchange.ParsePair(pair)[0]), Inverse: true}
+}
+
+// USDContract the inverse contracts whose amount is in USD, e.g. Bitmex and Deribit
+func USDContract(pair string) RiskContract {
+	return RiskContract{Value: 1, Inverse: true}
+}
+
+// notional the value of the amount in the quote currency
+func (c RiskContract) notional(amount float64, price float64) float64 {
+	switch {
+	case c.Value == 0:
+		return amount * price
+	case c.Inverse:
+		return amount * c.Value
+	}
+	return amount * c.Value * price
+}
+
+// profit the profit of the long amount opened at the cost and closed at the price, in the quote currency. The profit of
+// the inverse contracts is in the base currency and is converted at the closing price
+func (c RiskContract) profit(amount float64, cost float64, price float64) float64 {
+	switch {
+	case c.Value == 0:
+		return amount * (price - cost)
+	case c.Inverse:
+		if cost <= 0 {
+			return 0
+		}
+		return amount * c.Value * (price - cost) / cost
+	}
+	return amount * c.Value * (price - cost)
+}
+
+// average the cost after the amount is added at the price, the cost of the inverse contracts is the harmonic mean
+func (c RiskContract) average(position float64, cost float64, amount float64, price float64) float64 {
+	if position <= 0 {
+		return price
+	}
+	if c.Inverse && cost > 0 && price > 0 {
+		return (position + amount) / (position/cost + amount/price)
+	}
+	return (cost*position + price*amount) / (position + amount)
+}
+
+type riskOrder struct {
+	pair string
+	side float64
+	// closing the futures closing the position are never limited by the position and the loss
+	closing   bool
+	price     float64
+	amount    float64
+	dealt     float64
+	canceling bool
+}
+
+type riskPosition struct {
+	amount float64
+	cost   float64
+}
+
+const riskMinQuantity = 1e-9
+
+// riskPlacingPrefix the key of the order which is being placed
+const riskPlacingPrefix = "placing:"
+
+// riskErrorWindow the failures are counted in the window for the breaker
+const riskErrorWindow = time.Minute
+
+// RiskGateway checks the orders of the task before they're sent to the exchange
+type RiskGateway struct {
+	Exchange.IExchange
+	// Task the instance recorded in the rejections
+	Task string
+	// DB saves the rejections if it's assigned
+	DB *Mongo.RiskRecords
+
+	lock      sync.Mutex
+	config    RiskConfig
+	contract  func(pair string) RiskContract
+	orders    map[string]*riskOrder
+	positions map[string]*riskPosition
+	day       time.Time
+	pnl       float64
+	// failures the times of the failed orders in the error window
+	failures []time.Time
+	// lossTripped the day when the breaker is tripped by the loss, it's tripped once a day
+	lossTripped time.Time
+	killSwitch  *KillSwitch
+}
+
+// NewRiskGateway the gateway is registered to GlobalKillSwitch until it's closed
+func NewRiskGateway(exchange Exchange.IExchange, task string, config RiskConfig, db *Mongo.RiskRecords) *RiskGateway {
+	gateway := &RiskGateway{
+		IExchange:  exchange,
+		Task:       task,
+		DB:         db,
+		config:     config,
+		orders:     make(map[string]*riskOrder),
+		positions:  make(map[string]*riskPosition),
+		killSwitch: GlobalKillSwitch,
+	}
+	gateway.killSwitch.register(gateway)
+	return gateway
+}
+
+// Close unregisters the gateway from the kill switch and closes the exchange
+func (g *RiskGateway) Close() {
+	g.killSwitch.unregister(g)
+	g.IExchange.Close()
+}
+
+// Unregister removes the gateway from the kill switch without closing the exchange, e.g. the exchange fails to start
+func (g *RiskGateway) Unregister() {
+	g.killSwitch.unregister(g)
+}
+
+// SetConfig updates the limits, the positions and the loss are kept
+func (g *RiskGateway) SetConfig(config RiskConfig) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.config = config
+}
+
+// SetContract the contracts of the pairs, the pairs are the spot if it isn't set
+func (g *RiskGateway) SetContract(contract func(pair string) RiskContract) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.contract = contract
+}
+
+// contractOf the lock is held
+func (g *RiskGateway) contractOf(pair string) RiskContract {
+	if g.contract == nil {
+		return RiskContract{}
+	}
+	return g.contract(pair)
+}
+
+// tradeSide the buying orders increase the position and the selling orders decrease it
+func tradeSide(tradeType Exchange.TradeType) float64 {
+	switch tradeType {
+	case Exchange.TradeTypeBuy, Exchange.TradeTypeOpenLong, Exchange.TradeTypeCloseShort:
+		return 1
+	}
+	return -1
+}
+
+func closingType(tradeType Exchange.TradeType) bool {
+	return tradeType == Exchange.TradeTypeCloseLong || tradeType == Exchange.TradeTypeCloseShort
+}
+
+// midPrice the middle of the best bid and ask
+func midPrice(depth [][]Exchange.DepthPrice) (float64, bool) {
+	if len(depth) != 2 || len(depth[Exchange.DepthTypeBids]) == 0 || len(depth[Exchange.DepthTypeAsks]) == 0 {
+		return 0, false
+	}
+	mid := (depth[Exchange.DepthTypeBids][0].Price + depth[Exchange.DepthTypeAsks][0].Price) / 2
+	return mid, mid > 0
+}
+
+// resetDay the loss is counted from the local midnight
+func (g *RiskGateway) resetDay(now time.Time) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !day.Equal(g.day) {
+		g.day = day
+		g.pnl = 0
+	}
+}
+
+// check returns the error if the order breaks any of the limits, the lock is held
+func (g *RiskGateway) check(configs Exchange.TradeConfig, mid float64, hasMid bool) *RiskError {
+	config := g.config
+	side := tradeSide(configs.Type)
+
+	if g.killSwitch.Engaged() && !g.reducing(configs) {
+		return &RiskError{Rule: RiskKillSwitch}
+	}
+
+	if config.MaxOrderSize > 0 && configs.Amount > config.MaxOrderSize {
+		return &RiskError{Rule: RiskOrderSize, Limit: config.MaxOrderSize, Value: configs.Amount}
+	}
+
+	if notional := g.contractOf(configs.Pair).notional(configs.Amount, configs.Price); config.MaxNotional > 0 && notional > config.MaxNotional {
+		return &RiskError{Rule: RiskNotional, Limit: config.MaxNotional, Value: notional}
+	}
+
+	if config.PriceCollar > 0 {
+		if !hasMid {
+			return &RiskError{Rule: RiskMarketData}
+		}
+		// only the aggressive side is limited, the passive orders far from the mid are harmless
+		if deviation := side * (configs.Price - mid) / mid; deviation > config.PriceCollar {
+			return &RiskError{Rule: RiskPriceCollar, Limit: config.PriceCollar, Value: deviation}
+		}
+	}
+
+	var open int
+	var pending float64
+	for _, order := range g.orders {
+		if order.canceling {
+			continue
+		}
+		open++
+		if order.pair == configs.Pair && order.side == side && !order.closing {
+			pending += order.amount - order.dealt
+		}
+	}
+	if config.MaxOpenOrders > 0 && open >= config.MaxOpenOrders {
+		return &RiskError{Rule: RiskOpenOrders, Limit: float64(config.MaxOpenOrders), Value: float64(open + 1)}
+	}
+
+	if closingType(configs.Type) {
+		return nil
+	}
+
+	var position float64
+	if p := g.positions[configs.Pair]; p != nil {
+		position = p.amount
+	}
+	after := position + side*(pending+configs.Amount)
+	increasing := math.Abs(position+side*configs.Amount) > math.Abs(position)
+
+	if config.MaxPosition > 0 && math.Abs(after) > config.MaxPosition && math.Abs(after) > math.Abs(position) {
+		return &RiskError{Rule: RiskPosition, Limit: config.MaxPosition, Value: math.Abs(after)}
+	}
+
+	g.resetDay(time.Now())
+	if config.MaxDailyLoss > 0 && -g.pnl >= config.MaxDailyLoss && increasing {
+		return &RiskError{Rule: RiskDailyLoss, Limit: config.MaxDailyLoss, Value: -g.pnl}
+	}
+
+	return nil
+}
+
+// reducing whether the order only reduces the position, the lock is held
+func (g *RiskGateway) reducing(configs Exchange.TradeConfig) bool {
+	if closingType(configs.Type) {
+		return true
+	}
+	var position float64
+	if p := g.positions[configs.Pair]; p != nil {
+		position = p.amount
+	}
+	return position*tradeSide(configs.Type) < 0 && configs.Amount <= math.Abs(position)+riskMinQuantity
+}
+
+func (g *RiskGateway) reject(configs Exchange.TradeConfig, riskError *RiskError) {
+	Logger.Warnf("[%s]风控拒绝订单 %s %s 价格:%v 数量:%v:%v", g.Task, configs.Pair,
+		Exchange.TradeTypeString[configs.Type], configs.Price, configs.Amount, riskError)
+
+	if g.DB != nil {
+		if err := g.DB.Insert(&Mongo.RiskRecord{
+			Task:     g.Task,
+			Exchange: g.GetExchangeName(),
+			Pair:     configs.Pair,
+			Oper:     Exchange.TradeTypeString[configs.Type],
+			Price:    configs.Price,
+			Quantity: configs.Amount,
+			Rule:     string(riskError.Rule),
+			Limit:    riskError.Limit,
+			Value:    riskError.Value,
+		}); err != nil {
+			Logger.Errorf("保存风控记录失败:%v", err)
+		}
+	}
+}
+
+// Trade rejects the order with RiskError in TradeResult.Error if any limit is broken
+func (g *RiskGateway) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	var mid float64
+	var hasMid bool
+	g.lock.Lock()
+	collar := g.config.PriceCollar
+	g.lock.Unlock()
+	if collar > 0 {
+		mid, hasMid = midPrice(g.IExchange.GetDepthValue(configs.Pair))
+	}
+
+	g.lock.Lock()
+	if riskError := g.check(configs, mid, hasMid); riskError != nil {
+		g.lock.Unlock()
+		g.reject(configs, riskError)
+		return &Exchange.TradeResult{Error: riskError}
+	}
+	// the order is counted before it's sent, so that the concurrent orders are checked with it
+	placing := &riskOrder{
+		pair:    configs.Pair,
+		side:    tradeSide(configs.Type),
+		closing: closingType(configs.Type),
+		price:   configs.Price,
+		amount:  configs.Amount,
+	}
+	key := fmt.Sprintf("%s%p", riskPlacingPrefix, placing)
+	g.orders[key] = placing
+	reducing := g.reducing(configs)
+	g.lock.Unlock()
+
+	result := g.IExchange.Trade(configs)
+
+	g.lock.Lock()
+	delete(g.orders, key)
+	if result != nil && result.Error == nil && result.OrderID != "" {
+		g.orders[result.OrderID] = placing
+	}
+	g.lock.Unlock()
+
+	if result == nil || result.Error != nil {
+		g.CountError()
+		return result
+	}
+
+	if result.Info != nil && result.OrderID != "" {
+		info := *result.Info
+		info.OrderID = result.OrderID
+		g.update(info)
+		g.breakers()
+	}
+
+	// the switch is engaged while the order is being placed
+	if !reducing && result.OrderID != "" && g.killSwitch.Engaged() {
+		g.CancelOrder(Exchange.OrderInfo{OrderID: result.OrderID, Pair: configs.Pair})
+	}
+	return result
+}
+
+// update records the fills of the order, the order is removed once it's closed
+func (g *RiskGateway) update(info Exchange.OrderInfo) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	order := g.orders[info.OrderID]
+	if order == nil {
+		return
+	}
+
+	if dealt := info.DealAmount - order.dealt; dealt > riskMinQuantity {
+		price := info.AvgPrice
+		if price <= 0 {
+			price = order.price
+		}
+		order.dealt = info.DealAmount
+		g.fill(order.pair, order.side, dealt, price, order.closing)
+	}
+
+	switch info.Status {
+	case Exchange.OrderStatusDone, Exchange.OrderStatusCanceled, Exchange.OrderStatusRejected, Exchange.OrderStatusExpired:
+		delete(g.orders, info.OrderID)
+	}
+}
+
+// fill updates the position with the average cost, the reduced part realizes the profit. The closing orders of the
+// positions which aren't opened through the gateway are ignored
+func (g *RiskGateway) fill(pair string, side float64, amount float64, price float64, closing bool) {
+	contract := g.contractOf(pair)
+	position := g.positions[pair]
+	if position == nil {
+		position = &riskPosition{}
+		g.positions[pair] = position
+	}
+
+	if position.amount*side >= 0 {
+		if closing {
+			return
+		}
+		position.cost = contract.average(math.Abs(position.amount), position.cost, amount, price)
+		position.amount += side * amount
+		return
+	}
+
+	closed := math.Min(amount, math.Abs(position.amount))
+	g.resetDay(time.Now())
+	g.pnl += contract.profit(closed, position.cost, price) * -side
+	position.amount += side * closed
+
+	if rest := amount - closed; rest > riskMinQuantity && !closing {
+		position.amount = side * rest
+		position.cost = price
+	} else if math.Abs(position.amount) <= riskMinQuantity {
+		position.amount = 0
+		position.cost = 0
+	}
+}
+
+// GetOrderInfo updates the fills of the orders placed through the gateway
+func (g *RiskGateway) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	orders := g.IExchange.GetOrderInfo(filter)
+	for _, info := range orders {
+		if info.OrderID == "" {
+			info.OrderID = filter.OrderID
+		}
+		g.update(info)
+	}
+	g.breakers()
+	return orders
+}
+
+// CancelOrder the order isn't counted as open after it's canceled, the fills are still recorded until it's closed
+func (g *RiskGateway) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	result := g.IExchange.CancelOrder(order)
+	if result == nil || result.Error != nil {
+		g.CountError()
+	} else {
+		g.lock.Lock()
+		if item := g.orders[order.OrderID]; item != nil {
+			item.canceling = true
+		}
+		g.lock.Unlock()
+	}
+	return result
+}
+
+// CancelAll cancels the open orders placed through the gateway
+func (g *RiskGateway) CancelAll() {
+	g.lock.Lock()
+	var orders []Exchange.OrderInfo
+	for id, order := range g.orders {
+		if order.canceling || strings.HasPrefix(id, riskPlacingPrefix) {
+			continue
+		}
+		orders = append(orders, Exchange.OrderInfo{OrderID: id, Pair: order.pair})
+	}
+	g.lock.Unlock()
+
+	for _, order := range orders {
+		if result := g.CancelOrder(order); result == nil || result.Error != nil {
+			Logger.Errorf("[%s]撤销订单%s失败:%v", g.Task, order.OrderID, result)
+		}
+	}
+}
+
+// CountError counts the failure of the task for the breaker, the failed orders of the gateway are counted already
+func (g *RiskGateway) CountError() {
+	now := time.Now()
+	g.lock.Lock()
+	g.failures = append(g.failures, now)
+	g.lock.Unlock()
+	g.breakers()
+}
+
+// breakers engages the kill switch if the loss or the failures reach the thresholds
+func (g *RiskGateway) breakers() {
+	if g.killSwitch.Engaged() {
+		return
+	}
+
+	now := time.Now()
+	var reason string
+
+	g.lock.Lock()
+	config := g.config
+	g.resetDay(now)
+	for len(g.failures) > 0 && now.Sub(g.failures[0]) > riskErrorWindow {
+		g.failures = g.failures[1:]
+	}
+
+	if config.BreakerLoss > 0 && -g.pnl >= config.BreakerLoss && !g.lossTripped.Equal(g.day) {
+		g.lossTripped = g.day
+		reason = fmt.Sprintf("[%s]当日亏损%v达到熔断阈值%v", g.Task, -g.pnl, config.BreakerLoss)
+	} else if config.BreakerErrors > 0 && len(g.failures) >= config.BreakerErrors {
+		reason = fmt.Sprintf("[%s]一分钟内失败%v次达到熔断阈值%v", g.Task, len(g.failures), config.BreakerErrors)
+		g.failures = nil
+	}
+	g.lock.Unlock()
+
+	if reason != "" {
+		g.killSwitch.Engage(KillSourceBreaker, reason, config.BreakerFlatten)
+	}
+}
+
+// AddPosition seeds the position which is opened by the trade before the task is started
+func (g *RiskGateway) AddPosition(pair string, tradeType Exchange.TradeType, amount float64, price float64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.fill(pair, tradeSide(tradeType), amount, price, false)
+}
+
+// SeedPositions seeds the positions of the pairs held before the task is started at the current prices. The positions of
+// the futures are read from the account of the exchange, they're only counted from the fills if the exchange doesn't
+// report the account. The spot positions are the balances of the base coins
+func (g *RiskGateway) SeedPositions(pairs []string) error {
+	g.lock.Lock()
+	futures := g.contract != nil
+	g.lock.Unlock()
+
+	if futures {
+		account, ok := g.IExchange.(Exchange.IAccount)
+		if !ok {
+			Logger.Warnf("[%s]%s不支持读取持仓，持仓按任务的成交计算", g.Task, g.GetExchangeName())
+			return nil
+		}
+		err, info := account.GetAccount()
+		if err != nil {
+			return err
+		}
+		for _, pair := range pairs {
+			coin := Exchange.ParsePair(pair)[0]
+			for _, position := range info.Positions {
+				if Exchange.ParsePair(position.Pair)[0] != coin || math.Abs(position.Amount) <= riskMinQuantity {
+					continue
+				}
+				tradeType := Exchange.TradeTypeOpenLong
+				if position.Amount < 0 {
+					tradeType = Exchange.TradeTypeOpenShort
+				}
+				g.AddPosition(pair, tradeType, math.Abs(position.Amount), position.MarkPrice)
+			}
+		}
+		return nil
+	}
+
+	balances := g.IExchange.GetBalance()
+	if balances == nil {
+		return fmt.Errorf("Fail to get the balances of %s", g.GetExchangeName())
+	}
+	for _, pair := range pairs {
+		amount, ok := ParseBalance(balances, Exchange.ParsePair(pair)[0])
+		if !ok || amount <= riskMinQuantity {
+			continue
+		}
+		price, ok := midPrice(g.IExchange.GetDepthValue(pair))
+		if !ok {
+			return fmt.Errorf("No market data of %s", pair)
+		}
+		g.AddPosition(pair, Exchange.TradeTypeBuy, amount, price)
+	}
+	return nil
+}
+
+// Positions the net positions of the pairs traded through the gateway
+func (g *RiskGateway) Positions() map[string]float64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	positions := make(map[string]float64)
+	for pair, position := range g.positions {
+		if position.amount != 0 {
+			positions[pair] = position.amount
+		}
+	}
+	return positions
+}
+
+// DailyPnL the realized profit of today
+func (g *RiskGateway) DailyPnL() float64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.resetDay(time.Now())
+	return g.pnl
+}
+
+// ConnectRiskRecords the rejections are only logged if Mongo isn't connected
+func ConnectRiskRecords() *Mongo.RiskRecords {
+	db := new(Mongo.RiskRecords)
+	if err := db.Connect(); err != nil {
+		Logger.Errorf("Fail to connect the risk records:%v", err)
+		return nil
+	}
+	return db
+}
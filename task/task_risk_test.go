@@ -0,0 +1,261 @@
+package task
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	Exchange "madaoQT/exchange"
+)
+
+type riskExchange struct {
+	Exchange.IExchange
+	depth  [][]Exchange.DepthPrice
+	orders map[string]*Exchange.OrderInfo
+	trades []Exchange.TradeConfig
+}
+
+func (p *riskExchange) GetExchangeName() string {
+	return "fake"
+}
+
+func (p *riskExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return p.depth
+}
+
+func (p *riskExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	p.trades = append(p.trades, configs)
+	id := strconv.Itoa(len(p.trades))
+	p.orders[id] = &Exchange.OrderInfo{
+		OrderID: id,
+		Pair:    configs.Pair,
+		Type:    configs.Type,
+		Price:   configs.Price,
+		Amount:  configs.Amount,
+		Status:  Exchange.OrderStatusOpen,
+	}
+	return &Exchange.TradeResult{OrderID: id}
+}
+
+func (p *riskExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	if order := p.orders[filter.OrderID]; order != nil {
+		return []Exchange.OrderInfo{*order}
+	}
+	return nil
+}
+
+func (p *riskExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+// done fills the order and reads it through the gateway
+func (p *riskExchange) done(gateway *RiskGateway, id string) {
+	order := p.orders[id]
+	order.DealAmount = order.Amount
+	order.Status = Exchange.OrderStatusDone
+	gateway.GetOrderInfo(Exchange.OrderInfo{OrderID: id, Pair: order.Pair})
+}
+
+func newRiskExchange() *riskExchange {
+	return &riskExchange{
+		depth: [][]Exchange.DepthPrice{
+			{{Price: 99, Quantity: 1}},
+			{{Price: 101, Quantity: 1}},
+		},
+		orders: make(map[string]*Exchange.OrderInfo),
+	}
+}
+
+func expectRule(t *testing.T, result *Exchange.TradeResult, rule RiskRule) {
+	t.Helper()
+	if rule == "" {
+		if result.Error != nil {
+			t.Fatalf("The order should be accepted:%v", result.Error)
+		}
+		return
+	}
+	riskError, ok := IsRiskError(result.Error)
+	if !ok || riskError.Rule != rule {
+		t.Fatalf("The order should be rejected by %s:%v", rule, result.Error)
+	}
+}
+
+func TestRiskOrderLimits(t *testing.T) {
+	exchange := newRiskExchange()
+	gateway := NewRiskGateway(exchange, "test", RiskConfig{
+		MaxOrderSize: 2,
+		MaxNotional:  150,
+		PriceCollar:  0.05,
+	}, nil)
+
+	order := Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 1}
+	expectRule(t, gateway.Trade(order), "")
+
+	order.Amount = 3
+	expectRule(t, gateway.Trade(order), RiskOrderSize)
+
+	order.Amount = 1.6
+	expectRule(t, gateway.Trade(order), RiskNotional)
+
+	// buying far above the mid is rejected but buying below it is passive
+	order.Amount = 1
+	order.Price = 106
+	expectRule(t, gateway.Trade(order), RiskPriceCollar)
+	order.Price = 90
+	expectRule(t, gateway.Trade(order), "")
+
+	order.Type = Exchange.TradeTypeSell
+	expectRule(t, gateway.Trade(order), RiskPriceCollar)
+
+	exchange.depth = nil
+	order.Price = 100
+	expectRule(t, gateway.Trade(order), RiskMarketData)
+
+	if len(exchange.trades) != 2 {
+		t.Errorf("The rejected orders shouldn't be sent:%v", exchange.trades)
+	}
+}
+
+func TestRiskOpenOrders(t *testing.T) {
+	exchange := newRiskExchange()
+	gateway := NewRiskGateway(exchange, "test", RiskConfig{MaxOpenOrders: 2}, nil)
+
+	order := Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 1}
+	expectRule(t, gateway.Trade(order), "")
+	expectRule(t, gateway.Trade(order), "")
+	expectRule(t, gateway.Trade(order), RiskOpenOrders)
+
+	// the canceled and the filled orders aren't open
+	gateway.CancelOrder(Exchange.OrderInfo{OrderID: "1"})
+	expectRule(t, gateway.Trade(order), "")
+	exchange.done(gateway, "2")
+	expectRule(t, gateway.Trade(order), "")
+	expectRule(t, gateway.Trade(order), RiskOpenOrders)
+}
+
+func TestRiskPosition(t *testing.T) {
+	exchange := newRiskExchange()
+	gateway := NewRiskGateway(exchange, "test", RiskConfig{MaxPosition: 2}, nil)
+
+	buy := Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 1.5}
+	expectRule(t, gateway.Trade(buy), "")
+	// the open order is counted
+	expectRule(t, gateway.Trade(buy), RiskPosition)
+
+	exchange.done(gateway, "1")
+	if positions := gateway.Positions(); positions["eth/usdt"] != 1.5 {
+		t.Fatalf("Invalid positions:%v", positions)
+	}
+	expectRule(t, gateway.Trade(buy), RiskPosition)
+
+	// the other pairs are limited separately
+	other := buy
+	other.Pair = "btc/usdt"
+	expectRule(t, gateway.Trade(other), "")
+
+	// the reducing orders are accepted
+	sell := buy
+	sell.Type = Exchange.TradeTypeSell
+	sell.Amount = 3.5
+	expectRule(t, gateway.Trade(sell), "")
+	sell.Amount = 4
+	expectRule(t, gateway.Trade(sell), RiskPosition)
+
+	// the futures closing the positions opened before are never limited
+	gateway.AddPosition("eth/usd", Exchange.TradeTypeOpenShort, 5, 100)
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usd", Type: Exchange.TradeTypeCloseShort, Price: 100, Amount: 5}), "")
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usd", Type: Exchange.TradeTypeOpenShort, Price: 100, Amount: 1}), RiskPosition)
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeCloseLong, Price: 100, Amount: 10}), "")
+}
+
+func TestRiskDailyLoss(t *testing.T) {
+	exchange := newRiskExchange()
+	gateway := NewRiskGateway(exchange, "test", RiskConfig{MaxDailyLoss: 10}, nil)
+
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 2}), "")
+	exchange.done(gateway, "1")
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 110, Amount: 2}), "")
+	exchange.done(gateway, "2")
+
+	// the average cost is 105
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeSell, Price: 100, Amount: 2}), "")
+	exchange.done(gateway, "3")
+	if pnl := gateway.DailyPnL(); math.Abs(pnl+10) > 1e-9 {
+		t.Fatalf("Invalid pnl:%v", pnl)
+	}
+
+	// only the reducing orders are accepted after the loss limit
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 1}), RiskDailyLoss)
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "btc/usdt", Type: Exchange.TradeTypeSell, Price: 100, Amount: 1}), RiskDailyLoss)
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeSell, Price: 100, Amount: 1}), "")
+
+	gateway.SetConfig(RiskConfig{})
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 1}), "")
+}
+
+func TestRiskInverseContract(t *testing.T) {
+	exchange := newRiskExchange()
+	gateway := NewRiskGateway(exchange, "test", RiskConfig{MaxNotional: 150}, nil)
+	gateway.SetContract(func(pair string) RiskContract {
+		return RiskContract{Value: 10, Inverse: true}
+	})
+
+	// the notional is the face value of the contracts
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usd", Type: Exchange.TradeTypeOpenLong, Price: 100, Amount: 20}), RiskNotional)
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usd", Type: Exchange.TradeTypeOpenLong, Price: 100, Amount: 10}), "")
+	exchange.done(gateway, "1")
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usd", Type: Exchange.TradeTypeOpenLong, Price: 200, Amount: 10}), "")
+	exchange.done(gateway, "2")
+
+	// the cost is 20/(10/100+10/200), the loss of 0.5 eth is valued at the closing price
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usd", Type: Exchange.TradeTypeCloseLong, Price: 100, Amount: 10}), "")
+	expectRule(t, gateway.Trade(Exchange.TradeConfig{Pair: "eth/usd", Type: Exchange.TradeTypeCloseLong, Price: 100, Amount: 10}), "")
+	exchange.done(gateway, "3")
+	exchange.done(gateway, "4")
+	if pnl := gateway.DailyPnL(); math.Abs(pnl+50) > 1e-9 {
+		t.Fatalf("Invalid pnl:%v", pnl)
+	}
+}
+
+type accountExchange struct {
+	*riskExchange
+	balances map[string]interface{}
+	account  *Exchange.AccountInfo
+}
+
+func (p *accountExchange) GetBalance() map[string]interface{} {
+	return p.balances
+}
+
+func (p *accountExchange) GetAccount() (error, *Exchange.AccountInfo) {
+	return nil, p.account
+}
+
+func TestRiskSeedPositions(t *testing.T) {
+	exchange := &accountExchange{
+		riskExchange: newRiskExchange(),
+		balances:     map[string]interface{}{"eth": map[string]interface{}{"balance": 2.0}},
+		account: &Exchange.AccountInfo{Positions: []Exchange.AccountPosition{
+			{Pair: "eth/usd", Amount: -30, MarkPrice: 100},
+		}},
+	}
+
+	spot := NewRiskGateway(exchange, "test", RiskConfig{MaxPosition: 3}, nil)
+	if err := spot.SeedPositions([]string{"eth/usdt", "btc/usdt"}); err != nil {
+		t.Fatalf("Fail to seed the positions:%v", err)
+	}
+	if positions := spot.Positions(); len(positions) != 1 || positions["eth/usdt"] != 2 {
+		t.Fatalf("Invalid positions:%v", positions)
+	}
+	expectRule(t, spot.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Price: 100, Amount: 2}), RiskPosition)
+	expectRule(t, spot.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeSell, Price: 100, Amount: 2}), "")
+
+	future := NewRiskGateway(exchange, "test", RiskConfig{}, nil)
+	future.SetContract(InverseContract)
+	if err := future.SeedPositions([]string{"eth/usdt"}); err != nil {
+		t.Fatalf("Fail to seed the positions:%v", err)
+	}
+	if positions := future.Positions(); positions["eth/usdt"] != -30 {
+		t.Fatalf("Invalid positions:%v", positions)
+	}
+}
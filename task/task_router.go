@@ -0,0 +1,566 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	Exchange "madaoQT/exchange"
+)
+
+/*
+	智能路由：Router实现IExchange，合并多个交易所的深度，按含手续费的价格从优到劣拆分订单，
+	遵守各交易所的余额和最小下单量，子订单并行下单，成交汇总为一个订单返回，策略可以直接交易"市场"
+*/
+
+const routerEpsilon = 1e-9
+
+// routerOrderExpiry the routed orders are forgotten after the time
+const routerOrderExpiry = 24 * time.Hour
+
+// RouterVenue the exchange which the router trades on
+type RouterVenue struct {
+	Exchange Exchange.IExchange
+	// Fee the taker fee ratio, e.g. 0.002
+	Fee float64
+	// MinAmount the minimum amount of the order on the exchange, 0 means no limit
+	MinAmount float64
+}
+
+// RouterSlice the part of the order placed on the venue
+type RouterSlice struct {
+	Venue  int
+	Amount float64
+	// Price the worst price of the levels taken on the venue
+	Price float64
+	// Cost the expected cost including the fees, which is in the quote coin
+	Cost float64
+}
+
+type routerLevel struct {
+	venue     int
+	price     float64
+	quantity  float64
+	effective float64
+}
+
+func buyType(tradeType Exchange.TradeType) bool {
+	return tradeType == Exchange.TradeTypeOpenLong || tradeType == Exchange.TradeTypeCloseShort || tradeType == Exchange.TradeTypeBuy
+}
+
+// Allocate splits the amount over the depths of the venues from the best price including the fees. The funds limit
+// each venue, which are in the quote coin when buying and in the base coin when selling, the negative ones are not
+// limited. The limit price is skipped if it's 0. The slices below the minimum amounts are dropped and the amount is
+// allocated to the other venues again
+func Allocate(venues []*RouterVenue, depths [][][]Exchange.DepthPrice, funds []float64,
+	tradeType Exchange.TradeType, amount float64, limit float64) []RouterSlice {
+
+	excluded := make([]bool, len(venues))
+	for {
+		slices := allocate(venues, depths, funds, excluded, tradeType, amount, limit)
+		dropped := false
+		for _, slice := range slices {
+			if minimum := venues[slice.Venue].MinAmount; minimum > 0 && slice.Amount < minimum-routerEpsilon {
+				excluded[slice.Venue] = true
+				dropped = true
+			}
+		}
+		if !dropped {
+			return slices
+		}
+	}
+}
+
+func allocate(venues []*RouterVenue, depths [][][]Exchange.DepthPrice, funds []float64, excluded []bool,
+	tradeType Exchange.TradeType, amount float64, limit float64) []RouterSlice {
+
+	buying := buyType(tradeType)
+	side := Exchange.DepthTypeBids
+	if buying {
+		side = Exchange.DepthTypeAsks
+	}
+
+	var levels []routerLevel
+	for i, venue := range venues {
+		if excluded[i] || i >= len(depths) || len(depths[i]) <= side {
+			continue
+		}
+		for _, depth := range depths[i][side] {
+			if depth.Price <= 0 || depth.Quantity <= 0 {
+				continue
+			}
+			if limit > 0 && ((buying && depth.Price > limit) || (!buying && depth.Price < limit)) {
+				continue
+			}
+			effective := depth.Price * (1 - venue.Fee)
+			if buying {
+				effective = depth.Price * (1 + venue.Fee)
+			}
+			levels = append(levels, routerLevel{venue: i, price: depth.Price, quantity: depth.Quantity, effective: effective})
+		}
+	}
+
+	sort.SliceStable(levels, func(i, j int) bool {
+		if buying {
+			return levels[i].effective < levels[j].effective
+		}
+		return levels[i].effective > levels[j].effective
+	})
+
+	remains := make([]float64, len(venues))
+	for i := range remains {
+		remains[i] = -1
+		if i < len(funds) {
+			remains[i] = funds[i]
+		}
+	}
+
+	slices := make(map[int]*RouterSlice)
+	left := amount
+	for _, level := range levels {
+		if left <= routerEpsilon {
+			break
+		}
+		quantity := math.Min(level.quantity, left)
+		if remain := remains[level.venue]; remain >= 0 {
+			if buying {
+				quantity = math.Min(quantity, remain/level.effective)
+			} else {
+				quantity = math.Min(quantity, remain)
+			}
+		}
+		if quantity <= routerEpsilon {
+			continue
+		}
+
+		if remains[level.venue] >= 0 {
+			if buying {
+				remains[level.venue] -= quantity * level.effective
+			} else {
+				remains[level.venue] -= quantity
+			}
+		}
+
+		slice := slices[level.venue]
+		if slice == nil {
+			slice = &RouterSlice{Venue: level.venue}
+			slices[level.venue] = slice
+		}
+		slice.Amount += quantity
+		slice.Price = level.price
+		slice.Cost += quantity * level.effective
+		left -= quantity
+	}
+
+	var result []RouterSlice
+	for _, slice := range slices {
+		result = append(result, *slice)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Venue < result[j].Venue })
+	return result
+}
+
+type routerChild struct {
+	venue   int
+	orderID string
+	info    Exchange.OrderInfo
+}
+
+type routerOrder struct {
+	config   Exchange.TradeConfig
+	children []*routerChild
+	time     time.Time
+}
+
+// Router trades the pair on the venues as one exchange
+type Router struct {
+	Name   string
+	Venues []*RouterVenue
+
+	lock     sync.Mutex
+	orders   map[string]*routerOrder
+	sequence int
+	event    chan Exchange.EventType
+	watching []bool
+}
+
+func NewRouter(name string, venues ...*RouterVenue) *Router {
+	return &Router{
+		Name:     name,
+		Venues:   venues,
+		orders:   make(map[string]*routerOrder),
+		event:    make(chan Exchange.EventType, len(venues)+1),
+		watching: make([]bool, len(venues)),
+	}
+}
+
+func (r *Router) GetExchangeName() string {
+	return r.Name
+}
+
+// SetConfigure the venues are configured when they are created
+func (r *Router) SetConfigure(config Exchange.Config) {
+}
+
+func (r *Router) WatchEvent() chan Exchange.EventType {
+	return r.event
+}
+
+// Start starts the venues, the events of the venues are forwarded
+func (r *Router) Start() error {
+	var errs []string
+	for i, venue := range r.Venues {
+		if err := venue.Exchange.Start(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s:%v", venue.Exchange.GetExchangeName(), err))
+			continue
+		}
+
+		r.lock.Lock()
+		watching := r.watching[i]
+		r.watching[i] = true
+		r.lock.Unlock()
+		if events := venue.Exchange.WatchEvent(); !watching && events != nil {
+			go r.forward(events)
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, ";"))
+	}
+	return nil
+}
+
+func (r *Router) forward(events chan Exchange.EventType) {
+	for event := range events {
+		select {
+		case r.event <- event:
+		default:
+		}
+	}
+}
+
+func (r *Router) Close() {
+	for _, venue := range r.Venues {
+		venue.Exchange.Close()
+	}
+}
+
+func (r *Router) StartTicker(pair string) {
+	for _, venue := range r.Venues {
+		venue.Exchange.StartTicker(pair)
+	}
+}
+
+// GetTicker the ticker of the first venue which has it
+func (r *Router) GetTicker(pair string) *Exchange.TickerValue {
+	for _, venue := range r.Venues {
+		if ticker := venue.Exchange.GetTicker(pair); ticker != nil {
+			return ticker
+		}
+	}
+	return nil
+}
+
+func (r *Router) depths(pair string) [][][]Exchange.DepthPrice {
+	depths := make([][][]Exchange.DepthPrice, len(r.Venues))
+	for i, venue := range r.Venues {
+		depths[i] = venue.Exchange.GetDepthValue(pair)
+	}
+	return depths
+}
+
+// GetDepthValue the consolidated depth of the venues, the fees aren't included
+func (r *Router) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return consolidate(r.depths(pair))
+}
+
+func consolidate(depths [][][]Exchange.DepthPrice) [][]Exchange.DepthPrice {
+	quantities := []map[float64]float64{make(map[float64]float64), make(map[float64]float64)}
+	for _, depth := range depths {
+		for side := Exchange.DepthTypeBids; side <= Exchange.DepthTypeAsks && side < len(depth); side++ {
+			for _, level := range depth[side] {
+				quantities[side][level.Price] += level.Quantity
+			}
+		}
+	}
+
+	result := make([][]Exchange.DepthPrice, 2)
+	for side, levels := range quantities {
+		for price, quantity := range levels {
+			result[side] = append(result[side], Exchange.DepthPrice{Price: price, Quantity: quantity})
+		}
+		bids := side == Exchange.DepthTypeBids
+		sort.Slice(result[side], func(i, j int) bool {
+			if bids {
+				return result[side][i].Price > result[side][j].Price
+			}
+			return result[side][i].Price < result[side][j].Price
+		})
+	}
+
+	if len(result[Exchange.DepthTypeBids]) == 0 && len(result[Exchange.DepthTypeAsks]) == 0 {
+		return nil
+	}
+	return result
+}
+
+// GetBalance the sums of the balances of the venues
+func (r *Router) GetBalance() map[string]interface{} {
+	var venues []map[string]interface{}
+	totals := make(map[string]float64)
+	for _, venue := range r.Venues {
+		balances := venue.Exchange.GetBalance()
+		venues = append(venues, balances)
+		for key := range balances {
+			totals[strings.ToLower(key)] = 0
+		}
+	}
+
+	for _, balances := range venues {
+		for coin := range totals {
+			if balance, ok := ParseBalance(balances, coin); ok {
+				totals[coin] += balance
+			}
+		}
+	}
+
+	result := make(map[string]interface{})
+	for coin, balance := range totals {
+		result[coin] = balance
+	}
+	return result
+}
+
+// funds the balances available to the order, the futures and the venues without balances aren't limited
+func (r *Router) funds(config Exchange.TradeConfig) []float64 {
+	funds := make([]float64, len(r.Venues))
+	coins := Exchange.ParsePair(config.Pair)
+	for i, venue := range r.Venues {
+		funds[i] = -1
+		if len(coins) != 2 || (config.Type != Exchange.TradeTypeBuy && config.Type != Exchange.TradeTypeSell) {
+			continue
+		}
+		balances := venue.Exchange.GetBalance()
+		if balances == nil {
+			Logger.Warnf("The balances of %s are not available", venue.Exchange.GetExchangeName())
+			continue
+		}
+		coin := coins[0]
+		if config.Type == Exchange.TradeTypeBuy {
+			coin = coins[1]
+		}
+		funds[i], _ = ParseBalance(balances, coin)
+	}
+	return funds
+}
+
+// Plan the slices of the order on the current depths
+func (r *Router) Plan(config Exchange.TradeConfig) []RouterSlice {
+	return Allocate(r.Venues, r.depths(config.Pair), r.funds(config), config.Type, config.Amount, config.Price)
+}
+
+// Trade places the slices on the venues at the same time, the info of the result is the sum of the orders. The order
+// fails only if all the slices fail
+func (r *Router) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	slices := r.Plan(configs)
+	if len(slices) == 0 {
+		return &Exchange.TradeResult{Error: errors.New("No liquidity on the venues within the limit")}
+	}
+
+	children := make([]*routerChild, len(slices))
+	results := make([]*Exchange.TradeResult, len(slices))
+	var wait sync.WaitGroup
+	for i, slice := range slices {
+		wait.Add(1)
+		go func(i int, slice RouterSlice) {
+			defer wait.Done()
+			config := configs
+			config.Amount = slice.Amount
+			if config.Price == 0 {
+				config.Price = slice.Price
+			}
+			results[i] = r.Venues[slice.Venue].Exchange.Trade(config)
+			children[i] = &routerChild{
+				venue: slice.Venue,
+				info: Exchange.OrderInfo{
+					Pair:   config.Pair,
+					Price:  config.Price,
+					Amount: config.Amount,
+					Type:   config.Type,
+					Status: Exchange.OrderStatusOpen,
+				},
+			}
+		}(i, slice)
+	}
+	wait.Wait()
+
+	order := &routerOrder{config: configs, time: time.Now()}
+	var errs []string
+	for i, result := range results {
+		name := r.Venues[slices[i].Venue].Exchange.GetExchangeName()
+		if result == nil || result.Error != nil {
+			var err error
+			if result != nil {
+				err = result.Error
+			}
+			Logger.Errorf("Fail to trade %v on %s:%v", slices[i].Amount, name, err)
+			errs = append(errs, fmt.Sprintf("%s:%v", name, err))
+			continue
+		}
+		child := children[i]
+		child.orderID = result.OrderID
+		if result.Info != nil {
+			child.info = *result.Info
+		}
+		child.info.OrderID = result.OrderID
+		order.children = append(order.children, child)
+	}
+
+	if len(order.children) == 0 {
+		return &Exchange.TradeResult{Error: errors.New(strings.Join(errs, ";"))}
+	}
+
+	r.lock.Lock()
+	for id, routed := range r.orders {
+		if time.Since(routed.time) > routerOrderExpiry {
+			delete(r.orders, id)
+		}
+	}
+	r.sequence++
+	orderID := fmt.Sprintf("%s-%d-%d", r.Name, time.Now().Unix(), r.sequence)
+	r.orders[orderID] = order
+	r.lock.Unlock()
+
+	info := order.aggregate(orderID)
+	return &Exchange.TradeResult{
+		OrderID: orderID,
+		Info:    &info,
+	}
+}
+
+// aggregate the sum of the children, the order is done only if all the children are done
+func (o *routerOrder) aggregate(orderID string) Exchange.OrderInfo {
+	info := Exchange.OrderInfo{
+		Pair:    o.config.Pair,
+		OrderID: orderID,
+		Price:   o.config.Price,
+		Type:    o.config.Type,
+	}
+
+	var cost float64
+	done, open, unknown := 0, 0, 0
+	for _, child := range o.children {
+		info.Amount += child.info.Amount
+		info.DealAmount += child.info.DealAmount
+		cost += child.info.DealAmount * child.info.AvgPrice
+		switch child.info.Status {
+		case Exchange.OrderStatusDone:
+			done++
+		case Exchange.OrderStatusOpen, Exchange.OrderStatusPartDone, Exchange.OrderStatusOrdering, Exchange.OrderStatusCanceling:
+			open++
+		case Exchange.OrderStatusUnknown:
+			unknown++
+		}
+	}
+	if info.DealAmount > 0 {
+		info.AvgPrice = cost / info.DealAmount
+	}
+
+	switch {
+	case done == len(o.children):
+		info.Status = Exchange.OrderStatusDone
+	case unknown == len(o.children):
+		info.Status = Exchange.OrderStatusUnknown
+	case open != 0 && info.DealAmount > 0:
+		info.Status = Exchange.OrderStatusPartDone
+	case open != 0:
+		info.Status = Exchange.OrderStatusOpen
+	default:
+		info.Status = Exchange.OrderStatusCanceled
+	}
+	return info
+}
+
+func (r *Router) getOrder(orderID string) *routerOrder {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.orders[orderID]
+}
+
+// GetOrderInfo the routed order is updated from the venues, the other filters are sent to all the venues
+func (r *Router) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	order := r.getOrder(filter.OrderID)
+	if order == nil {
+		var infos []Exchange.OrderInfo
+		for _, venue := range r.Venues {
+			infos = append(infos, venue.Exchange.GetOrderInfo(filter)...)
+		}
+		return infos
+	}
+
+	for _, child := range order.children {
+		infos := r.Venues[child.venue].Exchange.GetOrderInfo(Exchange.OrderInfo{
+			Pair:    child.info.Pair,
+			OrderID: child.orderID,
+			Type:    child.info.Type,
+		})
+		if len(infos) == 0 {
+			continue
+		}
+		r.lock.Lock()
+		child.info = infos[0]
+		child.info.OrderID = child.orderID
+		r.lock.Unlock()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return []Exchange.OrderInfo{order.aggregate(filter.OrderID)}
+}
+
+// CancelOrder cancels the children which are still open, it fails if any of them fails
+func (r *Router) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	routed := r.getOrder(order.OrderID)
+	if routed == nil {
+		return &Exchange.TradeResult{Error: errors.New("The order is not placed by the router")}
+	}
+
+	var errs []string
+	for _, child := range routed.children {
+		r.lock.Lock()
+		status := child.info.Status
+		r.lock.Unlock()
+		if status == Exchange.OrderStatusDone || status == Exchange.OrderStatusCanceled {
+			continue
+		}
+
+		venue := r.Venues[child.venue].Exchange
+		result := venue.CancelOrder(Exchange.OrderInfo{
+			Pair:    child.info.Pair,
+			OrderID: child.orderID,
+			Type:    child.info.Type,
+		})
+		if result != nil && result.Error != nil {
+			errs = append(errs, fmt.Sprintf("%s:%v", venue.GetExchangeName(), result.Error))
+		}
+	}
+
+	if len(errs) != 0 {
+		return &Exchange.TradeResult{OrderID: order.OrderID, Error: errors.New(strings.Join(errs, ";"))}
+	}
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+// GetKline the klines of the first venue which has them
+func (r *Router) GetKline(pair string, period int, limit int) []Exchange.KlineValue {
+	for _, venue := range r.Venues {
+		if klines := venue.Exchange.GetKline(pair, period, limit); len(klines) != 0 {
+			return klines
+		}
+	}
+	return nil
+}
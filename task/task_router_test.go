@@ -0,0 +1,160 @@
+package task
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	Exchange "madaoQT/exchange"
+)
+
+type routerExchange struct {
+	Exchange.IExchange
+	name     string
+	depth    [][]Exchange.DepthPrice
+	balances map[string]interface{}
+	fail     bool
+	trades   []Exchange.TradeConfig
+	order    *Exchange.OrderInfo
+	canceled bool
+}
+
+func (p *routerExchange) GetExchangeName() string {
+	return p.name
+}
+
+func (p *routerExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return p.depth
+}
+
+func (p *routerExchange) GetBalance() map[string]interface{} {
+	return p.balances
+}
+
+// Trade fills the order at the limit price
+func (p *routerExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	p.trades = append(p.trades, configs)
+	if p.fail {
+		return &Exchange.TradeResult{Error: errors.New("rejected")}
+	}
+	p.order = &Exchange.OrderInfo{
+		Pair:       configs.Pair,
+		OrderID:    p.name,
+		Price:      configs.Price,
+		Amount:     configs.Amount,
+		DealAmount: configs.Amount,
+		AvgPrice:   configs.Price,
+		Type:       configs.Type,
+		Status:     Exchange.OrderStatusDone,
+	}
+	info := *p.order
+	return &Exchange.TradeResult{OrderID: p.name, Info: &info}
+}
+
+func (p *routerExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	if p.order != nil && p.order.OrderID == filter.OrderID {
+		return []Exchange.OrderInfo{*p.order}
+	}
+	return nil
+}
+
+func (p *routerExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	p.canceled = true
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+func TestAllocate(t *testing.T) {
+	venues := []*RouterVenue{{Fee: 0.002}, {Fee: 0.001}, {Fee: 0.001, MinAmount: 1}}
+	depths := [][][]Exchange.DepthPrice{
+		{{{Price: 99, Quantity: 5}}, {{Price: 100, Quantity: 2}, {Price: 101, Quantity: 5}}},
+		{{{Price: 99.1, Quantity: 5}}, {{Price: 100.1, Quantity: 1}, {Price: 100.5, Quantity: 5}}},
+		{{{Price: 99.2, Quantity: 5}}, {{Price: 100, Quantity: 0.5}}},
+	}
+	unlimited := []float64{-1, -1, -1}
+
+	// the third venue is the cheapest but below the minimum amount, the fees make the second venue better
+	slices := Allocate(venues, depths, unlimited, Exchange.TradeTypeBuy, 4, 0)
+	if len(slices) != 2 || slices[0].Venue != 0 || slices[1].Venue != 1 {
+		t.Fatalf("Invalid slices:%v", slices)
+	}
+	if math.Abs(slices[0].Amount-2) > 1e-9 || slices[0].Price != 100 || math.Abs(slices[1].Amount-2) > 1e-9 || slices[1].Price != 100.5 {
+		t.Errorf("Invalid amounts:%v", slices)
+	}
+	if math.Abs(slices[1].Cost-(100.1+100.5)*1.001) > 1e-9 {
+		t.Errorf("Invalid cost:%v", slices[1].Cost)
+	}
+
+	// the funds of the first venue buy only one
+	slices = Allocate(venues, depths, []float64{100.2, -1, -1}, Exchange.TradeTypeBuy, 4, 0)
+	if len(slices) != 2 || math.Abs(slices[0].Amount-1) > 1e-9 || math.Abs(slices[1].Amount-3) > 1e-9 {
+		t.Errorf("The funds should limit the venue:%v", slices)
+	}
+
+	// the limit price keeps the order from the worse levels
+	slices = Allocate(venues, depths, unlimited, Exchange.TradeTypeBuy, 10, 100.2)
+	var total float64
+	for _, slice := range slices {
+		total += slice.Amount
+	}
+	if math.Abs(total-3) > 1e-9 || len(slices) != 2 {
+		t.Errorf("Invalid slices within the limit:%v", slices)
+	}
+
+	// the first venue has no coin to sell
+	slices = Allocate(venues, depths, []float64{0, 3, -1}, Exchange.TradeTypeSell, 7, 0)
+	if len(slices) != 2 || slices[0].Venue != 1 || slices[0].Amount != 2 || slices[1].Venue != 2 || slices[1].Amount != 5 {
+		t.Errorf("Invalid slices of the sell:%v", slices)
+	}
+}
+
+func TestRouterTrade(t *testing.T) {
+	okex := &routerExchange{
+		name:     "okex",
+		depth:    [][]Exchange.DepthPrice{{{Price: 99, Quantity: 5}}, {{Price: 100, Quantity: 1}}},
+		balances: map[string]interface{}{"usdt": 1000.0, "eth": 1.0},
+	}
+	binance := &routerExchange{
+		name:     "binance",
+		depth:    [][]Exchange.DepthPrice{{{Price: 99, Quantity: 5}}, {{Price: 101, Quantity: 5}}},
+		balances: map[string]interface{}{"USDT": map[string]interface{}{"balance": 500.0}},
+	}
+	router := NewRouter("router", &RouterVenue{Exchange: okex}, &RouterVenue{Exchange: binance})
+
+	if balances := router.GetBalance(); balances["usdt"] != 1500.0 || balances["eth"] != 1.0 {
+		t.Errorf("Invalid balances:%v", balances)
+	}
+	if depth := router.GetDepthValue("eth/usdt"); len(depth[Exchange.DepthTypeBids]) != 1 || depth[Exchange.DepthTypeBids][0].Quantity != 10 ||
+		len(depth[Exchange.DepthTypeAsks]) != 2 || depth[Exchange.DepthTypeAsks][0].Price != 100 {
+		t.Errorf("Invalid depth:%v", depth)
+	}
+
+	result := router.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeBuy, Amount: 3})
+	if result.Error != nil || result.Info == nil {
+		t.Fatalf("Fail to trade:%v", result.Error)
+	}
+	if result.Info.DealAmount != 3 || math.Abs(result.Info.AvgPrice-(100+2*101)/3.0) > 1e-9 || result.Info.Status != Exchange.OrderStatusDone {
+		t.Errorf("Invalid fills:%v", *result.Info)
+	}
+	if len(okex.trades) != 1 || okex.trades[0].Amount != 1 || okex.trades[0].Price != 100 || len(binance.trades) != 1 || binance.trades[0].Amount != 2 {
+		t.Errorf("Invalid child orders:%v %v", okex.trades, binance.trades)
+	}
+
+	binance.order.Status = Exchange.OrderStatusPartDone
+	binance.order.DealAmount = 1
+	infos := router.GetOrderInfo(Exchange.OrderInfo{OrderID: result.OrderID, Pair: "eth/usdt"})
+	if len(infos) != 1 || infos[0].DealAmount != 2 || infos[0].Status != Exchange.OrderStatusPartDone {
+		t.Errorf("Invalid order info:%v", infos)
+	}
+	if cancel := router.CancelOrder(infos[0]); cancel.Error != nil || okex.canceled || !binance.canceled {
+		t.Errorf("Only the open order should be canceled:%v", cancel.Error)
+	}
+
+	// the sell is limited by the balance of the base coin, the order fails only if all the slices fail
+	okex.fail = true
+	if result = router.Trade(Exchange.TradeConfig{Pair: "eth/usdt", Type: Exchange.TradeTypeSell, Amount: 3}); result.Error == nil {
+		t.Errorf("The order should fail:%v", result.Info)
+	}
+	if len(okex.trades) != 2 || okex.trades[1].Amount != 1 || len(binance.trades) != 1 {
+		t.Errorf("Invalid child orders of the sell:%v %v", okex.trades, binance.trades)
+	}
+}
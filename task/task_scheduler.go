@@ -0,0 +1,411 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	Cron "github.com/robfig/cron"
+
+	Mongo "madaoQT/mongo"
+)
+
+/*
+	定时任务：策略和管理员可以按cron表达式注册命名的定时任务（余额快照、日报、移仓、定时启停任务实例），
+	可以查询下次运行时间，最后一次运行的记录保存在Mongo中
+
+	表达式包含秒：秒 分 时 日 月 周，例如"0 30 9 * * 1-5"，也支持"@daily"、"@every 1h"
+*/
+
+const (
+	JobActionStart   = "start"
+	JobActionStop    = "stop"
+	JobActionRestart = "restart"
+)
+
+const schedulerInterval = 1 * time.Second
+
+// IScheduled the task which registers its own jobs should implement this interface
+type IScheduled interface {
+	SetScheduler(scheduler *Scheduler)
+}
+
+// Job the named job, the name should be unique in the scheduler
+type Job struct {
+	Name string
+	Spec string
+	Desc string
+	Run  func() error
+}
+
+// JobInfo the information of the job, Action and TaskID are set for the jobs which start or stop the task instances
+type JobInfo struct {
+	Name   string    `json:"name"`
+	Spec   string    `json:"spec"`
+	Desc   string    `json:"desc"`
+	Action string    `json:"action"`
+	TaskID string    `json:"taskid"`
+	Next   time.Time `json:"next"`
+
+	LastRun   time.Time `json:"lastrun"`
+	LastError string    `json:"lasterror"`
+	// LastDuration in milliseconds
+	LastDuration int64 `json:"lastduration"`
+	Running      bool  `json:"running"`
+}
+
+type scheduledJob struct {
+	job      Job
+	schedule Cron.Schedule
+	info     JobInfo
+}
+
+// Window the time window between two cron specs, such as the trading hours
+type Window struct {
+	Start string `json:"start" title:"开始" desc:"cron表达式"`
+	End   string `json:"end" title:"结束" desc:"cron表达式"`
+}
+
+// Active returns whether the time is in the window, the empty window is never active
+func (w Window) Active(now time.Time) (error, bool) {
+	if w.Start == "" && w.End == "" {
+		return nil, false
+	}
+
+	start, err := Cron.Parse(w.Start)
+	if err != nil {
+		return err, false
+	}
+	end, err := Cron.Parse(w.End)
+	if err != nil {
+		return err, false
+	}
+
+	// the window ends before it starts again
+	return nil, end.Next(now).Before(start.Next(now))
+}
+
+type Scheduler struct {
+	// Manager runs the jobs of the task instances
+	Manager *TaskManager
+	// DB saves the last runs and the jobs of the task instances if assigned
+	DB *Mongo.Schedules
+
+	lock    sync.Mutex
+	jobs    map[string]*scheduledJob
+	records map[string]Mongo.ScheduleRecord
+	quit    chan bool
+}
+
+func (s *Scheduler) init() {
+	if s.jobs == nil {
+		s.jobs = make(map[string]*scheduledJob)
+		s.records = make(map[string]Mongo.ScheduleRecord)
+	}
+}
+
+// Add registers the job, the last run is loaded if it's recorded
+func (s *Scheduler) Add(job Job) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.add(job, "", "")
+}
+
+func (s *Scheduler) add(job Job, action string, taskID string) error {
+	s.init()
+
+	if job.Name == "" || job.Run == nil {
+		return errors.New(TaskErrorMsg[TaskInvalidInput])
+	}
+	if _, ok := s.jobs[job.Name]; ok {
+		return errors.New(TaskErrorMsg[TaskInvalidInput])
+	}
+
+	schedule, err := Cron.Parse(job.Spec)
+	if err != nil {
+		return err
+	}
+
+	item := &scheduledJob{
+		job:      job,
+		schedule: schedule,
+		info: JobInfo{
+			Name:   job.Name,
+			Spec:   job.Spec,
+			Desc:   job.Desc,
+			Action: action,
+			TaskID: taskID,
+			Next:   schedule.Next(time.Now()),
+		},
+	}
+
+	if record, ok := s.records[job.Name]; ok {
+		item.info.LastRun = record.LastRun
+		item.info.LastError = record.LastError
+		item.info.LastDuration = record.LastDuration
+	}
+
+	s.jobs[job.Name] = item
+	Logger.Infof("Add job %s:%s next:%v", job.Name, job.Spec, item.info.Next)
+	return nil
+}
+
+// AddTaskJob registers the job which starts, stops or restarts the task instance, the job is saved and restored by Start()
+func (s *Scheduler) AddTaskJob(name string, spec string, action string, taskID string, config string) error {
+	if s.Manager == nil {
+		return errors.New(TaskErrorMsg[TaskNotSupported])
+	}
+
+	if action != JobActionStart && action != JobActionStop && action != JobActionRestart {
+		return errors.New(TaskErrorMsg[TaskInvalidInput])
+	}
+
+	if err, _ := s.Manager.Status(taskID); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.add(s.taskJob(name, spec, action, taskID, config), action, taskID); err != nil {
+		return err
+	}
+
+	if s.DB != nil {
+		if err := s.DB.Save(&Mongo.ScheduleRecord{
+			Name:   name,
+			Spec:   spec,
+			Desc:   s.jobs[name].job.Desc,
+			Action: action,
+			TaskID: taskID,
+			Config: config,
+		}); err != nil {
+			Logger.Errorf("Fail to save job %s:%v", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) taskJob(name string, spec string, action string, taskID string, config string) Job {
+	return Job{
+		Name: name,
+		Spec: spec,
+		Desc: fmt.Sprintf("%s %s", action, taskID),
+		Run: func() error {
+			if action == JobActionStop || action == JobActionRestart {
+				if err := s.Manager.Stop(taskID); err != nil {
+					return err
+				}
+			}
+			if action == JobActionStart || action == JobActionRestart {
+				return s.Manager.Start(taskID, config)
+			}
+			return nil
+		},
+	}
+}
+
+// Remove removes the job, the saved job of the task instance is removed too
+func (s *Scheduler) Remove(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.init()
+
+	item := s.jobs[name]
+	if item == nil {
+		return errors.New(TaskErrorMsg[TaskNotFound])
+	}
+
+	delete(s.jobs, name)
+	if item.info.Action != "" && s.DB != nil {
+		if err := s.DB.Remove(name); err != nil {
+			Logger.Errorf("Fail to remove job %s:%v", name, err)
+		}
+	}
+	return nil
+}
+
+// RemoveTaskJobs removes the jobs of the removed task instance
+func (s *Scheduler) RemoveTaskJobs(taskID string) {
+	s.lock.Lock()
+	var names []string
+	for name, item := range s.jobs {
+		if item.info.Action != "" && item.info.TaskID == taskID {
+			names = append(names, name)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, name := range names {
+		s.Remove(name)
+	}
+}
+
+// List returns the jobs ordered by the next run
+func (s *Scheduler) List() []JobInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var jobs []JobInfo
+	for _, item := range s.jobs {
+		jobs = append(jobs, item.info)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Next.Equal(jobs[j].Next) {
+			return jobs[i].Name < jobs[j].Name
+		}
+		return jobs[i].Next.Before(jobs[j].Next)
+	})
+	return jobs
+}
+
+func (s *Scheduler) Get(name string) (error, *JobInfo) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	item := s.jobs[name]
+	if item == nil {
+		return errors.New(TaskErrorMsg[TaskNotFound]), nil
+	}
+
+	info := item.info
+	return nil, &info
+}
+
+// Run runs the job immediately and returns its error, the schedule isn't changed
+func (s *Scheduler) Run(name string) error {
+	s.lock.Lock()
+	item := s.jobs[name]
+	if item == nil {
+		s.lock.Unlock()
+		return errors.New(TaskErrorMsg[TaskNotFound])
+	}
+	if item.info.Running {
+		s.lock.Unlock()
+		return errors.New(TaskErrorMsg[TaskErrorStatus])
+	}
+	item.info.Running = true
+	s.lock.Unlock()
+
+	return s.run(item)
+}
+
+func (s *Scheduler) run(item *scheduledJob) (err error) {
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+
+		duration := time.Since(start)
+		message := ""
+		if err != nil {
+			message = err.Error()
+			Logger.Errorf("Job %s failed:%v", item.job.Name, err)
+		}
+
+		s.lock.Lock()
+		item.info.Running = false
+		item.info.LastRun = start
+		item.info.LastError = message
+		item.info.LastDuration = int64(duration / time.Millisecond)
+		s.lock.Unlock()
+
+		if s.DB != nil {
+			if err := s.DB.SetLastRun(item.job.Name, start, message, duration); err != nil {
+				Logger.Errorf("Fail to save the last run of job %s:%v", item.job.Name, err)
+			}
+		}
+	}()
+
+	Logger.Infof("Run job %s", item.job.Name)
+	return item.job.Run()
+}
+
+// tick runs the jobs which are due, the job is skipped if the last run isn't finished
+func (s *Scheduler) tick(now time.Time) {
+	s.lock.Lock()
+	var due []*scheduledJob
+	for _, item := range s.jobs {
+		if now.Before(item.info.Next) {
+			continue
+		}
+		item.info.Next = item.schedule.Next(now)
+		if item.info.Running {
+			Logger.Warnf("Job %s is skipped, the last run isn't finished", item.job.Name)
+			continue
+		}
+		item.info.Running = true
+		due = append(due, item)
+	}
+	s.lock.Unlock()
+
+	for _, item := range due {
+		go s.run(item)
+	}
+}
+
+// Start restores the jobs of the task instances and runs the jobs on time
+func (s *Scheduler) Start() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.init()
+
+	if s.quit != nil {
+		return errors.New(TaskErrorMsg[TaskErrorStatus])
+	}
+
+	if s.DB != nil {
+		err, records := s.DB.FindAll()
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			s.records[record.Name] = record
+			if item := s.jobs[record.Name]; item != nil {
+				item.info.LastRun = record.LastRun
+				item.info.LastError = record.LastError
+				item.info.LastDuration = record.LastDuration
+			}
+		}
+
+		for _, record := range records {
+			if record.Action == "" || s.Manager == nil {
+				continue
+			}
+			job := s.taskJob(record.Name, record.Spec, record.Action, record.TaskID, record.Config)
+			if err := s.add(job, record.Action, record.TaskID); err != nil {
+				Logger.Errorf("Fail to restore job %s:%v", record.Name, err)
+			}
+		}
+	}
+
+	s.quit = make(chan bool)
+	go func(quit chan bool) {
+		for {
+			select {
+			case <-quit:
+				return
+			case now := <-time.After(schedulerInterval):
+				s.tick(now)
+			}
+		}
+	}(s.quit)
+
+	return nil
+}
+
+func (s *Scheduler) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.quit != nil {
+		close(s.quit)
+		s.quit = nil
+	}
+}
@@ -0,0 +1,147 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	window := Window{Start: "0 0 11 * * 5", End: "0 0 17 * * 5"}
+
+	// 2018-03-09 is Friday
+	cases := map[time.Time]bool{
+		time.Date(2018, 3, 9, 10, 59, 59, 0, time.Local): false,
+		time.Date(2018, 3, 9, 11, 0, 0, 0, time.Local):   true,
+		time.Date(2018, 3, 9, 16, 59, 59, 0, time.Local): true,
+		time.Date(2018, 3, 9, 17, 0, 0, 0, time.Local):   false,
+		time.Date(2018, 3, 8, 12, 0, 0, 0, time.Local):   false,
+	}
+	for now, expect := range cases {
+		if err, active := window.Active(now); err != nil || active != expect {
+			t.Errorf("%v expect:%v got:%v %v", now, expect, active, err)
+		}
+	}
+
+	if err, active := (Window{}).Active(time.Now()); err != nil || active {
+		t.Errorf("The empty window should not be active")
+	}
+	if err, _ := (Window{Start: "invalid", End: "@daily"}).Active(time.Now()); err == nil {
+		t.Errorf("The invalid spec should fail")
+	}
+}
+
+func waitJob(scheduler *Scheduler, name string) *JobInfo {
+	for i := 0; i < 50; i++ {
+		if _, info := scheduler.Get(name); info != nil && !info.Running && !info.LastRun.IsZero() {
+			return info
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func TestScheduler(t *testing.T) {
+	scheduler := new(Scheduler)
+
+	runs := make(chan bool, 10)
+	if err := scheduler.Add(Job{Name: "hourly", Spec: "0 0 * * * *", Run: func() error {
+		runs <- true
+		return nil
+	}}); err != nil {
+		t.Fatalf("Fail to add:%v", err)
+	}
+	if err := scheduler.Add(Job{Name: "failed", Spec: "@daily", Run: func() error {
+		return errors.New("failed")
+	}}); err != nil {
+		t.Fatalf("Fail to add:%v", err)
+	}
+
+	if err := scheduler.Add(Job{Name: "hourly", Spec: "@daily", Run: func() error { return nil }}); err == nil {
+		t.Errorf("The name should be unique")
+	}
+	if err := scheduler.Add(Job{Name: "invalid", Spec: "invalid", Run: func() error { return nil }}); err == nil {
+		t.Errorf("The invalid spec should fail")
+	}
+
+	jobs := scheduler.List()
+	if len(jobs) != 2 || jobs[0].Name != "hourly" || !jobs[0].Next.After(time.Now()) || jobs[0].Next.Minute() != 0 {
+		t.Fatalf("Invalid jobs:%v", jobs)
+	}
+
+	// only the due jobs run
+	next := jobs[0].Next
+	scheduler.tick(next)
+	if info := waitJob(scheduler, "hourly"); info == nil || info.LastError != "" || !info.Next.Equal(next.Add(time.Hour)) {
+		t.Errorf("Invalid job:%v", info)
+	}
+	if len(runs) != 1 {
+		t.Errorf("The job should run once:%v", len(runs))
+	}
+	if _, info := scheduler.Get("failed"); !info.LastRun.IsZero() {
+		t.Errorf("The job is not due:%v", info)
+	}
+
+	if err := scheduler.Run("failed"); err == nil {
+		t.Errorf("The error of the job should be returned")
+	}
+	if _, info := scheduler.Get("failed"); info.LastError != "failed" || info.LastRun.IsZero() {
+		t.Errorf("The error should be recorded:%v", info)
+	}
+
+	scheduler.Add(Job{Name: "panic", Spec: "@daily", Run: func() error { panic("panic") }})
+	if err := scheduler.Run("panic"); err == nil {
+		t.Errorf("The panic should be recovered as the error")
+	}
+
+	if err := scheduler.Remove("hourly"); err != nil || len(scheduler.List()) != 2 {
+		t.Errorf("Fail to remove:%v", err)
+	}
+	if err := scheduler.Run("hourly"); err == nil {
+		t.Errorf("The removed job should not run")
+	}
+}
+
+func TestSchedulerTaskJob(t *testing.T) {
+	manager := new(TaskManager)
+	scheduler := &Scheduler{Manager: manager}
+	manager.Scheduler = scheduler
+	manager.Register(func() ITask { return new(fakeTask) })
+
+	err, id := manager.Create("fake", `{"coin":"eth"}`)
+	if err != nil {
+		t.Fatalf("Fail to create:%v", err)
+	}
+
+	if err := scheduler.AddTaskJob("start", "0 30 9 * * 1-5", JobActionStart, id, ""); err != nil {
+		t.Fatalf("Fail to add:%v", err)
+	}
+	if err := scheduler.AddTaskJob("stop", "0 0 16 * * 1-5", JobActionStop, id, ""); err != nil {
+		t.Fatalf("Fail to add:%v", err)
+	}
+	if err := scheduler.AddTaskJob("unknown", "@daily", JobActionStart, "unknown", ""); err == nil {
+		t.Errorf("The task should exist")
+	}
+	if err := scheduler.AddTaskJob("invalid", "@daily", "invalid", id, ""); err == nil {
+		t.Errorf("The action should be valid")
+	}
+
+	if _, info := scheduler.Get("start"); info.Next.Hour() != 9 || info.Next.Minute() != 30 || info.TaskID != id {
+		t.Errorf("Invalid job:%v", info)
+	}
+
+	if err := scheduler.Run("start"); err != nil || manager.Get(id).GetStatus() != StatusProcessing {
+		t.Errorf("Fail to start:%v", err)
+	}
+	if task := manager.Get(id).(*fakeTask); task.config != `{"coin":"eth"}` {
+		t.Errorf("The saved config should be used:%v", task.config)
+	}
+	if err := scheduler.Run("stop"); err != nil || manager.Get(id).GetStatus() != StatusNone {
+		t.Errorf("Fail to stop:%v", err)
+	}
+
+	// the jobs are removed with the task
+	if err := manager.Remove(id); err != nil || len(scheduler.List()) != 0 {
+		t.Errorf("The jobs should be removed:%v %v", err, scheduler.List())
+	}
+}
@@ -3,6 +3,7 @@ package task
 import (
 	"errors"
 	"math"
+	"strings"
 
 	"github.com/kataras/golog"
 
@@ -47,6 +48,10 @@ const (
 	TaskAPINotFound
 	TaskIOCReturn
 	TaskInvalidResponseFromServer
+	TaskNotFound
+	TaskNotSupported
+	TaskLostStrategy
+	TaskUnreconciled
 )
 
 var TaskErrorMsg = map[TaskErrorType]string{
@@ -60,6 +65,10 @@ var TaskErrorMsg = map[TaskErrorType]string{
 	TaskLostMongodb:       "Lost the connection of Mongodb",
 	TaskInvalidInput:      "Invalid Input",
 	TaskAPINotFound:       "API or Key not found",
+	TaskNotFound:          "Task not found",
+	TaskNotSupported:      "Not supported",
+	TaskLostStrategy:      "Lost the connection of the strategy process",
+	TaskUnreconciled:      "The positions are not reconciled with the exchange",
 }
 
 type TradeResult struct {
@@ -85,6 +94,7 @@ func ProcessTradeRoutineIOC(exchange Exchange.IExchange,
 		var errorCode TaskErrorType
 
 		var tradePrice, tradeAmount float64
+		execution := StartExecution(exchange, tradeConfig)
 
 		for {
 
@@ -106,6 +116,8 @@ func ProcessTradeRoutineIOC(exchange Exchange.IExchange,
 					Quantity: tradeAmount,
 					Price:    tradePrice,
 					OrderID:  trade.OrderID,
+					Decision: tradeConfig.Price,
+					Arrival:  execution.Record.Arrival,
 				}); err != nil {
 					Logger.Errorf("保存交易操作失败:%v", err)
 				}
@@ -114,6 +126,7 @@ func ProcessTradeRoutineIOC(exchange Exchange.IExchange,
 			if trade != nil && trade.Error == nil {
 
 				if trade.Info == nil {
+					execution.Filled(0, 0)
 					channel <- TradeResult{
 						Error: TaskIOCReturn,
 					}
@@ -123,8 +136,9 @@ func ProcessTradeRoutineIOC(exchange Exchange.IExchange,
 					avgPrice = trade.Info.AvgPrice
 
 					if dbTrades != nil {
-						dbTrades.SetDone(trade.OrderID)
+						dbTrades.SetFill(trade.OrderID, avgPrice, dealAmount, execution.Latency())
 					}
+					execution.Filled(dealAmount, avgPrice)
 					channel <- TradeResult{
 						Error:      TaskErrorSuccess,
 						DealAmount: dealAmount,
@@ -135,6 +149,7 @@ func ProcessTradeRoutineIOC(exchange Exchange.IExchange,
 
 			} else {
 				Logger.Errorf("交易失败：%v", trade.Error)
+				execution.Filled(0, 0)
 				errorCode = TaskUnableTrade
 				channel <- TradeResult{
 					Error: errorCode,
@@ -307,6 +322,21 @@ func CalcDepthPrice(isFuture bool, ratios map[string]float64, exchange Exchange.
 	return errors.New("Invalid depth"), 0, 0, 0, 0
 }
 
+// ParseBalance the balance of the coin is either the value or the map with the key "balance", the coin is case-insensitive
+func ParseBalance(balances map[string]interface{}, coin string) (float64, bool) {
+	for _, key := range []string{coin, strings.ToLower(coin), strings.ToUpper(coin)} {
+		switch value := balances[key].(type) {
+		case float64:
+			return value, true
+		case map[string]interface{}:
+			if amount, ok := value["balance"].(float64); ok {
+				return amount, true
+			}
+		}
+	}
+	return 0, false
+}
+
 func Reconnect(exchange Exchange.IExchange) {
 	Logger.Debug("Reconnecting......")
 	Utils.SleepAsyncBySecond(10)
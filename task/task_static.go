@@ -50,6 +50,8 @@ type ITask interface {
 	GetDefaultConfig() interface{}
 	GetDescription() Description
 	Start(configJSON string) error
+	// UpdateConfig() applies the config to the running task, the fields which can't be changed at runtime are rejected
+	UpdateConfig(configJSON string) error
 	Close()
 	GetStatus() StatusType
 
@@ -0,0 +1,148 @@
+package trend
+
+import (
+	"errors"
+	"math"
+
+	Exchange "madaoQT/exchange"
+)
+
+/*
+	FXCM、OANDA和IB的接口与IExchange不一致，并且都以市价成交，
+	适配后下单直接返回成交的订单，OrderID为平仓时需要的持仓编号
+*/
+
+type fxcmAdapter struct {
+	*Exchange.FXCM
+}
+
+func (p *fxcmAdapter) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	result := p.FXCM.Trade(configs)
+	if result == nil {
+		return &Exchange.TradeResult{Error: errors.New("Fail to trade")}
+	} else if result.Error != nil {
+		return result
+	}
+
+	info := &Exchange.OrderInfo{
+		Pair:       configs.Pair,
+		OrderID:    configs.Batch,
+		Type:       configs.Type,
+		Price:      configs.Price,
+		Amount:     configs.Amount,
+		DealAmount: configs.Amount,
+		AvgPrice:   configs.Price,
+		Status:     Exchange.OrderStatusDone,
+	}
+
+	if configs.Type == Exchange.TradeTypeOpenLong || configs.Type == Exchange.TradeTypeOpenShort {
+		if position := p.FXCM.GetOpenPositions(); position != nil {
+			info.OrderID = position.OrderID
+			info.AvgPrice = position.AvgPrice
+		}
+	} else if position := p.FXCM.GetClosePositions(Exchange.OrderInfo{OrderID: configs.Batch}); position != nil {
+		info.AvgPrice = position.AvgPrice
+	}
+
+	return &Exchange.TradeResult{
+		OrderID: info.OrderID,
+		Info:    info,
+	}
+}
+
+func (p *fxcmAdapter) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	if order := p.FXCM.GetOrderInfo(filter); order != nil {
+		order.Status = Exchange.OrderStatusDone
+		return []Exchange.OrderInfo{*order}
+	}
+	return nil
+}
+
+type oandaAdapter struct {
+	*Exchange.OandaAPI
+}
+
+func (p *oandaAdapter) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	result := p.OandaAPI.Trade(configs)
+	if result == nil {
+		return &Exchange.TradeResult{Error: errors.New("Fail to trade")}
+	} else if result.Error != nil || result.Info == nil {
+		return result
+	}
+
+	info := *result.Info
+	info.Type = configs.Type
+	info.Price = configs.Price
+	info.Amount = configs.Amount
+	info.DealAmount = math.Abs(info.DealAmount)
+	info.Status = Exchange.OrderStatusDone
+
+	// the trade is identified by the transaction which filled the order
+	if configs.Type == Exchange.TradeTypeOpenLong || configs.Type == Exchange.TradeTypeOpenShort {
+		if order := p.OandaAPI.GetOrderInfo(Exchange.OrderInfo{OrderID: info.OrderID}); order != nil {
+			info.OrderID = order.OrderID
+		}
+	}
+
+	return &Exchange.TradeResult{
+		OrderID: info.OrderID,
+		Info:    &info,
+	}
+}
+
+func (p *oandaAdapter) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	if order := p.OandaAPI.GetPositionInfo(filter); order != nil {
+		order.DealAmount = math.Abs(order.DealAmount)
+		order.Status = Exchange.OrderStatusDone
+		return []Exchange.OrderInfo{*order}
+	}
+	return nil
+}
+
+func (p *oandaAdapter) GetKline(pair string, period int, limit int) []Exchange.KlineValue {
+	klines, _, _ := p.OandaAPI.GetKline(pair, period, limit, 0, Exchange.DirectionMid)
+	return klines
+}
+
+type ibAdapter struct {
+	*Exchange.InteractiveBrokers
+	// conType the security type of the contract, such as "STK" and "FUT"
+	conType string
+}
+
+func (p *ibAdapter) Start() error {
+	if err := p.InteractiveBrokers.Start(); err != nil {
+		return err
+	}
+	err, _ := p.InteractiveBrokers.GetAccountUID()
+	return err
+}
+
+func (p *ibAdapter) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	result := p.InteractiveBrokers.Trade(configs, p.conType)
+	if result == nil {
+		return &Exchange.TradeResult{Error: errors.New("Fail to trade")}
+	} else if result.Error != nil {
+		return result
+	}
+
+	info := &Exchange.OrderInfo{
+		Pair:       configs.Pair,
+		OrderID:    result.OrderID,
+		Type:       configs.Type,
+		Price:      configs.Price,
+		Amount:     configs.Amount,
+		DealAmount: configs.Amount,
+		AvgPrice:   configs.Price,
+		Status:     Exchange.OrderStatusDone,
+	}
+
+	if positions := p.InteractiveBrokers.GetOrderInfo(Exchange.OrderInfo{Pair: configs.Pair}); len(positions) > 0 && positions[0].AvgPrice > 0 {
+		info.AvgPrice = positions[0].AvgPrice
+	}
+
+	return &Exchange.TradeResult{
+		OrderID: info.OrderID,
+		Info:    info,
+	}
+}
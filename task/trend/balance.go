@@ -0,0 +1,41 @@
+package trend
+
+import (
+	"errors"
+	"time"
+
+	MongoTrend "madaoQT/mongo/trend"
+)
+
+// BalanceManager 查询趋势策略保存的余额记录
+type BalanceManager struct {
+	balances *MongoTrend.BalanceStruct
+}
+
+func (p *BalanceManager) Init(balances *MongoTrend.BalanceStruct) {
+	p.balances = balances
+}
+
+// GetDialyLast 获取当天最后一次的余额记录
+func (p *BalanceManager) GetDialyLast(date time.Time) (error, *MongoTrend.BalanceInfo) {
+	if p.balances == nil {
+		return errors.New("BalanceManager is not initialized"), nil
+	}
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	err, records := p.balances.FindAll(map[string]interface{}{
+		"time": map[string]interface{}{
+			"$gte": start,
+			"$lt":  start.AddDate(0, 0, 1),
+		},
+	}, "-time")
+	if err != nil {
+		return err, nil
+	}
+
+	if len(records) == 0 {
+		return errors.New("No balance record"), nil
+	}
+
+	return nil, &records[0]
+}
@@ -0,0 +1,98 @@
+package trend
+
+import (
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	MongoTrend "madaoQT/mongo/trend"
+	Task "madaoQT/task"
+)
+
+// protectionSource the venues of the protections opened by the trend tasks
+const protectionSource = "trend"
+
+func init() {
+	Task.GlobalProtector.RegisterVenues(protectionSource, func(name string) *Task.ProtectionVenue {
+		venue := venues[name]
+		if venue == nil {
+			return nil
+		}
+		return &Task.ProtectionVenue{Exchange: venue.Exchange, Create: venue.create}
+	})
+}
+
+func (p *TrendOkex) protectionID(position *MongoTrend.TradeInfo) string {
+	return Task.CollectionName(p.namespace, "trend") + ":" + position.Batch
+}
+
+// protect attaches the stop loss, the take profit and the trailing stop to the opened position
+func (p *TrendOkex) protect(position *MongoTrend.TradeInfo) {
+	closeType := Exchange.TradeTypeCloseLong
+	if position.Type == PositionShort {
+		closeType = Exchange.TradeTypeCloseShort
+	} else if p.venue.Spot {
+		closeType = Exchange.TradeTypeSell
+	}
+
+	record := &Mongo.ProtectionRecord{
+		ID:        p.protectionID(position),
+		Task:      Task.CollectionName(p.namespace, "trend"),
+		Source:    protectionSource,
+		Venue:     p.config.Venue,
+		Account:   p.config.Account,
+		Custom:    p.config.Custom,
+		Proxy:     p.config.Proxy,
+		Pair:      position.Pair,
+		CloseType: int(closeType),
+		Amount:    position.FutureAmount,
+		Entry:     position.FutureOpen,
+	}
+	if p.venue.CloseByOrder {
+		record.TradeID = position.OrderID
+	}
+
+	if err := Task.GlobalProtector.Attach(p.exchange, record, p.config.Protection); err != nil {
+		Logger.Errorf("Fail to protect the position:%v", err)
+	}
+}
+
+// reprotect protects the amount left after the position is closed partly
+func (p *TrendOkex) reprotect(position *MongoTrend.TradeInfo) {
+	if err := Task.GlobalProtector.Resize(p.exchange, p.protectionID(position), position.FutureAmount); err != nil {
+		Logger.Errorf("Fail to resize the protection:%v", err)
+	}
+}
+
+func (p *TrendOkex) unprotect(position *MongoTrend.TradeInfo) {
+	if err := Task.GlobalProtector.Detach(p.exchange, p.protectionID(position)); err != nil {
+		Logger.Errorf("Fail to cancel the protection:%v", err)
+	}
+}
+
+// protected returns true if the position is closed by the protection, the record is closed by the triggered price
+func (p *TrendOkex) protected(position *MongoTrend.TradeInfo) bool {
+	triggered, price := Task.GlobalProtector.Triggered(p.protectionID(position))
+	if !triggered {
+		return false
+	}
+
+	Logger.Infof("仓位保护已平仓 开仓价:%v 触发价:%v", position.FutureOpen, price)
+	if p.db != nil {
+		if err := p.db.TradeCollection.Update(map[string]interface{}{
+			"batch": position.Batch,
+		}, map[string]interface{}{
+			"closetime":   time.Now(),
+			"futureclose": price,
+			"status":      MongoTrend.TradeStatusClose,
+		}); err != nil {
+			Logger.Errorf("更新交易记录失败:%v", err)
+		}
+	}
+	p.unprotect(position)
+
+	p.lock.Lock()
+	p.position = nil
+	p.lock.Unlock()
+	return true
+}
@@ -0,0 +1,178 @@
+package trend
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	MongoTrend "madaoQT/mongo/trend"
+	Task "madaoQT/task"
+	Utils "madaoQT/utils"
+)
+
+/*
+	启动前核对持仓：合约交易所对比保存的仓位与交易所的持仓，现货交易所对比仓位与余额，
+	交易所中的挂单都不是任务下的单。接管时按交易所的数量修改或者新增持仓记录
+*/
+
+// reconcileTolerance the differences less than it are ignored
+const reconcileTolerance = 1e-6
+
+// savedState the position is signed on the futures venues, the spot position requires the balance of the coin
+func savedState(position *MongoTrend.TradeInfo, pair string, spot bool) Task.ReconcileState {
+	state := Task.ReconcileState{
+		Positions: make(map[string]float64),
+		Balances:  make(map[string]float64),
+	}
+	if position == nil {
+		return state
+	}
+
+	if spot {
+		state.Balances[Exchange.ParsePair(pair)[0]] = position.FutureAmount
+	} else if position.Type == PositionShort {
+		state.Positions[pair] = -position.FutureAmount
+	} else {
+		state.Positions[pair] = position.FutureAmount
+	}
+	return state
+}
+
+// actualState the positions aren't compared if the exchange can't list them
+func actualState(exchange Exchange.IExchange, pair string, spot bool) (error, Task.ReconcileState, bool) {
+	state := Task.ReconcileState{
+		Positions: make(map[string]float64),
+		Balances:  make(map[string]float64),
+	}
+	coin := Exchange.ParsePair(pair)[0]
+
+	err, orders := Task.OpenOrders(exchange, pair)
+	if err != nil {
+		return err, state, false
+	}
+	state.Orders = orders
+
+	if gateway, ok := exchange.(*Task.RiskGateway); ok {
+		exchange = gateway.IExchange
+	}
+
+	if spot {
+		balances := exchange.GetBalance()
+		if balances == nil {
+			return errors.New("Fail to get the balances"), state, false
+		}
+		state.Balances[coin], _ = Task.ParseBalance(balances, coin)
+		return nil, state, true
+	}
+
+	account, ok := exchange.(Exchange.IAccount)
+	if !ok {
+		return nil, state, false
+	}
+	err, info := account.GetAccount()
+	if err != nil {
+		return err, state, false
+	}
+	for _, position := range info.Positions {
+		// the quote of the contracts may differ from the pair, such as btc/usd and btc/usdt
+		if Exchange.ParsePair(position.Pair)[0] == coin && position.Amount != 0 {
+			state.Positions[pair] += position.Amount
+		}
+	}
+	return nil, state, true
+}
+
+// reconcile returns the function checked by Task.GlobalReconciler, the position is reloaded after the adoption
+func (p *TrendOkex) reconcile(exchange Exchange.IExchange) Task.ReconcileFunc {
+	return func(adopt bool) (error, []Mongo.ReconcileItem, []Mongo.ReconcileItem) {
+		err, actual, ok := actualState(exchange, p.config.Pair, p.venue.Spot)
+		if err != nil {
+			return err, nil, nil
+		}
+
+		saved := savedState(p.position, p.config.Pair, p.venue.Spot)
+		if !ok {
+			Logger.Infof("%s不支持查询持仓，只核对挂单", p.config.Venue)
+			saved.Positions, saved.Balances = nil, nil
+		}
+
+		items := Task.Compare(p.config.Venue, saved, actual, reconcileTolerance)
+		if !adopt || len(items) == 0 {
+			return nil, items, nil
+		}
+
+		var left, adopted []Mongo.ReconcileItem
+		for _, item := range items {
+			if item.Kind == Mongo.ReconcileOrphanOrder {
+				// the task doesn't manage the open orders
+				left = append(left, item)
+				continue
+			}
+			if err := p.adopt(item); err != nil {
+				Logger.Errorf("Fail to adopt %v:%v", item, err)
+				left = append(left, item)
+				continue
+			}
+			adopted = append(adopted, item)
+		}
+
+		if len(adopted) > 0 {
+			if err := p.loadPosition(); err != nil {
+				return err, nil, nil
+			}
+		}
+		return nil, left, adopted
+	}
+}
+
+// adopt replaces the amount of the position with the actual one, the orphan position is opened without the price
+// so that it isn't stopped by the loss
+func (p *TrendOkex) adopt(item Mongo.ReconcileItem) error {
+	if p.db == nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+
+	positionType := PositionLong
+	tradeType := Exchange.TradeTypeOpenLong
+	if item.Actual < 0 {
+		positionType = PositionShort
+		tradeType = Exchange.TradeTypeOpenShort
+	}
+	amount := math.Abs(item.Actual)
+
+	if p.position != nil {
+		update := map[string]interface{}{
+			"futureamount": amount,
+			"futuretype":   Exchange.TradeTypeString[tradeType],
+			"type":         positionType,
+		}
+		if amount <= reconcileTolerance {
+			update = map[string]interface{}{
+				"status":    MongoTrend.TradeStatusClose,
+				"closetime": time.Now(),
+			}
+		}
+		Logger.Infof("接管持仓:%v", update)
+		return p.db.TradeCollection.Update(map[string]interface{}{
+			"batch":  p.position.Batch,
+			"status": MongoTrend.TradeStatusOpen,
+		}, update)
+	}
+
+	if amount <= reconcileTolerance {
+		return nil
+	}
+	record := &MongoTrend.TradeInfo{
+		Batch:        Utils.GetRandomHexString(12),
+		Pair:         p.config.Pair,
+		OpenTime:     time.Now(),
+		Status:       MongoTrend.TradeStatusOpen,
+		FutureType:   Exchange.TradeTypeString[tradeType],
+		FutureAmount: amount,
+		Type:         positionType,
+	}
+	Logger.Infof("接管孤儿仓位:%v", *record)
+	return p.db.TradeCollection.Insert(record)
+}
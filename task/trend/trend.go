@@ -0,0 +1,921 @@
+package trend
+
+/*
+	趋势策略：按STC(Schaff Trend Cycle)指标在K线收盘时开平仓，
+	STC上穿超卖线开多，下穿超买线开空，反向信号或止损时平仓，
+	交易所由配置中的venue决定，交易和余额记录保存在venue对应的collection中
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	Global "madaoQT/config"
+	Exchange "madaoQT/exchange"
+	Mongo "madaoQT/mongo"
+	MongoTrend "madaoQT/mongo/trend"
+	Task "madaoQT/task"
+	Utils "madaoQT/utils"
+
+	"github.com/kataras/golog"
+)
+
+var Logger *golog.Logger
+
+func init() {
+	logger := golog.New()
+	Logger = logger
+	Logger.SetLevel("debug")
+	Logger.SetTimeFormat(Global.TimeFormat)
+	Logger.SetPrefix("[TREND]")
+}
+
+// MongoServer the server of the trend records
+const MongoServer = Mongo.MongoURL
+
+// klineCount SchaffIndicatorInit needs at least 200 closed klines
+const klineCount = 300
+const checkingPeriod = 10 * time.Second
+const orderWaitCount = 10
+
+// recordBalancesSpec the daily balance snapshot
+const recordBalancesSpec = "@daily"
+
+// the type of the position, the same as the values returned by SchaffTrend.GetThreshValue()
+const (
+	PositionLong  = 1
+	PositionShort = 2
+)
+
+var orderWaitPeriod = time.Second
+
+type SchaffConfig struct {
+	Cycle    int     `json:"cycle" title:"周期" min:"1" required:"true"`
+	Fast     uint    `json:"fast" title:"快线" min:"1" required:"true"`
+	Slow     uint    `json:"slow" title:"慢线" min:"1" required:"true"`
+	Factor   float64 `json:"factor" title:"平滑系数" min:"0" max:"1" required:"true"`
+	OverBuy  float64 `json:"overbuy" title:"超买线" min:"0" max:"100" required:"true"`
+	OverSell float64 `json:"oversell" title:"超卖线" min:"0" max:"100" required:"true"`
+}
+
+type TrendConfig struct {
+	Venue      string                 `json:"venue" title:"交易所" enum:"okex|okexv3|okexv3spot|binance|bitmex|cryptofacilities|deribit|huobispot|huobidm|fxcm|oanda|ib|ctp" required:"true" readonly:"true"`
+	Pair       string                 `json:"pair" title:"交易对" required:"true" readonly:"true"`
+	Period     int                    `json:"period" title:"K线周期" desc:"分钟" min:"1" required:"true"`
+	Amount     float64                `json:"amount" title:"开仓数量" min:"0" required:"true"`
+	LimitOpen  float64                `json:"limitopen" title:"价格波动范围" min:"0" max:"0.1"`
+	LimitClose float64                `json:"limitclose" title:"止损幅度" desc:"0表示不止损" min:"0" max:"1"`
+	Schaff     SchaffConfig           `json:"schaff" title:"STC参数" required:"true"`
+	Account    string                 `json:"account,omitempty" title:"账户" desc:"密钥的账户名称，为空时使用默认账户" readonly:"true"`
+	Custom     map[string]interface{} `json:"custom,omitempty" title:"交易所配置" desc:"如passphrase、period、contype、token、account" readonly:"true"`
+	Proxy      string                 `json:"proxy" title:"代理" readonly:"true"`
+	Risk       Task.RiskConfig        `json:"risk" title:"风控"`
+	Protection Task.ProtectionRule    `json:"protection" title:"仓位保护" desc:"交易所支持时下条件单，否则本地监控"`
+}
+
+var defaultConfig = TrendConfig{
+	Venue:      VenueOKEX,
+	Pair:       "eth/usdt",
+	Period:     Exchange.KlinePeriod2Hour,
+	Amount:     1,
+	LimitOpen:  0.005,
+	LimitClose: 0.03,
+	Schaff: SchaffConfig{
+		Cycle:    10,
+		Fast:     23,
+		Slow:     50,
+		Factor:   0.5,
+		OverBuy:  75,
+		OverSell: 25,
+	},
+	Proxy: "SOCKS5:127.0.0.1:1080",
+}
+
+// TrendOkex the trend task, the venue isn't limited to OKEX but is assigned by the config
+type TrendOkex struct {
+	config   TrendConfig
+	venue    *venue
+	exchange Exchange.IExchange
+	db       *MongoTrend.TrendMongo
+	riskDB   *Mongo.RiskRecords
+
+	status    Task.StatusType
+	namespace string
+	scheduler *Task.Scheduler
+
+	// lock protects the position which is read by the server
+	lock       sync.Mutex
+	position   *MongoTrend.TradeInfo
+	lastTime   float64
+	forceClose bool
+	// closing the rest of the position closed partly is closed on the next check
+	closing bool
+
+	// pending the updated config, which is applied by the trading loop
+	pending     *TrendConfig
+	pendingLock sync.Mutex
+
+	quit chan bool
+}
+
+func (p *TrendOkex) GetDescription() Task.Description {
+	return Task.Description{
+		Name:  "trend",
+		Title: "趋势策略",
+		Desc:  "按STC指标跟踪趋势，支持多个交易所",
+	}
+}
+
+func (p *TrendOkex) GetDefaultConfig() interface{} {
+	return defaultConfig
+}
+
+// SetNamespace isolates the collections of the instance
+func (p *TrendOkex) SetNamespace(namespace string) {
+	p.namespace = namespace
+}
+
+// SetScheduler the daily balance snapshot is registered to the scheduler
+func (p *TrendOkex) SetScheduler(scheduler *Task.Scheduler) {
+	p.scheduler = scheduler
+}
+
+func (p *TrendOkex) GetStatus() Task.StatusType {
+	return p.status
+}
+
+func checkConfig(config TrendConfig) Task.ConfigErrors {
+	var errs Task.ConfigErrors
+	if venues[config.Venue] == nil {
+		errs = append(errs, Task.ConfigError{Field: "venue", Message: "unsupported venue"})
+	}
+	if config.Schaff.OverSell >= config.Schaff.OverBuy {
+		errs = append(errs, Task.ConfigError{Field: "schaff.oversell", Message: "should be less than overbuy"})
+	}
+	if config.Schaff.Fast >= config.Schaff.Slow {
+		errs = append(errs, Task.ConfigError{Field: "schaff.fast", Message: "should be less than slow"})
+	}
+	return errs
+}
+
+// collection the records of the coin are saved separately
+func (p *TrendOkex) collection(name string) string {
+	if name == "" {
+		return ""
+	}
+	coin := Exchange.ParsePair(p.config.Pair)[0]
+	return Task.CollectionName(p.namespace, name+"_"+strings.ToUpper(coin))
+}
+
+func (p *TrendOkex) Start(configJSON string) error {
+
+	if p.status != Task.StatusNone {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	config := defaultConfig
+	if configJSON != "" {
+		config = TrendConfig{}
+		if err := Task.ParseConfig(configJSON, &config); err != nil {
+			Logger.Errorf("Fail to get config:%v", err)
+			return err
+		}
+	}
+	if errs := checkConfig(config); errs != nil {
+		return errs
+	}
+	p.config = config
+	p.venue = venues[config.Venue]
+
+	Logger.Infof("Config:%v", p.config)
+
+	mongo := new(Mongo.ExchangeDB)
+	if mongo.Connect() != nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+	defer mongo.Close()
+
+	err, record := mongo.FindAccount(p.venue.Exchange, p.config.Account)
+	if err != nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskAPINotFound])
+	}
+
+	p.db = &MongoTrend.TrendMongo{
+		TradeCollectionName:   p.collection(p.venue.Trade),
+		BalanceCollectionName: p.collection(p.venue.Balance),
+		FundCollectionName:    p.collection(p.venue.Fund),
+		Server:                MongoServer,
+	}
+	if err := p.db.Connect(); err != nil {
+		Logger.Errorf("Fail to connect TrendDB:%v", err)
+		p.db = nil
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+
+	p.riskDB = Task.ConnectRiskRecords()
+	exchange := Task.NewRiskGateway(p.venue.create(Exchange.Config{
+		API:    string(record.API),
+		Secret: string(record.Secret),
+		Custom: p.config.Custom,
+		Proxy:  p.config.Proxy,
+	}), Task.CollectionName(p.namespace, "trend"), p.config.Risk, p.riskDB)
+	if p.venue.Contract != nil {
+		exchange.SetContract(p.venue.Contract)
+	}
+
+	Logger.Infof("启动%s趋势策略:%s", p.config.Venue, p.config.Pair)
+	if err := exchange.Start(); err != nil {
+		Logger.Errorf("Fail to start:%v", err)
+		exchange.Unregister()
+		p.db.Disconnect()
+		p.db = nil
+		if p.riskDB != nil {
+			p.riskDB.Close()
+			p.riskDB = nil
+		}
+		return err
+	}
+
+	// the task isn't started if the position can't be loaded and reconciled
+	err = p.loadPosition()
+	if err == nil {
+		err = Task.GlobalReconciler.Check(Task.CollectionName(p.namespace, "trend"), p.reconcile(exchange))
+	}
+	if err != nil {
+		Logger.Errorf("Fail to reconcile the position:%v", err)
+		exchange.Close()
+		p.db.Disconnect()
+		p.db = nil
+		if p.riskDB != nil {
+			p.riskDB.Close()
+			p.riskDB = nil
+		}
+		return err
+	}
+	if position := p.position; position != nil {
+		tradeType := Exchange.TradeTypeOpenLong
+		if position.Type == PositionShort {
+			tradeType = Exchange.TradeTypeOpenShort
+		}
+		exchange.AddPosition(p.config.Pair, tradeType, position.FutureAmount, position.FutureOpen)
+	}
+
+	if p.scheduler != nil {
+		if err := p.scheduler.Add(Task.Job{
+			Name: p.recordBalancesJob(),
+			Spec: recordBalancesSpec,
+			Desc: "记录余额",
+			Run:  p.RecordBalances,
+		}); err != nil {
+			Logger.Errorf("Fail to add the job:%v", err)
+		}
+	}
+
+	p.run(exchange)
+	return nil
+}
+
+// run starts the trading loop on the exchange
+func (p *TrendOkex) run(exchange Exchange.IExchange) {
+	p.exchange = exchange
+	p.lastTime = 0
+	p.forceClose = false
+	p.status = Task.StatusProcessing
+	p.quit = make(chan bool)
+
+	go func(quit chan bool) {
+		for {
+			select {
+			case <-quit:
+				return
+			case event := <-exchange.WatchEvent():
+				if event == Exchange.EventLostConnection && p.status == Task.StatusProcessing {
+					go Task.Reconnect(exchange)
+				}
+			case <-time.After(checkingPeriod):
+				p.applyConfig()
+				p.Watch()
+			}
+		}
+	}(p.quit)
+}
+
+func (p *TrendOkex) recordBalancesJob() string {
+	return Task.CollectionName(p.namespace, "TrendRecordBalances")
+}
+
+// UpdateConfig 运行时更新K线周期、数量和STC参数，交易所和交易对不能修改
+func (p *TrendOkex) UpdateConfig(configJSON string) error {
+
+	if p.status != Task.StatusProcessing {
+		return errors.New(Task.TaskErrorMsg[Task.TaskErrorStatus])
+	}
+
+	var config TrendConfig
+	if err := Task.ParseConfig(configJSON, &config); err != nil {
+		return err
+	}
+
+	errs := checkConfig(config)
+	if config.Venue != p.config.Venue {
+		errs = append(errs, Task.ConfigError{Field: "venue", Message: "can't be changed while the task is running"})
+	}
+	if config.Pair != p.config.Pair {
+		errs = append(errs, Task.ConfigError{Field: "pair", Message: "can't be changed while the task is running"})
+	}
+	if errs != nil {
+		return errs
+	}
+
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+	p.pending = &config
+	return nil
+}
+
+func (p *TrendOkex) applyConfig() {
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+
+	if p.pending != nil {
+		if p.pending.Period != p.config.Period || p.pending.Schaff != p.config.Schaff {
+			// the signal is recalculated with the new parameters
+			p.lastTime = 0
+		}
+		p.config = *p.pending
+		p.pending = nil
+		if gateway, ok := p.exchange.(*Task.RiskGateway); ok {
+			gateway.SetConfig(p.config.Risk)
+		}
+		Logger.Infof("更新配置:%v", p.config)
+	}
+}
+
+func (p *TrendOkex) loadPosition() error {
+	if p.db == nil {
+		return nil
+	}
+
+	err, records := p.db.TradeCollection.Find(map[string]interface{}{
+		"pair":   p.config.Pair,
+		"status": MongoTrend.TradeStatusOpen,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.position = nil
+	if len(records) > 0 {
+		p.position = &records[len(records)-1]
+		Logger.Infof("加载持仓:%v", *p.position)
+	}
+	return nil
+}
+
+// signal returns whether to close the position and the position to open by the last two STC values:
+// crossing above the oversell line is bullish and crossing below the overbuy line is bearish
+func signal(config SchaffConfig, last float64, current float64, position int) (bool, int) {
+	bullish := last <= config.OverSell && current > config.OverSell
+	bearish := last >= config.OverBuy && current < config.OverBuy
+
+	switch position {
+	case PositionLong:
+		if bearish {
+			return true, PositionShort
+		}
+	case PositionShort:
+		if bullish {
+			return true, PositionLong
+		}
+	default:
+		if bullish {
+			return false, PositionLong
+		} else if bearish {
+			return false, PositionShort
+		}
+	}
+	return false, 0
+}
+
+// stopLoss whether the loss of the position reaches the limit, the limit 0 disables the stop loss
+func stopLoss(position *MongoTrend.TradeInfo, price float64, limit float64) bool {
+	if position == nil || limit <= 0 || position.FutureOpen <= 0 {
+		return false
+	}
+	if position.Type == PositionLong {
+		return price <= position.FutureOpen*(1-limit)
+	}
+	return price >= position.FutureOpen*(1+limit)
+}
+
+// schaffValues returns the STC values of the last two closed klines
+func (p *TrendOkex) schaffValues(closed []Exchange.KlineValue) (error, float64, float64) {
+	schaff := &Exchange.SchaffTrend{
+		Period:     p.config.Schaff.Cycle,
+		FastLength: p.config.Schaff.Fast,
+		SlowLength: p.config.Schaff.Slow,
+		Factor:     p.config.Schaff.Factor,
+		OverBuy:    p.config.Schaff.OverBuy,
+		OverSell:   p.config.Schaff.OverSell,
+	}
+
+	if !schaff.SchaffIndicatorInit(closed[:len(closed)-1]) || !schaff.Ready() {
+		return errors.New("Fail to init the indicator"), 0, 0
+	}
+
+	last := schaff.GetLastSchaffValue()
+	schaff.Update(closed[len(closed)-1])
+	return nil, last, schaff.GetLastSchaffValue()
+}
+
+// Watch checks the stop loss on every check, and the signal when the kline is closed
+func (p *TrendOkex) Watch() {
+	if p.status != Task.StatusProcessing {
+		return
+	}
+
+	klines := Task.GlobalKlines.GetKline(p.exchange, p.config.Pair, p.config.Period, klineCount)
+	if len(klines) < 201 {
+		Logger.Errorf("K线数量不足:%d", len(klines))
+		return
+	}
+
+	// the last kline isn't closed
+	closed := klines[:len(klines)-1]
+	price := klines[len(klines)-1].Close
+
+	p.lock.Lock()
+	position := p.position
+	p.lock.Unlock()
+
+	if position != nil {
+		if p.protected(position) {
+			return
+		}
+		if p.closing {
+			Logger.Infof("继续平仓 剩余数量:%v", position.FutureAmount)
+			p.closePosition(position, price)
+			return
+		}
+		if p.forceClose {
+			Logger.Info("强制平仓")
+			p.closePosition(position, price)
+			p.forceClose = false
+			return
+		}
+		if stopLoss(position, price, p.config.LimitClose) {
+			Logger.Infof("止损 开仓价:%v 当前价:%v", position.FutureOpen, price)
+			p.closePosition(position, price)
+			return
+		}
+	}
+	p.forceClose = false
+
+	lastTime := closed[len(closed)-1].OpenTime
+	if lastTime == p.lastTime {
+		return
+	}
+
+	err, last, current := p.schaffValues(closed)
+	if err != nil {
+		Logger.Errorf("Error:%v", err)
+		return
+	}
+	p.lastTime = lastTime
+	Logger.Debugf("STC:%.2f => %.2f", last, current)
+
+	positionType := 0
+	if position != nil {
+		positionType = position.Type
+	}
+
+	closing, open := signal(p.config.Schaff, last, current, positionType)
+	if closing && !p.closePosition(position, price) {
+		return
+	}
+
+	if open == PositionShort && p.venue.Spot {
+		return
+	}
+	if open != 0 {
+		p.openPosition(open, price)
+	}
+}
+
+// trade places the order and waits until it's filled, the rest is canceled. The execution is measured from the price
+// of the kline
+func (p *TrendOkex) trade(tradeType Exchange.TradeType, batch string, price float64, amount float64) (error, *Exchange.OrderInfo) {
+	if p.venue.Spot {
+		if tradeType == Exchange.TradeTypeOpenLong {
+			tradeType = Exchange.TradeTypeBuy
+		} else if tradeType == Exchange.TradeTypeCloseLong {
+			tradeType = Exchange.TradeTypeSell
+		}
+	}
+
+	execution := Task.StartExecution(p.exchange, Exchange.TradeConfig{
+		Batch:  batch,
+		Pair:   p.config.Pair,
+		Type:   tradeType,
+		Price:  price,
+		Amount: amount,
+		Limit:  p.config.LimitOpen,
+	})
+	err, info := p.placeOrder(tradeType, batch, price, amount)
+	if info != nil {
+		execution.Filled(info.DealAmount, info.AvgPrice)
+	} else {
+		execution.Filled(0, 0)
+	}
+	return err, info
+}
+
+func (p *TrendOkex) placeOrder(tradeType Exchange.TradeType, batch string, price float64, amount float64) (error, *Exchange.OrderInfo) {
+	result := p.exchange.Trade(Exchange.TradeConfig{
+		Batch:  batch,
+		Pair:   p.config.Pair,
+		Type:   tradeType,
+		Price:  Task.GetPlacedPrice(tradeType, price, p.config.LimitOpen),
+		Amount: amount,
+		Limit:  p.config.LimitOpen,
+	})
+	if result == nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskUnableTrade]), nil
+	} else if result.Error != nil {
+		return result.Error, nil
+	}
+
+	info := result.Info
+	for i := 0; i < orderWaitCount; i++ {
+		if info != nil && info.Status == Exchange.OrderStatusDone {
+			return nil, info
+		}
+		time.Sleep(orderWaitPeriod)
+		if orders := p.exchange.GetOrderInfo(Exchange.OrderInfo{
+			OrderID: result.OrderID,
+			Pair:    p.config.Pair,
+			Type:    tradeType,
+		}); len(orders) > 0 {
+			info = &orders[0]
+		}
+	}
+
+	p.exchange.CancelOrder(Exchange.OrderInfo{
+		OrderID: result.OrderID,
+		Pair:    p.config.Pair,
+		Type:    tradeType,
+	})
+	if orders := p.exchange.GetOrderInfo(Exchange.OrderInfo{
+		OrderID: result.OrderID,
+		Pair:    p.config.Pair,
+		Type:    tradeType,
+	}); len(orders) > 0 {
+		info = &orders[0]
+	}
+
+	if info == nil || info.DealAmount <= 0 {
+		return errors.New(Task.TaskErrorMsg[Task.TaskUnableTrade]), nil
+	}
+	return nil, info
+}
+
+func (p *TrendOkex) openPosition(positionType int, price float64) {
+	tradeType := Exchange.TradeTypeOpenLong
+	if positionType == PositionShort {
+		tradeType = Exchange.TradeTypeOpenShort
+	}
+
+	batch := Utils.GetRandomHexString(12)
+	Logger.Infof("开仓:%s 价格:%v 数量:%v", Exchange.TradeTypeString[tradeType], price, p.config.Amount)
+
+	err, info := p.trade(tradeType, batch, price, p.config.Amount)
+	if err != nil {
+		Logger.Errorf("开仓失败:%v", err)
+		return
+	}
+
+	record := &MongoTrend.TradeInfo{
+		Batch:        batch,
+		Pair:         p.config.Pair,
+		OpenTime:     time.Now(),
+		Status:       MongoTrend.TradeStatusOpen,
+		FutureType:   Exchange.TradeTypeString[tradeType],
+		FutureOpen:   info.AvgPrice,
+		FutureAmount: info.DealAmount,
+		Type:         positionType,
+		OrderID:      info.OrderID,
+	}
+
+	if p.db != nil {
+		if err := p.db.TradeCollection.Insert(record); err != nil {
+			Logger.Errorf("保存交易记录失败:%v", err)
+		}
+	}
+
+	p.lock.Lock()
+	p.position = record
+	p.lock.Unlock()
+
+	p.protect(record)
+}
+
+// closePosition returns false if the position isn't closed. The part closed is saved as a closed record, and the
+// rest is kept in the position and closed on the next check
+func (p *TrendOkex) closePosition(position *MongoTrend.TradeInfo, price float64) bool {
+	tradeType := Exchange.TradeTypeCloseLong
+	if position.Type == PositionShort {
+		tradeType = Exchange.TradeTypeCloseShort
+	}
+
+	batch := position.Batch
+	if p.venue.CloseByOrder {
+		batch = position.OrderID
+	}
+
+	Logger.Infof("平仓:%s 价格:%v 数量:%v", Exchange.TradeTypeString[tradeType], price, position.FutureAmount)
+	err, info := p.trade(tradeType, batch, price, position.FutureAmount)
+	if err != nil {
+		Logger.Errorf("平仓失败:%v", err)
+		return false
+	}
+
+	if left := position.FutureAmount - info.DealAmount; left > 1e-9 {
+		Logger.Errorf("部分平仓:%v/%v", info.DealAmount, position.FutureAmount)
+		p.closePart(position, info, left)
+		return false
+	}
+
+	if p.db != nil {
+		if err := p.db.TradeCollection.Update(map[string]interface{}{
+			"batch": position.Batch,
+		}, map[string]interface{}{
+			"closetime":   time.Now(),
+			"futureclose": info.AvgPrice,
+			"status":      MongoTrend.TradeStatusClose,
+		}); err != nil {
+			Logger.Errorf("更新交易记录失败:%v", err)
+		}
+	}
+	p.unprotect(position)
+
+	p.lock.Lock()
+	p.position = nil
+	p.closing = false
+	p.lock.Unlock()
+	return true
+}
+
+// closePart records the part closed, the amount left is kept open and protected
+func (p *TrendOkex) closePart(position *MongoTrend.TradeInfo, info *Exchange.OrderInfo, left float64) {
+	if p.db != nil {
+		part := *position
+		part.Batch = position.Batch + "-" + Utils.GetRandomHexString(4)
+		part.CloseTime = time.Now()
+		part.FutureClose = info.AvgPrice
+		part.FutureAmount = info.DealAmount
+		part.Status = MongoTrend.TradeStatusClose
+		if err := p.db.TradeCollection.Insert(&part); err != nil {
+			Logger.Errorf("保存交易记录失败:%v", err)
+		}
+		if err := p.db.TradeCollection.Update(map[string]interface{}{
+			"batch": position.Batch,
+		}, map[string]interface{}{
+			"futureamount": left,
+		}); err != nil {
+			Logger.Errorf("更新交易记录失败:%v", err)
+		}
+	}
+
+	p.lock.Lock()
+	position.FutureAmount = left
+	p.closing = true
+	p.lock.Unlock()
+	p.reprotect(position)
+}
+
+func (p *TrendOkex) GetBalances() map[string]interface{} {
+	if p.exchange == nil {
+		return nil
+	}
+
+	balances := p.exchange.GetBalance()
+	if balances == nil {
+		return nil
+	}
+
+	var items []map[string]interface{}
+	for _, coin := range Exchange.ParsePair(p.config.Pair) {
+		if amount, ok := Task.ParseBalance(balances, coin); ok {
+			items = append(items, map[string]interface{}{
+				"name":    coin,
+				"balance": amount,
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"venue":    p.config.Venue,
+		"balances": items,
+	}
+}
+
+// RecordBalances 保存交易对的余额，用于统计收益
+func (p *TrendOkex) RecordBalances() error {
+	if p.db == nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+
+	balances := p.GetBalances()
+	if balances == nil {
+		return errors.New("Fail to get the balances")
+	}
+
+	var record MongoTrend.BalanceInfo
+	for _, item := range balances["balances"].([]map[string]interface{}) {
+		record.Item = append(record.Item, MongoTrend.BalanceItemInfo{
+			Coin:    item["name"].(string),
+			Balance: item["balance"].(float64),
+		})
+	}
+
+	if len(record.Item) == 0 {
+		return nil
+	}
+	return p.db.BalanceCollection.Insert(record)
+}
+
+// GetTrades the opening and the closing of the positions
+func (p *TrendOkex) GetTrades() []Mongo.TradesRecord {
+	if p.db == nil {
+		return nil
+	}
+
+	err, records := p.db.TradeCollection.Find(nil)
+	if err != nil {
+		Logger.Errorf("Fail to get trades:%v", err)
+		return nil
+	}
+
+	var trades []Mongo.TradesRecord
+	for _, record := range records {
+		trades = append(trades, Mongo.TradesRecord{
+			Batch:    record.Batch,
+			Time:     record.OpenTime,
+			Oper:     record.FutureType,
+			Exchange: p.config.Venue,
+			Pair:     record.Pair,
+			Price:    record.FutureOpen,
+			Quantity: record.FutureAmount,
+			OrderID:  record.OrderID,
+			Status:   record.Status,
+		})
+
+		if record.Status != MongoTrend.TradeStatusOpen {
+			closeType := Exchange.TradeTypeCloseLong
+			if record.Type == PositionShort {
+				closeType = Exchange.TradeTypeCloseShort
+			}
+			trades = append(trades, Mongo.TradesRecord{
+				Batch:    record.Batch,
+				Time:     record.CloseTime,
+				Oper:     Exchange.TradeTypeString[closeType],
+				Exchange: p.config.Venue,
+				Pair:     record.Pair,
+				Price:    record.FutureClose,
+				Quantity: record.FutureAmount,
+				Status:   record.Status,
+			})
+		}
+	}
+
+	return trades
+}
+
+func positionInfo(record MongoTrend.TradeInfo) map[string]interface{} {
+	if math.IsNaN(record.FutureClose) {
+		record.FutureClose = 0
+	}
+
+	return map[string]interface{}{
+		"time":         record.OpenTime,
+		"batch":        record.Batch,
+		"pair":         record.Pair,
+		"futuretype":   record.FutureType,
+		"futureopen":   record.FutureOpen,
+		"futureclose":  record.FutureClose,
+		"futureamount": record.FutureAmount,
+		"status":       record.Status,
+	}
+}
+
+func (p *TrendOkex) GetPositions() []map[string]interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.position == nil {
+		return nil
+	}
+	return []map[string]interface{}{positionInfo(*p.position)}
+}
+
+// GetFailedPositions the positions which are closed partly
+func (p *TrendOkex) GetFailedPositions() []map[string]interface{} {
+	if p.db == nil {
+		Logger.Error("MongoDB is not connected")
+		return nil
+	}
+
+	err, records := p.db.TradeCollection.Find(map[string]interface{}{
+		"status": MongoTrend.TradeStatusError,
+	})
+	if err != nil {
+		Logger.Errorf("Error:%v", err)
+		return nil
+	}
+
+	var positions []map[string]interface{}
+	for _, record := range records {
+		positions = append(positions, positionInfo(record))
+	}
+	return positions
+}
+
+// FixFailedPosition 手动处理后更新记录，如{"batch":"...","status":"close","futureclose":100}
+func (p *TrendOkex) FixFailedPosition(updateJSON string) error {
+	if p.db == nil {
+		return errors.New(Task.TaskErrorMsg[Task.TaskLostMongodb])
+	}
+
+	var fix struct {
+		Batch       string  `json:"batch"`
+		Status      string  `json:"status"`
+		FutureClose float64 `json:"futureclose"`
+	}
+	if err := json.Unmarshal([]byte(updateJSON), &fix); err != nil || fix.Batch == "" {
+		return errors.New(Task.TaskErrorMsg[Task.TaskInvalidInput])
+	}
+	if fix.Status != MongoTrend.TradeStatusClose && fix.Status != MongoTrend.TradeStatusOpen {
+		return errors.New(Task.TaskErrorMsg[Task.TaskInvalidInput])
+	}
+
+	update := map[string]interface{}{
+		"status": fix.Status,
+	}
+	if fix.FutureClose > 0 {
+		update["futureclose"] = fix.FutureClose
+	}
+
+	if err := p.db.TradeCollection.Update(map[string]interface{}{
+		"batch":  fix.Batch,
+		"status": MongoTrend.TradeStatusError,
+	}, update); err != nil {
+		return err
+	}
+
+	// the reopened position is managed by the task again
+	if fix.Status == MongoTrend.TradeStatusOpen {
+		return p.loadPosition()
+	}
+	return nil
+}
+
+// ForceClosePositions 在下一次检查时平仓
+func (p *TrendOkex) ForceClosePositions() {
+	p.forceClose = true
+}
+
+func (p *TrendOkex) Close() {
+
+	Logger.Info("关闭任务")
+
+	p.status = Task.StatusNone
+
+	if p.quit != nil {
+		close(p.quit)
+		p.quit = nil
+	}
+
+	if p.scheduler != nil {
+		p.scheduler.Remove(p.recordBalancesJob())
+	}
+
+	if p.exchange != nil {
+		p.exchange.Close()
+	}
+
+	if p.db != nil {
+		p.db.Disconnect()
+		p.db = nil
+	}
+
+	if p.riskDB != nil {
+		p.riskDB.Close()
+		p.riskDB = nil
+	}
+}
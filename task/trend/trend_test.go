@@ -0,0 +1,265 @@
+package trend
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	MongoTrend "madaoQT/mongo/trend"
+	Task "madaoQT/task"
+)
+
+func TestSignal(t *testing.T) {
+	config := defaultConfig.Schaff
+
+	cases := []struct {
+		last, current float64
+		position      int
+		closing       bool
+		open          int
+	}{
+		{20, 30, 0, false, PositionLong},
+		{80, 70, 0, false, PositionShort},
+		{30, 40, 0, false, 0},
+		{20, 30, PositionLong, false, 0},
+		{80, 70, PositionLong, true, PositionShort},
+		{20, 30, PositionShort, true, PositionLong},
+		{80, 70, PositionShort, false, 0},
+	}
+
+	for _, c := range cases {
+		closing, open := signal(config, c.last, c.current, c.position)
+		if closing != c.closing || open != c.open {
+			t.Errorf("%v expect:%v %v got:%v %v", c, c.closing, c.open, closing, open)
+		}
+	}
+}
+
+func TestStopLoss(t *testing.T) {
+	long := &MongoTrend.TradeInfo{Type: PositionLong, FutureOpen: 100}
+	short := &MongoTrend.TradeInfo{Type: PositionShort, FutureOpen: 100}
+
+	if stopLoss(long, 98, 0.03) || !stopLoss(long, 97, 0.03) {
+		t.Errorf("Invalid stop loss of the long position")
+	}
+	if stopLoss(short, 102, 0.03) || !stopLoss(short, 103, 0.03) {
+		t.Errorf("Invalid stop loss of the short position")
+	}
+	if stopLoss(long, 50, 0) || stopLoss(nil, 50, 0.03) {
+		t.Errorf("The stop loss should be disabled")
+	}
+}
+
+type fakeExchange struct {
+	Exchange.IExchange
+	klines []Exchange.KlineValue
+	trades []Exchange.TradeConfig
+	// fill the ratio of the order which is dealt, the order is filled if it's 0
+	fill  float64
+	order *Exchange.OrderInfo
+}
+
+func (p *fakeExchange) GetKline(pair string, period int, limit int) []Exchange.KlineValue {
+	return p.klines
+}
+
+func (p *fakeExchange) GetExchangeName() string {
+	return "fake"
+}
+
+func (p *fakeExchange) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return nil
+}
+
+func (p *fakeExchange) GetBalance() map[string]interface{} {
+	return map[string]interface{}{
+		"ETH":  1.5,
+		"usdt": map[string]interface{}{"balance": 100.0},
+	}
+}
+
+func (p *fakeExchange) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	p.trades = append(p.trades, configs)
+	p.order = &Exchange.OrderInfo{
+		Pair:       configs.Pair,
+		OrderID:    configs.Batch,
+		Type:       configs.Type,
+		DealAmount: configs.Amount,
+		AvgPrice:   configs.Price,
+		Status:     Exchange.OrderStatusDone,
+	}
+	if p.fill > 0 {
+		p.order.DealAmount = configs.Amount * p.fill
+		p.order.Status = Exchange.OrderStatusPartDone
+	}
+	info := *p.order
+	return &Exchange.TradeResult{OrderID: configs.Batch, Info: &info}
+}
+
+func (p *fakeExchange) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	if p.order == nil {
+		return nil
+	}
+	return []Exchange.OrderInfo{*p.order}
+}
+
+func (p *fakeExchange) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	p.order.Status = Exchange.OrderStatusCanceled
+	return &Exchange.TradeResult{OrderID: order.OrderID}
+}
+
+func TestWatch(t *testing.T) {
+	var klines []Exchange.KlineValue
+	for i := 0; i < 800; i++ {
+		price := 100 + 20*math.Sin(float64(i)/15)
+		klines = append(klines, Exchange.KlineValue{OpenTime: float64(i), Close: price})
+	}
+
+	for _, name := range []string{VenueOKEX, VenueBinance} {
+		exchange := new(fakeExchange)
+		task := &TrendOkex{
+			config: defaultConfig,
+			venue:  venues[name],
+		}
+		task.config.LimitClose = 0
+		task.exchange = exchange
+		task.status = Task.StatusProcessing
+
+		for i := klineCount; i < len(klines); i++ {
+			exchange.klines = klines[i-klineCount : i+1]
+			task.Watch()
+			// the signal is checked once for the closed kline
+			task.Watch()
+		}
+
+		if len(exchange.trades) < 4 {
+			t.Fatalf("[%s] There should be trades in the cycles:%v", name, exchange.trades)
+		}
+
+		var position Exchange.TradeType
+		for _, trade := range exchange.trades {
+			switch trade.Type {
+			case Exchange.TradeTypeOpenLong, Exchange.TradeTypeOpenShort, Exchange.TradeTypeBuy:
+				if position != 0 {
+					t.Fatalf("[%s] The position should be closed before opening:%v", name, exchange.trades)
+				}
+				position = trade.Type
+			case Exchange.TradeTypeCloseLong, Exchange.TradeTypeSell:
+				if position != Exchange.TradeTypeOpenLong && position != Exchange.TradeTypeBuy {
+					t.Fatalf("[%s] Invalid close:%v", name, exchange.trades)
+				}
+				position = 0
+			case Exchange.TradeTypeCloseShort:
+				if position != Exchange.TradeTypeOpenShort {
+					t.Fatalf("[%s] Invalid close:%v", name, exchange.trades)
+				}
+				position = 0
+			}
+			if name == VenueBinance && (trade.Type == Exchange.TradeTypeOpenShort || trade.Type == Exchange.TradeTypeOpenLong) {
+				t.Fatalf("The spot venue should only buy and sell:%v", trade)
+			}
+		}
+	}
+}
+
+func TestForceClose(t *testing.T) {
+	exchange := &fakeExchange{klines: make([]Exchange.KlineValue, 201)}
+	task := &TrendOkex{
+		config:   defaultConfig,
+		venue:    venues[VenueFXCM],
+		exchange: exchange,
+		status:   Task.StatusProcessing,
+		position: &MongoTrend.TradeInfo{Batch: "batch", OrderID: "trade", Type: PositionShort, FutureAmount: 2},
+	}
+
+	task.ForceClosePositions()
+	task.Watch()
+
+	if len(exchange.trades) != 1 || exchange.trades[0].Type != Exchange.TradeTypeCloseShort ||
+		exchange.trades[0].Amount != 2 || exchange.trades[0].Batch != "trade" {
+		t.Errorf("Invalid trades:%v", exchange.trades)
+	}
+	if task.GetPositions() != nil {
+		t.Errorf("The position should be closed")
+	}
+}
+
+func TestClosePart(t *testing.T) {
+	orderWaitPeriod = time.Millisecond
+	defer func() { orderWaitPeriod = time.Second }()
+
+	exchange := &fakeExchange{klines: make([]Exchange.KlineValue, 201), fill: 0.25}
+	task := &TrendOkex{
+		config:   defaultConfig,
+		venue:    venues[VenueOKEX],
+		exchange: exchange,
+		status:   Task.StatusProcessing,
+		position: &MongoTrend.TradeInfo{Batch: "batch", Type: PositionLong, FutureAmount: 4},
+	}
+
+	task.ForceClosePositions()
+	task.Watch()
+	if task.position == nil || task.position.FutureAmount != 3 || !task.closing {
+		t.Fatalf("The rest of the position should be kept:%v", task.position)
+	}
+
+	// the rest is closed on the next check
+	exchange.fill = 0
+	task.Watch()
+	if len(exchange.trades) != 2 || exchange.trades[1].Amount != 3 || exchange.trades[1].Type != Exchange.TradeTypeCloseLong {
+		t.Errorf("The rest should be closed:%v", exchange.trades)
+	}
+	if task.position != nil || task.closing {
+		t.Errorf("The position should be closed:%v", task.position)
+	}
+}
+
+func TestGetBalances(t *testing.T) {
+	task := &TrendOkex{
+		config:   defaultConfig,
+		exchange: new(fakeExchange),
+	}
+
+	balances := task.GetBalances()["balances"].([]map[string]interface{})
+	if len(balances) != 2 || balances[0]["name"] != "eth" || balances[0]["balance"] != 1.5 ||
+		balances[1]["name"] != "usdt" || balances[1]["balance"] != 100.0 {
+		t.Errorf("Invalid balances:%v", balances)
+	}
+}
+
+type accountExchange struct {
+	fakeExchange
+	positions []Exchange.AccountPosition
+}
+
+func (p *accountExchange) GetAccount() (error, *Exchange.AccountInfo) {
+	return nil, &Exchange.AccountInfo{Positions: p.positions}
+}
+
+func TestReconcileState(t *testing.T) {
+	short := &MongoTrend.TradeInfo{Type: PositionShort, FutureAmount: 3}
+	if state := savedState(short, "eth/usdt", false); state.Positions["eth/usdt"] != -3 || len(state.Balances) != 0 {
+		t.Errorf("Invalid saved state:%v", state)
+	}
+	long := &MongoTrend.TradeInfo{Type: PositionLong, FutureAmount: 1}
+	if state := savedState(long, "eth/usdt", true); state.Balances["eth"] != 1 || len(state.Positions) != 0 {
+		t.Errorf("Invalid saved state of the spot:%v", state)
+	}
+
+	exchange := &accountExchange{positions: []Exchange.AccountPosition{
+		{Pair: "eth/usd", Amount: -2},
+		{Pair: "btc/usd", Amount: 1},
+	}}
+	err, state, ok := actualState(&Task.RiskGateway{IExchange: exchange}, "eth/usdt", false)
+	if err != nil || !ok || len(state.Positions) != 1 || state.Positions["eth/usdt"] != -2 {
+		t.Errorf("Invalid actual state:%v %v %v", err, state, ok)
+	}
+
+	if err, state, ok = actualState(exchange, "eth/usdt", true); err != nil || !ok || state.Balances["eth"] != 1.5 {
+		t.Errorf("Invalid actual state of the spot:%v %v %v", err, state, ok)
+	}
+	if _, _, ok = actualState(new(fakeExchange), "eth/usdt", false); ok {
+		t.Errorf("The positions can't be listed by the exchange")
+	}
+}
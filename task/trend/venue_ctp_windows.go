@@ -0,0 +1,120 @@
+package trend
+
+import (
+	"errors"
+	"syscall"
+	"time"
+
+	Exchange "madaoQT/exchange"
+	Task "madaoQT/task"
+)
+
+/*
+	CTP通过CTPDll交易，K线从行情服务获取，custom中配置dll、url和config(CTP的连接参数)
+*/
+
+func init() {
+	venues[VenueCTP] = &venue{
+		Exchange: "CTP",
+		Trade:    Task.TrendTradeCollectionCTP,
+		Balance:  Task.TrendBalanceCTP,
+		Fund:     Task.TrendFundCTP,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &ctpAdapter{
+				CTPDll: &Exchange.CTPDll{
+					Dll: syscall.NewLazyDLL(customString(config, "dll", "ctp.dll")),
+					URL: customString(config, "url", ""),
+				},
+				config: customString(config, "config", ""),
+			}
+		},
+	}
+}
+
+type ctpAdapter struct {
+	*Exchange.CTPDll
+	config string
+	event  chan Exchange.EventType
+}
+
+func (p *ctpAdapter) GetExchangeName() string {
+	return "CTP"
+}
+
+func (p *ctpAdapter) SetConfigure(config Exchange.Config) {
+}
+
+func (p *ctpAdapter) WatchEvent() chan Exchange.EventType {
+	return p.event
+}
+
+func (p *ctpAdapter) Start() error {
+	if p.config != "" && !p.CTPDll.SetConfig(p.config) {
+		return errors.New("Invalid CTP config")
+	}
+	if !p.CTPDll.InitMarket() || !p.CTPDll.InitTrade() {
+		return errors.New("Fail to init CTP")
+	}
+	return nil
+}
+
+func (p *ctpAdapter) Close() {
+	p.CTPDll.CloseTrade()
+	p.CTPDll.CloseMarket()
+}
+
+func (p *ctpAdapter) StartTicker(pair string) {
+}
+
+func (p *ctpAdapter) GetTicker(pair string) *Exchange.TickerValue {
+	return nil
+}
+
+func (p *ctpAdapter) GetDepthValue(pair string) [][]Exchange.DepthPrice {
+	return nil
+}
+
+func (p *ctpAdapter) Trade(configs Exchange.TradeConfig) *Exchange.TradeResult {
+	var values map[string]interface{}
+	switch configs.Type {
+	case Exchange.TradeTypeOpenLong:
+		values = p.CTPDll.MarketOpenPosition(configs.Pair, int(configs.Amount), int(configs.Price), 1, 1)
+	case Exchange.TradeTypeOpenShort:
+		values = p.CTPDll.MarketOpenPosition(configs.Pair, int(configs.Amount), int(configs.Price), 0, 1)
+	case Exchange.TradeTypeCloseLong:
+		values = p.CTPDll.MarketClosePosition(configs.Pair, int(configs.Amount), int(configs.Price), 0, 1, 0)
+	case Exchange.TradeTypeCloseShort:
+		values = p.CTPDll.MarketClosePosition(configs.Pair, int(configs.Amount), int(configs.Price), 1, 1, 0)
+	}
+
+	if values == nil {
+		return &Exchange.TradeResult{Error: errors.New("Fail to trade")}
+	}
+
+	info := &Exchange.OrderInfo{
+		Pair:       configs.Pair,
+		OrderID:    configs.Batch,
+		Type:       configs.Type,
+		Price:      configs.Price,
+		Amount:     configs.Amount,
+		DealAmount: configs.Amount,
+		AvgPrice:   configs.Price,
+		Status:     Exchange.OrderStatusDone,
+	}
+	return &Exchange.TradeResult{
+		OrderID: info.OrderID,
+		Info:    info,
+	}
+}
+
+func (p *ctpAdapter) CancelOrder(order Exchange.OrderInfo) *Exchange.TradeResult {
+	return &Exchange.TradeResult{Error: errors.New("The market order can't be canceled")}
+}
+
+func (p *ctpAdapter) GetOrderInfo(filter Exchange.OrderInfo) []Exchange.OrderInfo {
+	return nil
+}
+
+func (p *ctpAdapter) GetKline(pair string, period int, limit int) []Exchange.KlineValue {
+	return p.CTPDll.GetKlines(pair, period, limit, time.Now().Format("150405.000"))
+}
@@ -0,0 +1,224 @@
+package trend
+
+import (
+	Exchange "madaoQT/exchange"
+	Task "madaoQT/task"
+)
+
+/*
+	趋势策略支持的交易所，配置中的venue决定使用的交易所和保存记录的collection
+*/
+
+const (
+	VenueOKEX             = "okex"
+	VenueOkexV3           = "okexv3"
+	VenueOkexV3Spot       = "okexv3spot"
+	VenueBinance          = "binance"
+	VenueBitmex           = "bitmex"
+	VenueCryptoFacilities = "cryptofacilities"
+	VenueDeribit          = "deribit"
+	VenueHuobiSpot        = "huobispot"
+	VenueHuobiDM          = "huobidm"
+	VenueFXCM             = "fxcm"
+	VenueOanda            = "oanda"
+	VenueIB               = "ib"
+	VenueCTP              = "ctp"
+)
+
+type venue struct {
+	// Exchange the name of the keys saved in ExchangeDB
+	Exchange string
+	// Trade, Balance and Fund the collections of the records, the fund collection is optional
+	Trade   string
+	Balance string
+	Fund    string
+	// Spot only the long positions are opened on the spot venues
+	Spot bool
+	// CloseByOrder the position is closed by the order which opened it
+	CloseByOrder bool
+	// Contract the contracts of the futures for the risk checks, the amounts are linear if it's nil
+	Contract func(pair string) Task.RiskContract
+
+	create func(config Exchange.Config) Exchange.IExchange
+}
+
+func customString(config Exchange.Config, key string, value string) string {
+	if v, ok := config.Custom[key].(string); ok && v != "" {
+		return v
+	}
+	return value
+}
+
+// tokenConfig FXCM and OANDA use the API key as the token and the secret as the account
+func tokenConfig(config Exchange.Config) Exchange.Config {
+	custom := map[string]interface{}{}
+	for k, v := range config.Custom {
+		custom[k] = v
+	}
+	custom["token"] = customString(config, "token", config.API)
+	custom["account"] = customString(config, "account", config.Secret)
+	config.Custom = custom
+	return config
+}
+
+var venues = map[string]*venue{
+	VenueOKEX: {
+		Exchange: Exchange.NameOKEX,
+		Trade:    Task.TrendTradeCollectionOKEX,
+		Balance:  Task.TrendBalanceOKEX,
+		Fund:     Task.TrendFundOKEX,
+		Contract: Task.InverseContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			future := new(Exchange.OKExAPI)
+			config.Custom = map[string]interface{}{
+				"exchangeType": Exchange.ExchangeTypeFuture,
+				"period":       customString(config, "period", "quarter"),
+			}
+			future.SetConfigure(config)
+			return future
+		},
+	},
+	VenueOkexV3: {
+		Exchange: Exchange.NameOKEXV3,
+		Trade:    Task.TrendTradeOkexV3,
+		Balance:  Task.TrendBalanceOkexV3,
+		Contract: Task.InverseContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.OKEXV3API{
+				InstrumentType: Exchange.InstrumentTypeSwap,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Passphare:      customString(config, "passphrase", ""),
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueOkexV3Spot: {
+		Exchange: Exchange.NameOKEXV3,
+		Trade:    Task.TrendTradeOkexV3Spot,
+		Balance:  Task.TrendBalanceOkexV3Spot,
+		Spot:     true,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.OKEXV3API{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Passphare:      customString(config, "passphrase", ""),
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueBinance: {
+		Exchange: Exchange.NameBinance,
+		Trade:    Task.TrendTradeCollectionBinance,
+		Balance:  Task.TrendBalanceBinance,
+		Fund:     Task.TrendFundBinance,
+		Spot:     true,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			binance := new(Exchange.Binance)
+			binance.SetConfigure(config)
+			return binance
+		},
+	},
+	VenueBitmex: {
+		Exchange: Exchange.NameBitmex,
+		Trade:    Task.TrendTradeCollectionBitmex,
+		Balance:  Task.TrendBalanceBitmex,
+		Contract: Task.USDContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			bitmex := new(Exchange.ExchangeBitmex)
+			bitmex.SetConfigure(config)
+			return bitmex
+		},
+	},
+	VenueCryptoFacilities: {
+		Exchange: Exchange.NameCryptoFacilities,
+		Trade:    Task.TrendTradeCollectionCryptoFacilities,
+		Balance:  Task.TrendBalanceCryptoFacilities,
+		Fund:     Task.TrendFundCryptoFacilities,
+		Contract: Task.USDContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			cf := new(Exchange.CryptoFacilities)
+			cf.SetConfigure(config)
+			return cf
+		},
+	},
+	VenueDeribit: {
+		Exchange: Exchange.NameDeribit,
+		Trade:    Task.TrendTradeDeribit,
+		Balance:  Task.TrendBalanceDeribit,
+		Contract: Task.USDContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.DeribitV2API{
+				ApiKey:    config.API,
+				SecretKey: config.Secret,
+				Proxy:     config.Proxy,
+			}
+		},
+	},
+	VenueHuobiSpot: {
+		Exchange: Exchange.ExchangeHuobi,
+		Trade:    Task.TrendTradeHuobiSpot,
+		Balance:  Task.TrendBalanceHuobiSpot,
+		Spot:     true,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSpot,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueHuobiDM: {
+		Exchange: Exchange.ExchangeHuobi,
+		Trade:    Task.TrendTradeHuobiDM,
+		Balance:  Task.TrendBalanceHuobiDM,
+		Contract: Task.InverseContract,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			return &Exchange.Huobi{
+				InstrumentType: Exchange.InstrumentTypeSwap,
+				ApiKey:         config.API,
+				SecretKey:      config.Secret,
+				Proxy:          config.Proxy,
+			}
+		},
+	},
+	VenueFXCM: {
+		Exchange:     Exchange.ExchangeFXCM,
+		Trade:        Task.TrendTradeCollectionFXCM,
+		Balance:      Task.TrendBalanceFXCM,
+		Fund:         Task.TrendFundFXCM,
+		CloseByOrder: true,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			fxcm := &fxcmAdapter{FXCM: new(Exchange.FXCM)}
+			fxcm.SetConfigure(tokenConfig(config))
+			return fxcm
+		},
+	},
+	VenueOanda: {
+		Exchange:     Exchange.NameOdanda,
+		Trade:        Task.TrendTradeCollectionOanda,
+		Balance:      Task.TrendBalanceOanda,
+		Fund:         Task.TrendFundOanda,
+		CloseByOrder: true,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			oanda := &oandaAdapter{OandaAPI: new(Exchange.OandaAPI)}
+			oanda.SetConfigure(tokenConfig(config))
+			return oanda
+		},
+	},
+	VenueIB: {
+		Exchange: Exchange.NameInteractiveBrokers,
+		Trade:    Task.TrendTradeIB,
+		Balance:  Task.TrendBalanceIB,
+		create: func(config Exchange.Config) Exchange.IExchange {
+			ib := &ibAdapter{
+				InteractiveBrokers: new(Exchange.InteractiveBrokers),
+				conType:            customString(config, "contype", "STK"),
+			}
+			ib.SetConfigure(config)
+			return ib
+		},
+	},
+}